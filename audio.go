@@ -0,0 +1,172 @@
+package scriber
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alesr/scriber/wav"
+)
+
+// AudioCodec is the codec of the converted audio uploaded
+// to the transcription backend.
+type AudioCodec string
+
+const (
+	// CodecWAV is 16-bit PCM in a WAV container, which every feature
+	// relying on the converted audio, such as chunking, can read.
+	CodecWAV AudioCodec = "wav"
+
+	// CodecFLAC is lossless FLAC, about half the size of WAV. It is only
+	// used for audio streamed to a single upload; chunked, voice activity
+	// detected, and multi-language jobs are converted to WAV.
+	CodecFLAC AudioCodec = "flac"
+)
+
+// AudioSpec describes the audio the default converter produces.
+// Zero fields are left to the default, or to the backend's preference.
+type AudioSpec struct {
+	SampleRate int
+	Channels   int
+	Codec      AudioCodec
+}
+
+// merge returns spec with the non-zero fields of o.
+func (spec AudioSpec) merge(o AudioSpec) AudioSpec {
+	if o.SampleRate > 0 {
+		spec.SampleRate = o.SampleRate
+	}
+	if o.Channels > 0 {
+		spec.Channels = o.Channels
+	}
+	if o.Codec != "" {
+		spec.Codec = o.Codec
+	}
+	return spec
+}
+
+// UploadedAudio describes the audio uploaded to the transcription backend,
+// as read from its header: the negotiated audio for the default converter,
+// whatever a custom converter produced, or the file given to Reprocess.
+// The spec is zero when the audio isn't WAV or FLAC.
+type UploadedAudio struct {
+	AudioSpec
+
+	// Bytes is the number of bytes uploaded, as Output.ConvertedBytes.
+	Bytes int64
+
+	// Duration is the duration of the audio, as Output.AudioDuration.
+	Duration time.Duration
+}
+
+// wavSpec returns the spec of WAV audio in format.
+func wavSpec(format wav.Format) AudioSpec {
+	return AudioSpec{SampleRate: int(format.SampleRate), Channels: int(format.Channels), Codec: CodecWAV}
+}
+
+// AudioPreferrer is implemented by transcription clients that prefer
+// audio other than the default 5200 Hz stereo WAV, e.g. 16 kHz mono for
+// whisper.cpp. The default converter produces the preferred audio,
+// unless WithAudioSpec overrides it.
+type AudioPreferrer interface {
+	PreferredAudio() AudioSpec
+}
+
+// WithAudioSpec sets the audio the default converter produces,
+// overriding the backend's preference, if any. It has no effect
+// with WithConverter.
+func WithAudioSpec(spec AudioSpec) Option {
+	return func(s *Scriber) {
+		s.audioSpec = &spec
+	}
+}
+
+// WithUploadCodec sets the codec the default converter produces,
+// regardless of the backend's preference and WithAudioSpec. FLAC roughly
+// halves the bytes uploaded; the OpenAI API accepts it.
+func WithUploadCodec(codec AudioCodec) Option {
+	return func(s *Scriber) {
+		s.uploadCodec = codec
+	}
+}
+
+// negotiateAudio returns the audio the default converter produces: the
+// default audio, then the backend's preference, then the configured spec.
+func (s *Scriber) negotiateAudio() AudioSpec {
+	spec := AudioSpec{SampleRate: convertedSampleRate, Channels: convertedChannels, Codec: CodecWAV}
+	if p, ok := s.whisperClient.(AudioPreferrer); ok {
+		spec = spec.merge(p.PreferredAudio())
+	}
+	if s.audioSpec != nil {
+		spec = spec.merge(*s.audioSpec)
+	}
+	if s.uploadCodec != "" {
+		spec.Codec = s.uploadCodec
+	}
+	return spec
+}
+
+// codecFor returns the codec the input of in is converted to, or an empty
+// codec when the converter is replaced. The negotiated codec is only used
+// when the audio is streamed to a single upload: chunking, voice activity
+// detection, and multi-language jobs read the converted audio as WAV.
+func (s *Scriber) codecFor(in Input) AudioCodec {
+	if s.ffmpeg == nil {
+		return ""
+	}
+	if s.chunking != nil || s.vad != nil || len(in.Languages) > 0 {
+		return CodecWAV
+	}
+	return s.ffmpeg.codec
+}
+
+// uploadName returns the name audio converted to codec is uploaded under:
+// name with the codec's extension, since some backends sniff the format
+// from it, or name as is when the codec is unknown.
+func uploadName(name string, codec AudioCodec) string {
+	if codec == "" {
+		return name
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name)) + "." + string(codec)
+}
+
+// uploadName returns the name the job's audio, converted to codec, is
+// uploaded under: Input.UploadName, or the sanitized base name of
+// Input.Name, with the codec's extension.
+func (j *job) uploadName(codec AudioCodec) string {
+	name := j.in.UploadName
+	if name == "" {
+		name = sanitizeName(filepath.Base(j.in.Name))
+	}
+	return uploadName(name, codec)
+}
+
+// callerAudio marks the job's audio as supplied by the caller rather
+// than converted, so that it is uploaded under its name as is: its
+// format isn't the one the default converter would produce.
+func (j *job) callerAudio() {
+	j.codec, j.ffmpegArgs = "", nil
+}
+
+// checkUploadName validates the upload name of in, if set: it must be
+// safe as a file name, and have the extension of the audio uploaded,
+// or any extension when the converter is replaced.
+func (s *Scriber) checkUploadName(in Input) error {
+	name := in.UploadName
+	if name == "" {
+		return nil
+	}
+	if sanitizeName(name) != name {
+		return fmt.Errorf("%w: %q is not a safe file name", errUploadName, name)
+	}
+
+	ext := inputExtension(name)
+	if codec := s.codecFor(in); codec != "" && ext != "."+string(codec) {
+		return fmt.Errorf("%w: %q should end in .%s", errUploadName, name, codec)
+	}
+	if ext == "" {
+		return fmt.Errorf("%w: %q has no extension", errUploadName, name)
+	}
+	return nil
+}
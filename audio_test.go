@@ -0,0 +1,677 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alesr/scriber/wav"
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// preferringClient is a fake backend advertising the audio it prefers.
+type preferringClient struct {
+	mockWhisperClient
+	spec AudioSpec
+}
+
+func (c *preferringClient) PreferredAudio() AudioSpec {
+	return c.spec
+}
+
+func TestNegotiateAudio(t *testing.T) {
+	t.Parallel()
+
+	wavArgs := func(rate, channels string) []string {
+		return []string{"-y", "-i", "pipe:0", "-vn", "-acodec", "pcm_s16le", "-ar", rate, "-ac", channels, "-b:a", "32k", "-f", "wav", "pipe:1"}
+	}
+	flacArgs := func(rate, channels string) []string {
+		return []string{"-y", "-i", "pipe:0", "-vn", "-acodec", "flac", "-ar", rate, "-ac", channels, "-f", "flac", "pipe:1"}
+	}
+
+	testCases := []struct {
+		name           string
+		givenClient    whisperClient
+		givenOpts      []Option
+		givenInput     Input
+		expectedArgs   []string
+		expectedLimitF [2]int
+	}{
+		{
+			name:           "no preference",
+			givenClient:    &mockWhisperClient{},
+			expectedArgs:   wavArgs("5200", "2"),
+			expectedLimitF: [2]int{5200, 2},
+		},
+		{
+			name:           "hosted API",
+			givenClient:    &preferringClient{spec: AudioSpec{SampleRate: 16000, Channels: 1, Codec: CodecWAV}},
+			expectedArgs:   wavArgs("16000", "1"),
+			expectedLimitF: [2]int{16000, 1},
+		},
+		{
+			name:           "partial preference",
+			givenClient:    &preferringClient{spec: AudioSpec{Channels: 1}},
+			expectedArgs:   wavArgs("5200", "1"),
+			expectedLimitF: [2]int{5200, 1},
+		},
+		{
+			name:           "FLAC backend",
+			givenClient:    &preferringClient{spec: AudioSpec{SampleRate: 16000, Channels: 1, Codec: CodecFLAC}},
+			expectedArgs:   flacArgs("16000", "1"),
+			expectedLimitF: [2]int{16000, 1},
+		},
+		{
+			name:           "FLAC backend with chunking",
+			givenClient:    &preferringClient{spec: AudioSpec{SampleRate: 16000, Channels: 1, Codec: CodecFLAC}},
+			givenOpts:      []Option{WithChunking(ChunkConfig{Length: time.Minute})},
+			expectedArgs:   wavArgs("16000", "1"),
+			expectedLimitF: [2]int{16000, 1},
+		},
+		{
+			name:           "FLAC backend with several languages",
+			givenClient:    &preferringClient{spec: AudioSpec{SampleRate: 16000, Channels: 1, Codec: CodecFLAC}},
+			givenInput:     Input{Languages: []string{"en", "pt"}},
+			expectedArgs:   wavArgs("16000", "1"),
+			expectedLimitF: [2]int{16000, 1},
+		},
+		{
+			name:           "overridden preference",
+			givenClient:    &preferringClient{spec: AudioSpec{SampleRate: 16000, Channels: 1, Codec: CodecFLAC}},
+			givenOpts:      []Option{WithAudioSpec(AudioSpec{SampleRate: 8000, Codec: CodecWAV})},
+			expectedArgs:   wavArgs("8000", "1"),
+			expectedLimitF: [2]int{8000, 1},
+		},
+		{
+			name:           "FLAC without preference",
+			givenClient:    &mockWhisperClient{},
+			givenOpts:      []Option{WithAudioSpec(AudioSpec{Codec: CodecFLAC})},
+			expectedArgs:   flacArgs("5200", "2"),
+			expectedLimitF: [2]int{5200, 2},
+		},
+		{
+			name:           "FLAC upload codec",
+			givenClient:    &preferringClient{spec: AudioSpec{SampleRate: 16000, Channels: 1, Codec: CodecWAV}},
+			givenOpts:      []Option{WithUploadCodec(CodecFLAC)},
+			expectedArgs:   flacArgs("16000", "1"),
+			expectedLimitF: [2]int{16000, 1},
+		},
+		{
+			name:           "WAV upload codec",
+			givenClient:    &preferringClient{spec: AudioSpec{Codec: CodecFLAC}},
+			givenOpts:      []Option{WithUploadCodec(CodecWAV), WithAudioSpec(AudioSpec{Codec: CodecFLAC})},
+			expectedArgs:   wavArgs("5200", "2"),
+			expectedLimitF: [2]int{5200, 2},
+		},
+		{
+			name:        "custom converter",
+			givenClient: &preferringClient{spec: AudioSpec{SampleRate: 16000, Channels: 1, Codec: CodecFLAC}},
+			givenOpts: []Option{WithConverter(func(context.Context, io.Reader, io.Writer) error {
+				return nil
+			})},
+			expectedLimitF: [2]int{5200, 2},
+		},
+		{
+			name:           "explicit upload limit format",
+			givenClient:    &preferringClient{spec: AudioSpec{SampleRate: 16000, Channels: 1}},
+			givenOpts:      []Option{WithUploadLimit(UploadLimit{MaxSize: 1 << 20, SampleRate: 44100})},
+			expectedArgs:   wavArgs("16000", "1"),
+			expectedLimitF: [2]int{44100, 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := New(noopLogger(), tc.givenClient, tc.givenOpts...)
+
+			assert.Equal(t, tc.expectedArgs, s.ffmpegArgsFor(ffmpegInput{codec: s.codecFor(tc.givenInput)}))
+			assert.Equal(t, tc.expectedLimitF, [2]int{s.uploadLimit.SampleRate, s.uploadLimit.Channels})
+		})
+	}
+}
+
+func TestProcess_NegotiatedAudio(t *testing.T) {
+	t.Parallel()
+
+	client := &preferringClient{
+		mockWhisperClient: mockWhisperClient{
+			transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+				_, err := io.ReadAll(in.Data)
+				return []byte("hello"), err
+			},
+		},
+		spec: AudioSpec{SampleRate: 16000, Channels: 1, Codec: CodecFLAC},
+	}
+	s := New(noopLogger(), client)
+
+	// Stand in for ffmpeg, recording the arguments the job asks for.
+	var gotArgs []string
+	s.runFFmpeg = func(_ context.Context, args []string, r io.Reader, w io.Writer) error {
+		gotArgs = args
+		_, err := io.Copy(w, r)
+		return err
+	}
+
+	err := s.Process(context.TODO(), Input{
+		Name:       "talk.mp4",
+		OutputType: OutputTypeTranscript,
+		Language:   "en",
+		Data:       io.NopCloser(bytes.NewReader([]byte("flac"))),
+	})
+	require.NoError(t, err)
+
+	out := <-s.Collect()
+	require.NoError(t, out.Body.Close())
+
+	expected := "ffmpeg -y -i pipe:0 -vn -acodec flac -ar 16000 -ac 1 -f flac pipe:1"
+	assert.Equal(t, expected, ffmpegCommand(gotArgs))
+	assert.Equal(t, expected, out.Metadata[MetadataFFmpegCommand])
+}
+
+func TestUploadName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		givenName string
+		givenCode AudioCodec
+		expected  string
+	}{
+		{name: "wav", givenName: "talk.mp4", givenCode: CodecWAV, expected: "talk.wav"},
+		{name: "flac", givenName: "talk.mp4", givenCode: CodecFLAC, expected: "talk.flac"},
+		{name: "several dots", givenName: "dir.v2/talk.final.mp4", givenCode: CodecFLAC, expected: "dir.v2/talk.final.flac"},
+		{name: "no extension", givenName: "talk", givenCode: CodecWAV, expected: "talk.wav"},
+		{name: "unknown codec", givenName: "talk.mp4", expected: "talk.mp4"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, uploadName(tc.givenName, tc.givenCode))
+		})
+	}
+}
+
+func TestProcess_UploadCodec(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name                  string
+		givenCodec            AudioCodec
+		givenAudio            []byte
+		expectedUploadName    string
+		expectedCommand       string
+		expectedAudioDuration time.Duration
+	}{
+		{
+			name:                  "wav",
+			givenCodec:            CodecWAV,
+			givenAudio:            syntheticWAV(2),
+			expectedUploadName:    "talk.wav",
+			expectedCommand:       "ffmpeg -y -i pipe:0 -vn -acodec pcm_s16le -ar 5200 -ac 2 -b:a 32k -f wav pipe:1",
+			expectedAudioDuration: 2 * time.Second,
+		},
+		{
+			name:                  "flac",
+			givenCodec:            CodecFLAC,
+			givenAudio:            append(syntheticFLACHeader(5200, 2, 10400), bytes.Repeat([]byte{0xff}, 100)...),
+			expectedUploadName:    "talk.flac",
+			expectedCommand:       "ffmpeg -y -i pipe:0 -vn -acodec flac -ar 5200 -ac 2 -f flac pipe:1",
+			expectedAudioDuration: 2 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotName string
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					gotName = in.Name
+					_, err := io.ReadAll(in.Data)
+					return []byte("hello"), err
+				},
+			}, WithUploadCodec(tc.givenCodec))
+
+			// Stand in for ffmpeg, keeping the arguments of the default converter.
+			s.convertToWavFunc = copyConverter
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "talk.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(tc.givenAudio)),
+			})
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+
+			assert.Equal(t, tc.expectedUploadName, gotName)
+			assert.Equal(t, tc.expectedCommand, out.Metadata[MetadataFFmpegCommand])
+			assert.Equal(t, tc.expectedAudioDuration, out.AudioDuration)
+		})
+	}
+}
+
+// fakeFFmpeg stands in for ffmpeg, ignoring its input and producing
+// a second of silence in the sample rate, channels, and format of the
+// arguments the job asks for.
+func fakeFFmpeg(_ context.Context, args []string, r io.Reader, w io.Writer) error {
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return err
+	}
+
+	spec := AudioSpec{Codec: CodecWAV}
+	for i := 0; i < len(args)-1; i++ {
+		switch args[i] {
+		case "-ar":
+			spec.SampleRate, _ = strconv.Atoi(args[i+1])
+		case "-ac":
+			spec.Channels, _ = strconv.Atoi(args[i+1])
+		case "-f":
+			spec.Codec = AudioCodec(args[i+1])
+		}
+	}
+
+	if spec.Codec == CodecFLAC {
+		_, err := w.Write(append(syntheticFLACHeader(spec.SampleRate, spec.Channels, 0), make([]byte, 100)...))
+		return err
+	}
+
+	format := wav.Format{AudioFormat: wav.FormatPCM, Channels: uint16(spec.Channels), SampleRate: uint32(spec.SampleRate), BitsPerSample: 16}
+	if err := wav.WriteHeader(w, format, uint32(format.ByteRate())); err != nil {
+		return err
+	}
+	_, err := w.Write(make([]byte, format.ByteRate()))
+	return err
+}
+
+func TestProcess_UploadedAudio(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		givenClient   whisperClient
+		givenOpts     []Option
+		givenFFmpeg   bool // The default converter is kept, with fakeFFmpeg standing in for ffmpeg.
+		givenData     []byte
+		expectedAudio UploadedAudio
+	}{
+		{
+			name:        "default",
+			givenFFmpeg: true,
+			expectedAudio: UploadedAudio{
+				AudioSpec: AudioSpec{SampleRate: 5200, Channels: 2, Codec: CodecWAV},
+				Bytes:     wav.HeaderSize + 5200*2*2,
+				Duration:  time.Second,
+			},
+		},
+		{
+			name:        "backend preference",
+			givenClient: &preferringClient{spec: AudioSpec{SampleRate: 16000, Channels: 1}},
+			givenFFmpeg: true,
+			expectedAudio: UploadedAudio{
+				AudioSpec: AudioSpec{SampleRate: 16000, Channels: 1, Codec: CodecWAV},
+				Bytes:     wav.HeaderSize + 16000*2,
+				Duration:  time.Second,
+			},
+		},
+		{
+			name:        "overridden",
+			givenClient: &preferringClient{spec: AudioSpec{SampleRate: 16000, Channels: 1}},
+			givenOpts:   []Option{WithAudioSpec(AudioSpec{SampleRate: 8000})},
+			givenFFmpeg: true,
+			expectedAudio: UploadedAudio{
+				AudioSpec: AudioSpec{SampleRate: 8000, Channels: 1, Codec: CodecWAV},
+				Bytes:     wav.HeaderSize + 8000*2,
+				Duration:  time.Second,
+			},
+		},
+		{
+			// Streamed FLAC doesn't declare its duration.
+			name:        "flac",
+			givenOpts:   []Option{WithUploadCodec(CodecFLAC)},
+			givenFFmpeg: true,
+			expectedAudio: UploadedAudio{
+				AudioSpec: AudioSpec{SampleRate: 5200, Channels: 2, Codec: CodecFLAC},
+				Bytes:     flacHeaderSize + 100,
+			},
+		},
+		{
+			name: "flac overridden for chunking",
+			givenOpts: []Option{
+				WithUploadCodec(CodecFLAC),
+				WithChunking(ChunkConfig{Length: time.Minute}),
+			},
+			givenFFmpeg: true,
+			expectedAudio: UploadedAudio{
+				AudioSpec: AudioSpec{SampleRate: 5200, Channels: 2, Codec: CodecWAV},
+				Bytes:     wav.HeaderSize + 5200*2*2,
+				Duration:  time.Second,
+			},
+		},
+		{
+			name:      "custom converter",
+			givenOpts: []Option{WithConverter(copyConverter)},
+			givenData: syntheticWAV(2),
+			expectedAudio: UploadedAudio{
+				AudioSpec: AudioSpec{SampleRate: 100, Channels: 1, Codec: CodecWAV},
+				Bytes:     wav.HeaderSize + 2*200,
+				Duration:  2 * time.Second,
+			},
+		},
+		{
+			name:          "custom converter producing another format",
+			givenOpts:     []Option{WithConverter(copyConverter)},
+			givenData:     []byte("ID3 an mp3 rather than a wav"),
+			expectedAudio: UploadedAudio{Bytes: 28},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			transcribe := func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+				_, err := io.ReadAll(in.Data)
+				return []byte("hello"), err
+			}
+
+			client := tc.givenClient
+			switch c := client.(type) {
+			case nil:
+				client = &mockWhisperClient{transcribeAudioFunc: transcribe}
+			case *preferringClient:
+				c.transcribeAudioFunc = transcribe
+			}
+
+			s := New(noopLogger(), client, tc.givenOpts...)
+			if tc.givenFFmpeg {
+				s.runFFmpeg = fakeFFmpeg
+			}
+
+			data := tc.givenData
+			if data == nil {
+				data = []byte("video")
+			}
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "talk.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(data)),
+			})
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+
+			assert.Equal(t, tc.expectedAudio, out.AudioSpec)
+			assert.Equal(t, out.ConvertedBytes, out.AudioSpec.Bytes)
+			assert.Equal(t, out.AudioDuration, out.AudioSpec.Duration)
+		})
+	}
+}
+
+func TestReprocess_UploadedAudio(t *testing.T) {
+	t.Parallel()
+
+	// The file given to Reprocess is uploaded as is, whatever the
+	// audio the default converter would produce.
+	path := filepath.Join(t.TempDir(), "talk.wav")
+	require.NoError(t, os.WriteFile(path, syntheticWAV(3), 0o600))
+
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.ReadAll(in.Data)
+			return []byte("hello"), err
+		},
+	}, WithAudioSpec(AudioSpec{SampleRate: 16000, Channels: 1}))
+
+	err := s.Reprocess(context.TODO(), path, Input{
+		Name:       "talk.mp4",
+		OutputType: OutputTypeTranscript,
+		Language:   "en",
+	})
+	require.NoError(t, err)
+
+	out := <-s.Collect()
+	require.NoError(t, out.Body.Close())
+
+	assert.Equal(t, UploadedAudio{
+		AudioSpec: AudioSpec{SampleRate: 100, Channels: 1, Codec: CodecWAV},
+		Bytes:     wav.HeaderSize + 3*200,
+		Duration:  3 * time.Second,
+	}, out.AudioSpec)
+}
+
+func BenchmarkUploadCodec(b *testing.B) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		b.Skip("ffmpeg not found")
+	}
+
+	// A minute of a tone over noise stands in for a recording.
+	sample, err := exec.Command("ffmpeg", "-v", "error",
+		"-f", "lavfi", "-i", "sine=frequency=220:duration=60",
+		"-f", "lavfi", "-i", "anoisesrc=amplitude=0.05:duration=60",
+		"-filter_complex", "amix=inputs=2", "-acodec", "pcm_s16le", "-f", "wav", "pipe:1",
+	).Output()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, codec := range []AudioCodec{CodecWAV, CodecFLAC} {
+		b.Run(string(codec), func(b *testing.B) {
+			args := buildFFmpegArgs(defaultFFmpegConfig(), ffmpegInput{codec: codec})
+
+			var n int64
+			for i := 0; i < b.N; i++ {
+				w := &countingWriter{}
+				if err := runFFmpeg(context.Background(), args, bytes.NewReader(sample), w); err != nil {
+					b.Fatal(err)
+				}
+				n = w.n
+			}
+			b.ReportMetric(float64(n), "upload-bytes")
+		})
+	}
+}
+
+// countingWriter counts the bytes written to it.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+func TestProcess_UploadName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name               string
+		givenName          string
+		givenUploadName    string
+		givenCodec         AudioCodec
+		givenConverter     bool
+		expectedUploadName string
+		expectedErr        error
+	}{
+		{
+			name:               "default",
+			givenName:          "/mnt/tenants/acme/board-meeting.mp4",
+			expectedUploadName: "board-meeting.wav",
+		},
+		{
+			name:               "default sanitized",
+			givenName:          "/mnt/tenants/acme/board:meeting.mp4",
+			givenCodec:         CodecFLAC,
+			expectedUploadName: "board_meeting.flac",
+		},
+		{
+			name:               "default with converter",
+			givenName:          "/mnt/tenants/acme/board-meeting.mp4",
+			givenConverter:     true,
+			expectedUploadName: "board-meeting.mp4",
+		},
+		{
+			name:               "set",
+			givenName:          "/mnt/tenants/acme/board-meeting.mp4",
+			givenUploadName:    "audio.wav",
+			expectedUploadName: "audio.wav",
+		},
+		{
+			name:               "set in upper case",
+			givenName:          "board-meeting.mp4",
+			givenUploadName:    "AUDIO.FLAC",
+			givenCodec:         CodecFLAC,
+			expectedUploadName: "AUDIO.flac",
+		},
+		{
+			name:               "set with converter",
+			givenName:          "board-meeting.mp4",
+			givenUploadName:    "audio.ogg",
+			givenConverter:     true,
+			expectedUploadName: "audio.ogg",
+		},
+		{
+			name:            "extension mismatch",
+			givenName:       "board-meeting.mp4",
+			givenUploadName: "audio.flac",
+			expectedErr:     errUploadName,
+		},
+		{
+			name:            "no extension with converter",
+			givenName:       "board-meeting.mp4",
+			givenUploadName: "audio",
+			givenConverter:  true,
+			expectedErr:     errUploadName,
+		},
+		{
+			name:            "path",
+			givenName:       "board-meeting.mp4",
+			givenUploadName: "acme/audio.wav",
+			expectedErr:     errUploadName,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := []Option{WithUploadCodec(tc.givenCodec)}
+			if tc.givenConverter {
+				opts = append(opts, WithConverter(copyConverter))
+			}
+
+			var uploaded string
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					uploaded = in.Name
+					_, err := io.ReadAll(in.Data)
+					return []byte("hello"), err
+				},
+			}, opts...)
+
+			if !tc.givenConverter {
+				// Stand in for ffmpeg, keeping the codec of the default converter.
+				s.convertToWavFunc = copyConverter
+			}
+
+			err := s.Process(context.TODO(), Input{
+				Name:       tc.givenName,
+				UploadName: tc.givenUploadName,
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+			})
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+
+				var pe *ProcessError
+				require.ErrorAs(t, err, &pe)
+				assert.Equal(t, StageValidation, pe.Stage)
+				assert.Empty(t, uploaded, "nothing should be uploaded")
+				return
+			}
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+			assert.Equal(t, tc.expectedUploadName, uploaded)
+			assert.Equal(t, baseName(tc.givenName)+".txt", out.Name, "outputs are named after Name")
+		})
+	}
+}
+
+func TestUploadName_CallerAudio(t *testing.T) {
+	t.Parallel()
+
+	// Audio the caller supplies is uploaded under its name as is,
+	// whatever the codec the default converter would produce.
+	testCases := []struct {
+		name               string
+		givenTranscribe    func(s *Scriber, name string) error
+		givenName          string
+		expectedUploadName string
+	}{
+		{
+			name: "transcribe",
+			givenTranscribe: func(s *Scriber, name string) error {
+				_, err := s.Transcribe(context.TODO(), name, "en", OutputTypeTranscript, bytes.NewReader([]byte("mp3 data")))
+				return err
+			},
+			givenName:          "talk.mp3",
+			expectedUploadName: "talk.mp3",
+		},
+		{
+			name: "reprocess",
+			givenTranscribe: func(s *Scriber, name string) error {
+				path := filepath.Join(t.TempDir(), "talk.wav")
+				if err := os.WriteFile(path, syntheticWAV(1), 0o600); err != nil {
+					return err
+				}
+				if err := s.Reprocess(context.TODO(), path, Input{Name: name, OutputType: OutputTypeTranscript, Language: "en"}); err != nil {
+					return err
+				}
+				out := <-s.Collect()
+				return out.Body.Close()
+			},
+			givenName:          "/mnt/acme/talk.mp4",
+			expectedUploadName: "talk.mp4",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var uploaded string
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					uploaded = in.Name
+					_, err := io.ReadAll(in.Data)
+					return []byte("hello"), err
+				},
+			}, WithUploadCodec(CodecFLAC))
+
+			require.NoError(t, tc.givenTranscribe(s, tc.givenName))
+			assert.Equal(t, tc.expectedUploadName, uploaded)
+		})
+	}
+}
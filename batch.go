@@ -0,0 +1,593 @@
+package scriber
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchOptions configures ProcessBatch and ProcessDir.
+type BatchOptions struct {
+	// Parallelism is the number of inputs processed concurrently.
+	// It defaults to 1.
+	Parallelism int
+
+	// FailFast stops starting inputs after the first failure. The inputs
+	// that weren't started are reported as skipped. By default, every
+	// input is processed regardless of failures.
+	FailFast bool
+
+	// ReportPath, when set, is the file the BatchReport is written to
+	// as JSON once the batch is done.
+	ReportPath string
+
+	// RetryBudget, when set, caps the retries of the whole batch.
+	// Retries are configured with WithRetry.
+	RetryBudget *RetryBudget
+
+	// FailFastValidation has ProcessBatch validate every input with
+	// ValidateBatch before starting, and process none of them if any
+	// is invalid: the invalid inputs are reported as failed at
+	// StageValidation and the others as skipped. ProcessDir ignores it.
+	FailFastValidation bool
+}
+
+// BatchReport summarizes a batch.
+type BatchReport struct {
+	Succeeded []FileResult `json:"succeeded"`
+	Failed    []FileResult `json:"failed"`
+
+	// Skipped lists the names of the inputs that weren't processed.
+	Skipped []string `json:"skipped"`
+
+	// Resumed lists the inputs that weren't processed because a previous
+	// run completed them, with the reason. See DirOptions.ResumeFrom.
+	Resumed []FileResult `json:"resumed,omitempty"`
+
+	// Deferred lists the inputs handed off to the long job handler, with
+	// the reason. They neither succeeded nor failed. See WithLongJobRouting.
+	Deferred []FileResult `json:"deferred,omitempty"`
+
+	// TotalAudioDuration is the audio duration of the succeeded inputs.
+	TotalAudioDuration time.Duration `json:"total_audio_duration"`
+	WallTime           time.Duration `json:"wall_time"`
+
+	// RetryBudget is the state of the retry budget, if set.
+	RetryBudget *RetryBudgetReport `json:"retry_budget,omitempty"`
+}
+
+// FileResult is the outcome of processing one input of a batch.
+type FileResult struct {
+	// Index is the position of the input in the batch.
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+
+	// Stage and Error describe the failure of failed inputs.
+	Stage Stage  `json:"stage,omitempty"`
+	Error string `json:"error,omitempty"`
+
+	AudioDuration time.Duration `json:"audio_duration,omitempty"`
+
+	// Size and ModTime fingerprint the files processed by ProcessDir,
+	// telling whether they changed since.
+	Size    int64     `json:"size,omitempty"`
+	ModTime time.Time `json:"mod_time,omitempty"`
+
+	// Reason tells why a resumed input was skipped,
+	// or why a deferred one was handed off.
+	Reason string `json:"reason,omitempty"`
+}
+
+// ProcessBatch processes inputs with Process, continuing past failures
+// unless opts.FailFast is set, and reports on every input. Outputs are
+// published on the Collect channel as usual, so it must be read while the
+// batch runs. When inputs fail, the returned error is a *BatchError
+// holding their errors.
+func (s *Scriber) ProcessBatch(ctx context.Context, inputs []Input, opts BatchOptions) (*BatchReport, error) {
+	if opts.FailFastValidation {
+		if report, err := s.rejectBatch(inputs, opts); err != nil {
+			return report, err
+		}
+	}
+	return s.processBatch(ctx, inputSource(inputs), opts)
+}
+
+// ValidateBatch runs the checks Process runs on each input before reading
+// it: its fields, name, extension or content type, languages, output type,
+// and the limits that apply to its Size hint, along with the configuration
+// of s. It reads no data and makes no request. The returned errors are
+// aligned with inputs, nil for the valid ones, and *ProcessErrors at
+// StageValidation for the others.
+func (s *Scriber) ValidateBatch(inputs []Input) []error {
+	errs := make([]error, len(inputs))
+	for i, in := range inputs {
+		if in, err := s.validateStatic(in); err != nil {
+			pe := asProcessError(StageValidation, in, err)
+			pe.Input.Index = i
+			errs[i] = pe
+		}
+	}
+	return errs
+}
+
+// validateStatic checks in as Process does before reading its data,
+// returning it with its defaults applied and its name normalized.
+func (s *Scriber) validateStatic(in Input) (Input, error) {
+	in, err := s.normalizeInput(s.applyDefaults(in))
+	if err != nil {
+		return in, fmt.Errorf("invalid input: %w", err)
+	}
+	if err := in.validate(); err != nil {
+		return in, fmt.Errorf("invalid input: %w", err)
+	}
+	if err := s.checkUploadName(in); err != nil {
+		return in, fmt.Errorf("invalid input: %w", err)
+	}
+	if err := s.checkCapabilities(in); err != nil {
+		return in, err
+	}
+	if in.DataRef != "" && s.blobStore == nil {
+		return in, fmt.Errorf("invalid input: %w", errBlobStoreRequired)
+	}
+	if err := s.checkSizeHint(in); err != nil {
+		return in, err
+	}
+	if !s.allowEmptyInput && in.sizeDeclared && in.Size == 0 {
+		return in, fmt.Errorf("%w: declared size is zero", errEmptyInput)
+	}
+	return in, s.checkConfig()
+}
+
+// rejectBatch validates inputs, and if any is invalid, releases them all
+// and reports the batch as refused. It returns a nil error otherwise.
+func (s *Scriber) rejectBatch(inputs []Input, opts BatchOptions) (*BatchReport, error) {
+	start := time.Now()
+	src := inputSource(inputs)
+
+	var (
+		report = &BatchReport{Skipped: []string{}}
+		inErrs []*ProcessError
+	)
+	for i, err := range s.ValidateBatch(inputs) {
+		if err == nil {
+			report.Skipped = append(report.Skipped, src.name(i))
+			continue
+		}
+
+		pe := err.(*ProcessError)
+		res := src.result(i)
+		res.Stage, res.Error = pe.Stage, pe.Error()
+		report.Failed = append(report.Failed, res)
+		inErrs = append(inErrs, pe)
+	}
+	if len(inErrs) == 0 {
+		return nil, nil
+	}
+
+	for i := range inputs {
+		src.skip(i)
+	}
+	report.WallTime = time.Since(start)
+
+	var errs []error
+	if opts.ReportPath != "" {
+		if err := writeBatchReport(opts.ReportPath, report); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return report, &BatchError{Errors: inErrs, Err: errors.Join(errs...)}
+}
+
+// batchSource provides the inputs of a batch.
+type batchSource interface {
+	len() int
+	name(i int) string
+
+	// result returns the result of input i, before processing.
+	result(i int) FileResult
+
+	// resumed returns the inputs left out of the batch as completed already.
+	resumed() []FileResult
+
+	// open returns input i, to be processed.
+	open(i int) (Input, error)
+
+	// skip releases input i, which won't be processed.
+	skip(i int)
+}
+
+// inputSource is a batch of given inputs.
+type inputSource []Input
+
+func (src inputSource) len() int                  { return len(src) }
+func (src inputSource) name(i int) string         { return src[i].Name }
+func (src inputSource) result(i int) FileResult   { return FileResult{Index: i, Name: src[i].Name} }
+func (src inputSource) resumed() []FileResult     { return nil }
+func (src inputSource) open(i int) (Input, error) { return src[i], nil }
+
+// skip closes the input data, as Process would have.
+func (src inputSource) skip(i int) {
+	if in := src[i]; in.Data != nil && !in.KeepOpen {
+		in.Data.Close()
+	}
+}
+
+// DirOptions configures ProcessDir.
+type DirOptions struct {
+	BatchOptions
+
+	// OutputType and Language apply to every file.
+	// Unset fields take the Scriber's defaults.
+	OutputType OutputType
+	Language   string
+
+	// Extensions selects the files to process by extension, compared
+	// case-insensitively. It defaults to DefaultMediaExtensions.
+	Extensions []string
+
+	// ResumeFrom is the path of the report of a previous run. Files it
+	// reports as succeeded, resumed, or deferred are skipped, unless their
+	// size or modification time changed since.
+	ResumeFrom string
+
+	// SkipIfOutputExists skips files whose output, named as Output.Name,
+	// exists in OutputDir. With FreshOutputOnly, the output must also
+	// have been modified after the file.
+	SkipIfOutputExists bool
+	FreshOutputOnly    bool
+
+	// OutputDir is where SkipIfOutputExists looks for outputs.
+	// It defaults to the processed directory.
+	OutputDir string
+}
+
+// DefaultMediaExtensions are the file extensions ProcessDir processes by default.
+var DefaultMediaExtensions = []string{
+	".aac", ".flac", ".m4a", ".mkv", ".mov", ".mp3", ".mp4", ".mpeg", ".mpga", ".oga", ".ogg", ".opus", ".wav", ".webm",
+}
+
+// ProcessDir processes the media files found under dir, recursively, as
+// ProcessBatch does. Inputs are named after their path relative to dir.
+// Files are opened only once their turn comes. Files completed by a
+// previous run can be skipped, see DirOptions.ResumeFrom.
+func (s *Scriber) ProcessDir(ctx context.Context, dir string, opts DirOptions) (*BatchReport, error) {
+	files, err := mediaFiles(dir, opts.Extensions)
+	if err != nil {
+		return nil, err
+	}
+
+	done := map[string]FileResult{}
+	if opts.ResumeFrom != "" {
+		prev, err := readBatchReport(opts.ResumeFrom)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range append(prev.Succeeded, prev.Resumed...) {
+			done[r.Name] = r
+		}
+		for _, r := range prev.Deferred {
+			r.Reason = reasonDeferred
+			done[r.Name] = r
+		}
+	}
+
+	src := &dirSource{dir: dir, opts: opts}
+	for _, f := range files {
+		reason := s.resumeReason(dir, f, done, opts)
+		if reason == "" {
+			src.files = append(src.files, f)
+			continue
+		}
+
+		s.logger.Info("Skipping input", slog.String("file", f.name), slog.String("reason", reason))
+
+		res := done[f.name]
+		res.Index, res.Name, res.Size, res.ModTime, res.Reason = len(src.done), f.name, f.size, f.modTime, reason
+		src.done = append(src.done, res)
+	}
+
+	return s.processBatch(ctx, src, opts.BatchOptions)
+}
+
+// reasonDeferred is the reason of the inputs resumed
+// because a previous run handed them off.
+const reasonDeferred = "deferred in a previous run"
+
+// resumeReason returns why f doesn't need to be processed, if it doesn't.
+func (s *Scriber) resumeReason(dir string, f dirFile, done map[string]FileResult, opts DirOptions) string {
+	if prev, ok := done[f.name]; ok && prev.Size == f.size && prev.ModTime.Equal(f.modTime) {
+		if prev.Reason == reasonDeferred {
+			return reasonDeferred
+		}
+		return "completed in a previous run"
+	}
+
+	if !opts.SkipIfOutputExists {
+		return ""
+	}
+
+	outDir := opts.OutputDir
+	if outDir == "" {
+		outDir = dir
+	}
+	outType, _ := s.resolveOutputType(s.applyDefaults(Input{OutputType: opts.OutputType}).OutputType)
+
+	info, err := os.Stat(filepath.Join(outDir, generateOutputFileName(f.name, outType)))
+	if err != nil {
+		return ""
+	}
+	if opts.FreshOutputOnly && !info.ModTime().After(f.modTime) {
+		return ""
+	}
+	return "output exists"
+}
+
+// dirFile is a file found by ProcessDir.
+type dirFile struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+// dirSource is a batch of the files in a directory.
+type dirSource struct {
+	dir   string
+	files []dirFile
+	done  []FileResult
+	opts  DirOptions
+}
+
+func (src *dirSource) len() int              { return len(src.files) }
+func (src *dirSource) name(i int) string     { return src.files[i].name }
+func (src *dirSource) resumed() []FileResult { return src.done }
+func (src *dirSource) skip(int)              {}
+
+func (src *dirSource) result(i int) FileResult {
+	f := src.files[i]
+	return FileResult{Index: i, Name: f.name, Size: f.size, ModTime: f.modTime}
+}
+
+func (src *dirSource) open(i int) (Input, error) {
+	f, err := os.Open(filepath.Join(src.dir, src.files[i].name))
+	if err != nil {
+		return Input{}, fmt.Errorf("could not open input: %w", err)
+	}
+	return Input{
+		Name:       src.files[i].name,
+		OutputType: src.opts.OutputType,
+		Language:   src.opts.Language,
+		Data:       f,
+	}, nil
+}
+
+// mediaFiles returns the files under dir having one of extensions,
+// named after their path relative to dir, in lexical order.
+func mediaFiles(dir string, extensions []string) ([]dirFile, error) {
+	if len(extensions) == 0 {
+		extensions = DefaultMediaExtensions
+	}
+
+	wanted := make(map[string]bool, len(extensions))
+	for _, ext := range extensions {
+		wanted[strings.ToLower(ext)] = true
+	}
+
+	var files []dirFile
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !wanted[inputExtension(path)] {
+			return nil
+		}
+
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, dirFile{name: name, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list %q: %w", dir, err)
+	}
+	return files, nil
+}
+
+// processBatch processes the inputs of src, reporting on each.
+func (s *Scriber) processBatch(ctx context.Context, src batchSource, opts BatchOptions) (*BatchReport, error) {
+	start := time.Now()
+	n := src.len()
+
+	parallelism := max(opts.Parallelism, 1)
+
+	var budget *retryBudget
+	if opts.RetryBudget != nil {
+		budget = newRetryBudget(*opts.RetryBudget)
+		ctx = withRetryBudget(ctx, budget)
+	}
+
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, parallelism)
+
+		mu      sync.Mutex
+		report  = &BatchReport{}
+		skipped []int
+		inErrs  []*ProcessError
+		errs    []error
+		failed  bool
+	)
+
+	record := func(i int, j *job, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		res := src.result(i)
+		if j != nil {
+			res.AudioDuration = j.audioDuration
+		}
+
+		if err == nil {
+			report.Succeeded = append(report.Succeeded, res)
+			report.TotalAudioDuration += res.AudioDuration
+			return
+		}
+
+		var deferred *DeferredError
+		if errors.As(err, &deferred) {
+			res.Reason = deferred.Error()
+			report.Deferred = append(report.Deferred, res)
+			return
+		}
+
+		var pe *ProcessError
+		if errors.As(err, &pe) {
+			res.Stage = pe.Stage
+		}
+		res.Error = err.Error()
+		report.Failed = append(report.Failed, res)
+		failed = true
+
+		for _, pe := range processErrors(err) {
+			pe.Input.Index = i
+			if pe.Input.Name == "" {
+				pe.Input.Name = res.Name
+			}
+			inErrs = append(inErrs, pe)
+		}
+	}
+
+	stopped := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return ctx.Err() != nil || (opts.FailFast && failed)
+	}
+
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if stopped() {
+			for ; i < n; i++ {
+				skipped = append(skipped, i)
+			}
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			// With asynchronous post-processing, the slot is
+			// freed as soon as the input is handed off.
+			free := sync.OnceFunc(func() { <-sem })
+			defer func() {
+				free()
+				wg.Done()
+			}()
+
+			in, err := src.open(i)
+			if err != nil {
+				record(i, nil, asProcessError(StageValidation, Input{Name: src.name(i)}, err))
+				return
+			}
+
+			j, err := s.process(ctx, in, free)
+			record(i, j, err)
+		}(i)
+	}
+	wg.Wait()
+
+	report.Skipped = make([]string, 0, len(skipped))
+	for _, i := range skipped {
+		src.skip(i)
+		report.Skipped = append(report.Skipped, src.name(i))
+	}
+
+	sortResults(report.Succeeded)
+	sortResults(report.Failed)
+	sortResults(report.Deferred)
+	report.Resumed = src.resumed()
+	report.WallTime = time.Since(start)
+	if budget != nil {
+		report.RetryBudget = budget.report()
+	}
+
+	if err := ctx.Err(); err != nil && len(skipped) > 0 {
+		errs = append(errs, fmt.Errorf("batch stopped with %d inputs left: %w", len(skipped), err))
+	}
+
+	if opts.ReportPath != "" {
+		if err := writeBatchReport(opts.ReportPath, report); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(inErrs) == 0 && len(errs) == 0 {
+		return report, nil
+	}
+	sort.SliceStable(inErrs, func(a, b int) bool { return inErrs[a].Input.Index < inErrs[b].Input.Index })
+	return report, &BatchError{Errors: inErrs, Err: errors.Join(errs...)}
+}
+
+func sortResults(results []FileResult) {
+	sort.Slice(results, func(a, b int) bool { return results[a].Index < results[b].Index })
+}
+
+// readBatchReport reads a report written by writeBatchReport.
+func readBatchReport(path string) (*BatchReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read batch report: %w", err)
+	}
+
+	var report BatchReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("could not decode batch report %q: %w", path, err)
+	}
+	return &report, nil
+}
+
+// writeBatchReport writes report to path as JSON, atomically
+// so that an interrupted write doesn't leave a truncated report.
+func writeBatchReport(path string, report *BatchReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode batch report: %w", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".scriber-report-*")
+	if err != nil {
+		return fmt.Errorf("could not create batch report: %w", err)
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return fmt.Errorf("could not write batch report: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("could not write batch report: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("could not write batch report: %w", err)
+	}
+	return nil
+}
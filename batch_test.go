@@ -0,0 +1,534 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBatchScriber returns a Scriber whose backend fails inputs
+// containing "fail", and a function returning the collected names.
+func newBatchScriber(t *testing.T) (*Scriber, func() []string) {
+	t.Helper()
+
+	mockClient := &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			data, err := io.ReadAll(in.Data)
+			require.NoError(t, err)
+			if strings.Contains(string(data), "fail") {
+				return nil, assert.AnError
+			}
+			return []byte("text"), nil
+		},
+	}
+
+	s := New(noopLogger(), mockClient, WithConverter(copyConverter))
+
+	var names []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for out := range s.Collect() {
+			out.Body.Close()
+			names = append(names, out.Name)
+		}
+	}()
+
+	return s, func() []string {
+		require.NoError(t, s.Shutdown(context.TODO()))
+		<-done
+		return names
+	}
+}
+
+func TestProcessBatch(t *testing.T) {
+	t.Parallel()
+
+	newInputs := func(closes *closeCounter) []Input {
+		return []Input{
+			{Name: "a.mp4", OutputType: OutputTypeTranscript, Language: "en", Data: io.NopCloser(strings.NewReader("ok"))},
+			{Name: "b", OutputType: OutputTypeTranscript, Language: "en", Data: io.NopCloser(strings.NewReader("ok"))},
+			{Name: "c.mp4", OutputType: OutputTypeTranscript, Language: "en", Data: io.NopCloser(strings.NewReader("fail"))},
+			{Name: "d.mp4", OutputType: OutputTypeTranscript, Language: "en", Data: closes},
+		}
+	}
+
+	testCases := []struct {
+		name              string
+		givenOpts         BatchOptions
+		expectedSucceeded []string
+		expectedFailed    map[string]Stage
+		expectedSkipped   []string
+	}{
+		{
+			name:              "soft fail",
+			givenOpts:         BatchOptions{Parallelism: 2},
+			expectedSucceeded: []string{"a.mp4", "d.mp4"},
+			expectedFailed:    map[string]Stage{"b": StageValidation, "c.mp4": StageTranscription},
+			expectedSkipped:   []string{},
+		},
+		{
+			name:              "fail fast",
+			givenOpts:         BatchOptions{FailFast: true},
+			expectedSucceeded: []string{"a.mp4"},
+			expectedFailed:    map[string]Stage{"b": StageValidation},
+			expectedSkipped:   []string{"c.mp4", "d.mp4"},
+		},
+		{
+			name:            "fail fast validation",
+			givenOpts:       BatchOptions{Parallelism: 2, FailFastValidation: true},
+			expectedFailed:  map[string]Stage{"b": StageValidation},
+			expectedSkipped: []string{"a.mp4", "c.mp4", "d.mp4"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, collected := newBatchScriber(t)
+
+			closes := &closeCounter{Reader: strings.NewReader("ok")}
+
+			tc.givenOpts.ReportPath = filepath.Join(t.TempDir(), "report.json")
+
+			report, err := s.ProcessBatch(context.TODO(), newInputs(closes), tc.givenOpts)
+			require.Error(t, err)
+			require.NotNil(t, report)
+
+			var succeeded []string
+			for _, r := range report.Succeeded {
+				succeeded = append(succeeded, r.Name)
+				assert.Empty(t, r.Error)
+			}
+			assert.Equal(t, tc.expectedSucceeded, succeeded)
+
+			failed := map[string]Stage{}
+			for _, r := range report.Failed {
+				failed[r.Name] = r.Stage
+				assert.NotEmpty(t, r.Error)
+				assert.Contains(t, err.Error(), r.Error)
+			}
+			assert.Equal(t, tc.expectedFailed, failed)
+			assert.Equal(t, tc.expectedSkipped, report.Skipped)
+			assert.Positive(t, report.WallTime)
+
+			assert.EqualValues(t, 1, closes.closes.Load(), "skipped inputs should be closed too")
+			assert.Len(t, collected(), len(tc.expectedSucceeded))
+
+			data, err := os.ReadFile(tc.givenOpts.ReportPath)
+			require.NoError(t, err)
+
+			var written BatchReport
+			require.NoError(t, json.Unmarshal(data, &written))
+			assert.Equal(t, *report, written)
+		})
+	}
+}
+
+func TestScriber_ValidateBatch(t *testing.T) {
+	t.Parallel()
+
+	s := New(noopLogger(), &mockWhisperClient{},
+		WithMaxInputSize(100),
+		WithMaxNameLength(20),
+		WithConverter(func(context.Context, io.Reader, io.Writer) error { return nil }),
+	)
+
+	// The data must not be read.
+	data := io.NopCloser(iotest.ErrReader(assert.AnError))
+
+	inputs := []Input{
+		{Name: "a.mp4", OutputType: OutputTypeTranscript, Language: "en", Data: data},
+		{Name: "", OutputType: OutputTypeTranscript, Language: "en", Data: data},
+		{Name: "b", OutputType: OutputTypeTranscript, Language: "en", Data: data},
+		{Name: "c.mp4", OutputType: "pdf", Language: "en", Data: data},
+		{Name: "d.mp4", OutputType: OutputTypeTranscript, Data: data},
+		{Name: "e.mp4", OutputType: OutputTypeTranscript, Language: "en", Languages: []string{"pt"}, Data: data},
+		{Name: "f.mp4", OutputType: OutputTypeTranscript, Language: "en", Size: 101, Data: data},
+		{Name: "g.mp4", OutputType: OutputTypeTranscript, Language: "en", DataRef: "g.mp4"},
+		{Name: strings.Repeat("h", 30) + ".mp4", OutputType: OutputTypeTranscript, Language: "en", Data: data},
+		NewInput("i.mp4", strings.NewReader(""), WithInputSize(0), WithInputLanguage("en"), WithInputOutputType(OutputTypeTranscript)),
+		{Name: "j.mp4", OutputType: OutputTypeSubtitles, Languages: []string{"en", "pt"}, Size: 100, Data: data},
+	}
+
+	expected := []error{
+		nil,
+		errNameRequired,
+		errExtRequired,
+		errorOutputType,
+		errorLanguage,
+		errLanguagesExclusive,
+		errInputTooLarge,
+		errBlobStoreRequired,
+		errNameTooLong,
+		errEmptyInput,
+		nil,
+	}
+
+	errs := s.ValidateBatch(inputs)
+	require.Len(t, errs, len(inputs))
+
+	for i, err := range errs {
+		if expected[i] == nil {
+			assert.NoError(t, err, "input %d", i)
+			continue
+		}
+		assert.ErrorIs(t, err, expected[i], "input %d", i)
+
+		var pe *ProcessError
+		require.ErrorAs(t, err, &pe)
+		assert.Equal(t, StageValidation, pe.Stage)
+		assert.Equal(t, i, pe.Input.Index)
+	}
+}
+
+func TestProcessBatch_AudioDuration(t *testing.T) {
+	t.Parallel()
+
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.Copy(io.Discard, in.Data)
+			return []byte("text"), err
+		},
+	}, WithConverter(func(_ context.Context, r io.Reader, w io.Writer) error {
+		_, err := w.Write(syntheticWAV(3))
+		return err
+	}))
+
+	inputs := []Input{
+		{Name: "a.mp4", OutputType: OutputTypeTranscript, Language: "en", Data: io.NopCloser(bytes.NewBufferString("media"))},
+		{Name: "b.mp4", OutputType: OutputTypeTranscript, Languages: []string{"en", "pt"}, Data: io.NopCloser(bytes.NewBufferString("media"))},
+	}
+
+	report, err := s.ProcessBatch(context.TODO(), inputs, BatchOptions{})
+	require.NoError(t, err)
+
+	require.Len(t, report.Succeeded, 2)
+	for _, r := range report.Succeeded {
+		assert.Equal(t, testWAVFormat.Duration(3*testWAVFormat.ByteRate()), r.AudioDuration, r.Name)
+	}
+	assert.Equal(t, 2*testWAVFormat.Duration(3*testWAVFormat.ByteRate()), report.TotalAudioDuration)
+}
+
+func TestProcessBatch_BatchError(t *testing.T) {
+	t.Parallel()
+
+	newScriber := func(transcribe func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error)) *Scriber {
+		s := New(noopLogger(), &mockWhisperClient{transcribeAudioFunc: transcribe},
+			WithConverter(func(_ context.Context, r io.Reader, w io.Writer) error {
+				if _, err := io.Copy(io.Discard, r); err != nil {
+					return err
+				}
+				_, err := w.Write(syntheticWAV(1))
+				return err
+			}),
+		)
+		go func() {
+			for out := range s.Collect() {
+				out.Body.Close()
+			}
+		}()
+		return s
+	}
+
+	t.Run("per input", func(t *testing.T) {
+		t.Parallel()
+
+		s := newScriber(func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.ReadAll(in.Data)
+			require.NoError(t, err)
+			if in.Name == "c.mp4" || in.Language == "pt" {
+				return nil, assert.AnError
+			}
+			return []byte("text"), nil
+		})
+
+		inputs := []Input{
+			{Name: "a.mp4", OutputType: OutputTypeTranscript, Language: "en", Data: io.NopCloser(strings.NewReader("media"))},
+			{Name: "b", OutputType: OutputTypeTranscript, Language: "en", Data: io.NopCloser(strings.NewReader("media"))},
+			{Name: "c.mp4", OutputType: OutputTypeTranscript, Language: "en", Data: io.NopCloser(strings.NewReader("media"))},
+			{Name: "d.mp4", OutputType: OutputTypeTranscript, Language: "en", Data: io.NopCloser(strings.NewReader("media"))},
+			{Name: "e.mp4", OutputType: OutputTypeTranscript, Languages: []string{"en", "pt"}, Data: io.NopCloser(strings.NewReader("media"))},
+		}
+
+		_, err := s.ProcessBatch(context.TODO(), inputs, BatchOptions{Parallelism: 3})
+
+		be, ok := AsBatchError(fmt.Errorf("wrapped: %w", err))
+		require.True(t, ok)
+		assert.NoError(t, be.Err)
+
+		type failure struct {
+			Index    int
+			Name     string
+			Language string
+			Stage    Stage
+		}
+		var failures []failure
+		for _, pe := range be.Errors {
+			failures = append(failures, failure{pe.Input.Index, pe.Input.Name, pe.Input.Language, pe.Stage})
+		}
+		assert.Equal(t, []failure{
+			{1, "b", "en", StageValidation},
+			{2, "c.mp4", "en", StageTranscription},
+			{4, "e.mp4", "pt", StageTranscription},
+		}, failures)
+
+		assert.ErrorIs(t, be.Errors[0], errExtRequired)
+		assert.ErrorIs(t, be.Errors[1], assert.AnError)
+		assert.ErrorIs(t, err, errExtRequired)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		t.Parallel()
+
+		s := newScriber(func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.ReadAll(in.Data)
+			require.NoError(t, err)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+
+		ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+		defer cancel()
+
+		inputs := []Input{
+			{Name: "a.mp4", OutputType: OutputTypeTranscript, Language: "en", Data: io.NopCloser(strings.NewReader("media"))},
+			{Name: "b.mp4", OutputType: OutputTypeTranscript, Language: "en", Data: io.NopCloser(strings.NewReader("media"))},
+		}
+
+		_, err := s.ProcessBatch(ctx, inputs, BatchOptions{Parallelism: 2})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		be, ok := AsBatchError(err)
+		require.True(t, ok)
+		require.Len(t, be.Errors, 2)
+		for i, pe := range be.Errors {
+			assert.Equal(t, i, pe.Input.Index)
+			assert.ErrorIs(t, pe, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		s := newScriber(func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.ReadAll(in.Data)
+			return []byte("text"), err
+		})
+
+		_, err := s.ProcessBatch(context.TODO(), []Input{
+			{Name: "a.mp4", OutputType: OutputTypeTranscript, Language: "en", Data: io.NopCloser(strings.NewReader("media"))},
+		}, BatchOptions{})
+		require.NoError(t, err)
+
+		_, ok := AsBatchError(err)
+		assert.False(t, ok)
+	})
+}
+
+func TestProcessDir(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.mp4":         "ok",
+		"sub/b.MP3":     "ok",
+		"sub/c.wav":     "fail",
+		"notes.txt":     "ok",
+		"sub/.DS_Store": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	s, collected := newBatchScriber(t)
+
+	report, err := s.ProcessDir(context.TODO(), dir, DirOptions{
+		OutputType: OutputTypeTranscript,
+		Language:   "en",
+	})
+	require.Error(t, err)
+
+	var succeeded []string
+	for _, r := range report.Succeeded {
+		succeeded = append(succeeded, r.Name)
+	}
+	assert.Equal(t, []string{"a.mp4", filepath.Join("sub", "b.MP3")}, succeeded)
+
+	require.Len(t, report.Failed, 1)
+	assert.Equal(t, filepath.Join("sub", "c.wav"), report.Failed[0].Name)
+	assert.Equal(t, StageTranscription, report.Failed[0].Stage)
+
+	assert.ElementsMatch(t, []string{"a.txt", filepath.Join("sub", "b.txt")}, collected())
+}
+
+func TestProcessDir_Resume(t *testing.T) {
+	t.Parallel()
+
+	// writeFiles writes each file under dir.
+	writeFiles := func(t *testing.T, dir string, files map[string]string) {
+		t.Helper()
+		for name, content := range files {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+		}
+	}
+
+	t.Run("from a previous report", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		reportPath := filepath.Join(t.TempDir(), "report.json")
+		writeFiles(t, dir, map[string]string{"a.mp4": "ok", "b.mp4": "fail", "c.mp4": "ok"})
+
+		// The previous run was interrupted once a.mp4 succeeded and b.mp4 failed.
+		s, _ := newBatchScriber(t)
+		prev, err := s.ProcessDir(context.TODO(), dir, DirOptions{
+			BatchOptions: BatchOptions{FailFast: true, ReportPath: reportPath},
+			OutputType:   OutputTypeTranscript,
+			Language:     "en",
+		})
+		require.Error(t, err)
+		require.Len(t, prev.Succeeded, 1)
+		require.Len(t, prev.Skipped, 1)
+
+		// c.mp4 was left out and b.mp4 has been fixed since.
+		writeFiles(t, dir, map[string]string{"b.mp4": "ok now"})
+
+		s, collected := newBatchScriber(t)
+		report, err := s.ProcessDir(context.TODO(), dir, DirOptions{
+			BatchOptions: BatchOptions{ReportPath: reportPath},
+			OutputType:   OutputTypeTranscript,
+			Language:     "en",
+			ResumeFrom:   reportPath,
+		})
+		require.NoError(t, err)
+
+		require.Len(t, report.Resumed, 1)
+		assert.Equal(t, "a.mp4", report.Resumed[0].Name)
+		assert.Equal(t, "completed in a previous run", report.Resumed[0].Reason)
+		assert.Len(t, report.Succeeded, 2)
+		assert.ElementsMatch(t, []string{"b.txt", "c.txt"}, collected())
+
+		// Resuming again skips everything, the resumed inputs included.
+		s, collected = newBatchScriber(t)
+		report, err = s.ProcessDir(context.TODO(), dir, DirOptions{OutputType: OutputTypeTranscript, Language: "en", ResumeFrom: reportPath})
+		require.NoError(t, err)
+		assert.Len(t, report.Resumed, 3)
+		assert.Empty(t, report.Succeeded)
+		assert.Empty(t, collected())
+	})
+
+	t.Run("changed since the previous report", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		reportPath := filepath.Join(t.TempDir(), "report.json")
+		writeFiles(t, dir, map[string]string{"a.mp4": "ok"})
+
+		s, _ := newBatchScriber(t)
+		_, err := s.ProcessDir(context.TODO(), dir, DirOptions{
+			BatchOptions: BatchOptions{ReportPath: reportPath},
+			OutputType:   OutputTypeTranscript,
+			Language:     "en",
+		})
+		require.NoError(t, err)
+
+		writeFiles(t, dir, map[string]string{"a.mp4": "ok, longer"})
+
+		s, collected := newBatchScriber(t)
+		report, err := s.ProcessDir(context.TODO(), dir, DirOptions{OutputType: OutputTypeTranscript, Language: "en", ResumeFrom: reportPath})
+		require.NoError(t, err)
+		assert.Empty(t, report.Resumed)
+		assert.Len(t, report.Succeeded, 1)
+		assert.Len(t, collected(), 1)
+	})
+
+	t.Run("missing report", func(t *testing.T) {
+		t.Parallel()
+
+		s, _ := newBatchScriber(t)
+		_, err := s.ProcessDir(context.TODO(), t.TempDir(), DirOptions{ResumeFrom: filepath.Join(t.TempDir(), "none.json")})
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestProcessDir_SkipIfOutputExists(t *testing.T) {
+	t.Parallel()
+
+	past := time.Now().Add(-time.Hour)
+
+	testCases := []struct {
+		name            string
+		givenOutputTime time.Time
+		givenFresh      bool
+		expectedResumed []string
+	}{
+		{
+			name:            "output exists",
+			givenOutputTime: past.Add(-time.Hour),
+			expectedResumed: []string{"a.mp4"},
+		},
+		{
+			name:            "fresh output",
+			givenOutputTime: past.Add(time.Minute),
+			givenFresh:      true,
+			expectedResumed: []string{"a.mp4"},
+		},
+		{
+			name:            "stale output",
+			givenOutputTime: past.Add(-time.Minute),
+			givenFresh:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir, outDir := t.TempDir(), t.TempDir()
+			for _, name := range []string{"a.mp4", "b.mp4"} {
+				path := filepath.Join(dir, name)
+				require.NoError(t, os.WriteFile(path, []byte("ok"), 0o644))
+				require.NoError(t, os.Chtimes(path, past, past))
+			}
+
+			outPath := filepath.Join(outDir, "a.srt")
+			require.NoError(t, os.WriteFile(outPath, []byte("1"), 0o644))
+			require.NoError(t, os.Chtimes(outPath, tc.givenOutputTime, tc.givenOutputTime))
+
+			s, collected := newBatchScriber(t)
+			report, err := s.ProcessDir(context.TODO(), dir, DirOptions{
+				OutputType:         OutputTypeSubtitles,
+				Language:           "en",
+				SkipIfOutputExists: true,
+				FreshOutputOnly:    tc.givenFresh,
+				OutputDir:          outDir,
+			})
+			require.NoError(t, err)
+
+			var resumed []string
+			for _, r := range report.Resumed {
+				resumed = append(resumed, r.Name)
+				assert.Equal(t, "output exists", r.Reason)
+			}
+			assert.Equal(t, tc.expectedResumed, resumed)
+			assert.Len(t, collected(), 2-len(tc.expectedResumed))
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package scriber
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// BlobStore opens inputs by reference, e.g. the key of an S3 object, for
+// jobs given an Input.DataRef rather than Data. Open returns the blob along
+// with its size in bytes, or zero if unknown; blobs implementing io.Seeker
+// can be probed and rewound like seekable Data.
+type BlobStore interface {
+	Open(ctx context.Context, ref string) (io.ReadCloser, int64, error)
+}
+
+// WithBlobStore sets the store inputs with a DataRef are opened from.
+// Process opens the blob once the input is validated, uses its size as the
+// size hint unless Input.Size is set, and closes it before returning.
+func WithBlobStore(store BlobStore) Option {
+	return func(s *Scriber) {
+		s.blobStore = store
+	}
+}
+
+// DirBlobStore is a BlobStore reading blobs from the files under Dir.
+// References are slash-separated paths relative to Dir, which can't
+// escape it.
+type DirBlobStore struct {
+	Dir string
+}
+
+var _ BlobStore = DirBlobStore{}
+
+func (d DirBlobStore) Open(_ context.Context, ref string) (io.ReadCloser, int64, error) {
+	if !fs.ValidPath(ref) {
+		return nil, 0, &fs.PathError{Op: "open", Path: ref, Err: fs.ErrInvalid}
+	}
+
+	f, err := os.Open(filepath.Join(d.Dir, filepath.FromSlash(ref)))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	if !info.Mode().IsRegular() {
+		f.Close()
+		return nil, 0, &fs.PathError{Op: "open", Path: ref, Err: fs.ErrInvalid}
+	}
+	return f, info.Size(), nil
+}
+
+// openBlob opens the blob in.DataRef points to, returning in reading from
+// it. The caller must close the returned Data.
+func (s *Scriber) openBlob(ctx context.Context, in Input) (Input, error) {
+	if s.blobStore == nil {
+		return in, errBlobStoreRequired
+	}
+
+	data, size, err := s.callBlobStore(ctx, in.DataRef)
+	if err != nil {
+		return in, fmt.Errorf("could not open %q: %w", in.DataRef, err)
+	}
+
+	in.Data, in.KeepOpen = data, false
+	if in.Size <= 0 {
+		in.Size = size
+	}
+	return in, nil
+}
@@ -0,0 +1,212 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// trackingBlobStore wraps a BlobStore, recording the sizes of the
+// blobs opened and whether they were closed.
+type trackingBlobStore struct {
+	BlobStore
+	opened atomic.Int32
+	closed atomic.Int32
+}
+
+func (s *trackingBlobStore) Open(ctx context.Context, ref string) (io.ReadCloser, int64, error) {
+	rc, size, err := s.BlobStore.Open(ctx, ref)
+	if err != nil {
+		return nil, 0, err
+	}
+	s.opened.Add(1)
+	return &closeTracker{ReadCloser: rc, closed: &s.closed}, size, nil
+}
+
+type closeTracker struct {
+	io.ReadCloser
+	closed *atomic.Int32
+}
+
+func (c *closeTracker) Close() error {
+	c.closed.Add(1)
+	return c.ReadCloser.Close()
+}
+
+// failingBlobStore is a BlobStore that fails to open every blob.
+type failingBlobStore struct{}
+
+func (failingBlobStore) Open(context.Context, string) (io.ReadCloser, int64, error) {
+	return nil, 0, assert.AnError
+}
+
+func TestDirBlobStore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "uploads"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "uploads", "talk.mp4"), []byte("video"), 0o600))
+
+	store := DirBlobStore{Dir: dir}
+
+	testCases := []struct {
+		name         string
+		givenRef     string
+		expectedData string
+		expectedErr  error
+	}{
+		{name: "file", givenRef: "uploads/talk.mp4", expectedData: "video"},
+		{name: "missing", givenRef: "uploads/missing.mp4", expectedErr: fs.ErrNotExist},
+		{name: "directory", givenRef: "uploads", expectedErr: fs.ErrInvalid},
+		{name: "escaping the dir", givenRef: "../talk.mp4", expectedErr: fs.ErrInvalid},
+		{name: "absolute", givenRef: "/etc/passwd", expectedErr: fs.ErrInvalid},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rc, size, err := store.Open(context.TODO(), tc.givenRef)
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			defer rc.Close()
+
+			data, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedData, string(data))
+			assert.Equal(t, int64(len(tc.expectedData)), size)
+
+			_, ok := rc.(io.Seeker)
+			assert.True(t, ok, "files are seekable")
+		})
+	}
+}
+
+func TestProcess_DataRef(t *testing.T) {
+	t.Parallel()
+
+	audio := syntheticWAV(1)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "talk.wav"), audio, 0o600))
+
+	testCases := []struct {
+		name        string
+		givenStore  BlobStore
+		givenRef    string
+		givenData   io.ReadCloser
+		expectedErr error
+	}{
+		{
+			name:       "dir store",
+			givenStore: DirBlobStore{Dir: dir},
+			givenRef:   "talk.wav",
+		},
+		{
+			name:        "missing blob",
+			givenStore:  DirBlobStore{Dir: dir},
+			givenRef:    "missing.wav",
+			expectedErr: fs.ErrNotExist,
+		},
+		{
+			name:        "failing store",
+			givenStore:  failingBlobStore{},
+			givenRef:    "talk.wav",
+			expectedErr: assert.AnError,
+		},
+		{
+			name:        "no store",
+			givenRef:    "talk.wav",
+			expectedErr: errBlobStoreRequired,
+		},
+		{
+			name:        "data and data ref",
+			givenStore:  DirBlobStore{Dir: dir},
+			givenRef:    "talk.wav",
+			givenData:   io.NopCloser(bytes.NewReader(audio)),
+			expectedErr: errDataExclusive,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var opts []Option
+			var store *trackingBlobStore
+			if tc.givenStore != nil {
+				store = &trackingBlobStore{BlobStore: tc.givenStore}
+				opts = append(opts, WithBlobStore(store))
+			}
+
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.ReadAll(in.Data)
+					return []byte("hello"), err
+				},
+			}, opts...)
+			s.convertToWavFunc = copyConverter
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "talk.wav",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				DataRef:    tc.givenRef,
+				Data:       tc.givenData,
+			})
+			if store != nil {
+				assert.Equal(t, store.opened.Load(), store.closed.Load(), "opened blobs are closed")
+			}
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+
+				var pe *ProcessError
+				require.ErrorAs(t, err, &pe)
+				assert.Equal(t, StageValidation, pe.Stage)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int32(1), store.opened.Load())
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+
+			assert.Equal(t, "hello", string(out.Text))
+			assert.Equal(t, int64(len(audio)), out.InputBytes)
+		})
+	}
+}
+
+func TestProcess_DataRefSizeHint(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "talk.wav"), syntheticWAV(2), 0o600))
+
+	// The size of the blob is the size hint, enforcing the maximum
+	// input size before anything is converted.
+	s := New(noopLogger(), &mockWhisperClient{},
+		WithBlobStore(DirBlobStore{Dir: dir}),
+		WithMaxInputSize(100),
+	)
+
+	err := s.Process(context.TODO(), Input{
+		Name:       "talk.wav",
+		OutputType: OutputTypeTranscript,
+		Language:   "en",
+		DataRef:    "talk.wav",
+	})
+	require.ErrorIs(t, err, errInputTooLarge)
+}
@@ -0,0 +1,98 @@
+package scriber
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RetryBudget caps the transcription retries of a batch, shared by all of
+// its inputs, so that a degraded backend doesn't face a retry storm. Once
+// the budget is exhausted, failed transcriptions fail without retrying,
+// with an error wrapping a RetryBudgetError.
+type RetryBudget struct {
+	// Retries is the number of retries the batch starts with,
+	// and the most it can hold when refilled.
+	Retries int
+
+	// RefillInterval gives a retry back to the budget every interval,
+	// as a token bucket. Zero never refills it.
+	RefillInterval time.Duration
+}
+
+// RetryBudgetReport is the state of the retry budget of a batch.
+type RetryBudgetReport struct {
+	// Used is the number of retries taken from the budget.
+	Used int `json:"used"`
+
+	// Denied is the number of retries refused because the
+	// budget was exhausted.
+	Denied int `json:"denied"`
+
+	// Remaining is the number of retries left once the batch is done.
+	Remaining int `json:"remaining"`
+}
+
+// retryBudget is the token bucket of a RetryBudget.
+type retryBudget struct {
+	cfg RetryBudget
+
+	mu       sync.Mutex
+	tokens   int
+	refilled time.Time
+	used     int
+	denied   int
+}
+
+func newRetryBudget(cfg RetryBudget) *retryBudget {
+	return &retryBudget{cfg: cfg, tokens: cfg.Retries, refilled: time.Now()}
+}
+
+// take takes a retry from the budget, reporting whether one was left.
+func (b *retryBudget) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	if b.tokens <= 0 {
+		b.denied++
+		return false
+	}
+	b.tokens--
+	b.used++
+	return true
+}
+
+// refill gives back the retries earned since the last refill.
+func (b *retryBudget) refill() {
+	if b.cfg.RefillInterval <= 0 {
+		return
+	}
+
+	n := int(time.Since(b.refilled) / b.cfg.RefillInterval)
+	if n == 0 {
+		return
+	}
+	b.refilled = b.refilled.Add(time.Duration(n) * b.cfg.RefillInterval)
+	b.tokens = min(b.tokens+n, b.cfg.Retries)
+}
+
+func (b *retryBudget) report() *RetryBudgetReport {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	return &RetryBudgetReport{Used: b.used, Denied: b.denied, Remaining: b.tokens}
+}
+
+type retryBudgetCtxKey struct{}
+
+func withRetryBudget(ctx context.Context, b *retryBudget) context.Context {
+	return context.WithValue(ctx, retryBudgetCtxKey{}, b)
+}
+
+// retryBudgetFromContext returns the retry budget of the batch ctx belongs to, if any.
+func retryBudgetFromContext(ctx context.Context) *retryBudget {
+	b, _ := ctx.Value(retryBudgetCtxKey{}).(*retryBudget)
+	return b
+}
@@ -0,0 +1,119 @@
+package scriber
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessBatch_RetryBudget(t *testing.T) {
+	t.Parallel()
+
+	// The backend fails the first attempt of every input.
+	var (
+		mu       sync.Mutex
+		attempts = map[string]int{}
+	)
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.ReadAll(in.Data)
+			require.NoError(t, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			attempts[in.Name]++
+			if attempts[in.Name] == 1 {
+				return nil, assert.AnError
+			}
+			return []byte("text"), nil
+		},
+	},
+		WithRetry(RetryConfig{Attempts: 3}),
+		WithConverter(copyConverter),
+	)
+	go func() {
+		for out := range s.Collect() {
+			out.Body.Close()
+		}
+	}()
+
+	var inputs []Input
+	for i := range 5 {
+		inputs = append(inputs, Input{
+			Name:       fmt.Sprintf("%d.mp4", i),
+			OutputType: OutputTypeTranscript,
+			Language:   "en",
+			Data:       io.NopCloser(strings.NewReader("media")),
+		})
+	}
+
+	report, err := s.ProcessBatch(context.TODO(), inputs, BatchOptions{
+		RetryBudget: &RetryBudget{Retries: 2},
+	})
+
+	be, ok := AsBatchError(err)
+	require.True(t, ok)
+
+	var failed []string
+	for _, pe := range be.Errors {
+		assert.Equal(t, StageTranscription, pe.Stage)
+		assert.ErrorIs(t, pe, errRetryBudget)
+		assert.ErrorIs(t, pe, assert.AnError)
+		failed = append(failed, pe.Input.Name)
+	}
+	assert.Equal(t, []string{"2.mp4", "3.mp4", "4.mp4"}, failed)
+
+	require.Len(t, report.Succeeded, 2)
+	assert.Equal(t, &RetryBudgetReport{Used: 2, Denied: 3, Remaining: 0}, report.RetryBudget)
+
+	// Budget exhaustion stops retries, not first attempts.
+	assert.Equal(t, map[string]int{"0.mp4": 2, "1.mp4": 2, "2.mp4": 1, "3.mp4": 1, "4.mp4": 1}, attempts)
+
+	// Without a budget, every input is retried.
+	for i := range inputs {
+		inputs[i].Data = io.NopCloser(strings.NewReader("media"))
+		inputs[i].Name = "again-" + inputs[i].Name
+	}
+	report, err = s.ProcessBatch(context.TODO(), inputs, BatchOptions{})
+	require.NoError(t, err)
+	assert.Len(t, report.Succeeded, 5)
+	assert.Nil(t, report.RetryBudget)
+}
+
+func TestRetryBudget(t *testing.T) {
+	t.Parallel()
+
+	t.Run("fixed", func(t *testing.T) {
+		t.Parallel()
+
+		b := newRetryBudget(RetryBudget{Retries: 1})
+		assert.True(t, b.take())
+		assert.False(t, b.take())
+		assert.Equal(t, &RetryBudgetReport{Used: 1, Denied: 1}, b.report())
+	})
+
+	t.Run("refilled", func(t *testing.T) {
+		t.Parallel()
+
+		b := newRetryBudget(RetryBudget{Retries: 2, RefillInterval: time.Minute})
+		assert.True(t, b.take())
+		assert.True(t, b.take())
+		assert.False(t, b.take())
+
+		// Three intervals went by, but the budget holds two retries at most.
+		b.refilled = b.refilled.Add(-3*time.Minute - time.Second)
+		assert.Equal(t, &RetryBudgetReport{Used: 2, Denied: 1, Remaining: 2}, b.report())
+
+		assert.True(t, b.take())
+		assert.True(t, b.take())
+		assert.False(t, b.take())
+	})
+}
@@ -0,0 +1,66 @@
+package scriber
+
+import (
+	"io"
+	"sync"
+)
+
+const defaultCopyBufferSize = 32 * 1024
+
+var defaultBufferPool = newBufferPool(defaultCopyBufferSize)
+
+// bufferPool is a pool of fixed-size byte slices used for copying
+// data between the input, the converter, and the transcription upload.
+type bufferPool struct {
+	size int
+	pool sync.Pool
+}
+
+func newBufferPool(size int) *bufferPool {
+	if size <= 0 {
+		size = defaultCopyBufferSize
+	}
+
+	p := &bufferPool{size: size}
+	p.pool.New = func() any {
+		b := make([]byte, size)
+		return &b
+	}
+	return p
+}
+
+func (p *bufferPool) get() *[]byte {
+	return p.pool.Get().(*[]byte)
+}
+
+func (p *bufferPool) put(b *[]byte) {
+	if len(*b) != p.size {
+		return
+	}
+	p.pool.Put(b)
+}
+
+// pooledReader wraps a reader so that io.Copy calls reading
+// from it use a buffer from the pool instead of allocating one.
+type pooledReader struct {
+	r    io.Reader
+	pool *bufferPool
+}
+
+func newPooledReader(r io.Reader, pool *bufferPool) *pooledReader {
+	return &pooledReader{r: r, pool: pool}
+}
+
+func (p *pooledReader) Read(b []byte) (int, error) {
+	return p.r.Read(b)
+}
+
+// WriteTo implements io.WriterTo. Both ends are wrapped to hide their
+// ReaderFrom and WriterTo implementations, which would otherwise make
+// io.CopyBuffer bypass the pooled buffer.
+func (p *pooledReader) WriteTo(w io.Writer) (int64, error) {
+	buf := p.pool.get()
+	defer p.pool.put(buf)
+
+	return io.CopyBuffer(struct{ io.Writer }{w}, struct{ io.Reader }{p.r}, *buf)
+}
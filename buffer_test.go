@@ -0,0 +1,122 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBufferPool(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		givenSize int
+		expected  int
+	}{
+		{name: "configured size", givenSize: 1024, expected: 1024},
+		{name: "zero falls back to default", givenSize: 0, expected: defaultCopyBufferSize},
+		{name: "negative falls back to default", givenSize: -1, expected: defaultCopyBufferSize},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			pool := newBufferPool(tc.givenSize)
+			buf := pool.get()
+			defer pool.put(buf)
+
+			assert.Len(t, *buf, tc.expected)
+		})
+	}
+}
+
+func TestPooledReader_WriteTo(t *testing.T) {
+	t.Parallel()
+
+	payload := bytes.Repeat([]byte("0123456789"), 10_000)
+
+	var dst bytes.Buffer
+	n, err := io.Copy(&dst, newPooledReader(bytes.NewReader(payload), newBufferPool(100)))
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(len(payload)), n)
+	assert.Equal(t, payload, dst.Bytes())
+}
+
+func TestProcess_ConcurrentJobsSharePool(t *testing.T) {
+	t.Parallel()
+
+	const jobs = 20
+
+	mockClient := &mockWhisperClient{
+		transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			// Echo the audio back so each output can be checked against its input.
+			return io.ReadAll(in.Data)
+		},
+	}
+
+	scriber := New(noopLogger(), mockClient, WithCopyBufferSize(64))
+	scriber.resultsCh = make(chan Output, jobs)
+	scriber.convertToWavFunc = copyConverter
+
+	payloads := make(map[string][]byte, jobs)
+	for i := 0; i < jobs; i++ {
+		payloads[fmt.Sprintf("job-%d.srt", i)] = bytes.Repeat([]byte{byte('a' + i)}, 10_000+i)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			err := scriber.Process(context.TODO(), Input{
+				Name:       fmt.Sprintf("job-%d.mp4", i),
+				OutputType: OutputTypeSubtitles,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(payloads[fmt.Sprintf("job-%d.srt", i)])),
+			})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < jobs; i++ {
+		output := <-scriber.Collect()
+		assert.Equal(t, payloads[output.Name], output.Text, output.Name)
+	}
+}
+
+func BenchmarkCopy(b *testing.B) {
+	payload := bytes.Repeat([]byte("a"), 1<<20)
+
+	b.Run("plain", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(payload)))
+
+		for i := 0; i < b.N; i++ {
+			// Hide WriterTo and ReaderFrom to force io.Copy to allocate its own buffer,
+			// as it does when copying from a pipe into ffmpeg's stdin.
+			_, _ = io.Copy(struct{ io.Writer }{io.Discard}, struct{ io.Reader }{bytes.NewReader(payload)})
+		}
+	})
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(payload)))
+
+		pool := newBufferPool(defaultCopyBufferSize)
+		for i := 0; i < b.N; i++ {
+			_, _ = io.Copy(struct{ io.Writer }{io.Discard}, newPooledReader(bytes.NewReader(payload), pool))
+		}
+	})
+}
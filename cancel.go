@@ -0,0 +1,59 @@
+package scriber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// CancellationPolicy sets what becomes of a job canceled once it is past
+// conversion. See WithCancellationPolicy.
+type CancellationPolicy int
+
+const (
+	// CancelAbandon drops the job at the next checkpoint, failing it with
+	// a CanceledError. It is the default.
+	CancelAbandon CancellationPolicy = iota
+
+	// CancelSalvage drops the job at the next checkpoint too, but attaches
+	// its transcription, if any, to the ProcessError as Salvaged, as
+	// WithSalvage does for failures.
+	CancelSalvage
+)
+
+// WithCancellationPolicy sets what becomes of jobs canceled between
+// stages. Jobs check their context after conversion, after transcription,
+// before each post-processing step, and before publishing, so that a
+// canceled job doesn't carry on to publish a result nobody waits for.
+// The ProcessError is tagged with the stage the job was about to enter,
+// and wraps both a CanceledError and the context's error.
+func WithCancellationPolicy(p CancellationPolicy) Option {
+	return func(s *Scriber) {
+		s.cancellation = p
+	}
+}
+
+// postProcessor is a post-processing step, run on the transcription
+// with a checkpoint before it.
+type postProcessor struct {
+	name string
+	fn   func(text []byte) ([]byte, error)
+}
+
+// checkpoint returns an error tagged with stage if ctx is done,
+// telling the job to stop before step.
+func (s *Scriber) checkpoint(ctx context.Context, j *job, stage Stage, step string) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+
+	j.logger.Debug("Job canceled", slog.String("before", step))
+	return stageError(stage, fmt.Errorf("%w before %s: %w", errCanceled, step, context.Cause(ctx)))
+}
+
+// salvages reports whether a job failing with err, after transcription,
+// hands its transcription back.
+func (s *Scriber) salvages(err error) bool {
+	return s.salvage || (s.cancellation == CancelSalvage && errors.Is(err, errCanceled))
+}
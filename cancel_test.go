@@ -0,0 +1,157 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_CancellationCheckpoints(t *testing.T) {
+	t.Parallel()
+
+	const srt = "1\n00:00:00,000 --> 00:00:01,000\nhello\n"
+
+	testCases := []struct {
+		name                       string
+		givenOpts                  func(cancel func()) []Option
+		givenCancelOnTranscription bool
+		givenPolicy                CancellationPolicy
+		expectedStage              Stage
+		expectedBefore             string
+		expectedTranscribed        bool
+		expectedSalvaged           string
+	}{
+		{
+			name: "after conversion",
+			givenOpts: func(cancel func()) []Option {
+				return []Option{
+					WithChunking(ChunkConfig{Length: time.Second}),
+					WithConverter(func(_ context.Context, _ io.Reader, w io.Writer) error {
+						defer cancel()
+						_, err := w.Write(syntheticWAV(2))
+						return err
+					}),
+				}
+			},
+			givenPolicy:    CancelSalvage,
+			expectedStage:  StageTranscription,
+			expectedBefore: "transcription",
+		},
+		{
+			name: "after transcription",
+			givenOpts: func(cancel func()) []Option {
+				return nil
+			},
+			givenCancelOnTranscription: true,
+			expectedStage:              StagePostProcess,
+			expectedBefore:             "post-processing",
+			expectedTranscribed:        true,
+		},
+		{
+			name: "after transcription salvaged",
+			givenOpts: func(cancel func()) []Option {
+				return nil
+			},
+			givenCancelOnTranscription: true,
+			givenPolicy:                CancelSalvage,
+			expectedStage:              StagePostProcess,
+			expectedBefore:             "post-processing",
+			expectedTranscribed:        true,
+			expectedSalvaged:           srt,
+		},
+		{
+			name: "between post-processors",
+			givenOpts: func(cancel func()) []Option {
+				return []Option{
+					WithRedactor(Redactor{Rules: []RedactionRule{{
+						Class:   "greeting",
+						Pattern: regexp.MustCompile(`hello`),
+						valid: func(string, int, int) bool {
+							cancel()
+							return true
+						},
+					}}}),
+				}
+			},
+			givenPolicy:         CancelSalvage,
+			expectedStage:       StagePostProcess,
+			expectedBefore:      "line wrapping",
+			expectedTranscribed: true,
+			expectedSalvaged:    "1\n00:00:00,000 --> 00:00:01,000\n[GREETING]\n",
+		},
+		{
+			name: "before publishing",
+			givenOpts: func(cancel func()) []Option {
+				return []Option{
+					WithExistsFunc(func(context.Context, string) (bool, error) {
+						cancel()
+						return false, nil
+					}, 0),
+				}
+			},
+			expectedStage:       StagePublish,
+			expectedBefore:      "publishing",
+			expectedTranscribed: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+
+			var transcribed bool
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					transcribed = true
+					if tc.givenCancelOnTranscription {
+						defer cancel()
+					}
+					_, err := io.Copy(io.Discard, in.Data)
+					return []byte(srt), err
+				},
+			}, append([]Option{
+				WithCancellationPolicy(tc.givenPolicy),
+				WithConverter(copyConverter),
+			}, tc.givenOpts(cancel)...)...)
+
+			err := s.Process(ctx, Input{
+				Name:       "talk.mp4",
+				OutputType: OutputTypeSubtitles,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(syntheticWAV(2))),
+			})
+			require.ErrorIs(t, err, context.Canceled)
+			assert.ErrorIs(t, err, errCanceled)
+			assert.ErrorContains(t, err, "before "+tc.expectedBefore)
+
+			var pe *ProcessError
+			require.ErrorAs(t, err, &pe)
+			assert.Equal(t, tc.expectedStage, pe.Stage)
+			assert.Equal(t, tc.expectedTranscribed, transcribed)
+
+			if tc.expectedSalvaged == "" {
+				assert.Nil(t, pe.Salvaged)
+			} else {
+				require.NotNil(t, pe.Salvaged)
+				assert.Equal(t, tc.expectedSalvaged, string(pe.Salvaged.Text))
+				require.NoError(t, pe.Salvaged.Body.Close())
+			}
+
+			select {
+			case out := <-s.Collect():
+				t.Fatalf("canceled job published %q", out.Name)
+			default:
+			}
+		})
+	}
+}
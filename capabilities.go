@@ -0,0 +1,121 @@
+package scriber
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/alesr/whisperclient"
+)
+
+// Capabilities describes what a transcription backend supports. Inputs
+// requiring what it doesn't are rejected before conversion starts.
+type Capabilities struct {
+	// ResponseFormats are the response formats the backend produces,
+	// e.g. whisperclient.FormatSrt. Nil means any.
+	ResponseFormats []string
+
+	// Codecs are the codecs of the audio the backend accepts. Nil means
+	// any.
+	Codecs []AudioCodec
+
+	// MaxUploadSize is the largest upload accepted, in bytes. Zero means
+	// no limit. It is the default upload limit, see WithUploadLimit.
+	MaxUploadSize int64
+}
+
+// CapabilityReporter is implemented by transcription clients that
+// report their capabilities.
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// WhisperClientCapabilities are the capabilities of a whisperclient.Client,
+// which uploads to the OpenAI API. They are assumed for such clients.
+var WhisperClientCapabilities = Capabilities{
+	ResponseFormats: []string{whisperclient.FormatSrt, whisperclient.FormatText, formatVerboseJSON, "json", "vtt"},
+	Codecs:          []AudioCodec{CodecWAV, CodecFLAC},
+	MaxUploadSize:   25 << 20,
+}
+
+// WithCapabilities declares the capabilities of the transcription
+// client, overriding those it reports, if any.
+func WithCapabilities(c Capabilities) Option {
+	return func(s *Scriber) {
+		s.capabilities = &c
+	}
+}
+
+// Capability is a capability of a transcription backend an input may
+// require.
+type Capability string
+
+const (
+	CapabilityResponseFormat    Capability = "response format"
+	CapabilityLanguageDetection Capability = "language detection"
+	CapabilityCodec             Capability = "codec"
+	CapabilityUploadSize        Capability = "upload size"
+)
+
+// UnsupportedByBackendError is the error of inputs requiring a capability
+// the transcription backend lacks.
+type UnsupportedByBackendError struct {
+	// Capability is the capability lacking.
+	Capability Capability
+
+	// Required is what the input requires of it, e.g. "srt".
+	Required string
+}
+
+func (e *UnsupportedByBackendError) Error() string {
+	return fmt.Sprintf("backend does not support %s %s", e.Capability, e.Required)
+}
+
+// backendCapabilities returns the capabilities of the transcription
+// client: those declared, reported, or known, in that order.
+func (s *Scriber) backendCapabilities() *Capabilities {
+	if s.capabilities != nil {
+		return s.capabilities
+	}
+	switch c := s.whisperClient.(type) {
+	case CapabilityReporter:
+		caps := c.Capabilities()
+		return &caps
+	case *whisperclient.Client:
+		caps := WhisperClientCapabilities
+		return &caps
+	}
+	return nil
+}
+
+// checkCapabilities checks that the backend supports what in requires:
+// the response format of its output type, language detection, the codec
+// of its upload, and the upload limit.
+func (s *Scriber) checkCapabilities(in Input) error {
+	caps := s.capabilities
+	if caps == nil {
+		return nil
+	}
+
+	unsupported := func(c Capability, required string) error {
+		return &UnsupportedByBackendError{Capability: c, Required: required}
+	}
+
+	if format, err := responseFormat(in.OutputType); err == nil && !supports(caps.ResponseFormats, format) {
+		return unsupported(CapabilityResponseFormat, format)
+	}
+	if in.Language == LanguageAuto && !supports(caps.ResponseFormats, formatVerboseJSON) {
+		return unsupported(CapabilityLanguageDetection, formatVerboseJSON)
+	}
+	if codec := s.codecFor(in); codec != "" && !supports(caps.Codecs, codec) {
+		return unsupported(CapabilityCodec, string(codec))
+	}
+	if caps.MaxUploadSize > 0 && s.uploadLimit.MaxSize > caps.MaxUploadSize {
+		return unsupported(CapabilityUploadSize, fmt.Sprintf("of %d bytes", s.uploadLimit.MaxSize))
+	}
+	return nil
+}
+
+// supports reports whether v is among supported, nil meaning any.
+func supports[T comparable](supported []T, v T) bool {
+	return supported == nil || slices.Contains(supported, v)
+}
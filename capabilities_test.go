@@ -0,0 +1,186 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type reportingWhisperClient struct {
+	mockWhisperClient
+	capabilities Capabilities
+}
+
+func (c *reportingWhisperClient) Capabilities() Capabilities { return c.capabilities }
+
+func TestProcess_Capabilities(t *testing.T) {
+	t.Parallel()
+
+	textOnly := Capabilities{ResponseFormats: []string{whisperclient.FormatText}}
+
+	testCases := []struct {
+		name               string
+		givenCapabilities  Capabilities
+		givenOpts          []Option
+		givenType          OutputType
+		givenLanguage      string
+		expectedCapability Capability
+		expectedRequired   string
+	}{
+		{
+			name:              "supported",
+			givenCapabilities: WhisperClientCapabilities,
+			givenType:         OutputTypeSubtitles,
+			givenLanguage:     "en",
+		},
+		{
+			name:              "unknown capabilities",
+			givenCapabilities: Capabilities{},
+			givenType:         OutputTypeSBV,
+			givenLanguage:     LanguageAuto,
+		},
+		{
+			name:               "response format",
+			givenCapabilities:  textOnly,
+			givenType:          OutputTypeSubtitles,
+			givenLanguage:      "en",
+			expectedCapability: CapabilityResponseFormat,
+			expectedRequired:   whisperclient.FormatSrt,
+		},
+		{
+			name:               "language detection",
+			givenCapabilities:  textOnly,
+			givenType:          OutputTypeTranscript,
+			givenLanguage:      LanguageAuto,
+			expectedCapability: CapabilityLanguageDetection,
+			expectedRequired:   formatVerboseJSON,
+		},
+		{
+			name:               "codec",
+			givenCapabilities:  Capabilities{Codecs: []AudioCodec{CodecWAV}},
+			givenOpts:          []Option{WithUploadCodec(CodecFLAC)},
+			givenType:          OutputTypeTranscript,
+			givenLanguage:      "en",
+			expectedCapability: CapabilityCodec,
+			expectedRequired:   string(CodecFLAC),
+		},
+		{
+			name:               "upload size",
+			givenCapabilities:  Capabilities{MaxUploadSize: 25 << 20},
+			givenOpts:          []Option{WithUploadLimit(UploadLimit{MaxSize: 30 << 20})},
+			givenType:          OutputTypeTranscript,
+			givenLanguage:      "en",
+			expectedCapability: CapabilityUploadSize,
+			expectedRequired:   "of 31457280 bytes",
+		},
+		{
+			name:               "declared capabilities",
+			givenCapabilities:  WhisperClientCapabilities,
+			givenOpts:          []Option{WithCapabilities(textOnly)},
+			givenType:          OutputTypeSBV,
+			givenLanguage:      "en",
+			expectedCapability: CapabilityResponseFormat,
+			expectedRequired:   whisperclient.FormatSrt,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var transcribed bool
+			client := &reportingWhisperClient{
+				mockWhisperClient: mockWhisperClient{
+					transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+						transcribed = true
+						_, err := io.Copy(io.Discard, in.Data)
+						if in.Format == formatVerboseJSON {
+							return []byte(`{"language":"english","text":"hello"}`), err
+						}
+						return []byte("1\n00:00:00,000 --> 00:00:01,000\nhello\n"), err
+					},
+				},
+				capabilities: tc.givenCapabilities,
+			}
+
+			var converted bool
+			opts := append([]Option{
+				WithConverter(func(_ context.Context, r io.Reader, w io.Writer) error {
+					converted = true
+					_, err := io.Copy(w, r)
+					return err
+				}),
+			}, tc.givenOpts...)
+			if tc.expectedCapability != "" {
+				// The default converter, to upload in the negotiated codec.
+				opts = tc.givenOpts
+			}
+			s := New(noopLogger(), client, opts...)
+
+			in := Input{
+				Name:       "talk.mp4",
+				OutputType: tc.givenType,
+				Language:   tc.givenLanguage,
+				Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+			}
+
+			validated := s.ValidateBatch([]Input{in})[0]
+			err := s.Process(context.TODO(), in)
+
+			if tc.expectedCapability == "" {
+				require.NoError(t, validated)
+				require.NoError(t, err)
+				out := <-s.Collect()
+				require.NoError(t, out.Body.Close())
+				return
+			}
+
+			for _, err := range []error{validated, err} {
+				var pe *ProcessError
+				require.ErrorAs(t, err, &pe)
+				assert.Equal(t, StageValidation, pe.Stage)
+
+				var ue *UnsupportedByBackendError
+				require.ErrorAs(t, err, &ue)
+				assert.Equal(t, tc.expectedCapability, ue.Capability)
+				assert.Equal(t, tc.expectedRequired, ue.Required)
+			}
+			assert.False(t, converted, "the input should not be converted")
+			assert.False(t, transcribed, "the backend should not be called")
+		})
+	}
+}
+
+func TestScriber_BackendCapabilities(t *testing.T) {
+	t.Parallel()
+
+	t.Run("whisperclient", func(t *testing.T) {
+		t.Parallel()
+
+		s := New(noopLogger(), whisperclient.New(http.DefaultClient, "key", "whisper-1"))
+		require.NotNil(t, s.capabilities)
+		assert.Equal(t, WhisperClientCapabilities, *s.capabilities)
+		assert.Equal(t, WhisperClientCapabilities.MaxUploadSize, s.uploadLimit.MaxSize, "the upload limit should default to the backend's")
+	})
+
+	t.Run("configured upload limit", func(t *testing.T) {
+		t.Parallel()
+
+		s := New(noopLogger(), whisperclient.New(http.DefaultClient, "key", "whisper-1"), WithUploadLimit(UploadLimit{MaxSize: 1 << 20}))
+		assert.Equal(t, int64(1<<20), s.uploadLimit.MaxSize)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		t.Parallel()
+
+		s := New(noopLogger(), &mockWhisperClient{})
+		assert.Nil(t, s.capabilities)
+		assert.Zero(t, s.uploadLimit.MaxSize)
+	})
+}
@@ -0,0 +1,387 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/alesr/scriber/wav"
+)
+
+// maxOverlapWords bounds the number of words compared when
+// de-duplicating the overlap between consecutive transcript chunks.
+const maxOverlapWords = 50
+
+// ChunkConfig configures chunked transcription of long recordings.
+// The converted audio is split into windows of Length, each overlapping
+// the previous one by Overlap, and transcribed with up to Parallelism
+// concurrent requests.
+type ChunkConfig struct {
+	Length      time.Duration
+	Overlap     time.Duration
+	Parallelism int
+}
+
+func (c ChunkConfig) validate() error {
+	if c.Length <= 0 {
+		return errors.New("chunk length must be positive")
+	}
+
+	if c.Overlap < 0 || c.Overlap >= c.Length {
+		return errors.New("chunk overlap must be non-negative and shorter than the chunk length")
+	}
+	return nil
+}
+
+// MetadataChunkLength is the Output.Metadata key holding the length of the
+// chunks a chunked transcription was split into, e.g. 10m0s.
+const MetadataChunkLength = "chunk_length"
+
+// WithChunking enables chunked transcription. Instead of streaming the converted
+// audio to the backend in one request, it is spooled to a temporary file, split
+// into overlapping windows which are transcribed in parallel, and the results
+// are stitched back together.
+func WithChunking(cfg ChunkConfig) Option {
+	return func(s *Scriber) {
+		if cfg.Parallelism <= 0 {
+			cfg.Parallelism = 1
+		}
+		s.chunking = &cfg
+	}
+}
+
+// chunkWindow is a frame-aligned window over the PCM data of a WAV stream.
+type chunkWindow struct {
+	index  int
+	start  time.Duration
+	end    time.Duration
+	offset int64 // Byte offset relative to the start of the data chunk.
+	size   int64
+}
+
+// chunkWindows splits dataLen bytes of PCM audio into overlapping windows.
+func chunkWindows(dataLen int64, f wav.Format, cfg ChunkConfig) []chunkWindow {
+	align := f.BlockAlign()
+	rate := f.ByteRate()
+
+	toBytes := func(d time.Duration) int64 {
+		n := int64(d) * rate / int64(time.Second)
+		return n - n%align
+	}
+	length := max(toBytes(cfg.Length), align)
+	step := max(length-toBytes(cfg.Overlap), align)
+
+	var windows []chunkWindow
+	for offset := int64(0); offset < dataLen; offset += step {
+		end := min(offset+length, dataLen)
+
+		windows = append(windows, chunkWindow{
+			index:  len(windows),
+			start:  f.Duration(offset),
+			end:    f.Duration(end),
+			offset: offset,
+			size:   end - offset,
+		})
+
+		if end == dataLen {
+			break
+		}
+	}
+	return windows
+}
+
+// transcribeChunked converts the input into a temporary WAV file,
+// transcribes it in overlapping chunks, and stitches the results.
+func (s *Scriber) transcribeChunked(ctx context.Context, j *job) ([]byte, error) {
+	audio, err := s.convertToFile(ctx, j)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		audio.Close()
+		os.Remove(audio.Name())
+	}()
+
+	return s.transcribeChunkedFile(ctx, j, audio)
+}
+
+// convertToFile converts the job's input into a temporary WAV file.
+// The caller must close and remove the file.
+func (s *Scriber) convertToFile(ctx context.Context, j *job) (*os.File, error) {
+	spool, err := os.CreateTemp(s.spoolDir, "scriber-audio-*.wav")
+	if err != nil {
+		return nil, stageError(StageConversion, fmt.Errorf("could not create audio spool: %w", err))
+	}
+
+	convertStart := time.Now()
+	input, stop := s.inputReader(j)
+	err = s.convert(ctx, j.ffmpegArgs, newPooledReader(input, s.buffers()), spool)
+	stop()
+	j.timing.Convert = time.Since(convertStart)
+	if err != nil {
+		j.logFFmpegOutput(err)
+		spool.Close()
+		os.Remove(spool.Name())
+		return nil, stageError(StageConversion, fmt.Errorf("could not convert to wav: %w", err))
+	}
+
+	if err := s.checkpoint(ctx, j, StageTranscription, "transcription"); err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		return nil, err
+	}
+	return spool, nil
+}
+
+// wavFileLayout returns the format of the WAV file f, the offset of its
+// first data byte, and the length of its data. The file is read with
+// ReadAt, so it can be shared by concurrent jobs.
+func wavFileLayout(f *os.File) (wav.Format, int64, int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return wav.Format{}, 0, 0, fmt.Errorf("could not stat audio spool: %w", err)
+	}
+
+	format, dataOffset, _, err := readWAVHeader(io.NewSectionReader(f, 0, info.Size()))
+	if err != nil {
+		return wav.Format{}, 0, 0, fmt.Errorf("could not read converted audio: %w", err)
+	}
+
+	// The header written by ffmpeg to a pipe doesn't know the final size,
+	// so trust the file size instead of the declared data length.
+	dataLen := info.Size() - dataOffset
+	dataLen -= dataLen % format.BlockAlign()
+	return format, dataOffset, dataLen, nil
+}
+
+// transcribeChunkedFile transcribes the WAV file audio in
+// overlapping chunks and stitches the results.
+func (s *Scriber) transcribeChunkedFile(ctx context.Context, j *job, audio *os.File) ([]byte, error) {
+	format, dataOffset, dataLen, err := wavFileLayout(audio)
+	if err != nil {
+		return nil, stageError(StageConversion, err)
+	}
+
+	j.audioDuration = format.Duration(dataLen)
+
+	cfg, err := s.chunkConfigFor(j, format)
+	if err != nil {
+		return nil, err
+	}
+	windows := chunkWindows(dataLen, format, cfg)
+
+	j.logger.Debug("Transcribing in chunks",
+		slog.String("file", j.in.Name),
+		slog.Int("chunks", len(windows)),
+	)
+
+	transcribeStart := time.Now()
+	results, err := s.transcribeWindows(ctx, j, audio, dataOffset, format, windows, cfg.Parallelism)
+	j.timing.Transcribe = time.Since(transcribeStart)
+	if err != nil {
+		return nil, stageError(StageTranscription, err)
+	}
+
+	j.uploadSpec = wavSpec(format)
+	j.convertedBytes = 0
+	for _, w := range windows {
+		j.convertedBytes += wav.HeaderSize + w.size
+	}
+
+	postStart := time.Now()
+	defer func() { j.timing.PostProcess = time.Since(postStart) }()
+
+	if j.in.OutputType == OutputTypeTranscript {
+		return []byte(stitchTranscripts(results)), nil
+	}
+
+	chunks := make([]SubtitleChunk, len(results))
+	for i, r := range results {
+		chunks[i] = SubtitleChunk{Offset: windows[i].start, SRT: []byte(r)}
+	}
+	text, err := StitchSubtitles(chunks)
+	if err != nil {
+		// Keep the chunk transcriptions so they can be salvaged.
+		j.raw = []byte(strings.Join(results, "\n"))
+		return nil, stageError(StagePostProcess, err)
+	}
+	return text, nil
+}
+
+// transcribeWindows transcribes every window, parallelism at a time,
+// returning the results in window order.
+func (s *Scriber) transcribeWindows(
+	ctx context.Context,
+	j *job,
+	audio io.ReaderAt,
+	dataOffset int64,
+	format wav.Format,
+	windows []chunkWindow,
+	parallelism int,
+) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var header bytes.Buffer
+	open := func(w chunkWindow) io.Reader {
+		header.Reset()
+		_ = wav.WriteHeader(&header, format, uint32(w.size))
+		return io.MultiReader(bytes.NewReader(header.Bytes()), io.NewSectionReader(audio, dataOffset+w.offset, w.size))
+	}
+
+	keys, resumed, err := s.resumeWindows(j, open, windows)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		results  = make([]string, len(windows))
+		sem      = make(chan struct{}, parallelism)
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+		partials = newPartialSequencer(s.partialsCh, &s.outlets)
+	)
+
+	for _, w := range windows {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(w chunkWindow) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			defer func() {
+				if v := recover(); v != nil {
+					err := newPanicError(v)
+					errOnce.Do(func() { firstErr = fmt.Errorf("chunk %d: %w", w.index, err); cancel() })
+				}
+			}()
+
+			text, err := s.transcribeWindow(ctx, j, audio, dataOffset, format, w, keys, resumed)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = fmt.Errorf("chunk %d: %w", w.index, err)
+					cancel()
+				})
+				return
+			}
+			results[w.index] = string(text)
+
+			partial, _ := s.redact(text, j.in.OutputType)
+			err = partials.complete(ctx, PartialOutput{
+				JobID:        j.id,
+				SegmentIndex: w.index,
+				Language:     j.in.Language,
+				Text:         string(partial),
+				Start:        w.start,
+				End:          w.end,
+			})
+			if err != nil {
+				errOnce.Do(func() { firstErr = err; cancel() })
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if s.chunkStore != nil {
+		s.chunkStore.remove(keys)
+	}
+	return results, nil
+}
+
+// transcribeWindow transcribes the window w, unless its transcription was
+// resumed, saving it under its key for later runs if chunks are resumable.
+func (s *Scriber) transcribeWindow(
+	ctx context.Context,
+	j *job,
+	audio io.ReaderAt,
+	dataOffset int64,
+	format wav.Format,
+	w chunkWindow,
+	keys, resumed []string,
+) ([]byte, error) {
+	if keys != nil && resumed[w.index] != "" {
+		return []byte(resumed[w.index]), nil
+	}
+
+	var header bytes.Buffer
+	if err := wav.WriteHeader(&header, format, uint32(w.size)); err != nil {
+		return nil, err
+	}
+
+	chunkCtx := withIdempotencyKey(ctx, chunkIdempotencyKey(j.idempotencyKey, w.index))
+
+	text, err := s.transcribeRetrying(chunkCtx, j, func() io.Reader {
+		return io.MultiReader(bytes.NewReader(header.Bytes()), io.NewSectionReader(audio, dataOffset+w.offset, w.size))
+	}, format.Duration(w.size), slog.Int("chunk", w.index))
+	if err != nil {
+		return nil, err
+	}
+
+	if keys != nil {
+		if err := s.chunkStore.save(keys[w.index], string(text)); err != nil {
+			j.logger.Warn("Could not save chunk", slog.Int("chunk", w.index), slog.String("error", err.Error()))
+		}
+	}
+	return text, nil
+}
+
+// stitchTranscripts concatenates chunk transcripts, removing the words
+// repeated at the start of a chunk because of the overlap with the previous one.
+func stitchTranscripts(texts []string) string {
+	var words []string
+	for _, t := range texts {
+		next := strings.Fields(t)
+		n := overlappingWords(words, next)
+		words = append(words, next[n:]...)
+	}
+	return strings.Join(words, " ")
+}
+
+// overlappingWords returns the length of the longest suffix of prev
+// that matches a prefix of next, ignoring case and punctuation.
+func overlappingWords(prev, next []string) int {
+	limit := min(len(prev), len(next), maxOverlapWords)
+
+	for n := limit; n > 0; n-- {
+		match := true
+		for i := 0; i < n; i++ {
+			if normalizeWord(prev[len(prev)-n+i]) != normalizeWord(next[i]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return n
+		}
+	}
+	return 0
+}
+
+func normalizeWord(w string) string {
+	return strings.ToLower(strings.TrimFunc(w, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r)
+	}))
+}
@@ -0,0 +1,288 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alesr/scriber/subtitle"
+	"github.com/alesr/scriber/wav"
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testWAVFormat is a tiny format (200 bytes per second)
+// that keeps synthetic fixtures small.
+var testWAVFormat = wav.Format{AudioFormat: wav.FormatPCM, Channels: 1, SampleRate: 100, BitsPerSample: 16}
+
+func TestChunkConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		given   ChunkConfig
+		wantErr bool
+	}{
+		{name: "valid", given: ChunkConfig{Length: time.Minute, Overlap: time.Second}},
+		{name: "no overlap", given: ChunkConfig{Length: time.Minute}},
+		{name: "zero length", given: ChunkConfig{}, wantErr: true},
+		{name: "negative overlap", given: ChunkConfig{Length: time.Minute, Overlap: -time.Second}, wantErr: true},
+		{name: "overlap as long as chunk", given: ChunkConfig{Length: time.Minute, Overlap: time.Minute}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.given.validate()
+			if tc.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestChunkWindows(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		givenLen int64
+		givenCfg ChunkConfig
+		expected []chunkWindow
+	}{
+		{
+			name:     "shorter than one chunk",
+			givenLen: 5 * 200,
+			givenCfg: ChunkConfig{Length: 10 * time.Second, Overlap: 2 * time.Second},
+			expected: []chunkWindow{
+				{index: 0, start: 0, end: 5 * time.Second, offset: 0, size: 1000},
+			},
+		},
+		{
+			name:     "overlapping windows",
+			givenLen: 25 * 200,
+			givenCfg: ChunkConfig{Length: 10 * time.Second, Overlap: 2 * time.Second},
+			expected: []chunkWindow{
+				{index: 0, start: 0, end: 10 * time.Second, offset: 0, size: 2000},
+				{index: 1, start: 8 * time.Second, end: 18 * time.Second, offset: 1600, size: 2000},
+				{index: 2, start: 16 * time.Second, end: 25 * time.Second, offset: 3200, size: 1800},
+			},
+		},
+		{
+			name:     "exact multiple without overlap",
+			givenLen: 20 * 200,
+			givenCfg: ChunkConfig{Length: 10 * time.Second},
+			expected: []chunkWindow{
+				{index: 0, start: 0, end: 10 * time.Second, offset: 0, size: 2000},
+				{index: 1, start: 10 * time.Second, end: 20 * time.Second, offset: 2000, size: 2000},
+			},
+		},
+		{
+			name:     "windows are frame aligned",
+			givenLen: 1001,
+			givenCfg: ChunkConfig{Length: 2505 * time.Millisecond},
+			expected: []chunkWindow{
+				{index: 0, start: 0, end: 2500 * time.Millisecond, offset: 0, size: 500},
+				{index: 1, start: 2500 * time.Millisecond, end: 5000 * time.Millisecond, offset: 500, size: 500},
+				{index: 2, start: 5000 * time.Millisecond, end: 5005 * time.Millisecond, offset: 1000, size: 1},
+			},
+		},
+		{
+			name:     "empty",
+			givenLen: 0,
+			givenCfg: ChunkConfig{Length: 10 * time.Second},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := chunkWindows(tc.givenLen, testWAVFormat, tc.givenCfg)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestStitchTranscripts(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		given    []string
+		expected string
+	}{
+		{
+			name:     "single chunk",
+			given:    []string{"hello world"},
+			expected: "hello world",
+		},
+		{
+			name:     "overlap is removed ignoring case and punctuation",
+			given:    []string{"the quick brown fox", "Brown fox, jumps over", "over the lazy dog."},
+			expected: "the quick brown fox jumps over the lazy dog.",
+		},
+		{
+			name:     "no overlap",
+			given:    []string{"one two", "three four"},
+			expected: "one two three four",
+		},
+		{
+			name:     "empty chunks",
+			given:    []string{"", "one two", "  ", "two three"},
+			expected: "one two three",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, stitchTranscripts(tc.given))
+		})
+	}
+}
+
+func TestProcess_Chunked(t *testing.T) {
+	t.Parallel()
+
+	const seconds = 25
+
+	testCases := []struct {
+		name       string
+		outputType OutputType
+		respond    func(startSec int) string
+		expected   string
+	}{
+		{
+			name:       "transcript",
+			outputType: OutputTypeTranscript,
+			respond: func(startSec int) string {
+				// Each chunk says the numbers of the seconds it covers.
+				var words []string
+				for s := startSec; s < min(startSec+10, seconds); s++ {
+					words = append(words, fmt.Sprint(s))
+				}
+				return strings.Join(words, " ")
+			},
+			expected: "0 1 2 3 4 5 6 7 8 9 10 11 12 13 14 15 16 17 18 19 20 21 22 23 24",
+		},
+		{
+			name:       "subtitles",
+			outputType: OutputTypeSubtitles,
+			respond: func(startSec int) string {
+				return string(formatSRT([]subtitle.Cue{
+					{Start: 0, End: 5 * time.Second, Text: fmt.Sprintf("from %d", startSec)},
+					{Start: 5 * time.Second, End: 9 * time.Second, Text: fmt.Sprintf("from %d", startSec+5)},
+				}))
+			},
+			expected: string(formatSRT([]subtitle.Cue{
+				{Start: 0, End: 5 * time.Second, Text: "from 0"},
+				{Start: 5 * time.Second, End: 9 * time.Second, Text: "from 5"},
+				{Start: 9 * time.Second, End: 13 * time.Second, Text: "from 8"},
+				{Start: 13 * time.Second, End: 17 * time.Second, Text: "from 13"},
+				{Start: 17 * time.Second, End: 21 * time.Second, Text: "from 16"},
+				{Start: 21 * time.Second, End: 25 * time.Second, Text: "from 21"},
+			})),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls atomic.Int32
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					calls.Add(1)
+
+					_, offset, _, err := readWAVHeader(in.Data)
+					require.NoError(t, err)
+					require.Equal(t, int64(wav.HeaderSize), offset)
+
+					// The first sample identifies the second the chunk starts at.
+					first := make([]byte, 1)
+					_, err = io.ReadFull(in.Data, first)
+					require.NoError(t, err)
+					_, err = io.Copy(io.Discard, in.Data)
+					require.NoError(t, err)
+
+					return []byte(tc.respond(int(first[0]))), nil
+				},
+			}
+
+			scriber := New(noopLogger(), mockClient,
+				WithChunking(ChunkConfig{Length: 10 * time.Second, Overlap: 2 * time.Second, Parallelism: 2}),
+				WithSpoolThreshold(0, t.TempDir()),
+			)
+			scriber.convertToWavFunc = func(_ context.Context, r io.Reader, w io.Writer) error {
+				if _, err := io.Copy(io.Discard, r); err != nil {
+					return err
+				}
+				_, err := w.Write(syntheticWAV(seconds))
+				return err
+			}
+
+			err := scriber.Process(context.TODO(), Input{
+				Name:       "long.mp4",
+				OutputType: tc.outputType,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewBufferString("foo")),
+			})
+			require.NoError(t, err)
+
+			output := <-scriber.Collect()
+			assert.Equal(t, tc.expected, string(output.Text))
+			assert.Equal(t, seconds*time.Second, output.AudioDuration)
+			assert.Equal(t, int32(3), calls.Load())
+		})
+	}
+}
+
+func TestProcess_ChunkedTranscriptionError(t *testing.T) {
+	t.Parallel()
+
+	mockClient := &mockWhisperClient{
+		transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			return nil, assert.AnError
+		},
+	}
+
+	scriber := New(noopLogger(), mockClient, WithChunking(ChunkConfig{Length: 10 * time.Second}))
+	scriber.convertToWavFunc = func(_ context.Context, r io.Reader, w io.Writer) error {
+		_, err := w.Write(syntheticWAV(25))
+		return err
+	}
+
+	err := scriber.Process(context.TODO(), Input{
+		Name:       "long.mp4",
+		OutputType: OutputTypeTranscript,
+		Language:   "en",
+		Data:       io.NopCloser(bytes.NewBufferString("foo")),
+	})
+	require.ErrorIs(t, err, assert.AnError)
+}
+
+// syntheticWAV returns a WAV stream in testWAVFormat where every
+// byte of second n has value n, with a streamed (unknown) data size.
+func syntheticWAV(seconds int) []byte {
+	var buf bytes.Buffer
+	_ = wav.WriteHeader(&buf, testWAVFormat, wav.UnknownSize)
+
+	rate := int(testWAVFormat.ByteRate())
+	for s := 0; s < seconds; s++ {
+		buf.Write(bytes.Repeat([]byte{byte(s)}, rate))
+	}
+	return buf.Bytes()
+}
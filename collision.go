@@ -0,0 +1,127 @@
+package scriber
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync"
+)
+
+// defaultMaxNameAttempts is how many suffixes are tried by default
+// before giving up on finding a free output name.
+const defaultMaxNameAttempts = 100
+
+// ExistsFunc reports whether an output named name already exists at the
+// destination, e.g. in a bucket or directory. It must return promptly
+// once ctx is done.
+type ExistsFunc func(ctx context.Context, name string) (bool, error)
+
+// WithExistsFunc makes outputs avoid names that are taken at the destination,
+// so that outputs of inputs named alike, even across restarts, don't
+// overwrite each other. Before an output is published, exists is called
+// with its name; while the name is taken, -1, -2, and so on are appended
+// to the base name (talk-1.srt), up to maxAttempts times, 100 when zero.
+// Jobs fail in StagePublish with a NameTakenError when no free name is
+// found, or with the error of exists.
+//
+// Names are also held until the Body of their output is closed, which
+// should be once it is stored, so that concurrent jobs don't pick the
+// same free name.
+func WithExistsFunc(exists ExistsFunc, maxAttempts int) Option {
+	return func(s *Scriber) {
+		if maxAttempts <= 0 {
+			maxAttempts = defaultMaxNameAttempts
+		}
+		s.existsFunc = exists
+		s.maxNameAttempts = maxAttempts
+	}
+}
+
+// nameReservations tracks the output names being published.
+type nameReservations struct {
+	mu    sync.Mutex
+	names map[string]struct{}
+}
+
+// reserve holds name, reporting whether it was free.
+func (r *nameReservations) reserve(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.names[name]; ok {
+		return false
+	}
+	if r.names == nil {
+		r.names = make(map[string]struct{})
+	}
+	r.names[name] = struct{}{}
+	return true
+}
+
+func (r *nameReservations) release(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.names, name)
+}
+
+// reserveName renames out until its name is free at the destination and
+// not held by another job, then holds it until out.Body is closed.
+// It does nothing without an ExistsFunc.
+func (s *Scriber) reserveName(ctx context.Context, j *job, out *Output) error {
+	if s.existsFunc == nil {
+		return nil
+	}
+
+	base, name := out.BaseName, out.Name
+
+	for attempt := 0; attempt <= s.maxNameAttempts; attempt++ {
+		if attempt > 0 {
+			out.BaseName = base + "-" + strconv.Itoa(attempt)
+			out.Name = out.Filename(j.nameOptions()...)
+		}
+
+		if !s.names.reserve(out.Name) {
+			continue
+		}
+
+		exists, err := s.nameExists(ctx, out.Name)
+		if err != nil {
+			s.names.release(out.Name)
+			return fmt.Errorf("could not check output name %q: %w", out.Name, err)
+		}
+		if exists {
+			s.names.release(out.Name)
+			continue
+		}
+
+		if attempt > 0 {
+			j.logger.Info("Output name taken, renamed", slog.String("name", name), slog.String("renamed", out.Name))
+		}
+
+		reserved := out.Name
+		out.Body = &reservedBody{ReadCloser: out.Body, release: func() { s.names.release(reserved) }}
+		return nil
+	}
+	return fmt.Errorf("%w for %q after %d attempts", errNameTaken, name, s.maxNameAttempts)
+}
+
+// nameExists runs the ExistsFunc, recovering from its panics.
+func (s *Scriber) nameExists(ctx context.Context, name string) (exists bool, err error) {
+	defer recoverPanic(&err)
+	return s.existsFunc(ctx, name)
+}
+
+// reservedBody releases the name of its output once closed.
+type reservedBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *reservedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
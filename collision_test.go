@@ -0,0 +1,231 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_ExistsFunc(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		givenTaken       []string
+		givenInName      bool
+		givenExtInName   bool
+		givenMaxAttempts int
+		givenExistsErr   error
+		expectedName     string
+		expectedChecked  []string
+		expectedErr      error
+	}{
+		{
+			name:            "free name",
+			expectedName:    "talk.srt",
+			expectedChecked: []string{"talk.srt"},
+		},
+		{
+			name:            "taken names",
+			givenTaken:      []string{"talk.srt", "talk-1.srt"},
+			expectedName:    "talk-2.srt",
+			expectedChecked: []string{"talk.srt", "talk-1.srt", "talk-2.srt"},
+		},
+		{
+			name:            "taken name with language",
+			givenTaken:      []string{"talk.en.srt"},
+			givenInName:     true,
+			expectedName:    "talk-1.en.srt",
+			expectedChecked: []string{"talk.en.srt", "talk-1.en.srt"},
+		},
+		{
+			name:            "taken name with input extension and language",
+			givenTaken:      []string{"talk.mp4.en.srt"},
+			givenInName:     true,
+			givenExtInName:  true,
+			expectedName:    "talk-1.mp4.en.srt",
+			expectedChecked: []string{"talk.mp4.en.srt", "talk-1.mp4.en.srt"},
+		},
+		{
+			name:             "no free name",
+			givenTaken:       []string{"talk.srt", "talk-1.srt", "talk-2.srt"},
+			givenMaxAttempts: 2,
+			expectedChecked:  []string{"talk.srt", "talk-1.srt", "talk-2.srt"},
+			expectedErr:      errNameTaken,
+		},
+		{
+			name:            "exists fails",
+			givenExistsErr:  assert.AnError,
+			expectedChecked: []string{"talk.srt"},
+			expectedErr:     assert.AnError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var checked []string
+			exists := func(ctx context.Context, name string) (bool, error) {
+				require.NoError(t, ctx.Err())
+
+				checked = append(checked, name)
+				if tc.givenExistsErr != nil {
+					return false, tc.givenExistsErr
+				}
+				for _, taken := range tc.givenTaken {
+					if name == taken {
+						return true, nil
+					}
+				}
+				return false, nil
+			}
+
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.ReadAll(in.Data)
+					require.NoError(t, err)
+					return []byte("1\n00:00:00,000 --> 00:00:01,000\nhello\n"), nil
+				},
+			},
+				WithExistsFunc(exists, tc.givenMaxAttempts),
+				WithLanguageInName(tc.givenInName),
+				WithInputExtensionInName(tc.givenExtInName),
+				WithConverter(copyConverter),
+			)
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "talk.mp4",
+				OutputType: OutputTypeSubtitles,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+			})
+			assert.Equal(t, tc.expectedChecked, checked)
+
+			if tc.expectedErr != nil {
+				var pe *ProcessError
+				require.ErrorAs(t, err, &pe)
+				assert.Equal(t, StagePublish, pe.Stage)
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+			assert.Equal(t, tc.expectedName, out.Name)
+		})
+	}
+}
+
+func TestProcess_ExistsFuncReservesNames(t *testing.T) {
+	t.Parallel()
+
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.ReadAll(in.Data)
+			require.NoError(t, err)
+			return []byte("hello"), nil
+		},
+	},
+		WithExistsFunc(func(context.Context, string) (bool, error) { return false, nil }, 0),
+		WithConverter(copyConverter),
+	)
+
+	process := func() {
+		err := s.Process(context.TODO(), Input{
+			Name:       "talk.mp4",
+			OutputType: OutputTypeTranscript,
+			Language:   "en",
+			Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+		})
+		assert.NoError(t, err)
+	}
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			process()
+		}()
+	}
+	wg.Wait()
+
+	// Names are held until the outputs are closed.
+	var outs []Output
+	for range 3 {
+		outs = append(outs, <-s.Collect())
+	}
+	assert.ElementsMatch(t, []string{"talk.txt", "talk-1.txt", "talk-2.txt"},
+		[]string{outs[0].Name, outs[1].Name, outs[2].Name})
+
+	for _, out := range outs {
+		require.NoError(t, out.Body.Close())
+	}
+
+	process()
+	out := <-s.Collect()
+	require.NoError(t, out.Body.Close())
+	assert.Equal(t, "talk.txt", out.Name)
+}
+
+func TestProcess_InputExtensionInName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		givenExtInName bool
+		expectedNames  []string
+	}{
+		{
+			name:          "disabled",
+			expectedNames: []string{"video.srt", "video-1.srt"},
+		},
+		{
+			name:           "enabled",
+			givenExtInName: true,
+			expectedNames:  []string{"video.mp4.srt", "video.mov.srt"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.ReadAll(in.Data)
+					require.NoError(t, err)
+					return []byte("1\n00:00:00,000 --> 00:00:01,000\nhello\n"), nil
+				},
+			},
+				WithExistsFunc(func(context.Context, string) (bool, error) { return false, nil }, 0),
+				WithInputExtensionInName(tc.givenExtInName),
+				WithConverter(copyConverter),
+			)
+
+			var names []string
+			for _, name := range []string{"video.mp4", "video.mov"} {
+				require.NoError(t, s.Process(context.TODO(), Input{
+					Name:       name,
+					OutputType: OutputTypeSubtitles,
+					Language:   "en",
+					Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+				}))
+
+				// Outputs are held open, so their names stay reserved.
+				out := <-s.Collect()
+				defer out.Body.Close()
+				names = append(names, out.Name)
+			}
+			assert.Equal(t, tc.expectedNames, names)
+		})
+	}
+}
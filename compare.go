@@ -0,0 +1,161 @@
+package scriber
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode"
+)
+
+// ComparisonResult is the word-level comparison of a transcript with its
+// reference, as returned by CompareTranscripts.
+type ComparisonResult struct {
+	// ReferenceWords is the number of words of the reference.
+	ReferenceWords int
+
+	// Substitutions, Deletions, and Insertions are the word edits of the
+	// alignment of the candidate with the reference: words replaced,
+	// missing from the candidate, and extra in it.
+	Substitutions int
+	Deletions     int
+	Insertions    int
+
+	// WER is the word error rate, the number of edits over ReferenceWords.
+	// It exceeds 1 when the candidate has many extra words. With an empty
+	// reference, it is 0 if the candidate is empty too, and 1 otherwise.
+	WER float64
+}
+
+// CompareTranscripts compares candidate with reference word by word, for
+// regression testing. Both are normalized with NormalizeTranscript first,
+// so case and punctuation don't count, then split into words at spaces.
+// Scripts written without spaces, Chinese and Japanese, are compared
+// character by character instead.
+func CompareTranscripts(reference, candidate string) ComparisonResult {
+	ref, cand := transcriptWords(reference), transcriptWords(candidate)
+
+	// edits is the cost of aligning a prefix of ref with one of cand,
+	// along with its edits. Only two rows are kept, so long transcripts
+	// take linear memory.
+	type edits struct{ cost, sub, del, ins int }
+
+	prev := make([]edits, len(cand)+1)
+	curr := make([]edits, len(cand)+1)
+	for j := range prev {
+		prev[j] = edits{cost: j, ins: j}
+	}
+
+	for i := 1; i <= len(ref); i++ {
+		curr[0] = edits{cost: i, del: i}
+		for j := 1; j <= len(cand); j++ {
+			if ref[i-1] == cand[j-1] {
+				curr[j] = prev[j-1]
+				continue
+			}
+
+			best := prev[j-1]
+			best.sub++
+			if e := prev[j]; e.cost < best.cost {
+				best = e
+				best.del++
+			}
+			if e := curr[j-1]; e.cost < best.cost {
+				best = e
+				best.ins++
+			}
+			best.cost++
+			curr[j] = best
+		}
+		prev, curr = curr, prev
+	}
+
+	e := prev[len(cand)]
+	res := ComparisonResult{
+		ReferenceWords: len(ref),
+		Substitutions:  e.sub,
+		Deletions:      e.del,
+		Insertions:     e.ins,
+	}
+	switch {
+	case len(ref) > 0:
+		res.WER = float64(e.cost) / float64(len(ref))
+	case len(cand) > 0:
+		res.WER = 1
+	}
+	return res
+}
+
+// transcriptWords returns the words of text, normalized, with the
+// characters of scripts written without spaces as words of their own.
+func transcriptWords(text string) []string {
+	var words []string
+	for _, field := range strings.Fields(NormalizeTranscript(text)) {
+		start := 0
+		for i, r := range field {
+			if !unspacedScript(r) {
+				continue
+			}
+			if start < i {
+				words = append(words, field[start:i])
+			}
+			words = append(words, string(r))
+			start = i + len(string(r))
+		}
+		if start < len(field) {
+			words = append(words, field[start:])
+		}
+	}
+	return words
+}
+
+// unspacedScript reports whether r belongs to a script written without
+// spaces between words.
+func unspacedScript(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
+// WithMaxWordErrorRate fails jobs whose transcription, compared with
+// their Input.ReferenceText, has a word error rate over max, in
+// StagePostProcess with a *WordErrorRateError. Zero, the default, only
+// attaches the comparison to outputs.
+func WithMaxWordErrorRate(max float64) Option {
+	return func(s *Scriber) {
+		s.maxWordErrorRate = max
+	}
+}
+
+// WordErrorRateError is the error of jobs whose transcription differs
+// too much from their reference, see WithMaxWordErrorRate.
+type WordErrorRateError struct {
+	Comparison ComparisonResult
+	Max        float64
+}
+
+func (e *WordErrorRateError) Error() string {
+	return fmt.Sprintf("word error rate %.3f exceeds %.3f", e.Comparison.WER, e.Max)
+}
+
+// compareReference compares plain, the plain text of the job's
+// transcription, with the job's reference, if any. The reference is
+// redacted as the transcription is, so that masked words match.
+func (s *Scriber) compareReference(j *job, plain string) (*ComparisonResult, error) {
+	if j.in.ReferenceText == "" {
+		return nil, nil
+	}
+
+	ref, _ := s.redact([]byte(j.in.ReferenceText), OutputTypeTranscript)
+	res := CompareTranscripts(string(ref), plain)
+
+	j.logger.Info("Compared with reference",
+		slog.String("file", j.in.Name),
+		slog.Float64("wer", res.WER),
+		slog.Int("substitutions", res.Substitutions),
+		slog.Int("deletions", res.Deletions),
+		slog.Int("insertions", res.Insertions),
+	)
+
+	if s.maxWordErrorRate > 0 && res.WER > s.maxWordErrorRate {
+		return &res, &WordErrorRateError{Comparison: res, Max: s.maxWordErrorRate}
+	}
+	return &res, nil
+}
@@ -0,0 +1,177 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareTranscripts(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		givenReference string
+		givenCandidate string
+		expected       ComparisonResult
+	}{
+		{
+			name:           "identical",
+			givenReference: "the cat sat on the mat",
+			givenCandidate: "the cat sat on the mat",
+			expected:       ComparisonResult{ReferenceWords: 6},
+		},
+		{
+			name:           "case and punctuation",
+			givenReference: "Hello, World! How are you?",
+			givenCandidate: "hello world\nhow are you",
+			expected:       ComparisonResult{ReferenceWords: 5},
+		},
+		{
+			name:           "substitution and deletion",
+			givenReference: "the cat sat on the mat",
+			givenCandidate: "the cat sit on mat",
+			expected:       ComparisonResult{ReferenceWords: 6, Substitutions: 1, Deletions: 1, WER: 2.0 / 6},
+		},
+		{
+			name:           "insertion",
+			givenReference: "hello world",
+			givenCandidate: "hello big world",
+			expected:       ComparisonResult{ReferenceWords: 2, Insertions: 1, WER: 0.5},
+		},
+		{
+			name:           "swapped words",
+			givenReference: "the quick brown fox jumps over the lazy dog",
+			givenCandidate: "the quick brown dog jumps over the lazy fox",
+			expected:       ComparisonResult{ReferenceWords: 9, Substitutions: 2, WER: 2.0 / 9},
+		},
+		{
+			name:           "more insertions than words",
+			givenReference: "yes",
+			givenCandidate: "no no no",
+			expected:       ComparisonResult{ReferenceWords: 1, Substitutions: 1, Insertions: 2, WER: 3},
+		},
+		{
+			name:           "empty candidate",
+			givenReference: "a b c",
+			expected:       ComparisonResult{ReferenceWords: 3, Deletions: 3, WER: 1},
+		},
+		{
+			name:           "empty reference",
+			givenCandidate: "a b",
+			expected:       ComparisonResult{Insertions: 2, WER: 1},
+		},
+		{
+			name:     "both empty",
+			expected: ComparisonResult{},
+		},
+		{
+			name:           "japanese by character",
+			givenReference: "今日は良い天気です。",
+			givenCandidate: "今日は悪い天気です",
+			expected:       ComparisonResult{ReferenceWords: 9, Substitutions: 1, WER: 1.0 / 9},
+		},
+		{
+			name:           "chinese mixed with latin",
+			givenReference: "我喜欢Go语言",
+			givenCandidate: "我 喜欢 go 语言",
+			expected:       ComparisonResult{ReferenceWords: 6},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := CompareTranscripts(tc.givenReference, tc.givenCandidate)
+			assert.InDelta(t, tc.expected.WER, got.WER, 1e-9)
+			got.WER = tc.expected.WER
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestProcess_ReferenceText(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		givenRef      string
+		givenOpts     []Option
+		expectedWER   float64
+		expectedNoCmp bool
+		expectedErr   bool
+	}{
+		{
+			name:          "no reference",
+			expectedNoCmp: true,
+		},
+		{
+			name:        "within the threshold",
+			givenRef:    "Hello there. Write to jane@example.com.",
+			givenOpts:   []Option{WithMaxWordErrorRate(0.5)},
+			expectedWER: 1.0 / 7,
+		},
+		{
+			name:        "redacted like the transcription",
+			givenRef:    "Hello world. Write to jane@example.com.",
+			givenOpts:   []Option{WithRedactor(Redactor{Emails: true})},
+			expectedWER: 0,
+		},
+		{
+			name:        "over the threshold",
+			givenRef:    "Goodbye moon. Call me later.",
+			givenOpts:   []Option{WithMaxWordErrorRate(0.5)},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.Copy(io.Discard, in.Data)
+					return []byte("1\n00:00:00,000 --> 00:00:01,000\nHello world.\n\n2\n00:00:01,000 --> 00:00:02,000\nWrite to jane@example.com.\n"), err
+				},
+			}, append([]Option{WithConverter(copyConverter)}, tc.givenOpts...)...)
+
+			err := s.Process(context.TODO(), Input{
+				Name:          "talk.mp4",
+				OutputType:    OutputTypeSubtitles,
+				Language:      "en",
+				ReferenceText: tc.givenRef,
+				Data:          io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+			})
+
+			if tc.expectedErr {
+				var pe *ProcessError
+				require.ErrorAs(t, err, &pe)
+				assert.Equal(t, StagePostProcess, pe.Stage)
+
+				var werErr *WordErrorRateError
+				require.ErrorAs(t, err, &werErr)
+				assert.Greater(t, werErr.Comparison.WER, 0.5)
+				assert.Equal(t, 0.5, werErr.Max)
+				return
+			}
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+
+			if tc.expectedNoCmp {
+				assert.Nil(t, out.Comparison)
+				return
+			}
+			require.NotNil(t, out.Comparison)
+			assert.InDelta(t, tc.expectedWER, out.Comparison.WER, 1e-9)
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// defaultPriorityAging is how long a job waits for a slot
+// before its priority is raised by one level.
+const defaultPriorityAging = 30 * time.Second
+
+// WithMaxConcurrentConversions limits how many inputs are converted at once,
+// across all jobs. Zero, the default, means no limit.
+func WithMaxConcurrentConversions(n int) Option {
+	return func(s *Scriber) {
+		s.maxConversions = n
+	}
+}
+
+// WithMaxConcurrentTranscriptions limits how many uploads to the transcription
+// backend run at once, across all jobs, independently of conversions. A job
+// takes an upload slot once its first converted bytes are ready; until then,
+// its conversion is held back by the pipe to the upload, unless the audio is
+// spooled. Zero, the default, means no limit.
+func WithMaxConcurrentTranscriptions(n int) Option {
+	return func(s *Scriber) {
+		s.maxTranscriptions = n
+	}
+}
+
+// WithPriorityAging sets how long a job waits for a conversion or upload slot
+// before its priority is raised by one level, so that a steady stream of
+// high-priority jobs can't starve the others. It defaults to 30 seconds.
+// See Input.Priority.
+func WithPriorityAging(step time.Duration) Option {
+	return func(s *Scriber) {
+		s.priorityAging = step
+	}
+}
+
+type priorityCtxKey struct{}
+
+// withPriority attaches the priority of a job to ctx.
+func withPriority(ctx context.Context, priority int) context.Context {
+	return context.WithValue(ctx, priorityCtxKey{}, priority)
+}
+
+// priorityFromContext returns the priority attached to ctx, or zero.
+func priorityFromContext(ctx context.Context) int {
+	p, _ := ctx.Value(priorityCtxKey{}).(int)
+	return p
+}
+
+// semaphore bounds the number of concurrent holders. Waiters are served
+// by priority, taken from their context, then round-robin across tenants,
+// then in arrival order; waiting raises the priority by one level every
+// aging period. A nil semaphore has no bound.
+type semaphore struct {
+	size  int
+	aging time.Duration
+
+	mu      sync.Mutex
+	held    int
+	seq     uint64
+	waiters []*semaphoreWaiter
+
+	// served holds the turn each tenant was last served at,
+	// while there is contention.
+	turn   uint64
+	served map[string]uint64
+}
+
+// semaphoreWaiter is a caller of acquire waiting for a slot.
+type semaphoreWaiter struct {
+	priority int
+	tenant   string
+	since    time.Time
+	seq      uint64
+
+	// ready is closed once the slot is handed over.
+	ready chan struct{}
+}
+
+// newSemaphore returns a semaphore with n slots, or nil if n isn't positive.
+func newSemaphore(n int, aging time.Duration) *semaphore {
+	if n <= 0 {
+		return nil
+	}
+	if aging <= 0 {
+		aging = defaultPriorityAging
+	}
+	return &semaphore{size: n, aging: aging}
+}
+
+// acquire waits for a slot until ctx is done.
+// The returned function releases the slot.
+func (sem *semaphore) acquire(ctx context.Context) (func(), error) {
+	if sem == nil {
+		return func() {}, nil
+	}
+
+	tenant := tenantFromContext(ctx)
+
+	sem.mu.Lock()
+	if sem.held < sem.size && len(sem.waiters) == 0 {
+		sem.held++
+		sem.serve(tenant)
+		sem.mu.Unlock()
+		return sem.release, nil
+	}
+
+	sem.seq++
+	w := &semaphoreWaiter{
+		priority: priorityFromContext(ctx),
+		tenant:   tenant,
+		since:    time.Now(),
+		seq:      sem.seq,
+		ready:    make(chan struct{}),
+	}
+	sem.waiters = append(sem.waiters, w)
+	sem.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return sem.release, nil
+	case <-ctx.Done():
+	}
+
+	sem.mu.Lock()
+	select {
+	case <-w.ready:
+		// The slot was handed over as ctx was done: pass it on.
+		sem.mu.Unlock()
+		sem.release()
+	default:
+		sem.remove(w)
+		sem.mu.Unlock()
+	}
+	return nil, ctx.Err()
+}
+
+// release hands the slot over to the next waiter, or frees it.
+func (sem *semaphore) release() {
+	sem.mu.Lock()
+	defer sem.mu.Unlock()
+
+	if w := sem.next(time.Now()); w != nil {
+		sem.remove(w)
+		sem.serve(w.tenant)
+		close(w.ready)
+		return
+	}
+	sem.held--
+	if sem.held == 0 {
+		sem.served = nil
+	}
+}
+
+// serve records that tenant was handed a slot.
+func (sem *semaphore) serve(tenant string) {
+	if sem.served == nil {
+		sem.served = make(map[string]uint64)
+	}
+	sem.turn++
+	sem.served[tenant] = sem.turn
+}
+
+// next returns the waiter to serve at now, if any.
+func (sem *semaphore) next(now time.Time) *semaphoreWaiter {
+	var best *semaphoreWaiter
+	for _, w := range sem.waiters {
+		if best == nil || sem.before(w, best, now) {
+			best = w
+		}
+	}
+	return best
+}
+
+// before reports whether a is served before b at now.
+func (sem *semaphore) before(a, b *semaphoreWaiter, now time.Time) bool {
+	pa, pb := sem.effectivePriority(a, now), sem.effectivePriority(b, now)
+	if pa != pb {
+		return pa > pb
+	}
+	if ta, tb := sem.served[a.tenant], sem.served[b.tenant]; ta != tb {
+		return ta < tb
+	}
+	return a.seq < b.seq
+}
+
+// effectivePriority returns the priority of w, aged at now.
+func (sem *semaphore) effectivePriority(w *semaphoreWaiter, now time.Time) int {
+	return w.priority + int(now.Sub(w.since)/sem.aging)
+}
+
+func (sem *semaphore) remove(w *semaphoreWaiter) {
+	for i, other := range sem.waiters {
+		if other == w {
+			sem.waiters = append(sem.waiters[:i], sem.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// acquireWhenReady waits for data to be readable from r, then for a slot.
+// Waiting for data first keeps streamed uploads from holding a slot while
+// their conversion waits for one. It returns a reader yielding all of r.
+func (sem *semaphore) acquireWhenReady(ctx context.Context, r io.Reader) (io.Reader, func(), error) {
+	if sem == nil {
+		return r, func() {}, nil
+	}
+
+	var first [1]byte
+	n, err := io.ReadFull(r, first[:])
+	switch {
+	case err == io.EOF:
+		// Empty audio: let the backend reject it.
+	case err != nil:
+		return nil, nil, err
+	default:
+		r = io.MultiReader(bytes.NewReader(first[:n]), r)
+	}
+
+	release, err := sem.acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, release, nil
+}
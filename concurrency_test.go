@@ -0,0 +1,283 @@
+package scriber
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gauge tracks the current and maximum number of concurrent holders.
+type gauge struct {
+	cur, peak atomic.Int32
+}
+
+func (g *gauge) enter() {
+	n := g.cur.Add(1)
+	for {
+		peak := g.peak.Load()
+		if n <= peak || g.peak.CompareAndSwap(peak, n) {
+			return
+		}
+	}
+}
+
+func (g *gauge) leave() { g.cur.Add(-1) }
+
+func TestProcess_ConcurrencyLimits(t *testing.T) {
+	t.Parallel()
+
+	const (
+		jobs  = 6
+		delay = 100 * time.Millisecond
+	)
+
+	testCases := []struct {
+		name                   string
+		givenConversions       int
+		givenTranscriptions    int
+		expectedConversions    int32 // Reached exactly, since conversions start slowly.
+		expectedTranscriptions int32 // Never exceeded.
+	}{
+		{
+			name:                   "more conversions than uploads",
+			givenConversions:       3,
+			givenTranscriptions:    1,
+			expectedConversions:    3,
+			expectedTranscriptions: 1,
+		},
+		{
+			name:                   "more uploads than conversions",
+			givenConversions:       1,
+			givenTranscriptions:    3,
+			expectedConversions:    1,
+			expectedTranscriptions: 3,
+		},
+		{
+			name:                   "unlimited",
+			expectedConversions:    jobs,
+			expectedTranscriptions: jobs,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var conversions, transcriptions gauge
+
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					transcriptions.enter()
+					defer transcriptions.leave()
+
+					_, err := io.Copy(io.Discard, in.Data)
+					time.Sleep(delay)
+					return []byte("text"), err
+				},
+			},
+				WithMaxConcurrentConversions(tc.givenConversions),
+				WithMaxConcurrentTranscriptions(tc.givenTranscriptions),
+				WithConverter(func(_ context.Context, r io.Reader, w io.Writer) error {
+					conversions.enter()
+					defer conversions.leave()
+
+					// Slow to start, so conversions overlap whatever the uploads do.
+					time.Sleep(delay)
+					_, err := io.Copy(w, r)
+					return err
+				}),
+			)
+
+			go func() {
+				for out := range s.Collect() {
+					out.Body.Close()
+				}
+			}()
+
+			var wg sync.WaitGroup
+			for range jobs {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					err := s.Process(context.TODO(), Input{
+						Name:       "test.mp4",
+						OutputType: OutputTypeTranscript,
+						Language:   "en",
+						Data:       io.NopCloser(strings.NewReader("data")),
+					})
+					assert.NoError(t, err)
+				}()
+			}
+			wg.Wait()
+			require.NoError(t, s.Shutdown(context.TODO()))
+
+			assert.Equal(t, tc.expectedConversions, conversions.peak.Load(), "conversions")
+			assert.LessOrEqual(t, transcriptions.peak.Load(), tc.expectedTranscriptions, "transcriptions")
+		})
+	}
+}
+
+func TestSemaphore_AcquireWhenReady(t *testing.T) {
+	t.Parallel()
+
+	sem := newSemaphore(1, 0)
+
+	// The slot is only taken once data is readable.
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		r, release, err := sem.acquireWhenReady(context.TODO(), pr)
+		require.NoError(t, err)
+		defer release()
+
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "data", string(data))
+	}()
+
+	release, err := sem.acquire(context.TODO())
+	require.NoError(t, err)
+	release()
+
+	_, err = pw.Write([]byte("data"))
+	require.NoError(t, err)
+	pw.Close()
+	<-done
+
+	// Waiting for a slot stops with the context.
+	release, err = sem.acquire(context.TODO())
+	require.NoError(t, err)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	_, _, err = sem.acquireWhenReady(ctx, strings.NewReader("data"))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSemaphore_Priority(t *testing.T) {
+	t.Parallel()
+
+	sem := newSemaphore(1, time.Hour)
+
+	release, err := sem.acquire(context.TODO())
+	require.NoError(t, err)
+
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+
+	// Waiters queue up in turn while the slot is held.
+	waiters := []struct {
+		name     string
+		priority int
+	}{
+		{"backfill 1", 0},
+		{"interactive", 5},
+		{"batch", 2},
+		{"backfill 2", 0},
+		{"urgent", 5},
+	}
+	for i, w := range waiters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			release, err := sem.acquire(withPriority(context.TODO(), w.priority))
+			require.NoError(t, err)
+
+			mu.Lock()
+			order = append(order, w.name)
+			mu.Unlock()
+			release()
+		}()
+
+		require.Eventually(t, func() bool {
+			sem.mu.Lock()
+			defer sem.mu.Unlock()
+			return len(sem.waiters) == i+1
+		}, time.Second, time.Millisecond)
+	}
+
+	release()
+	wg.Wait()
+
+	assert.Equal(t, []string{"interactive", "urgent", "batch", "backfill 1", "backfill 2"}, order)
+}
+
+func TestSemaphore_Aging(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+
+	testCases := []struct {
+		name           string
+		givenHighAfter time.Duration // When the high-priority job arrives, after the low one.
+		expectedLow    bool
+	}{
+		{name: "high priority first", givenHighAfter: time.Minute},
+		{name: "low priority aged past it", givenHighAfter: 3 * time.Minute, expectedLow: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			sem := newSemaphore(1, time.Minute)
+
+			low := &semaphoreWaiter{priority: 0, since: start, seq: 1}
+			high := &semaphoreWaiter{priority: 2, since: start.Add(tc.givenHighAfter), seq: 2}
+			sem.waiters = []*semaphoreWaiter{low, high}
+
+			expected := high
+			if tc.expectedLow {
+				expected = low
+			}
+			assert.Same(t, expected, sem.next(start.Add(tc.givenHighAfter)))
+		})
+	}
+}
+
+func TestSemaphore_CanceledWaiter(t *testing.T) {
+	t.Parallel()
+
+	sem := newSemaphore(1, 0)
+
+	release, err := sem.acquire(context.TODO())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	done := make(chan error)
+	go func() {
+		_, err := sem.acquire(withPriority(ctx, 10))
+		done <- err
+	}()
+
+	require.Eventually(t, func() bool {
+		sem.mu.Lock()
+		defer sem.mu.Unlock()
+		return len(sem.waiters) == 1
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	// The canceled waiter left the queue, so the slot is free once released.
+	release()
+	release, err = sem.acquire(context.TODO())
+	require.NoError(t, err)
+	release()
+}
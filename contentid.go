@@ -0,0 +1,96 @@
+package scriber
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// ContentIDVersion is the version of the output format ContentID is
+// derived from. It is bumped whenever a change to scriber changes the
+// output of an unchanged input and configuration, such as a change to
+// post-processing, so that outputs made before and after the change
+// get different IDs.
+const ContentIDVersion = 1
+
+// WithContentID sets Output.ContentID, a deterministic ID downstream
+// indexers can deduplicate outputs by. The input data is read once to be
+// hashed before processing, which requires it to implement io.Seeker;
+// the outputs of other inputs, and of Reprocess, carry no ID.
+func WithContentID(enabled bool) Option {
+	return func(s *Scriber) {
+		s.contentIDs = enabled
+	}
+}
+
+// model returns the model the backend transcribes with, if known.
+func (s *Scriber) model() string {
+	if s.pricing == nil {
+		return ""
+	}
+	return s.pricing.Model
+}
+
+// hashInput sets the hash of the job's input data when content IDs or the
+// deduplication cache need it and the data can be rewound once hashed.
+func (s *Scriber) hashInput(j *job) error {
+	if !s.contentIDs && s.dedup == nil {
+		return nil
+	}
+	seeker, ok := j.in.Data.(io.ReadSeeker)
+	if !ok {
+		return nil
+	}
+
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("could not get data position: %w", err)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, seeker); err != nil {
+		return fmt.Errorf("could not hash data: %w", err)
+	}
+	if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+		return fmt.Errorf("could not rewind data: %w", err)
+	}
+	j.contentHash = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// contentID returns the content ID of the job's output, or an empty
+// string if content IDs are disabled or the input wasn't hashed.
+func (s *Scriber) contentID(j *job) string {
+	if !s.contentIDs || j.contentHash == "" {
+		return ""
+	}
+	return newContentID(j.contentHash, j.in.Language, j.in.OutputType, s.model(), s.derivation(j)...)
+}
+
+// derivation describes how the job's output is derived from the
+// transcription of its input, if it isn't the transcription itself:
+// translations are keyed on their source language and translator, so
+// that they don't pass for transcriptions in their language, and
+// normalized transcripts on the rules they were normalized by.
+func (s *Scriber) derivation(j *job) []string {
+	var d []string
+	if j.translatedFrom != "" {
+		d = append(d, "translated", j.translatedFrom, fmt.Sprintf("%T", s.translator))
+	}
+	if j.normalized {
+		d = append(d, "normalized", NormalizationVersion)
+	}
+	return d
+}
+
+// newContentID derives a content ID from its components. derivation is
+// empty for transcriptions, keeping their IDs unchanged.
+func newContentID(contentHash, lang string, t OutputType, model string, derivation ...string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "scriber/v%d\x00%s\x00%s\x00%s\x00%s", ContentIDVersion, contentHash, lang, t, model)
+	for _, d := range derivation {
+		fmt.Fprintf(h, "\x00%s", d)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
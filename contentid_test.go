@@ -0,0 +1,156 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewContentID(t *testing.T) {
+	t.Parallel()
+
+	id := newContentID("abc", "en", OutputTypeSubtitles, "whisper-1")
+
+	// The ID must not change across runs and machines; a change to it
+	// requires bumping ContentIDVersion.
+	assert.Equal(t, "745bf37f95ecd6f4d333cde85b82b9dc1e9328834d9982eeb367ed207bbecdfb", id)
+
+	testCases := []struct {
+		name  string
+		given string
+	}{
+		{name: "content", given: newContentID("abd", "en", OutputTypeSubtitles, "whisper-1")},
+		{name: "language", given: newContentID("abc", "pt", OutputTypeSubtitles, "whisper-1")},
+		{name: "output type", given: newContentID("abc", "en", OutputTypeTranscript, "whisper-1")},
+		{name: "model", given: newContentID("abc", "en", OutputTypeSubtitles, "whisper-2")},
+		{name: "shifted components", given: newContentID("abce", "n", OutputTypeSubtitles, "whisper-1")},
+		{name: "normalization", given: newContentID("abc", "en", OutputTypeSubtitles, "whisper-1", "normalized", NormalizationVersion)},
+		{name: "translation", given: newContentID("abc", "en", OutputTypeSubtitles, "whisper-1", "translated", "pt", "*scriber.mockTranslator")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.NotEqual(t, id, tc.given)
+		})
+	}
+}
+
+func TestProcess_ContentID(t *testing.T) {
+	t.Parallel()
+
+	process := func(t *testing.T, in Input, opts ...Option) Output {
+		t.Helper()
+
+		s := New(noopLogger(), &mockWhisperClient{
+			transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+				_, err := io.Copy(io.Discard, in.Data)
+				return []byte("hello"), err
+			},
+		}, append(opts, WithConverter(copyConverter))...)
+
+		require.NoError(t, s.Process(context.TODO(), in))
+
+		out := <-s.Collect()
+		require.NoError(t, out.Body.Close())
+		return out
+	}
+
+	newInput := func(name, lang string, data []byte) Input {
+		return Input{
+			Name:       name,
+			OutputType: OutputTypeTranscript,
+			Language:   lang,
+			Data:       readSeekNopCloser{bytes.NewReader(data)},
+		}
+	}
+
+	first := process(t, newInput("talk.mp4", "en", syntheticWAV(2)), WithContentID(true))
+	require.NotEmpty(t, first.ContentID)
+
+	t.Run("stable", func(t *testing.T) {
+		t.Parallel()
+
+		out := process(t, newInput("renamed.mp4", "en", syntheticWAV(2)), WithContentID(true))
+		assert.Equal(t, first.ContentID, out.ContentID)
+	})
+
+	t.Run("other data", func(t *testing.T) {
+		t.Parallel()
+
+		out := process(t, newInput("talk.mp4", "en", syntheticWAV(3)), WithContentID(true))
+		assert.NotEmpty(t, out.ContentID)
+		assert.NotEqual(t, first.ContentID, out.ContentID)
+	})
+
+	t.Run("other language", func(t *testing.T) {
+		t.Parallel()
+
+		out := process(t, newInput("talk.mp4", "pt", syntheticWAV(2)), WithContentID(true))
+		assert.NotEmpty(t, out.ContentID)
+		assert.NotEqual(t, first.ContentID, out.ContentID)
+	})
+
+	t.Run("other model", func(t *testing.T) {
+		t.Parallel()
+
+		out := process(t, newInput("talk.mp4", "en", syntheticWAV(2)),
+			WithContentID(true),
+			WithPricing(Pricing{Model: "whisper-1"}),
+		)
+		assert.NotEmpty(t, out.ContentID)
+		assert.NotEqual(t, first.ContentID, out.ContentID)
+	})
+
+	t.Run("translation", func(t *testing.T) {
+		t.Parallel()
+
+		translator := &mockTranslator{translateFunc: prefixSegments}
+		s := New(noopLogger(), &mockWhisperClient{
+			transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+				_, err := io.Copy(io.Discard, in.Data)
+				return []byte("hello"), err
+			},
+		},
+			WithConverter(copyConverter),
+			WithContentID(true),
+			WithTranslation(translator, TranslationConfig{Languages: []string{"pt"}}),
+		)
+		require.NoError(t, s.Process(context.TODO(), newInput("talk.mp4", "en", syntheticWAV(2))))
+
+		original := <-s.Collect()
+		require.NoError(t, original.Body.Close())
+		translated := <-s.Collect()
+		require.NoError(t, translated.Body.Close())
+		assert.Equal(t, first.ContentID, original.ContentID)
+
+		// A machine translation into pt isn't a transcription in pt.
+		direct := process(t, newInput("talk.mp4", "pt", syntheticWAV(2)), WithContentID(true))
+		assert.NotEmpty(t, translated.ContentID)
+		assert.NotEqual(t, direct.ContentID, translated.ContentID)
+		assert.NotEqual(t, original.ContentID, translated.ContentID)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		out := process(t, newInput("talk.mp4", "en", syntheticWAV(2)))
+		assert.Empty(t, out.ContentID)
+	})
+
+	t.Run("unseekable data", func(t *testing.T) {
+		t.Parallel()
+
+		in := newInput("talk.mp4", "en", nil)
+		in.Data = io.NopCloser(bytes.NewReader(syntheticWAV(2)))
+
+		out := process(t, in, WithContentID(true))
+		assert.Empty(t, out.ContentID)
+	})
+}
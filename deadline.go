@@ -0,0 +1,80 @@
+package scriber
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WithMinTranscriptionTime sets the least time the backend takes to
+// transcribe a minute of audio. Jobs with an Input.Deadline don't start
+// a transcription request expected to take longer than the time left,
+// given the duration of its audio when known. It defaults to zero, which
+// only refuses requests once the deadline has passed.
+func WithMinTranscriptionTime(perAudioMinute time.Duration) Option {
+	return func(s *Scriber) {
+		s.minTranscriptionTime = perAudioMinute
+	}
+}
+
+// DeadlineBudgetError is returned for jobs that didn't start a stage
+// because too little time was left before their Input.Deadline. It wraps
+// a DeadlineExceededError.
+type DeadlineBudgetError struct {
+	// Stage is the stage that wasn't started.
+	Stage Stage
+
+	// Remaining is the time that was left before the deadline.
+	Remaining time.Duration
+
+	// Required is the least time the stage was expected to take.
+	Required time.Duration
+}
+
+func (e *DeadlineBudgetError) Error() string {
+	return fmt.Sprintf("%s: %s left, %s needs at least %s", errDeadlineExceeded, e.Remaining, e.Stage, e.Required)
+}
+
+func (e *DeadlineBudgetError) Unwrap() error { return errDeadlineExceeded }
+
+// withDeadline bounds ctx by the deadline of in, if any, canceling it
+// with a DeadlineExceededError.
+func withDeadline(ctx context.Context, in Input) (context.Context, context.CancelFunc) {
+	if in.Deadline.IsZero() {
+		return ctx, func() {}
+	}
+	return context.WithDeadlineCause(ctx, in.Deadline, errDeadlineExceeded)
+}
+
+// checkDeadline fails if the job has less than required left before its
+// deadline to go through stage, or none at all.
+func (s *Scriber) checkDeadline(j *job, stage Stage, required time.Duration) error {
+	if j.in.Deadline.IsZero() {
+		return nil
+	}
+
+	remaining := j.in.Deadline.Sub(s.clock())
+	if remaining > 0 && remaining >= required {
+		return nil
+	}
+	return stageError(stage, &DeadlineBudgetError{Stage: stage, Remaining: max(remaining, 0), Required: required})
+}
+
+// minTranscriptionTimeFor returns the least time transcribing audio is
+// expected to take, from its duration or else the probed duration of
+// the job's input.
+func (s *Scriber) minTranscriptionTimeFor(j *job, audio func() time.Duration) time.Duration {
+	d := j.probedDuration
+	if audio != nil {
+		d = max(d, audio())
+	}
+	return time.Duration(d.Minutes() * float64(s.minTranscriptionTime))
+}
+
+// clock returns the current time.
+func (s *Scriber) clock() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
@@ -0,0 +1,144 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_Deadline(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name              string
+		givenRemaining    time.Duration // Time left before the deadline, on the fake clock.
+		givenNoDeadline   bool
+		givenOpts         []Option
+		expectedStage     Stage
+		expectedRemaining time.Duration
+		expectedRequired  time.Duration
+		expectedConverted bool
+	}{
+		{
+			name:              "no deadline",
+			givenNoDeadline:   true,
+			givenOpts:         []Option{WithMinTranscriptionTime(time.Hour)},
+			expectedConverted: true,
+		},
+		{
+			name:              "within budget",
+			givenRemaining:    time.Minute,
+			givenOpts:         []Option{WithMinTranscriptionTime(5 * time.Second)},
+			expectedConverted: true,
+		},
+		{
+			name:           "deadline passed",
+			givenRemaining: -time.Second,
+			expectedStage:  StageConversion,
+		},
+		{
+			name:              "transcription longer than the budget",
+			givenRemaining:    5 * time.Second,
+			givenOpts:         []Option{WithMinTranscriptionTime(time.Minute)},
+			expectedStage:     StageTranscription,
+			expectedRemaining: 5 * time.Second,
+			expectedRequired:  10 * time.Second,
+			expectedConverted: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var converted, transcribed atomic.Bool
+			opts := append([]Option{
+				WithConverter(func(_ context.Context, r io.Reader, w io.Writer) error {
+					converted.Store(true)
+					_, err := io.Copy(w, r)
+					return err
+				}),
+				WithChunking(ChunkConfig{Length: 10 * time.Second}),
+			}, tc.givenOpts...)
+
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					transcribed.Store(true)
+					_, err := io.Copy(io.Discard, in.Data)
+					return []byte("hello"), err
+				},
+			}, opts...)
+
+			// The job's context runs on the real clock: the deadline is far
+			// enough for it not to expire, while the fake clock sets the
+			// time left as seen by the budget checks.
+			deadline := time.Now().Add(time.Hour)
+			s.now = func() time.Time { return deadline.Add(-tc.givenRemaining) }
+
+			in := Input{
+				Name:       "talk.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(syntheticWAV(30))),
+			}
+			if !tc.givenNoDeadline {
+				in.Deadline = deadline
+			}
+
+			err := s.Process(context.TODO(), in)
+			assert.Equal(t, tc.expectedConverted, converted.Load())
+
+			if tc.expectedStage == "" {
+				require.NoError(t, err)
+				out := <-s.Collect()
+				require.NoError(t, out.Body.Close())
+				return
+			}
+
+			var pe *ProcessError
+			require.ErrorAs(t, err, &pe)
+			assert.Equal(t, tc.expectedStage, pe.Stage)
+
+			var be *DeadlineBudgetError
+			require.ErrorAs(t, err, &be)
+			assert.Equal(t, tc.expectedStage, be.Stage)
+			assert.Equal(t, tc.expectedRemaining, be.Remaining)
+			assert.Equal(t, tc.expectedRequired, be.Required)
+			assert.ErrorIs(t, err, errDeadlineExceeded)
+			assert.False(t, transcribed.Load(), "no transcription should start")
+		})
+	}
+}
+
+func TestProcess_DeadlineCancelsJob(t *testing.T) {
+	t.Parallel()
+
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}, WithConverter(copyConverter))
+
+	err := s.Process(context.TODO(), Input{
+		Name:       "talk.mp4",
+		OutputType: OutputTypeTranscript,
+		Language:   "en",
+		Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+		Deadline:   time.Now().Add(100 * time.Millisecond),
+	})
+
+	var pe *ProcessError
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, StageTranscription, pe.Stage)
+
+	var de DeadlineExceededError
+	assert.ErrorAs(t, err, &de)
+}
@@ -0,0 +1,172 @@
+package scriber
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// WithDedupCache remembers the transcriptions of the last size inputs
+// for ttl, so that an input submitted again within ttl, such as a file
+// uploaded twice by mistake, is published without being converted or
+// transcribed again. Inputs are keyed by a hash of their data along
+// with their output type, language, and the model set with WithPricing,
+// whatever their name. Post-processing runs again on each hit, and the
+// Output is marked Deduplicated. The data must implement io.Seeker, as
+// it is read once to be hashed; other inputs, and inputs transcribed in
+// several languages, bypass the cache. See DedupStats.
+func WithDedupCache(size int, ttl time.Duration) Option {
+	return func(s *Scriber) {
+		s.dedup = newDedupCache(size, ttl)
+	}
+}
+
+// DedupStats counts the lookups of the deduplication cache.
+type DedupStats struct {
+	// Hits is the number of inputs published from the cache.
+	Hits int64
+
+	// Misses is the number of inputs the cache couldn't serve.
+	Misses int64
+
+	// Entries is the number of transcriptions the cache holds.
+	Entries int
+}
+
+// DedupStats returns the lookups of the deduplication cache since s was
+// created. It returns zero stats unless WithDedupCache is set.
+func (s *Scriber) DedupStats() DedupStats {
+	if s.dedup == nil {
+		return DedupStats{}
+	}
+	return s.dedup.stats()
+}
+
+// dedupEntry is a transcription held by the deduplication cache.
+type dedupEntry struct {
+	key     string
+	text    []byte
+	lang    string
+	audio   time.Duration
+	expires time.Time
+}
+
+// dedupCache is a least recently used cache of transcriptions.
+type dedupCache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List // Most recently used first.
+	entries map[string]*list.Element
+	hits    int64
+	misses  int64
+}
+
+func newDedupCache(size int, ttl time.Duration) *dedupCache {
+	return &dedupCache{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the transcription held under key, if it hasn't expired.
+func (c *dedupCache) get(key string) (dedupEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if ok && !c.now().Before(el.Value.(*dedupEntry).expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		ok = false
+	}
+	if !ok {
+		c.misses++
+		return dedupEntry{}, false
+	}
+
+	c.hits++
+	c.order.MoveToFront(el)
+	return *el.Value.(*dedupEntry), true
+}
+
+// add holds e, evicting the least recently used entry if the cache is full.
+func (c *dedupCache) add(e dedupEntry) {
+	if c.size <= 0 || c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e.expires = c.now().Add(c.ttl)
+	if el, ok := c.entries[e.key]; ok {
+		el.Value = &e
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[e.key] = c.order.PushFront(&e)
+	for c.order.Len() > c.size {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.entries, last.Value.(*dedupEntry).key)
+	}
+}
+
+func (c *dedupCache) stats() DedupStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return DedupStats{Hits: c.hits, Misses: c.misses, Entries: c.order.Len()}
+}
+
+// dedupKey returns the key of the job's transcription, derived from the
+// hash of its input data and the settings its transcription depends on.
+// It returns an empty key if the job bypasses the cache.
+func (s *Scriber) dedupKey(j *job) string {
+	if s.dedup == nil || j.contentHash == "" || len(j.in.Languages) > 0 {
+		return ""
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s", j.in.OutputType, j.in.Language, s.model(), j.contentHash)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// completeFromDedup publishes the transcription held under key for the
+// job, reporting whether there was one.
+func (s *Scriber) completeFromDedup(ctx context.Context, j *job, key string) (bool, error) {
+	if key == "" {
+		return false, nil
+	}
+
+	e, ok := s.dedup.get(key)
+	if !ok {
+		return false, nil
+	}
+
+	j.logger.Info("Publishing deduplicated transcription", slog.String("file", j.in.Name))
+
+	j.deduplicated = true
+	j.in.Language = e.lang
+	j.audioDuration = e.audio
+	return true, s.complete(ctx, j, e.text)
+}
+
+// cacheTranscription holds the job's transcription under key.
+func (s *Scriber) cacheTranscription(j *job, key string) {
+	if key == "" {
+		return
+	}
+	s.dedup.add(dedupEntry{key: key, text: j.raw, lang: j.in.Language, audio: j.audioDuration})
+}
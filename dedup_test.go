@@ -0,0 +1,209 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("expiry", func(t *testing.T) {
+		t.Parallel()
+
+		clock := &fakeClock{now: time.Date(2024, time.March, 15, 10, 0, 0, 0, time.UTC)}
+		c := newDedupCache(2, 5*time.Minute)
+		c.now = clock.Now
+
+		c.add(dedupEntry{key: "a", text: []byte("hello")})
+
+		clock.Advance(4 * time.Minute)
+		e, ok := c.get("a")
+		require.True(t, ok)
+		assert.Equal(t, "hello", string(e.text))
+
+		clock.Advance(time.Minute)
+		_, ok = c.get("a")
+		assert.False(t, ok)
+
+		assert.Equal(t, DedupStats{Hits: 1, Misses: 1}, c.stats())
+	})
+
+	t.Run("eviction", func(t *testing.T) {
+		t.Parallel()
+
+		c := newDedupCache(2, time.Hour)
+
+		c.add(dedupEntry{key: "a"})
+		c.add(dedupEntry{key: "b"})
+
+		// Using a makes b the least recently used.
+		_, ok := c.get("a")
+		require.True(t, ok)
+
+		c.add(dedupEntry{key: "c"})
+
+		_, ok = c.get("b")
+		assert.False(t, ok)
+		_, ok = c.get("a")
+		assert.True(t, ok)
+		_, ok = c.get("c")
+		assert.True(t, ok)
+
+		assert.Equal(t, DedupStats{Hits: 3, Misses: 1, Entries: 2}, c.stats())
+	})
+
+	t.Run("concurrent use", func(t *testing.T) {
+		t.Parallel()
+
+		c := newDedupCache(8, time.Hour)
+
+		var wg sync.WaitGroup
+		for i := range 16 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				key := fmt.Sprint(i % 10)
+				c.add(dedupEntry{key: key})
+				c.get(key)
+			}()
+		}
+		wg.Wait()
+
+		stats := c.stats()
+		assert.Equal(t, int64(16), stats.Hits+stats.Misses)
+		assert.LessOrEqual(t, stats.Entries, 8)
+	})
+}
+
+func TestProcess_DedupCache(t *testing.T) {
+	t.Parallel()
+
+	first := Input{Name: "talk.mp4", OutputType: OutputTypeTranscript, Language: "en"}
+
+	testCases := []struct {
+		name          string
+		givenSecond   Input
+		givenData     []byte
+		expectedCalls int32
+		expectedStats DedupStats
+	}{
+		{
+			name:          "identical input",
+			givenSecond:   Input{Name: "copy.mp4", OutputType: OutputTypeTranscript, Language: "en"},
+			expectedCalls: 1,
+			expectedStats: DedupStats{Hits: 1, Misses: 1, Entries: 1},
+		},
+		{
+			name:          "other data",
+			givenSecond:   first,
+			givenData:     syntheticWAV(3),
+			expectedCalls: 2,
+			expectedStats: DedupStats{Misses: 2, Entries: 2},
+		},
+		{
+			name:          "other language",
+			givenSecond:   Input{Name: "talk.mp4", OutputType: OutputTypeTranscript, Language: "pt"},
+			expectedCalls: 2,
+			expectedStats: DedupStats{Misses: 2, Entries: 2},
+		},
+		{
+			name:          "other output type",
+			givenSecond:   Input{Name: "talk.mp4", OutputType: OutputTypeSubtitles, Language: "en"},
+			expectedCalls: 2,
+			expectedStats: DedupStats{Misses: 2, Entries: 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls atomic.Int32
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					calls.Add(1)
+					if _, err := io.Copy(io.Discard, in.Data); err != nil {
+						return nil, err
+					}
+					return []byte("1\n00:00:00,000 --> 00:00:01,000\nhello\n"), nil
+				},
+			},
+				WithDedupCache(10, time.Hour),
+				WithConverter(copyConverter),
+			)
+
+			in := first
+			in.Data = readSeekNopCloser{bytes.NewReader(syntheticWAV(2))}
+			require.NoError(t, s.Process(context.TODO(), in))
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+			assert.False(t, out.Deduplicated)
+
+			data := tc.givenData
+			if data == nil {
+				data = syntheticWAV(2)
+			}
+			in = tc.givenSecond
+			in.Data = readSeekNopCloser{bytes.NewReader(data)}
+			require.NoError(t, s.Process(context.TODO(), in))
+
+			out = <-s.Collect()
+			require.NoError(t, out.Body.Close())
+
+			assert.Equal(t, tc.expectedCalls, calls.Load())
+			assert.Equal(t, tc.expectedStats, s.DedupStats())
+			assert.Equal(t, tc.expectedCalls == 1, out.Deduplicated)
+			assert.Equal(t, in.Language, out.Language)
+
+			if out.Deduplicated {
+				assert.Equal(t, "copy.txt", out.Name)
+				assert.Equal(t, 2*time.Second, out.AudioDuration)
+
+				// Only the first transcription is billed.
+				assert.Equal(t, 2*time.Second, s.Stats().Audio)
+			}
+		})
+	}
+}
+
+func TestProcess_DedupCacheUnseekable(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			calls.Add(1)
+			_, err := io.Copy(io.Discard, in.Data)
+			return []byte("hello"), err
+		},
+	},
+		WithDedupCache(10, time.Hour),
+		WithConverter(copyConverter),
+	)
+
+	for range 2 {
+		require.NoError(t, s.Process(context.TODO(), Input{
+			Name:       "talk.mp4",
+			OutputType: OutputTypeTranscript,
+			Language:   "en",
+			Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+		}))
+		out := <-s.Collect()
+		require.NoError(t, out.Body.Close())
+	}
+
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, DedupStats{}, s.DedupStats())
+}
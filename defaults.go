@@ -0,0 +1,53 @@
+package scriber
+
+// WithDefaultLanguage sets the language used for inputs that don't set one.
+func WithDefaultLanguage(lang string) Option {
+	return func(s *Scriber) {
+		s.inputDefaults.Language = lang
+	}
+}
+
+// WithDefaultOutputType sets the output type used for inputs that don't set one.
+func WithDefaultOutputType(t OutputType) Option {
+	return func(s *Scriber) {
+		s.inputDefaults.OutputType = t
+	}
+}
+
+// WithInputDefaults sets defaults for the OutputType, Language, UserTag, and
+// KeepOpen fields of inputs. Zero-valued fields of d are ignored, so it can be combined
+// with WithDefaultLanguage and WithDefaultOutputType. Since KeepOpen is a bool,
+// a default of true can't be overridden per input.
+func WithInputDefaults(d Input) Option {
+	return func(s *Scriber) {
+		if d.OutputType != "" {
+			s.inputDefaults.OutputType = d.OutputType
+		}
+		if d.Language != "" {
+			s.inputDefaults.Language = d.Language
+		}
+		if d.UserTag != "" {
+			s.inputDefaults.UserTag = d.UserTag
+		}
+		if d.KeepOpen {
+			s.inputDefaults.KeepOpen = true
+		}
+	}
+}
+
+// applyDefaults fills the zero-valued fields of in with the configured defaults.
+func (s *Scriber) applyDefaults(in Input) Input {
+	if in.OutputType == "" {
+		in.OutputType = s.inputDefaults.OutputType
+	}
+	if in.Language == "" && len(in.Languages) == 0 {
+		in.Language = s.inputDefaults.Language
+	}
+	if in.UserTag == "" {
+		in.UserTag = s.inputDefaults.UserTag
+	}
+	if s.inputDefaults.KeepOpen {
+		in.KeepOpen = true
+	}
+	return in
+}
@@ -0,0 +1,111 @@
+package scriber
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_InputDefaults(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		givenOpts        []Option
+		givenInput       Input
+		expectedLanguage string
+		expectedFormat   string
+		expectedErr      error  // Nil when the job succeeds.
+		expectedMessage  string // Substring of the error message.
+	}{
+		{
+			name:             "defaults applied",
+			givenOpts:        []Option{WithDefaultLanguage("pt"), WithDefaultOutputType(OutputTypeSubtitles)},
+			givenInput:       Input{Name: "test.mp4"},
+			expectedLanguage: "pt",
+			expectedFormat:   whisperclient.FormatSrt,
+		},
+		{
+			name:             "explicit values win",
+			givenOpts:        []Option{WithDefaultLanguage("pt"), WithDefaultOutputType(OutputTypeSubtitles)},
+			givenInput:       Input{Name: "test.mp4", Language: "en", OutputType: OutputTypeTranscript},
+			expectedLanguage: "en",
+			expectedFormat:   whisperclient.FormatText,
+		},
+		{
+			name: "input defaults merge with other options",
+			givenOpts: []Option{
+				WithDefaultLanguage("pt"),
+				WithInputDefaults(Input{OutputType: OutputTypeTranscript}),
+			},
+			givenInput:       Input{Name: "test.mp4"},
+			expectedLanguage: "pt",
+			expectedFormat:   whisperclient.FormatText,
+		},
+		{
+			name:        "required field still missing",
+			givenOpts:   []Option{WithDefaultOutputType(OutputTypeSubtitles)},
+			givenInput:  Input{Name: "test.mp4"},
+			expectedErr: errorLanguage,
+		},
+		{
+			name:            "invalid default reported",
+			givenOpts:       []Option{WithDefaultLanguage("pt"), WithDefaultOutputType("lyrics")},
+			givenInput:      Input{Name: "test.mp4"},
+			expectedErr:     errorOutputType,
+			expectedMessage: `"lyrics"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got whisperclient.TranscribeAudioInput
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					got = in
+					_, err := io.Copy(io.Discard, in.Data)
+					return []byte("1\n00:00:00,000 --> 00:00:01,000\nhi\n"), err
+				},
+			}
+
+			opts := append([]Option{WithConverter(copyConverter)}, tc.givenOpts...)
+			s := New(noopLogger(), mockClient, opts...)
+
+			in := tc.givenInput
+			in.Data = io.NopCloser(strings.NewReader("data"))
+
+			err := s.Process(context.TODO(), in)
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+				assert.Contains(t, err.Error(), tc.expectedMessage)
+				return
+			}
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			out.Body.Close()
+
+			assert.Equal(t, tc.expectedLanguage, got.Language)
+			assert.Equal(t, tc.expectedFormat, got.Format)
+			assert.Equal(t, tc.expectedLanguage, out.Language)
+		})
+	}
+}
+
+func TestProcess_DefaultKeepOpen(t *testing.T) {
+	t.Parallel()
+
+	s := New(noopLogger(), &mockWhisperClient{}, WithInputDefaults(Input{KeepOpen: true}))
+
+	data := &closeCounter{Reader: strings.NewReader("data")}
+	err := s.Process(context.TODO(), Input{Name: "test", Data: data})
+	require.Error(t, err)
+	assert.Zero(t, data.closes.Load())
+}
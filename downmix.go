@@ -0,0 +1,204 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"strings"
+)
+
+// MetadataDownmixFilter is the Output.Metadata key holding the ffmpeg
+// filter a surround input was downmixed with. See WithDialogDownmix.
+const MetadataDownmixFilter = "downmix_filter"
+
+// surroundGain is the gain of the channels other than the
+// center one, where the dialog is, in a dialog downmix.
+const surroundGain = "0.3"
+
+// layoutChannels maps the surround layouts, as named by ffprobe, that
+// have a center channel to their channels, in ffmpeg's order.
+var layoutChannels = map[string][]string{
+	"3.0":            {"FL", "FR", "FC"},
+	"3.1":            {"FL", "FR", "FC", "LFE"},
+	"4.0":            {"FL", "FR", "FC", "BC"},
+	"4.1":            {"FL", "FR", "FC", "LFE", "BC"},
+	"5.0":            {"FL", "FR", "FC", "BL", "BR"},
+	"5.0(side)":      {"FL", "FR", "FC", "SL", "SR"},
+	"5.1":            {"FL", "FR", "FC", "LFE", "BL", "BR"},
+	"5.1(side)":      {"FL", "FR", "FC", "LFE", "SL", "SR"},
+	"6.1":            {"FL", "FR", "FC", "LFE", "BC", "SL", "SR"},
+	"7.1":            {"FL", "FR", "FC", "LFE", "BL", "BR", "SL", "SR"},
+	"7.1(wide)":      {"FL", "FR", "FC", "LFE", "BL", "BR", "FLC", "FRC"},
+	"7.1(wide-side)": {"FL", "FR", "FC", "LFE", "FLC", "FRC", "SL", "SR"},
+}
+
+// channelLayout is the channel layout of the first audio stream of an input.
+type channelLayout struct {
+	// name is the layout's name, e.g. "5.1(side)", or empty if unknown.
+	name     string
+	channels int
+}
+
+// probeLayoutFunc is a function that probes the channel layout of media data.
+type probeLayoutFunc func(ctx context.Context, r io.Reader) (channelLayout, error)
+
+var errNoAudioStream = errors.New("no audio stream")
+
+// WithDialogDownmix downmixes inputs with more than two channels, whose
+// dialog is usually in the center channel that ffmpeg's default downmix
+// mixes with everything else, with a filter that boosts the center
+// channel. The channel layout of seekable inputs is probed before
+// converting them; stereo and mono inputs are untouched, and so are
+// inputs that aren't seekable.
+//
+// The filter used is recorded in Output.Metadata[MetadataDownmixFilter]
+// along with a WarningDownmixed warning. Surround inputs whose layout has
+// no known center channel are left to ffmpeg's default downmix, which is
+// also reported as a warning. It has no effect with WithConverter.
+func WithDialogDownmix(enabled bool) Option {
+	return func(s *Scriber) {
+		s.dialogDownmix = enabled
+	}
+}
+
+// newFFprobeLayoutProber returns the default layout prober,
+// which reads the layout of the first audio stream with ffprobe.
+func newFFprobeLayoutProber() probeLayoutFunc {
+	return func(ctx context.Context, r io.Reader) (channelLayout, error) {
+		cmd := exec.CommandContext(ctx,
+			"ffprobe",
+			"-v", "error",
+			"-select_streams", "a:0",
+			"-show_entries", "stream=channels,channel_layout",
+			"-of", "json",
+			"pipe:0",
+		)
+
+		var stdout, stderr bytes.Buffer
+		cmd.Stdin = r
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			return channelLayout{}, fmt.Errorf("ffprobe failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return parseChannelLayout(stdout.Bytes())
+	}
+}
+
+// parseChannelLayout parses the JSON output of ffprobe
+// showing the channels and layout of an audio stream.
+func parseChannelLayout(data []byte) (channelLayout, error) {
+	var probe struct {
+		Streams []struct {
+			Channels      int    `json:"channels"`
+			ChannelLayout string `json:"channel_layout"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return channelLayout{}, fmt.Errorf("could not parse ffprobe output: %w", err)
+	}
+	if len(probe.Streams) == 0 {
+		return channelLayout{}, errNoAudioStream
+	}
+
+	stream := probe.Streams[0]
+	return channelLayout{name: stream.ChannelLayout, channels: stream.Channels}, nil
+}
+
+// downmixFilter returns the ffmpeg filter downmixing the layout to
+// channels, one or two, keeping the center channel at full gain and
+// dropping the LFE one. It returns false if the layout needs no downmix,
+// or has no known center channel.
+func downmixFilter(layout channelLayout, channels int) (string, bool) {
+	if layout.channels <= 2 || channels < 1 || channels > 2 {
+		return "", false
+	}
+
+	names, ok := layoutChannels[layout.name]
+	if !ok || len(names) != layout.channels {
+		return "", false
+	}
+
+	var mono, left, right []string
+	for _, name := range names {
+		term := surroundGain + "*" + name
+		switch name {
+		case "FC":
+			term = name
+			left, right = append(left, term), append(right, term)
+		case "LFE":
+			continue
+		case "FL", "BL", "SL", "FLC":
+			left = append(left, term)
+		case "FR", "BR", "SR", "FRC":
+			right = append(right, term)
+		default:
+			left, right = append(left, term), append(right, term)
+		}
+		mono = append(mono, term)
+	}
+
+	// "<" renormalizes the gains, so the mix doesn't clip.
+	if channels == 1 {
+		return "pan=mono|c0<" + strings.Join(mono, "+"), true
+	}
+	return "pan=stereo|FL<" + strings.Join(left, "+") + "|FR<" + strings.Join(right, "+"), true
+}
+
+// probeDownmix probes the channel layout of the job's input, when enabled
+// with WithDialogDownmix, and has surround audio converted with a dialog
+// downmix. Failures are reported as warnings, and only returned if escalated.
+func (s *Scriber) probeDownmix(ctx context.Context, j *job) error {
+	if !s.dialogDownmix || s.ffmpeg == nil {
+		return nil
+	}
+
+	var layout channelLayout
+	err := rewindAfter(j.in, func(r io.Reader) (err error) {
+		layout, err = s.probeLayout(ctx, r)
+		if err != nil {
+			return fmt.Errorf("could not probe channel layout: %w", err)
+		}
+		return nil
+	})
+	if errors.Is(err, errSeekableRequired) {
+		j.logger.Debug("Input not seekable, skipping channel layout probe", slog.String("file", j.in.Name))
+		return nil
+	}
+	if err != nil {
+		if werr := j.warn(WarningLayoutProbeFailed, err.Error()); werr != nil {
+			return werr
+		}
+		j.logger.Warn("Could not probe channel layout", slog.String("file", j.in.Name), slog.String("error", err.Error()))
+		return nil
+	}
+
+	if layout.channels <= 2 {
+		return nil
+	}
+
+	filter, ok := downmixFilter(layout, s.ffmpeg.channels)
+	if !ok {
+		if s.ffmpeg.channels <= 0 || layout.channels <= s.ffmpeg.channels {
+			return nil
+		}
+		msg := fmt.Sprintf("%d-channel input with layout %q downmixed by ffmpeg's default, which may bury the dialog", layout.channels, layout.name)
+		return j.warn(WarningDownmixed, msg)
+	}
+
+	msg := fmt.Sprintf("%d-channel input with layout %q downmixed with %s", layout.channels, layout.name, filter)
+	if err := j.warn(WarningDownmixed, msg); err != nil {
+		return err
+	}
+	j.logger.Info("Downmixing surround input", slog.String("file", j.in.Name), slog.String("layout", layout.name), slog.String("filter", filter))
+
+	j.downmixFilter = filter
+	j.ffmpegArgs = s.ffmpegArgsFor(ffmpegInput{codec: j.codec, filter: filter})
+	return nil
+}
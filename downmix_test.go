@@ -0,0 +1,290 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ffprobe output for the first audio stream of a 5.1, a 7.1,
+// and a stereo input, and of an input without audio.
+const (
+	probed51 = `{
+    "programs": [],
+    "streams": [
+        {
+            "channels": 6,
+            "channel_layout": "5.1(side)"
+        }
+    ]
+}`
+	probed71 = `{
+    "programs": [],
+    "streams": [
+        {
+            "channels": 8,
+            "channel_layout": "7.1"
+        }
+    ]
+}`
+	probedStereo = `{
+    "programs": [],
+    "streams": [
+        {
+            "channels": 2,
+            "channel_layout": "stereo"
+        }
+    ]
+}`
+	probedHexagonal = `{
+    "programs": [],
+    "streams": [
+        {
+            "channels": 6,
+            "channel_layout": "hexagonal"
+        }
+    ]
+}`
+	probedNoAudio = `{
+    "programs": [],
+    "streams": []
+}`
+)
+
+func TestParseChannelLayout(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		given         string
+		expected      channelLayout
+		expectedError error
+	}{
+		{name: "5.1", given: probed51, expected: channelLayout{name: "5.1(side)", channels: 6}},
+		{name: "7.1", given: probed71, expected: channelLayout{name: "7.1", channels: 8}},
+		{name: "stereo", given: probedStereo, expected: channelLayout{name: "stereo", channels: 2}},
+		{name: "no audio", given: probedNoAudio, expectedError: errNoAudioStream},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			layout, err := parseChannelLayout([]byte(tc.given))
+			require.ErrorIs(t, err, tc.expectedError)
+			assert.Equal(t, tc.expected, layout)
+		})
+	}
+
+	t.Run("not json", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := parseChannelLayout([]byte("6"))
+		require.Error(t, err)
+	})
+}
+
+func TestDownmixFilter(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		givenLayout   channelLayout
+		givenChannels int
+		expected      string
+	}{
+		{
+			name:          "5.1 to stereo",
+			givenLayout:   channelLayout{name: "5.1", channels: 6},
+			givenChannels: 2,
+			expected:      "pan=stereo|FL<0.3*FL+FC+0.3*BL|FR<0.3*FR+FC+0.3*BR",
+		},
+		{
+			name:          "5.1(side) to stereo",
+			givenLayout:   channelLayout{name: "5.1(side)", channels: 6},
+			givenChannels: 2,
+			expected:      "pan=stereo|FL<0.3*FL+FC+0.3*SL|FR<0.3*FR+FC+0.3*SR",
+		},
+		{
+			name:          "5.1 to mono",
+			givenLayout:   channelLayout{name: "5.1", channels: 6},
+			givenChannels: 1,
+			expected:      "pan=mono|c0<0.3*FL+0.3*FR+FC+0.3*BL+0.3*BR",
+		},
+		{
+			name:          "7.1 to stereo",
+			givenLayout:   channelLayout{name: "7.1", channels: 8},
+			givenChannels: 2,
+			expected:      "pan=stereo|FL<0.3*FL+FC+0.3*BL+0.3*SL|FR<0.3*FR+FC+0.3*BR+0.3*SR",
+		},
+		{
+			name:          "7.1 to mono",
+			givenLayout:   channelLayout{name: "7.1", channels: 8},
+			givenChannels: 1,
+			expected:      "pan=mono|c0<0.3*FL+0.3*FR+FC+0.3*BL+0.3*BR+0.3*SL+0.3*SR",
+		},
+		{
+			name:          "6.1 back center",
+			givenLayout:   channelLayout{name: "6.1", channels: 7},
+			givenChannels: 2,
+			expected:      "pan=stereo|FL<0.3*FL+FC+0.3*BC+0.3*SL|FR<0.3*FR+FC+0.3*BC+0.3*SR",
+		},
+		{
+			name:          "stereo",
+			givenLayout:   channelLayout{name: "stereo", channels: 2},
+			givenChannels: 1,
+		},
+		{
+			name:          "no center channel",
+			givenLayout:   channelLayout{name: "hexagonal", channels: 6},
+			givenChannels: 2,
+		},
+		{
+			name:          "layout and channels disagree",
+			givenLayout:   channelLayout{name: "5.1", channels: 8},
+			givenChannels: 2,
+		},
+		{
+			name:          "source channels",
+			givenLayout:   channelLayout{name: "5.1", channels: 6},
+			givenChannels: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			filter, ok := downmixFilter(tc.givenLayout, tc.givenChannels)
+			assert.Equal(t, tc.expected, filter)
+			assert.Equal(t, tc.expected != "", ok)
+		})
+	}
+}
+
+func TestProcess_DialogDownmix(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		givenProbe       string
+		givenProbeErr    error
+		givenNotSeekable bool
+		givenOpts        []Option
+		expectedFilter   string
+		expectedWarning  WarningCode
+	}{
+		{
+			name:            "5.1",
+			givenProbe:      probed51,
+			expectedFilter:  "pan=stereo|FL<0.3*FL+FC+0.3*SL|FR<0.3*FR+FC+0.3*SR",
+			expectedWarning: WarningDownmixed,
+		},
+		{
+			name:            "7.1 to mono",
+			givenProbe:      probed71,
+			givenOpts:       []Option{WithAudioSpec(AudioSpec{Channels: 1})},
+			expectedFilter:  "pan=mono|c0<0.3*FL+0.3*FR+FC+0.3*BL+0.3*BR+0.3*SL+0.3*SR",
+			expectedWarning: WarningDownmixed,
+		},
+		{
+			name:       "stereo",
+			givenProbe: probedStereo,
+		},
+		{
+			name:            "no center channel",
+			givenProbe:      probedHexagonal,
+			expectedWarning: WarningDownmixed,
+		},
+		{
+			name:            "probe failure",
+			givenProbeErr:   assert.AnError,
+			expectedWarning: WarningLayoutProbeFailed,
+		},
+		{
+			name:             "not seekable",
+			givenProbe:       probed51,
+			givenNotSeekable: true,
+		},
+		{
+			name:       "disabled",
+			givenProbe: probed51,
+			givenOpts:  []Option{WithDialogDownmix(false)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := append([]Option{WithDialogDownmix(true)}, tc.givenOpts...)
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.ReadAll(in.Data)
+					return []byte("hello"), err
+				},
+			}, opts...)
+
+			s.probeLayoutFunc = func(_ context.Context, r io.Reader) (channelLayout, error) {
+				if _, err := io.ReadAll(r); err != nil {
+					return channelLayout{}, err
+				}
+				if tc.givenProbeErr != nil {
+					return channelLayout{}, tc.givenProbeErr
+				}
+				return parseChannelLayout([]byte(tc.givenProbe))
+			}
+
+			// Stand in for ffmpeg, recording the arguments the job asks for.
+			var gotArgs []string
+			s.runFFmpeg = func(_ context.Context, args []string, r io.Reader, w io.Writer) error {
+				gotArgs = args
+				_, err := io.Copy(w, r)
+				return err
+			}
+
+			var data io.ReadCloser = readSeekNopCloser{bytes.NewReader(syntheticWAV(1))}
+			if tc.givenNotSeekable {
+				data = io.NopCloser(bytes.NewReader(syntheticWAV(1)))
+			}
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "talk.mkv",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       data,
+			})
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+
+			// The whole input is converted, after being rewound.
+			assert.Equal(t, int64(len(syntheticWAV(1))), out.ConvertedBytes)
+
+			var codes []WarningCode
+			for _, w := range out.Warnings {
+				codes = append(codes, w.Code)
+			}
+			if tc.expectedWarning == "" {
+				assert.Empty(t, codes)
+			} else {
+				assert.Equal(t, []WarningCode{tc.expectedWarning}, codes)
+			}
+
+			if tc.expectedFilter == "" {
+				assert.NotContains(t, out.Metadata, MetadataDownmixFilter)
+				assert.NotContains(t, gotArgs, "-af")
+				return
+			}
+			assert.Equal(t, tc.expectedFilter, out.Metadata[MetadataDownmixFilter])
+			assert.Contains(t, out.Metadata[MetadataFFmpegCommand], "-af '"+tc.expectedFilter+"'")
+			assert.Equal(t, []string{"-af", tc.expectedFilter}, gotArgs[4:6])
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package scriber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// defaultDurationTolerance is how much the converted audio's duration may
+// differ from the probed duration before WithDurationProbe logs a warning.
+const defaultDurationTolerance = time.Second
+
+// WithDurationProbe probes the duration of seekable inputs before converting
+// them, and reports it in Output.AudioDuration instead of the duration computed
+// from the converted audio. A warning is logged when the two differ by more
+// than tolerance, which usually means a broken conversion. A zero tolerance
+// defaults to one second. Inputs that aren't seekable or fail to probe are
+// processed as usual.
+func WithDurationProbe(tolerance time.Duration) Option {
+	return func(s *Scriber) {
+		if tolerance <= 0 {
+			tolerance = defaultDurationTolerance
+		}
+		s.durationTolerance = tolerance
+	}
+}
+
+// WithAdaptiveAudioTimeout extends the transcription timeout by perMinute
+// for every minute of converted audio. The duration is computed from the
+// PCM data as it is converted, so inputs need no Size hint.
+func WithAdaptiveAudioTimeout(perMinute time.Duration) Option {
+	return func(s *Scriber) {
+		s.timeoutPerAudioMinute = perMinute
+	}
+}
+
+// audioTimeoutFor returns the timeout extension for the audio
+// whose duration is returned by audio, or nil if there is none.
+func (s *Scriber) audioTimeoutFor(audio func() time.Duration) func() time.Duration {
+	if s.transcriptionTimeout <= 0 || s.timeoutPerAudioMinute <= 0 || audio == nil {
+		return nil
+	}
+	return func() time.Duration {
+		return time.Duration(audio().Minutes() * float64(s.timeoutPerAudioMinute))
+	}
+}
+
+// fixedDuration returns a function returning d, or nil if d is unknown.
+func fixedDuration(d time.Duration) func() time.Duration {
+	if d <= 0 {
+		return nil
+	}
+	return func() time.Duration { return d }
+}
+
+// probeInputDuration probes the duration of the job's input, when enabled
+// with WithDurationProbe or needed by WithUploadLimit. Failures are
+// reported as warnings, and only returned if escalated.
+func (s *Scriber) probeInputDuration(ctx context.Context, j *job) error {
+	if s.durationTolerance <= 0 && s.uploadLimit.MaxSize <= 0 || j.probedDuration > 0 {
+		return nil
+	}
+
+	d, err := s.probeSeekable(ctx, j.in)
+	if errors.Is(err, errSeekableRequired) {
+		j.logger.Debug("Input not seekable, skipping duration probe", slog.String("file", j.in.Name))
+		return nil
+	}
+	if err != nil {
+		if werr := j.warn(WarningDurationProbeFailed, err.Error()); werr != nil {
+			return werr
+		}
+		j.logger.Warn("Could not probe input duration", slog.String("file", j.in.Name), slog.String("error", err.Error()))
+		return nil
+	}
+	j.probedDuration = d
+	return nil
+}
+
+// probeSeekable probes the duration of the input, rewinding its data after.
+func (s *Scriber) probeSeekable(ctx context.Context, in Input) (time.Duration, error) {
+	var duration time.Duration
+	err := rewindAfter(in, func(r io.Reader) (err error) {
+		duration, err = s.probeDuration(ctx, r)
+		if err != nil {
+			return fmt.Errorf("could not probe duration: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return duration, nil
+}
+
+// rewindAfter runs probe on the input's data, which must be seekable,
+// rewinding it after.
+func rewindAfter(in Input, probe func(r io.Reader) error) error {
+	seeker, ok := in.Data.(io.Seeker)
+	if !ok {
+		return errSeekableRequired
+	}
+
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("could not get data position: %w", err)
+	}
+
+	err = probe(in.Data)
+
+	if _, serr := seeker.Seek(start, io.SeekStart); serr != nil && err == nil {
+		err = fmt.Errorf("could not rewind data: %w", serr)
+	}
+	return err
+}
+
+// checkAudioDuration compares the duration computed from the converted
+// audio with the probed one, if any, which then becomes the job's duration.
+// A mismatch is reported as a warning, and only returned if escalated.
+func (s *Scriber) checkAudioDuration(j *job) error {
+	if s.durationTolerance <= 0 || j.probedDuration <= 0 {
+		return nil
+	}
+
+	if diff := (j.audioDuration - j.probedDuration).Abs(); j.audioDuration > 0 && diff > s.durationTolerance {
+		msg := fmt.Sprintf("converted audio duration %s differs from the probed duration %s", j.audioDuration, j.probedDuration)
+		if err := j.warn(WarningDurationMismatch, msg); err != nil {
+			return err
+		}
+		j.logger.Warn("Converted audio duration differs from the probed duration",
+			slog.String("file", j.in.Name),
+			slog.Duration("audio_duration", j.audioDuration),
+			slog.Duration("probed_duration", j.probedDuration),
+		)
+	}
+	j.audioDuration = j.probedDuration
+	return nil
+}
@@ -0,0 +1,198 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alesr/scriber/wav"
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_DurationProbe(t *testing.T) {
+	t.Parallel()
+
+	converted := testWAVFormat.Duration(3 * testWAVFormat.ByteRate())
+
+	testCases := []struct {
+		name             string
+		givenProbe       bool
+		givenProbed      time.Duration
+		givenProbeErr    error
+		expectedDuration time.Duration
+		expectedWarning  string // Empty when no warning is expected.
+	}{
+		{
+			name:             "not probed",
+			expectedDuration: converted,
+		},
+		{
+			name:             "within tolerance",
+			givenProbe:       true,
+			givenProbed:      converted + 500*time.Millisecond,
+			expectedDuration: converted + 500*time.Millisecond,
+		},
+		{
+			name:             "mismatch",
+			givenProbe:       true,
+			givenProbed:      10 * converted,
+			expectedDuration: 10 * converted,
+			expectedWarning:  "Converted audio duration differs from the probed duration",
+		},
+		{
+			name:             "probe failure",
+			givenProbe:       true,
+			givenProbeErr:    assert.AnError,
+			expectedDuration: converted,
+			expectedWarning:  "Could not probe input duration",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := newCapturingHandler()
+
+			opts := []Option{WithConverter(copyConverter)}
+			if tc.givenProbe {
+				opts = append(opts, WithDurationProbe(0))
+			}
+
+			s := New(slog.New(handler), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.Copy(io.Discard, in.Data)
+					return []byte("text"), err
+				},
+			}, opts...)
+
+			// The probe reads the data, which must be rewound for the conversion.
+			s.probeDurationFunc = func(_ context.Context, r io.Reader) (time.Duration, error) {
+				_, err := io.Copy(io.Discard, r)
+				require.NoError(t, err)
+				return tc.givenProbed, tc.givenProbeErr
+			}
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "test.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       readSeekNopCloser{bytes.NewReader(syntheticWAV(3))},
+			})
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			out.Body.Close()
+			assert.Equal(t, tc.expectedDuration, out.AudioDuration)
+
+			var warnings []string
+			for _, e := range handler.entries() {
+				if strings.Contains(e.msg, "duration") {
+					warnings = append(warnings, e.msg)
+				}
+			}
+			if tc.expectedWarning == "" {
+				assert.Empty(t, warnings)
+				return
+			}
+			assert.Equal(t, []string{tc.expectedWarning}, warnings)
+		})
+	}
+}
+
+func TestAudioTimeoutFor(t *testing.T) {
+	t.Parallel()
+
+	// A minute of audio is 60 times the byte rate.
+	minute := 60 * testWAVFormat.ByteRate()
+
+	testCases := []struct {
+		name              string
+		givenPerMinute    time.Duration
+		givenTimeout      time.Duration
+		givenBytes        []int64 // Synthetic PCM byte counts, written in turn.
+		expectedExtension []time.Duration
+	}{
+		{
+			name:              "grows with the converted audio",
+			givenPerMinute:    30 * time.Second,
+			givenTimeout:      time.Minute,
+			givenBytes:        []int64{0, minute / 2, minute, 3 * minute},
+			expectedExtension: []time.Duration{0, 15 * time.Second, 45 * time.Second, 135 * time.Second},
+		},
+		{
+			name:         "disabled",
+			givenTimeout: time.Minute,
+		},
+		{
+			name:           "no transcription timeout",
+			givenPerMinute: 30 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := New(noopLogger(), &mockWhisperClient{},
+				WithTranscriptionTimeout(tc.givenTimeout),
+				WithAdaptiveAudioTimeout(tc.givenPerMinute),
+			)
+
+			counter := newWAVCounter(io.Discard)
+			require.NoError(t, wav.WriteHeader(counter, testWAVFormat, wav.UnknownSize))
+
+			extension := s.audioTimeoutFor(counter.duration)
+			if tc.expectedExtension == nil {
+				assert.Nil(t, extension)
+				return
+			}
+			require.NotNil(t, extension)
+
+			for i, n := range tc.givenBytes {
+				_, err := counter.Write(make([]byte, n))
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectedExtension[i], extension(), "after %d bytes", n)
+			}
+		})
+	}
+}
+
+func TestWithFirstByteTimeout_Extension(t *testing.T) {
+	t.Parallel()
+
+	const timeout = 20 * time.Millisecond
+
+	testCases := []struct {
+		name          string
+		givenExtended time.Duration
+	}{
+		{name: "not extended"},
+		{name: "extended", givenExtended: 10 * timeout},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, r, cancel := withFirstByteTimeout(context.TODO(), strings.NewReader("data"), timeout, func() time.Duration {
+				return tc.givenExtended
+			})
+			defer cancel()
+
+			start := time.Now()
+			_, err := r.Read(make([]byte, 1))
+			require.NoError(t, err)
+
+			<-ctx.Done()
+			assert.ErrorIs(t, context.Cause(ctx), errTranscriptionTimeout)
+			assert.GreaterOrEqual(t, time.Since(start), timeout+tc.givenExtended)
+		})
+	}
+}
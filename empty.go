@@ -0,0 +1,153 @@
+package scriber
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// EmptyTranscriptionPolicy controls how empty transcriptions are handled.
+type EmptyTranscriptionPolicy int
+
+const (
+	// EmptyTranscriptionFail fails the job with an EmptyTranscriptionError.
+	EmptyTranscriptionFail EmptyTranscriptionPolicy = iota
+
+	// EmptyTranscriptionRetry transcribes the input once more before failing.
+	// Retrying requires the input data to implement io.Seeker; otherwise the
+	// job fails as with EmptyTranscriptionFail.
+	EmptyTranscriptionRetry
+
+	// EmptyTranscriptionAllow publishes empty transcriptions,
+	// which is legitimate for silent audio.
+	EmptyTranscriptionAllow
+)
+
+// WithEmptyTranscriptionPolicy sets how transcriptions that are empty,
+// whitespace-only, or (for subtitles) contain no cues are handled.
+// Defaults to EmptyTranscriptionFail.
+func WithEmptyTranscriptionPolicy(p EmptyTranscriptionPolicy) Option {
+	return func(s *Scriber) {
+		s.emptyPolicy = p
+	}
+}
+
+// isEmptyTranscription reports whether text carries no transcription.
+func isEmptyTranscription(text []byte, outType OutputType) bool {
+	if len(bytes.TrimSpace(text)) == 0 {
+		return true
+	}
+
+	if isSubtitles(outType) {
+		cues, err := parseSRT(text)
+		return err == nil && len(cues) == 0
+	}
+	return false
+}
+
+// checkEmptyTranscription applies the empty transcription policy to text,
+// transcribing the input again if the policy asks for a retry.
+func (s *Scriber) checkEmptyTranscription(ctx context.Context, j *job, text []byte) ([]byte, error) {
+	if s.emptyPolicy == EmptyTranscriptionAllow || !isEmptyTranscription(text, j.in.OutputType) {
+		return text, nil
+	}
+
+	if s.emptyPolicy == EmptyTranscriptionRetry {
+		if _, ok := j.in.Data.(io.Seeker); !ok {
+			j.logger.Warn("Empty transcription can't be retried: data is not seekable",
+				slog.String("file", j.in.Name),
+			)
+			return nil, errEmptyTranscription
+		}
+
+		j.logger.Warn("Empty transcription, retrying", slog.String("file", j.in.Name))
+
+		if err := j.rewind(); err != nil {
+			return nil, fmt.Errorf("could not retry: %w", err)
+		}
+
+		text, err := s.transcribe(ctx, j)
+		if err != nil {
+			return nil, err
+		}
+
+		if !isEmptyTranscription(text, j.in.OutputType) {
+			return text, nil
+		}
+	}
+	return nil, errEmptyTranscription
+}
+
+// WithEmptyInputCheck sets whether inputs without a single byte fail with
+// an EmptyInputError before anything is converted or uploaded, which is
+// the default. Disable it for custom converters that produce audio from
+// empty inputs.
+func WithEmptyInputCheck(enabled bool) Option {
+	return func(s *Scriber) {
+		s.allowEmptyInput = !enabled
+	}
+}
+
+// checkEmptyInput fails if the job's input is empty: when a size of zero
+// was declared with WithInputSize, or when its first read hits EOF. The
+// byte read is put back, rewinding seekable data or buffering it.
+func (s *Scriber) checkEmptyInput(j *job) error {
+	if s.allowEmptyInput {
+		return nil
+	}
+	if j.in.sizeDeclared && j.in.Size == 0 {
+		return fmt.Errorf("%w: declared size is zero", errEmptyInput)
+	}
+
+	if seeker, ok := j.in.Data.(io.ReadSeeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return fmt.Errorf("could not get data position: %w", err)
+		}
+
+		var b [1]byte
+		n, err := io.ReadFull(seeker, b[:])
+		if _, serr := seeker.Seek(start, io.SeekStart); serr != nil {
+			return fmt.Errorf("could not rewind data: %w", serr)
+		}
+		return emptyInputErr(n, err)
+	}
+
+	// Peek through the idle timeout, so that a stalled input
+	// doesn't block the job before the conversion starts.
+	ir, stop := newIdleTimeoutReader(j.in.Data, s.inputIdleTimeout)
+	defer stop()
+
+	r := bufio.NewReader(ir)
+	b, err := r.Peek(1)
+	if err := emptyInputErr(len(b), err); err != nil {
+		return err
+	}
+
+	// The rest of the data is read directly, the conversion
+	// bounding its reads with the idle timeout itself.
+	peeked, _ := r.Peek(r.Buffered())
+	j.in.Data = peekedReader{Reader: io.MultiReader(bytes.NewReader(peeked), j.in.Data), Closer: j.in.Data}
+	return nil
+}
+
+// emptyInputErr returns the error of a read of n bytes from the start of an input.
+func emptyInputErr(n int, err error) error {
+	if n > 0 {
+		return nil
+	}
+	if err == nil || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return errEmptyInput
+	}
+	return fmt.Errorf("could not read data: %w", err)
+}
+
+// peekedReader reads input data after the bytes peeked from it.
+type peekedReader struct {
+	io.Reader
+	io.Closer
+}
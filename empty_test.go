@@ -0,0 +1,268 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsEmptyTranscription(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		givenText string
+		givenType OutputType
+		expected  bool
+	}{
+		{name: "empty transcript", givenText: "", givenType: OutputTypeTranscript, expected: true},
+		{name: "whitespace transcript", givenText: " \n\t ", givenType: OutputTypeTranscript, expected: true},
+		{name: "transcript", givenText: "hello", givenType: OutputTypeTranscript, expected: false},
+		{name: "empty subtitles", givenText: "", givenType: OutputTypeSubtitles, expected: true},
+		{name: "subtitles without cues", givenText: "\n\n\n", givenType: OutputTypeSubtitles, expected: true},
+		{name: "subtitles", givenText: "1\n00:00:00,000 --> 00:00:01,000\nhi\n", givenType: OutputTypeSubtitles, expected: false},
+		{name: "unparsable subtitles are not empty", givenText: "hello", givenType: OutputTypeSubtitles, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, isEmptyTranscription([]byte(tc.givenText), tc.givenType))
+		})
+	}
+}
+
+func TestProcess_EmptyTranscription(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		givenPolicy   EmptyTranscriptionPolicy
+		givenSeekable bool
+		givenReplies  []string
+		expectedCalls int32
+		expectedErr   bool
+		expectedText  string
+	}{
+		{
+			name:          "fail",
+			givenPolicy:   EmptyTranscriptionFail,
+			givenSeekable: true,
+			givenReplies:  []string{" "},
+			expectedCalls: 1,
+			expectedErr:   true,
+		},
+		{
+			name:          "allow",
+			givenPolicy:   EmptyTranscriptionAllow,
+			givenReplies:  []string{" "},
+			expectedCalls: 1,
+			expectedText:  " ",
+		},
+		{
+			name:          "retry succeeds",
+			givenPolicy:   EmptyTranscriptionRetry,
+			givenSeekable: true,
+			givenReplies:  []string{"", "hello"},
+			expectedCalls: 2,
+			expectedText:  "hello",
+		},
+		{
+			name:          "retry still empty",
+			givenPolicy:   EmptyTranscriptionRetry,
+			givenSeekable: true,
+			givenReplies:  []string{"", ""},
+			expectedCalls: 2,
+			expectedErr:   true,
+		},
+		{
+			name:          "retry without seekable data",
+			givenPolicy:   EmptyTranscriptionRetry,
+			givenReplies:  []string{""},
+			expectedCalls: 1,
+			expectedErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls atomic.Int32
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					data, err := io.ReadAll(in.Data)
+					require.NoError(t, err)
+					assert.Equal(t, "media", string(data), "retries must see the whole input")
+
+					n := calls.Add(1)
+					return []byte(tc.givenReplies[n-1]), nil
+				},
+			}
+
+			scriber := New(noopLogger(), mockClient, WithEmptyTranscriptionPolicy(tc.givenPolicy))
+			scriber.convertToWavFunc = copyConverter
+
+			var data io.ReadCloser = io.NopCloser(bytes.NewBufferString("media"))
+			if tc.givenSeekable {
+				data = readSeekNopCloser{bytes.NewReader([]byte("media"))}
+			}
+
+			err := scriber.Process(context.TODO(), Input{
+				Name:       "test.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       data,
+			})
+
+			assert.Equal(t, tc.expectedCalls, calls.Load())
+
+			if tc.expectedErr {
+				require.ErrorAs(t, err, &EmptyTranscriptionError{})
+				return
+			}
+
+			require.NoError(t, err)
+			output := <-scriber.Collect()
+			assert.Equal(t, tc.expectedText, string(output.Text))
+		})
+	}
+}
+
+// unreadable is an input failing the test if read.
+type unreadable struct{ t *testing.T }
+
+func (u unreadable) Read([]byte) (int, error) {
+	u.t.Error("the input is read")
+	return 0, io.EOF
+}
+
+func TestProcess_EmptyInput(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		givenOpts    []Option
+		givenInput   func(t *testing.T) Input
+		expectedData []byte // The data converted, when the input isn't rejected.
+		expectedErr  error
+	}{
+		{
+			name: "empty seekable reader",
+			givenInput: func(*testing.T) Input {
+				return NewInput("talk.mp4", bytes.NewReader(nil))
+			},
+			expectedErr: errEmptyInput,
+		},
+		{
+			name: "empty stream",
+			givenInput: func(*testing.T) Input {
+				return NewInput("talk.mp4", io.MultiReader())
+			},
+			expectedErr: errEmptyInput,
+		},
+		{
+			name: "declared size of zero",
+			givenInput: func(t *testing.T) Input {
+				return NewInput("talk.mp4", unreadable{t}, WithInputSize(0))
+			},
+			expectedErr: errEmptyInput,
+		},
+		{
+			name: "empty bytes",
+			givenInput: func(*testing.T) Input {
+				return InputFromBytes("talk.mp4", "en", OutputTypeTranscript, nil)
+			},
+			expectedErr: errEmptyInput,
+		},
+		{
+			name: "failing read",
+			givenInput: func(*testing.T) Input {
+				return NewInput("talk.mp4", iotest.ErrReader(assert.AnError))
+			},
+			expectedErr: assert.AnError,
+		},
+		{
+			name: "stream",
+			givenInput: func(*testing.T) Input {
+				return NewInput("talk.mp4", io.MultiReader(strings.NewReader("vi"), strings.NewReader("deo")))
+			},
+			expectedData: []byte("video"),
+		},
+		{
+			name: "seekable reader",
+			givenInput: func(*testing.T) Input {
+				r := bytes.NewReader([]byte("--video"))
+				_, _ = r.Seek(2, io.SeekStart)
+				return NewInput("talk.mp4", r)
+			},
+			expectedData: []byte("video"),
+		},
+		{
+			name:      "check disabled",
+			givenOpts: []Option{WithEmptyInputCheck(false)},
+			givenInput: func(*testing.T) Input {
+				return NewInput("talk.mp4", io.MultiReader())
+			},
+			expectedData: []byte{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var uploads atomic.Int32
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					uploads.Add(1)
+					_, err := io.ReadAll(in.Data)
+					return []byte("hello"), err
+				},
+			}, tc.givenOpts...)
+
+			var converted []byte
+			s.convertToWavFunc = func(_ context.Context, r io.Reader, w io.Writer) error {
+				data, err := io.ReadAll(r)
+				if err != nil {
+					return err
+				}
+				converted = data
+				_, err = w.Write(syntheticWAV(1))
+				return err
+			}
+
+			in := tc.givenInput(t)
+			in.Language, in.OutputType = "en", OutputTypeTranscript
+
+			err := s.Process(context.TODO(), in)
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+
+				var pe *ProcessError
+				require.ErrorAs(t, err, &pe)
+				assert.Equal(t, StageValidation, pe.Stage)
+				assert.Nil(t, converted, "the input isn't converted")
+				assert.Zero(t, uploads.Load(), "nothing is uploaded")
+				return
+			}
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+
+			assert.Equal(t, tc.expectedData, converted)
+			assert.Equal(t, int32(1), uploads.Load())
+		})
+	}
+}
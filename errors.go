@@ -1,24 +1,228 @@
 package scriber
 
+import (
+	"errors"
+	"fmt"
+)
+
 var (
 	// Enum errors
 
-	errNameRequired = NameRequiredError{"name is required"}
-	errExtRequired  = ExtRequiredError{"extension is required"}
-	errorOutputType = OutputTypeError{"output type is not supported"}
-	errorLanguage   = LanguageError{"language is required"}
-	errorData       = DataError{"data is required"}
+	errNameRequired   = NameRequiredError{"name is required"}
+	errExtRequired    = ExtRequiredError{"extension is required"}
+	errNameTooLong    = NameTooLongError{"name is too long"}
+	errNameEncoding   = NameEncodingError{"name is not valid UTF-8"}
+	errUserTagTooLong = UserTagError{"user tag is too long"}
+	errOutputExt      = OutputExtensionError{"output extension must start with a dot and contain no path separators"}
+	errContentType    = ContentTypeError{"content type is not audio or video"}
+	errorOutputType   = OutputTypeError{"output type is not supported"}
+	errorLanguage     = LanguageError{"language is required"}
+	errorData         = DataError{"data is required"}
+	errDataExclusive  = DataError{"data and data ref are mutually exclusive"}
+	errEmptyAudio     = DataError{"audio has no samples"}
+	errEmptyInput     = EmptyInputError{"input is empty"}
+
+	errLanguagesExclusive = LanguageError{"language and languages are mutually exclusive"}
+	errLanguagesInvalid   = LanguageError{"languages must be distinct and not auto"}
+	errLanguageAuto       = LanguageError{"language detection is only available through Process"}
+
+	errPricingRequired  = PricingError{"pricing is not configured"}
+	errSeekableRequired = SeekableError{"data must implement io.Seeker"}
+
+	errBlobStoreRequired = BlobStoreError{"data ref requires a blob store"}
+
+	errEmptyTranscription = EmptyTranscriptionError{"transcription is empty"}
+
+	errShuttingDown = ShutdownError{"scriber is shutting down"}
+	errClosed       = ClosedError{"scriber is closed"}
+
+	errTranscriptionTimeout = TranscriptionTimeoutError{"transcription timed out"}
+
+	errInputTooLarge  = InputTooLargeError{"input is too large"}
+	errUploadTooLarge = UploadTooLargeError{"upload is too large for the backend"}
+	errInputStalled   = InputStalledError{"input stalled"}
+	errSizeMismatch   = SizeMismatchError{"input is larger than its declared size"}
+
+	errPublishTimeout = PublishTimeoutError{"publish timed out"}
+
+	errFallback = FallbackError{"transcription should fall back to another backend"}
+
+	errRetryBudget = RetryBudgetError{"batch retry budget exhausted"}
+
+	errNameTaken = NameTakenError{"no free output name"}
+
+	errOutputWrite = OutputWriteError{"could not write output"}
+
+	errCanceled = CanceledError{"job canceled"}
+
+	errUploadName = UploadNameError{"invalid upload name"}
+
+	errDeadlineExceeded = DeadlineExceededError{"job deadline exceeded"}
 )
 
 type (
-	NameRequiredError struct{ E }
-	ExtRequiredError  struct{ E }
-	OutputTypeError   struct{ E }
-	LanguageError     struct{ E }
-	DataError         struct{ E }
+	NameRequiredError    struct{ E }
+	ExtRequiredError     struct{ E }
+	NameTooLongError     struct{ E }
+	NameEncodingError    struct{ E }
+	UserTagError         struct{ E }
+	OutputExtensionError struct{ E }
+	ContentTypeError     struct{ E }
+	OutputTypeError      struct{ E }
+	LanguageError        struct{ E }
+	DataError            struct{ E }
+	PricingError         struct{ E }
+	RateError            struct{ E }
+	SeekableError        struct{ E }
+	BlobStoreError       struct{ E }
+
+	EmptyTranscriptionError struct{ E }
+	EmptyInputError         struct{ E }
+	ShutdownError           struct{ E }
+	ClosedError             struct{ E }
+
+	TranscriptionTimeoutError struct{ E }
+	InputTooLargeError        struct{ E }
+	UploadTooLargeError       struct{ E }
+	InputStalledError         struct{ E }
+	SizeMismatchError         struct{ E }
+	PublishTimeoutError       struct{ E }
+	FallbackError             struct{ E }
+	RetryBudgetError          struct{ E }
+	NameTakenError            struct{ E }
+	OutputWriteError          struct{ E }
+	CanceledError             struct{ E }
+	UploadNameError           struct{ E }
+	DeadlineExceededError     struct{ E }
 )
 
 // E is an error type that implements the error interface.
 type E string
 
 func (e E) Error() string { return string(e) }
+
+// Stage identifies the processing stage an error occurred in.
+type Stage string
+
+const (
+	StageAdmission     Stage = "admission"
+	StageValidation    Stage = "validation"
+	StageConversion    Stage = "conversion"
+	StageTranscription Stage = "transcription"
+	StagePostProcess   Stage = "postprocess"
+	StagePublish       Stage = "publish"
+)
+
+// InputRef identifies the input a ProcessError refers to.
+type InputRef struct {
+	Name       string
+	OutputType OutputType
+	Language   string
+
+	// Index is the position of the input in its batch, for errors
+	// returned by ProcessBatch and ProcessDir. It is zero otherwise.
+	Index int
+}
+
+// ProcessError is the error returned by Process.
+// It records the stage the failure occurred in,
+// so callers can decide, for instance, what to retry.
+type ProcessError struct {
+	Stage Stage
+	Input InputRef
+	Err   error
+
+	// Salvaged holds the transcription of a job that failed after
+	// transcribing, when salvaging is enabled. Its Body must be closed.
+	Salvaged *Output
+
+	// Timing is the time the job spent in each stage up to the failure.
+	Timing ProcessingTime
+}
+
+func (e *ProcessError) Error() string {
+	return fmt.Sprintf("%s failed for %q: %v", e.Stage, e.Input.Name, e.Err)
+}
+
+func (e *ProcessError) Unwrap() error { return e.Err }
+
+// BatchError is the error returned by ProcessBatch and ProcessDir when
+// inputs fail. Errors.Is and errors.As see through it to the errors of
+// every input.
+type BatchError struct {
+	// Errors holds the errors of the failed inputs, ordered by index.
+	// Inputs transcribed in several languages may have one per language.
+	Errors []*ProcessError
+
+	// Err is the error of the batch itself, if any, such as the
+	// cancellation that stopped it or a failure to write its report.
+	Err error
+}
+
+func (e *BatchError) Error() string {
+	return errors.Join(e.Unwrap()...).Error()
+}
+
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors)+1)
+	for _, pe := range e.Errors {
+		errs = append(errs, pe)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// AsBatchError returns err as a *BatchError, if it is or wraps one.
+func AsBatchError(err error) (*BatchError, bool) {
+	var be *BatchError
+	ok := errors.As(err, &be)
+	return be, ok
+}
+
+// processErrors returns the *ProcessErrors err is made of, which is
+// several for inputs transcribed in several languages.
+func processErrors(err error) []*ProcessError {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var pes []*ProcessError
+		for _, err := range joined.Unwrap() {
+			pes = append(pes, processErrors(err)...)
+		}
+		return pes
+	}
+
+	var pe *ProcessError
+	if errors.As(err, &pe) {
+		return []*ProcessError{pe}
+	}
+	return []*ProcessError{{Err: err}}
+}
+
+// stageError tags err with the stage it occurred in.
+// The input is filled in by Process.
+func stageError(stage Stage, err error) error {
+	return &ProcessError{Stage: stage, Err: err}
+}
+
+// wrapStaged wraps err with wrap, keeping the stage err is tagged with.
+// Wrapping must happen inside the tag, which asProcessError unwraps.
+func wrapStaged(err error, wrap func(error) error) error {
+	var pe *ProcessError
+	if errors.As(err, &pe) {
+		return stageError(pe.Stage, wrap(pe.Err))
+	}
+	return wrap(err)
+}
+
+// asProcessError returns err as a *ProcessError for in, keeping the stage
+// of an error already tagged with stageError and using stage otherwise.
+func asProcessError(stage Stage, in Input, err error) *ProcessError {
+	ref := InputRef{Name: in.Name, OutputType: in.OutputType, Language: in.Language}
+
+	var pe *ProcessError
+	if errors.As(err, &pe) {
+		return &ProcessError{Stage: pe.Stage, Input: ref, Err: pe.Err}
+	}
+	return &ProcessError{Stage: stage, Input: ref, Err: err}
+}
@@ -0,0 +1,138 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultBillingIncrement is the granularity durations
+// are rounded up to when computing billable minutes.
+const defaultBillingIncrement = time.Second
+
+// newFFprobeProber returns the default prober, which reads the duration with ffprobe.
+func newFFprobeProber() probeDurationFunc {
+	return func(ctx context.Context, r io.Reader) (time.Duration, error) {
+		cmd := exec.CommandContext(ctx,
+			"ffprobe",
+			"-v", "error",
+			"-show_entries", "format=duration",
+			"-of", "default=noprint_wrappers=1:nokey=1",
+			"pipe:0",
+		)
+
+		var stdout, stderr bytes.Buffer
+		cmd.Stdin = r
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			return 0, fmt.Errorf("ffprobe failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+
+		secs, err := strconv.ParseFloat(strings.TrimSpace(stdout.String()), 64)
+		if err != nil {
+			return 0, fmt.Errorf("could not parse ffprobe duration %q: %w", stdout.String(), err)
+		}
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+}
+
+type (
+	// probeDurationFunc is a function that probes the duration of media data.
+	probeDurationFunc func(ctx context.Context, r io.Reader) (time.Duration, error)
+
+	// RateTable maps model names to their price per minute of audio.
+	RateTable map[string]float64
+
+	// Pricing configures cost estimates.
+	Pricing struct {
+		// Model is the model the backend transcribes with.
+		Model string
+
+		// Rates holds the per-minute price of each model.
+		Rates RateTable
+
+		// Increment is the billing granularity. Durations are rounded
+		// up to a multiple of it. Defaults to one second.
+		Increment time.Duration
+	}
+
+	// Estimate is the expected cost of transcribing an input.
+	Estimate struct {
+		Duration        time.Duration
+		BillableMinutes float64
+		Model           string
+		Cost            float64
+	}
+)
+
+// WithPricing sets the pricing used by Estimate.
+func WithPricing(p Pricing) Option {
+	return func(s *Scriber) {
+		if p.Increment <= 0 {
+			p.Increment = defaultBillingIncrement
+		}
+		s.pricing = &p
+	}
+}
+
+// Estimate probes the duration of the input and returns the expected
+// cost of transcribing it. The input data must implement io.Seeker;
+// it is rewound after probing so the same Input can then be processed.
+// Inputs with a DataRef are opened from the blob store, and closed after.
+func (s *Scriber) Estimate(ctx context.Context, in Input) (Estimate, error) {
+	in, err := s.normalizeInput(in)
+	if err != nil {
+		return Estimate{}, fmt.Errorf("invalid input: %w", err)
+	}
+
+	if err := in.validate(); err != nil {
+		return Estimate{}, fmt.Errorf("invalid input: %w", err)
+	}
+
+	if s.pricing == nil {
+		return Estimate{}, errPricingRequired
+	}
+
+	rate, ok := s.pricing.Rates[s.pricing.Model]
+	if !ok {
+		return Estimate{}, RateError{E(fmt.Sprintf("no rate for model %q", s.pricing.Model))}
+	}
+
+	if in.DataRef != "" {
+		in, err = s.openBlob(ctx, in)
+		if err != nil {
+			return Estimate{}, fmt.Errorf("invalid input: %w", err)
+		}
+		defer in.Data.Close()
+	}
+
+	duration, err := s.probeSeekable(ctx, in)
+	if err != nil {
+		return Estimate{}, err
+	}
+
+	billable := roundUp(duration, s.pricing.Increment)
+
+	return Estimate{
+		Duration:        duration,
+		BillableMinutes: billable.Minutes(),
+		Model:           s.pricing.Model,
+		Cost:            math.Round(billable.Minutes()*rate*1e6) / 1e6,
+	}, nil
+}
+
+// roundUp rounds d up to a multiple of m.
+func roundUp(d, m time.Duration) time.Duration {
+	if r := d % m; r != 0 {
+		return d + m - r
+	}
+	return d
+}
@@ -0,0 +1,177 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimate(t *testing.T) {
+	t.Parallel()
+
+	rates := RateTable{"whisper-1": 0.006, "whisper-large": 0.01}
+
+	testCases := []struct {
+		name          string
+		givenPricing  Pricing
+		givenDuration time.Duration
+		expected      Estimate
+	}{
+		{
+			name:          "rounded up to the second by default",
+			givenPricing:  Pricing{Model: "whisper-1", Rates: rates},
+			givenDuration: 89*time.Second + 100*time.Millisecond,
+			expected: Estimate{
+				Duration:        89*time.Second + 100*time.Millisecond,
+				BillableMinutes: 1.5,
+				Model:           "whisper-1",
+				Cost:            0.009,
+			},
+		},
+		{
+			name:          "per-minute increment",
+			givenPricing:  Pricing{Model: "whisper-large", Rates: rates, Increment: time.Minute},
+			givenDuration: 61 * time.Second,
+			expected: Estimate{
+				Duration:        61 * time.Second,
+				BillableMinutes: 2,
+				Model:           "whisper-large",
+				Cost:            0.02,
+			},
+		},
+		{
+			name:          "exact increment",
+			givenPricing:  Pricing{Model: "whisper-1", Rates: rates},
+			givenDuration: 10 * time.Minute,
+			expected: Estimate{
+				Duration:        10 * time.Minute,
+				BillableMinutes: 10,
+				Model:           "whisper-1",
+				Cost:            0.06,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			scriber := New(noopLogger(), &mockWhisperClient{}, WithPricing(tc.givenPricing))
+			scriber.probeDurationFunc = func(ctx context.Context, r io.Reader) (time.Duration, error) {
+				_, err := io.Copy(io.Discard, r)
+				require.NoError(t, err)
+				return tc.givenDuration, nil
+			}
+
+			data := bytes.NewReader([]byte("media"))
+			got, err := scriber.Estimate(context.TODO(), Input{
+				Name:       "test.mp4",
+				OutputType: OutputTypeSubtitles,
+				Language:   "en",
+				Data:       readSeekNopCloser{data},
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, got)
+
+			// The data must be rewound for processing.
+			rest, err := io.ReadAll(data)
+			require.NoError(t, err)
+			assert.Equal(t, "media", string(rest))
+		})
+	}
+}
+
+func TestEstimate_Errors(t *testing.T) {
+	t.Parallel()
+
+	validInput := func() Input {
+		return Input{
+			Name:       "test.mp4",
+			OutputType: OutputTypeSubtitles,
+			Language:   "en",
+			Data:       readSeekNopCloser{bytes.NewReader([]byte("media"))},
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		givenOpts    []Option
+		givenInput   func() Input
+		givenProbeEr error
+		expectedErr  any
+	}{
+		{
+			name:        "no pricing",
+			givenInput:  validInput,
+			expectedErr: &PricingError{},
+		},
+		{
+			name:        "unknown model",
+			givenOpts:   []Option{WithPricing(Pricing{Model: "nope", Rates: RateTable{"whisper-1": 1}})},
+			givenInput:  validInput,
+			expectedErr: &RateError{},
+		},
+		{
+			name:      "not seekable",
+			givenOpts: []Option{WithPricing(Pricing{Model: "whisper-1", Rates: RateTable{"whisper-1": 1}})},
+			givenInput: func() Input {
+				in := validInput()
+				in.Data = io.NopCloser(bytes.NewReader(nil))
+				return in
+			},
+			expectedErr: &SeekableError{},
+		},
+		{
+			name:        "invalid input",
+			givenOpts:   []Option{WithPricing(Pricing{Model: "whisper-1", Rates: RateTable{"whisper-1": 1}})},
+			givenInput:  func() Input { return Input{} },
+			expectedErr: &NameRequiredError{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			scriber := New(noopLogger(), &mockWhisperClient{}, tc.givenOpts...)
+			scriber.probeDurationFunc = func(ctx context.Context, r io.Reader) (time.Duration, error) {
+				t.Fatal("unexpected probe")
+				return 0, nil
+			}
+
+			_, err := scriber.Estimate(context.TODO(), tc.givenInput())
+			require.ErrorAs(t, err, tc.expectedErr)
+		})
+	}
+
+	t.Run("probe failure rewinds data", func(t *testing.T) {
+		t.Parallel()
+
+		scriber := New(noopLogger(), &mockWhisperClient{}, WithPricing(Pricing{Model: "whisper-1", Rates: RateTable{"whisper-1": 1}}))
+		scriber.probeDurationFunc = func(ctx context.Context, r io.Reader) (time.Duration, error) {
+			_, _ = io.Copy(io.Discard, r)
+			return 0, assert.AnError
+		}
+
+		in := validInput()
+		_, err := scriber.Estimate(context.TODO(), in)
+		require.ErrorIs(t, err, assert.AnError)
+
+		rest, err := io.ReadAll(in.Data)
+		require.NoError(t, err)
+		assert.Equal(t, "media", string(rest))
+	})
+}
+
+// readSeekNopCloser is an io.ReadSeekCloser with a no-op Close.
+type readSeekNopCloser struct {
+	io.ReadSeeker
+}
+
+func (readSeekNopCloser) Close() error { return nil }
@@ -0,0 +1,182 @@
+package scriber
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// defaultMaxEvents is how many events a job keeps by default.
+const defaultMaxEvents = 100
+
+// Event is an entry of the event log that travels with an Output,
+// recording what happened to the job for later debugging.
+type Event struct {
+	Time time.Time
+
+	// Stage is the stage the job was in. When audio is streamed, the
+	// conversion overlaps the transcription stage.
+	Stage Stage
+
+	Message string
+
+	// Attrs are the attributes of the log line the event mirrors,
+	// flattened like Output.Metadata. Attributes common to every
+	// event of the job are in Output.Metadata instead.
+	Attrs map[string]string
+}
+
+// WithEventLog sets how many events a job keeps in Output.Events,
+// 100 by default. Past max, events in between are dropped: the earliest
+// ones and the latest are kept. Zero disables the event log.
+func WithEventLog(max int) Option {
+	return func(s *Scriber) {
+		s.maxEvents = max
+	}
+}
+
+// eventLog records the events of a job. It mirrors the job's log lines,
+// whatever their level, and tracks the stage the job is in.
+type eventLog struct {
+	max int
+
+	mu     sync.Mutex
+	stage  Stage
+	events []Event
+}
+
+func newEventLog(max int) *eventLog {
+	return &eventLog{max: max, stage: StageAdmission}
+}
+
+// enter moves the log to stage, reporting whether the stage changed.
+func (l *eventLog) enter(stage Stage) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.stage == stage {
+		return false
+	}
+	l.stage = stage
+	return true
+}
+
+// add records an event in the current stage.
+func (l *eventLog) add(t time.Time, msg string, attrs []slog.Attr) {
+	if l.max <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := Event{Time: t, Stage: l.stage, Message: msg, Attrs: attrsToMetadata(attrs)}
+	if len(l.events) < l.max {
+		l.events = append(l.events, e)
+		return
+	}
+	l.events[len(l.events)-1] = e
+}
+
+// snapshot returns a copy of the events recorded so far, or nil.
+func (l *eventLog) snapshot() []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.events) == 0 {
+		return nil
+	}
+	return append([]Event(nil), l.events...)
+}
+
+// fork returns a log starting with the events recorded so far,
+// for a job that carries on separately.
+func (l *eventLog) fork() *eventLog {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return &eventLog{
+		max:    l.max,
+		stage:  l.stage,
+		events: append([]Event(nil), l.events...),
+	}
+}
+
+// attach returns logger with its lines also recorded in the log,
+// detaching it from any log it was recording in.
+func (l *eventLog) attach(logger *slog.Logger) *slog.Logger {
+	h := logger.Handler()
+	if eh, ok := h.(*eventHandler); ok {
+		eh := *eh
+		eh.log = l
+		return slog.New(&eh)
+	}
+	if l.max <= 0 {
+		return logger
+	}
+	return slog.New(&eventHandler{next: h, log: l})
+}
+
+// eventHandler passes records on to next while recording them in log.
+// Attributes added to it with WithAttrs are recorded along, under the
+// groups opened with WithGroup.
+type eventHandler struct {
+	next   slog.Handler
+	log    *eventLog
+	attrs  []slog.Attr
+	groups []string
+}
+
+func (h *eventHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *eventHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	h.log.add(r.Time, r.Message, append(h.attrs[:len(h.attrs):len(h.attrs)], h.grouped(attrs)...))
+
+	if !h.next.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *eventHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.next = h.next.WithAttrs(attrs)
+	c.attrs = append(h.attrs[:len(h.attrs):len(h.attrs)], h.grouped(attrs)...)
+	return &c
+}
+
+func (h *eventHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.next = h.next.WithGroup(name)
+	c.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &c
+}
+
+// grouped nests attrs in the handler's groups.
+func (h *eventHandler) grouped(attrs []slog.Attr) []slog.Attr {
+	for i := len(h.groups) - 1; i >= 0 && len(attrs) > 0; i-- {
+		attrs = []slog.Attr{{Key: h.groups[i], Value: slog.GroupValue(attrs...)}}
+	}
+	return attrs
+}
+
+// enter moves the job to stage, logging the change.
+func (j *job) enter(stage Stage) {
+	if !j.events.enter(stage) {
+		return
+	}
+
+	if stage == StageConversion && j.ffmpegArgs != nil {
+		j.logger.Debug("Stage started", slog.String("stage", string(stage)), slog.String(MetadataFFmpegCommand, ffmpegCommand(j.ffmpegArgs)))
+		return
+	}
+	j.logger.Debug("Stage started", slog.String("stage", string(stage)))
+}
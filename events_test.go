@@ -0,0 +1,149 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_Events(t *testing.T) {
+	t.Parallel()
+
+	audio := syntheticWAV(5)
+
+	newScriber := func(t *testing.T, opts ...Option) *Scriber {
+		var calls atomic.Int32
+
+		mockClient := &mockWhisperClient{
+			transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+				_, err := io.ReadAll(in.Data)
+				require.NoError(t, err)
+
+				if calls.Add(1) == 1 {
+					return nil, errors.New("connection reset")
+				}
+				return []byte("text"), nil
+			},
+		}
+
+		opts = append([]Option{
+			WithRetry(RetryConfig{Attempts: 2}),
+			WithSpoolThreshold(0, t.TempDir()),
+			WithConverter(copyConverter),
+		}, opts...)
+		return New(noopLogger(), mockClient, opts...)
+	}
+
+	process := func(t *testing.T, s *Scriber) Output {
+		err := s.Process(context.TODO(), Input{
+			Name:       "test.mp4",
+			OutputType: OutputTypeTranscript,
+			Language:   "en",
+			Data:       io.NopCloser(bytes.NewReader(audio)),
+		})
+		require.NoError(t, err)
+
+		out := <-s.Collect()
+		require.NoError(t, out.Body.Close())
+		return out
+	}
+
+	t.Run("retried job", func(t *testing.T) {
+		t.Parallel()
+
+		before := time.Now()
+		out := process(t, newScriber(t))
+
+		type entry struct {
+			stage Stage
+			msg   string
+		}
+
+		var got []entry
+		for i, e := range out.Events {
+			got = append(got, entry{e.Stage, e.Message})
+
+			assert.False(t, e.Time.Before(before), "event %d", i)
+			before = e.Time
+		}
+
+		assert.Equal(t, []entry{
+			{StageValidation, "Stage started"},
+			{StageValidation, "Processing file"},
+			{StageConversion, "Stage started"},
+			{StageTranscription, "Stage started"},
+			{StageTranscription, "Transcribing audio"},
+			{StageTranscription, "Converted audio"},
+			{StageTranscription, "Classified transcription error"},
+			{StageTranscription, "Transcription failed, retrying from spooled audio"},
+			{StageTranscription, "Transcribing audio"},
+			{StageTranscription, "Received transcription"},
+			{StagePostProcess, "Stage started"},
+		}, got)
+
+		converted := out.Events[5].Attrs
+		assert.Equal(t, strconv.Itoa(len(audio)), converted["converted_bytes"])
+		assert.Equal(t, (5 * time.Second).String(), converted["audio_duration"])
+
+		retried := out.Events[7].Attrs
+		assert.Equal(t, "1", retried["attempt"])
+		assert.Contains(t, retried["error"], "connection reset")
+		assert.NotContains(t, retried, MetadataIdempotencyKey)
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		t.Parallel()
+
+		out := process(t, newScriber(t, WithEventLog(3)))
+
+		require.Len(t, out.Events, 3)
+		assert.Equal(t, "Stage started", out.Events[0].Message)
+		assert.Equal(t, "Processing file", out.Events[1].Message)
+		assert.Equal(t, StagePostProcess, out.Events[2].Stage)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		out := process(t, newScriber(t, WithEventLog(0)))
+		assert.Nil(t, out.Events)
+	})
+}
+
+func TestEventLog_Attach(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	l := newEventLog(10)
+
+	base := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	logger := l.attach(base).WithGroup("chunk").With(slog.Int("index", 2))
+	logger.Debug("Transcribing", slog.String("file", "a.mp4"))
+
+	forked := l.fork()
+	forked.enter(StageTranscription)
+	forked.attach(logger).Info("Forked")
+
+	require.Len(t, l.snapshot(), 1)
+	assert.Equal(t, map[string]string{"chunk.index": "2", "chunk.file": "a.mp4"}, l.snapshot()[0].Attrs)
+
+	events := forked.snapshot()
+	require.Len(t, events, 2)
+	assert.Equal(t, StageAdmission, events[0].Stage)
+	assert.Equal(t, StageTranscription, events[1].Stage)
+	assert.Equal(t, map[string]string{"chunk.index": "2"}, events[1].Attrs)
+
+	// Debug lines are recorded whatever the level of the logger.
+	assert.NotContains(t, buf.String(), "Transcribing")
+	assert.Contains(t, buf.String(), "msg=Forked chunk.index=2")
+}
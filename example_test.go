@@ -0,0 +1,186 @@
+package scriber_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alesr/scriber"
+	"github.com/alesr/whisperclient"
+)
+
+// fakeWhisper stands in for a whisperclient.Client, answering every
+// request with text once it has read the uploaded audio.
+type fakeWhisper struct {
+	text string
+}
+
+func (f fakeWhisper) TranscribeAudio(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+	if _, err := io.Copy(io.Discard, in.Data); err != nil {
+		return nil, err
+	}
+	return []byte(f.text), nil
+}
+
+// copyConverter stands in for ffmpeg, passing the input through.
+func copyConverter(_ context.Context, r io.Reader, w io.Writer) error {
+	_, err := io.Copy(w, r)
+	return err
+}
+
+const exampleSubtitles = "1\n00:00:00,000 --> 00:00:02,000\nHello, world.\n"
+
+func exampleLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func exampleInput(name string) scriber.Input {
+	return scriber.Input{
+		Name:       name,
+		OutputType: scriber.OutputTypeSubtitles,
+		Language:   "en",
+		Data:       io.NopCloser(strings.NewReader("audio")),
+	}
+}
+
+func Example() {
+	s := scriber.New(exampleLogger(), fakeWhisper{text: exampleSubtitles}, scriber.WithConverter(copyConverter))
+
+	if err := s.Process(context.Background(), exampleInput("talk.mp4")); err != nil {
+		fmt.Println("process:", err)
+		return
+	}
+
+	out := <-s.Collect()
+	defer out.Body.Close()
+
+	fmt.Println(out.Name)
+	fmt.Print(string(out.Text))
+	// Output:
+	// talk.srt
+	// 1
+	// 00:00:00,000 --> 00:00:02,000
+	// Hello, world.
+}
+
+func ExampleScriber_ProcessBatch() {
+	s := scriber.New(exampleLogger(), fakeWhisper{text: exampleSubtitles}, scriber.WithConverter(copyConverter))
+
+	var names []string
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for out := range s.Collect() {
+			names = append(names, out.Name)
+			out.Body.Close()
+		}
+	}()
+
+	batch := []scriber.Input{exampleInput("a.mp4"), exampleInput("b.mp4"), exampleInput("c.mp4")}
+	report, err := s.ProcessBatch(context.Background(), batch, scriber.BatchOptions{Parallelism: 2})
+	if err != nil {
+		fmt.Println("batch:", err)
+		return
+	}
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		fmt.Println("shutdown:", err)
+		return
+	}
+	<-collected
+
+	sort.Strings(names)
+	fmt.Println(len(report.Succeeded), "succeeded:", strings.Join(names, " "))
+	// Output:
+	// 3 succeeded: a.srt b.srt c.srt
+}
+
+func ExampleWithConverter() {
+	// A converter turns the input into the audio uploaded to the backend.
+	// This one counts the bytes it passes through instead of running ffmpeg.
+	var converted int64
+	convert := func(_ context.Context, r io.Reader, w io.Writer) error {
+		n, err := io.Copy(w, r)
+		converted += n
+		return err
+	}
+
+	s := scriber.New(exampleLogger(), fakeWhisper{text: exampleSubtitles}, scriber.WithConverter(convert))
+
+	if err := s.Process(context.Background(), exampleInput("talk.mp4")); err != nil {
+		fmt.Println("process:", err)
+		return
+	}
+
+	out := <-s.Collect()
+	defer out.Body.Close()
+
+	fmt.Println(out.Name, converted, "bytes converted")
+	// Output:
+	// talk.srt 5 bytes converted
+}
+
+func ExampleWithRedactor() {
+	s := scriber.New(exampleLogger(), fakeWhisper{text: "Write to jane@example.com."},
+		scriber.WithConverter(copyConverter),
+		scriber.WithRedactor(scriber.Redactor{Emails: true}),
+	)
+
+	in := exampleInput("call.mp4")
+	in.OutputType = scriber.OutputTypeTranscript
+
+	if err := s.Process(context.Background(), in); err != nil {
+		fmt.Println("process:", err)
+		return
+	}
+
+	out := <-s.Collect()
+	defer out.Body.Close()
+
+	fmt.Println(string(out.Text))
+	fmt.Println(out.Metadata[scriber.MetadataRedactedPrefix+string(scriber.RedactEmail)], "redacted")
+	// Output:
+	// Write to [EMAIL].
+	// 1 redacted
+}
+
+func ExampleWithOutputDir() {
+	dir, err := os.MkdirTemp("", "scriber-example-*")
+	if err != nil {
+		fmt.Println("temp dir:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	s := scriber.New(exampleLogger(), fakeWhisper{text: exampleSubtitles},
+		scriber.WithConverter(copyConverter),
+		scriber.WithOutputDir(dir),
+	)
+
+	if err := s.Process(context.Background(), exampleInput("talk.mp4")); err != nil {
+		fmt.Println("process:", err)
+		return
+	}
+
+	out := <-s.Collect()
+	defer out.Body.Close()
+
+	written, err := os.ReadFile(out.Path)
+	if err != nil {
+		fmt.Println("read:", err)
+		return
+	}
+
+	fmt.Println(filepath.Base(out.Path))
+	fmt.Print(string(written))
+	// Output:
+	// talk.srt
+	// 1
+	// 00:00:00,000 --> 00:00:02,000
+	// Hello, world.
+}
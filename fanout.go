@@ -0,0 +1,132 @@
+package scriber
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// processLanguages converts the job's input once and transcribes it
+// concurrently in each of the input's languages, publishing one output
+// per language. Languages that fail don't prevent the others from being
+// published; their errors are joined.
+func (s *Scriber) processLanguages(ctx context.Context, j *job) error {
+	if err := s.checkConfig(); err != nil {
+		return s.fail(j, StageValidation, err)
+	}
+	if err := s.checkUploadSize(j); err != nil {
+		return s.fail(j, StageValidation, err)
+	}
+
+	audio, err := s.convertToFile(ctx, j)
+	if err != nil {
+		return s.fail(j, StageConversion, err)
+	}
+	defer func() {
+		audio.Close()
+		os.Remove(audio.Name())
+	}()
+
+	return s.processLanguagesFile(ctx, j, audio)
+}
+
+// processLanguagesFile transcribes the WAV file audio concurrently in each
+// of the job's languages, as processLanguages does once it is converted.
+func (s *Scriber) processLanguagesFile(ctx context.Context, j *job, audio *os.File) error {
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, len(j.in.Languages))
+		jobs = make([]*job, len(j.in.Languages))
+	)
+
+	for i, lang := range j.in.Languages {
+		jobs[i] = j.forLanguage(lang)
+
+		wg.Add(1)
+		go func(i int, lj *job) {
+			defer wg.Done()
+			defer func() {
+				if v := recover(); v != nil {
+					errs[i] = s.fail(lj, StageTranscription, newPanicError(v))
+				}
+			}()
+			errs[i] = s.processLanguage(ctx, lj, audio)
+		}(i, jobs[i])
+	}
+	wg.Wait()
+
+	// Every language transcribes the same audio.
+	for _, lj := range jobs {
+		j.audioDuration = max(j.audioDuration, lj.audioDuration)
+	}
+
+	return errors.Join(errs...)
+}
+
+// processLanguage transcribes the converted audio in the job's language and publishes it.
+func (s *Scriber) processLanguage(ctx context.Context, j *job, audio *os.File) error {
+	ctx = withIdempotencyKey(ctx, j.idempotencyKey)
+
+	var (
+		text []byte
+		err  error
+	)
+	switch {
+	case s.vad != nil:
+		text, err = s.transcribeSpeechFile(ctx, j, audio)
+	case s.chunking != nil:
+		text, err = s.transcribeChunkedFile(ctx, j, audio)
+	default:
+		text, err = s.transcribeFile(ctx, j, audio)
+	}
+	if err != nil {
+		return s.fail(j, StageTranscription, err)
+	}
+	return s.complete(ctx, j, text)
+}
+
+// transcribeFile transcribes the WAV file audio in a single request.
+func (s *Scriber) transcribeFile(ctx context.Context, j *job, audio *os.File) ([]byte, error) {
+	format, dataOffset, dataLen, err := wavFileLayout(audio)
+	if err != nil {
+		return nil, stageError(StageConversion, err)
+	}
+
+	size := dataOffset + dataLen
+	j.audioDuration = format.Duration(dataLen)
+	j.convertedBytes = size
+	j.uploadSpec = wavSpec(format)
+
+	if err := s.checkFileUploadSize(size); err != nil {
+		return nil, stageError(StageTranscription, err)
+	}
+
+	start := time.Now()
+	text, err := s.transcribeRetrying(ctx, j, func() io.Reader {
+		return io.NewSectionReader(audio, 0, size)
+	}, j.audioDuration)
+	j.timing.Transcribe = time.Since(start)
+	if err != nil {
+		return nil, stageError(StageTranscription, err)
+	}
+	return text, nil
+}
+
+// forLanguage returns a copy of the job transcribing its input in lang.
+// The copy has no input data, since the input has been converted already.
+func (j *job) forLanguage(lang string) *job {
+	lj := *j
+	lj.in.Language = lang
+	lj.in.Languages = nil
+	lj.in.Data = nil
+	lj.idempotencyKey = languageIdempotencyKey(j.idempotencyKey, lang)
+	lj.languageInName = true
+	lj.events = j.events.fork()
+	lj.warnings = j.warnings.fork()
+	lj.logger = lj.events.attach(j.logger).With(slog.String("language", lang))
+	return &lj
+}
@@ -0,0 +1,162 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_Languages(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		givenOpts     []Option
+		givenFailLang string
+		expectedCalls int32
+		expectedNames []string
+	}{
+		{
+			name:          "streamed",
+			expectedCalls: 3,
+			expectedNames: []string{"test.de.txt", "test.en.txt", "test.pt.txt"},
+		},
+		{
+			name:          "chunked",
+			givenOpts:     []Option{WithChunking(ChunkConfig{Length: time.Second})},
+			expectedCalls: 6,
+			expectedNames: []string{"test.de.txt", "test.en.txt", "test.pt.txt"},
+		},
+		{
+			name:          "one language fails",
+			givenFailLang: "de",
+			expectedCalls: 3,
+			expectedNames: []string{"test.en.txt", "test.pt.txt"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				calls, conversions atomic.Int32
+
+				mu   sync.Mutex
+				keys = map[string]bool{}
+			)
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					calls.Add(1)
+
+					key, _ := IdempotencyKeyFromContext(ctx)
+					mu.Lock()
+					keys[key] = true
+					mu.Unlock()
+
+					if _, err := io.Copy(io.Discard, in.Data); err != nil {
+						return nil, err
+					}
+					if in.Language == tc.givenFailLang {
+						return nil, assert.AnError
+					}
+					return []byte("text in " + in.Language), nil
+				},
+			}
+
+			opts := append([]Option{WithConverter(func(_ context.Context, r io.Reader, w io.Writer) error {
+				conversions.Add(1)
+				_, err := w.Write(syntheticWAV(2))
+				return err
+			})}, tc.givenOpts...)
+
+			s := New(noopLogger(), mockClient, opts...)
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "test.mp4",
+				OutputType: OutputTypeTranscript,
+				Languages:  []string{"en", "pt", "de"},
+				Data:       io.NopCloser(bytes.NewBufferString("media")),
+			})
+
+			assert.EqualValues(t, 1, conversions.Load())
+			assert.Equal(t, tc.expectedCalls, calls.Load())
+			assert.Len(t, keys, int(tc.expectedCalls), "every request should have its own key")
+
+			if tc.givenFailLang != "" {
+				var pe *ProcessError
+				require.ErrorAs(t, err, &pe)
+				assert.Equal(t, StageTranscription, pe.Stage)
+				assert.Equal(t, tc.givenFailLang, pe.Input.Language)
+			} else {
+				require.NoError(t, err)
+			}
+
+			var (
+				names  []string
+				jobIDs = map[string]bool{}
+			)
+			for range tc.expectedNames {
+				out := <-s.Collect()
+				out.Body.Close()
+
+				names = append(names, out.Name)
+				jobIDs[out.JobID] = true
+				assert.Equal(t, "text in "+out.Language, string(out.Text))
+				assert.Equal(t, 2*time.Second, out.AudioDuration)
+			}
+			sort.Strings(names)
+
+			assert.Equal(t, tc.expectedNames, names)
+			assert.Len(t, jobIDs, 1, "outputs should share the job ID")
+		})
+	}
+}
+
+func TestInputValidate_Languages(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		givenLang string
+		givenLngs []string
+		expected  error
+	}{
+		{name: "languages only", givenLngs: []string{"en", "pt"}},
+		{name: "both", givenLang: "en", givenLngs: []string{"pt"}, expected: errLanguagesExclusive},
+		{name: "auto", givenLngs: []string{"en", LanguageAuto}, expected: errLanguagesInvalid},
+		{name: "empty", givenLngs: []string{""}, expected: errLanguagesInvalid},
+		{name: "repeated", givenLngs: []string{"en", "en"}, expected: errLanguagesInvalid},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			in := Input{
+				Name:       "test.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   tc.givenLang,
+				Languages:  tc.givenLngs,
+				Data:       io.NopCloser(bytes.NewBufferString("media")),
+			}
+
+			err := in.validate()
+			if tc.expected == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package scriber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// MetadataFFmpegCommand is the Output.Metadata key holding the ffmpeg
+// command the input was converted with, to reproduce conversions offline.
+// It is only set when the default converter is used.
+const MetadataFFmpegCommand = "ffmpeg_command"
+
+// ffmpegConfig holds the settings of the default converter.
+type ffmpegConfig struct {
+	sampleRate int
+	channels   int
+	bitrate    string
+	codec      AudioCodec
+}
+
+// ffmpegInput holds the settings of the default converter specific to
+// the input of a job.
+type ffmpegInput struct {
+	// codec is the codec the input is converted to,
+	// overriding the one of the config if not empty.
+	codec AudioCodec
+
+	// filter is an audio filter graph applied before encoding.
+	filter string
+}
+
+func defaultFFmpegConfig() ffmpegConfig {
+	return ffmpegConfig{
+		sampleRate: convertedSampleRate,
+		channels:   convertedChannels,
+		bitrate:    "32k",
+		codec:      CodecWAV,
+	}
+}
+
+// ffmpegConfigFor returns the config of the default converter producing spec.
+func ffmpegConfigFor(spec AudioSpec) ffmpegConfig {
+	cfg := defaultFFmpegConfig()
+	cfg.sampleRate, cfg.channels, cfg.codec = spec.SampleRate, spec.Channels, spec.Codec
+	return cfg
+}
+
+// buildFFmpegArgs returns the arguments ffmpeg converts in with, without
+// the program name. The input is read from stdin and the audio written to
+// stdout, as WAV unless in or the config asks for FLAC.
+func buildFFmpegArgs(cfg ffmpegConfig, in ffmpegInput) []string {
+	if in.codec != "" {
+		cfg.codec = in.codec
+	}
+
+	codec, format := "pcm_s16le", "wav"
+	if cfg.codec == CodecFLAC {
+		// FLAC is lossless, so the bitrate doesn't apply.
+		codec, format = "flac", "flac"
+		cfg.bitrate = ""
+	}
+
+	args := []string{"-y", "-i", "pipe:0", "-vn"}
+	if in.filter != "" {
+		args = append(args, "-af", in.filter)
+	}
+	args = append(args, "-acodec", codec)
+	if cfg.sampleRate > 0 {
+		args = append(args, "-ar", strconv.Itoa(cfg.sampleRate))
+	}
+	if cfg.channels > 0 {
+		args = append(args, "-ac", strconv.Itoa(cfg.channels))
+	}
+	if cfg.bitrate != "" {
+		args = append(args, "-b:a", cfg.bitrate)
+	}
+	return append(args, "-f", format, "pipe:1")
+}
+
+// ffmpegArgsFor returns the arguments the default converter converts
+// in with, or nil when the converter is replaced.
+func (s *Scriber) ffmpegArgsFor(in ffmpegInput) []string {
+	if s.ffmpeg == nil {
+		return nil
+	}
+	return buildFFmpegArgs(*s.ffmpeg, in)
+}
+
+// ffmpegCommand renders args as a shell command line, quoting
+// the arguments that need it.
+func ffmpegCommand(args []string) string {
+	quoted := make([]string, 0, len(args)+1)
+	quoted = append(quoted, "ffmpeg")
+	for _, a := range args {
+		if a == "" || strings.ContainsAny(a, " \t\n'\"\\$`|&;<>()*?[]#~") {
+			a = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		}
+		quoted = append(quoted, a)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// runFFmpeg is the default converter, which pipes the audio
+// through ffmpeg run with args.
+func runFFmpeg(ctx context.Context, args []string, r io.Reader, w io.Writer) error {
+	return runFFmpegCmd(ctx, exec.CommandContext(ctx, "ffmpeg", args...), r, w)
+}
+
+// ffmpegStderrLimit is the number of trailing bytes of the stderr
+// output of ffmpeg kept to report failed conversions.
+const ffmpegStderrLimit = 8 << 10
+
+// runFFmpegCmd runs the ffmpeg command cmd, whose stderr output is
+// captured rather than written to the host's, and returns an
+// *ffmpegError holding its end if the command fails.
+func runFFmpegCmd(ctx context.Context, cmd *exec.Cmd, r io.Reader, w io.Writer) error {
+	stderr := &tailBuffer{limit: ffmpegStderrLimit}
+	cmd.Stderr = stderr
+
+	if err := runConverter(cmd, r, w); err != nil {
+		if ctx.Err() != nil {
+			// ffmpeg was killed because the job was canceled.
+			err = ctx.Err()
+		}
+		return &ffmpegError{err: err, stderr: strings.TrimSpace(string(stderr.buf))}
+	}
+	return nil
+}
+
+// ffmpegError reports a failed ffmpeg run.
+type ffmpegError struct {
+	err error
+
+	// stderr is the end of the stderr output of ffmpeg.
+	stderr string
+}
+
+func (e *ffmpegError) Error() string {
+	if e.stderr == "" {
+		return fmt.Sprintf("ffmpeg failed: %v", e.err)
+	}
+	return fmt.Sprintf("ffmpeg failed: %v: %s", e.err, e.stderr)
+}
+
+func (e *ffmpegError) Unwrap() error {
+	return e.err
+}
+
+// logFFmpegOutput logs the stderr output of ffmpeg at debug level
+// if err reports a failed ffmpeg run.
+func (j *job) logFFmpegOutput(err error) {
+	var fe *ffmpegError
+	if errors.As(err, &fe) && fe.stderr != "" {
+		j.logger.Debug("ffmpeg output", slog.String("file", j.in.Name), slog.String("stderr", fe.stderr))
+	}
+}
+
+// tailBuffer is a writer keeping the last limit bytes written to it.
+type tailBuffer struct {
+	limit int
+	buf   []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(p) > b.limit {
+		p = p[len(p)-b.limit:]
+	}
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.limit; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	return n, nil
+}
@@ -0,0 +1,232 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os/exec"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildFFmpegArgs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		givenConfig ffmpegConfig
+		givenInput  ffmpegInput
+		expected    []string
+	}{
+		{
+			name:        "default",
+			givenConfig: defaultFFmpegConfig(),
+			expected:    []string{"-y", "-i", "pipe:0", "-vn", "-acodec", "pcm_s16le", "-ar", "5200", "-ac", "2", "-b:a", "32k", "-f", "wav", "pipe:1"},
+		},
+		{
+			name:        "mono at 16 kHz",
+			givenConfig: ffmpegConfig{sampleRate: 16000, channels: 1, bitrate: "32k"},
+			expected:    []string{"-y", "-i", "pipe:0", "-vn", "-acodec", "pcm_s16le", "-ar", "16000", "-ac", "1", "-b:a", "32k", "-f", "wav", "pipe:1"},
+		},
+		{
+			name:        "source sample rate",
+			givenConfig: ffmpegConfig{channels: 2, bitrate: "32k"},
+			expected:    []string{"-y", "-i", "pipe:0", "-vn", "-acodec", "pcm_s16le", "-ac", "2", "-b:a", "32k", "-f", "wav", "pipe:1"},
+		},
+		{
+			name:        "source channels and bitrate",
+			givenConfig: ffmpegConfig{sampleRate: 8000},
+			expected:    []string{"-y", "-i", "pipe:0", "-vn", "-acodec", "pcm_s16le", "-ar", "8000", "-f", "wav", "pipe:1"},
+		},
+		{
+			name:     "zero config",
+			expected: []string{"-y", "-i", "pipe:0", "-vn", "-acodec", "pcm_s16le", "-f", "wav", "pipe:1"},
+		},
+		{
+			name:        "FLAC config",
+			givenConfig: ffmpegConfig{sampleRate: 16000, channels: 1, bitrate: "32k", codec: CodecFLAC},
+			expected:    []string{"-y", "-i", "pipe:0", "-vn", "-acodec", "flac", "-ar", "16000", "-ac", "1", "-f", "flac", "pipe:1"},
+		},
+		{
+			name:        "input codec over the config's",
+			givenConfig: ffmpegConfig{sampleRate: 16000, channels: 1, bitrate: "32k", codec: CodecFLAC},
+			givenInput:  ffmpegInput{codec: CodecWAV},
+			expected:    []string{"-y", "-i", "pipe:0", "-vn", "-acodec", "pcm_s16le", "-ar", "16000", "-ac", "1", "-b:a", "32k", "-f", "wav", "pipe:1"},
+		},
+		{
+			name:        "input filter",
+			givenConfig: defaultFFmpegConfig(),
+			givenInput:  ffmpegInput{filter: "pan=stereo|FL=FC+FL|FR=FC+FR"},
+			expected:    []string{"-y", "-i", "pipe:0", "-vn", "-af", "pan=stereo|FL=FC+FL|FR=FC+FR", "-acodec", "pcm_s16le", "-ar", "5200", "-ac", "2", "-b:a", "32k", "-f", "wav", "pipe:1"},
+		},
+		{
+			name:        "input filter and codec",
+			givenConfig: ffmpegConfig{sampleRate: 16000, channels: 1},
+			givenInput:  ffmpegInput{codec: CodecFLAC, filter: "volume=2"},
+			expected:    []string{"-y", "-i", "pipe:0", "-vn", "-af", "volume=2", "-acodec", "flac", "-ar", "16000", "-ac", "1", "-f", "flac", "pipe:1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, buildFFmpegArgs(tc.givenConfig, tc.givenInput))
+		})
+	}
+}
+
+func TestFFmpegCommand(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t,
+		"ffmpeg -y -i pipe:0 -vn -acodec pcm_s16le -ar 5200 -ac 2 -b:a 32k -f wav pipe:1",
+		ffmpegCommand(buildFFmpegArgs(defaultFFmpegConfig(), ffmpegInput{})),
+	)
+	assert.Equal(t, `ffmpeg -af 'volume=0.5, pan' '' 'it'\''s'`, ffmpegCommand([]string{"-af", "volume=0.5, pan", "", "it's"}))
+}
+
+func TestProcess_FFmpegCommand(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name            string
+		givenOpts       []Option
+		expectedCommand string
+	}{
+		{
+			name:            "default converter",
+			expectedCommand: "ffmpeg -y -i pipe:0 -vn -acodec pcm_s16le -ar 5200 -ac 2 -b:a 32k -f wav pipe:1",
+		},
+		{
+			name:      "custom converter",
+			givenOpts: []Option{WithConverter(copyConverter)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.ReadAll(in.Data)
+					return []byte("hello"), err
+				},
+			}, tc.givenOpts...)
+
+			// Stand in for ffmpeg, keeping the arguments of the default converter.
+			s.convertToWavFunc = copyConverter
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "talk.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+			})
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+
+			var started map[string]string
+			for _, e := range out.Events {
+				if e.Stage == StageConversion && e.Message == "Stage started" {
+					started = e.Attrs
+				}
+			}
+			require.NotNil(t, started)
+
+			if tc.expectedCommand == "" {
+				assert.NotContains(t, out.Metadata, MetadataFFmpegCommand)
+				assert.NotContains(t, started, MetadataFFmpegCommand)
+				return
+			}
+			assert.Equal(t, tc.expectedCommand, out.Metadata[MetadataFFmpegCommand])
+			assert.Equal(t, tc.expectedCommand, started[MetadataFFmpegCommand])
+		})
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		given    []string
+		expected string
+	}{
+		{
+			name:     "under the limit",
+			given:    []string{"ab", "cd"},
+			expected: "abcd",
+		},
+		{
+			name:     "over the limit across writes",
+			given:    []string{"abc", "def"},
+			expected: "cdef",
+		},
+		{
+			name:     "write over the limit",
+			given:    []string{"a", "bcdefgh"},
+			expected: "efgh",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := &tailBuffer{limit: 4}
+			for _, p := range tc.given {
+				n, err := b.Write([]byte(p))
+				require.NoError(t, err)
+				assert.Equal(t, len(p), n)
+			}
+			assert.Equal(t, tc.expected, string(b.buf))
+		})
+	}
+}
+
+func TestProcess_FFmpegStderr(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skipf("sh not available: %v", err)
+	}
+
+	handler := newCapturingHandler()
+	s := New(slog.New(handler), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.ReadAll(in.Data)
+			return []byte("hello"), err
+		},
+	})
+
+	// Stand in for ffmpeg, failing with an error message.
+	s.runFFmpeg = func(ctx context.Context, _ []string, r io.Reader, w io.Writer) error {
+		return runFFmpegCmd(ctx, exec.CommandContext(ctx, "sh", "-c", "cat >/dev/null; echo 'Invalid data found' >&2; exit 1"), r, w)
+	}
+
+	err := s.Process(context.TODO(), Input{
+		Name:       "talk.mp4",
+		OutputType: OutputTypeTranscript,
+		Language:   "en",
+		Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid data found")
+
+	var logged bool
+	for _, e := range handler.entries() {
+		if e.msg == "ffmpeg output" {
+			logged = true
+			assert.Equal(t, "Invalid data found", e.attrs["scriber.stderr"])
+		}
+	}
+	assert.True(t, logged, "ffmpeg output must be logged")
+}
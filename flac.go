@@ -0,0 +1,54 @@
+package scriber
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+// flacHeaderSize is the size of the "fLaC" marker
+// and of the STREAMINFO block following it.
+const flacHeaderSize = 42
+
+var errNotFLAC = errors.New("not a FLAC stream")
+
+// flacStreamInfo holds the fields of a FLAC STREAMINFO block scriber uses.
+type flacStreamInfo struct {
+	sampleRate int
+	channels   int
+
+	// totalSamples is the number of samples per channel,
+	// or zero when unknown, as for streamed output.
+	totalSamples int64
+}
+
+// duration returns the duration of the stream, or zero if it is unknown.
+func (i flacStreamInfo) duration() time.Duration {
+	if i.sampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(i.totalSamples) * time.Second / time.Duration(i.sampleRate)
+}
+
+// readFLACHeader reads the "fLaC" marker from r and the STREAMINFO block
+// that must follow it.
+func readFLACHeader(r io.Reader) (flacStreamInfo, error) {
+	var b [flacHeaderSize]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return flacStreamInfo{}, err
+	}
+
+	// The marker is followed by the metadata block header: the last-block
+	// flag and the block type, zero for STREAMINFO, then its 24-bit length.
+	if string(b[:4]) != "fLaC" || b[4]&0x7f != 0 || b[5] != 0 || b[6] != 0 || b[7] != 34 {
+		return flacStreamInfo{}, errNotFLAC
+	}
+
+	info := b[8:]
+	return flacStreamInfo{
+		sampleRate:   int(info[10])<<12 | int(info[11])<<4 | int(info[12])>>4,
+		channels:     int(info[12]>>1&0x07) + 1,
+		totalSamples: int64(info[13]&0x0f)<<32 | int64(binary.BigEndian.Uint32(info[14:18])),
+	}, nil
+}
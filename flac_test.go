@@ -0,0 +1,114 @@
+package scriber
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syntheticFLACHeader returns the "fLaC" marker and a STREAMINFO
+// block declaring the given format and number of samples.
+func syntheticFLACHeader(sampleRate, channels int, totalSamples int64) []byte {
+	b := make([]byte, flacHeaderSize)
+	copy(b, "fLaC")
+	b[4], b[7] = 0x80, 34 // The last metadata block, of 34 bytes.
+
+	info := b[8:]
+	info[10] = byte(sampleRate >> 12)
+	info[11] = byte(sampleRate >> 4)
+	info[12] = byte(sampleRate<<4) | byte(channels-1)<<1 // 16 bits per sample: 15, split over bytes 12 and 13.
+	info[13] = 0xf0 | byte(totalSamples>>32&0x0f)
+	binary.BigEndian.PutUint32(info[14:18], uint32(totalSamples))
+	return b
+}
+
+func TestReadFLACHeader(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		given         []byte
+		expectedInfo  flacStreamInfo
+		expectedError error
+	}{
+		{
+			name:         "streamed",
+			given:        syntheticFLACHeader(16000, 1, 0),
+			expectedInfo: flacStreamInfo{sampleRate: 16000, channels: 1},
+		},
+		{
+			name:         "with number of samples",
+			given:        syntheticFLACHeader(44100, 2, 1<<33+7),
+			expectedInfo: flacStreamInfo{sampleRate: 44100, channels: 2, totalSamples: 1<<33 + 7},
+		},
+		{
+			name:          "wav",
+			given:         syntheticWAV(1),
+			expectedError: errNotFLAC,
+		},
+		{
+			name:          "short",
+			given:         syntheticFLACHeader(16000, 1, 0)[:20],
+			expectedError: io.ErrUnexpectedEOF,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			info, err := readFLACHeader(bytes.NewReader(tc.given))
+			require.ErrorIs(t, err, tc.expectedError)
+			assert.Equal(t, tc.expectedInfo, info)
+		})
+	}
+}
+
+func TestFLACCounter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("declared duration", func(t *testing.T) {
+		t.Parallel()
+
+		src := append(syntheticFLACHeader(16000, 1, 48000), bytes.Repeat([]byte{0xff}, 100)...)
+
+		var dst bytes.Buffer
+		counter := newFLACCounter(&dst)
+		for len(src) > 0 {
+			n := min(7, len(src))
+			_, err := counter.Write(src[:n])
+			require.NoError(t, err)
+			src = src[n:]
+		}
+
+		assert.Equal(t, int64(flacHeaderSize+100), counter.n)
+		assert.Equal(t, 3*time.Second, counter.duration())
+	})
+
+	t.Run("streamed", func(t *testing.T) {
+		t.Parallel()
+
+		counter := newFLACCounter(io.Discard)
+		_, err := counter.Write(syntheticFLACHeader(16000, 1, 0))
+		require.NoError(t, err)
+
+		assert.True(t, counter.parsed)
+		assert.Equal(t, time.Duration(0), counter.duration())
+	})
+
+	t.Run("wav stream", func(t *testing.T) {
+		t.Parallel()
+
+		counter := newFLACCounter(io.Discard)
+		_, err := counter.Write(syntheticWAV(1))
+		require.NoError(t, err)
+
+		assert.False(t, counter.parsed)
+		assert.Equal(t, time.Duration(0), counter.duration())
+	})
+}
@@ -0,0 +1,141 @@
+package scriber
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// MusicNotePolicy controls the music note markers in transcriptions.
+type MusicNotePolicy int
+
+const (
+	// MusicNotesKeep leaves music notes as the backend wrote them.
+	MusicNotesKeep MusicNotePolicy = iota
+
+	// MusicNotesNormalize replaces every music note variant with ♪.
+	MusicNotesNormalize
+
+	// MusicNotesRemove removes music notes.
+	MusicNotesRemove
+)
+
+// FormattingConfig configures the clean-up of the annotations
+// the backend adds to transcriptions. Each setting is independent;
+// the zero value leaves transcriptions untouched.
+type FormattingConfig struct {
+	// RemoveSoundDescriptions removes sound descriptions such as [music]
+	// or (LAUGHS). Square-bracketed annotations are always removed.
+	// Parenthesized ones are removed when they fill a whole line or
+	// are written in capitals, so parentheses in speech are kept.
+	RemoveSoundDescriptions bool
+
+	// RemoveItalics removes <i> tags, keeping the text they enclose.
+	RemoveItalics bool
+
+	// MusicNotes controls music note markers such as ♪ and ♫.
+	MusicNotes MusicNotePolicy
+}
+
+// enabled reports whether cfg changes anything.
+func (c FormattingConfig) enabled() bool {
+	return c.RemoveSoundDescriptions || c.RemoveItalics || c.MusicNotes != MusicNotesKeep
+}
+
+// WithFormatting cleans up the annotations in transcriptions as configured.
+// Subtitle cues left empty are dropped, and the remaining ones renumbered.
+// Output.Text carries the cleaned-up transcription.
+func WithFormatting(cfg FormattingConfig) Option {
+	return func(s *Scriber) {
+		s.formatting = cfg
+	}
+}
+
+var (
+	bracketAnnotationPattern = regexp.MustCompile(`\[[^\[\]\n]{1,40}\]`)
+	parenAnnotationPattern   = regexp.MustCompile(`[(（][^()（）\n]{1,40}[)）]`)
+	italicTagPattern         = regexp.MustCompile(`(?i)</?i>`)
+	musicNotePattern         = regexp.MustCompile(`[♪♫♩♬🎵🎶]+`)
+	spacesPattern            = regexp.MustCompile(`[ \t]{2,}`)
+)
+
+// applyFormatting cleans up text, a transcription of type t, as cfg says.
+func applyFormatting(text []byte, t OutputType, cfg FormattingConfig) []byte {
+	if !cfg.enabled() {
+		return text
+	}
+
+	if isSubtitles(t) {
+		if cues, err := parseSRT(text); err == nil {
+			kept := cues[:0]
+			for _, c := range cues {
+				if c.Text = formatLines(c.Text, cfg); c.Text != "" {
+					kept = append(kept, c)
+				}
+			}
+			return formatSRT(kept)
+		}
+	}
+	return []byte(formatLines(string(text), cfg))
+}
+
+// formatLines cleans up each line of text, dropping the lines left empty.
+func formatLines(text string, cfg FormattingConfig) string {
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		cleaned := formatLine(line, cfg)
+		if cleaned == "" && strings.TrimSpace(line) != "" {
+			continue
+		}
+		kept = append(kept, cleaned)
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// formatLine cleans up a single line. It returns an empty string if the
+// clean-up left nothing but punctuation, e.g. the dash of "- [music]".
+func formatLine(line string, cfg FormattingConfig) string {
+	orig := strings.TrimSpace(line)
+
+	if cfg.RemoveSoundDescriptions {
+		line = bracketAnnotationPattern.ReplaceAllString(line, "")
+
+		whole := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-"))
+		line = parenAnnotationPattern.ReplaceAllStringFunc(line, func(m string) string {
+			if m == whole || isCapitalized(m) {
+				return ""
+			}
+			return m
+		})
+	}
+
+	if cfg.RemoveItalics {
+		line = italicTagPattern.ReplaceAllString(line, "")
+	}
+
+	switch cfg.MusicNotes {
+	case MusicNotesNormalize:
+		line = musicNotePattern.ReplaceAllString(line, "♪")
+	case MusicNotesRemove:
+		line = musicNotePattern.ReplaceAllString(line, "")
+	}
+
+	line = strings.TrimSpace(spacesPattern.ReplaceAllString(line, " "))
+	if line != orig && !strings.ContainsFunc(line, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '♪' }) {
+		return ""
+	}
+	return line
+}
+
+// isCapitalized reports whether s has cased letters, all of them upper case.
+func isCapitalized(s string) bool {
+	var upper bool
+	for _, r := range s {
+		if unicode.IsLower(r) {
+			return false
+		}
+		upper = upper || unicode.IsUpper(r)
+	}
+	return upper
+}
@@ -0,0 +1,196 @@
+package scriber
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatLines(t *testing.T) {
+	t.Parallel()
+
+	removeSounds := FormattingConfig{RemoveSoundDescriptions: true}
+
+	testCases := []struct {
+		name     string
+		given    string
+		givenCfg FormattingConfig
+		expected string
+	}{
+		{
+			name:     "english brackets",
+			given:    "[music] Welcome back",
+			givenCfg: removeSounds,
+			expected: "Welcome back",
+		},
+		{
+			name:     "spanish brackets",
+			given:    "Hola a todos [MÚSICA]",
+			givenCfg: removeSounds,
+			expected: "Hola a todos",
+		},
+		{
+			name:     "german brackets",
+			given:    "[Applaus]\nDanke schön",
+			givenCfg: removeSounds,
+			expected: "Danke schön",
+		},
+		{
+			name:     "japanese brackets",
+			given:    "[拍手] ありがとうございます",
+			givenCfg: removeSounds,
+			expected: "ありがとうございます",
+		},
+		{
+			name:     "japanese full-width parentheses on their own line",
+			given:    "（笑）\nそうですね",
+			givenCfg: removeSounds,
+			expected: "そうですね",
+		},
+		{
+			name:     "portuguese parentheses on their own line",
+			given:    "- (risos)\nQue engraçado",
+			givenCfg: removeSounds,
+			expected: "Que engraçado",
+		},
+		{
+			name:     "capitalized parentheses",
+			given:    "That's great (LAUGHS) really",
+			givenCfg: removeSounds,
+			expected: "That's great really",
+		},
+		{
+			name:     "parentheses in speech are kept",
+			given:    "I (really) think it works (mostly)",
+			givenCfg: removeSounds,
+			expected: "I (really) think it works (mostly)",
+		},
+		{
+			name:     "french parentheses in speech are kept",
+			given:    "C'est vrai (enfin, presque)",
+			givenCfg: removeSounds,
+			expected: "C'est vrai (enfin, presque)",
+		},
+		{
+			name:     "italics removed",
+			given:    "<i>Previously</i> on the show",
+			givenCfg: FormattingConfig{RemoveItalics: true},
+			expected: "Previously on the show",
+		},
+		{
+			name:     "italics kept",
+			given:    "<i>Previously</i> on the show",
+			givenCfg: removeSounds,
+			expected: "<i>Previously</i> on the show",
+		},
+		{
+			name:     "music notes normalized",
+			given:    "♫ Happy birthday to you ♬♬",
+			givenCfg: FormattingConfig{MusicNotes: MusicNotesNormalize},
+			expected: "♪ Happy birthday to you ♪",
+		},
+		{
+			name:     "music notes removed",
+			given:    "♪ Parabéns a você ♪\n♪♪",
+			givenCfg: FormattingConfig{MusicNotes: MusicNotesRemove},
+			expected: "Parabéns a você",
+		},
+		{
+			name:     "music notes kept",
+			given:    "♫ Happy birthday ♫",
+			givenCfg: removeSounds,
+			expected: "♫ Happy birthday ♫",
+		},
+		{
+			name:     "punctuation-only lines are kept when untouched",
+			given:    "...\nWell",
+			givenCfg: removeSounds,
+			expected: "...\nWell",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, formatLines(tc.given, tc.givenCfg))
+		})
+	}
+}
+
+func TestApplyFormatting(t *testing.T) {
+	t.Parallel()
+
+	given := "1\n00:00:00,000 --> 00:00:02,000\n[music]\n\n" +
+		"2\n00:00:02,000 --> 00:00:04,000\n<i>Hello</i> (LAUGHS)\nthere\n\n" +
+		"3\n00:00:04,000 --> 00:00:06,000\n♫ la la ♫\n"
+
+	testCases := []struct {
+		name     string
+		givenCfg FormattingConfig
+		expected string
+	}{
+		{
+			name:     "disabled",
+			expected: given,
+		},
+		{
+			name: "everything",
+			givenCfg: FormattingConfig{
+				RemoveSoundDescriptions: true,
+				RemoveItalics:           true,
+				MusicNotes:              MusicNotesNormalize,
+			},
+			expected: "1\n00:00:02,000 --> 00:00:04,000\nHello\nthere\n\n" +
+				"2\n00:00:04,000 --> 00:00:06,000\n♪ la la ♪\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, string(applyFormatting([]byte(given), OutputTypeSubtitles, tc.givenCfg)))
+		})
+	}
+
+	t.Run("transcript", func(t *testing.T) {
+		t.Parallel()
+
+		got := applyFormatting([]byte("[music] Hello there"), OutputTypeTranscript, FormattingConfig{RemoveSoundDescriptions: true})
+		assert.Equal(t, "Hello there", string(got))
+	})
+}
+
+func TestProcess_Formatting(t *testing.T) {
+	t.Parallel()
+
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.Copy(io.Discard, in.Data)
+			return []byte("1\n00:00:00,000 --> 00:00:01,000\n[applause]\n\n2\n00:00:01,000 --> 00:00:02,000\n<i>Thanks</i>\n"), err
+		},
+	},
+		WithFormatting(FormattingConfig{RemoveSoundDescriptions: true}),
+		WithConverter(copyConverter),
+	)
+
+	err := s.Process(context.TODO(), Input{
+		Name:       "test.mp4",
+		OutputType: OutputTypeSubtitles,
+		Language:   "en",
+		Data:       io.NopCloser(strings.NewReader("data")),
+	})
+	require.NoError(t, err)
+
+	out := <-s.Collect()
+	defer out.Body.Close()
+
+	assert.Equal(t, "1\n00:00:01,000 --> 00:00:02,000\n<i>Thanks</i>\n", string(out.Text))
+	assert.Equal(t, "Thanks", out.PlainText)
+}
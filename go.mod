@@ -5,10 +5,12 @@ go 1.22.3
 require (
 	github.com/alesr/whisperclient v0.0.0-20230822131735-ec185102ef54
 	github.com/stretchr/testify v1.9.0
+	go.uber.org/goleak v1.3.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/kr/text v0.2.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
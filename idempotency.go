@@ -0,0 +1,36 @@
+package scriber
+
+import (
+	"context"
+	"fmt"
+)
+
+// MetadataIdempotencyKey is the Output.Metadata key holding the job's idempotency key.
+const MetadataIdempotencyKey = "idempotency_key"
+
+type idempotencyKeyCtxKey struct{}
+
+// IdempotencyKeyFromContext returns the idempotency key of the transcription
+// request ctx belongs to. Backends that support request de-duplication
+// (e.g. through an Idempotency-Key HTTP header) should forward it.
+// The key is the same for every attempt scriber makes for a request.
+func IdempotencyKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKeyCtxKey{}).(string)
+	return key, ok
+}
+
+func withIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtxKey{}, key)
+}
+
+// languageIdempotencyKey derives the key for the transcription in lang of an
+// input transcribed in several languages, or for the language detection probe.
+func languageIdempotencyKey(key, lang string) string {
+	return key + "-" + lang
+}
+
+// chunkIdempotencyKey derives the key for one chunk of a chunked
+// transcription, since each chunk is a distinct backend request.
+func chunkIdempotencyKey(key string, index int) string {
+	return fmt.Sprintf("%s-%d", key, index)
+}
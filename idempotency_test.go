@@ -0,0 +1,136 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_IdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	newScriber := func(keys *[]string, mu *sync.Mutex, replies ...string) *Scriber {
+		var calls int
+
+		mockClient := &mockWhisperClient{
+			transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+				_, err := io.Copy(io.Discard, in.Data)
+				require.NoError(t, err)
+
+				key, ok := IdempotencyKeyFromContext(ctx)
+				require.True(t, ok)
+
+				mu.Lock()
+				defer mu.Unlock()
+				*keys = append(*keys, key)
+				calls++
+				return []byte(replies[min(calls, len(replies))-1]), nil
+			},
+		}
+
+		return New(noopLogger(), mockClient,
+			WithEmptyTranscriptionPolicy(EmptyTranscriptionRetry),
+			WithConverter(copyConverter),
+		)
+	}
+
+	input := func(key string) Input {
+		return Input{
+			Name:           "test.mp4",
+			OutputType:     OutputTypeTranscript,
+			Language:       "en",
+			Data:           readSeekNopCloser{bytes.NewReader([]byte("foo"))},
+			IdempotencyKey: key,
+		}
+	}
+
+	t.Run("generated key is stable across retries", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			keys []string
+			mu   sync.Mutex
+		)
+
+		scriber := newScriber(&keys, &mu, "", "hello")
+
+		require.NoError(t, scriber.Process(context.TODO(), input("")))
+		output := <-scriber.Collect()
+
+		require.Len(t, keys, 2)
+		assert.NotEmpty(t, keys[0])
+		assert.Equal(t, keys[0], keys[1])
+		assert.Equal(t, keys[0], output.Metadata[MetadataIdempotencyKey])
+	})
+
+	t.Run("distinct jobs get distinct keys", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			keys []string
+			mu   sync.Mutex
+		)
+
+		scriber := newScriber(&keys, &mu, "hello")
+
+		require.NoError(t, scriber.Process(context.TODO(), input("")))
+		<-scriber.Collect()
+		require.NoError(t, scriber.Process(context.TODO(), input("")))
+		<-scriber.Collect()
+
+		require.Len(t, keys, 2)
+		assert.NotEqual(t, keys[0], keys[1])
+	})
+
+	t.Run("caller-provided key", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			keys []string
+			mu   sync.Mutex
+		)
+
+		scriber := newScriber(&keys, &mu, "", "hello")
+
+		require.NoError(t, scriber.Process(context.TODO(), input("my-key")))
+		output := <-scriber.Collect()
+
+		assert.Equal(t, []string{"my-key", "my-key"}, keys)
+		assert.Equal(t, "my-key", output.Metadata[MetadataIdempotencyKey])
+	})
+
+	t.Run("chunks get derived keys", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			keys []string
+			mu   sync.Mutex
+		)
+
+		scriber := newScriber(&keys, &mu, "hello")
+		WithChunking(ChunkConfig{Length: 10 * time.Second})(scriber)
+		scriber.convertToWavFunc = func(_ context.Context, r io.Reader, w io.Writer) error {
+			_, err := w.Write(syntheticWAV(25))
+			return err
+		}
+
+		require.NoError(t, scriber.Process(context.TODO(), input("my-key")))
+		<-scriber.Collect()
+
+		assert.ElementsMatch(t, []string{"my-key-0", "my-key-1", "my-key-2"}, keys)
+	})
+}
+
+func TestIdempotencyKeyFromContext_Missing(t *testing.T) {
+	t.Parallel()
+
+	_, ok := IdempotencyKeyFromContext(context.TODO())
+	assert.False(t, ok)
+}
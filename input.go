@@ -0,0 +1,137 @@
+package scriber
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+)
+
+// InputOption configures an Input built with NewInput.
+type InputOption func(*Input)
+
+// InputFromBytes returns an Input reading data, which is held in memory.
+// The data is seekable, so passes that read the input more than once,
+// such as probing, language detection, and retries, rewind it rather
+// than spooling it to a temporary file.
+func InputFromBytes(name, lang string, t OutputType, data []byte) Input {
+	return NewInput(name, bytes.NewReader(data),
+		WithInputLanguage(lang),
+		WithInputOutputType(t),
+		WithInputSize(int64(len(data))),
+	)
+}
+
+// NewInput returns an Input named name reading from data, which doesn't
+// need to implement io.Closer. If it does, Process closes it exactly once,
+// as when set directly on Input.Data; otherwise closing is a no-op.
+// If data implements io.Seeker, so does the resulting Input.Data.
+func NewInput(name string, data io.Reader, opts ...InputOption) Input {
+	in := Input{Name: name, Data: asReadCloser(data)}
+	for _, opt := range opts {
+		opt(&in)
+	}
+	return in
+}
+
+// WithInputLanguage sets the language of the input.
+func WithInputLanguage(lang string) InputOption {
+	return func(in *Input) {
+		in.Language = lang
+	}
+}
+
+// WithInputOutputType sets the output type to generate for the input.
+func WithInputOutputType(t OutputType) InputOption {
+	return func(in *Input) {
+		in.OutputType = t
+	}
+}
+
+// WithInputKeepOpen leaves closing the input data to the caller.
+func WithInputKeepOpen() InputOption {
+	return func(in *Input) {
+		in.KeepOpen = true
+	}
+}
+
+// WithInputIdempotencyKey sets the idempotency key of the input.
+func WithInputIdempotencyKey(key string) InputOption {
+	return func(in *Input) {
+		in.IdempotencyKey = key
+	}
+}
+
+// WithInputSize sets the size hint of the input. Unlike a zero Input.Size,
+// which means unknown, declaring a size of zero fails the input with an
+// EmptyInputError without reading it.
+func WithInputSize(n int64) InputOption {
+	return func(in *Input) {
+		in.Size, in.sizeDeclared = n, true
+	}
+}
+
+// WithInputUserTag sets the user tag of the input.
+func WithInputUserTag(tag string) InputOption {
+	return func(in *Input) {
+		in.UserTag = tag
+	}
+}
+
+// WithInputOutputExtension overrides the extension of the output name.
+func WithInputOutputExtension(ext string) InputOption {
+	return func(in *Input) {
+		in.OutputExtension = ext
+	}
+}
+
+// WithInputUploadName sets the file name sent to the transcription backend.
+func WithInputUploadName(name string) InputOption {
+	return func(in *Input) {
+		in.UploadName = name
+	}
+}
+
+// WithInputContentType sets the MIME type of the input data.
+func WithInputContentType(contentType string) InputOption {
+	return func(in *Input) {
+		in.ContentType = contentType
+	}
+}
+
+// audioMediaType returns the media type of contentType, without parameters,
+// if it is an audio or video type.
+func audioMediaType(contentType string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q: %w", errContentType, contentType, err)
+	}
+
+	if !strings.HasPrefix(mediaType, "audio/") && !strings.HasPrefix(mediaType, "video/") {
+		return "", fmt.Errorf("%w: %q", errContentType, contentType)
+	}
+	return mediaType, nil
+}
+
+// asReadCloser returns r as an io.ReadCloser, adding a no-op Close
+// if needed while keeping io.Seeker available.
+func asReadCloser(r io.Reader) io.ReadCloser {
+	switch r := r.(type) {
+	case nil:
+		return nil
+	case io.ReadCloser:
+		return r
+	case io.ReadSeeker:
+		return nopReadSeekCloser{r}
+	default:
+		return io.NopCloser(r)
+	}
+}
+
+// nopReadSeekCloser is an io.ReadSeeker with a no-op Close.
+type nopReadSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopReadSeekCloser) Close() error { return nil }
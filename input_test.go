@@ -0,0 +1,267 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewInput(t *testing.T) {
+	t.Parallel()
+
+	in := NewInput("test.mp4", strings.NewReader("data"),
+		WithInputLanguage("pt"),
+		WithInputOutputType(OutputTypeSubtitles),
+		WithInputKeepOpen(),
+		WithInputIdempotencyKey("key"),
+		WithInputSize(4),
+		WithInputContentType("video/mp4"),
+	)
+
+	assert.Equal(t, "test.mp4", in.Name)
+	assert.Equal(t, "pt", in.Language)
+	assert.Equal(t, OutputTypeSubtitles, in.OutputType)
+	assert.True(t, in.KeepOpen)
+	assert.Equal(t, "key", in.IdempotencyKey)
+	assert.EqualValues(t, 4, in.Size)
+	assert.Equal(t, "video/mp4", in.ContentType)
+	require.NoError(t, in.validate())
+
+	_, seekable := in.Data.(io.Seeker)
+	assert.True(t, seekable, "seekable readers should stay seekable")
+
+	assert.Nil(t, NewInput("test.mp4", nil).Data)
+}
+
+func TestProcess_NewInputCloses(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		givenData      func(r io.Reader) io.Reader
+		expectedCloses int32
+	}{
+		{
+			name:           "closer",
+			givenData:      func(r io.Reader) io.Reader { return &closeCounter{Reader: r} },
+			expectedCloses: 1,
+		},
+		{
+			name:      "reader",
+			givenData: func(r io.Reader) io.Reader { return io.MultiReader(r) },
+		},
+		{
+			name:      "read seeker",
+			givenData: func(r io.Reader) io.Reader { return bytes.NewReader([]byte("data")) },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					data, err := io.ReadAll(in.Data)
+					assert.Equal(t, "data", string(data))
+					return []byte("text"), err
+				},
+			}
+
+			s := New(noopLogger(), mockClient, WithConverter(copyConverter))
+
+			data := tc.givenData(strings.NewReader("data"))
+			err := s.Process(context.TODO(), NewInput("test.mp4", data,
+				WithInputLanguage("en"),
+				WithInputOutputType(OutputTypeTranscript),
+			))
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			out.Body.Close()
+			assert.Equal(t, "text", string(out.Text))
+
+			if c, ok := data.(*closeCounter); ok {
+				assert.Equal(t, tc.expectedCloses, c.closes.Load())
+			}
+		})
+	}
+}
+
+func TestInputValidate_ContentType(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		givenName        string
+		givenContentType string
+		expectedErr      error
+		expectedOutput   string
+	}{
+		{
+			name:             "content type only",
+			givenName:        "blob-42",
+			givenContentType: "audio/mpeg",
+			expectedOutput:   "blob-42.txt",
+		},
+		{
+			name:             "content type with parameters",
+			givenName:        "blob-42",
+			givenContentType: "video/webm; codecs=opus",
+			expectedOutput:   "blob-42.txt",
+		},
+		{
+			name:           "extension only",
+			givenName:      "talk.mp4",
+			expectedOutput: "talk.txt",
+		},
+		{
+			name:             "both",
+			givenName:        "talk.mp4",
+			givenContentType: "video/mp4",
+			expectedOutput:   "talk.txt",
+		},
+		{
+			name:        "neither",
+			givenName:   "blob-42",
+			expectedErr: errExtRequired,
+		},
+		{
+			name:             "not audio or video",
+			givenName:        "talk.mp4",
+			givenContentType: "text/plain",
+			expectedErr:      errContentType,
+		},
+		{
+			name:             "malformed",
+			givenName:        "blob-42",
+			givenContentType: "audio/",
+			expectedErr:      errContentType,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			in := NewInput(tc.givenName, strings.NewReader("media"),
+				WithInputLanguage("en"),
+				WithInputOutputType(OutputTypeTranscript),
+				WithInputContentType(tc.givenContentType),
+			)
+
+			err := in.validate()
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedOutput, generateOutputFileName(in.Name, in.OutputType))
+		})
+	}
+}
+
+func TestInputFromBytes(t *testing.T) {
+	t.Parallel()
+
+	in := InputFromBytes("test.mp4", "pt", OutputTypeSubtitles, []byte("data"))
+
+	assert.Equal(t, "test.mp4", in.Name)
+	assert.Equal(t, "pt", in.Language)
+	assert.Equal(t, OutputTypeSubtitles, in.OutputType)
+	assert.EqualValues(t, 4, in.Size)
+	assert.Implements(t, (*io.Seeker)(nil), in.Data)
+	require.NoError(t, in.validate())
+}
+
+func TestProcess_SeekableInputPasses(t *testing.T) {
+	t.Parallel()
+
+	media := bytes.Repeat([]byte("media"), 1000)
+
+	testCases := []struct {
+		name           string
+		givenInput     func() Input
+		expectedPasses int
+		expectedErr    bool
+	}{
+		{
+			name: "bytes are rewound",
+			givenInput: func() Input {
+				return InputFromBytes("test.mp4", LanguageAuto, OutputTypeTranscript, media)
+			},
+			expectedPasses: 2,
+		},
+		{
+			name: "stream is spooled",
+			givenInput: func() Input {
+				return NewInput("test.mp4", io.MultiReader(bytes.NewReader(media)),
+					WithInputLanguage(LanguageAuto),
+					WithInputOutputType(OutputTypeTranscript),
+				)
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var passes atomic.Int32
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.ReadAll(in.Data)
+					require.NoError(t, err)
+
+					if in.Format == formatVerboseJSON {
+						return []byte(`{"language":"pt"}`), nil
+					}
+					return []byte("olá"), nil
+				},
+			}
+
+			s := New(noopLogger(), mockClient,
+				// Spooling the input fails, so it must be read in place.
+				WithSpoolThreshold(0, filepath.Join(t.TempDir(), "missing")),
+				WithConverter(func(_ context.Context, r io.Reader, w io.Writer) error {
+					passes.Add(1)
+
+					data, err := io.ReadAll(r)
+					if err != nil {
+						return err
+					}
+					assert.Equal(t, media, data)
+
+					_, err = w.Write(syntheticWAV(40))
+					return err
+				}),
+			)
+
+			err := s.Process(context.TODO(), tc.givenInput())
+			if tc.expectedErr {
+				var pe *ProcessError
+				require.ErrorAs(t, err, &pe)
+				assert.Equal(t, StageConversion, pe.Stage)
+				return
+			}
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+
+			assert.Equal(t, "pt", out.Language)
+			assert.EqualValues(t, tc.expectedPasses, passes.Load())
+			assert.EqualValues(t, len(media), out.InputBytes)
+		})
+	}
+}
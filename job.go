@@ -0,0 +1,243 @@
+package scriber
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// job holds the state of a single Process call.
+type job struct {
+	id string
+	in Input
+
+	idempotencyKey string
+
+	// dataStart is the offset of the input's data when the job was
+	// admitted, if seekable, which rereading the input rewinds it to.
+	dataStart int64
+
+	// seq is the submission order of the job, when results are ordered.
+	seq uint64
+
+	// languageInName and inputExtInName add the language and the input
+	// extension to the output name, and sanitizeName makes it safe as a
+	// file name.
+	languageInName bool
+	inputExtInName bool
+	sanitizeName   bool
+
+	// handOff releases the caller once the job is transcribed, with
+	// asynchronous post-processing, see WithAsyncPostProcessing.
+	handOff func()
+
+	// normalized names the output as the normalized transcript of the
+	// transcription, see WithNormalizedOutput.
+	normalized bool
+
+	logger *slog.Logger
+	attrs  []slog.Attr
+	events *eventLog
+
+	// raw is the transcription as returned by the backend, set once
+	// transcription succeeds, or the unstitched chunk transcriptions.
+	raw []byte
+
+	started       time.Time
+	timing        ProcessingTime
+	audioDuration time.Duration
+
+	// probedDuration is the input's duration, if probed.
+	probedDuration time.Duration
+
+	// chunkLength is the length of the chunks the audio was split into.
+	chunkLength time.Duration
+
+	// translatedFrom is the language the job's transcription was
+	// translated from, for translations.
+	translatedFrom string
+
+	// speechRegions are the regions transcribed, with voice activity detection.
+	speechRegions []SpeechRegion
+
+	// truncated is set when the transcription seems truncated.
+	truncated *TruncatedTranscriptionWarning
+
+	// inputBytes and convertedBytes count the bytes read from the
+	// input and uploaded to the backend by the last transcription.
+	inputBytes     int64
+	convertedBytes int64
+
+	// uploadSpec is the spec of the audio uploaded, read from its header.
+	uploadSpec AudioSpec
+
+	// resumedChunks is the number of chunks reused from an
+	// interrupted run. See WithChunkResume.
+	resumedChunks int
+
+	// contentHash is the hash of the input data, when it is hashed.
+	// See WithContentID and WithDedupCache.
+	contentHash string
+
+	// deduplicated is set when the transcription comes from
+	// the deduplication cache. See WithDedupCache.
+	deduplicated bool
+
+	// codec and ffmpegArgs are the codec and the arguments the input
+	// is converted with, when the default converter is used.
+	codec      AudioCodec
+	ffmpegArgs []string
+
+	// downmixFilter is the filter surround audio is downmixed with,
+	// if any. See WithDialogDownmix.
+	downmixFilter string
+
+	// warnings are the warnings raised by the job, and
+	// escalatedWarnings the codes failing it instead.
+	warnings          *warningLog
+	escalatedWarnings map[WarningCode]bool
+}
+
+func (s *Scriber) newJob(in Input, attrs []slog.Attr) *job {
+	id := newJobID()
+
+	key := in.IdempotencyKey
+	if key == "" {
+		key = id
+	}
+
+	logger := s.logger
+	if len(attrs) > 0 {
+		logger = logger.With(attrsToArgs(attrs)...)
+	}
+	logger = logger.With(slog.String(MetadataIdempotencyKey, key))
+	if in.UserTag != "" {
+		logger = logger.With(slog.String("user_tag", in.UserTag))
+	}
+
+	events := newEventLog(s.maxEvents)
+	codec := s.codecFor(in)
+
+	return &job{
+		id:                id,
+		in:                in,
+		idempotencyKey:    key,
+		languageInName:    s.languageInName,
+		inputExtInName:    s.inputExtInName,
+		sanitizeName:      s.outputDir != "",
+		codec:             codec,
+		ffmpegArgs:        s.ffmpegArgsFor(ffmpegInput{codec: codec}),
+		warnings:          &warningLog{},
+		escalatedWarnings: s.escalatedWarnings,
+		logger:            events.attach(logger),
+		attrs:             attrs,
+		events:            events,
+		started:           time.Now(),
+	}
+}
+
+// markDataStart records the offset of the job's data, if seekable,
+// for rewind to seek back to it.
+func (j *job) markDataStart() error {
+	seeker, ok := j.in.Data.(io.Seeker)
+	if !ok {
+		return nil
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("could not get data position: %w", err)
+	}
+	j.dataStart = start
+	return nil
+}
+
+// rewind seeks the job's data back to where it was when the job was
+// admitted, for it to be read again. The data must be seekable.
+func (j *job) rewind() error {
+	if _, err := j.in.Data.(io.Seeker).Seek(j.dataStart, io.SeekStart); err != nil {
+		return fmt.Errorf("could not rewind data: %w", err)
+	}
+	return nil
+}
+
+// newJobID returns a random identifier for a job.
+func newJobID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// output returns an Output for the job carrying text.
+func (j *job) output(text []byte) Output {
+	md := attrsToMetadata(j.attrs)
+	if md == nil {
+		md = make(map[string]string, 1)
+	}
+	md[MetadataIdempotencyKey] = j.idempotencyKey
+	if j.chunkLength > 0 {
+		md[MetadataChunkLength] = j.chunkLength.String()
+	}
+	if j.translatedFrom != "" {
+		md[MetadataTranslatedFrom] = j.translatedFrom
+	}
+	if j.ffmpegArgs != nil {
+		md[MetadataFFmpegCommand] = ffmpegCommand(j.ffmpegArgs)
+	}
+	if j.downmixFilter != "" {
+		md[MetadataDownmixFilter] = j.downmixFilter
+	}
+
+	out := Output{
+		BaseName:       baseName(j.in.Name),
+		Extension:      j.in.outputExtension(),
+		InputExtension: originalExtension(j.in.Name),
+		JobID:          j.id,
+		Text:           text,
+		Language:       j.in.Language,
+		Metadata:       md,
+		AudioDuration:  j.audioDuration,
+		InputBytes:     j.inputBytes,
+		ConvertedBytes: j.convertedBytes,
+		AudioSpec:      UploadedAudio{AudioSpec: j.uploadSpec, Bytes: j.convertedBytes, Duration: j.audioDuration},
+		ResumedChunks:  j.resumedChunks,
+		Deduplicated:   j.deduplicated,
+		ProcessingTime: j.timing,
+		SpeechRegions:  j.speechRegions,
+		Truncated:      j.truncated,
+		Events:         j.events.snapshot(),
+		Warnings:       j.warnings.snapshot(),
+	}
+
+	out.Name = out.Filename(j.nameOptions()...)
+	return out
+}
+
+// nameOptions returns the options the job's output names are assembled with.
+func (j *job) nameOptions() []NameOption {
+	var opts []NameOption
+	if j.inputExtInName {
+		opts = append(opts, WithNameInputExtension())
+	}
+	if j.languageInName {
+		opts = append(opts, WithNameLanguage())
+	}
+	if j.sanitizeName {
+		opts = append(opts, WithNameSanitized())
+	}
+	if j.normalized {
+		opts = append(opts, WithNameNormalized())
+	}
+	return opts
+}
+
+// billedAudio returns the duration of the audio the backend transcribed
+// for the job, which is none when its transcription was deduplicated.
+func (j *job) billedAudio() time.Duration {
+	if j.deduplicated {
+		return 0
+	}
+	return j.audioDuration
+}
@@ -0,0 +1,218 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alesr/scriber/wav"
+	"github.com/alesr/whisperclient"
+)
+
+const (
+	// LanguageAuto makes Process detect the input language by transcribing
+	// a short sample first, then transcribe the whole input in that language.
+	LanguageAuto = "auto"
+
+	// languageProbeLength is the length of the sample used to detect the language.
+	languageProbeLength = 30 * time.Second
+
+	// formatVerboseJSON is the backend format that reports the detected language.
+	formatVerboseJSON = "verbose_json"
+)
+
+// whisperLanguages maps the language names reported by
+// the backend to the ISO-639-1 codes it accepts as input.
+var whisperLanguages = map[string]string{
+	"afrikaans": "af", "arabic": "ar", "armenian": "hy", "azerbaijani": "az",
+	"belarusian": "be", "bosnian": "bs", "bulgarian": "bg", "catalan": "ca",
+	"chinese": "zh", "croatian": "hr", "czech": "cs", "danish": "da",
+	"dutch": "nl", "english": "en", "estonian": "et", "finnish": "fi",
+	"french": "fr", "galician": "gl", "german": "de", "greek": "el",
+	"hebrew": "he", "hindi": "hi", "hungarian": "hu", "icelandic": "is",
+	"indonesian": "id", "italian": "it", "japanese": "ja", "kannada": "kn",
+	"kazakh": "kk", "korean": "ko", "latvian": "lv", "lithuanian": "lt",
+	"macedonian": "mk", "malay": "ms", "marathi": "mr", "maori": "mi",
+	"nepali": "ne", "norwegian": "no", "persian": "fa", "polish": "pl",
+	"portuguese": "pt", "romanian": "ro", "russian": "ru", "serbian": "sr",
+	"slovak": "sk", "slovenian": "sl", "spanish": "es", "swahili": "sw",
+	"swedish": "sv", "tagalog": "tl", "tamil": "ta", "thai": "th",
+	"turkish": "tr", "ukrainian": "uk", "urdu": "ur", "vietnamese": "vi",
+	"welsh": "cy",
+}
+
+// WithLanguageInName adds the transcription language to output names,
+// e.g. talk.pt.srt instead of talk.srt. This is mostly useful together
+// with LanguageAuto, to tell the detected language from the file name.
+func WithLanguageInName(enabled bool) Option {
+	return func(s *Scriber) {
+		s.languageInName = enabled
+	}
+}
+
+// detectLanguage transcribes the first seconds of the job's input to
+// detect the language, and sets the job's language to the detected one.
+// The input is read twice: seekable inputs are rewound in between, others
+// are spooled first, and the spool replaces the job's data; release removes it.
+func (s *Scriber) detectLanguage(ctx context.Context, j *job) (func(), error) {
+	release := func() {}
+
+	data, ok := j.in.Data.(io.ReadSeeker)
+	if !ok {
+		input, stop := s.inputReader(j)
+		spool, err := spoolInput(input, s.spoolDir, s.buffers())
+		stop()
+		if err != nil {
+			return nil, stageError(StageConversion, err)
+		}
+
+		release = func() {
+			spool.Close()
+			os.Remove(spool.Name())
+		}
+		j.in.Data = spool
+		data = spool
+	}
+
+	lang, err := s.probeLanguage(ctx, j, data)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	j.logger.Info("Detected language", slog.String("file", j.in.Name), slog.String("language", lang))
+
+	j.in.Language = lang
+	return release, nil
+}
+
+// probeLanguage detects the language of the first seconds of data,
+// rewinding it after.
+func (s *Scriber) probeLanguage(ctx context.Context, j *job, data io.ReadSeeker) (string, error) {
+	start, err := data.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", stageError(StageConversion, fmt.Errorf("could not get data position: %w", err))
+	}
+
+	// The converter has let go of data once convertSample returns.
+	sample, err := s.convertSample(ctx, data, languageProbeLength)
+	if _, serr := data.Seek(start, io.SeekStart); serr != nil && err == nil {
+		return "", stageError(StageConversion, fmt.Errorf("could not rewind data: %w", serr))
+	}
+	if err != nil {
+		return "", stageError(StageConversion, fmt.Errorf("could not convert language sample: %w", err))
+	}
+	return s.probeSampleLanguage(ctx, j, sample)
+}
+
+// probeSampleLanguage detects the language of sample, a WAV file.
+func (s *Scriber) probeSampleLanguage(ctx context.Context, j *job, sample []byte) (string, error) {
+	ctx = withIdempotencyKey(ctx, languageIdempotencyKey(j.idempotencyKey, LanguageAuto))
+
+	resp, err := s.requestTranscription(ctx, j, whisperclient.TranscribeAudioInput{
+		Name:   j.uploadName(CodecWAV),
+		Format: formatVerboseJSON,
+		Data:   bytes.NewReader(sample),
+	}, s.transcriptionTimeout, nil)
+	if err != nil {
+		return "", stageError(StageTranscription, fmt.Errorf("could not detect language: %w", err))
+	}
+
+	lang, err := parseDetectedLanguage(resp)
+	if err != nil {
+		return "", stageError(StageTranscription, fmt.Errorf("could not detect language: %w", err))
+	}
+	return lang, nil
+}
+
+// convertSample converts the first d of r to a WAV file, stopping the
+// converter as soon as enough audio has been produced.
+func (s *Scriber) convertSample(ctx context.Context, r io.Reader, d time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pipeReader, pipeWriter := io.Pipe()
+	done := make(chan error, 1)
+
+	go func() {
+		err := s.convert(ctx, nil, newPooledReader(r, s.buffers()), pipeWriter)
+		pipeWriter.CloseWithError(err)
+		done <- err
+	}()
+
+	sample, err := clipWAV(pipeReader, d)
+
+	// Stop the converter, which fails writing to the closed pipe if it
+	// hasn't finished already, and wait for it to let go of r.
+	pipeReader.Close()
+	cancel()
+	convertErr := <-done
+
+	if err != nil {
+		if convertErr != nil {
+			return nil, convertErr
+		}
+		return nil, err
+	}
+	return sample, nil
+}
+
+// clipWAV reads a WAV stream from r and returns
+// a WAV file holding at most its first d of audio.
+func clipWAV(r io.Reader, d time.Duration) ([]byte, error) {
+	format, _, dataLen, err := readWAVHeader(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not read converted audio: %w", err)
+	}
+
+	n := format.ByteRate() * int64(d) / int64(time.Second)
+	n -= n % format.BlockAlign()
+	if dataLen != wav.UnknownSize && dataLen != 0 && int64(dataLen) < n {
+		n = int64(dataLen)
+	}
+
+	data := make([]byte, n)
+	read, err := io.ReadFull(r, data)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("could not read converted audio: %w", err)
+	}
+	data = data[:read-read%int(format.BlockAlign())]
+
+	var buf bytes.Buffer
+	buf.Grow(wav.HeaderSize + len(data))
+	if err := wav.WriteHeader(&buf, format, uint32(len(data))); err != nil {
+		return nil, err
+	}
+	buf.Write(data)
+	return buf.Bytes(), nil
+}
+
+// parseDetectedLanguage returns the ISO-639-1 code of the
+// language reported in a verbose JSON transcription.
+func parseDetectedLanguage(resp []byte) (string, error) {
+	var v struct {
+		Language string `json:"language"`
+	}
+	if err := json.Unmarshal(resp, &v); err != nil {
+		return "", fmt.Errorf("could not parse response: %w", err)
+	}
+
+	lang := strings.ToLower(strings.TrimSpace(v.Language))
+	if code, ok := whisperLanguages[lang]; ok {
+		return code, nil
+	}
+
+	// Some backends report the code directly.
+	for _, code := range whisperLanguages {
+		if lang == code {
+			return code, nil
+		}
+	}
+	return "", fmt.Errorf("unknown language %q", v.Language)
+}
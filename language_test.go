@@ -0,0 +1,173 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alesr/scriber/wav"
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_LanguageAuto(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		givenProbeReply  string
+		givenInName      bool
+		expectedLanguage string
+		expectedName     string
+		expectedStage    Stage // Empty when the job succeeds.
+	}{
+		{
+			name:             "detected language name",
+			givenProbeReply:  `{"language":"portuguese","text":"olá"}`,
+			expectedLanguage: "pt",
+			expectedName:     "test.txt",
+		},
+		{
+			name:             "detected language in name",
+			givenProbeReply:  `{"language":"Portuguese","text":"olá"}`,
+			givenInName:      true,
+			expectedLanguage: "pt",
+			expectedName:     "test.pt.txt",
+		},
+		{
+			name:             "detected language code",
+			givenProbeReply:  `{"language":"de"}`,
+			expectedLanguage: "de",
+			expectedName:     "test.txt",
+		},
+		{
+			name:            "unknown language",
+			givenProbeReply: `{"language":"klingon"}`,
+			expectedStage:   StageTranscription,
+		},
+		{
+			name:            "malformed response",
+			givenProbeReply: `1\n00:00:00,000 --> 00:00:01,000\nolá\n`,
+			expectedStage:   StageTranscription,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			audio := syntheticWAV(60)
+
+			var (
+				mu    sync.Mutex
+				calls []whisperclient.TranscribeAudioInput
+				sizes []int
+			)
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					data, err := io.ReadAll(in.Data)
+					require.NoError(t, err)
+
+					mu.Lock()
+					calls = append(calls, in)
+					sizes = append(sizes, len(data))
+					mu.Unlock()
+
+					if in.Format == formatVerboseJSON {
+						return []byte(tc.givenProbeReply), nil
+					}
+					return []byte("olá"), nil
+				},
+			}
+
+			s := New(noopLogger(), mockClient,
+				WithLanguageInName(tc.givenInName),
+				WithConverter(copyConverter),
+			)
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "test.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   LanguageAuto,
+				Data:       io.NopCloser(bytes.NewReader(audio)),
+			})
+
+			if tc.expectedStage != "" {
+				var pe *ProcessError
+				require.ErrorAs(t, err, &pe)
+				assert.Equal(t, tc.expectedStage, pe.Stage)
+				require.Len(t, calls, 1)
+				return
+			}
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			defer out.Body.Close()
+
+			assert.Equal(t, tc.expectedLanguage, out.Language)
+			assert.Equal(t, tc.expectedName, out.Name)
+			assert.Equal(t, "olá", string(out.Text))
+
+			require.Len(t, calls, 2)
+
+			// The probe carries no language and only the first 30 seconds.
+			assert.Empty(t, calls[0].Language)
+			assert.Equal(t, wav.HeaderSize+30*int(testWAVFormat.ByteRate()), sizes[0])
+
+			// The full transcription uses the detected language.
+			assert.Equal(t, tc.expectedLanguage, calls[1].Language)
+			assert.Equal(t, len(audio), sizes[1])
+		})
+	}
+}
+
+func TestClipWAV(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		givenSeconds int
+		givenClip    time.Duration
+		expectedLen  int64
+	}{
+		{
+			name:         "longer than the clip",
+			givenSeconds: 10,
+			givenClip:    2 * time.Second,
+			expectedLen:  2 * testWAVFormat.ByteRate(),
+		},
+		{
+			name:         "shorter than the clip",
+			givenSeconds: 1,
+			givenClip:    30 * time.Second,
+			expectedLen:  testWAVFormat.ByteRate(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			clip, err := clipWAV(bytes.NewReader(syntheticWAV(tc.givenSeconds)), tc.givenClip)
+			require.NoError(t, err)
+
+			format, offset, dataLen, err := readWAVHeader(bytes.NewReader(clip))
+			require.NoError(t, err)
+			assert.Equal(t, testWAVFormat, format)
+			assert.EqualValues(t, tc.expectedLen, dataLen)
+			assert.EqualValues(t, tc.expectedLen, int64(len(clip))-offset)
+		})
+	}
+
+	t.Run("not wav", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := clipWAV(bytes.NewReader(bytes.Repeat([]byte("x"), 64)), time.Second)
+		require.ErrorIs(t, err, wav.ErrNotWAV)
+	})
+}
@@ -0,0 +1,206 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+// TestProcess_NoGoroutineLeaks checks that Process doesn't leave goroutines
+// behind. It doesn't run in parallel, so that goroutines started by other
+// tests don't show up; parallel tests are paused while it runs.
+func TestProcess_NoGoroutineLeaks(t *testing.T) {
+	wavConverter := func(_ context.Context, r io.Reader, w io.Writer) error {
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			return err
+		}
+		_, err := w.Write(syntheticWAV(2))
+		return err
+	}
+	reply := func(text string, err error) func(context.Context, whisperclient.TranscribeAudioInput) ([]byte, error) {
+		return func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			if _, err := io.Copy(io.Discard, in.Data); err != nil {
+				return nil, err
+			}
+			return []byte(text), err
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		givenInput    Input
+		givenOpts     []Option
+		givenReply    func(context.Context, whisperclient.TranscribeAudioInput) ([]byte, error)
+		givenConvert  func(context.Context, io.Reader, io.Writer) error
+		givenCancel   time.Duration // Cancels the context after this long, when set.
+		givenNoReader bool          // Nobody reads the results.
+		expectedStage Stage         // Empty when the job succeeds.
+	}{
+		{
+			name:       "success",
+			givenReply: reply("text", nil),
+		},
+		{
+			name:          "validation failure",
+			givenInput:    Input{Name: "test"},
+			givenReply:    reply("text", nil),
+			expectedStage: StageValidation,
+		},
+		{
+			name:          "conversion failure",
+			givenReply:    reply("text", nil),
+			givenConvert:  func(context.Context, io.Reader, io.Writer) error { return assert.AnError },
+			expectedStage: StageConversion,
+		},
+		{
+			name: "transcription failure before reading",
+			givenReply: func(context.Context, whisperclient.TranscribeAudioInput) ([]byte, error) {
+				return nil, assert.AnError
+			},
+			expectedStage: StageTranscription,
+		},
+		{
+			name:          "transcription failure",
+			givenReply:    reply("", assert.AnError),
+			expectedStage: StageTranscription,
+		},
+		{
+			name:          "post-processing failure",
+			givenOpts:     []Option{WithSpoolThreshold(1, "/nonexistent/dir")},
+			givenReply:    reply("text", nil),
+			expectedStage: StagePostProcess,
+		},
+		{
+			name:          "publish failure",
+			givenReply:    reply("text", nil),
+			givenCancel:   50 * time.Millisecond,
+			givenNoReader: true,
+			expectedStage: StagePublish,
+		},
+		{
+			name:       "cancellation during conversion",
+			givenReply: reply("text", nil),
+			givenConvert: func(ctx context.Context, _ io.Reader, _ io.Writer) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+			givenCancel:   20 * time.Millisecond,
+			expectedStage: StageConversion,
+		},
+		{
+			name: "cancellation during transcription",
+			givenReply: func(ctx context.Context, _ whisperclient.TranscribeAudioInput) ([]byte, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+			givenCancel:   20 * time.Millisecond,
+			expectedStage: StageTranscription,
+		},
+		{
+			name: "cancellation during the converter command",
+			givenConvert: func(ctx context.Context, r io.Reader, w io.Writer) error {
+				return runConverter(exec.CommandContext(ctx, "sleep", "30"), r, w)
+			},
+			givenReply:    reply("text", nil),
+			givenCancel:   20 * time.Millisecond,
+			expectedStage: StageConversion,
+		},
+		{
+			name:      "transcription timeout",
+			givenOpts: []Option{WithTranscriptionTimeout(time.Millisecond)},
+			givenReply: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+				_, _ = in.Data.Read(make([]byte, 1))
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+			expectedStage: StageTranscription,
+		},
+		{
+			name:       "input idle timeout",
+			givenOpts:  []Option{WithInputIdleTimeout(time.Minute)},
+			givenReply: reply("text", nil),
+		},
+		{
+			name:          "retries",
+			givenOpts:     []Option{WithRetry(RetryConfig{Attempts: 2})},
+			givenReply:    reply("", assert.AnError),
+			expectedStage: StageTranscription,
+		},
+		{
+			name:         "chunks",
+			givenOpts:    []Option{WithChunking(ChunkConfig{Length: time.Second}), WithPartialResults(10)},
+			givenReply:   reply("text", nil),
+			givenConvert: wavConverter,
+		},
+		{
+			name:          "chunk failure",
+			givenOpts:     []Option{WithChunking(ChunkConfig{Length: time.Second, Parallelism: 1})},
+			givenReply:    reply("", assert.AnError),
+			givenConvert:  wavConverter,
+			expectedStage: StageTranscription,
+		},
+		{
+			name: "languages",
+			givenInput: Input{
+				Name:       "test.mp4",
+				OutputType: OutputTypeTranscript,
+				Languages:  []string{"en", "pt"},
+			},
+			givenReply:   reply("text", nil),
+			givenConvert: wavConverter,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+			convert := tc.givenConvert
+			if convert == nil {
+				convert = copyConverter
+			}
+
+			s := New(noopLogger(), &mockWhisperClient{transcribeAudioFunc: tc.givenReply},
+				append([]Option{WithConverter(convert)}, tc.givenOpts...)...)
+			if tc.givenNoReader {
+				s.resultsCh = make(chan Output)
+			}
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			if tc.givenCancel > 0 {
+				timer := time.AfterFunc(tc.givenCancel, cancel)
+				defer timer.Stop()
+			}
+
+			in := tc.givenInput
+			if in.Name == "" {
+				in = Input{Name: "test.mp4", OutputType: OutputTypeTranscript, Language: "en"}
+			}
+			in.Data = io.NopCloser(bytes.NewBufferString("media"))
+
+			err := s.Process(ctx, in)
+
+			if tc.expectedStage != "" {
+				var pe *ProcessError
+				require.ErrorAs(t, err, &pe)
+				assert.Equal(t, tc.expectedStage, pe.Stage)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, s.Shutdown(context.TODO()))
+			for out := range s.Collect() {
+				out.Body.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package scriber
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// WithLogTimeout bounds each log call to d, so that a slog handler that
+// blocks, e.g. writing to a full pipe, can't stall the jobs logging through
+// it. Records the handler takes longer than d to handle are dropped; while
+// a call is still stuck past d, further records are dropped right away.
+// Zero, the default, waits for the handler. Panics in the handler are
+// always recovered and the record dropped. See LogStats.
+func WithLogTimeout(d time.Duration) Option {
+	return func(s *Scriber) {
+		s.logTimeout = d
+	}
+}
+
+// LogStats counts the log records dropped to keep a misbehaving
+// slog handler from stalling or crashing jobs.
+type LogStats struct {
+	// TimedOut is the number of records dropped because the handler
+	// didn't handle them within the log timeout, or was still stuck.
+	TimedOut int64
+
+	// Panics is the number of records whose handler panicked.
+	Panics int64
+}
+
+// LogStats returns the log records dropped since s was created.
+func (s *Scriber) LogStats() LogStats {
+	return LogStats{
+		TimedOut: s.logGuard.timedOut.Load(),
+		Panics:   s.logGuard.panics.Load(),
+	}
+}
+
+// logGuard is the state shared by the guard handlers of a Scriber.
+type logGuard struct {
+	timeout time.Duration
+
+	timedOut atomic.Int64
+	panics   atomic.Int64
+
+	// stuck is the number of handler calls still running past the timeout.
+	stuck atomic.Int32
+}
+
+// guardHandler passes records on to next, recovering its panics and
+// dropping the records it takes too long to handle.
+type guardHandler struct {
+	next  slog.Handler
+	guard *logGuard
+}
+
+func (h *guardHandler) Enabled(ctx context.Context, level slog.Level) (enabled bool) {
+	defer func() {
+		if recover() != nil {
+			h.guard.panics.Add(1)
+			enabled = false
+		}
+	}()
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *guardHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.guard.timeout <= 0 {
+		return h.handle(ctx, r)
+	}
+	if h.guard.stuck.Load() > 0 {
+		h.guard.timedOut.Add(1)
+		return nil
+	}
+
+	done := make(chan struct{})
+	r = r.Clone()
+	go func() {
+		defer close(done)
+		_ = h.handle(ctx, r)
+	}()
+
+	timer := time.NewTimer(h.guard.timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		h.guard.timedOut.Add(1)
+		h.guard.stuck.Add(1)
+		go func() {
+			<-done
+			h.guard.stuck.Add(-1)
+		}()
+	}
+	return nil
+}
+
+// handle passes r on to next, recovering its panics.
+func (h *guardHandler) handle(ctx context.Context, r slog.Record) (err error) {
+	defer func() {
+		if recover() != nil {
+			h.guard.panics.Add(1)
+			err = nil
+		}
+	}()
+	return h.next.Handle(ctx, r)
+}
+
+func (h *guardHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *guardHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+// with returns a guard over the handler fn derives from next,
+// or h itself if fn panics.
+func (h *guardHandler) with(fn func(next slog.Handler) slog.Handler) (derived slog.Handler) {
+	defer func() {
+		if recover() != nil {
+			h.guard.panics.Add(1)
+			derived = h
+		}
+	}()
+	return &guardHandler{next: fn(h.next), guard: h.guard}
+}
@@ -0,0 +1,147 @@
+package scriber
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// misbehavingHandler is a slog handler that blocks on unblock,
+// or panics, in the calls it is told to.
+type misbehavingHandler struct {
+	unblock     chan struct{}
+	panicHandle bool
+	panicAttrs  bool
+}
+
+func (h *misbehavingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *misbehavingHandler) Handle(context.Context, slog.Record) error {
+	if h.panicHandle {
+		panic("handler panic")
+	}
+	if h.unblock != nil {
+		<-h.unblock
+	}
+	return nil
+}
+
+func (h *misbehavingHandler) WithAttrs([]slog.Attr) slog.Handler {
+	if h.panicAttrs {
+		panic("handler panic")
+	}
+	return h
+}
+
+func (h *misbehavingHandler) WithGroup(string) slog.Handler { return h }
+
+func TestProcess_LogGuard(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		givenHandler     func(t *testing.T) *misbehavingHandler
+		givenTimeout     time.Duration
+		expectedTimeouts bool
+		expectedPanics   bool
+	}{
+		{
+			name: "blocking handler",
+			givenHandler: func(t *testing.T) *misbehavingHandler {
+				unblock := make(chan struct{})
+				t.Cleanup(func() { close(unblock) })
+				return &misbehavingHandler{unblock: unblock}
+			},
+			givenTimeout:     20 * time.Millisecond,
+			expectedTimeouts: true,
+		},
+		{
+			name: "panicking handler",
+			givenHandler: func(*testing.T) *misbehavingHandler {
+				return &misbehavingHandler{panicHandle: true}
+			},
+			expectedPanics: true,
+		},
+		{
+			name: "panicking attrs",
+			givenHandler: func(*testing.T) *misbehavingHandler {
+				return &misbehavingHandler{panicAttrs: true}
+			},
+			expectedPanics: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := New(slog.New(tc.givenHandler(t)), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.Copy(io.Discard, in.Data)
+					return []byte("text"), err
+				},
+			},
+				WithLogTimeout(tc.givenTimeout),
+				WithConverter(copyConverter),
+			)
+
+			done := make(chan error, 1)
+			go func() {
+				done <- s.Process(context.TODO(), Input{
+					Name:       "talk.mp4",
+					OutputType: OutputTypeTranscript,
+					Language:   "en",
+					Data:       io.NopCloser(strings.NewReader("data")),
+				})
+			}()
+
+			select {
+			case err := <-done:
+				require.NoError(t, err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("Process is stalled by its logger")
+			}
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+			assert.Equal(t, "text", string(out.Text))
+			assert.NotEmpty(t, out.Events, "events are recorded whatever the handler does")
+
+			stats := s.LogStats()
+			assert.Equal(t, tc.expectedTimeouts, stats.TimedOut > 0)
+			assert.Equal(t, tc.expectedPanics, stats.Panics > 0)
+		})
+	}
+}
+
+func TestGuardHandler_Stuck(t *testing.T) {
+	t.Parallel()
+
+	unblock := make(chan struct{})
+	guard := &logGuard{timeout: 10 * time.Millisecond}
+	logger := slog.New(&guardHandler{next: &misbehavingHandler{unblock: unblock}, guard: guard})
+
+	start := time.Now()
+	for range 10 {
+		logger.Info("hello")
+	}
+
+	// Only the first call waits for the timeout; the others are dropped
+	// while the handler is stuck.
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+	assert.EqualValues(t, 10, guard.timedOut.Load())
+	assert.EqualValues(t, 1, guard.stuck.Load())
+
+	close(unblock)
+	assert.Eventually(t, func() bool { return guard.stuck.Load() == 0 }, time.Second, time.Millisecond)
+
+	logger.Info("hello")
+	assert.EqualValues(t, 10, guard.timedOut.Load())
+}
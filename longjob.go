@@ -0,0 +1,147 @@
+package scriber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LongJobHandler takes over an input too long to be transcribed inline,
+// typically by enqueueing it for a batch or chunked pipeline. It must not
+// keep Process waiting: Process returns once it has returned.
+type LongJobHandler func(ctx context.Context, in Input) error
+
+// LongJobConfig configures routing long inputs away from Process.
+type LongJobConfig struct {
+	// Threshold is the duration above which inputs are handed off.
+	Threshold time.Duration
+
+	// Handler takes over the inputs longer than Threshold.
+	Handler LongJobHandler
+
+	// SpoolDir is the directory Input.Data is copied to before being
+	// handed off, since the caller of Process may not be able to read it
+	// again. It defaults to the system's temporary directory.
+	SpoolDir string
+}
+
+func (c LongJobConfig) validate() error {
+	if c.Threshold <= 0 {
+		return errors.New("threshold must be positive")
+	}
+	if c.Handler == nil {
+		return errors.New("handler is required")
+	}
+	return nil
+}
+
+// DeferredError is returned by Process for inputs handed off to the
+// LongJobHandler set with WithLongJobRouting, which produced no Output.
+// It isn't a failure: the handler accepted the input, e.g. for the
+// caller to answer 202 Accepted. The job isn't logged or timed as failed,
+// and ProcessBatch reports it in BatchReport.Deferred, not counting it
+// toward FailFast.
+type DeferredError struct {
+	// Duration is the duration of the input.
+	Duration time.Duration
+
+	// Threshold is the duration above which inputs are handed off.
+	Threshold time.Duration
+
+	// DataRef is the reference of the input handed off, see Input.DataRef.
+	DataRef string
+}
+
+func (e *DeferredError) Error() string {
+	return fmt.Sprintf("input deferred: %s is longer than %s", e.Duration, e.Threshold)
+}
+
+// WithLongJobRouting transcribes the inputs up to cfg.Threshold long
+// inline, and hands those above it to cfg.Handler. The duration is taken
+// from Input.DurationHint, or probed: inputs whose Data isn't seekable
+// are first copied to cfg.SpoolDir. Inputs whose duration can't be told
+// are transcribed inline.
+//
+// The handler is given the Input with Data nil and DataRef set: a Data
+// input is copied to a file in cfg.SpoolDir, which DataRef names, to be
+// opened with DirBlobStore{Dir: cfg.SpoolDir}, and which the handler
+// owns and must remove once done with it. A DataRef input is handed off
+// as is. Process then fails with a *ProcessError wrapping a
+// *DeferredError, unless the handler fails.
+func WithLongJobRouting(cfg LongJobConfig) Option {
+	return func(s *Scriber) {
+		if cfg.SpoolDir == "" {
+			cfg.SpoolDir = os.TempDir()
+		}
+		s.longJobs = &cfg
+	}
+}
+
+// routeLongJob hands the job's input off to the long job handler if it is
+// longer than the threshold, returning a *DeferredError once handed off.
+// Otherwise it returns nil, and the job is transcribed inline; when its
+// data had to be spooled to be probed, the job reads the spool instead,
+// and cleanup removes it.
+func (s *Scriber) routeLongJob(ctx context.Context, j *job) (cleanup func(), err error) {
+	cleanup = func() {}
+	if s.longJobs == nil {
+		return cleanup, nil
+	}
+	cfg := *s.longJobs
+	if err := cfg.validate(); err != nil {
+		return cleanup, fmt.Errorf("invalid long job config: %w", err)
+	}
+
+	var spool *os.File
+	d := j.in.DurationHint
+	if d <= 0 {
+		if _, ok := j.in.Data.(io.Seeker); !ok && j.in.DataRef == "" {
+			if spool, err = spoolInput(j.in.Data, cfg.SpoolDir, s.buffers()); err != nil {
+				return cleanup, err
+			}
+			j.in.Data = spool
+			cleanup = func() {
+				spool.Close()
+				os.Remove(spool.Name())
+			}
+		}
+
+		d, err = s.probeSeekable(ctx, j.in)
+		if err != nil {
+			j.logger.Warn("Could not probe input duration, transcribing inline", slog.String("file", j.in.Name), slog.String("error", err.Error()))
+			return cleanup, nil
+		}
+		j.probedDuration = d
+	}
+
+	if d <= cfg.Threshold {
+		return cleanup, nil
+	}
+
+	handoff := j.in
+	handoff.Data, handoff.KeepOpen = nil, false
+	if j.in.DataRef == "" {
+		if spool == nil {
+			if spool, err = spoolInput(j.in.Data, cfg.SpoolDir, s.buffers()); err != nil {
+				return cleanup, err
+			}
+		}
+		spool.Close()
+		handoff.DataRef = filepath.Base(spool.Name())
+	}
+
+	j.logger.Info("Handing long input off", slog.String("file", j.in.Name), slog.Duration("duration", d), slog.String("ref", handoff.DataRef))
+
+	if err := s.callLongJobHandler(ctx, cfg.Handler, handoff); err != nil {
+		if spool != nil {
+			os.Remove(spool.Name())
+		}
+		return func() {}, fmt.Errorf("could not hand long input off: %w", err)
+	}
+	return func() {}, &DeferredError{Duration: d, Threshold: cfg.Threshold, DataRef: handoff.DataRef}
+}
@@ -0,0 +1,366 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_LongJobRouting(t *testing.T) {
+	t.Parallel()
+
+	audio := syntheticWAV(1)
+
+	testCases := []struct {
+		name          string
+		givenHint     time.Duration
+		givenProbed   time.Duration
+		givenData     func() io.ReadCloser
+		expectedDefer bool
+	}{
+		{
+			name:      "short hint runs inline",
+			givenHint: 30 * time.Second,
+			givenData: func() io.ReadCloser { return io.NopCloser(bytes.NewReader(audio)) },
+		},
+		{
+			name:          "long hint is deferred",
+			givenHint:     2 * time.Hour,
+			givenData:     func() io.ReadCloser { return io.NopCloser(bytes.NewReader(audio)) },
+			expectedDefer: true,
+		},
+		{
+			name:        "short seekable probe runs inline",
+			givenProbed: 30 * time.Second,
+			givenData:   func() io.ReadCloser { return readSeekNopCloser{bytes.NewReader(audio)} },
+		},
+		{
+			name:          "long seekable probe is deferred",
+			givenProbed:   2 * time.Hour,
+			givenData:     func() io.ReadCloser { return readSeekNopCloser{bytes.NewReader(audio)} },
+			expectedDefer: true,
+		},
+		{
+			name:        "short streamed probe runs inline from the spool",
+			givenProbed: 30 * time.Second,
+			givenData:   func() io.ReadCloser { return io.NopCloser(bytes.NewReader(audio)) },
+		},
+		{
+			name:          "long streamed probe is deferred",
+			givenProbed:   2 * time.Hour,
+			givenData:     func() io.ReadCloser { return io.NopCloser(bytes.NewReader(audio)) },
+			expectedDefer: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+
+			var (
+				transcribed int
+				handedOff   []Input
+			)
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					transcribed++
+					_, err := io.Copy(io.Discard, in.Data)
+					return []byte("text"), err
+				},
+			}, WithConverter(copyConverter), WithLongJobRouting(LongJobConfig{
+				Threshold: time.Minute,
+				SpoolDir:  dir,
+				Handler: func(_ context.Context, in Input) error {
+					handedOff = append(handedOff, in)
+					return nil
+				},
+			}))
+			s.probeDurationFunc = func(_ context.Context, r io.Reader) (time.Duration, error) {
+				require.NotZero(t, tc.givenProbed, "probed despite the hint")
+				_, err := io.Copy(io.Discard, r)
+				return tc.givenProbed, err
+			}
+
+			err := s.Process(context.TODO(), Input{
+				Name:         "talk.mp4",
+				OutputType:   OutputTypeTranscript,
+				Language:     "en",
+				Data:         tc.givenData(),
+				DurationHint: tc.givenHint,
+			})
+
+			if !tc.expectedDefer {
+				require.NoError(t, err)
+				assert.Empty(t, handedOff)
+				assert.Equal(t, 1, transcribed)
+
+				out := <-s.Collect()
+				out.Body.Close()
+				assert.Equal(t, "text", string(out.Text))
+
+				// The spool of a streamed input is removed once transcribed.
+				entries, err := os.ReadDir(dir)
+				require.NoError(t, err)
+				assert.Empty(t, entries)
+				return
+			}
+
+			var deferred *DeferredError
+			require.ErrorAs(t, err, &deferred)
+			assert.Equal(t, time.Minute, deferred.Threshold)
+			assert.Equal(t, max(tc.givenHint, tc.givenProbed), deferred.Duration)
+			assert.Zero(t, transcribed)
+			assert.Empty(t, s.Collect())
+
+			// The handler gets a reference it can read the input from
+			// once Process has returned.
+			require.Len(t, handedOff, 1)
+			in := handedOff[0]
+			assert.Nil(t, in.Data)
+			assert.Equal(t, deferred.DataRef, in.DataRef)
+			assert.Equal(t, "talk.mp4", in.Name)
+
+			data, _, err := DirBlobStore{Dir: dir}.Open(context.TODO(), in.DataRef)
+			require.NoError(t, err)
+			defer data.Close()
+
+			got, err := io.ReadAll(data)
+			require.NoError(t, err)
+			assert.Equal(t, audio, got)
+		})
+	}
+}
+
+func TestProcess_LongJobRoutingDataRef(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(dir+"/talk.mp4", syntheticWAV(1), 0o644))
+
+	var handedOff []Input
+	s := New(noopLogger(), &mockWhisperClient{}, WithConverter(copyConverter),
+		WithBlobStore(DirBlobStore{Dir: dir}),
+		WithLongJobRouting(LongJobConfig{
+			Threshold: time.Minute,
+			SpoolDir:  t.TempDir(),
+			Handler: func(_ context.Context, in Input) error {
+				handedOff = append(handedOff, in)
+				return nil
+			},
+		}))
+
+	err := s.Process(context.TODO(), Input{
+		Name:         "talk.mp4",
+		OutputType:   OutputTypeTranscript,
+		Language:     "en",
+		DataRef:      "talk.mp4",
+		DurationHint: time.Hour,
+	})
+
+	var deferred *DeferredError
+	require.ErrorAs(t, err, &deferred)
+	require.Len(t, handedOff, 1)
+	assert.Equal(t, "talk.mp4", handedOff[0].DataRef)
+	assert.Nil(t, handedOff[0].Data)
+}
+
+func TestProcess_LongJobHandlerError(t *testing.T) {
+	t.Parallel()
+
+	errQueueFull := errors.New("queue full")
+
+	testCases := []struct {
+		name          string
+		givenHandler  LongJobHandler
+		expectedErr   error
+		expectedPanic bool
+	}{
+		{
+			name: "error",
+			givenHandler: func(context.Context, Input) error {
+				return errQueueFull
+			},
+			expectedErr: errQueueFull,
+		},
+		{
+			name: "panic",
+			givenHandler: func(context.Context, Input) error {
+				panic(errQueueFull)
+			},
+			expectedErr:   errQueueFull,
+			expectedPanic: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			s := New(noopLogger(), &mockWhisperClient{}, WithConverter(copyConverter), WithErrorChannel(1),
+				WithLongJobRouting(LongJobConfig{
+					Threshold: time.Minute,
+					SpoolDir:  dir,
+					Handler:   tc.givenHandler,
+				}))
+
+			err := s.Process(context.TODO(), Input{
+				Name:         "talk.mp4",
+				OutputType:   OutputTypeTranscript,
+				Language:     "en",
+				Data:         io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+				DurationHint: time.Hour,
+			})
+			require.ErrorIs(t, err, tc.expectedErr)
+
+			var pe *ProcessError
+			require.ErrorAs(t, err, &pe)
+			assert.Equal(t, StageValidation, pe.Stage)
+
+			var deferred *DeferredError
+			assert.False(t, errors.As(err, &deferred))
+
+			var panicErr *PanicError
+			assert.Equal(t, tc.expectedPanic, errors.As(err, &panicErr))
+			if tc.expectedPanic {
+				select {
+				case notified := <-s.Errors():
+					require.ErrorAs(t, notified, &panicErr)
+				default:
+					t.Fatal("the panic wasn't reported on the error channel")
+				}
+			}
+
+			// The spool the handler didn't take is removed.
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+}
+
+// newLongJobScriber returns a Scriber handing the inputs whose data starts
+// with "long" off to a handler, which records their names.
+func newLongJobScriber(t *testing.T) (*Scriber, func() []string) {
+	t.Helper()
+
+	var (
+		mu        sync.Mutex
+		handedOff []string
+	)
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.Copy(io.Discard, in.Data)
+			return []byte("text"), err
+		},
+	}, WithConverter(copyConverter), WithLongJobRouting(LongJobConfig{
+		Threshold: time.Minute,
+		SpoolDir:  t.TempDir(),
+		Handler: func(_ context.Context, in Input) error {
+			mu.Lock()
+			defer mu.Unlock()
+			handedOff = append(handedOff, in.Name)
+			return nil
+		},
+	}))
+	s.probeDurationFunc = func(_ context.Context, r io.Reader) (time.Duration, error) {
+		data, err := io.ReadAll(r)
+		if bytes.HasPrefix(data, []byte("long")) {
+			return time.Hour, err
+		}
+		return 10 * time.Second, err
+	}
+
+	go func() {
+		for out := range s.Collect() {
+			out.Body.Close()
+		}
+	}()
+
+	return s, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), handedOff...)
+	}
+}
+
+func TestProcessBatch_LongJobDeferred(t *testing.T) {
+	t.Parallel()
+
+	s, handedOff := newLongJobScriber(t)
+
+	input := func(name, data string) Input {
+		return Input{
+			Name:       name,
+			OutputType: OutputTypeTranscript,
+			Language:   "en",
+			Data:       io.NopCloser(strings.NewReader(data)),
+		}
+	}
+
+	// A deferred input doesn't stop the batch, even failing fast.
+	report, err := s.ProcessBatch(context.TODO(), []Input{
+		input("a.mp4", "long audio"),
+		input("b.mp4", "short audio"),
+		input("c.mp4", "short audio"),
+	}, BatchOptions{FailFast: true})
+	require.NoError(t, err)
+
+	require.Len(t, report.Deferred, 1)
+	assert.Equal(t, "a.mp4", report.Deferred[0].Name)
+	assert.Contains(t, report.Deferred[0].Reason, "input deferred")
+	assert.Len(t, report.Succeeded, 2)
+	assert.Empty(t, report.Failed)
+	assert.Empty(t, report.Skipped)
+	assert.Equal(t, []string{"a.mp4"}, handedOff())
+
+	// Nor is it timed as failed.
+	stats := s.TimingStats()
+	assert.Empty(t, stats.Failed)
+	assert.Equal(t, int64(2), stats.Succeeded.Jobs)
+}
+
+func TestProcessDir_ResumeDeferred(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.mp4"), []byte("long audio"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.mp4"), []byte("short audio"), 0o644))
+
+	opts := DirOptions{
+		BatchOptions: BatchOptions{ReportPath: reportPath},
+		OutputType:   OutputTypeTranscript,
+		Language:     "en",
+	}
+
+	s, handedOff := newLongJobScriber(t)
+	report, err := s.ProcessDir(context.TODO(), dir, opts)
+	require.NoError(t, err)
+	require.Len(t, report.Deferred, 1)
+	assert.Equal(t, []string{"a.mp4"}, handedOff())
+
+	// Resuming doesn't hand the deferred input off again.
+	s, handedOff = newLongJobScriber(t)
+	opts.ResumeFrom = reportPath
+	report, err = s.ProcessDir(context.TODO(), dir, opts)
+	require.NoError(t, err)
+	require.Len(t, report.Resumed, 2)
+	assert.Equal(t, "deferred in a previous run", report.Resumed[0].Reason)
+	assert.Equal(t, "completed in a previous run", report.Resumed[1].Reason)
+	assert.Empty(t, report.Deferred)
+	assert.Empty(t, handedOff())
+}
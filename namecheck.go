@@ -0,0 +1,85 @@
+package scriber
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// defaultMaxNameLength is the longest input name accepted by default,
+// in bytes, the longest file name on most file systems.
+const defaultMaxNameLength = 255
+
+// WithMaxNameLength sets the longest Input.Name accepted, in bytes,
+// 255 when zero. Longer names fail validation with a NameTooLongError.
+func WithMaxNameLength(n int) Option {
+	return func(s *Scriber) {
+		s.maxNameLength = n
+	}
+}
+
+// WithLenientNames repairs input names that aren't valid UTF-8, replacing
+// invalid bytes with the Unicode replacement character, instead of
+// failing them with a NameEncodingError.
+func WithLenientNames(enabled bool) Option {
+	return func(s *Scriber) {
+		s.lenientNames = enabled
+	}
+}
+
+// normalizeInput normalizes the name, user tag, and output type of in.
+// On error, the name of the returned input is made fit to be reported.
+func (s *Scriber) normalizeInput(in Input) (Input, error) {
+	name, err := s.normalizeName(in.Name)
+	if err != nil {
+		in.Name = rejectedName(in.Name)
+		return in, err
+	}
+	in.Name = name
+
+	tag, err := normalizeUserTag(in.UserTag)
+	if err != nil {
+		return in, err
+	}
+	in.UserTag = tag
+
+	t, fellBack := s.resolveOutputType(in.OutputType)
+	if fellBack {
+		in.requestedOutputType = in.OutputType
+	}
+	in.OutputType = t
+	return in, nil
+}
+
+// normalizeName returns name stripped of surrounding whitespace, or an
+// error if it isn't valid UTF-8 or is too long. It runs before the name
+// is logged, sent to the backend, or used to name the output.
+func (s *Scriber) normalizeName(name string) (string, error) {
+	if !utf8.ValidString(name) {
+		if !s.lenientNames {
+			return "", errNameEncoding
+		}
+		name = strings.ToValidUTF8(name, string(utf8.RuneError))
+	}
+
+	limit := s.maxNameLength
+	if limit <= 0 {
+		limit = defaultMaxNameLength
+	}
+
+	name = strings.TrimSpace(name)
+	if len(name) > limit {
+		return "", fmt.Errorf("%w: %d bytes, at most %d", errNameTooLong, len(name), limit)
+	}
+	return name, nil
+}
+
+// rejectedName returns the beginning of a name that failed normalization,
+// made valid UTF-8, to report it in errors without echoing it whole.
+func rejectedName(name string) string {
+	const n = 64
+	if len(name) > n {
+		name = name[:n] + "..."
+	}
+	return strings.ToValidUTF8(name, string(utf8.RuneError))
+}
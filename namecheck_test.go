@@ -0,0 +1,125 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_NameNormalization(t *testing.T) {
+	t.Parallel()
+
+	junk := strings.Repeat("%2F%2E%2E", 512) + ".mp3"
+
+	testCases := []struct {
+		name         string
+		givenOpts    []Option
+		givenName    string
+		expectedName string
+		expectedErr  error
+	}{
+		{
+			name:         "surrounding whitespace",
+			givenName:    " \t talk.mp3\n",
+			expectedName: "talk.txt",
+		},
+		{
+			name:        "whitespace only",
+			givenName:   " \t\n",
+			expectedErr: errNameRequired,
+		},
+		{
+			name:        "invalid UTF-8",
+			givenName:   "talk\xff\xfe.mp3",
+			expectedErr: errNameEncoding,
+		},
+		{
+			name:         "invalid UTF-8 repaired",
+			givenOpts:    []Option{WithLenientNames(true)},
+			givenName:    "talk\xff.mp3",
+			expectedName: "talk�.txt",
+		},
+		{
+			name:        "URL-encoded junk",
+			givenName:   junk,
+			expectedErr: errNameTooLong,
+		},
+		{
+			name:         "at the maximum length",
+			givenName:    strings.Repeat("a", defaultMaxNameLength-4) + ".mp3",
+			expectedName: strings.Repeat("a", defaultMaxNameLength-4) + ".txt",
+		},
+		{
+			// The length is checked once the name is trimmed.
+			name:         "custom maximum length",
+			givenOpts:    []Option{WithMaxNameLength(8)},
+			givenName:    "talk.mp3 ",
+			expectedName: "talk.txt",
+		},
+		{
+			name:        "over the custom maximum length",
+			givenOpts:   []Option{WithMaxNameLength(8)},
+			givenName:   "talks.mp3",
+			expectedErr: errNameTooLong,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var uploaded string
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					uploaded = in.Name
+					_, err := io.ReadAll(in.Data)
+					return []byte("hello"), err
+				},
+			}, tc.givenOpts...)
+			s.convertToWavFunc = copyConverter
+
+			err := s.Process(context.TODO(), Input{
+				Name:       tc.givenName,
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+			})
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+
+				var pe *ProcessError
+				require.ErrorAs(t, err, &pe)
+				assert.Equal(t, StageValidation, pe.Stage)
+				assert.LessOrEqual(t, len(err.Error()), 256, "the name is not echoed whole")
+				return
+			}
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+
+			assert.Equal(t, tc.expectedName, out.Name)
+			assert.Equal(t, strings.TrimSuffix(tc.expectedName, ".txt")+".wav", uploaded)
+		})
+	}
+}
+
+func TestNormalizeName_Lenient(t *testing.T) {
+	t.Parallel()
+
+	s := New(noopLogger(), &mockWhisperClient{}, WithLenientNames(true), WithMaxNameLength(4))
+
+	// Repaired names are still subject to the length limit.
+	_, err := s.normalizeName("\xff\xff.mp3")
+	require.ErrorIs(t, err, errNameTooLong)
+
+	name, err := s.normalizeName(" \xff ")
+	require.NoError(t, err)
+	assert.Equal(t, "�", name)
+}
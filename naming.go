@@ -0,0 +1,180 @@
+package scriber
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// NameOption configures how Output.Filename assembles a file name.
+type NameOption func(*nameOptions)
+
+type nameOptions struct {
+	inputExt   bool
+	language   bool
+	sanitize   bool
+	raw        bool
+	normalized bool
+	dir        string
+}
+
+// WithNameInputExtension inserts the input extension right after the base
+// name, e.g. video.mp4.srt, so that inputs differing only by their
+// extension get distinct outputs. Outputs without an input extension are
+// unaffected. It comes before the language and raw, e.g. video.mp4.pt.raw.srt.
+func WithNameInputExtension() NameOption {
+	return func(o *nameOptions) {
+		o.inputExt = true
+	}
+}
+
+// WithNameLanguage inserts the output language before the extension,
+// e.g. talk.pt.srt. Outputs without a language are unaffected.
+func WithNameLanguage() NameOption {
+	return func(o *nameOptions) {
+		o.language = true
+	}
+}
+
+// WithNameSanitized drops any directories from the base name and replaces
+// characters that are unsafe in file names on common file systems.
+func WithNameSanitized() NameOption {
+	return func(o *nameOptions) {
+		o.sanitize = true
+	}
+}
+
+// WithNameRaw inserts raw before the extension, after the language if any,
+// e.g. talk.raw.srt, to name the untouched transcription (see Output.RawText).
+func WithNameRaw() NameOption {
+	return func(o *nameOptions) {
+		o.raw = true
+	}
+}
+
+// WithNameNormalized inserts normalized before the extension, after the
+// language if any, e.g. talk.normalized.txt, to name the normalized
+// transcript (see WithNormalizedOutput).
+func WithNameNormalized() NameOption {
+	return func(o *nameOptions) {
+		o.normalized = true
+	}
+}
+
+// WithNameDir places the file in dir.
+func WithNameDir(dir string) NameOption {
+	return func(o *nameOptions) {
+		o.dir = dir
+	}
+}
+
+// WithInputExtensionInName keeps the input extension in output names,
+// e.g. video.mp4.srt instead of video.srt, so that video.mp4 and video.mov
+// don't produce the same output name. With WithLanguageInName too, the
+// input extension comes first: video.mp4.pt.srt. See WithNameInputExtension.
+func WithInputExtensionInName(enabled bool) Option {
+	return func(s *Scriber) {
+		s.inputExtInName = enabled
+	}
+}
+
+// Filename assembles the output's file name from its base name, input
+// extension, language, raw or normalized, and extension, in that order.
+// Without options, it is the base name followed by the extension.
+func (o Output) Filename(opts ...NameOption) string {
+	var cfg nameOptions
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	base, inExt, lang := o.BaseName, strings.TrimPrefix(o.InputExtension, "."), o.Language
+	if cfg.sanitize {
+		base, lang = sanitizeName(filepath.Base(base)), sanitizeName(lang)
+		if inExt != "" {
+			inExt = sanitizeName(inExt)
+		}
+	}
+
+	name := base
+	if cfg.inputExt && inExt != "" {
+		name += "." + inExt
+	}
+	if cfg.language && lang != "" {
+		name += "." + lang
+	}
+	if cfg.raw {
+		name += ".raw"
+	}
+	if cfg.normalized {
+		name += ".normalized"
+	}
+	name += o.Extension
+
+	if cfg.dir != "" {
+		name = filepath.Join(cfg.dir, name)
+	}
+	return name
+}
+
+// baseName returns name without its extension, keeping its case.
+func baseName(name string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// originalExtension returns the extension of name, including the dot
+// and keeping its case. A trailing dot alone isn't an extension.
+func originalExtension(name string) string {
+	if ext := filepath.Ext(name); ext != "." {
+		return ext
+	}
+	return ""
+}
+
+// inputExtension returns the lowercased extension of name, including the
+// dot, for comparisons. A trailing dot alone isn't an extension.
+func inputExtension(name string) string {
+	return strings.ToLower(originalExtension(name))
+}
+
+// outputExtension returns the file extension for outputs of type t.
+func outputExtension(t OutputType) string {
+	switch t {
+	case OutputTypeTranscript:
+		return ".txt"
+	case OutputTypeSBV:
+		return ".sbv"
+	default:
+		return ".srt"
+	}
+}
+
+// outputExtension returns the extension of the outputs of i.
+func (i Input) outputExtension() string {
+	if i.OutputExtension != "" {
+		return i.OutputExtension
+	}
+	return outputExtension(i.OutputType)
+}
+
+// generateOutputFileName returns the default output name for an input named filename.
+func generateOutputFileName(filename string, outType OutputType) string {
+	return Output{BaseName: baseName(filename), Extension: outputExtension(outType)}.Filename()
+}
+
+// sanitizeName replaces path separators, reserved characters, and control
+// characters in name, and trims the leading dots and surrounding spaces
+// that make files hidden or awkward to handle.
+func sanitizeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+	if name == "" {
+		return "_"
+	}
+	return name
+}
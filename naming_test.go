@@ -0,0 +1,319 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputFilename(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		givenOutput Output
+		givenOpts   []NameOption
+		expected    string
+	}{
+		{
+			name:        "default",
+			givenOutput: Output{BaseName: "talk", Extension: ".srt", Language: "pt"},
+			expected:    "talk.srt",
+		},
+		{
+			name:        "language",
+			givenOutput: Output{BaseName: "talk", Extension: ".srt", Language: "pt"},
+			givenOpts:   []NameOption{WithNameLanguage()},
+			expected:    "talk.pt.srt",
+		},
+		{
+			name:        "language with dotted base name",
+			givenOutput: Output{BaseName: "my.talk", Extension: ".txt", Language: "en"},
+			givenOpts:   []NameOption{WithNameLanguage()},
+			expected:    "my.talk.en.txt",
+		},
+		{
+			name:        "language without a language",
+			givenOutput: Output{BaseName: "talk", Extension: ".srt"},
+			givenOpts:   []NameOption{WithNameLanguage()},
+			expected:    "talk.srt",
+		},
+		{
+			name:        "sanitized",
+			givenOutput: Output{BaseName: `../dir/..a:b*c?"d"<e>|f`, Extension: ".txt"},
+			givenOpts:   []NameOption{WithNameSanitized()},
+			expected:    `a_b_c__d__e__f.txt`,
+		},
+		{
+			name:        "sanitized control characters",
+			givenOutput: Output{BaseName: "a\nb\tc", Extension: ".txt"},
+			givenOpts:   []NameOption{WithNameSanitized()},
+			expected:    "a_b_c.txt",
+		},
+		{
+			name:        "sanitized empty base name",
+			givenOutput: Output{BaseName: "..", Extension: ".txt"},
+			givenOpts:   []NameOption{WithNameSanitized()},
+			expected:    "_.txt",
+		},
+		{
+			name:        "unsanitized keeps directories",
+			givenOutput: Output{BaseName: "dir/talk", Extension: ".srt"},
+			expected:    "dir/talk.srt",
+		},
+		{
+			name:        "dir",
+			givenOutput: Output{BaseName: "talk", Extension: ".srt"},
+			givenOpts:   []NameOption{WithNameDir("out")},
+			expected:    filepath.Join("out", "talk.srt"),
+		},
+		{
+			name:        "raw",
+			givenOutput: Output{BaseName: "talk", Extension: ".srt"},
+			givenOpts:   []NameOption{WithNameRaw()},
+			expected:    "talk.raw.srt",
+		},
+		{
+			name:        "raw with language",
+			givenOutput: Output{BaseName: "talk", Extension: ".srt", Language: "pt"},
+			givenOpts:   []NameOption{WithNameRaw(), WithNameLanguage()},
+			expected:    "talk.pt.raw.srt",
+		},
+		{
+			name:        "sanitized language",
+			givenOutput: Output{BaseName: "talk", Extension: ".srt", Language: "../pt"},
+			givenOpts:   []NameOption{WithNameLanguage(), WithNameSanitized()},
+			expected:    "talk._pt.srt",
+		},
+		{
+			name:        "all options",
+			givenOutput: Output{BaseName: "in/talk:1", Extension: ".srt", Language: "pt"},
+			givenOpts:   []NameOption{WithNameLanguage(), WithNameSanitized(), WithNameDir("out")},
+			expected:    filepath.Join("out", "talk_1.pt.srt"),
+		},
+		{
+			name:        "input extension",
+			givenOutput: Output{BaseName: "video", Extension: ".srt", InputExtension: ".MP4"},
+			givenOpts:   []NameOption{WithNameInputExtension()},
+			expected:    "video.MP4.srt",
+		},
+		{
+			name:        "no input extension",
+			givenOutput: Output{BaseName: "video", Extension: ".srt"},
+			givenOpts:   []NameOption{WithNameInputExtension()},
+			expected:    "video.srt",
+		},
+		{
+			name:        "input extension with language and raw",
+			givenOutput: Output{BaseName: "video", Extension: ".srt", InputExtension: ".mp4", Language: "pt"},
+			givenOpts:   []NameOption{WithNameRaw(), WithNameLanguage(), WithNameInputExtension()},
+			expected:    "video.mp4.pt.raw.srt",
+		},
+		{
+			name:        "sanitized input extension",
+			givenOutput: Output{BaseName: "video", Extension: ".srt", InputExtension: ".m:p4"},
+			givenOpts:   []NameOption{WithNameInputExtension(), WithNameSanitized()},
+			expected:    "video.m_p4.srt",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, tc.givenOutput.Filename(tc.givenOpts...))
+		})
+	}
+}
+
+func TestExtensionCase(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name              string
+		givenName         string
+		expectedExt       string
+		expectedBase      string
+		expectedSubtitles string
+	}{
+		{
+			name:              "lowercase",
+			givenName:         "talk.mp4",
+			expectedExt:       ".mp4",
+			expectedBase:      "talk",
+			expectedSubtitles: "talk.srt",
+		},
+		{
+			name:              "uppercase",
+			givenName:         "Talk.MP4",
+			expectedExt:       ".mp4",
+			expectedBase:      "Talk",
+			expectedSubtitles: "Talk.srt",
+		},
+		{
+			name:              "mixed case",
+			givenName:         "My.Talk.Mp3",
+			expectedExt:       ".mp3",
+			expectedBase:      "My.Talk",
+			expectedSubtitles: "My.Talk.srt",
+		},
+		{
+			name:              "trailing dot",
+			givenName:         "talk.",
+			expectedExt:       "",
+			expectedBase:      "talk",
+			expectedSubtitles: "talk.srt",
+		},
+		{
+			name:              "no extension",
+			givenName:         "talk",
+			expectedExt:       "",
+			expectedBase:      "talk",
+			expectedSubtitles: "talk.srt",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expectedExt, inputExtension(tc.givenName))
+			assert.Equal(t, tc.expectedBase, baseName(tc.givenName))
+			assert.Equal(t, tc.expectedSubtitles, generateOutputFileName(tc.givenName, OutputTypeSubtitles))
+		})
+	}
+}
+
+func TestProcess_OutputExtension(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		givenType      OutputType
+		givenExt       string
+		givenLanguages []string
+		givenInName    bool
+		givenTaken     string
+		expectedNames  []string
+		expectedErr    error
+	}{
+		{
+			name:          "default transcript",
+			givenType:     OutputTypeTranscript,
+			expectedNames: []string{"talk.txt"},
+		},
+		{
+			name:          "transcript",
+			givenType:     OutputTypeTranscript,
+			givenExt:      ".text",
+			expectedNames: []string{"talk.text"},
+		},
+		{
+			name:          "sbv",
+			givenType:     OutputTypeSBV,
+			givenExt:      ".sub",
+			expectedNames: []string{"talk.sub"},
+		},
+		{
+			name:          "language in name",
+			givenType:     OutputTypeSubtitles,
+			givenExt:      ".captions",
+			givenInName:   true,
+			expectedNames: []string{"talk.en.captions"},
+		},
+		{
+			name:           "languages",
+			givenType:      OutputTypeSubtitles,
+			givenExt:       ".captions",
+			givenLanguages: []string{"en", "pt"},
+			expectedNames:  []string{"talk.en.captions", "talk.pt.captions"},
+		},
+		{
+			name:          "taken name",
+			givenType:     OutputTypeTranscript,
+			givenExt:      ".text",
+			givenTaken:    "talk.text",
+			expectedNames: []string{"talk-1.text"},
+		},
+		{
+			name:        "no dot",
+			givenType:   OutputTypeTranscript,
+			givenExt:    "text",
+			expectedErr: errOutputExt,
+		},
+		{
+			name:        "dot only",
+			givenType:   OutputTypeTranscript,
+			givenExt:    ".",
+			expectedErr: errOutputExt,
+		},
+		{
+			name:        "path separator",
+			givenType:   OutputTypeTranscript,
+			givenExt:    "./../text",
+			expectedErr: errOutputExt,
+		},
+		{
+			name:        "backslash",
+			givenType:   OutputTypeTranscript,
+			givenExt:    `.a\b`,
+			expectedErr: errOutputExt,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := []Option{
+				WithLanguageInName(tc.givenInName),
+				WithConverter(copyConverter),
+			}
+			if tc.givenTaken != "" {
+				opts = append(opts, WithExistsFunc(func(_ context.Context, name string) (bool, error) {
+					return name == tc.givenTaken, nil
+				}, 0))
+			}
+
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.ReadAll(in.Data)
+					require.NoError(t, err)
+					return []byte("1\n00:00:00,000 --> 00:00:01,000\nhello\n"), nil
+				},
+			}, opts...)
+
+			in := Input{
+				Name:            "talk.mp4",
+				OutputType:      tc.givenType,
+				Languages:       tc.givenLanguages,
+				OutputExtension: tc.givenExt,
+				Data:            io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+			}
+			if len(tc.givenLanguages) == 0 {
+				in.Language = "en"
+			}
+
+			err := s.Process(context.TODO(), in)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			var names []string
+			for range tc.expectedNames {
+				out := <-s.Collect()
+				require.NoError(t, out.Body.Close())
+				names = append(names, out.Name)
+			}
+			assert.ElementsMatch(t, tc.expectedNames, names)
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package scriber
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode"
+)
+
+// NormalizationVersion identifies the rules NormalizeTranscript applies.
+// It changes whenever they do, so that normalized transcripts are only
+// compared with ones normalized by the same rules.
+const NormalizationVersion = "v1"
+
+// MetadataNormalization is the Output.Metadata key holding the
+// NormalizationVersion of normalized transcripts.
+const MetadataNormalization = "normalization"
+
+// WithNormalizedOutput publishes, along with each transcription, its
+// normalized transcript, see NormalizeTranscript, as an Output of its own
+// named talk.normalized.txt, for diffing transcripts and scoring their
+// similarity. It carries MetadataNormalization, and is normalized from
+// the redacted transcription. With WithContentID, its ID also covers
+// NormalizationVersion. Failing to publish it doesn't fail the
+// transcription: the failure is logged and reported on the Errors channel.
+func WithNormalizedOutput(enabled bool) Option {
+	return func(s *Scriber) {
+		s.normalizedOutput = enabled
+	}
+}
+
+// NormalizeTranscript returns text normalized for diffing, one sentence
+// per line, so that line wrapping, cue boundaries, case, and punctuation
+// don't show up as differences. The rules, versioned as
+// NormalizationVersion, are applied in order:
+//
+//   - Text is lowercased.
+//   - Sentences end at '.', '!', '?' and '…' followed by a space, a
+//     closing quote or bracket, or the end of the text, and at '。',
+//     '！' and '？'. Line breaks don't end sentences.
+//   - Apostrophes are dropped, joining contractions (don't → dont).
+//   - Other characters but letters, marks, and numbers become spaces,
+//     so 3.5 becomes "3 5".
+//   - Whitespace is collapsed to single spaces, and trimmed.
+//
+// Empty sentences are dropped, and every line ends with a newline.
+// For subtitles, normalize StripSubtitleFormatting's output.
+func NormalizeTranscript(text string) string {
+	runes := []rune(strings.ToLower(text))
+
+	var b, sentence strings.Builder
+	flush := func() {
+		if line := strings.Join(strings.Fields(sentence.String()), " "); line != "" {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+		sentence.Reset()
+	}
+
+	for i, r := range runes {
+		switch {
+		case endsSentence(runes, i):
+			flush()
+		case r == '\'' || r == '’':
+		case unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsNumber(r):
+			sentence.WriteRune(r)
+		default:
+			sentence.WriteRune(' ')
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// endsSentence reports whether runes[i] ends a sentence.
+func endsSentence(runes []rune, i int) bool {
+	switch runes[i] {
+	case '。', '！', '？':
+		return true
+	case '.', '!', '?', '…':
+		if i+1 == len(runes) {
+			return true
+		}
+		next := runes[i+1]
+		return unicode.IsSpace(next) || unicode.In(next, unicode.Pe, unicode.Pf) || next == '"' || next == '\''
+	}
+	return false
+}
+
+// publishNormalized publishes the normalized transcript of the job's
+// transcription, reporting failures on the Errors channel.
+func (s *Scriber) publishNormalized(ctx context.Context, j *job) {
+	if !s.normalizedOutput {
+		return
+	}
+
+	nj := *j
+	nj.normalized = true
+	nj.in.OutputExtension = ".txt"
+	nj.events = j.events.fork()
+	nj.warnings = j.warnings.fork()
+
+	if err := s.publishNormalizedJob(ctx, &nj); err != nil {
+		pe := asProcessError(StagePublish, j.in, fmt.Errorf("could not publish normalized transcript: %w", err))
+		j.logger.Error("Normalized transcript failed", slog.String("file", j.in.Name), slog.String("error", pe.Err.Error()))
+		s.notify(pe)
+	}
+}
+
+// publishNormalizedJob publishes the normalized transcript of j, a copy
+// of the transcription's job named for it.
+func (s *Scriber) publishNormalizedJob(ctx context.Context, j *job) error {
+	src, _ := s.redact(j.raw, j.in.OutputType)
+	plain := NormalizeTranscript(plainText(src, j.in.OutputType))
+
+	text, body, err := newOutputBody([]byte(plain), s.spoolThreshold, s.spoolDir)
+	if err != nil {
+		return fmt.Errorf("could not create output body: %w", err)
+	}
+
+	out := j.output(text)
+	out.Body = body
+	out.Metadata[MetadataNormalization] = NormalizationVersion
+	out.PlainText = plain
+	out.ContentID = s.contentID(j)
+
+	if err := s.reserveName(ctx, j, &out); err != nil {
+		out.Body.Close()
+		return err
+	}
+	if err := s.writeOutput(j, &out); err != nil {
+		out.Body.Close()
+		return err
+	}
+	return s.publish(ctx, j, out)
+}
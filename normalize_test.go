@@ -0,0 +1,149 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTranscript_Golden(t *testing.T) {
+	t.Parallel()
+
+	inputs, err := filepath.Glob(filepath.Join("testdata", "normalize", "*"))
+	require.NoError(t, err)
+
+	var tested int
+	for _, path := range inputs {
+		if strings.HasSuffix(path, ".normalized.txt") {
+			continue
+		}
+		tested++
+
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			expected, err := os.ReadFile(strings.TrimSuffix(path, filepath.Ext(path)) + ".normalized.txt")
+			require.NoError(t, err)
+
+			outType := OutputTypeTranscript
+			if filepath.Ext(path) == ".srt" {
+				outType = OutputTypeSubtitles
+			}
+			assert.Equal(t, string(expected), NormalizeTranscript(plainText(data, outType)))
+		})
+	}
+	require.NotZero(t, tested)
+}
+
+func TestNormalizeTranscript(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		given    string
+		expected string
+	}{
+		{name: "empty", given: "", expected: ""},
+		{name: "punctuation only", given: "... !?", expected: ""},
+		{name: "wrapping", given: "one two\nthree.", expected: "one two three\n"},
+		{name: "idempotent", given: "one two three\n", expected: "one two three\n"},
+		{name: "abbreviation", given: "Mr. Smith", expected: "mr\nsmith\n"},
+		{name: "decimal", given: "pi is 3.14", expected: "pi is 3 14\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, NormalizeTranscript(tc.given))
+		})
+	}
+}
+
+func TestProcess_NormalizedOutput(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.Copy(io.Discard, in.Data)
+			return []byte("1\n00:00:00,000 --> 00:00:01,000\nHello, Jane!\n\n2\n00:00:01,000 --> 00:00:02,000\nWrite to\njane@example.com.\n"), err
+		},
+	},
+		WithConverter(copyConverter),
+		WithRedactor(Redactor{Emails: true}),
+		WithNormalizedOutput(true),
+		WithOutputDir(dir),
+	)
+
+	err := s.Process(context.TODO(), Input{
+		Name:       "talk.mp4",
+		OutputType: OutputTypeSubtitles,
+		Language:   "en",
+		Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+	})
+	require.NoError(t, err)
+
+	transcription := <-s.Collect()
+	require.NoError(t, transcription.Body.Close())
+	assert.Equal(t, "talk.srt", transcription.Name)
+	assert.NotContains(t, transcription.Metadata, MetadataNormalization)
+
+	normalized := <-s.Collect()
+	defer normalized.Body.Close()
+
+	const expected = "hello jane\nwrite to email\n"
+	assert.Equal(t, "talk.normalized.txt", normalized.Name)
+	assert.Equal(t, ".txt", normalized.Extension)
+	assert.Equal(t, expected, string(normalized.Text))
+	assert.Equal(t, expected, normalized.PlainText)
+	assert.Equal(t, NormalizationVersion, normalized.Metadata[MetadataNormalization])
+	assert.Equal(t, transcription.JobID, normalized.JobID)
+
+	written, err := os.ReadFile(filepath.Join(dir, "talk.normalized.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(written))
+}
+
+func TestProcess_NormalizedOutputContentID(t *testing.T) {
+	t.Parallel()
+
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.Copy(io.Discard, in.Data)
+			return []byte("Hello, Jane!"), err
+		},
+	},
+		WithConverter(copyConverter),
+		WithNormalizedOutput(true),
+		WithContentID(true),
+	)
+
+	err := s.Process(context.TODO(), Input{
+		Name:       "talk.mp4",
+		OutputType: OutputTypeTranscript,
+		Language:   "en",
+		Data:       readSeekNopCloser{bytes.NewReader(syntheticWAV(1))},
+	})
+	require.NoError(t, err)
+
+	transcription := <-s.Collect()
+	require.NoError(t, transcription.Body.Close())
+	normalized := <-s.Collect()
+	require.NoError(t, normalized.Body.Close())
+
+	require.NotEmpty(t, transcription.ContentID)
+	assert.NotEmpty(t, normalized.ContentID)
+	assert.NotEqual(t, transcription.ContentID, normalized.ContentID)
+}
@@ -0,0 +1,137 @@
+package scriber
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxHeldResults    = 100
+	defaultResultHoldTimeout = 10 * time.Minute
+)
+
+// WithOrderedResults publishes Outputs in the order Process was called,
+// even when jobs finish out of order, e.g. to diff transcripts between
+// runs. A job's Outputs are held until every job submitted before it has
+// returned. See WithOrderedResultsLimits for what bounds the wait.
+func WithOrderedResults(enabled bool) Option {
+	return func(s *Scriber) {
+		s.orderedResults = enabled
+	}
+}
+
+// WithOrderedResultsLimits bounds how many completed jobs hold their Outputs
+// at once, and for how long, when results are ordered. Outputs beyond either
+// limit are published out of order with a warning. Non-positive values
+// keep the defaults of 100 jobs and 10 minutes.
+func WithOrderedResultsLimits(maxHeld int, timeout time.Duration) Option {
+	return func(s *Scriber) {
+		if maxHeld > 0 {
+			s.maxHeldResults = maxHeld
+		}
+		if timeout > 0 {
+			s.resultHoldTimeout = timeout
+		}
+	}
+}
+
+// resultSequencer orders publishing by submission.
+// Jobs are numbered as they are admitted, and a job may publish
+// once every job numbered before it has finished.
+type resultSequencer struct {
+	maxHeld int
+	timeout time.Duration
+
+	mu        sync.Mutex
+	submitted uint64
+	next      uint64 // The oldest unfinished job.
+	finished  map[uint64]bool
+	held      int
+
+	// advanced is closed, and replaced, whenever next moves.
+	advanced chan struct{}
+}
+
+func newResultSequencer(maxHeld int, timeout time.Duration) *resultSequencer {
+	return &resultSequencer{
+		maxHeld:  maxHeld,
+		timeout:  timeout,
+		finished: make(map[uint64]bool),
+		advanced: make(chan struct{}),
+	}
+}
+
+// submit numbers a new job.
+func (q *resultSequencer) submit() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	seq := q.submitted
+	q.submitted++
+	return seq
+}
+
+// finish records that job seq won't publish anymore.
+func (q *resultSequencer) finish(seq uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.finished[seq] = true
+	if seq != q.next {
+		return
+	}
+
+	for q.finished[q.next] {
+		delete(q.finished, q.next)
+		q.next++
+	}
+	close(q.advanced)
+	q.advanced = make(chan struct{})
+}
+
+// wait blocks until job seq may publish. It returns early, logging
+// a warning, when holding the job would exceed the sequencer limits.
+func (q *resultSequencer) wait(ctx context.Context, logger *slog.Logger, seq uint64) error {
+	timer := time.NewTimer(q.timeout)
+	defer timer.Stop()
+
+	q.mu.Lock()
+	if seq == q.next {
+		q.mu.Unlock()
+		return nil
+	}
+	if q.held >= q.maxHeld {
+		q.mu.Unlock()
+		logger.Warn("Publishing out of order, too many held results", slog.Int("max_held", q.maxHeld))
+		return nil
+	}
+	q.held++
+	q.mu.Unlock()
+
+	defer func() {
+		q.mu.Lock()
+		q.held--
+		q.mu.Unlock()
+	}()
+
+	for {
+		q.mu.Lock()
+		if seq == q.next {
+			q.mu.Unlock()
+			return nil
+		}
+		advanced := q.advanced
+		q.mu.Unlock()
+
+		select {
+		case <-advanced:
+		case <-timer.C:
+			logger.Warn("Publishing out of order, earlier jobs are still running", slog.Duration("timeout", q.timeout))
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_OrderedResults(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		givenOpts     []Option
+		givenFail     string   // Job whose transcription fails.
+		givenRelease  []string // Order in which transcriptions complete.
+		expectedNames []string
+	}{
+		{
+			name:          "reversed completion",
+			givenOpts:     []Option{WithOrderedResults(true)},
+			givenRelease:  []string{"c", "b", "a"},
+			expectedNames: []string{"a.txt", "b.txt", "c.txt"},
+		},
+		{
+			name:          "failed job",
+			givenOpts:     []Option{WithOrderedResults(true)},
+			givenFail:     "a",
+			givenRelease:  []string{"c", "b", "a"},
+			expectedNames: []string{"b.txt", "c.txt"},
+		},
+		{
+			name:          "unordered",
+			givenRelease:  []string{"c", "b", "a"},
+			expectedNames: []string{"c.txt", "b.txt", "a.txt"},
+		},
+		{
+			name:          "max held exceeded",
+			givenOpts:     []Option{WithOrderedResults(true), WithOrderedResultsLimits(1, time.Minute)},
+			givenRelease:  []string{"b", "c", "a"},
+			expectedNames: []string{"c.txt", "a.txt", "b.txt"},
+		},
+		{
+			name:          "hold timeout",
+			givenOpts:     []Option{WithOrderedResults(true), WithOrderedResultsLimits(0, time.Millisecond)},
+			givenRelease:  []string{"c", "b", "a"},
+			expectedNames: []string{"c.txt", "b.txt", "a.txt"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				started  = make(chan string)
+				releases = map[string]chan struct{}{}
+			)
+			for _, name := range tc.givenRelease {
+				releases[name] = make(chan struct{})
+			}
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					data, err := io.ReadAll(in.Data)
+					require.NoError(t, err)
+
+					name := string(data)
+					started <- name
+					<-releases[name]
+
+					if name == tc.givenFail {
+						return nil, assert.AnError
+					}
+					return []byte(name), nil
+				},
+			}
+
+			s := New(noopLogger(), mockClient, append(tc.givenOpts, WithConverter(copyConverter))...)
+
+			var wg sync.WaitGroup
+			for _, name := range []string{"a", "b", "c"} {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_ = s.Process(context.TODO(), Input{
+						Name:       name + ".mp4",
+						OutputType: OutputTypeTranscript,
+						Language:   "en",
+						Data:       io.NopCloser(bytes.NewBufferString(name)),
+					})
+				}()
+
+				// Submit the next job only once this one is admitted.
+				require.Equal(t, name, <-started)
+			}
+
+			var names []string
+			for _, name := range tc.givenRelease {
+				close(releases[name])
+
+				// Give the job time to publish, or to be held.
+				time.Sleep(20 * time.Millisecond)
+			}
+
+			for range tc.expectedNames {
+				select {
+				case out := <-s.Collect():
+					out.Body.Close()
+					names = append(names, out.Name)
+				case <-time.After(5 * time.Second):
+					t.Fatalf("missing outputs, got %v", names)
+				}
+			}
+			wg.Wait()
+
+			assert.Equal(t, tc.expectedNames, names)
+		})
+	}
+}
+
+func TestResultSequencer(t *testing.T) {
+	t.Parallel()
+
+	q := newResultSequencer(defaultMaxHeldResults, time.Minute)
+
+	first, second := q.submit(), q.submit()
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	require.ErrorIs(t, q.wait(ctx, noopLogger(), second), context.Canceled)
+
+	require.NoError(t, q.wait(context.TODO(), noopLogger(), first))
+
+	q.finish(first)
+	require.NoError(t, q.wait(context.TODO(), noopLogger(), second))
+	assert.Zero(t, q.held)
+}
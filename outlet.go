@@ -0,0 +1,119 @@
+package scriber
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// outletState is the state of the channels returned by Collect,
+// Partials, and Errors, the outlets of a Scriber.
+type outletState int
+
+const (
+	// outletOpen admits jobs, which publish on the outlets.
+	outletOpen outletState = iota
+
+	// outletDraining admits no more jobs, but those
+	// in flight still publish on the outlets.
+	outletDraining
+
+	// outletClosed has closed the outlets. Anything
+	// sent on them is dropped and counted.
+	outletClosed
+)
+
+// outlets holds the state of the outlets of a Scriber. Senders register
+// while the outlets aren't closed, and closing waits for them before
+// closing the channels, so that nothing is ever sent on a closed channel,
+// whatever the interleaving. No lock is held while sending, so a sender
+// blocked on a full channel holds up neither draining nor closing: once
+// closing starts, it gives up.
+type outlets struct {
+	mu    sync.Mutex
+	state outletState
+
+	// senders counts the sends in progress, which must give
+	// up once closing, created on first use, is closed.
+	senders sync.WaitGroup
+	closing chan struct{}
+
+	// closeMu makes concurrent closes wait for the first one.
+	closeMu sync.Mutex
+
+	// dropped counts what was sent once the outlets were closed.
+	dropped atomic.Int64
+}
+
+// open runs admit if the outlets are open, holding them open
+// meanwhile. It reports whether they were.
+func (o *outlets) open(admit func()) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.state != outletOpen {
+		return false
+	}
+	admit()
+	return true
+}
+
+// drain stops admitting jobs. It reports whether the
+// outlets were open, i.e. whether this call drained them.
+func (o *outlets) drain() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.state != outletOpen {
+		return false
+	}
+	o.state = outletDraining
+	return true
+}
+
+// close makes the sends in progress give up, waits for them,
+// then runs closeChannels once.
+func (o *outlets) close(closeChannels func()) {
+	o.closeMu.Lock()
+	defer o.closeMu.Unlock()
+
+	o.mu.Lock()
+	if o.state == outletClosed {
+		o.mu.Unlock()
+		return
+	}
+	o.state = outletClosed
+	close(o.closingCh())
+	o.mu.Unlock()
+
+	// No sender registers once closed.
+	o.senders.Wait()
+	closeChannels()
+}
+
+// send runs send unless the outlets are closed, in which case it counts
+// a drop instead. It reports whether send ran. send must give up once
+// closing is closed, and count a drop if it does.
+func (o *outlets) send(send func(closing <-chan struct{})) bool {
+	o.mu.Lock()
+	if o.state == outletClosed {
+		o.mu.Unlock()
+		o.dropped.Add(1)
+		return false
+	}
+	o.senders.Add(1)
+	closing := o.closingCh()
+	o.mu.Unlock()
+	defer o.senders.Done()
+
+	send(closing)
+	return true
+}
+
+// closingCh returns the channel closed once closing starts.
+// o.mu must be held.
+func (o *outlets) closingCh() chan struct{} {
+	if o.closing == nil {
+		o.closing = make(chan struct{})
+	}
+	return o.closing
+}
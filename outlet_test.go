@@ -0,0 +1,243 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClose(t *testing.T) {
+	t.Parallel()
+
+	passthrough := copyConverter
+
+	t.Run("closes the channels", func(t *testing.T) {
+		t.Parallel()
+
+		s := New(noopLogger(), &mockWhisperClient{}, WithConverter(passthrough), WithPartialResults(1), WithErrorChannel(1))
+		require.NoError(t, s.Close())
+
+		out, ok := <-s.Collect()
+		assert.False(t, ok)
+		assert.Zero(t, out)
+
+		partial, ok := <-s.Partials()
+		assert.False(t, ok)
+		assert.Zero(t, partial)
+
+		err, ok := <-s.Errors()
+		assert.False(t, ok)
+		assert.NoError(t, err)
+	})
+
+	t.Run("twice", func(t *testing.T) {
+		t.Parallel()
+
+		s := New(noopLogger(), &mockWhisperClient{}, WithConverter(passthrough))
+		require.NoError(t, s.Close())
+
+		var closedErr ClosedError
+		require.ErrorAs(t, s.Close(), &closedErr)
+	})
+
+	t.Run("after shutdown", func(t *testing.T) {
+		t.Parallel()
+
+		s := New(noopLogger(), &mockWhisperClient{}, WithConverter(passthrough))
+		require.NoError(t, s.Shutdown(context.TODO()))
+
+		var closedErr ClosedError
+		require.ErrorAs(t, s.Close(), &closedErr)
+		require.NoError(t, s.Shutdown(context.TODO()))
+	})
+
+	t.Run("rejects new jobs", func(t *testing.T) {
+		t.Parallel()
+
+		s := New(noopLogger(), &mockWhisperClient{}, WithConverter(passthrough))
+		require.NoError(t, s.Close())
+
+		err := s.Process(context.TODO(), Input{
+			Name:       "talk.mp4",
+			OutputType: OutputTypeTranscript,
+			Language:   "en",
+			Data:       io.NopCloser(strings.NewReader("data")),
+		})
+
+		var shutdownErr ShutdownError
+		require.ErrorAs(t, err, &shutdownErr)
+	})
+}
+
+func TestSendAfterClose(t *testing.T) {
+	t.Parallel()
+
+	s := New(noopLogger(), &mockWhisperClient{}, WithPartialResults(1), WithErrorChannel(1), WithSalvage(true))
+	require.NoError(t, s.Close())
+
+	// Outputs fail their job, handing the output back when salvaging.
+	j := s.newJob(Input{Name: "talk.mp4", OutputType: OutputTypeTranscript}, nil)
+	err := s.publish(context.TODO(), j, j.output([]byte("hello")))
+
+	var closedErr ClosedError
+	require.ErrorAs(t, err, &closedErr)
+
+	var pe *ProcessError
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, StagePublish, pe.Stage)
+	require.NotNil(t, pe.Salvaged)
+	assert.Equal(t, "hello", string(pe.Salvaged.Text))
+
+	// Partial results and errors are dropped.
+	require.NoError(t, newPartialSequencer(s.partialsCh, &s.outlets).complete(context.TODO(), PartialOutput{}))
+	s.notify(assert.AnError)
+
+	stats := s.PublishStats()
+	assert.Equal(t, int64(3), stats.DroppedAfterClose)
+	assert.Zero(t, stats.BufferedBytes)
+}
+
+func TestShutdown_StuckConsumer(t *testing.T) {
+	t.Parallel()
+
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.ReadAll(in.Data)
+			return []byte("hello"), err
+		},
+	}, WithConverter(copyConverter))
+
+	// One more job than the Collect channel has room for, and nobody reads.
+	n := cap(s.resultsCh) + 1
+	errs := make(chan error, n)
+	for range n {
+		go func() {
+			errs <- s.Process(context.TODO(), Input{
+				Name:       "talk.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       io.NopCloser(strings.NewReader("data")),
+			})
+		}()
+	}
+	require.Eventually(t, func() bool {
+		return len(s.resultsCh) == cap(s.resultsCh)
+	}, 5*time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := s.Shutdown(ctx)
+	assert.Less(t, time.Since(start), 2*time.Second, "Shutdown must give up once ctx is done")
+
+	var abandoned *AbandonedJobsError
+	require.ErrorAs(t, err, &abandoned)
+	assert.Equal(t, 1, abandoned.Abandoned)
+
+	for range n {
+		<-errs
+	}
+	for out := range s.Collect() {
+		require.NoError(t, out.Body.Close())
+	}
+}
+
+func TestClose_Stress(t *testing.T) {
+	t.Parallel()
+
+	const (
+		producers = 8
+		consumers = 4
+		jobs      = 25
+	)
+
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			data, err := io.ReadAll(in.Data)
+			return data, err
+		},
+	},
+		WithConverter(copyConverter),
+		WithErrorChannel(8),
+	)
+
+	var (
+		received  atomic.Int64
+		succeeded atomic.Int64
+		consumed  sync.WaitGroup
+		produced  sync.WaitGroup
+	)
+
+	for range consumers {
+		consumed.Add(1)
+		go func() {
+			defer consumed.Done()
+			for out := range s.Collect() {
+				received.Add(1)
+				out.Body.Close()
+			}
+		}()
+	}
+
+	// Errors are reported from outside jobs too, racing the close.
+	consumed.Add(1)
+	go func() {
+		defer consumed.Done()
+		for range s.Errors() {
+		}
+	}()
+	notifying := make(chan struct{})
+	go func() {
+		defer close(notifying)
+		for range 1000 {
+			s.notify(assert.AnError)
+		}
+	}()
+
+	for p := range producers {
+		produced.Add(1)
+		go func() {
+			defer produced.Done()
+			for i := range jobs {
+				err := s.Process(context.TODO(), Input{
+					Name:       "talk.mp4",
+					OutputType: OutputTypeTranscript,
+					Language:   "en",
+					Data:       io.NopCloser(bytes.NewReader([]byte{byte(p), byte(i)})),
+				})
+				if err == nil {
+					succeeded.Add(1)
+					continue
+				}
+
+				var shutdownErr ShutdownError
+				if !errors.As(err, &shutdownErr) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+		}()
+	}
+
+	time.Sleep(time.Millisecond)
+	require.NoError(t, s.Close())
+
+	produced.Wait()
+	<-notifying
+	consumed.Wait()
+
+	// Every job admitted before the close published its output,
+	// and every output published was received.
+	assert.Equal(t, succeeded.Load(), received.Load())
+	assert.Zero(t, s.PublishStats().BufferedBytes)
+}
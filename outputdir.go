@@ -0,0 +1,150 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// WithOutputDir makes the Scriber write each Output to dir itself, once
+// post-processed and before publishing it, for callers that just want
+// files on disk. Output names are sanitized (see WithNameSanitized), and
+// names taken in dir are avoided as with WithExistsFunc, unless another
+// ExistsFunc is set. Files are written atomically, through a temporary
+// file renamed into place, so readers never see partial outputs.
+//
+// The published Output carries the file path in Path, and its Body reads
+// the file. Jobs whose output can't be written fail in StagePublish with
+// an error wrapping an OutputWriteError.
+func WithOutputDir(dir string) Option {
+	return func(s *Scriber) {
+		s.outputDir = dir
+	}
+}
+
+// WithWrittenTextOmitted drops Output.Text and Output.RawText from the
+// Outputs written by WithOutputDir, so that they don't hold their
+// transcription in memory until consumed. Read it from Body, or from
+// the file at Output.Path.
+func WithWrittenTextOmitted(enabled bool) Option {
+	return func(s *Scriber) {
+		s.omitWrittenText = enabled
+	}
+}
+
+// dirExists returns an ExistsFunc checking for the names taken in dir.
+func dirExists(dir string) ExistsFunc {
+	return func(_ context.Context, name string) (bool, error) {
+		_, err := os.Lstat(filepath.Join(dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+}
+
+// writeOutput writes out to the output directory, if any, and has its
+// Body read the file written. out.Body is consumed either way.
+func (s *Scriber) writeOutput(j *job, out *Output) error {
+	if s.outputDir == "" {
+		return nil
+	}
+
+	if out.RawText != nil {
+		rawPath := filepath.Join(s.outputDir, out.RawName)
+		if err := writeFileAtomic(rawPath, bytes.NewReader(out.RawText)); err != nil {
+			return fmt.Errorf("%w %q: %w", errOutputWrite, rawPath, err)
+		}
+	}
+
+	path := filepath.Join(s.outputDir, out.Name)
+	if err := writeFileAtomic(path, out.Body); err != nil {
+		return fmt.Errorf("%w %q: %w", errOutputWrite, path, err)
+	}
+
+	out.Path = path
+	out.Body = &fileBody{path: path, orig: out.Body}
+	if s.omitWrittenText {
+		out.Text, out.RawText = nil, nil
+	}
+
+	j.logger.Debug("Output written", slog.String("path", path))
+	return nil
+}
+
+// writeFileAtomic writes r to path through a temporary file in
+// the same directory, renamed into place once complete.
+func writeFileAtomic(path string, r io.Reader) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// fileBody reads the file an output was written to, opened on the first
+// read so that unconsumed outputs hold no file descriptor. Closing it
+// closes the output's original body, releasing what it holds.
+type fileBody struct {
+	path string
+	orig io.Closer
+
+	mu     sync.Mutex
+	f      *os.File
+	closed bool
+}
+
+func (b *fileBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return 0, os.ErrClosed
+	}
+	if b.f == nil {
+		f, err := os.Open(b.path)
+		if err != nil {
+			return 0, err
+		}
+		b.f = f
+	}
+	return b.f.Read(p)
+}
+
+func (b *fileBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+
+	var err error
+	if b.f != nil {
+		err = b.f.Close()
+	}
+	return errors.Join(err, b.orig.Close())
+}
@@ -0,0 +1,148 @@
+package scriber
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithOutputDir(t *testing.T) {
+	t.Parallel()
+
+	newScriber := func(opts ...Option) *Scriber {
+		return New(noopLogger(), &mockWhisperClient{
+			transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+				_, err := io.Copy(io.Discard, in.Data)
+				return []byte("hello"), err
+			},
+		}, append([]Option{
+			WithConverter(copyConverter),
+		}, opts...)...)
+	}
+
+	newInput := func(name string) Input {
+		return Input{
+			Name:       name,
+			OutputType: OutputTypeTranscript,
+			Language:   "en",
+			Data:       io.NopCloser(strings.NewReader("media")),
+		}
+	}
+
+	testCases := []struct {
+		name         string
+		givenName    string
+		givenExists  []string
+		givenOpts    []Option
+		expectedName string
+		expectedText string
+	}{
+		{
+			name:         "written",
+			givenName:    "talk.mp4",
+			expectedName: "talk.txt",
+			expectedText: "hello",
+		},
+		{
+			name:         "text omitted",
+			givenName:    "talk.mp4",
+			givenOpts:    []Option{WithWrittenTextOmitted(true)},
+			expectedName: "talk.txt",
+		},
+		{
+			name:         "name taken",
+			givenName:    "talk.mp4",
+			givenExists:  []string{"talk.txt", "talk-1.txt"},
+			expectedName: "talk-2.txt",
+			expectedText: "hello",
+		},
+		{
+			name:         "sanitized",
+			givenName:    "../tenants/acme/board: q3.mp4",
+			expectedName: "board_ q3.txt",
+			expectedText: "hello",
+		},
+		{
+			name:         "spooled",
+			givenName:    "talk.mp4",
+			givenOpts:    []Option{WithSpoolThreshold(1, "")},
+			expectedName: "talk.txt",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			for _, name := range tc.givenExists {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("taken"), 0o644))
+			}
+
+			s := newScriber(append([]Option{WithOutputDir(dir)}, tc.givenOpts...)...)
+			require.NoError(t, s.Process(context.TODO(), newInput(tc.givenName)))
+
+			out := <-s.Collect()
+			assert.Equal(t, tc.expectedName, out.Name)
+			assert.Equal(t, filepath.Join(dir, tc.expectedName), out.Path)
+			assert.Equal(t, tc.expectedText, string(out.Text))
+
+			written, err := os.ReadFile(out.Path)
+			require.NoError(t, err)
+			assert.Equal(t, "hello", string(written))
+
+			body, err := io.ReadAll(out.Body)
+			require.NoError(t, err)
+			assert.Equal(t, "hello", string(body))
+			require.NoError(t, out.Body.Close())
+
+			// Nothing but the outputs is left in the directory.
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Len(t, entries, len(tc.givenExists)+1)
+		})
+	}
+
+	t.Run("missing directory", func(t *testing.T) {
+		t.Parallel()
+
+		s := newScriber(WithOutputDir(filepath.Join(t.TempDir(), "missing")))
+		err := s.Process(context.TODO(), newInput("talk.mp4"))
+
+		var writeErr OutputWriteError
+		require.ErrorAs(t, err, &writeErr)
+
+		var pe *ProcessError
+		require.ErrorAs(t, err, &pe)
+		assert.Equal(t, StagePublish, pe.Stage)
+	})
+
+	t.Run("read-only directory", func(t *testing.T) {
+		t.Parallel()
+
+		if os.Geteuid() == 0 {
+			t.Skip("permissions don't apply to root")
+		}
+
+		dir := t.TempDir()
+		require.NoError(t, os.Chmod(dir, 0o555))
+		t.Cleanup(func() { os.Chmod(dir, 0o755) })
+
+		s := newScriber(WithOutputDir(dir))
+		err := s.Process(context.TODO(), newInput("talk.mp4"))
+
+		var writeErr OutputWriteError
+		require.ErrorAs(t, err, &writeErr)
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+}
@@ -0,0 +1,98 @@
+package scriber
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// UnknownOutputTypePolicy sets how inputs are handled whose output type
+// is neither supported nor an alias. See WithUnknownOutputTypePolicy.
+type UnknownOutputTypePolicy int
+
+const (
+	// RejectUnknownOutputTypes fails the inputs of unknown output types
+	// with an OutputTypeError. It is the default.
+	RejectUnknownOutputTypes UnknownOutputTypePolicy = iota
+
+	// FallbackToTranscript processes the inputs of unknown output types as
+	// OutputTypeTranscript, raising a WarningOutputTypeFallback.
+	FallbackToTranscript
+)
+
+// defaultOutputTypeAliases are the aliases every Scriber accepts.
+var defaultOutputTypeAliases = map[OutputType]OutputType{
+	"srt":      OutputTypeSubtitles,
+	"subtitle": OutputTypeSubtitles,
+	"txt":      OutputTypeTranscript,
+	"text":     OutputTypeTranscript,
+}
+
+// WithOutputTypeAliases accepts the output types in aliases as the ones
+// they map to, e.g. "vtt" as OutputTypeSubtitles for queues fed by newer
+// producers. Aliases add to the built-in ones, srt and subtitle for
+// OutputTypeSubtitles, and txt and text for OutputTypeTranscript, and
+// override them. Output types and aliases are matched regardless of case.
+// Aliases are resolved as the input is validated, before anything else
+// sees its output type, such as naming and the backend request. Aliases
+// of unsupported output types are ignored.
+func WithOutputTypeAliases(aliases map[OutputType]OutputType) Option {
+	return func(s *Scriber) {
+		if s.outputTypeAliases == nil {
+			s.outputTypeAliases = make(map[OutputType]OutputType, len(aliases))
+		}
+		for alias, t := range aliases {
+			s.outputTypeAliases[OutputType(strings.ToLower(string(alias)))] = t
+		}
+	}
+}
+
+// WithUnknownOutputTypePolicy sets how inputs of an output type that is
+// neither supported nor an alias are handled, RejectUnknownOutputTypes by
+// default. Inputs without an output type are rejected either way.
+func WithUnknownOutputTypePolicy(p UnknownOutputTypePolicy) Option {
+	return func(s *Scriber) {
+		s.unknownOutputTypes = p
+	}
+}
+
+// resolveOutputType returns the supported output type t stands for,
+// and whether it is the fallback for an unknown one. Unknown output
+// types are returned unchanged unless they fall back.
+func (s *Scriber) resolveOutputType(t OutputType) (OutputType, bool) {
+	if _, ok := supportedOutputTypes[t]; ok {
+		return t, false
+	}
+
+	key := OutputType(strings.ToLower(strings.TrimSpace(string(t))))
+	resolved, ok := s.outputTypeAliases[key]
+	if !ok {
+		resolved, ok = defaultOutputTypeAliases[key]
+	}
+	if !ok {
+		resolved = key
+	}
+	if _, ok := supportedOutputTypes[resolved]; ok {
+		return resolved, false
+	}
+
+	if t != "" && s.unknownOutputTypes == FallbackToTranscript {
+		return OutputTypeTranscript, true
+	}
+	return t, false
+}
+
+// warnOutputTypeFallback raises a warning if the job's output type is
+// the fallback for the unknown one it was submitted with.
+func (s *Scriber) warnOutputTypeFallback(j *job) error {
+	if j.in.requestedOutputType == "" {
+		return nil
+	}
+
+	msg := fmt.Sprintf("output type %q is unknown, processed as %q", j.in.requestedOutputType, j.in.OutputType)
+	if err := j.warn(WarningOutputTypeFallback, msg); err != nil {
+		return err
+	}
+	j.logger.Warn("Unknown output type", slog.String("file", j.in.Name), slog.String("output_type", string(j.in.requestedOutputType)))
+	return nil
+}
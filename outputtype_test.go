@@ -0,0 +1,177 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScriber_ResolveOutputType(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		givenType        OutputType
+		givenOpts        []Option
+		expectedType     OutputType
+		expectedFellBack bool
+	}{
+		{
+			name:         "supported",
+			givenType:    OutputTypeSBV,
+			expectedType: OutputTypeSBV,
+		},
+		{
+			name:         "supported in upper case",
+			givenType:    "Subtitles",
+			expectedType: OutputTypeSubtitles,
+		},
+		{
+			name:         "built-in alias",
+			givenType:    " SRT ",
+			expectedType: OutputTypeSubtitles,
+		},
+		{
+			name:         "custom alias",
+			givenType:    "vtt",
+			givenOpts:    []Option{WithOutputTypeAliases(map[OutputType]OutputType{"VTT": OutputTypeSubtitles})},
+			expectedType: OutputTypeSubtitles,
+		},
+		{
+			name:         "overridden built-in alias",
+			givenType:    "text",
+			givenOpts:    []Option{WithOutputTypeAliases(map[OutputType]OutputType{"text": OutputTypeSBV})},
+			expectedType: OutputTypeSBV,
+		},
+		{
+			name:         "alias of unsupported type",
+			givenType:    "vtt",
+			givenOpts:    []Option{WithOutputTypeAliases(map[OutputType]OutputType{"vtt": "webvtt"})},
+			expectedType: "vtt",
+		},
+		{
+			name:         "unknown rejected",
+			givenType:    "vtt",
+			expectedType: "vtt",
+		},
+		{
+			name:             "unknown fallback",
+			givenType:        "vtt",
+			givenOpts:        []Option{WithUnknownOutputTypePolicy(FallbackToTranscript)},
+			expectedType:     OutputTypeTranscript,
+			expectedFellBack: true,
+		},
+		{
+			name:      "empty fallback",
+			givenOpts: []Option{WithUnknownOutputTypePolicy(FallbackToTranscript)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := New(noopLogger(), &mockWhisperClient{}, tc.givenOpts...)
+
+			got, fellBack := s.resolveOutputType(tc.givenType)
+			assert.Equal(t, tc.expectedType, got)
+			assert.Equal(t, tc.expectedFellBack, fellBack)
+		})
+	}
+}
+
+func TestProcess_OutputTypeAliases(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		givenType        OutputType
+		givenOpts        []Option
+		expectedName     string
+		expectedFormat   string
+		expectedWarnings []Warning
+		expectedErr      error
+	}{
+		{
+			name:           "alias",
+			givenType:      "vtt",
+			givenOpts:      []Option{WithOutputTypeAliases(map[OutputType]OutputType{"vtt": OutputTypeSubtitles})},
+			expectedName:   "talk.srt",
+			expectedFormat: "srt",
+		},
+		{
+			name:        "unknown rejected",
+			givenType:   "vtt",
+			expectedErr: errorOutputType,
+		},
+		{
+			name:           "unknown fallback",
+			givenType:      "vtt",
+			givenOpts:      []Option{WithUnknownOutputTypePolicy(FallbackToTranscript)},
+			expectedName:   "talk.txt",
+			expectedFormat: "text",
+			expectedWarnings: []Warning{{
+				Code:    WarningOutputTypeFallback,
+				Message: `output type "vtt" is unknown, processed as "transcript"`,
+			}},
+		},
+		{
+			name:      "unknown fallback escalated",
+			givenType: "vtt",
+			givenOpts: []Option{
+				WithUnknownOutputTypePolicy(FallbackToTranscript),
+				WithWarningsAsErrors(WarningOutputTypeFallback),
+			},
+			expectedErr: &WarningError{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var format string
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					format = in.Format
+					_, err := io.Copy(io.Discard, in.Data)
+					return []byte("1\n00:00:00,000 --> 00:00:01,000\nhello\n"), err
+				},
+			}, append([]Option{
+				WithConverter(copyConverter),
+			}, tc.givenOpts...)...)
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "talk.mp4",
+				OutputType: tc.givenType,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+			})
+
+			if tc.expectedErr != nil {
+				var pe *ProcessError
+				require.ErrorAs(t, err, &pe)
+				assert.Equal(t, StageValidation, pe.Stage)
+				if we, ok := tc.expectedErr.(*WarningError); ok {
+					assert.ErrorAs(t, err, &we)
+				} else {
+					assert.ErrorIs(t, err, tc.expectedErr)
+				}
+				assert.Empty(t, format, "the backend should not be called")
+				return
+			}
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+			assert.Equal(t, tc.expectedName, out.Name)
+			assert.Equal(t, tc.expectedFormat, format)
+			assert.Equal(t, tc.expectedWarnings, out.Warnings)
+		})
+	}
+}
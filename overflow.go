@@ -0,0 +1,322 @@
+package scriber
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// overflowSuffix ends the names of the entries of the overflow directory.
+const overflowSuffix = ".overflow.json"
+
+// WithResultOverflow spills the Outputs the Collect channel has no room
+// for to dir, instead of making their jobs wait for the consumer. Spilled
+// Outputs are fed back to the Collect channel, in the order they were
+// spilled, as the consumer frees room; once one is spilled, later Outputs
+// are spilled behind it, so that order is kept as far as jobs complete in
+// order. Entries left over when s is shut down, or when the process exits,
+// are recovered by the next Scriber created with the same dir. An entry
+// that can't be read back is skipped with a warning and renamed with a
+// .corrupt suffix. An Output is removed from dir once received, so an
+// Output received right before a crash may be received again.
+//
+// Shutdown waits for the spilled Outputs to be received until its context
+// is done. Spilled Outputs are held in memory while written and read back;
+// those fed back don't count toward PublishConfig.MaxBufferedBytes.
+func WithResultOverflow(dir string) Option {
+	return func(s *Scriber) {
+		s.overflow = &resultOverflow{dir: dir}
+	}
+}
+
+// overflowEntry is an Output as spilled to the overflow directory.
+type overflowEntry struct {
+	Output Output
+
+	// Body is the payload of the Output, when Output.Text
+	// doesn't hold it because it was spooled.
+	Body []byte `json:",omitempty"`
+}
+
+// resultOverflow spills the Outputs the Collect channel has no room for
+// to a directory, and feeds them back to it in order as room frees.
+type resultOverflow struct {
+	dir string
+
+	mu sync.Mutex
+	// queue holds the paths of the entries not received yet, oldest first.
+	queue []string
+	next  uint64
+	// pumping is set while a goroutine feeds the entries back,
+	// which closes done when it returns.
+	pumping bool
+	done    chan struct{}
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// recoverOverflow queues the entries left in the overflow directory by
+// an earlier Scriber, and starts feeding them back.
+func (s *Scriber) recoverOverflow() {
+	o := s.overflow
+	o.stop = make(chan struct{})
+
+	if err := os.MkdirAll(o.dir, 0o755); err != nil {
+		s.logger.Warn("Could not create overflow directory", slog.String("dir", o.dir), slog.Any("error", err))
+		return
+	}
+
+	entries, err := os.ReadDir(o.dir)
+	if err != nil {
+		s.logger.Warn("Could not read overflow directory", slog.String("dir", o.dir), slog.Any("error", err))
+		return
+	}
+
+	// Entry names are zero-padded sequence numbers, so the directory
+	// order, sorted by name, is the order they were spilled in.
+	for _, e := range entries {
+		seq, ok := overflowSeq(e.Name())
+		if !ok || e.IsDir() {
+			continue
+		}
+		o.queue = append(o.queue, filepath.Join(o.dir, e.Name()))
+		o.next = max(o.next, seq+1)
+	}
+	sort.Strings(o.queue)
+
+	if len(o.queue) > 0 {
+		s.logger.Info("Recovered overflowed outputs", slog.String("dir", o.dir), slog.Int("count", len(o.queue)))
+		o.mu.Lock()
+		s.startPump()
+		o.mu.Unlock()
+	}
+}
+
+// overflowSeq returns the sequence number of the overflow entry name.
+func overflowSeq(name string) (uint64, bool) {
+	seq, ok := strings.CutSuffix(name, overflowSuffix)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return n, err == nil
+}
+
+// overflowPublish sends out on the Collect channel if it has room and
+// nothing is spilled ahead of out, and spills it otherwise. It reports
+// whether out was handled; if not, because it couldn't be spilled, it
+// returns out with a body reading the payload again, to be published
+// like without an overflow directory.
+func (s *Scriber) overflowPublish(j *job, out Output) (Output, bool, error) {
+	o := s.overflow
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.queue) == 0 {
+		sent := false
+		open := s.outlets.send(func(<-chan struct{}) {
+			select {
+			case s.resultsCh <- out:
+				sent = true
+			default:
+			}
+		})
+		if !open {
+			return out, true, s.abandonOutput(j, out, errClosed)
+		}
+		if sent {
+			return out, true, nil
+		}
+	}
+
+	path, out, err := o.spill(out)
+	if err != nil {
+		j.logger.Warn("Could not overflow output, waiting for the consumer", slog.Any("error", err))
+		return out, false, nil
+	}
+	o.queue = append(o.queue, path)
+	s.publishCounters.overflowed.Add(1)
+	j.logger.Debug("Collect channel full, output overflowed", slog.String("path", path))
+
+	s.startPump()
+	return out, true, nil
+}
+
+// spill writes out to the next entry of the overflow directory and
+// returns its path. The body of out is consumed and closed; if spilling
+// fails, out is returned with a body reading the payload again.
+func (o *resultOverflow) spill(out Output) (string, Output, error) {
+	payload, err := io.ReadAll(out.Body)
+	out.Body.Close()
+	out.Body = io.NopCloser(bytes.NewReader(payload))
+	if err != nil {
+		return "", out, fmt.Errorf("could not read output body: %w", err)
+	}
+
+	entry := overflowEntry{Output: out}
+	entry.Output.Body = nil
+	if out.Text == nil {
+		entry.Body = payload
+	}
+
+	data, err := json.Marshal(entry)
+	if err == nil {
+		path := filepath.Join(o.dir, fmt.Sprintf("%020d%s", o.next, overflowSuffix))
+		if err = writeFileAtomic(path, bytes.NewReader(data)); err == nil {
+			o.next++
+			return path, out, nil
+		}
+	}
+	return "", out, fmt.Errorf("could not write overflow entry: %w", err)
+}
+
+// startPump starts feeding the spilled outputs back, unless already
+// feeding them. o.mu must be held.
+func (s *Scriber) startPump() {
+	o := s.overflow
+	if o.pumping {
+		return
+	}
+	o.pumping = true
+	o.done = make(chan struct{})
+	go s.pump(o.done)
+}
+
+// pump feeds the spilled outputs back to the Collect channel, oldest
+// first, until none is left or the overflow is stopped.
+func (s *Scriber) pump(done chan struct{}) {
+	o := s.overflow
+	defer close(done)
+
+	for {
+		o.mu.Lock()
+		if len(o.queue) == 0 || o.stopped() {
+			o.pumping = false
+			o.mu.Unlock()
+			return
+		}
+		path := o.queue[0]
+		o.mu.Unlock()
+
+		out, err := s.readOverflowEntry(path)
+		if err != nil {
+			s.logger.Warn("Skipping corrupt overflow entry", slog.String("path", path), slog.Any("error", err))
+			if err := os.Rename(path, path+".corrupt"); err != nil && !os.IsNotExist(err) {
+				s.logger.Warn("Could not set aside corrupt overflow entry", slog.String("path", path), slog.Any("error", err))
+			}
+			o.pop()
+			continue
+		}
+
+		// Unlike jobs, the pump sends without holding the outlets open:
+		// Shutdown stops it before closing them, and must not wait for
+		// the consumer to drain them first.
+		sent := false
+		select {
+		case s.resultsCh <- out:
+			sent = true
+		case <-o.stop:
+		}
+		if !sent {
+			// Stopped: the entry stays on disk for the next Scriber.
+			out.Body.Close()
+			o.mu.Lock()
+			o.pumping = false
+			o.mu.Unlock()
+			return
+		}
+
+		o.pop()
+		if err := os.Remove(path); err != nil {
+			s.logger.Warn("Could not remove overflow entry", slog.String("path", path), slog.Any("error", err))
+		}
+	}
+}
+
+// readOverflowEntry reads the output spilled to path back.
+func (s *Scriber) readOverflowEntry(path string) (Output, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Output{}, err
+	}
+
+	var entry overflowEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return Output{}, err
+	}
+
+	out := entry.Output
+	if out.Text != nil {
+		out.Body = io.NopCloser(bytes.NewReader(out.Text))
+		return out, nil
+	}
+
+	text, body, err := newOutputBody(entry.Body, s.spoolThreshold, s.spoolDir)
+	if err != nil {
+		return Output{}, err
+	}
+	out.Text, out.Body = text, body
+	return out, nil
+}
+
+func (o *resultOverflow) pop() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.queue = o.queue[1:]
+}
+
+func (o *resultOverflow) stopped() bool {
+	select {
+	case <-o.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// pending returns the number of spilled outputs not received yet.
+func (o *resultOverflow) pending() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.queue)
+}
+
+// drain waits until the spilled outputs are received or done is closed,
+// then stops feeding them back. It returns the number left on disk.
+func (o *resultOverflow) drain(done <-chan struct{}) int {
+wait:
+	for {
+		o.mu.Lock()
+		pumping, pumped := o.pumping, o.done
+		o.mu.Unlock()
+
+		if !pumping {
+			break
+		}
+		select {
+		case <-pumped:
+		case <-done:
+			break wait
+		}
+	}
+
+	o.stopOnce.Do(func() { close(o.stop) })
+
+	o.mu.Lock()
+	pumped := o.done
+	o.mu.Unlock()
+	if pumped != nil {
+		<-pumped
+	}
+	return o.pending()
+}
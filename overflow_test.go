@@ -0,0 +1,139 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOverflowScriber(dir string) *Scriber {
+	return New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.Copy(io.Discard, in.Data)
+			return []byte("hello world"), err
+		},
+	}, WithConverter(copyConverter), WithResultOverflow(dir))
+}
+
+func processOverflowJobs(t *testing.T, s *Scriber, n int) []string {
+	t.Helper()
+
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("talk-%02d.txt", i)
+		require.NoError(t, s.Process(context.TODO(), Input{
+			Name:       fmt.Sprintf("talk-%02d.mp4", i),
+			OutputType: OutputTypeTranscript,
+			Language:   "en",
+			Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+		}))
+	}
+	return names
+}
+
+func collectOutputs(t *testing.T, s *Scriber, n int) []string {
+	t.Helper()
+
+	var names []string
+	for range n {
+		out := <-s.Collect()
+		body, err := io.ReadAll(out.Body)
+		require.NoError(t, err)
+		require.NoError(t, out.Body.Close())
+
+		assert.Equal(t, "hello world", string(body))
+		assert.Equal(t, "hello world", string(out.Text))
+		names = append(names, out.Name)
+	}
+	return names
+}
+
+func overflowEntries(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := filepath.Glob(filepath.Join(dir, "*"+overflowSuffix))
+	require.NoError(t, err)
+	return entries
+}
+
+func TestProcess_ResultOverflow(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	s := newOverflowScriber(dir)
+
+	// Nothing consumes the outputs: jobs beyond the channel capacity
+	// would block without the overflow directory.
+	expected := processOverflowJobs(t, s, 25)
+
+	stats := s.PublishStats()
+	assert.Equal(t, int64(25-cap(s.resultsCh)), stats.Overflowed)
+	assert.Equal(t, 25-cap(s.resultsCh), stats.OverflowPending)
+	assert.Len(t, overflowEntries(t, dir), 25-cap(s.resultsCh))
+
+	assert.Equal(t, expected, collectOutputs(t, s, 25))
+
+	require.NoError(t, s.Shutdown(context.TODO()))
+	assert.Zero(t, s.PublishStats().OverflowPending)
+	assert.Empty(t, overflowEntries(t, dir))
+
+	_, ok := <-s.Collect()
+	assert.False(t, ok)
+}
+
+func TestProcess_ResultOverflowRecovery(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	s := newOverflowScriber(dir)
+	expected := processOverflowJobs(t, s, 15)
+
+	// Shut down without consuming: the overflowed outputs stay on disk.
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	require.NoError(t, s.Shutdown(ctx))
+	require.Len(t, overflowEntries(t, dir), 15-cap(s.resultsCh))
+
+	var dropped []string
+	for out := range s.Collect() {
+		dropped = append(dropped, out.Name)
+		out.Body.Close()
+	}
+	require.Equal(t, expected[:cap(s.resultsCh)], dropped)
+
+	// A Scriber pointed at the same directory feeds them back,
+	// ahead of the outputs of its own jobs.
+	restarted := newOverflowScriber(dir)
+	more := processOverflowJobs(t, restarted, 2)
+
+	assert.Equal(t, append(expected[cap(s.resultsCh):], more...), collectOutputs(t, restarted, 15-cap(s.resultsCh)+2))
+
+	require.NoError(t, restarted.Shutdown(context.TODO()))
+	assert.Empty(t, overflowEntries(t, dir))
+}
+
+func TestProcess_ResultOverflowCorruptEntry(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	corrupt := filepath.Join(dir, fmt.Sprintf("%020d%s", 0, overflowSuffix))
+	require.NoError(t, os.WriteFile(corrupt, []byte("{not json"), 0o644))
+
+	s := newOverflowScriber(dir)
+	expected := processOverflowJobs(t, s, 3)
+
+	assert.Equal(t, expected, collectOutputs(t, s, 3))
+	require.NoError(t, s.Shutdown(context.TODO()))
+
+	assert.NoFileExists(t, corrupt)
+	assert.FileExists(t, corrupt+".corrupt")
+	assert.Empty(t, overflowEntries(t, dir))
+}
@@ -0,0 +1,130 @@
+package scriber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/alesr/whisperclient"
+)
+
+// PanicError reports a panic recovered while processing a job, in a
+// user-supplied function such as the converter or the transcription
+// client, or in a goroutine started by the job. The Scriber remains usable.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// newPanicError returns a *PanicError for the recovered value v.
+// It must be called from the deferred function that recovered v,
+// for the stack to be the panicking goroutine's.
+func newPanicError(v any) *PanicError {
+	return &PanicError{Value: v, Stack: debug.Stack()}
+}
+
+// recoverPanic stores a recovered panic in *err as a *PanicError.
+// It must be deferred directly: defer recoverPanic(&err).
+func recoverPanic(err *error) {
+	if v := recover(); v != nil {
+		*err = newPanicError(v)
+	}
+}
+
+// convert runs the converter once a conversion slot is available,
+// recovering from its panics. The default converter runs ffmpeg with
+// args, the job's, or converts to WAV if args is nil.
+func (s *Scriber) convert(ctx context.Context, args []string, r io.Reader, w io.Writer) (err error) {
+	release, err := s.conversions.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	defer recoverPanic(&err)
+	if s.convertToWavFunc != nil {
+		return s.convertToWavFunc(ctx, r, w)
+	}
+	if args == nil {
+		args = s.ffmpegArgs
+	}
+	return s.runFFmpeg(ctx, args, r, w)
+}
+
+// callTranscriber sends req to the transcription client, recovering from its panics.
+func (s *Scriber) callTranscriber(ctx context.Context, req whisperclient.TranscribeAudioInput) (text []byte, err error) {
+	defer recoverPanic(&err)
+	return s.whisperClient.TranscribeAudio(ctx, req)
+}
+
+// callTranslator translates text with the translator, recovering from its panics.
+func (s *Scriber) callTranslator(ctx context.Context, text, srcLang, dstLang string) (translated string, err error) {
+	defer recoverPanic(&err)
+	return s.translator.Translate(ctx, text, srcLang, dstLang)
+}
+
+// callLongJobHandler hands in off to the long job handler, recovering from its panics.
+func (s *Scriber) callLongJobHandler(ctx context.Context, handler LongJobHandler, in Input) (err error) {
+	defer recoverPanic(&err)
+	return handler(ctx, in)
+}
+
+// callBlobStore opens ref from the blob store, recovering from its panics.
+func (s *Scriber) callBlobStore(ctx context.Context, ref string) (data io.ReadCloser, size int64, err error) {
+	defer recoverPanic(&err)
+	return s.blobStore.Open(ctx, ref)
+}
+
+// probeDuration runs the duration prober, recovering from its panics.
+func (s *Scriber) probeDuration(ctx context.Context, r io.Reader) (d time.Duration, err error) {
+	defer recoverPanic(&err)
+	return s.probeDurationFunc(ctx, r)
+}
+
+// probeLayout runs the channel layout prober, recovering from its panics.
+func (s *Scriber) probeLayout(ctx context.Context, r io.Reader) (layout channelLayout, err error) {
+	defer recoverPanic(&err)
+	return s.probeLayoutFunc(ctx, r)
+}
+
+// classifyRetry runs the retry classifier, recovering from its panics.
+func (s *Scriber) classifyRetry(err error) (decision RetryDecision, panicErr error) {
+	defer recoverPanic(&panicErr)
+
+	if s.retryClassifier == nil {
+		return DefaultRetryClassifier(err), nil
+	}
+	return s.retryClassifier(err), nil
+}
+
+// notifyPanic reports err on the Errors channel if it was caused by a panic.
+func (s *Scriber) notifyPanic(j *job, err error) {
+	var pe *PanicError
+	if !errors.As(err, &pe) {
+		return
+	}
+
+	j.logger.Error("Recovered from panic",
+		slog.String("file", j.in.Name),
+		slog.String("error", pe.Error()),
+		slog.String("stack", string(pe.Stack)),
+	)
+	s.notify(err)
+}
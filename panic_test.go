@@ -0,0 +1,202 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_RecoversPanics(t *testing.T) {
+	t.Parallel()
+
+	wavConverter := func(_ context.Context, r io.Reader, w io.Writer) error {
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			return err
+		}
+		_, err := w.Write(syntheticWAV(2))
+		return err
+	}
+
+	testCases := []struct {
+		name            string
+		givenOpts       func(panicOnce func()) []Option
+		givenPanicInCli bool
+		givenFailOnce   bool // The client fails its first call.
+		givenLanguages  []string
+		givenDataRef    bool // The input is opened from the blob store.
+		expectedStage   Stage
+	}{
+		{
+			name: "converter",
+			givenOpts: func(panicOnce func()) []Option {
+				return []Option{WithConverter(func(ctx context.Context, r io.Reader, w io.Writer) error {
+					panicOnce()
+					return copyConverter(ctx, r, w)
+				})}
+			},
+			expectedStage: StageConversion,
+		},
+		{
+			name: "blob store",
+			givenOpts: func(panicOnce func()) []Option {
+				return []Option{WithBlobStore(panickingBlobStore{panicOnce: panicOnce})}
+			},
+			givenDataRef:  true,
+			expectedStage: StageValidation,
+		},
+		{
+			name:            "transcription client",
+			givenPanicInCli: true,
+			expectedStage:   StageTranscription,
+		},
+		{
+			name: "progress function",
+			givenOpts: func(panicOnce func()) []Option {
+				return []Option{WithProgress(func(Progress) { panicOnce() })}
+			},
+			expectedStage: StageConversion,
+		},
+		{
+			name: "context attribute extractor",
+			givenOpts: func(panicOnce func()) []Option {
+				return []Option{WithContextAttrExtractor(func(context.Context) []slog.Attr {
+					panicOnce()
+					return nil
+				})}
+			},
+			expectedStage: StageAdmission,
+		},
+		{
+			name: "retry classifier",
+			givenOpts: func(panicOnce func()) []Option {
+				return []Option{
+					WithRetry(RetryConfig{Attempts: 2}),
+					WithRetryClassifier(func(error) RetryDecision {
+						panicOnce()
+						return RetryDecisionRetry
+					}),
+				}
+			},
+			givenFailOnce: true,
+			expectedStage: StageTranscription,
+		},
+		{
+			name: "chunked transcription",
+			givenOpts: func(func()) []Option {
+				return []Option{WithChunking(ChunkConfig{Length: time.Second}), WithConverter(wavConverter)}
+			},
+			givenPanicInCli: true,
+			expectedStage:   StageTranscription,
+		},
+		{
+			name: "languages",
+			givenOpts: func(func()) []Option {
+				return []Option{WithConverter(wavConverter)}
+			},
+			givenPanicInCli: true,
+			givenLanguages:  []string{"en", "pt"},
+			expectedStage:   StageTranscription,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var panicked, failed atomic.Bool
+			panicOnce := func() {
+				if panicked.CompareAndSwap(false, true) {
+					panic("boom")
+				}
+			}
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					if _, err := io.Copy(io.Discard, in.Data); err != nil {
+						return nil, err
+					}
+					if tc.givenPanicInCli {
+						panicOnce()
+					}
+					if tc.givenFailOnce && failed.CompareAndSwap(false, true) {
+						return nil, assert.AnError
+					}
+					return []byte("text"), nil
+				},
+			}
+
+			opts := []Option{WithErrorChannel(len(tc.givenLanguages) + 1), WithConverter(copyConverter)}
+			if tc.givenOpts != nil {
+				opts = append(opts, tc.givenOpts(panicOnce)...)
+			}
+			s := New(noopLogger(), mockClient, opts...)
+
+			process := func() error {
+				in := Input{
+					Name:       "test.mp4",
+					OutputType: OutputTypeTranscript,
+					Languages:  tc.givenLanguages,
+					Data:       io.NopCloser(bytes.NewBufferString("media")),
+				}
+				if len(tc.givenLanguages) == 0 {
+					in.Language = "en"
+				}
+				if tc.givenDataRef {
+					in.Data, in.DataRef = nil, "media.mp4"
+				}
+				return s.Process(context.TODO(), in)
+			}
+
+			err := process()
+			require.True(t, panicked.Load(), "the extension point wasn't reached")
+
+			var pe *ProcessError
+			require.ErrorAs(t, err, &pe)
+			assert.Equal(t, tc.expectedStage, pe.Stage)
+
+			var panicErr *PanicError
+			require.ErrorAs(t, err, &panicErr)
+			assert.Equal(t, "boom", panicErr.Value)
+			assert.Contains(t, string(panicErr.Stack), "panic_test.go")
+
+			select {
+			case notified := <-s.Errors():
+				require.ErrorAs(t, notified, &panicErr)
+			default:
+				t.Fatal("the panic wasn't reported on the error channel")
+			}
+
+			// Outputs of languages that didn't panic.
+			for range max(len(tc.givenLanguages)-1, 0) {
+				out := <-s.Collect()
+				out.Body.Close()
+			}
+
+			// The Scriber is still usable.
+			require.NoError(t, process())
+			for range max(len(tc.givenLanguages), 1) {
+				out := <-s.Collect()
+				out.Body.Close()
+				assert.Equal(t, "text", string(out.Text))
+			}
+		})
+	}
+}
+
+// panickingBlobStore is a BlobStore calling panicOnce before opening a blob.
+type panickingBlobStore struct {
+	panicOnce func()
+}
+
+func (s panickingBlobStore) Open(context.Context, string) (io.ReadCloser, int64, error) {
+	s.panicOnce()
+	return io.NopCloser(bytes.NewBufferString("media")), 5, nil
+}
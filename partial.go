@@ -0,0 +1,96 @@
+package scriber
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PartialOutput is the transcription of one segment of an input,
+// published while the rest of the input is still being transcribed.
+type PartialOutput struct {
+	JobID        string
+	SegmentIndex int
+
+	// Language is the language of the segment, which tells apart the
+	// segments of inputs transcribed in several languages.
+	Language string
+
+	// Text is the segment transcription as returned by the backend.
+	// Subtitle timestamps in it are relative to Start.
+	Text string
+
+	// Start and End locate the segment within the input audio.
+	Start time.Duration
+	End   time.Duration
+}
+
+// WithPartialResults enables publishing partial results on the channel
+// returned by Partials, buffered to hold size entries. Partial results are
+// produced by chunked transcription (see WithChunking), one per chunk.
+func WithPartialResults(size int) Option {
+	return func(s *Scriber) {
+		s.partialsCh = make(chan PartialOutput, size)
+	}
+}
+
+// Partials returns the channel partial results are published on.
+// Within a job, partial results are published in segment order,
+// followed by the final Output on the Collect channel.
+// It returns nil unless WithPartialResults is set.
+func (s *Scriber) Partials() <-chan PartialOutput {
+	return s.partialsCh
+}
+
+// partialSequencer publishes partial results of a
+// job in segment order as segments complete.
+type partialSequencer struct {
+	ch      chan<- PartialOutput
+	outlets *outlets
+	mu      sync.Mutex
+	next    int
+	ready   map[int]PartialOutput
+}
+
+func newPartialSequencer(ch chan<- PartialOutput, o *outlets) *partialSequencer {
+	return &partialSequencer{ch: ch, outlets: o, ready: make(map[int]PartialOutput)}
+}
+
+// complete records a finished segment and publishes it along with any
+// following segments that were waiting for it. Sending happens under
+// the lock so that concurrent completions can't interleave.
+func (p *partialSequencer) complete(ctx context.Context, out PartialOutput) error {
+	if p == nil || p.ch == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.ready[out.SegmentIndex] = out
+
+	for {
+		next, ok := p.ready[p.next]
+		if !ok {
+			return nil
+		}
+
+		// Once the outlets are closed, the result is dropped.
+		var err error
+		p.outlets.send(func(closing <-chan struct{}) {
+			select {
+			case p.ch <- next:
+			case <-ctx.Done():
+				err = ctx.Err()
+			case <-closing:
+				p.outlets.dropped.Add(1)
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		delete(p.ready, p.next)
+		p.next++
+	}
+}
@@ -0,0 +1,124 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartialSequencer(t *testing.T) {
+	t.Parallel()
+
+	ch := make(chan PartialOutput, 5)
+	seq := newPartialSequencer(ch, &outlets{})
+
+	for _, i := range []int{2, 0, 4, 1, 3} {
+		require.NoError(t, seq.complete(context.TODO(), PartialOutput{SegmentIndex: i}))
+	}
+	close(ch)
+
+	var got []int
+	for p := range ch {
+		got = append(got, p.SegmentIndex)
+	}
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, got)
+}
+
+func TestPartialSequencer_Disabled(t *testing.T) {
+	t.Parallel()
+
+	var seq *partialSequencer
+	require.NoError(t, seq.complete(context.TODO(), PartialOutput{}))
+
+	require.NoError(t, newPartialSequencer(nil, &outlets{}).complete(context.TODO(), PartialOutput{}))
+}
+
+func TestPartialSequencer_Cancelled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+
+	seq := newPartialSequencer(make(chan PartialOutput), &outlets{}) // Nobody reads.
+	require.ErrorIs(t, seq.complete(ctx, PartialOutput{}), context.Canceled)
+}
+
+func TestProcess_PartialResults(t *testing.T) {
+	t.Parallel()
+
+	const seconds = 30
+
+	mockClient := &mockWhisperClient{
+		transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, _, _, err := readWAVHeader(in.Data)
+			require.NoError(t, err)
+
+			first := make([]byte, 1)
+			_, err = io.ReadFull(in.Data, first)
+			require.NoError(t, err)
+			_, err = io.Copy(io.Discard, in.Data)
+			require.NoError(t, err)
+
+			// Earlier chunks finish last.
+			startSec := int(first[0])
+			time.Sleep(time.Duration(seconds-startSec) * 3 * time.Millisecond)
+
+			return []byte(fmt.Sprintf("chunk at %d", startSec)), nil
+		},
+	}
+
+	scriber := New(noopLogger(), mockClient,
+		WithChunking(ChunkConfig{Length: 10 * time.Second, Parallelism: 3}),
+		WithPartialResults(0),
+		WithSpoolThreshold(0, t.TempDir()),
+		WithConverter(func(_ context.Context, r io.Reader, w io.Writer) error {
+			_, err := w.Write(syntheticWAV(seconds))
+			return err
+		}),
+	)
+
+	partials := make(chan []PartialOutput)
+	go func() {
+		var got []PartialOutput
+		for p := range scriber.Partials() {
+			got = append(got, p)
+			if len(got) == 3 {
+				break
+			}
+		}
+		partials <- got
+	}()
+
+	err := scriber.Process(context.TODO(), Input{
+		Name:       "long.mp4",
+		OutputType: OutputTypeTranscript,
+		Language:   "en",
+		Data:       io.NopCloser(bytes.NewBufferString("foo")),
+	})
+	require.NoError(t, err)
+
+	output := <-scriber.Collect()
+	got := <-partials
+
+	require.Len(t, got, 3)
+	for i, p := range got {
+		assert.Equal(t, output.JobID, p.JobID)
+		assert.Equal(t, i, p.SegmentIndex)
+		assert.Equal(t, time.Duration(i)*10*time.Second, p.Start)
+		assert.Equal(t, time.Duration(i+1)*10*time.Second, p.End)
+		assert.Equal(t, fmt.Sprintf("chunk at %d", i*10), p.Text)
+	}
+}
+
+func TestPartials_DisabledByDefault(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, New(noopLogger(), &mockWhisperClient{}).Partials())
+}
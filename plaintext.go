@@ -0,0 +1,38 @@
+package scriber
+
+import (
+	"regexp"
+	"strings"
+)
+
+// formattingTagPattern matches HTML-like tags such as <i> or
+// <font color="red">, and SSA override blocks such as {\an8}.
+var formattingTagPattern = regexp.MustCompile(`</?[a-zA-Z][^<>]*>|\{\\[^{}]*\}`)
+
+// StripSubtitleFormatting returns the text of an SRT document without cue
+// numbers, timestamps, and formatting tags. The lines of a cue are joined
+// with spaces and cues with newlines; cues left empty are dropped. Data
+// that doesn't parse as SRT is returned with its formatting tags removed.
+func StripSubtitleFormatting(data []byte) string {
+	cues, err := parseSRT(data)
+	if err != nil {
+		return formattingTagPattern.ReplaceAllString(string(data), "")
+	}
+
+	lines := make([]string, 0, len(cues))
+	for _, c := range cues {
+		text := formattingTagPattern.ReplaceAllString(c.Text, "")
+		if line := strings.Join(strings.Fields(text), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// plainText returns the plain text view of a transcription of type t.
+func plainText(text []byte, t OutputType) string {
+	if isSubtitles(t) {
+		return StripSubtitleFormatting(text)
+	}
+	return string(text)
+}
@@ -0,0 +1,122 @@
+package scriber
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripSubtitleFormatting(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		given    string
+		expected string
+	}{
+		{
+			name:     "multi-line cues",
+			given:    "1\n00:00:00,000 --> 00:00:01,000\nHello\nthere\n\n2\n00:00:01,000 --> 00:00:02,000\nGeneral Kenobi\n",
+			expected: "Hello there\nGeneral Kenobi",
+		},
+		{
+			name:     "italic and bold tags",
+			given:    "1\n00:00:00,000 --> 00:00:01,000\n<i>Hello</i> <b>there</b>\n",
+			expected: "Hello there",
+		},
+		{
+			name:     "font tags with attributes",
+			given:    "1\n00:00:00,000 --> 00:00:01,000\n<font color=\"#ffff00\">Olá</font> mundo\n",
+			expected: "Olá mundo",
+		},
+		{
+			name:     "ssa override blocks",
+			given:    "1\n00:00:00,000 --> 00:00:01,000\n{\\an8}Top of the screen\n",
+			expected: "Top of the screen",
+		},
+		{
+			name:     "comparisons in speech are kept",
+			given:    "1\n00:00:00,000 --> 00:00:01,000\n3 < 5 and 7 > 2\n",
+			expected: "3 < 5 and 7 > 2",
+		},
+		{
+			name:     "cues left empty are dropped",
+			given:    "1\n00:00:00,000 --> 00:00:01,000\n<i></i>\n\n2\n00:00:01,000 --> 00:00:02,000\nBye\n",
+			expected: "Bye",
+		},
+		{
+			name:     "crlf line endings",
+			given:    "1\r\n00:00:00,000 --> 00:00:01,000\r\n<i>Hello</i>\r\n",
+			expected: "Hello",
+		},
+		{
+			name:     "not srt",
+			given:    "not <i>srt</i>",
+			expected: "not srt",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, StripSubtitleFormatting([]byte(tc.given)))
+		})
+	}
+}
+
+func TestProcess_PlainText(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name            string
+		givenOutputType OutputType
+		givenText       string
+		expected        string
+	}{
+		{
+			name:            "subtitles",
+			givenOutputType: OutputTypeSubtitles,
+			givenText:       "1\n00:00:00,000 --> 00:00:01,000\n<i>Hello</i>\nthere\n\n2\n00:00:01,000 --> 00:00:02,000\nfriend\n",
+			expected:        "Hello there\nfriend",
+		},
+		{
+			name:            "transcript",
+			givenOutputType: OutputTypeTranscript,
+			givenText:       "Hello there friend",
+			expected:        "Hello there friend",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.Copy(io.Discard, in.Data)
+					return []byte(tc.givenText), err
+				},
+			}, WithConverter(copyConverter))
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "test.mp4",
+				OutputType: tc.givenOutputType,
+				Language:   "en",
+				Data:       io.NopCloser(strings.NewReader("data")),
+			})
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			out.Body.Close()
+
+			assert.Equal(t, tc.expected, out.PlainText)
+			assert.Equal(t, tc.givenText, string(out.Text))
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package scriber
+
+import (
+	"context"
+	"sync"
+)
+
+// WithAsyncPostProcessing detaches post-processing from Process: once an
+// input is transcribed, Process returns nil, freeing its caller, and the
+// transcription is post-processed and published in the background by at
+// most workers jobs at once. The job's events keep their order, and
+// Shutdown waits for it as for any job. Its failures, with the
+// transcription attached as Salvaged when WithSalvage is set, are reported
+// on the Errors channel, so set WithErrorChannel too. Canceling the context
+// passed to Process no longer stops the job once it is handed off.
+// ProcessBatch frees the parallelism slot of an input on hand-off, but
+// still reports on it once it is done. Zero, the default, post-processes
+// in Process.
+func WithAsyncPostProcessing(workers int) Option {
+	return func(s *Scriber) {
+		s.postProcessWorkers = workers
+	}
+}
+
+// handOff releases the caller of the job, and waits for a post-processing
+// worker. It returns a func releasing the worker, which is a no-op if
+// post-processing is synchronous.
+func (s *Scriber) handOff(ctx context.Context, j *job) (func(), error) {
+	if s.postProcessWorkers <= 0 {
+		return func() {}, nil
+	}
+	if j.handOff != nil {
+		j.handOff()
+	}
+	return s.postProcessors.acquire(ctx)
+}
+
+// processDetached runs the job for in, returning once it is handed off
+// for post-processing or done, whichever comes first. The job's context
+// follows ctx until the hand-off only. Failures after the hand-off are
+// reported on the Errors channel.
+func (s *Scriber) processDetached(ctx context.Context, in Input) error {
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, cancel)
+
+	var (
+		once      sync.Once
+		handedOff = make(chan struct{})
+		errCh     = make(chan error, 1)
+	)
+	handOff := func() {
+		once.Do(func() {
+			stop()
+			close(handedOff)
+		})
+	}
+
+	go func() {
+		defer cancel()
+		// Jobs done before the hand-off unregister from ctx here.
+		defer stop()
+
+		_, err := s.process(jobCtx, in, handOff)
+		select {
+		case <-handedOff:
+			if err != nil {
+				s.notify(err)
+			}
+		default:
+			errCh <- err
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-handedOff:
+		return nil
+	}
+}
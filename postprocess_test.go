@@ -0,0 +1,233 @@
+package scriber
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSlowPostProcessingScriber returns a Scriber with fast transcriptions
+// whose post-processing takes delay, tracked by g, through its exists func.
+func newSlowPostProcessingScriber(g *gauge, delay time.Duration, opts ...Option) *Scriber {
+	exists := func(context.Context, string) (bool, error) {
+		g.enter()
+		defer g.leave()
+
+		time.Sleep(delay)
+		return false, nil
+	}
+
+	return New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.Copy(io.Discard, in.Data)
+			return []byte("text"), err
+		},
+	}, append([]Option{
+		WithExistsFunc(exists, 1),
+		WithConverter(copyConverter),
+	}, opts...)...)
+}
+
+func newPostProcessInput(name string) Input {
+	return Input{
+		Name:       name,
+		OutputType: OutputTypeTranscript,
+		Language:   "en",
+		Data:       io.NopCloser(strings.NewReader("data")),
+	}
+}
+
+func TestProcess_AsyncPostProcessing(t *testing.T) {
+	t.Parallel()
+
+	const (
+		jobs    = 6
+		workers = 2
+		delay   = 100 * time.Millisecond
+	)
+
+	var postProcessing gauge
+	s := newSlowPostProcessingScriber(&postProcessing, delay, WithAsyncPostProcessing(workers))
+
+	start := time.Now()
+	for i := range jobs {
+		require.NoError(t, s.Process(context.TODO(), newPostProcessInput(string(rune('a'+i))+".mp4")))
+	}
+	assert.Less(t, time.Since(start), delay, "Process should return once transcribed")
+
+	outs := make(chan Output, jobs)
+	go func() {
+		for out := range s.Collect() {
+			out.Body.Close()
+			outs <- out
+		}
+		close(outs)
+	}()
+	require.NoError(t, s.Shutdown(context.TODO()))
+
+	var n int
+	for out := range outs {
+		n++
+
+		transcribed, postProcessed := -1, -1
+		for i, e := range out.Events {
+			if e.Message != "Stage started" {
+				continue
+			}
+			switch e.Stage {
+			case StageTranscription:
+				transcribed = i
+			case StagePostProcess:
+				postProcessed = i
+			}
+		}
+		assert.GreaterOrEqual(t, transcribed, 0)
+		assert.Greater(t, postProcessed, transcribed, "events should keep their order")
+	}
+	assert.Equal(t, jobs, n, "Shutdown should wait for post-processing")
+	assert.EqualValues(t, workers, postProcessing.peak.Load())
+}
+
+func TestProcess_AsyncPostProcessingFailure(t *testing.T) {
+	t.Parallel()
+
+	t.Run("after hand-off", func(t *testing.T) {
+		t.Parallel()
+
+		s := New(noopLogger(), &mockWhisperClient{
+			transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+				_, err := io.Copy(io.Discard, in.Data)
+				return []byte("text"), err
+			},
+		},
+			WithAsyncPostProcessing(1),
+			WithSalvage(true),
+			WithErrorChannel(1),
+			WithExistsFunc(func(context.Context, string) (bool, error) { return false, assert.AnError }, 1),
+			WithConverter(copyConverter),
+		)
+
+		require.NoError(t, s.Process(context.TODO(), newPostProcessInput("talk.mp4")))
+
+		err := <-s.Errors()
+		require.ErrorIs(t, err, assert.AnError)
+
+		var pe *ProcessError
+		require.ErrorAs(t, err, &pe)
+		assert.Equal(t, StagePublish, pe.Stage)
+		require.NotNil(t, pe.Salvaged)
+		assert.Equal(t, "text", string(pe.Salvaged.Text))
+		require.NoError(t, pe.Salvaged.Body.Close())
+	})
+
+	t.Run("before hand-off", func(t *testing.T) {
+		t.Parallel()
+
+		s := New(noopLogger(), &mockWhisperClient{
+			transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+				_, err := io.Copy(io.Discard, in.Data)
+				require.NoError(t, err)
+				return nil, assert.AnError
+			},
+		},
+			WithAsyncPostProcessing(1),
+			WithConverter(copyConverter),
+		)
+
+		err := s.Process(context.TODO(), newPostProcessInput("talk.mp4"))
+		require.ErrorIs(t, err, assert.AnError)
+
+		var pe *ProcessError
+		require.ErrorAs(t, err, &pe)
+		assert.Equal(t, StageTranscription, pe.Stage)
+	})
+}
+
+func TestProcess_AsyncPostProcessingCanceledCaller(t *testing.T) {
+	t.Parallel()
+
+	var postProcessing gauge
+	s := newSlowPostProcessingScriber(&postProcessing, 50*time.Millisecond, WithAsyncPostProcessing(1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, s.Process(ctx, newPostProcessInput("talk.mp4")))
+	cancel()
+
+	out := <-s.Collect()
+	require.NoError(t, out.Body.Close())
+	assert.Equal(t, "talk.txt", out.Name)
+}
+
+// afterFuncContext is a context never done that counts the functions
+// registered with context.AfterFunc and not stopped yet.
+type afterFuncContext struct {
+	context.Context
+	done       chan struct{}
+	registered atomic.Int32
+}
+
+func (c *afterFuncContext) Done() <-chan struct{} { return c.done }
+
+func (c *afterFuncContext) AfterFunc(func()) func() bool {
+	c.registered.Add(1)
+	var once sync.Once
+	return func() bool {
+		once.Do(func() { c.registered.Add(-1) })
+		return true
+	}
+}
+
+func TestProcess_AsyncPostProcessingUnregisters(t *testing.T) {
+	t.Parallel()
+
+	var postProcessing gauge
+	s := newSlowPostProcessingScriber(&postProcessing, 0, WithAsyncPostProcessing(1))
+
+	ctx := &afterFuncContext{Context: context.Background(), done: make(chan struct{})}
+
+	// Handed off.
+	require.NoError(t, s.Process(ctx, newPostProcessInput("talk.mp4")))
+	out := <-s.Collect()
+	require.NoError(t, out.Body.Close())
+
+	// Failed before the hand-off.
+	require.Error(t, s.Process(ctx, newPostProcessInput("talk")))
+
+	assert.Eventually(t, func() bool { return ctx.registered.Load() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestProcessBatch_AsyncPostProcessing(t *testing.T) {
+	t.Parallel()
+
+	const inputs = 3
+
+	var postProcessing gauge
+	s := newSlowPostProcessingScriber(&postProcessing, 100*time.Millisecond, WithAsyncPostProcessing(inputs))
+
+	go func() {
+		for out := range s.Collect() {
+			out.Body.Close()
+		}
+	}()
+
+	batch := make([]Input, inputs)
+	for i := range batch {
+		batch[i] = newPostProcessInput(string(rune('a'+i)) + ".mp4")
+	}
+
+	report, err := s.ProcessBatch(context.TODO(), batch, BatchOptions{Parallelism: 1})
+	require.NoError(t, err)
+	assert.Len(t, report.Succeeded, inputs)
+
+	// The slot of each input is freed on hand-off, so a batch processed
+	// one input at a time still post-processes them concurrently.
+	assert.EqualValues(t, inputs, postProcessing.peak.Load())
+}
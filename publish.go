@@ -0,0 +1,339 @@
+package scriber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PublishPolicy decides what happens to an Output
+// that can't be published before the publish timeout.
+type PublishPolicy int
+
+const (
+	// PublishDrop drops the Output and reports it on the Errors channel.
+	PublishDrop PublishPolicy = iota
+
+	// PublishSpill writes the Output's transcription to the overflow
+	// directory, named after its job ID and name, for later recovery.
+	// Outputs that can't be spilled are dropped.
+	PublishSpill
+)
+
+// PublishConfig configures publishing Outputs to a consumer that doesn't keep up.
+type PublishConfig struct {
+	// Timeout is how long to wait for the Collect channel to accept an
+	// Output. Zero waits until the Process context is done.
+	Timeout time.Duration
+
+	// Policy applies to Outputs not accepted within Timeout.
+	Policy PublishPolicy
+
+	// OverflowDir is the directory spilled Outputs are written to.
+	OverflowDir string
+
+	// MaxBufferedBytes is a soft ceiling on the bytes of the Outputs
+	// published but not consumed yet, counted from Output.Text until
+	// Body is closed. An Output that would take them past the ceiling
+	// waits for earlier ones to be consumed, like for a full Collect
+	// channel, and Policy applies once Timeout elapses. An Output larger
+	// than the ceiling is published once no other is buffered. Spooled
+	// Outputs don't count. Zero disables the ceiling.
+	MaxBufferedBytes int64
+}
+
+func (c PublishConfig) validate() error {
+	if c.Timeout < 0 {
+		return errors.New("timeout must not be negative")
+	}
+	if c.MaxBufferedBytes < 0 {
+		return errors.New("max buffered bytes must not be negative")
+	}
+	if c.Policy == PublishSpill && c.OverflowDir == "" {
+		return errors.New("spilling requires an overflow directory")
+	}
+	return nil
+}
+
+// PublishStats counts the Outputs that couldn't be published in time.
+type PublishStats struct {
+	Dropped int64
+	Spilled int64
+
+	// DroppedAfterClose is the number of Outputs, partial results, and
+	// errors dropped because they were sent once the channels returned
+	// by Collect, Partials, and Errors were closed.
+	DroppedAfterClose int64
+
+	// BufferedBytes is the number of bytes of the Outputs published
+	// and not consumed yet, whose Body hasn't been closed.
+	BufferedBytes int64
+
+	// Overflowed is the number of Outputs spilled to the overflow
+	// directory, and OverflowPending the number of those, recovered
+	// ones included, not received yet. See WithResultOverflow.
+	Overflowed      int64
+	OverflowPending int
+}
+
+// publishCounters tracks PublishStats.
+type publishCounters struct {
+	dropped    atomic.Int64
+	spilled    atomic.Int64
+	overflowed atomic.Int64
+}
+
+// WithPublishTimeout bounds how long a job waits for the consumer of the
+// Collect channel, so that a stuck consumer can't wedge every job. By
+// default, jobs wait until their context is done.
+func WithPublishTimeout(cfg PublishConfig) Option {
+	return func(s *Scriber) {
+		s.publishing = &cfg
+	}
+}
+
+// WithErrorChannel enables reporting errors that have no caller to return
+// to, such as dropped Outputs, on the channel returned by Errors, buffered
+// to hold size entries. Errors are discarded while the channel is full.
+func WithErrorChannel(size int) Option {
+	return func(s *Scriber) {
+		s.errorsCh = make(chan error, size)
+	}
+}
+
+// Errors returns the channel errors are reported on.
+// It returns nil unless WithErrorChannel is set.
+func (s *Scriber) Errors() <-chan error {
+	return s.errorsCh
+}
+
+// PublishStats returns the number of Outputs dropped and spilled
+// since s was created, and the bytes of those not consumed yet.
+func (s *Scriber) PublishStats() PublishStats {
+	return PublishStats{
+		Dropped:           s.publishCounters.dropped.Load(),
+		Spilled:           s.publishCounters.spilled.Load(),
+		DroppedAfterClose: s.outlets.dropped.Load(),
+		BufferedBytes:     s.buffered.load(),
+		Overflowed:        s.publishCounters.overflowed.Load(),
+		OverflowPending:   s.overflowPending(),
+	}
+}
+
+func (s *Scriber) overflowPending() int {
+	if s.overflow == nil {
+		return 0
+	}
+	return s.overflow.pending()
+}
+
+// publish sends out on the Collect channel, once earlier jobs have published
+// if results are ordered, applying the publish timeout policy if the
+// consumer doesn't accept it in time.
+func (s *Scriber) publish(ctx context.Context, j *job, out Output) error {
+	if s.sequencer != nil {
+		if err := s.sequencer.wait(ctx, j.logger, j.seq); err != nil {
+			return s.abandonOutput(j, out, err)
+		}
+	}
+
+	if s.overflow != nil {
+		var (
+			handled bool
+			err     error
+		)
+		if out, handled, err = s.overflowPublish(j, out); handled {
+			return err
+		}
+	}
+
+	var timeout <-chan time.Time
+	if s.publishing != nil && s.publishing.Timeout > 0 {
+		timer := time.NewTimer(s.publishing.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	sent, err := s.send(ctx, out, timeout)
+	if err != nil {
+		return s.abandonOutput(j, out, err)
+	}
+	if sent {
+		return nil
+	}
+
+	var pe *ProcessError
+	if s.publishing.Policy == PublishSpill {
+		path, err := spillOutput(s.publishing.OverflowDir, out)
+		if err == nil {
+			s.publishCounters.spilled.Add(1)
+			j.logger.Warn("Publish timed out, output spilled", slog.String("path", path))
+			return nil
+		}
+		// The body is consumed, so there is nothing left to salvage.
+		pe = asProcessError(StagePublish, j.in, fmt.Errorf("%w, and spilling failed: %w", errPublishTimeout, err))
+	} else {
+		pe = s.abandonOutput(j, out, errPublishTimeout)
+	}
+
+	s.publishCounters.dropped.Add(1)
+	j.logger.Warn("Publish timed out, output dropped", slog.Duration("timeout", s.publishing.Timeout))
+	s.notify(pe)
+	return pe
+}
+
+// send sends out on the Collect channel once its payload fits under the
+// buffered bytes ceiling. It returns false if timeout fires first.
+func (s *Scriber) send(ctx context.Context, out Output, timeout <-chan time.Time) (bool, error) {
+	var ceiling int64
+	if s.publishing != nil {
+		ceiling = s.publishing.MaxBufferedBytes
+	}
+
+	n := int64(len(out.Text))
+	if ok, err := s.buffered.reserve(ctx, n, ceiling, timeout); !ok || err != nil {
+		return false, err
+	}
+
+	held := out
+	held.Body = &reservedBody{ReadCloser: out.Body, release: func() { s.buffered.release(n) }}
+
+	var (
+		sent bool
+		err  error
+	)
+	open := s.outlets.send(func(closing <-chan struct{}) {
+		select {
+		case s.resultsCh <- held:
+			sent = true
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-timeout:
+		case <-closing:
+			s.outlets.dropped.Add(1)
+			err = errClosed
+		}
+	})
+	if !open {
+		err = errClosed
+	}
+	if !sent {
+		s.buffered.release(n)
+	}
+	return sent, err
+}
+
+// byteGauge counts the bytes of the Outputs published and not consumed
+// yet, waking the publishers waiting for room as they are released.
+type byteGauge struct {
+	mu sync.Mutex
+	n  int64
+
+	// released is closed, and replaced, whenever bytes are released.
+	released chan struct{}
+}
+
+func (g *byteGauge) load() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.n
+}
+
+// reserve adds n bytes once they fit under ceiling, or nothing else is
+// held. A ceiling of zero always fits. It returns false if timeout fires
+// first, and ctx's error if ctx is done first.
+func (g *byteGauge) reserve(ctx context.Context, n, ceiling int64, timeout <-chan time.Time) (bool, error) {
+	for {
+		g.mu.Lock()
+		if ceiling <= 0 || g.n == 0 || g.n+n <= ceiling {
+			g.n += n
+			g.mu.Unlock()
+			return true, nil
+		}
+		if g.released == nil {
+			g.released = make(chan struct{})
+		}
+		released := g.released
+		g.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-timeout:
+			return false, nil
+		}
+	}
+}
+
+func (g *byteGauge) release(n int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.n -= n
+	if g.released != nil {
+		close(g.released)
+		g.released = nil
+	}
+}
+
+// abandonOutput returns the error for an output that wasn't published
+// because of err. With salvaging enabled the output, body included,
+// is handed to the caller through the error; otherwise it is discarded.
+func (s *Scriber) abandonOutput(j *job, out Output, err error) *ProcessError {
+	pe := asProcessError(StagePublish, j.in, err)
+	if s.salvage {
+		out.Degraded = true
+		pe.Salvaged = &out
+		return pe
+	}
+	out.Body.Close()
+	return pe
+}
+
+// notify reports err on the Errors channel without blocking.
+// Once the Errors channel is closed, err is dropped.
+func (s *Scriber) notify(err error) {
+	if s.errorsCh == nil {
+		return
+	}
+
+	s.outlets.send(func(<-chan struct{}) {
+		select {
+		case s.errorsCh <- err:
+		default:
+		}
+	})
+}
+
+// spillOutput writes the transcription of out to dir and returns the file path.
+// The output body is consumed and closed.
+func spillOutput(dir string, out Output) (string, error) {
+	defer out.Body.Close()
+
+	path := filepath.Join(dir, out.JobID+"-"+out.Filename(WithNameLanguage(), WithNameSanitized()))
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("could not create overflow file: %w", err)
+	}
+
+	if _, err := io.Copy(f, out.Body); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", fmt.Errorf("could not write overflow file: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("could not close overflow file: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,273 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_PublishTimeout(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name            string
+		givenPolicy     PublishPolicy
+		givenMissingDir bool
+		expectedErr     bool
+		expectedStats   PublishStats
+	}{
+		{
+			name:          "drop",
+			givenPolicy:   PublishDrop,
+			expectedErr:   true,
+			expectedStats: PublishStats{Dropped: 1},
+		},
+		{
+			name:          "spill",
+			givenPolicy:   PublishSpill,
+			expectedStats: PublishStats{Spilled: 1},
+		},
+		{
+			name:            "spill failure drops",
+			givenPolicy:     PublishSpill,
+			givenMissingDir: true,
+			expectedErr:     true,
+			expectedStats:   PublishStats{Dropped: 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			if tc.givenMissingDir {
+				dir = filepath.Join(dir, "missing")
+			}
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.Copy(io.Discard, in.Data)
+					require.NoError(t, err)
+					return []byte("text"), nil
+				},
+			}
+
+			s := New(noopLogger(), mockClient,
+				WithErrorChannel(1),
+				WithPublishTimeout(PublishConfig{
+					Timeout:     10 * time.Millisecond,
+					Policy:      tc.givenPolicy,
+					OverflowDir: dir,
+				}),
+				WithConverter(copyConverter),
+			)
+
+			// Nobody ever reads.
+			s.resultsCh = make(chan Output)
+
+			done := make(chan error, 1)
+			go func() {
+				done <- s.Process(context.TODO(), Input{
+					Name:       "test.mp4",
+					OutputType: OutputTypeTranscript,
+					Language:   "en",
+					Data:       io.NopCloser(bytes.NewBufferString("media")),
+				})
+			}()
+
+			var err error
+			select {
+			case err = <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Process blocked on a consumer that never reads")
+			}
+
+			assert.Equal(t, tc.expectedStats, s.PublishStats())
+
+			if !tc.expectedErr {
+				require.NoError(t, err)
+
+				entries, err := os.ReadDir(dir)
+				require.NoError(t, err)
+				require.Len(t, entries, 1)
+				assert.Contains(t, entries[0].Name(), "-test.en.txt")
+
+				data, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+				require.NoError(t, err)
+				assert.Equal(t, "text", string(data))
+
+				assert.Empty(t, s.Errors())
+				return
+			}
+
+			var pe *ProcessError
+			require.ErrorAs(t, err, &pe)
+			assert.Equal(t, StagePublish, pe.Stage)
+			require.ErrorIs(t, err, errPublishTimeout)
+
+			select {
+			case notified := <-s.Errors():
+				assert.Equal(t, err, notified)
+			default:
+				t.Fatal("dropped output wasn't reported")
+			}
+		})
+	}
+}
+
+func TestProcess_PublishTimeoutInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	s := New(noopLogger(), &mockWhisperClient{},
+		WithPublishTimeout(PublishConfig{Timeout: time.Second, Policy: PublishSpill}))
+
+	err := s.Process(context.TODO(), Input{
+		Name:       "test.mp4",
+		OutputType: OutputTypeTranscript,
+		Language:   "en",
+		Data:       io.NopCloser(bytes.NewBufferString("media")),
+	})
+
+	var pe *ProcessError
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, StageValidation, pe.Stage)
+}
+
+func TestProcess_MaxBufferedBytes(t *testing.T) {
+	t.Parallel()
+
+	const payload = 600 << 10
+
+	testCases := []struct {
+		name          string
+		givenTimeout  time.Duration
+		givenPolicy   PublishPolicy
+		expectedErr   error
+		expectedStats PublishStats
+	}{
+		{
+			name:          "drop",
+			givenTimeout:  20 * time.Millisecond,
+			givenPolicy:   PublishDrop,
+			expectedErr:   errPublishTimeout,
+			expectedStats: PublishStats{Dropped: 1, BufferedBytes: payload},
+		},
+		{
+			name:          "spill",
+			givenTimeout:  20 * time.Millisecond,
+			givenPolicy:   PublishSpill,
+			expectedStats: PublishStats{Spilled: 1, BufferedBytes: payload},
+		},
+		{
+			name:          "block",
+			expectedStats: PublishStats{BufferedBytes: payload},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.Copy(io.Discard, in.Data)
+					return bytes.Repeat([]byte("a"), payload), err
+				},
+			},
+				WithPublishTimeout(PublishConfig{
+					Timeout:          tc.givenTimeout,
+					Policy:           tc.givenPolicy,
+					OverflowDir:      t.TempDir(),
+					MaxBufferedBytes: 1 << 20,
+				}),
+				WithConverter(copyConverter),
+			)
+
+			process := func(name string) error {
+				return s.Process(context.TODO(), Input{
+					Name:       name,
+					OutputType: OutputTypeTranscript,
+					Language:   "en",
+					Data:       io.NopCloser(bytes.NewBufferString("media")),
+				})
+			}
+
+			// The channel has room, but the consumer doesn't read.
+			require.NoError(t, process("first.mp4"))
+			assert.Equal(t, PublishStats{BufferedBytes: payload}, s.PublishStats())
+
+			done := make(chan error, 1)
+			go func() { done <- process("second.mp4") }()
+
+			if tc.givenTimeout == 0 {
+				select {
+				case err := <-done:
+					t.Fatalf("publish didn't wait for room: %v", err)
+				case <-time.After(50 * time.Millisecond):
+				}
+
+				// Consuming the first output makes room for the second.
+				first := <-s.Collect()
+				require.NoError(t, first.Body.Close())
+			}
+
+			var err error
+			select {
+			case err = <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Process blocked past the publish timeout")
+			}
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedStats, s.PublishStats())
+
+			for len(s.Collect()) > 0 {
+				out := <-s.Collect()
+				require.NoError(t, out.Body.Close())
+				require.NoError(t, out.Body.Close())
+			}
+			assert.Zero(t, s.PublishStats().BufferedBytes)
+		})
+	}
+}
+
+func TestProcess_MaxBufferedBytesOversized(t *testing.T) {
+	t.Parallel()
+
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.Copy(io.Discard, in.Data)
+			return bytes.Repeat([]byte("a"), 2<<20), err
+		},
+	},
+		WithPublishTimeout(PublishConfig{Timeout: time.Second, MaxBufferedBytes: 1 << 20}),
+		WithConverter(copyConverter),
+	)
+
+	// Outputs larger than the ceiling are published alone.
+	err := s.Process(context.TODO(), Input{
+		Name:       "long.mp4",
+		OutputType: OutputTypeTranscript,
+		Language:   "en",
+		Data:       io.NopCloser(bytes.NewBufferString("media")),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, PublishStats{BufferedBytes: 2 << 20}, s.PublishStats())
+
+	out := <-s.Collect()
+	require.NoError(t, out.Body.Close())
+	assert.Zero(t, s.PublishStats().BufferedBytes)
+}
@@ -0,0 +1,139 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alesr/scriber/subtitle"
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_RawText(t *testing.T) {
+	t.Parallel()
+
+	raw := formatSRT([]subtitle.Cue{
+		{Start: 0, End: time.Second, Text: "<i>Hello</i> [music]"},
+		{Start: time.Second, End: 2 * time.Second, Text: "world"},
+	})
+	processed := formatSRT([]subtitle.Cue{
+		{Start: 0, End: time.Second, Text: "Hello"},
+		{Start: time.Second, End: 2 * time.Second, Text: "world"},
+	})
+
+	// Formatting rewrites the subtitles the backend returned.
+	formatting := WithFormatting(FormattingConfig{RemoveSoundDescriptions: true, RemoveItalics: true})
+
+	testCases := []struct {
+		name            string
+		givenOpts       []Option
+		givenLanguage   string
+		expectedText    []byte
+		expectedRaw     []byte
+		expectedRawName string
+	}{
+		{
+			name:            "post-processed",
+			givenOpts:       []Option{formatting, WithRawText(true)},
+			expectedText:    processed,
+			expectedRaw:     raw,
+			expectedRawName: "talk.raw.srt",
+		},
+		{
+			name:            "post-processed with the language in the name",
+			givenOpts:       []Option{formatting, WithRawText(true), WithLanguageInName(true)},
+			expectedText:    processed,
+			expectedRaw:     raw,
+			expectedRawName: "talk.en.raw.srt",
+		},
+		{
+			name:         "disabled",
+			givenOpts:    []Option{formatting},
+			expectedText: processed,
+		},
+		{
+			// Text is the raw transcription, so no copy is kept.
+			name:         "no post-processing",
+			givenOpts:    []Option{WithRawText(true)},
+			expectedText: raw,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.ReadAll(in.Data)
+					return bytes.Clone(raw), err
+				},
+			}, tc.givenOpts...)
+			s.convertToWavFunc = copyConverter
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "talk.mp4",
+				OutputType: OutputTypeSubtitles,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(syntheticWAV(2))),
+			})
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+
+			assert.Equal(t, string(tc.expectedText), string(out.Text))
+			assert.Equal(t, tc.expectedRaw, out.RawText)
+			assert.Equal(t, tc.expectedRawName, out.RawName)
+		})
+	}
+}
+
+func TestProcess_RawTextOutputDir(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.ReadAll(in.Data)
+			return []byte("Hello [music] world"), err
+		},
+	},
+		WithFormatting(FormattingConfig{RemoveSoundDescriptions: true}),
+		WithRawText(true),
+		WithOutputDir(dir),
+	)
+	s.convertToWavFunc = copyConverter
+
+	for range 2 {
+		err := s.Process(context.TODO(), Input{
+			Name:       "talk.mp4",
+			OutputType: OutputTypeTranscript,
+			Language:   "en",
+			Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+		})
+		require.NoError(t, err)
+
+		out := <-s.Collect()
+		require.NoError(t, out.Body.Close())
+	}
+
+	// The raw transcription follows the output's name, renamed when taken.
+	for _, name := range []string{"talk.raw.txt", "talk-1.raw.txt"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.Equal(t, "Hello [music] world", string(data))
+	}
+	for _, name := range []string{"talk.txt", "talk-1.txt"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.Equal(t, "Hello world", string(data))
+	}
+}
@@ -0,0 +1,219 @@
+package scriber
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MetadataRedactedPrefix prefixes the Output.Metadata keys holding the
+// number of redactions of each class, e.g. redacted_email. See WithRedactor.
+const MetadataRedactedPrefix = "redacted_"
+
+// RedactionClass names a kind of personal data.
+type RedactionClass string
+
+const (
+	// RedactEmail matches email addresses.
+	RedactEmail RedactionClass = "email"
+
+	// RedactPhone matches phone numbers of at least three groups of digits,
+	// such as +1 415-555-2671 or (415) 555 2671, or of a plus sign followed
+	// by at least ten digits.
+	RedactPhone RedactionClass = "phone"
+
+	// RedactCardNumber matches runs of 13 to 19 digits, optionally grouped
+	// with spaces or dashes, that pass the Luhn check, as card numbers do.
+	RedactCardNumber RedactionClass = "card_number"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9-]+(?:\.[a-z0-9-]+)*\.[a-z]{2,}\b`)
+	phonePattern = regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{2,4}\)[ .-]?|\b\d{2,4}[ .-])\d{3,4}[ .-]\d{3,4}\b|\+\d{10,15}\b`)
+	cardPattern  = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+)
+
+// RedactionRule masks the matches of Pattern as personal data of Class.
+type RedactionRule struct {
+	Class   RedactionClass
+	Pattern *regexp.Regexp
+
+	// Replacement replaces each match. It defaults to the class
+	// in upper case within brackets, e.g. [EMAIL].
+	Replacement string
+
+	// valid, if set, tells the matches at text[start:end] to
+	// redact from near misses.
+	valid func(text string, start, end int) bool
+}
+
+// Redactor masks personal data in transcriptions. Each built-in class is
+// enabled by its flag, and custom rules are applied after them, in order.
+type Redactor struct {
+	Emails       bool
+	PhoneNumbers bool
+	CardNumbers  bool
+
+	// Replacements overrides the replacement of the classes it holds,
+	// built-in or custom, unless a rule sets its own.
+	Replacements map[RedactionClass]string
+
+	// Rules are custom rules, such as account numbers.
+	Rules []RedactionRule
+}
+
+// WithRedactor masks personal data in transcriptions as r says, before
+// they leave scriber: in Output.Text, Output.PlainText, and Output.RawText,
+// in salvaged outputs, and in partial results. Subtitles are redacted cue
+// by cue, leaving their timestamps alone. The number of redactions of each
+// enabled class is set in Output.Metadata, see MetadataRedactedPrefix.
+func WithRedactor(r Redactor) Option {
+	return func(s *Scriber) {
+		s.redactor = &r
+	}
+}
+
+// rules returns the rules r applies, in order. Card numbers come before
+// phone numbers, whose groups of digits they could otherwise be taken for.
+func (r *Redactor) rules() []RedactionRule {
+	var rules []RedactionRule
+	if r.CardNumbers {
+		rules = append(rules, RedactionRule{Class: RedactCardNumber, Pattern: cardPattern, valid: isCardNumber})
+	}
+	if r.Emails {
+		rules = append(rules, RedactionRule{Class: RedactEmail, Pattern: emailPattern})
+	}
+	if r.PhoneNumbers {
+		rules = append(rules, RedactionRule{Class: RedactPhone, Pattern: phonePattern, valid: isDigitRun})
+	}
+	return append(rules, r.Rules...)
+}
+
+// replacement returns what the matches of rule are replaced with.
+func (r *Redactor) replacement(rule RedactionRule) string {
+	if rule.Replacement != "" {
+		return rule.Replacement
+	}
+	if repl, ok := r.Replacements[rule.Class]; ok {
+		return repl
+	}
+	return "[" + strings.ToUpper(string(rule.Class)) + "]"
+}
+
+// Redact masks the personal data in text, returning the number
+// of redactions of each enabled class.
+func (r *Redactor) Redact(text string) (string, map[RedactionClass]int) {
+	rules := r.rules()
+	counts := newRedactionCounts(rules)
+	return r.redact(text, rules, counts), counts
+}
+
+// newRedactionCounts returns zero counts for the classes of rules.
+func newRedactionCounts(rules []RedactionRule) map[RedactionClass]int {
+	counts := make(map[RedactionClass]int, len(rules))
+	for _, rule := range rules {
+		counts[rule.Class] = 0
+	}
+	return counts
+}
+
+// redact applies rules to text, adding up the redactions in counts.
+func (r *Redactor) redact(text string, rules []RedactionRule, counts map[RedactionClass]int) string {
+	for _, rule := range rules {
+		repl := r.replacement(rule)
+
+		var (
+			b    strings.Builder
+			last int
+		)
+		for _, m := range rule.Pattern.FindAllStringIndex(text, -1) {
+			if rule.valid != nil && !rule.valid(text, m[0], m[1]) {
+				continue
+			}
+			b.WriteString(text[last:m[0]])
+			b.WriteString(repl)
+			last = m[1]
+			counts[rule.Class]++
+		}
+		if last > 0 {
+			b.WriteString(text[last:])
+			text = b.String()
+		}
+	}
+	return text
+}
+
+// redactTranscription masks the personal data in text, a transcription
+// of type t, redacting subtitles cue by cue.
+func (r *Redactor) redactTranscription(text []byte, t OutputType) ([]byte, map[RedactionClass]int) {
+	if !isSubtitles(t) {
+		redacted, counts := r.Redact(string(text))
+		return []byte(redacted), counts
+	}
+
+	cues, err := parseSRT(text)
+	if err != nil {
+		redacted, counts := r.Redact(string(text))
+		return []byte(redacted), counts
+	}
+
+	rules := r.rules()
+	counts := newRedactionCounts(rules)
+	for i, c := range cues {
+		cues[i].Text = r.redact(c.Text, rules, counts)
+	}
+	return formatSRT(cues), counts
+}
+
+// isCardNumber reports whether text[start:end] is a whole run of digits
+// passing the Luhn check.
+func isCardNumber(text string, start, end int) bool {
+	return isDigitRun(text, start, end) && luhnValid(text[start:end])
+}
+
+// isDigitRun reports whether text[start:end] isn't part of a longer run
+// of digit groups, such as a phone number within a card number.
+func isDigitRun(text string, start, end int) bool {
+	isDigit := func(i int) bool { return i >= 0 && i < len(text) && text[i] >= '0' && text[i] <= '9' }
+	isSep := func(i int) bool { return i >= 0 && i < len(text) && strings.IndexByte(" .-", text[i]) >= 0 }
+
+	before := isDigit(start-1) || (isSep(start-1) && isDigit(start-2))
+	after := isDigit(end) || (isSep(end) && isDigit(end+1))
+	return !before && !after
+}
+
+// luhnValid reports whether the digits of s pass the Luhn check.
+func luhnValid(s string) bool {
+	var sum, n int
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
+
+// redact masks the personal data in text, a transcription of type t,
+// if a redactor is set.
+func (s *Scriber) redact(text []byte, t OutputType) ([]byte, map[RedactionClass]int) {
+	if s.redactor == nil || text == nil {
+		return text, nil
+	}
+	return s.redactor.redactTranscription(text, t)
+}
+
+// redactionMetadata adds the redaction counts to md.
+func redactionMetadata(md map[string]string, counts map[RedactionClass]int) {
+	for class, n := range counts {
+		md[MetadataRedactedPrefix+string(class)] = strconv.Itoa(n)
+	}
+}
@@ -0,0 +1,146 @@
+package scriber
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactor_Redact(t *testing.T) {
+	t.Parallel()
+
+	all := Redactor{Emails: true, PhoneNumbers: true, CardNumbers: true}
+
+	testCases := []struct {
+		name           string
+		givenRedactor  Redactor
+		givenText      string
+		expectedText   string
+		expectedCounts map[RedactionClass]int
+	}{
+		{
+			name:           "email",
+			givenRedactor:  all,
+			givenText:      "Write to Jane.Doe+news@mail.example.co.uk today.",
+			expectedText:   "Write to [EMAIL] today.",
+			expectedCounts: map[RedactionClass]int{RedactEmail: 1, RedactPhone: 0, RedactCardNumber: 0},
+		},
+		{
+			name:           "phone numbers",
+			givenRedactor:  all,
+			givenText:      "Call +1 415-555-2671, (415) 555 2671, 415.555.2671 or +14155552671.",
+			expectedText:   "Call [PHONE], [PHONE], [PHONE] or [PHONE].",
+			expectedCounts: map[RedactionClass]int{RedactEmail: 0, RedactPhone: 4, RedactCardNumber: 0},
+		},
+		{
+			name:           "card numbers",
+			givenRedactor:  all,
+			givenText:      "My card is 4111 1111 1111 1111 and the other 5500-0000-0000-0004.",
+			expectedText:   "My card is [CARD_NUMBER] and the other [CARD_NUMBER].",
+			expectedCounts: map[RedactionClass]int{RedactEmail: 0, RedactPhone: 0, RedactCardNumber: 2},
+		},
+		{
+			name:           "near misses",
+			givenRedactor:  all,
+			givenText:      "Mail user@localhost or @scriber. It cost 1,000,000 in 2024-03-15 at 10:30:45, v1.2.3, card 4111 1111 1111 1112.",
+			expectedText:   "Mail user@localhost or @scriber. It cost 1,000,000 in 2024-03-15 at 10:30:45, v1.2.3, card 4111 1111 1111 1112.",
+			expectedCounts: map[RedactionClass]int{RedactEmail: 0, RedactPhone: 0, RedactCardNumber: 0},
+		},
+		{
+			name:           "disabled classes",
+			givenRedactor:  Redactor{Emails: true},
+			givenText:      "jane@example.com, +1 415-555-2671",
+			expectedText:   "[EMAIL], +1 415-555-2671",
+			expectedCounts: map[RedactionClass]int{RedactEmail: 1},
+		},
+		{
+			name: "replacements",
+			givenRedactor: Redactor{
+				Emails:       true,
+				PhoneNumbers: true,
+				Replacements: map[RedactionClass]string{RedactEmail: "***"},
+			},
+			givenText:      "jane@example.com, +1 415-555-2671",
+			expectedText:   "***, [PHONE]",
+			expectedCounts: map[RedactionClass]int{RedactEmail: 1, RedactPhone: 1},
+		},
+		{
+			name: "custom rules",
+			givenRedactor: Redactor{
+				Emails: true,
+				Rules: []RedactionRule{
+					{Class: "account", Pattern: regexp.MustCompile(`\bACC-\d{6}\b`)},
+					{Class: "name", Pattern: regexp.MustCompile(`\bJane\b`), Replacement: "<name>"},
+				},
+			},
+			givenText:      "Jane's account ACC-123456, not ACC-12345, jane@example.com",
+			expectedText:   "<name>'s account [ACCOUNT], not ACC-12345, [EMAIL]",
+			expectedCounts: map[RedactionClass]int{RedactEmail: 1, "account": 1, "name": 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			text, counts := tc.givenRedactor.Redact(tc.givenText)
+			assert.Equal(t, tc.expectedText, text)
+			assert.Equal(t, tc.expectedCounts, counts)
+		})
+	}
+}
+
+func TestRedactor_Subtitles(t *testing.T) {
+	t.Parallel()
+
+	r := Redactor{PhoneNumbers: true, CardNumbers: true}
+
+	given := "1\n00:00:01,000 --> 00:00:02,500\nCall 415 555 2671\n\n2\n00:10:00,000 --> 00:10:04,000\nor pay with\n4111 1111 1111 1111\n"
+
+	text, counts := r.redactTranscription([]byte(given), OutputTypeSubtitles)
+	assert.Equal(t, "1\n00:00:01,000 --> 00:00:02,500\nCall [PHONE]\n\n2\n00:10:00,000 --> 00:10:04,000\nor pay with\n[CARD_NUMBER]\n", string(text))
+	assert.Equal(t, map[RedactionClass]int{RedactPhone: 1, RedactCardNumber: 1}, counts)
+}
+
+func TestProcess_Redactor(t *testing.T) {
+	t.Parallel()
+
+	const given = "1\n00:00:00,000 --> 00:00:02,000\nMail jane@example.com\n\n2\n00:00:02,000 --> 00:00:04,000\nor call 415-555-2671.\n"
+
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.Copy(io.Discard, in.Data)
+			return []byte(given), err
+		},
+	},
+		WithRedactor(Redactor{Emails: true, PhoneNumbers: true, CardNumbers: true}),
+		WithRawText(true),
+		WithConverter(copyConverter),
+	)
+
+	err := s.Process(context.TODO(), Input{
+		Name:       "talk.mp4",
+		OutputType: OutputTypeSBV,
+		Language:   "en",
+		Data:       io.NopCloser(strings.NewReader("data")),
+	})
+	require.NoError(t, err)
+
+	out := <-s.Collect()
+	require.NoError(t, out.Body.Close())
+
+	assert.Equal(t, "0:00:00.000,0:00:02.000\nMail [EMAIL]\n\n0:00:02.000,0:00:04.000\nor call [PHONE].\n", string(out.Text))
+	assert.Equal(t, "Mail [EMAIL]\nor call [PHONE].", out.PlainText)
+	assert.NotContains(t, string(out.RawText), "jane@example.com")
+	assert.NotContains(t, string(out.RawText), "2671")
+
+	assert.Equal(t, "1", out.Metadata[MetadataRedactedPrefix+string(RedactEmail)])
+	assert.Equal(t, "1", out.Metadata[MetadataRedactedPrefix+string(RedactPhone)])
+	assert.Equal(t, "0", out.Metadata[MetadataRedactedPrefix+string(RedactCardNumber)])
+}
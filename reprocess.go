@@ -0,0 +1,65 @@
+package scriber
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Reprocess transcribes the WAV file at wavPath, e.g. the converted audio
+// of an earlier job, without converting it, then post-processes and
+// publishes the outputs as Process does. in describes the original input:
+// its name, output type, and languages. Its Data is not read, and is
+// closed unless KeepOpen is set.
+//
+// This allows re-running the transcription of a job, e.g. to compare
+// backend settings, without the cost of converting it again.
+func (s *Scriber) Reprocess(ctx context.Context, wavPath string, in Input) error {
+	if in.Data != nil && !in.KeepOpen {
+		in.Data.Close()
+	}
+
+	audio, err := os.Open(wavPath)
+	if err != nil {
+		return asProcessError(StageValidation, in, fmt.Errorf("could not open audio: %w", err))
+	}
+	in.Data, in.DataRef, in.KeepOpen = audio, "", false
+
+	_, err = s.run(ctx, in, nil, func(ctx context.Context, j *job) error {
+		j.callerAudio()
+		if err := s.checkConfig(); err != nil {
+			return s.fail(j, StageValidation, err)
+		}
+
+		_, dataOffset, dataLen, err := wavFileLayout(audio)
+		if err != nil {
+			return s.fail(j, StageValidation, fmt.Errorf("invalid audio: %w", err))
+		}
+		if dataLen == 0 {
+			return s.fail(j, StageValidation, fmt.Errorf("invalid audio: %w", errEmptyAudio))
+		}
+
+		if j.in.Language == LanguageAuto {
+			sample, err := clipWAV(io.NewSectionReader(audio, 0, dataOffset+dataLen), languageProbeLength)
+			if err != nil {
+				return s.fail(j, StageTranscription, err)
+			}
+
+			lang, err := s.probeSampleLanguage(ctx, j, sample)
+			if err != nil {
+				return s.fail(j, StageTranscription, err)
+			}
+
+			j.logger.Info("Detected language", slog.String("file", j.in.Name), slog.String("language", lang))
+			j.in.Language = lang
+		}
+
+		if len(j.in.Languages) > 0 {
+			return s.processLanguagesFile(ctx, j, audio)
+		}
+		return s.processLanguage(ctx, j, audio)
+	})
+	return err
+}
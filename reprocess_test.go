@@ -0,0 +1,148 @@
+package scriber
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alesr/scriber/wav"
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScriber_Reprocess(t *testing.T) {
+	t.Parallel()
+
+	audio := syntheticWAV(60)
+
+	testCases := []struct {
+		name              string
+		givenFile         []byte // The file isn't created when nil.
+		givenLanguage     string
+		givenLanguages    []string
+		expectedNames     []string
+		expectedLanguages []string // Languages of the transcription calls.
+		expectedErr       error
+	}{
+		{
+			name:              "single language",
+			givenFile:         audio,
+			givenLanguage:     "en",
+			expectedNames:     []string{"talk.srt"},
+			expectedLanguages: []string{"en"},
+		},
+		{
+			name:              "several languages",
+			givenFile:         audio,
+			givenLanguages:    []string{"en", "pt"},
+			expectedNames:     []string{"talk.en.srt", "talk.pt.srt"},
+			expectedLanguages: []string{"en", "pt"},
+		},
+		{
+			name:              "detected language",
+			givenFile:         audio,
+			givenLanguage:     LanguageAuto,
+			expectedNames:     []string{"talk.srt"},
+			expectedLanguages: []string{"", "pt"},
+		},
+		{
+			name:          "not a wav file",
+			givenFile:     []byte("ID3 not a wav file at all, but an mp3 with a long enough header"),
+			givenLanguage: "en",
+			expectedErr:   wav.ErrNotWAV,
+		},
+		{
+			name:          "no samples",
+			givenFile:     syntheticWAV(0),
+			givenLanguage: "en",
+			expectedErr:   errEmptyAudio,
+		},
+		{
+			name:          "missing file",
+			givenLanguage: "en",
+			expectedErr:   os.ErrNotExist,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "audio.wav")
+			if tc.givenFile != nil {
+				require.NoError(t, os.WriteFile(path, tc.givenFile, 0o600))
+			}
+
+			var (
+				mu        sync.Mutex
+				languages []string
+			)
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					data, err := io.ReadAll(in.Data)
+					require.NoError(t, err)
+
+					mu.Lock()
+					languages = append(languages, in.Language)
+					mu.Unlock()
+
+					if in.Format == formatVerboseJSON {
+						return []byte(`{"language":"portuguese"}`), nil
+					}
+					assert.Equal(t, audio, data)
+					return []byte("1\n00:00:00,000 --> 00:00:01,000\nhello\n"), nil
+				},
+			}
+
+			s := New(noopLogger(), mockClient,
+				WithConverter(func(context.Context, io.Reader, io.Writer) error {
+					t.Error("the audio was converted")
+					return nil
+				}),
+			)
+
+			data := &closeCounter{Reader: strings.NewReader("media")}
+			err := s.Reprocess(context.TODO(), path, Input{
+				Name:       "talk.mp4",
+				OutputType: OutputTypeSubtitles,
+				Language:   tc.givenLanguage,
+				Languages:  tc.givenLanguages,
+				Data:       data,
+			})
+			assert.EqualValues(t, 1, data.closes.Load())
+
+			if tc.expectedErr != nil {
+				var pe *ProcessError
+				require.ErrorAs(t, err, &pe)
+				assert.Equal(t, StageValidation, pe.Stage)
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			var names []string
+			for range tc.expectedNames {
+				out := <-s.Collect()
+				require.NoError(t, out.Body.Close())
+
+				names = append(names, out.Name)
+				assert.Equal(t, "hello", out.PlainText)
+				assert.Equal(t, time.Minute, out.AudioDuration)
+				assert.EqualValues(t, len(audio), out.ConvertedBytes)
+			}
+
+			slices.Sort(names)
+			slices.Sort(languages)
+			assert.Equal(t, tc.expectedNames, names)
+			assert.Equal(t, tc.expectedLanguages, languages)
+		})
+	}
+}
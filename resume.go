@@ -0,0 +1,111 @@
+package scriber
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WithChunkResume persists the transcription of each chunk to dir as it
+// completes, so that resubmitting an input whose chunked transcription
+// was interrupted, e.g. by a crash, only transcribes the chunks missing
+// and stitches them with those of the previous run. Chunks are keyed by
+// a hash of their audio, language, and output type, so the same input
+// converted the same way resumes whatever its name. The chunks of a job
+// are removed once it succeeds. See Output.ResumedChunks.
+func WithChunkResume(dir string) Option {
+	return func(s *Scriber) {
+		s.chunkStore = &chunkStore{dir: dir}
+	}
+}
+
+// chunkStore holds the chunk transcriptions of interrupted jobs.
+type chunkStore struct {
+	dir string
+}
+
+// key returns the key of the chunk audio read from r, transcribed for j.
+func (c *chunkStore) key(j *job, r io.Reader) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", j.in.OutputType, j.in.Language)
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("could not hash chunk: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c *chunkStore) path(key string) string {
+	return filepath.Join(c.dir, "scriber-chunk-"+key+".txt")
+}
+
+// load returns the transcription stored under key, if any.
+func (c *chunkStore) load(key string) (string, bool, error) {
+	text, err := os.ReadFile(c.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("could not read chunk: %w", err)
+	}
+	return string(text), true, nil
+}
+
+func (c *chunkStore) save(key, text string) error {
+	if err := writeFileAtomic(c.path(key), strings.NewReader(text)); err != nil {
+		return fmt.Errorf("could not save chunk: %w", err)
+	}
+	return nil
+}
+
+func (c *chunkStore) remove(keys []string) {
+	for _, key := range keys {
+		os.Remove(c.path(key))
+	}
+}
+
+// resumeWindows keys the windows and loads the transcriptions stored
+// by a previous run, logging the resume decision. It returns nil keys
+// without a chunk store.
+func (s *Scriber) resumeWindows(j *job, open func(w chunkWindow) io.Reader, windows []chunkWindow) ([]string, []string, error) {
+	if s.chunkStore == nil {
+		return nil, nil, nil
+	}
+
+	keys := make([]string, len(windows))
+	results := make([]string, len(windows))
+	resumed := 0
+	for i, w := range windows {
+		key, err := s.chunkStore.key(j, open(w))
+		if err != nil {
+			return nil, nil, err
+		}
+		keys[i] = key
+
+		text, ok, err := s.chunkStore.load(key)
+		if err != nil {
+			j.logger.Warn("Could not load chunk, transcribing it again", slog.Int("chunk", i), slog.String("error", err.Error()))
+			continue
+		}
+		if ok {
+			results[i] = text
+			resumed++
+		}
+	}
+
+	j.resumedChunks = resumed
+	if resumed > 0 {
+		j.logger.Info("Resuming chunked transcription",
+			slog.String("file", j.in.Name),
+			slog.Int("reused_chunks", resumed),
+			slog.Int("chunks", len(windows)),
+		)
+	}
+	return keys, results, nil
+}
@@ -0,0 +1,95 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alesr/scriber/wav"
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_ChunkResume(t *testing.T) {
+	t.Parallel()
+
+	const (
+		seconds  = 5
+		failedAt = 3
+	)
+
+	var (
+		calls atomic.Int32
+		fail  atomic.Bool
+	)
+	fail.Store(true)
+
+	mockClient := &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			calls.Add(1)
+
+			// The first sample identifies the second the chunk starts at.
+			data, err := io.ReadAll(in.Data)
+			require.NoError(t, err)
+			second := int(data[wav.HeaderSize])
+
+			if fail.Load() && second == failedAt {
+				return nil, assert.AnError
+			}
+			return []byte(fmt.Sprint(second)), nil
+		},
+	}
+
+	dir := t.TempDir()
+	s := New(noopLogger(), mockClient,
+		WithChunking(ChunkConfig{Length: time.Second}),
+		WithChunkResume(dir),
+	)
+	s.convertToWavFunc = func(_ context.Context, r io.Reader, w io.Writer) error {
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			return err
+		}
+		_, err := w.Write(syntheticWAV(seconds))
+		return err
+	}
+
+	process := func() error {
+		return s.Process(context.TODO(), Input{
+			Name:       "long.mp4",
+			OutputType: OutputTypeTranscript,
+			Language:   "en",
+			Data:       io.NopCloser(bytes.NewBufferString("foo")),
+		})
+	}
+
+	// The first run is interrupted at the chunk failing.
+	require.ErrorIs(t, process(), assert.AnError)
+	assert.Equal(t, int32(failedAt+1), calls.Load())
+
+	stored, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, stored, failedAt)
+
+	// The rerun only transcribes the chunks missing.
+	calls.Store(0)
+	fail.Store(false)
+
+	require.NoError(t, process())
+
+	out := <-s.Collect()
+	require.NoError(t, out.Body.Close())
+
+	assert.Equal(t, "0 1 2 3 4", string(out.Text))
+	assert.Equal(t, failedAt, out.ResumedChunks)
+	assert.Equal(t, int32(seconds-failedAt), calls.Load())
+
+	stored, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, stored, "the chunks are removed once the job succeeds")
+}
@@ -0,0 +1,399 @@
+package scriber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// RetryConfig configures retrying failed transcriptions.
+type RetryConfig struct {
+	// Attempts is the maximum number of transcription attempts,
+	// the first one included.
+	Attempts int
+
+	// Backoff is the delay between attempts.
+	Backoff time.Duration
+
+	// SpoolLimit caps the size, in bytes, of the converted audio kept
+	// in a temporary file during the first attempt, so that retries
+	// don't need to convert the input again. Zero means no limit.
+	SpoolLimit int64
+
+	// Reconvert makes retries convert the input again when the converted
+	// audio exceeded SpoolLimit. Inputs that don't implement io.Seeker are
+	// then spooled to a temporary file up front. Without Reconvert, such
+	// jobs fail after the first attempt.
+	Reconvert bool
+
+	// MaxRetryAfter caps the delay before retrying a transcription that
+	// failed with a RetryAfterError, which replaces Backoff. Zero
+	// defaults to one minute.
+	MaxRetryAfter time.Duration
+}
+
+func (c RetryConfig) validate() error {
+	if c.Attempts < 1 {
+		return errors.New("attempts must be positive")
+	}
+	if c.Backoff < 0 || c.SpoolLimit < 0 || c.MaxRetryAfter < 0 {
+		return errors.New("backoff, spool limit and max retry after must not be negative")
+	}
+	return nil
+}
+
+// WithRetry retries transcriptions that fail at the transcription stage.
+// Streamed jobs retry from a spool of the converted audio, and chunked jobs
+// retry each failed chunk. Conversion failures and cancellations are never retried.
+func WithRetry(cfg RetryConfig) Option {
+	return func(s *Scriber) {
+		s.retry = &cfg
+	}
+}
+
+// RetryDecision is what to do about a failed transcription.
+type RetryDecision int
+
+const (
+	// RetryDecisionRetry retries the transcription, attempts permitting.
+	RetryDecisionRetry RetryDecision = iota
+
+	// RetryDecisionFail fails the job without retrying.
+	RetryDecisionFail
+
+	// RetryDecisionFallback fails the job without retrying, with an error
+	// wrapping a FallbackError, so that the caller can route the input
+	// to another backend.
+	RetryDecisionFallback
+)
+
+func (d RetryDecision) String() string {
+	switch d {
+	case RetryDecisionRetry:
+		return "retry"
+	case RetryDecisionFail:
+		return "fail"
+	case RetryDecisionFallback:
+		return "fallback"
+	default:
+		return fmt.Sprintf("RetryDecision(%d)", int(d))
+	}
+}
+
+// WithRetryClassifier sets the function deciding whether a transcription
+// backend error is transient. It defaults to DefaultRetryClassifier.
+// Errors from other stages and cancellations are never retried. Every
+// failed transcription is classified, the last attempt included, and
+// without WithRetry too, so that RetryDecisionFallback and
+// RetryDecisionFail apply whatever the attempts left.
+func WithRetryClassifier(fn func(error) RetryDecision) Option {
+	return func(s *Scriber) {
+		s.retryClassifier = fn
+	}
+}
+
+// DefaultRetryClassifier retries timeouts, network errors, RetryAfterErrors,
+// and errors carrying a retryable HTTP status code (408, 429, or 5xx)
+// through a StatusCode() int method. Other errors with an HTTP status code,
+// cancellations, inputs over their size limits, and jobs out of time
+// before their deadline fail. Unrecognized errors are retried.
+func DefaultRetryClassifier(err error) RetryDecision {
+	var (
+		tooLarge InputTooLargeError
+		mismatch SizeMismatchError
+		deadline DeadlineExceededError
+		status   interface{ StatusCode() int }
+		netErr   net.Error
+		after    *RetryAfterError
+	)
+
+	switch {
+	case errors.Is(err, context.Canceled),
+		errors.As(err, &tooLarge),
+		errors.As(err, &mismatch),
+		errors.As(err, &deadline):
+		return RetryDecisionFail
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, errTranscriptionTimeout),
+		errors.As(err, &netErr),
+		errors.As(err, &after):
+		return RetryDecisionRetry
+	case errors.As(err, &status):
+		code := status.StatusCode()
+		if code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500 {
+			return RetryDecisionRetry
+		}
+		return RetryDecisionFail
+	default:
+		return RetryDecisionRetry
+	}
+}
+
+// retryable reports whether a transcription of j that failed with err may
+// be retried, as decided by the retry classifier, and more attempts are
+// left. It returns err, marked for fallback if so decided, which is
+// applied even when no attempt is left.
+func (s *Scriber) retryable(ctx context.Context, j *job, err error, more bool) (bool, error) {
+	if ctx.Err() != nil {
+		return false, err
+	}
+
+	var pe *ProcessError
+	if errors.As(err, &pe) && pe.Stage != StageTranscription {
+		return false, err
+	}
+
+	decision, panicErr := s.classifyRetry(err)
+	if panicErr != nil {
+		return false, wrapStaged(err, func(err error) error { return errors.Join(err, panicErr) })
+	}
+
+	j.logger.Info("Classified transcription error",
+		slog.String("file", j.in.Name),
+		slog.String("decision", decision.String()),
+		slog.String("error", err.Error()),
+	)
+
+	switch decision {
+	case RetryDecisionRetry:
+		if !more {
+			return false, err
+		}
+		if b := retryBudgetFromContext(ctx); b != nil && !b.take() {
+			j.logger.Warn("Batch retry budget exhausted, not retrying", slog.String("file", j.in.Name))
+			return false, wrapStaged(err, func(err error) error { return fmt.Errorf("%w: %w", errRetryBudget, err) })
+		}
+		return true, err
+	case RetryDecisionFallback:
+		return false, wrapStaged(err, func(err error) error { return fmt.Errorf("%w: %w", errFallback, err) })
+	default:
+		return false, err
+	}
+}
+
+// backoff waits for the delay the backend asked for with err, if any, or
+// else for the configured backoff, or until ctx is done.
+func (s *Scriber) backoff(ctx context.Context, err error) error {
+	delay, ok := s.retryAfterDelay(err)
+	if !ok {
+		delay = s.retry.Backoff
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// convertAndTranscribeWithRetry streams the job's input as convertAndTranscribe
+// does while spooling the converted audio, and retries failed transcriptions
+// from the spool, or by converting the input again if the spool overflowed.
+func (s *Scriber) convertAndTranscribeWithRetry(ctx context.Context, j *job) ([]byte, error) {
+	if _, ok := j.in.Data.(io.Seeker); s.retry.Reconvert && !ok {
+		data, err := spoolInput(j.in.Data, s.spoolDir, s.buffers())
+		if err != nil {
+			return nil, stageError(StageConversion, err)
+		}
+		defer func() {
+			data.Close()
+			os.Remove(data.Name())
+		}()
+		j.in.Data = data
+	}
+
+	attempt := 0
+	for {
+		spool, err := newAudioSpool(s.spoolDir, s.retry.SpoolLimit)
+		if err != nil {
+			return nil, stageError(StageConversion, err)
+		}
+
+		attempt++
+		text, err := s.convertAndTranscribe(ctx, j, spool)
+
+		for err != nil && spool.complete {
+			var retry bool
+			if retry, err = s.retryable(ctx, j, err, attempt < s.retry.Attempts); !retry {
+				break
+			}
+
+			j.logger.Warn("Transcription failed, retrying from spooled audio",
+				slog.String("file", j.in.Name),
+				slog.Int("attempt", attempt),
+				slog.String("error", err.Error()),
+			)
+			if err := s.backoff(ctx, err); err != nil {
+				spool.Close()
+				return nil, stageError(StageTranscription, err)
+			}
+
+			attempt++
+			text, err = s.transcribeSpool(ctx, j, spool)
+		}
+		spool.Close()
+
+		if err == nil || spool.complete {
+			return text, err
+		}
+		if retry, err := s.retryable(ctx, j, err, attempt < s.retry.Attempts && s.retry.Reconvert); !retry {
+			return nil, err
+		}
+
+		j.logger.Warn("Transcription failed and the converted audio exceeded the spool limit, converting again",
+			slog.String("file", j.in.Name),
+			slog.Int("attempt", attempt),
+			slog.String("error", err.Error()),
+		)
+		if err := s.backoff(ctx, err); err != nil {
+			return nil, stageError(StageTranscription, err)
+		}
+		if err := j.rewind(); err != nil {
+			return nil, stageError(StageConversion, fmt.Errorf("could not retry: %w", err))
+		}
+	}
+}
+
+// transcribeRetrying transcribes the audio returned by open, which must
+// return a fresh reader on every call, retrying as configured with WithRetry.
+// audio is the duration of the audio. attrs are added to the retry log lines.
+func (s *Scriber) transcribeRetrying(ctx context.Context, j *job, open func() io.Reader, audio time.Duration, attrs ...slog.Attr) ([]byte, error) {
+	attempts := 1
+	if s.retry != nil {
+		attempts = s.retry.Attempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		text, err := s.transcribeAudio(ctx, j, newPooledReader(open(), s.buffers()), fixedDuration(audio))
+		if err == nil {
+			return text, nil
+		}
+		if retry, err := s.retryable(ctx, j, err, attempt < attempts); !retry {
+			return nil, err
+		}
+
+		args := append(attrsToArgs(attrs),
+			slog.String("file", j.in.Name),
+			slog.Int("attempt", attempt),
+			slog.String("error", err.Error()),
+		)
+		j.logger.Warn("Transcription failed, retrying", args...)
+
+		if err := s.backoff(ctx, err); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// transcribeSpool transcribes the converted audio held in spool.
+func (s *Scriber) transcribeSpool(ctx context.Context, j *job, spool *audioSpool) ([]byte, error) {
+	audio, err := spool.reader()
+	if err != nil {
+		return nil, stageError(StageTranscription, err)
+	}
+
+	start := time.Now()
+	text, err := s.transcribeAudio(ctx, j, newPooledReader(audio, s.buffers()), fixedDuration(j.audioDuration))
+	j.timing.Transcribe += time.Since(start)
+	if err != nil {
+		return nil, stageError(StageTranscription, fmt.Errorf("could not transcribe audio: %w", err))
+	}
+	return text, nil
+}
+
+// audioSpool is a size-capped temporary file holding converted audio.
+// Writes past the cap are dropped and the spool is marked as overflowed.
+type audioSpool struct {
+	f     *os.File
+	limit int64
+	n     int64
+
+	overflow bool
+
+	// complete is set once the conversion wrote all of the audio.
+	complete bool
+}
+
+func newAudioSpool(dir string, limit int64) (*audioSpool, error) {
+	f, err := os.CreateTemp(dir, "scriber-audio-*.wav")
+	if err != nil {
+		return nil, fmt.Errorf("could not create audio spool: %w", err)
+	}
+	return &audioSpool{f: f, limit: limit}, nil
+}
+
+// Write writes p to the spool. It never fails: a write error
+// or exceeding the cap marks the spool as overflowed instead.
+func (s *audioSpool) Write(p []byte) (int, error) {
+	if s.overflow {
+		return len(p), nil
+	}
+
+	if s.limit > 0 && s.n+int64(len(p)) > s.limit {
+		s.overflow = true
+		// The partial audio is useless; free the disk space early.
+		_ = s.f.Truncate(0)
+		return len(p), nil
+	}
+
+	n, err := s.f.Write(p)
+	s.n += int64(n)
+	if err != nil {
+		s.overflow = true
+	}
+	return len(p), nil
+}
+
+// reader returns a reader over the spooled audio.
+func (s *audioSpool) reader() (io.Reader, error) {
+	if _, err := s.f.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("could not rewind audio spool: %w", err)
+	}
+	return io.LimitReader(s.f, s.n), nil
+}
+
+// Close closes and removes the spool file.
+func (s *audioSpool) Close() error {
+	closeErr := s.f.Close()
+	if err := os.Remove(s.f.Name()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not remove audio spool: %w", err)
+	}
+	return closeErr
+}
+
+// spoolTee writes to w and to a spool. Once writing to w fails,
+// writes keep going to the spool alone, so that the conversion can
+// finish for a retry, unless the spool has overflowed.
+type spoolTee struct {
+	w     io.Writer
+	spool *audioSpool
+	err   error
+}
+
+func (t *spoolTee) Write(p []byte) (int, error) {
+	t.spool.Write(p)
+
+	if t.err == nil {
+		if _, err := t.w.Write(p); err != nil {
+			t.err = err
+		}
+	}
+
+	if t.err != nil && t.spool.overflow {
+		return 0, t.err
+	}
+	return len(p), nil
+}
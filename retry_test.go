@@ -0,0 +1,513 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_Retry(t *testing.T) {
+	t.Parallel()
+
+	wav := syntheticWAV(5)
+
+	testCases := []struct {
+		name                string
+		givenRetry          RetryConfig
+		givenFailures       int32 // Number of failing transcription calls.
+		givenConvertErr     error
+		givenSeekable       bool
+		givenSkipped        string // Data of a seekable input read before it's handed in.
+		expectedErr         bool
+		expectedCalls       int32
+		expectedConversions int32
+	}{
+		{
+			name:                "retries from the spool",
+			givenRetry:          RetryConfig{Attempts: 3},
+			givenFailures:       2,
+			expectedCalls:       3,
+			expectedConversions: 1,
+		},
+		{
+			name:                "gives up after the last attempt",
+			givenRetry:          RetryConfig{Attempts: 2},
+			givenFailures:       5,
+			expectedErr:         true,
+			expectedCalls:       2,
+			expectedConversions: 1,
+		},
+		{
+			name:                "spool limit exceeded without reconversion",
+			givenRetry:          RetryConfig{Attempts: 3, SpoolLimit: 100},
+			givenFailures:       1,
+			expectedErr:         true,
+			expectedCalls:       1,
+			expectedConversions: 1,
+		},
+		{
+			name:                "spool limit exceeded with reconversion",
+			givenRetry:          RetryConfig{Attempts: 3, SpoolLimit: 100, Reconvert: true},
+			givenFailures:       1,
+			expectedCalls:       2,
+			expectedConversions: 2,
+		},
+		{
+			name:                "spool limit exceeded with reconversion of seekable input",
+			givenRetry:          RetryConfig{Attempts: 3, SpoolLimit: 100, Reconvert: true},
+			givenFailures:       1,
+			givenSeekable:       true,
+			expectedCalls:       2,
+			expectedConversions: 2,
+		},
+		{
+			name:                "spool limit exceeded with reconversion of input handed in mid-stream",
+			givenRetry:          RetryConfig{Attempts: 3, SpoolLimit: 100, Reconvert: true},
+			givenFailures:       1,
+			givenSeekable:       true,
+			givenSkipped:        "header",
+			expectedCalls:       2,
+			expectedConversions: 2,
+		},
+		{
+			name:                "conversion failures aren't retried",
+			givenRetry:          RetryConfig{Attempts: 3},
+			givenConvertErr:     assert.AnError,
+			expectedErr:         true,
+			expectedCalls:       1,
+			expectedConversions: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+
+			var calls, conversions atomic.Int32
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					if calls.Add(1) <= tc.givenFailures {
+						// Fail mid-upload.
+						_, _ = in.Data.Read(make([]byte, 10))
+						return nil, assert.AnError
+					}
+
+					data, err := io.ReadAll(in.Data)
+					require.NoError(t, err)
+					assert.Equal(t, wav, data)
+					return []byte("text"), nil
+				},
+			}
+
+			s := New(noopLogger(), mockClient,
+				WithRetry(tc.givenRetry),
+				WithSpoolThreshold(0, dir),
+				WithConverter(func(_ context.Context, r io.Reader, w io.Writer) error {
+					conversions.Add(1)
+					input, err := io.ReadAll(r)
+					if err != nil {
+						return err
+					}
+					assert.Equal(t, "media", string(input))
+
+					if _, err := w.Write(wav); err != nil {
+						return err
+					}
+					return tc.givenConvertErr
+				}),
+			)
+
+			var data io.ReadCloser = io.NopCloser(bytes.NewBufferString("media"))
+			if tc.givenSeekable {
+				r := bytes.NewReader([]byte(tc.givenSkipped + "media"))
+				_, err := r.Seek(int64(len(tc.givenSkipped)), io.SeekStart)
+				require.NoError(t, err)
+				data = readSeekNopCloser{r}
+			}
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "test.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       data,
+			})
+
+			assert.Equal(t, tc.expectedCalls, calls.Load())
+			assert.Equal(t, tc.expectedConversions, conversions.Load())
+
+			if tc.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+
+				out := <-s.Collect()
+				out.Body.Close()
+				assert.Equal(t, "text", string(out.Text))
+				assert.Equal(t, testWAVFormat.Duration(5*testWAVFormat.ByteRate()), out.AudioDuration)
+			}
+
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Empty(t, entries, "spool files should be removed")
+		})
+	}
+}
+
+func TestProcess_RetryChunks(t *testing.T) {
+	t.Parallel()
+
+	var calls, failed atomic.Int32
+
+	mockClient := &mockWhisperClient{
+		transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			calls.Add(1)
+			if _, err := io.Copy(io.Discard, in.Data); err != nil {
+				return nil, err
+			}
+			if failed.CompareAndSwap(0, 1) {
+				return nil, assert.AnError
+			}
+			return []byte("word"), nil
+		},
+	}
+
+	s := New(noopLogger(), mockClient,
+		WithRetry(RetryConfig{Attempts: 2, Backoff: time.Millisecond}),
+		WithChunking(ChunkConfig{Length: time.Second}),
+		WithConverter(func(_ context.Context, r io.Reader, w io.Writer) error {
+			_, err := w.Write(syntheticWAV(3))
+			return err
+		}),
+	)
+
+	err := s.Process(context.TODO(), Input{
+		Name:       "test.mp4",
+		OutputType: OutputTypeTranscript,
+		Language:   "en",
+		Data:       io.NopCloser(bytes.NewBufferString("media")),
+	})
+	require.NoError(t, err)
+
+	out := <-s.Collect()
+	out.Body.Close()
+
+	assert.EqualValues(t, 4, calls.Load())
+}
+
+func TestProcess_RetryInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	s := New(noopLogger(), &mockWhisperClient{}, WithRetry(RetryConfig{}))
+
+	err := s.Process(context.TODO(), Input{
+		Name:       "test.mp4",
+		OutputType: OutputTypeTranscript,
+		Language:   "en",
+		Data:       io.NopCloser(bytes.NewBufferString("media")),
+	})
+
+	var pe *ProcessError
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, StageValidation, pe.Stage)
+}
+
+func TestProcess_RetryClassifier(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		givenDecision    RetryDecision
+		expectedCalls    int32
+		expectedFallback bool
+	}{
+		{
+			name:          "retry",
+			givenDecision: RetryDecisionRetry,
+			expectedCalls: 3,
+		},
+		{
+			name:          "fail",
+			givenDecision: RetryDecisionFail,
+			expectedCalls: 1,
+		},
+		{
+			name:             "fallback",
+			givenDecision:    RetryDecisionFallback,
+			expectedCalls:    1,
+			expectedFallback: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls atomic.Int32
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					calls.Add(1)
+					_, err := io.Copy(io.Discard, in.Data)
+					require.NoError(t, err)
+					return nil, assert.AnError
+				},
+			}
+
+			handler := newCapturingHandler()
+
+			s := New(slog.New(handler), mockClient,
+				WithRetry(RetryConfig{Attempts: 3}),
+				WithRetryClassifier(func(err error) RetryDecision {
+					assert.ErrorIs(t, err, assert.AnError)
+					return tc.givenDecision
+				}),
+				WithConverter(copyConverter),
+			)
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "test.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewBufferString("media")),
+			})
+
+			var pe *ProcessError
+			require.ErrorAs(t, err, &pe)
+			assert.Equal(t, StageTranscription, pe.Stage)
+			assert.Equal(t, tc.expectedCalls, calls.Load())
+
+			var fallback FallbackError
+			assert.Equal(t, tc.expectedFallback, errors.As(err, &fallback))
+
+			var decisions []string
+			for _, e := range handler.entries() {
+				if e.msg == "Classified transcription error" {
+					decisions = append(decisions, e.attrs["scriber.decision"])
+				}
+			}
+			require.NotEmpty(t, decisions)
+			for _, d := range decisions {
+				assert.Equal(t, tc.givenDecision.String(), d)
+			}
+		})
+	}
+}
+
+func TestProcess_RetryClassifierLastAttempt(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		givenRetry     *RetryConfig
+		givenChunking  bool
+		givenDecisions []RetryDecision
+		expectedCalls  int32
+	}{
+		{
+			name:           "without retries",
+			givenDecisions: []RetryDecision{RetryDecisionFallback},
+			expectedCalls:  1,
+		},
+		{
+			name:           "last attempt",
+			givenRetry:     &RetryConfig{Attempts: 2},
+			givenDecisions: []RetryDecision{RetryDecisionRetry, RetryDecisionFallback},
+			expectedCalls:  2,
+		},
+		{
+			name:           "last chunk attempt",
+			givenRetry:     &RetryConfig{Attempts: 2},
+			givenChunking:  true,
+			givenDecisions: []RetryDecision{RetryDecisionRetry, RetryDecisionFallback},
+			expectedCalls:  2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls atomic.Int32
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					calls.Add(1)
+					_, err := io.Copy(io.Discard, in.Data)
+					require.NoError(t, err)
+					return nil, assert.AnError
+				},
+			}
+
+			handler := newCapturingHandler()
+
+			var classified atomic.Int32
+			opts := []Option{
+				WithConverter(copyConverter),
+				WithRetryClassifier(func(err error) RetryDecision {
+					n := classified.Add(1)
+					return tc.givenDecisions[min(int(n), len(tc.givenDecisions))-1]
+				}),
+			}
+			if tc.givenRetry != nil {
+				opts = append(opts, WithRetry(*tc.givenRetry))
+			}
+			if tc.givenChunking {
+				opts = append(opts, WithChunking(ChunkConfig{Length: time.Minute}))
+			}
+
+			s := New(slog.New(handler), mockClient, opts...)
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "test.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+			})
+
+			var fallback FallbackError
+			require.ErrorAs(t, err, &fallback)
+			assert.ErrorIs(t, err, assert.AnError)
+			assert.Equal(t, tc.expectedCalls, calls.Load())
+
+			// Every attempt is classified, and its decision logged.
+			var decisions []string
+			for _, e := range handler.entries() {
+				if e.msg == "Classified transcription error" {
+					decisions = append(decisions, e.attrs["scriber.decision"])
+				}
+			}
+			var expected []string
+			for _, d := range tc.givenDecisions {
+				expected = append(expected, d.String())
+			}
+			assert.Equal(t, expected, decisions)
+		})
+	}
+}
+
+type statusError int
+
+func (e statusError) Error() string   { return http.StatusText(int(e)) }
+func (e statusError) StatusCode() int { return int(e) }
+
+func TestDefaultRetryClassifier(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		givenErr error
+		expected RetryDecision
+	}{
+		{name: "canceled", givenErr: fmt.Errorf("upload: %w", context.Canceled), expected: RetryDecisionFail},
+		{name: "deadline", givenErr: context.DeadlineExceeded, expected: RetryDecisionRetry},
+		{name: "transcription timeout", givenErr: fmt.Errorf("%w: after 1s", errTranscriptionTimeout), expected: RetryDecisionRetry},
+		{name: "network", givenErr: fmt.Errorf("send: %w", &net.OpError{Op: "dial", Err: assert.AnError}), expected: RetryDecisionRetry},
+		{name: "too many requests", givenErr: statusError(http.StatusTooManyRequests), expected: RetryDecisionRetry},
+		{name: "request timeout", givenErr: statusError(http.StatusRequestTimeout), expected: RetryDecisionRetry},
+		{name: "server error", givenErr: fmt.Errorf("backend: %w", statusError(http.StatusBadGateway)), expected: RetryDecisionRetry},
+		{name: "client error", givenErr: statusError(http.StatusBadRequest), expected: RetryDecisionFail},
+		{name: "retry after", givenErr: &RetryAfterError{Delay: time.Second, Err: assert.AnError}, expected: RetryDecisionRetry},
+		{name: "input too large", givenErr: errInputTooLarge, expected: RetryDecisionFail},
+		{name: "unknown", givenErr: assert.AnError, expected: RetryDecisionRetry},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, DefaultRetryClassifier(tc.givenErr))
+		})
+	}
+}
+
+func TestAudioSpool(t *testing.T) {
+	t.Parallel()
+
+	t.Run("within the limit", func(t *testing.T) {
+		t.Parallel()
+
+		spool, err := newAudioSpool(t.TempDir(), 10)
+		require.NoError(t, err)
+		defer spool.Close()
+
+		_, _ = spool.Write([]byte("hello"))
+		_, _ = spool.Write([]byte("world"))
+		assert.False(t, spool.overflow)
+
+		r, err := spool.reader()
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "helloworld", string(data))
+	})
+
+	t.Run("over the limit", func(t *testing.T) {
+		t.Parallel()
+
+		spool, err := newAudioSpool(t.TempDir(), 8)
+		require.NoError(t, err)
+		defer spool.Close()
+
+		n, err := spool.Write([]byte("hello"))
+		require.NoError(t, err)
+		assert.Equal(t, 5, n)
+
+		n, err = spool.Write([]byte("world"))
+		require.NoError(t, err, "overflowing must not fail the conversion")
+		assert.Equal(t, 5, n)
+		assert.True(t, spool.overflow)
+
+		info, err := spool.f.Stat()
+		require.NoError(t, err)
+		assert.Zero(t, info.Size())
+	})
+
+	t.Run("close removes the file", func(t *testing.T) {
+		t.Parallel()
+
+		spool, err := newAudioSpool(t.TempDir(), 0)
+		require.NoError(t, err)
+		require.NoError(t, spool.Close())
+
+		_, err = os.Stat(spool.f.Name())
+		assert.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestSpoolTee(t *testing.T) {
+	t.Parallel()
+
+	spool, err := newAudioSpool(t.TempDir(), 0)
+	require.NoError(t, err)
+	defer spool.Close()
+
+	pr, pw := io.Pipe()
+	pr.Close()
+
+	tee := &spoolTee{w: pw, spool: spool}
+
+	// Writes keep going to the spool once the upload stops reading.
+	n, err := tee.Write([]byte("audio"))
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+
+	spool.overflow = true
+	_, err = tee.Write([]byte("more"))
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+}
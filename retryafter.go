@@ -0,0 +1,98 @@
+package scriber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultMaxRetryAfter caps the delays backends ask for by default.
+const defaultMaxRetryAfter = time.Minute
+
+// RetryAfterError is returned by transcription clients when the backend
+// asks to be retried after Delay, e.g. with a 429 response carrying a
+// Retry-After header. See RetryAfter.
+type RetryAfterError struct {
+	Delay time.Duration
+	Err   error
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("retry after %s: %v", e.Delay, e.Err)
+}
+
+func (e *RetryAfterError) Unwrap() error { return e.Err }
+
+// RetryAfter returns the delay the Retry-After header of h asks for, in
+// seconds or as an HTTP date relative to now, and whether it has one.
+func RetryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(max(secs, 0)) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// retryAfterDelay returns the delay err asks for, capped by
+// RetryConfig.MaxRetryAfter, and whether it asks for one.
+func (s *Scriber) retryAfterDelay(err error) (time.Duration, bool) {
+	var ra *RetryAfterError
+	if !errors.As(err, &ra) {
+		return 0, false
+	}
+
+	limit := defaultMaxRetryAfter
+	if s.retry != nil && s.retry.MaxRetryAfter > 0 {
+		limit = s.retry.MaxRetryAfter
+	}
+	return min(max(ra.Delay, 0), limit), true
+}
+
+// backendPause holds back new transcription requests while the backend
+// has asked not to be sent any.
+type backendPause struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// extend holds back requests for d from now, unless they already are
+// for longer.
+func (p *backendPause) extend(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(p.until) {
+		p.until = until
+	}
+}
+
+// wait returns once requests are no longer held back, or ctx is done.
+func (p *backendPause) wait(ctx context.Context) error {
+	p.mu.Lock()
+	d := time.Until(p.until)
+	p.mu.Unlock()
+
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,159 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, time.March, 15, 10, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		givenHeader   string
+		expectedDelay time.Duration
+		expectedOK    bool
+	}{
+		{name: "missing"},
+		{name: "seconds", givenHeader: "120", expectedDelay: 2 * time.Minute, expectedOK: true},
+		{name: "date", givenHeader: "Fri, 15 Mar 2024 10:30:30 GMT", expectedDelay: 30 * time.Second, expectedOK: true},
+		{name: "past date", givenHeader: "Fri, 15 Mar 2024 10:00:00 GMT", expectedOK: true},
+		{name: "invalid", givenHeader: "soon"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := http.Header{}
+			if tc.givenHeader != "" {
+				h.Set("Retry-After", tc.givenHeader)
+			}
+
+			delay, ok := RetryAfter(h, now)
+			assert.Equal(t, tc.expectedDelay, delay)
+			assert.Equal(t, tc.expectedOK, ok)
+		})
+	}
+}
+
+// scriptedWhisperClient answers with the scripted errors, in order,
+// then with a transcription. It records the time of each request.
+type scriptedWhisperClient struct {
+	mu       sync.Mutex
+	script   []error
+	requests []time.Time
+}
+
+func (c *scriptedWhisperClient) TranscribeAudio(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+	if _, err := io.Copy(io.Discard, in.Data); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.requests = append(c.requests, time.Now())
+	if len(c.script) > 0 {
+		err := c.script[0]
+		c.script = c.script[1:]
+		return nil, err
+	}
+	return []byte("hello"), nil
+}
+
+func TestProcess_RetryAfter(t *testing.T) {
+	t.Parallel()
+
+	tooManyRequests := func(delay time.Duration) error {
+		return &RetryAfterError{Delay: delay, Err: statusError(http.StatusTooManyRequests)}
+	}
+
+	testCases := []struct {
+		name          string
+		givenRetry    RetryConfig
+		givenDelay    time.Duration
+		expectedDelay time.Duration
+	}{
+		{
+			name:          "preferred to the backoff",
+			givenRetry:    RetryConfig{Attempts: 2, Backoff: time.Minute},
+			givenDelay:    100 * time.Millisecond,
+			expectedDelay: 100 * time.Millisecond,
+		},
+		{
+			name:          "capped",
+			givenRetry:    RetryConfig{Attempts: 2, Backoff: time.Minute, MaxRetryAfter: 100 * time.Millisecond},
+			givenDelay:    time.Hour,
+			expectedDelay: 100 * time.Millisecond,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := &scriptedWhisperClient{script: []error{tooManyRequests(tc.givenDelay)}}
+			s := New(noopLogger(), client, WithConverter(copyConverter), WithRetry(tc.givenRetry))
+
+			start := time.Now()
+			err := s.Process(context.TODO(), Input{
+				Name:       "talk.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+			})
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+			assert.Equal(t, "hello", string(out.Text))
+
+			require.Len(t, client.requests, 2)
+			waited := client.requests[1].Sub(client.requests[0])
+			assert.GreaterOrEqual(t, waited, tc.expectedDelay)
+			assert.Less(t, time.Since(start), 10*time.Second, "the backoff should not apply")
+		})
+	}
+}
+
+func TestProcess_RetryAfterPausesTranscriptions(t *testing.T) {
+	t.Parallel()
+
+	const delay = 200 * time.Millisecond
+
+	client := &scriptedWhisperClient{script: []error{
+		&RetryAfterError{Delay: delay, Err: statusError(http.StatusTooManyRequests)},
+	}}
+	s := New(noopLogger(), client, WithConverter(copyConverter))
+
+	process := func(name string) error {
+		return s.Process(context.TODO(), Input{
+			Name:       name,
+			OutputType: OutputTypeTranscript,
+			Language:   "en",
+			Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+		})
+	}
+
+	var ra *RetryAfterError
+	require.ErrorAs(t, process("first.mp4"), &ra)
+
+	require.NoError(t, process("second.mp4"))
+	out := <-s.Collect()
+	require.NoError(t, out.Body.Close())
+
+	require.Len(t, client.requests, 2)
+	assert.GreaterOrEqual(t, client.requests[1].Sub(client.requests[0]), delay, "new transcriptions should wait for the window")
+}
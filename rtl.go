@@ -0,0 +1,92 @@
+package scriber
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// rtlEmbedding (U+202B) starts a right-to-left embedding,
+	// which lasts until the end of the line.
+	rtlEmbedding = '\u202B'
+
+	// rtlMark (U+200F) is an invisible right-to-left character that
+	// keeps trailing punctuation on the right-to-left side of a line.
+	rtlMark = '\u200F'
+
+	// popDirectional (U+202C) ends an embedding.
+	popDirectional = '\u202C'
+)
+
+// rtlLanguages holds the codes of the right-to-left languages
+// Whisper transcribes, or may report.
+var rtlLanguages = map[string]bool{
+	"ar": true, // Arabic
+	"fa": true, // Persian
+	"he": true, // Hebrew
+	"iw": true, // Hebrew, former code
+	"ps": true, // Pashto
+	"sd": true, // Sindhi
+	"ur": true, // Urdu
+	"yi": true, // Yiddish
+}
+
+// isRTLLanguage reports whether lang, a language code
+// optionally followed by a region, is written right to left.
+func isRTLLanguage(lang string) bool {
+	code, _, _ := strings.Cut(strings.ToLower(lang), "-")
+	code, _, _ = strings.Cut(code, "_")
+	return rtlLanguages[code]
+}
+
+// WithRTLMarks adds Unicode bidi controls to the cue lines of subtitles in
+// right-to-left languages, such as Arabic and Hebrew, so that players that
+// don't detect the direction render them in order: each line starts with a
+// right-to-left embedding, and lines ending in punctuation end with a
+// right-to-left mark, keeping the punctuation on the left. The language is
+// the requested or the detected one. Leave it disabled, the default, if
+// bidi is handled downstream. Output.PlainText carries no marks.
+func WithRTLMarks(enabled bool) Option {
+	return func(s *Scriber) {
+		s.rtlMarks = enabled
+	}
+}
+
+// applyRTLMarks adds bidi controls to the lines of subtitles in lang
+// if it is a right-to-left language.
+func applyRTLMarks(text []byte, t OutputType, lang string) []byte {
+	if !isSubtitles(t) || !isRTLLanguage(lang) {
+		return text
+	}
+
+	cues, err := parseSRT(text)
+	if err != nil {
+		return text
+	}
+
+	for i, c := range cues {
+		lines := strings.Split(c.Text, "\n")
+		for k, line := range lines {
+			lines[k] = markRTLLine(line)
+		}
+		cues[i].Text = strings.Join(lines, "\n")
+	}
+	return formatSRT(cues)
+}
+
+// markRTLLine adds bidi controls to line, replacing any it already has
+// at either end so that marking is idempotent.
+func markRTLLine(line string) string {
+	line = strings.TrimFunc(line, func(r rune) bool {
+		return r == rtlEmbedding || r == rtlMark || r == popDirectional || unicode.IsSpace(r)
+	})
+	if line == "" {
+		return line
+	}
+
+	if last, _ := utf8.DecodeLastRuneInString(line); unicode.IsPunct(last) {
+		line += string(rtlMark)
+	}
+	return string(rtlEmbedding) + line
+}
@@ -0,0 +1,140 @@
+package scriber
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRTLLanguage(t *testing.T) {
+	t.Parallel()
+
+	for lang, expected := range map[string]bool{
+		"ar":    true,
+		"ar-EG": true,
+		"HE":    true,
+		"fa_IR": true,
+		"en":    false,
+		"pt-BR": false,
+		"":      false,
+	} {
+		assert.Equal(t, expected, isRTLLanguage(lang), lang)
+	}
+}
+
+func TestMarkRTLLine(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		given    string
+		expected string
+	}{
+		{
+			name:     "arabic",
+			given:    "مرحبا بكم",
+			expected: "\u202Bمرحبا بكم",
+		},
+		{
+			name:     "arabic ending in punctuation",
+			given:    "كيف حالك؟",
+			expected: "\u202Bكيف حالك؟\u200F",
+		},
+		{
+			name:     "hebrew with a dialogue dash",
+			given:    "- שלום, מה שלומך?",
+			expected: "\u202B- שלום, מה שלומך?\u200F",
+		},
+		{
+			name:     "already marked",
+			given:    "\u202Bكيف حالك؟\u200F",
+			expected: "\u202Bكيف حالك؟\u200F",
+		},
+		{
+			name:     "empty",
+			given:    "",
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, markRTLLine(tc.given))
+		})
+	}
+}
+
+func TestProcess_RTLMarks(t *testing.T) {
+	t.Parallel()
+
+	const arabicSRT = "1\n00:00:00,000 --> 00:00:02,000\n[موسيقى]\n\n" +
+		"2\n00:00:02,000 --> 00:00:04,000\nمرحبا بكم.\nكيف حالكم؟\n"
+
+	testCases := []struct {
+		name          string
+		givenEnabled  bool
+		givenLanguage string
+		givenText     string
+		expectedText  string
+	}{
+		{
+			name:          "arabic",
+			givenEnabled:  true,
+			givenLanguage: "ar",
+			givenText:     arabicSRT,
+			expectedText:  "1\n00:00:02,000 --> 00:00:04,000\n\u202Bمرحبا بكم.\u200F\n\u202Bكيف حالكم؟\u200F\n",
+		},
+		{
+			name:          "disabled",
+			givenLanguage: "ar",
+			givenText:     arabicSRT,
+			expectedText:  "1\n00:00:02,000 --> 00:00:04,000\nمرحبا بكم.\nكيف حالكم؟\n",
+		},
+		{
+			name:          "left-to-right language",
+			givenEnabled:  true,
+			givenLanguage: "en",
+			givenText:     "1\n00:00:00,000 --> 00:00:01,000\nHello.\n",
+			expectedText:  "1\n00:00:00,000 --> 00:00:01,000\nHello.\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.Copy(io.Discard, in.Data)
+					return []byte(tc.givenText), err
+				},
+			},
+				WithRTLMarks(tc.givenEnabled),
+				WithFormatting(FormattingConfig{RemoveSoundDescriptions: true}),
+				WithConverter(copyConverter),
+			)
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "test.mp4",
+				OutputType: OutputTypeSubtitles,
+				Language:   tc.givenLanguage,
+				Data:       io.NopCloser(strings.NewReader("data")),
+			})
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			out.Body.Close()
+
+			assert.Equal(t, tc.expectedText, string(out.Text))
+			assert.NotContains(t, out.PlainText, "\u202B")
+			assert.NotContains(t, out.PlainText, "\u200F")
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_Salvage(t *testing.T) {
+	t.Parallel()
+
+	const srt = "1\n00:00:00,000 --> 00:00:01,000\nhi\n"
+
+	testCases := []struct {
+		name          string
+		givenSalvage  bool
+		givenReply    string
+		givenReplyErr error
+		givenOpts     []Option
+		givenConvert  func(_ context.Context, r io.Reader, w io.Writer) error
+		givenCancel   bool
+		expectedStage Stage
+		expectedText  string // Empty when nothing is salvaged.
+	}{
+		{
+			name:          "output body failure",
+			givenSalvage:  true,
+			givenReply:    srt,
+			givenOpts:     []Option{WithSpoolThreshold(1, "/nonexistent/dir")},
+			expectedStage: StagePostProcess,
+			expectedText:  srt,
+		},
+		{
+			name:         "stitching failure",
+			givenSalvage: true,
+			givenReply:   "not srt",
+			givenOpts:    []Option{WithChunking(ChunkConfig{Length: time.Second})},
+			givenConvert: func(_ context.Context, r io.Reader, w io.Writer) error {
+				_, err := w.Write(syntheticWAV(2))
+				return err
+			},
+			expectedStage: StagePostProcess,
+			expectedText:  "not srt\nnot srt",
+		},
+		{
+			name:          "publish failure",
+			givenSalvage:  true,
+			givenReply:    srt,
+			givenCancel:   true,
+			expectedStage: StagePublish,
+			expectedText:  srt,
+		},
+		{
+			name:          "disabled",
+			givenSalvage:  false,
+			givenReply:    srt,
+			givenOpts:     []Option{WithSpoolThreshold(1, "/nonexistent/dir")},
+			expectedStage: StagePostProcess,
+		},
+		{
+			name:          "nothing to salvage before transcription succeeds",
+			givenSalvage:  true,
+			givenReplyErr: assert.AnError,
+			expectedStage: StageTranscription,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.Copy(io.Discard, in.Data)
+					require.NoError(t, err)
+					return []byte(tc.givenReply), tc.givenReplyErr
+				},
+			}
+
+			convert := tc.givenConvert
+			if convert == nil {
+				convert = copyConverter
+			}
+
+			opts := append([]Option{WithSalvage(tc.givenSalvage), WithConverter(convert)}, tc.givenOpts...)
+			scriber := New(noopLogger(), mockClient, opts...)
+
+			if tc.givenCancel {
+				// Nobody reads, and the context is cancelled once conversion
+				// and transcription are done, so only the publish observes it.
+				scriber.resultsCh = make(chan Output)
+				go func() {
+					time.Sleep(50 * time.Millisecond)
+					cancel()
+				}()
+			}
+
+			err := scriber.Process(ctx, Input{
+				Name:       "test.mp4",
+				OutputType: OutputTypeSubtitles,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewBufferString("foo")),
+			})
+
+			var pe *ProcessError
+			require.ErrorAs(t, err, &pe)
+			assert.Equal(t, tc.expectedStage, pe.Stage)
+
+			if tc.expectedText == "" {
+				assert.Nil(t, pe.Salvaged)
+				return
+			}
+
+			require.NotNil(t, pe.Salvaged)
+			assert.True(t, pe.Salvaged.Degraded)
+			assert.Equal(t, "test.srt", pe.Salvaged.Name)
+			assert.NotEmpty(t, pe.Salvaged.JobID)
+
+			body, err := io.ReadAll(pe.Salvaged.Body)
+			require.NoError(t, err)
+			require.NoError(t, pe.Salvaged.Body.Close())
+			assert.Equal(t, tc.expectedText, string(body))
+		})
+	}
+}
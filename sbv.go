@@ -0,0 +1,57 @@
+package scriber
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/alesr/scriber/subtitle"
+)
+
+// ConvertSubtitles converts SRT subtitles to the subtitle output type t:
+// OutputTypeSubtitles renumbers and normalizes the SRT, and OutputTypeSBV
+// converts it to YouTube's SBV format.
+func ConvertSubtitles(srt []byte, t OutputType) ([]byte, error) {
+	cues, err := parseSRT(srt)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse subtitles: %w", err)
+	}
+
+	switch t {
+	case OutputTypeSubtitles:
+		return formatSRT(cues), nil
+	case OutputTypeSBV:
+		return formatSBV(cues), nil
+	default:
+		return nil, fmt.Errorf("%w: %q isn't a subtitle type", errorOutputType, t)
+	}
+}
+
+// formatSBV serializes cues as SBV: a H:MM:SS.mmm,H:MM:SS.mmm timing
+// line followed by the lines of the cue, without the index of SRT, and
+// cues separated by a blank line.
+func formatSBV(cues []subtitle.Cue) []byte {
+	var b bytes.Buffer
+	for i, c := range cues {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s,%s\n%s\n", formatSBVTimestamp(c.Start), formatSBVTimestamp(c.End), c.Text)
+	}
+	return b.Bytes()
+}
+
+func formatSBVTimestamp(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+
+	h := d / time.Hour
+	d -= h * time.Hour
+	m := d / time.Minute
+	d -= m * time.Minute
+	s := d / time.Second
+	d -= s * time.Second
+
+	return fmt.Sprintf("%d:%02d:%02d.%03d", h, m, s, d/time.Millisecond)
+}
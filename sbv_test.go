@@ -0,0 +1,128 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertSubtitles_SBVGolden(t *testing.T) {
+	t.Parallel()
+
+	inputs, err := filepath.Glob(filepath.Join("testdata", "sbv", "*.srt"))
+	require.NoError(t, err)
+	require.NotEmpty(t, inputs)
+
+	for _, path := range inputs {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			t.Parallel()
+
+			srt, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			expected, err := os.ReadFile(strings.TrimSuffix(path, ".srt") + ".sbv")
+			require.NoError(t, err)
+
+			got, err := ConvertSubtitles(srt, OutputTypeSBV)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(got))
+		})
+	}
+}
+
+func TestConvertSubtitles(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		givenSRT    string
+		givenType   OutputType
+		expected    string
+		expectedErr error
+	}{
+		{
+			name:      "srt is renumbered",
+			givenSRT:  "5\n00:00:01,000 --> 00:00:02,000\nhello\n",
+			givenType: OutputTypeSubtitles,
+			expected:  "1\n00:00:01,000 --> 00:00:02,000\nhello\n",
+		},
+		{
+			name:      "sbv without blank line before a cue",
+			givenSRT:  "1\n00:00:01,000 --> 00:00:02,000\nhello\n   \n2\n00:00:02,000 --> 00:00:03,000\nworld",
+			givenType: OutputTypeSBV,
+			expected:  "0:00:01.000,0:00:02.000\nhello\n\n0:00:02.000,0:00:03.000\nworld\n",
+		},
+		{
+			name:      "empty",
+			givenType: OutputTypeSBV,
+		},
+		{
+			name:        "not a subtitle type",
+			givenSRT:    "1\n00:00:01,000 --> 00:00:02,000\nhello\n",
+			givenType:   OutputTypeTranscript,
+			expectedErr: errorOutputType,
+		},
+		{
+			name:      "malformed",
+			givenSRT:  "one\n00:00:01,000 --> 00:00:02,000\nhello\n",
+			givenType: OutputTypeSBV,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ConvertSubtitles([]byte(tc.givenSRT), tc.givenType)
+
+			switch {
+			case tc.expectedErr != nil:
+				assert.ErrorIs(t, err, tc.expectedErr)
+			case tc.name == "malformed":
+				assert.ErrorContains(t, err, "line 1")
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, tc.expected, string(got))
+			}
+		})
+	}
+}
+
+func TestProcess_SBV(t *testing.T) {
+	t.Parallel()
+
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.ReadAll(in.Data)
+			require.NoError(t, err)
+			assert.Equal(t, whisperclient.FormatSrt, in.Format)
+			return []byte("1\n00:00:00,500 --> 00:00:01,250\n[music] Hello\nthere\n"), nil
+		},
+	},
+		WithFormatting(FormattingConfig{RemoveSoundDescriptions: true}),
+		WithConverter(copyConverter),
+	)
+
+	err := s.Process(context.TODO(), Input{
+		Name:       "talk.mp4",
+		OutputType: OutputTypeSBV,
+		Language:   "en",
+		Data:       io.NopCloser(bytes.NewReader(syntheticWAV(2))),
+	})
+	require.NoError(t, err)
+
+	out := <-s.Collect()
+	require.NoError(t, out.Body.Close())
+
+	assert.Equal(t, "talk.sbv", out.Name)
+	assert.Equal(t, "0:00:00.500,0:00:01.250\nHello\nthere\n", string(out.Text))
+	assert.Equal(t, "Hello there", out.PlainText)
+}
@@ -1,65 +1,112 @@
 package scriber
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
-	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/alesr/whisperclient"
 )
 
 const (
-	sampleRate                      = "5200"
+	convertedSampleRate             = 5200
+	convertedChannels               = 2
 	OutputTypeSubtitles  OutputType = "subtitles"
 	OutputTypeTranscript OutputType = "transcript"
+
+	// OutputTypeSBV is subtitles in YouTube's SBV format. They are
+	// transcribed and post-processed as SRT, and converted last.
+	OutputTypeSBV OutputType = "sbv"
 )
 
-var (
-	supportedOutputTypes = map[OutputType]struct{}{OutputTypeSubtitles: {}, OutputTypeTranscript: {}}
-
-	convertToWav convertToWavFunc = func(r io.Reader, w io.Writer) error {
-		cmd := exec.Command(
-			"ffmpeg", "-y",
-			"-i", "pipe:0",
-			"-vn",
-			"-acodec", "pcm_s16le",
-			"-ar", sampleRate,
-			"-ac", "2",
-			"-b:a", "32k",
-			"-f", "wav",
-			"pipe:1",
-		)
-
-		cmd.Stdout = w
-		stdin, err := cmd.StdinPipe()
-		if err != nil {
-			return fmt.Errorf("failed to create stdin pipe: %w", err)
-		}
-		cmd.Stderr = os.Stderr
+var supportedOutputTypes = map[OutputType]struct{}{OutputTypeSubtitles: {}, OutputTypeTranscript: {}, OutputTypeSBV: {}}
 
-		if err := cmd.Start(); err != nil {
-			return fmt.Errorf("failed to start ffmpeg: %w", err)
-		}
+// isSubtitles reports whether outputs of type t are subtitles,
+// which are handled as SRT until they are published.
+func isSubtitles(t OutputType) bool {
+	return t == OutputTypeSubtitles || t == OutputTypeSBV
+}
 
-		go func() {
-			defer stdin.Close()
-			if _, err := io.Copy(stdin, r); err != nil {
-				fmt.Fprintf(os.Stderr, "error copying to stdin: %v\n", err)
-			}
-		}()
+// responseFormat returns the backend response format that produces outputs
+// of type t. Every supported output type must have one.
+func responseFormat(t OutputType) (string, error) {
+	switch t {
+	case OutputTypeSubtitles, OutputTypeSBV:
+		return whisperclient.FormatSrt, nil
+	case OutputTypeTranscript:
+		return whisperclient.FormatText, nil
+	default:
+		return "", fmt.Errorf("%w: no response format for %q", errorOutputType, t)
+	}
+}
+
+// runConverter runs cmd, feeding r to its stdin and writing its stdout to w.
+// If reading r fails, the command is killed and the read error is returned,
+// since the command would otherwise wait for input that never comes.
+// It returns once it has stopped reading r.
+func runConverter(cmd *exec.Cmd, r io.Reader, w io.Writer) error {
+	cmd.Stdout = w
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start: %w", err)
+	}
+
+	src := &readErrRecorder{r: r}
+	readErrCh := make(chan error, 1)
+	copied := make(chan struct{})
+
+	go func() {
+		defer close(copied)
+		defer stdin.Close()
 
-		if err := cmd.Wait(); err != nil {
-			return fmt.Errorf("ffmpeg failed: %w", err)
+		// Write errors mean the command exited early, and are
+		// reported by Wait. Read errors are the input's fault.
+		if _, err := io.Copy(stdin, src); err != nil && src.err != nil {
+			readErrCh <- src.err
+			cmd.Process.Kill()
 		}
-		return nil
+	}()
+
+	waitErr := cmd.Wait()
+
+	// Once the command has exited, writes to its stdin fail,
+	// so the copy ends with the read in progress, if any.
+	<-copied
+
+	select {
+	case err := <-readErrCh:
+		return fmt.Errorf("could not read input: %w", err)
+	default:
 	}
-)
+
+	return waitErr
+}
+
+// readErrRecorder records the error returned by the underlying reader,
+// telling read failures apart from write failures in io.Copy.
+type readErrRecorder struct {
+	r   io.Reader
+	err error
+}
+
+func (r *readErrRecorder) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
 
 type (
 	// whisperClient is a client for the whisper service.
@@ -72,12 +119,138 @@ type (
 
 	// Output represents the result of processing an input file.
 	Output struct {
+		// Name is the file name of the output, as assembled by Filename
+		// with the options set on the Scriber.
 		Name string
+
+		// BaseName is the input name without its extension.
+		BaseName string
+
+		// Extension is the extension for the output type, dot included.
+		Extension string
+
+		// InputExtension is the extension of the input name, dot included
+		// and case kept, or empty if it has none. See WithInputExtensionInName.
+		InputExtension string
+
+		// JobID identifies the Process call that produced the output.
+		// Outputs produced by the same call, one per language of
+		// Input.Languages, share it.
+		JobID string
+
+		// Text holds the transcription. It is nil when the payload
+		// exceeded the spool threshold; read it from Body instead.
 		Text []byte
+
+		// PlainText is the text of the transcription. For subtitles, cue
+		// numbers, timestamps, and formatting tags are stripped, and cues
+		// are separated by newlines. See StripSubtitleFormatting.
+		PlainText string
+
+		// RawText is the transcription as returned by the backend, before
+		// post-processing, when WithRawText is enabled and post-processing
+		// changed it. It is nil otherwise, Text being the raw transcription.
+		RawText []byte
+
+		// RawName is the file name of RawText, Name with raw before the
+		// extension (talk.raw.srt), set along with it. See WithNameRaw.
+		RawName string
+
+		// Body streams the transcription. It is always set and must be
+		// closed by the consumer. When the payload was spooled to a
+		// temporary file, closing Body removes the file.
+		Body io.ReadCloser
+
+		// Path is the path of the file the output was written
+		// to, if any. See WithOutputDir.
+		Path string
+
+		// Language is the language the input was transcribed in.
+		// For LanguageAuto inputs, it is the detected language.
+		Language string
+
+		// Metadata carries the attributes returned by the context
+		// attribute extractor, for downstream correlation.
+		Metadata map[string]string
+
+		// AudioDuration is the duration of the converted audio.
+		AudioDuration time.Duration
+
+		// InputBytes is the number of bytes read from Input.Data.
+		InputBytes int64
+
+		// ConvertedBytes is the number of bytes of WAV audio uploaded to
+		// the transcription backend. When chunking, it is the sum of the
+		// chunk uploads, overlaps and per-chunk headers included.
+		ConvertedBytes int64
+
+		// AudioSpec describes the audio uploaded to the backend, to tell
+		// what a transcription was made from.
+		AudioSpec UploadedAudio
+
+		// ResumedChunks is the number of chunks whose transcription was
+		// reused from an interrupted run. See WithChunkResume.
+		ResumedChunks int
+
+		// ContentID identifies the output by its content: the same input
+		// data transcribed in the same language, to the same output type,
+		// with the same model, gets the same ID on any run and machine.
+		// It is empty unless the input was hashed. See WithContentID.
+		ContentID string
+
+		// Deduplicated is set when the transcription was that of an
+		// identical input submitted earlier. See WithDedupCache.
+		Deduplicated bool
+
+		// ProcessingTime is the time spent processing the input.
+		ProcessingTime ProcessingTime
+
+		// Degraded is set on outputs salvaged from a job that failed after
+		// transcription. Post-processing may be incomplete. See WithSalvage.
+		Degraded bool
+
+		// Truncated is set when the subtitles end well before the end of
+		// the audio, which suggests a truncated transcription.
+		// See WithTruncationCheck.
+		Truncated *TruncatedTranscriptionWarning
+
+		// SpeechRegions are the regions of the audio that were transcribed,
+		// when voice activity detection is enabled.
+		// See WithVoiceActivityDetection.
+		SpeechRegions []SpeechRegion
+
+		// Events is the event log of the job up to the output, unless
+		// disabled with WithEventLog. It records the same facts as the
+		// job's log lines, whatever their level.
+		Events []Event
+
+		// Warnings are the non-fatal anomalies detected while processing
+		// the input, in the order they were detected.
+		// See WithWarningsAsErrors.
+		Warnings []Warning
+
+		// TextStats are computed over the plain text of the transcription,
+		// with subtitle cue numbers and timestamps stripped.
+		TextStats
+
+		// Comparison compares the plain text of the transcription with
+		// Input.ReferenceText, when set. See CompareTranscripts.
+		Comparison *ComparisonResult
+	}
+
+	// ProcessingTime breaks down the time spent processing an input.
+	// When audio is streamed, conversion and transcription run
+	// concurrently, so their durations overlap.
+	ProcessingTime struct {
+		Total       time.Duration
+		Convert     time.Duration
+		Transcribe  time.Duration
+		PostProcess time.Duration
 	}
 
 	// convertToWavFunc is a function that converts audio data to wav format.
-	convertToWavFunc func(r io.Reader, w io.Writer) error
+	// It must stop when ctx is canceled.
+	convertToWavFunc func(ctx context.Context, r io.Reader, w io.Writer) error
 )
 
 // Input represents an input file to be processed.
@@ -85,7 +258,89 @@ type Input struct {
 	Name       string
 	OutputType OutputType
 	Language   string
-	Data       io.ReadCloser
+
+	// Languages transcribes the input in each of the given languages,
+	// publishing one Output per language. The input is converted once.
+	// It is mutually exclusive with Language.
+	Languages []string
+
+	// Data is the media to transcribe. Process closes it exactly once
+	// before returning, on every path including validation failures,
+	// unless KeepOpen is set. Use NewInput to pass a plain io.Reader.
+	Data io.ReadCloser
+
+	// DataRef references the media in the store set with WithBlobStore,
+	// which Process opens and closes itself. It is mutually exclusive
+	// with Data.
+	DataRef string
+
+	// KeepOpen leaves closing Data to the caller, e.g. to reuse an *os.File.
+	KeepOpen bool
+
+	// IdempotencyKey is forwarded to the transcription backend
+	// (see IdempotencyKeyFromContext). A key is generated when empty.
+	IdempotencyKey string
+
+	// ContentType is the MIME type of Data, e.g. "audio/mpeg". When it
+	// is an audio or video type, Name doesn't need an extension.
+	ContentType string
+
+	// Priority orders the jobs waiting for a conversion or upload slot
+	// (see WithMaxConcurrentConversions): higher priorities are served
+	// first, and equal ones in arrival order. It defaults to zero.
+	Priority int
+
+	// Size is the size of Data in bytes, if known, or zero. It is used
+	// to enforce the maximum input size early, to scale the transcription
+	// timeout, and to report progress as a percentage.
+	Size int64
+
+	// DurationHint is the duration of Data, if known, or zero, e.g.
+	// from the metadata of an upload. It routes the input without
+	// probing it (see WithLongJobRouting).
+	DurationHint time.Duration
+
+	// UserTag identifies the end user the input is transcribed for, to
+	// attribute requests on the backend's side (see UserTagFromContext).
+	// It is added to the job's logs. Control characters are stripped, and
+	// it must be at most 128 bytes long.
+	UserTag string
+
+	// UploadName is the file name sent to the transcription backend, so
+	// that Name, which may hold internal paths, isn't disclosed to it.
+	// Logs and output names still use Name. It defaults to the base
+	// name of Name, sanitized, with the extension of the uploaded audio.
+	// When set, it must be a base name safe as a file name, and end in
+	// the extension of the uploaded audio, .wav or .flac, when ffmpeg
+	// converts it: some backends sniff the format from the extension.
+	UploadName string
+
+	// ReferenceText is a verified transcript of the input, for regression
+	// testing: the transcription is compared with it, and the comparison
+	// attached to the Output (see WithMaxWordErrorRate). With Languages,
+	// every language is compared with it.
+	ReferenceText string
+
+	// Deadline is when the job must be done by, conversion, transcription
+	// and publishing included, if not zero. The job's context is canceled
+	// then, on top of the stage timeouts, and stages that can't be done
+	// in time aren't started: see DeadlineBudgetError.
+	Deadline time.Time
+
+	// OutputExtension overrides the extension of the output name, e.g.
+	// ".text", which defaults to the one of OutputType. The content is
+	// still governed by OutputType. It must start with a dot and contain
+	// no path separators. The language, when added to the name, still
+	// comes before it (talk.en.captions).
+	OutputExtension string
+
+	// sizeDeclared is set when Size was set with WithInputSize,
+	// for which zero means empty rather than unknown.
+	sizeDeclared bool
+
+	// requestedOutputType is the unknown output type the input was
+	// submitted with, when it fell back to OutputTypeTranscript.
+	requestedOutputType OutputType
 }
 
 func (i *Input) validate() error {
@@ -93,19 +348,46 @@ func (i *Input) validate() error {
 		return errNameRequired
 	}
 
-	if filepath.Ext(i.Name) == "" {
-		return errExtRequired
+	if i.ContentType != "" {
+		if _, err := audioMediaType(i.ContentType); err != nil {
+			return err
+		}
+	} else if inputExtension(i.Name) == "" {
+		return fmt.Errorf("%w: %q", errExtRequired, i.Name)
 	}
 
 	if _, ok := supportedOutputTypes[i.OutputType]; !ok {
-		return errorOutputType
+		return fmt.Errorf("%w: %q", errorOutputType, i.OutputType)
+	}
+
+	if ext := i.OutputExtension; ext != "" && (len(ext) < 2 || ext[0] != '.' || strings.ContainsAny(ext, `/\`)) {
+		return fmt.Errorf("%w: %q", errOutputExt, ext)
 	}
 
-	if i.Language == "" {
+	if i.Language == "" && len(i.Languages) == 0 {
 		return errorLanguage
 	}
 
-	if i.Data == nil {
+	if i.Language != "" && len(i.Languages) > 0 {
+		return errLanguagesExclusive
+	}
+
+	seen := make(map[string]bool, len(i.Languages))
+	for _, lang := range i.Languages {
+		if lang == "" || lang == LanguageAuto {
+			return fmt.Errorf("%w: %q", errLanguagesInvalid, lang)
+		}
+		if seen[lang] {
+			return fmt.Errorf("%w: %q is repeated", errLanguagesInvalid, lang)
+		}
+		seen[lang] = true
+	}
+
+	if i.Data != nil && i.DataRef != "" {
+		return errDataExclusive
+	}
+
+	if i.Data == nil && i.DataRef == "" {
 		return errorData
 	}
 	return nil
@@ -113,29 +395,665 @@ func (i *Input) validate() error {
 
 // Scriber is a service that processes
 // audio files and transcribes them.
+// It is safe for concurrent use, and meant to be shared by all the
+// goroutines processing inputs for the lifetime of the process.
 type Scriber struct {
-	logger           *slog.Logger
-	convertToWavFunc convertToWavFunc
-	whisperClient    whisperClient
-	resultsCh        chan Output
+	logger            *slog.Logger
+	convertToWavFunc  convertToWavFunc
+	whisperClient     whisperClient
+	resultsCh         chan Output
+	ctxAttrExtractor  func(ctx context.Context) []slog.Attr
+	spoolThreshold    int64
+	spoolDir          string
+	bufPool           *bufferPool
+	chunking          *ChunkConfig
+	vad               *VADConfig
+	pricing           *Pricing
+	dedup             *dedupCache
+	redactor          *Redactor
+	logTimeout        time.Duration
+	logGuard          logGuard
+	contentIDs        bool
+	probeDurationFunc probeDurationFunc
+	probeLayoutFunc   probeLayoutFunc
+	emptyPolicy       EmptyTranscriptionPolicy
+	allowEmptyInput   bool
+	partialsCh        chan PartialOutput
+	salvage           bool
+	cancellation      CancellationPolicy
+
+	outputTypeAliases  map[OutputType]OutputType
+	unknownOutputTypes UnknownOutputTypePolicy
+
+	translator   Translator
+	translation  TranslationConfig
+	capabilities *Capabilities
+	backendPause backendPause
+
+	minTranscriptionTime time.Duration
+	now                  func() time.Time
+	normalizedOutput     bool
+	maxWordErrorRate     float64
+
+	transcriptionTimeout time.Duration
+	retry                *RetryConfig
+	retryClassifier      func(error) RetryDecision
+	languageInName       bool
+	inputExtInName       bool
+	inputDefaults        Input
+	maxInputSize         int64
+	escalatedWarnings    map[WarningCode]bool
+	uploadLimit          UploadLimit
+
+	// audioSpec is set by WithAudioSpec.
+	audioSpec     *AudioSpec
+	uploadCodec   AudioCodec
+	dialogDownmix bool
+
+	// ffmpeg is the config of the default converter, negotiated with
+	// the backend, ffmpegArgs its arguments converting to WAV when no
+	// job's are given, and runFFmpeg runs it. All are nil when the
+	// converter is replaced.
+	ffmpeg                *ffmpegConfig
+	ffmpegArgs            []string
+	runFFmpeg             func(ctx context.Context, args []string, r io.Reader, w io.Writer) error
+	sizeMismatchPolicy    SizeMismatchPolicy
+	timeoutPerMB          time.Duration
+	inputIdleTimeout      time.Duration
+	timeoutPerAudioMinute time.Duration
+	durationTolerance     time.Duration
+	truncationThreshold   time.Duration
+	truncationPolicy      TruncationPolicy
+	formatting            FormattingConfig
+	rtlMarks              bool
+	languageProfiles      map[string]SubtitleStyle
+	maxConversions        int
+	maxTranscriptions     int
+	postProcessWorkers    int
+	postProcessors        *semaphore
+	priorityAging         time.Duration
+	tenantKeyFunc         func(Input) string
+	tenantLimits          TenantLimits
+	tenants               *tenantLimiter
+	maxEvents             int
+	usage                 usageTracker
+	timings               timingTracker
+	softLimits            SoftLimits
+	rawText               bool
+	maxNameLength         int
+	lenientNames          bool
+	outputDir             string
+	blobStore             BlobStore
+	chunkStore            *chunkStore
+	omitWrittenText       bool
+	existsFunc            ExistsFunc
+	maxNameAttempts       int
+	names                 nameReservations
+	conversions           *semaphore
+	transcriptions        *semaphore
+	progressFunc          func(Progress)
+	publishing            *PublishConfig
+	overflow              *resultOverflow
+	longJobs              *LongJobConfig
+	buffered              byteGauge
+	publishCounters       publishCounters
+	errorsCh              chan error
+	orderedResults        bool
+	maxHeldResults        int
+	resultHoldTimeout     time.Duration
+	sequencer             *resultSequencer
+
+	// outlets admits jobs, and guards the channels they publish on.
+	outlets outlets
+
+	// mu guards the fields below, which track in-flight jobs for Shutdown.
+	mu       sync.Mutex
+	inflight map[*job]context.CancelFunc
+	jobs     sync.WaitGroup
+}
+
+// Option configures optional Scriber behavior.
+type Option func(*Scriber)
+
+// WithContextAttrExtractor sets a function that extracts attributes
+// (e.g. request ID, tenant) from the context given to Process.
+// The attributes are added to every log line emitted while processing
+// and copied into Output.Metadata. The extractor is called once per job.
+func WithContextAttrExtractor(fn func(ctx context.Context) []slog.Attr) Option {
+	return func(s *Scriber) {
+		s.ctxAttrExtractor = fn
+	}
+}
+
+// WithSpoolThreshold makes outputs larger than n bytes be spooled to a
+// temporary file in dir (os.TempDir when empty) and exposed only through
+// Output.Body, with Output.Text left nil. A threshold of zero disables spooling.
+func WithSpoolThreshold(n int64, dir string) Option {
+	return func(s *Scriber) {
+		s.spoolThreshold = n
+		s.spoolDir = dir
+	}
+}
+
+// WithConverter replaces the default ffmpeg converter. The function must
+// read the input media from r, write WAV audio to w, and return
+// promptly once ctx is canceled.
+func WithConverter(fn func(ctx context.Context, r io.Reader, w io.Writer) error) Option {
+	return func(s *Scriber) {
+		s.convertToWavFunc = fn
+	}
+}
+
+// WithCopyBufferSize sets the size of the pooled buffers used to copy
+// data between the input, the converter, and the transcription upload.
+// Buffers are reused across jobs.
+func WithCopyBufferSize(n int) Option {
+	return func(s *Scriber) {
+		s.bufPool = newBufferPool(n)
+	}
 }
 
-func New(logger *slog.Logger, whisperCli whisperClient) *Scriber {
-	return &Scriber{
-		logger:           logger.WithGroup("scriber"),
-		convertToWavFunc: convertToWav,
-		whisperClient:    whisperCli,
-		resultsCh:        make(chan Output, 10),
+// WithRawText attaches the transcription as returned by the backend to
+// outputs whose text post-processing changed, such as with WithFormatting,
+// in Output.RawText, named Output.RawName. With WithOutputDir, it is written
+// next to the output. Outputs left untouched don't carry a copy.
+func WithRawText(enabled bool) Option {
+	return func(s *Scriber) {
+		s.rawText = enabled
 	}
 }
 
+// WithSalvage makes Process attach the transcription to the returned
+// ProcessError (see ProcessError.Salvaged) when the job fails after the
+// transcription succeeded, so that the transcription isn't lost.
+func WithSalvage(enabled bool) Option {
+	return func(s *Scriber) {
+		s.salvage = enabled
+	}
+}
+
+func New(logger *slog.Logger, whisperCli whisperClient, opts ...Option) *Scriber {
+	s := &Scriber{
+		whisperClient:     whisperCli,
+		resultsCh:         make(chan Output, 10),
+		bufPool:           defaultBufferPool,
+		probeDurationFunc: newFFprobeProber(),
+		probeLayoutFunc:   newFFprobeLayoutProber(),
+
+		transcriptionTimeout: defaultTranscriptionTimeout,
+		maxHeldResults:       defaultMaxHeldResults,
+		resultHoldTimeout:    defaultResultHoldTimeout,
+		maxEvents:            defaultMaxEvents,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	// Route all logging through a guard, so that a misbehaving
+	// handler can't stall or crash jobs.
+	s.logGuard.timeout = s.logTimeout
+	s.logger = slog.New(&guardHandler{next: logger.Handler(), guard: &s.logGuard}).WithGroup("scriber")
+
+	spec := AudioSpec{SampleRate: convertedSampleRate, Channels: convertedChannels}
+	if s.convertToWavFunc == nil {
+		spec = s.negotiateAudio()
+		cfg := ffmpegConfigFor(spec)
+		s.ffmpeg = &cfg
+
+		s.ffmpegArgs = buildFFmpegArgs(cfg, ffmpegInput{codec: CodecWAV})
+		s.runFFmpeg = runFFmpeg
+	}
+	s.capabilities = s.backendCapabilities()
+	if s.capabilities != nil && s.uploadLimit.MaxSize <= 0 {
+		s.uploadLimit.MaxSize = s.capabilities.MaxUploadSize
+	}
+	if s.uploadLimit.SampleRate <= 0 {
+		s.uploadLimit.SampleRate = spec.SampleRate
+	}
+	if s.uploadLimit.Channels <= 0 {
+		s.uploadLimit.Channels = spec.Channels
+	}
+	if s.outputDir != "" && s.existsFunc == nil {
+		s.existsFunc = dirExists(s.outputDir)
+		s.maxNameAttempts = defaultMaxNameAttempts
+	}
+	if s.overflow != nil {
+		s.recoverOverflow()
+	}
+	if s.orderedResults {
+		s.sequencer = newResultSequencer(s.maxHeldResults, s.resultHoldTimeout)
+	}
+	if s.tenantKeyFunc != nil {
+		s.tenants = newTenantLimiter(s.tenantLimits, s.priorityAging)
+	}
+	s.conversions = newSemaphore(s.maxConversions, s.priorityAging)
+	s.transcriptions = newSemaphore(s.maxTranscriptions, s.priorityAging)
+	s.postProcessors = newSemaphore(s.postProcessWorkers, s.priorityAging)
+	return s
+}
+
 func (s *Scriber) Process(ctx context.Context, in Input) error {
-	s.logger.Info("Processing file", slog.String("name", in.Name))
+	if s.postProcessWorkers > 0 {
+		return s.processDetached(ctx, in)
+	}
+	_, err := s.process(ctx, in, nil)
+	return err
+}
+
+// process runs the job for in, returning it along with its error
+// for callers that report on it, like ProcessBatch. handOff, if not nil,
+// is called once the job is transcribed, with asynchronous post-processing.
+func (s *Scriber) process(ctx context.Context, in Input, handOff func()) (*job, error) {
+	return s.run(ctx, in, handOff, func(ctx context.Context, j *job) error {
+		cleanup, err := s.routeLongJob(ctx, j)
+		defer cleanup()
+		if err != nil {
+			return asProcessError(StageValidation, j.in, err)
+		}
+
+		if err := s.hashInput(j); err != nil {
+			return s.fail(j, StageValidation, err)
+		}
+		key := s.dedupKey(j)
+		if ok, err := s.completeFromDedup(ctx, j, key); ok {
+			return err
+		}
+
+		if err := s.probeInputDuration(ctx, j); err != nil {
+			return s.fail(j, StageValidation, err)
+		}
+		if err := s.probeDownmix(ctx, j); err != nil {
+			return s.fail(j, StageValidation, err)
+		}
+
+		if j.in.Language == LanguageAuto {
+			release, err := s.detectLanguage(ctx, j)
+			if err != nil {
+				return s.fail(j, StageTranscription, err)
+			}
+			defer release()
+		}
+
+		if len(j.in.Languages) > 0 {
+			return s.processLanguages(ctx, j)
+		}
+
+		text, err := s.transcribe(ctx, j)
+		if err != nil {
+			return s.fail(j, StageTranscription, err)
+		}
+		if err := s.complete(ctx, j, text); err != nil {
+			return err
+		}
+		s.cacheTranscription(j, key)
+		return nil
+	})
+}
+
+// run admits and validates a job for in, then runs body, which must
+// return a *ProcessError on failure. It returns the job along with its
+// error. handOff, if not nil, is the job's, see process.
+func (s *Scriber) run(ctx context.Context, in Input, handOff func(), body func(ctx context.Context, j *job) error) (j *job, err error) {
+	in = s.applyDefaults(in)
+	in, inputErr := s.normalizeInput(in)
+
+	if in.Data != nil && !in.KeepOpen {
+		defer in.Data.Close()
+	}
+
+	attrs, attrsErr := s.contextAttrs(ctx)
+
+	j = s.newJob(in, attrs)
+	j.handOff = handOff
+	defer func() { s.notifyPanic(j, err) }()
+	defer func() { s.finishTiming(j, err) }()
+
+	if attrsErr != nil {
+		return j, asProcessError(StageAdmission, in, fmt.Errorf("could not extract context attributes: %w", attrsErr))
+	}
+
+	ctx, release, err := s.admit(ctx, j)
+	if err != nil {
+		return j, asProcessError(StageAdmission, in, err)
+	}
+	defer release()
+
+	ctx, cancel := withDeadline(ctx, in)
+	defer cancel()
+
+	if err := s.usage.check(); err != nil {
+		return j, asProcessError(StageAdmission, in, err)
+	}
+	defer func() {
+		if err == nil {
+			s.usage.record(j.billedAudio(), 0)
+		}
+	}()
+
+	ctx = withIdempotencyKey(ctx, j.idempotencyKey)
+	ctx = withPriority(ctx, in.Priority)
+	ctx = withUserTag(ctx, in.UserTag)
+
+	if s.tenants != nil {
+		// Distinct names keep the deferred charge reading the result.
+		tenant, keyErr := s.tenantKey(in)
+		if keyErr != nil {
+			return j, asProcessError(StageAdmission, in, fmt.Errorf("could not get tenant key: %w", keyErr))
+		}
+		ctx = withTenant(ctx, tenant)
+
+		done, admitErr := s.tenants.admit(ctx, tenant)
+		if admitErr != nil {
+			return j, asProcessError(StageAdmission, in, admitErr)
+		}
+		defer func() {
+			if err != nil {
+				done(0)
+				return
+			}
+			done(j.billedAudio())
+		}()
+	}
+
+	j.enter(StageValidation)
+
+	if inputErr != nil {
+		return j, asProcessError(StageValidation, in, fmt.Errorf("invalid input: %w", inputErr))
+	}
+
+	if err := s.warnOutputTypeFallback(j); err != nil {
+		return j, asProcessError(StageValidation, in, err)
+	}
+
+	if in.Size > 0 {
+		j.logger.Info("Processing file", slog.String("name", in.Name), slog.String("job_id", j.id), slog.Int64("size", in.Size))
+	} else {
+		j.logger.Info("Processing file", slog.String("name", in.Name), slog.String("job_id", j.id))
+	}
 
 	if err := in.validate(); err != nil {
-		return fmt.Errorf("invalid input: %w", err)
+		return j, asProcessError(StageValidation, in, fmt.Errorf("invalid input: %w", err))
+	}
+
+	if err := s.checkUploadName(in); err != nil {
+		return j, asProcessError(StageValidation, in, fmt.Errorf("invalid input: %w", err))
+	}
+
+	if err := s.checkCapabilities(in); err != nil {
+		return j, asProcessError(StageValidation, in, err)
+	}
+
+	if in.DataRef != "" {
+		in, err = s.openBlob(ctx, in)
+		if err != nil {
+			return j, asProcessError(StageValidation, in, fmt.Errorf("invalid input: %w", err))
+		}
+		defer in.Data.Close()
+		j.in = in
+	}
+
+	if err := s.checkSizeHint(in); err != nil {
+		return j, asProcessError(StageValidation, in, err)
+	}
+
+	if err := s.checkEmptyInput(j); err != nil {
+		return j, asProcessError(StageValidation, in, err)
+	}
+
+	if err := j.markDataStart(); err != nil {
+		return j, asProcessError(StageValidation, in, err)
+	}
+
+	if err := s.checkDeadline(j, StageConversion, 0); err != nil {
+		return j, asProcessError(StageConversion, in, err)
+	}
+
+	j.enter(StageConversion)
+
+	return j, body(ctx, j)
+}
+
+// complete publishes the job's transcription, then its normalized
+// transcript and translations.
+func (s *Scriber) complete(ctx context.Context, j *job, text []byte) error {
+	if err := s.publishTranscription(ctx, j, text); err != nil {
+		return err
+	}
+	s.publishNormalized(ctx, j)
+	s.translate(ctx, j)
+	return nil
+}
+
+// publishTranscription post-processes the job's transcription and publishes it.
+func (s *Scriber) publishTranscription(ctx context.Context, j *job, text []byte) (err error) {
+	in := j.in
+
+	text, err = s.checkEmptyTranscription(ctx, j, text)
+	if err != nil {
+		return s.fail(j, StageTranscription, err)
+	}
+	j.raw = text
+
+	if err := s.checkpoint(ctx, j, StagePostProcess, "post-processing"); err != nil {
+		return s.fail(j, StagePostProcess, err)
+	}
+
+	release, err := s.handOff(ctx, j)
+	if err != nil {
+		return s.fail(j, StagePostProcess, err)
+	}
+	defer release()
+
+	j.enter(StagePostProcess)
+
+	if err := s.checkAudioDuration(j); err != nil {
+		return s.fail(j, StagePostProcess, err)
+	}
+
+	if err := s.checkTruncation(j, text); err != nil {
+		return s.fail(j, StagePostProcess, err)
+	}
+
+	if err := s.checkSoftLimits(ctx, j); err != nil {
+		return s.fail(j, StagePostProcess, err)
+	}
+
+	postStart := time.Now()
+	defer func() {
+		if err != nil {
+			j.timing.PostProcess += time.Since(postStart)
+		}
+	}()
+
+	var (
+		style      = s.styleFor(in.Language)
+		redactions map[RedactionClass]int
+		plain      string
+		stats      TextStats
+		comparison *ComparisonResult
+	)
+	steps := []postProcessor{
+		{"formatting", func(text []byte) ([]byte, error) {
+			return applyFormatting(text, in.OutputType, *style.Formatting), nil
+		}},
+		{"redaction", func(text []byte) ([]byte, error) {
+			text, redactions = s.redact(text, in.OutputType)
+			return text, nil
+		}},
+		{"line wrapping", func(text []byte) ([]byte, error) {
+			return wrapLines(text, in.OutputType, style.MaxLineLength), nil
+		}},
+		{"text stats", func(text []byte) ([]byte, error) {
+			plain = plainText(text, in.OutputType)
+			stats = computeTextStats(plain, j.audioDuration)
+			return text, nil
+		}},
+		{"reference comparison", func(text []byte) (_ []byte, err error) {
+			comparison, err = s.compareReference(j, plain)
+			return text, err
+		}},
+		{"RTL marks", func(text []byte) ([]byte, error) {
+			if *style.RTLMarks {
+				text = applyRTLMarks(text, in.OutputType, in.Language)
+			}
+			return text, nil
+		}},
+		{"SBV conversion", func(text []byte) ([]byte, error) {
+			if in.OutputType != OutputTypeSBV {
+				return text, nil
+			}
+			return ConvertSubtitles(text, OutputTypeSBV)
+		}},
+	}
+	for _, step := range steps {
+		if err := s.checkpoint(ctx, j, StagePostProcess, step.name); err != nil {
+			return s.fail(j, StagePostProcess, err)
+		}
+		if text, err = step.fn(text); err != nil {
+			return s.fail(j, StagePostProcess, err)
+		}
+	}
+
+	var raw []byte
+	if s.rawText && !bytes.Equal(j.raw, text) {
+		raw, _ = s.redact(j.raw, in.OutputType)
+	}
+
+	text, body, err := newOutputBody(text, s.spoolThreshold, s.spoolDir)
+	if err != nil {
+		return s.fail(j, StagePostProcess, fmt.Errorf("could not create output body: %w", err))
+	}
+	j.timing.PostProcess += time.Since(postStart)
+
+	j.timing.Total = time.Since(j.started)
+
+	out := j.output(text)
+	out.Body = body
+	out.ContentID = s.contentID(j)
+	redactionMetadata(out.Metadata, redactions)
+	out.PlainText = plain
+	out.TextStats = stats
+	out.Comparison = comparison
+
+	if err := s.reserveName(ctx, j, &out); err != nil {
+		out.Body.Close()
+		return s.fail(j, StagePublish, err)
+	}
+
+	// Nothing has left scriber yet, and closing the body releases the name.
+	if err := s.checkpoint(ctx, j, StagePublish, "publishing"); err != nil {
+		out.Body.Close()
+		return s.fail(j, StagePublish, err)
+	}
+
+	if raw != nil {
+		out.RawText = raw
+		out.RawName = out.Filename(append(j.nameOptions(), WithNameRaw())...)
+	}
+
+	if err := s.writeOutput(j, &out); err != nil {
+		out.Body.Close()
+		return s.fail(j, StagePublish, err)
+	}
+
+	if err := s.publish(ctx, j, out); err != nil {
+		return err
+	}
+
+	j.logger.Info("Processing complete",
+		slog.String("file", in.Name),
+		slog.Duration("audio_duration", j.audioDuration),
+		slog.Duration("processing_time", j.timing.Total),
+		slog.Duration("convert_time", j.timing.Convert),
+		slog.Duration("transcribe_time", j.timing.Transcribe),
+		slog.Duration("postprocess_time", j.timing.PostProcess),
+		slog.Int64("input_bytes", j.inputBytes),
+		slog.Int64("converted_bytes", j.convertedBytes),
+	)
+	return nil
+}
+
+// transcribe converts and transcribes the job's input.
+func (s *Scriber) transcribe(ctx context.Context, j *job) ([]byte, error) {
+	if err := s.checkConfig(); err != nil {
+		return nil, err
+	}
+	if err := s.checkUploadSize(j); err != nil {
+		return nil, err
 	}
 
+	if s.vad != nil {
+		return s.transcribeSpeech(ctx, j)
+	}
+	if s.chunking != nil {
+		return s.transcribeChunked(ctx, j)
+	}
+	if s.retry != nil {
+		return s.convertAndTranscribeWithRetry(ctx, j)
+	}
+
+	text, err := s.convertAndTranscribe(ctx, j, nil)
+	if err != nil {
+		_, err = s.retryable(ctx, j, err, false)
+	}
+	return text, err
+}
+
+// checkConfig validates the optional transcription settings.
+func (s *Scriber) checkConfig() error {
+	if s.retry != nil {
+		if err := s.retry.validate(); err != nil {
+			return stageError(StageValidation, fmt.Errorf("invalid retry config: %w", err))
+		}
+	}
+	if s.chunking != nil {
+		cfg := *s.chunking
+		if cfg.Length == 0 && s.uploadLimit.MaxSize > 0 {
+			// Fitted to the upload limit once the audio format is known.
+			fit, err := s.uploadLimit.maxChunkLength(s.uploadLimit.format(), cfg.Overlap)
+			if err != nil {
+				return err
+			}
+			cfg.Length = fit
+		}
+		if err := cfg.validate(); err != nil {
+			return stageError(StageValidation, fmt.Errorf("invalid chunk config: %w", err))
+		}
+	}
+	if s.vad != nil {
+		if err := s.vad.validate(); err != nil {
+			return stageError(StageValidation, fmt.Errorf("invalid voice activity detection config: %w", err))
+		}
+	}
+	if s.publishing != nil {
+		if err := s.publishing.validate(); err != nil {
+			return stageError(StageValidation, fmt.Errorf("invalid publish config: %w", err))
+		}
+	}
+	return nil
+}
+
+// fail returns err as a *ProcessError. When salvaging is enabled and the job
+// failed after transcription, the raw transcription is attached as a degraded Output.
+func (s *Scriber) fail(j *job, stage Stage, err error) error {
+	pe := asProcessError(stage, j.in, err)
+
+	if s.salvages(err) && j.raw != nil && (pe.Stage == StagePostProcess || pe.Stage == StagePublish) {
+		raw, redactions := s.redact(j.raw, j.in.OutputType)
+		out := j.output(raw)
+		out.Body = io.NopCloser(bytes.NewReader(raw))
+		out.Degraded = true
+		redactionMetadata(out.Metadata, redactions)
+		pe.Salvaged = &out
+	}
+	return pe
+}
+
+// convertAndTranscribe converts the input and streams
+// the converted audio to the transcription backend.
+// If spool is not nil, the converted audio is also written to it.
+func (s *Scriber) convertAndTranscribe(ctx context.Context, j *job, spool *audioSpool) ([]byte, error) {
 	// Create pipes for conversion.
 	// The pipeWriter will be used for writing the audio data from the input to ffmpeg.
 	// The pipeReader will be used for reading the converted audio from ffmpeg and transcribing it.
@@ -143,10 +1061,26 @@ func (s *Scriber) Process(ctx context.Context, in Input) error {
 	// This is done to avoid writing the converted audio to disk or holding it in memory.
 	pipeReader, pipeWriter := io.Pipe()
 
+	j.enter(StageConversion)
+
+	ctx, cancel := context.WithCancel(ctx)
+
 	errCh := make(chan error, 1)
+	converted := make(chan struct{})
+	start := time.Now()
+
+	var dst io.Writer = pipeWriter
+	if spool != nil {
+		dst = &spoolTee{w: pipeWriter, spool: spool}
+	}
+	counter := newWAVCounter(dst)
+	if j.codec == CodecFLAC {
+		counter = newFLACCounter(dst)
+	}
 
 	// Start conversion in goroutine
 	go func() {
+		defer close(converted)
 		defer func() {
 			if err := pipeWriter.Close(); err != nil {
 				select {
@@ -156,71 +1090,227 @@ func (s *Scriber) Process(ctx context.Context, in Input) error {
 			}
 		}()
 
-		if err := s.convertToWavFunc(in.Data, pipeWriter); err != nil {
+		input, stop := s.inputReader(j)
+		err := s.convert(ctx, j.ffmpegArgs, newPooledReader(input, s.buffers()), counter)
+		stop()
+		j.timing.Convert = time.Since(start)
+		if err != nil {
+			j.logger.Error("Conversion failed", slog.String("file", j.in.Name), slog.String("error", err.Error()))
+			j.logFFmpegOutput(err)
 			errCh <- fmt.Errorf("could not convert to wav: %w", err)
 			return
 		}
 		close(errCh)
 	}()
 
+	// Stop the conversion on every return path, and wait
+	// for it so that it doesn't outlive the call.
 	defer func() {
-		in.Data.Close()
 		pipeReader.Close()
+		cancel()
+		<-converted
 	}()
 
-	text, err := s.transcribeAudio(ctx, pipeReader, in)
+	text, err := s.transcribeAudio(ctx, j, pipeAudio{newPooledReader(pipeReader, s.buffers()), pipeReader}, counter.duration)
+	j.timing.Transcribe = time.Since(start)
+	if err != nil && ctx.Err() != nil {
+		// The upload is aborted as soon as the job is canceled, failing
+		// the conversion's writes to the pipe. Any other conversion
+		// failure means the job was canceled while converting.
+		<-converted
+		if convErr := <-errCh; convErr != nil && !errors.Is(convErr, errUploadAborted) {
+			return nil, stageError(StageConversion, convErr)
+		}
+	}
 	if err != nil {
-		return fmt.Errorf("could not transcribe audio: %w", err)
+		if spool != nil {
+			// The conversion carries on into the spool once the
+			// upload stops reading; wait for it so it can be retried.
+			pipeReader.Close()
+			select {
+			case convErr := <-errCh:
+				spool.complete = convErr == nil && !spool.overflow
+				j.audioDuration = counter.duration()
+				j.convertedBytes = counter.n
+				j.uploadSpec = counter.spec()
+				if convErr == nil {
+					logConverted(j)
+				}
+			case <-ctx.Done():
+			}
+		}
+		return nil, stageError(StageTranscription, fmt.Errorf("could not transcribe audio: %w", err))
 	}
 
+	// A finished conversion wins over a cancellation that came after it,
+	// which the checkpoint after transcription reports instead.
 	select {
 	case err := <-errCh:
 		if err != nil {
-			return err
+			return nil, stageError(StageConversion, err)
+		}
+	default:
+		select {
+		case err := <-errCh:
+			if err != nil {
+				return nil, stageError(StageConversion, err)
+			}
+		case <-ctx.Done():
+			return nil, stageError(StageConversion, ctx.Err())
 		}
-	case <-ctx.Done():
-		return ctx.Err()
 	}
 
-	select {
-	case s.resultsCh <- Output{
-		Name: generateOutputFileName(in.Name, in.OutputType),
-		Text: text,
-	}:
-	case <-ctx.Done():
-		return ctx.Err()
-	}
+	// The conversion goroutine is done, so its results are safe to read.
+	j.audioDuration = counter.duration()
+	j.convertedBytes = counter.n
+	j.uploadSpec = counter.spec()
+	logConverted(j)
+	return text, nil
+}
 
-	s.logger.Info("Processing complete", slog.String("file", in.Name))
-	return nil
+// logConverted logs the result of the job's conversion.
+func logConverted(j *job) {
+	j.logger.Debug("Converted audio",
+		slog.String("file", j.in.Name),
+		slog.Int64("converted_bytes", j.convertedBytes),
+		slog.Duration("audio_duration", j.audioDuration),
+	)
 }
 
+// Collect returns the channel Outputs are published on. Shutdown and
+// Close close it once every job is done.
 func (s *Scriber) Collect() <-chan Output {
 	return s.resultsCh
 }
 
-func (s *Scriber) transcribeAudio(ctx context.Context, audioData io.Reader, in Input) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
-	defer cancel()
+// transcribeAudio transcribes audioData. audio returns the duration of the
+// audio, which may grow as it is converted, or is nil if it is unknown.
+func (s *Scriber) transcribeAudio(ctx context.Context, j *job, audioData io.Reader, audio func() time.Duration) ([]byte, error) {
+	format, err := responseFormat(j.in.OutputType)
+	if err != nil {
+		return nil, stageError(StageValidation, err)
+	}
 
-	s.logger.Debug("Transcribing audio", slog.String("file", in.Name))
+	if err := s.checkDeadline(j, StageTranscription, s.minTranscriptionTimeFor(j, audio)); err != nil {
+		return nil, err
+	}
 
-	text, err := s.whisperClient.TranscribeAudio(ctx, whisperclient.TranscribeAudioInput{
-		Name:     in.Name,
-		Language: in.Language,
-		Format:   string(in.OutputType),
+	return s.requestTranscription(ctx, j, whisperclient.TranscribeAudioInput{
+		Name:     j.uploadName(j.codec),
+		Language: j.in.Language,
+		Format:   format,
 		Data:     audioData,
-	})
+	}, s.transcriptionTimeoutFor(j.in), s.audioTimeoutFor(audio))
+}
+
+// requestTranscription sends req to the transcription backend, giving up
+// after timeout, plus extension if not nil, once the upload has started.
+func (s *Scriber) requestTranscription(ctx context.Context, j *job, req whisperclient.TranscribeAudioInput, timeout time.Duration, extension func() time.Duration) ([]byte, error) {
+	var abort func(error)
+	if a, ok := req.Data.(abortableReader); ok {
+		abort = a.abort
+	}
+
+	// Audio converted on the fly must be aborted if it isn't uploaded,
+	// or the conversion blocks writing it.
+	unsent := func(err error) ([]byte, error) {
+		if abort != nil {
+			abort(err)
+		}
+		return nil, fmt.Errorf("transcription failed: %w", err)
+	}
+
+	if err := s.backendPause.wait(ctx); err != nil {
+		return unsent(err)
+	}
+
+	data, release, err := s.transcriptions.acquireWhenReady(ctx, req.Data)
+	if err != nil {
+		return unsent(err)
+	}
+	defer release()
+
+	ctx, audioData, cancel := withFirstByteTimeout(ctx, data, timeout, extension)
+	defer cancel()
+
+	// Clients don't necessarily watch ctx while reading the audio, so
+	// reads fail once it is done, and a pending read from the converter
+	// pipe is aborted.
+	ctxData, stop := newContextReader(ctx, audioData, abort)
+	defer stop()
+	req.Data = ctxData
+
+	j.enter(StageTranscription)
+	j.logger.Debug("Transcribing audio", slog.String("file", req.Name))
+
+	s.usage.record(0, 1)
+	text, err := s.callTranscriber(ctx, req)
 	if err != nil {
+		if cause := context.Cause(ctx); errors.Is(cause, errTranscriptionTimeout) || errors.Is(cause, errDeadlineExceeded) {
+			err = cause
+		}
+		if delay, ok := s.retryAfterDelay(err); ok {
+			j.logger.Warn("Backend asked to retry later, pausing transcriptions", slog.String("file", req.Name), slog.Duration("delay", delay))
+			s.backendPause.extend(delay)
+		}
 		return nil, fmt.Errorf("transcription failed: %w", err)
 	}
+
+	j.logger.Debug("Received transcription", slog.String("file", req.Name), slog.Int("size", len(text)))
 	return text, nil
 }
 
-func generateOutputFileName(filename string, outType OutputType) string {
-	ext := ".srt"
-	if outType == OutputTypeTranscript {
-		ext = ".txt"
+// buffers returns the pool used for internal copies.
+func (s *Scriber) buffers() *bufferPool {
+	if s.bufPool == nil {
+		return defaultBufferPool
+	}
+	return s.bufPool
+}
+
+// contextAttrs returns the attributes extracted from ctx, if an extractor is set.
+func (s *Scriber) contextAttrs(ctx context.Context) (attrs []slog.Attr, err error) {
+	if s.ctxAttrExtractor == nil {
+		return nil, nil
+	}
+
+	defer recoverPanic(&err)
+	return s.ctxAttrExtractor(ctx), nil
+}
+
+func attrsToArgs(attrs []slog.Attr) []any {
+	args := make([]any, len(attrs))
+	for i, a := range attrs {
+		args[i] = a
+	}
+	return args
+}
+
+// attrsToMetadata flattens attrs into a string map.
+// Group attributes are flattened using dot-separated keys.
+func attrsToMetadata(attrs []slog.Attr) map[string]string {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	md := make(map[string]string, len(attrs))
+
+	var flatten func(prefix string, attrs []slog.Attr)
+	flatten = func(prefix string, attrs []slog.Attr) {
+		for _, a := range attrs {
+			key := a.Key
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+
+			v := a.Value.Resolve()
+			if v.Kind() == slog.KindGroup {
+				flatten(key, v.Group())
+				continue
+			}
+			md[key] = v.String()
+		}
 	}
-	return strings.Replace(filename, filepath.Ext(filename), ext, 1)
+	flatten("", attrs)
+	return md
 }
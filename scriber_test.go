@@ -3,12 +3,18 @@ package scriber
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"log/slog"
 
+	"github.com/alesr/scriber/wav"
 	"github.com/alesr/whisperclient"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -22,6 +28,12 @@ func (m *mockWhisperClient) TranscribeAudio(ctx context.Context, in whisperclien
 	return m.transcribeAudioFunc(ctx, in)
 }
 
+// copyConverter stands in for ffmpeg, passing the input through.
+func copyConverter(_ context.Context, r io.Reader, w io.Writer) error {
+	_, err := io.Copy(w, r)
+	return err
+}
+
 func TestNew(t *testing.T) {
 	t.Parallel()
 
@@ -32,9 +44,54 @@ func TestNew(t *testing.T) {
 	scriber := New(logger, whisperCli)
 
 	require.NotNil(t, scriber)
-	assert.Equal(t, logger.WithGroup("scriber"), scriber.logger)
+	assert.Equal(t, slog.New(&guardHandler{next: logger.Handler(), guard: &scriber.logGuard}).WithGroup("scriber"), scriber.logger)
 	assert.Equal(t, whisperCli, scriber.whisperClient)
 	assert.NotNil(t, scriber.resultsCh)
+	assert.Nil(t, scriber.convertToWavFunc)
+	assert.NotNil(t, scriber.runFFmpeg)
+	assert.NotNil(t, scriber.probeDurationFunc)
+}
+
+func TestNew_ConvertersArePerInstance(t *testing.T) {
+	t.Parallel()
+
+	const instances = 10
+
+	mockClient := &mockWhisperClient{
+		transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			return io.ReadAll(in.Data)
+		},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < instances; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			marker := fmt.Sprintf("converter-%d", i)
+
+			scriber := New(noopLogger(), mockClient, WithConverter(func(_ context.Context, r io.Reader, w io.Writer) error {
+				if _, err := io.Copy(io.Discard, r); err != nil {
+					return err
+				}
+				_, err := io.WriteString(w, marker)
+				return err
+			}))
+
+			err := scriber.Process(context.TODO(), Input{
+				Name:       "test.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewBufferString("foo")),
+			})
+			assert.NoError(t, err)
+
+			output := <-scriber.Collect()
+			assert.Equal(t, marker, string(output.Text))
+		}(i)
+	}
+	wg.Wait()
 }
 
 func TestGenerateOutputFileName(t *testing.T) {
@@ -69,6 +126,56 @@ func TestGenerateOutputFileName(t *testing.T) {
 	}
 }
 
+func TestResponseFormat(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		givenOutType  OutputType
+		expected      string
+		expectedError error
+	}{
+		{
+			name:         "subtitles",
+			givenOutType: OutputTypeSubtitles,
+			expected:     whisperclient.FormatSrt,
+		},
+		{
+			name:         "transcript",
+			givenOutType: OutputTypeTranscript,
+			expected:     whisperclient.FormatText,
+		},
+		{
+			name:          "unmapped",
+			givenOutType:  OutputType("vtt"),
+			expectedError: errorOutputType,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := responseFormat(tc.givenOutType)
+			if tc.expectedError != nil {
+				require.ErrorIs(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+
+	t.Run("every supported output type is mapped", func(t *testing.T) {
+		t.Parallel()
+
+		for outType := range supportedOutputTypes {
+			_, err := responseFormat(outType)
+			assert.NoError(t, err, "output type %q has no response format", outType)
+		}
+	})
+}
+
 func TestTranscribeAudio(t *testing.T) {
 	t.Parallel()
 
@@ -90,7 +197,7 @@ func TestTranscribeAudio(t *testing.T) {
 	}
 
 	ctx := context.TODO()
-	text, err := scriber.transcribeAudio(ctx, audioData, in)
+	text, err := scriber.transcribeAudio(ctx, scriber.newJob(in, nil), audioData, nil)
 
 	require.NoError(t, err)
 	assert.Equal(t, []byte("mock transcription"), text)
@@ -134,6 +241,26 @@ func TestInputValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "uppercase extension",
+			input: Input{
+				Name:       "test.MP4",
+				OutputType: OutputTypeSubtitles,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewBufferString("mock data")),
+			},
+			wantErr: false,
+		},
+		{
+			name: "trailing dot",
+			input: Input{
+				Name:       "test.",
+				OutputType: OutputTypeSubtitles,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewBufferString("mock data")),
+			},
+			wantErr: true,
+		},
 		{
 			name: "unsupported output type",
 			input: Input{
@@ -162,6 +289,27 @@ func TestInputValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "data ref",
+			input: Input{
+				Name:       "test.mp4",
+				OutputType: OutputTypeSubtitles,
+				Language:   "en",
+				DataRef:    "uploads/test.mp4",
+			},
+			wantErr: false,
+		},
+		{
+			name: "data and data ref",
+			input: Input{
+				Name:       "test.mp4",
+				OutputType: OutputTypeSubtitles,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewBufferString("mock data")),
+				DataRef:    "uploads/test.mp4",
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -228,7 +376,7 @@ func TestProcess(t *testing.T) {
 			scriber := Scriber{
 				logger:        noopLogger(),
 				whisperClient: mockClient,
-				convertToWavFunc: func(r io.Reader, w io.Writer) error {
+				convertToWavFunc: func(_ context.Context, r io.Reader, w io.Writer) error {
 					_, err := io.Copy(w, r)
 					require.NoError(t, err)
 					return tc.givenConvertToWavErr
@@ -268,6 +416,619 @@ func TestProcess(t *testing.T) {
 	}
 }
 
+func TestProcess_ReportsAudioDurationAndTiming(t *testing.T) {
+	t.Parallel()
+
+	mockClient := &mockWhisperClient{
+		transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.Copy(io.Discard, in.Data)
+			require.NoError(t, err)
+			return []byte("mock transcription"), nil
+		},
+	}
+
+	scriber := New(noopLogger(), mockClient)
+	scriber.convertToWavFunc = func(_ context.Context, r io.Reader, w io.Writer) error {
+		time.Sleep(20 * time.Millisecond)
+		_, err := w.Write(syntheticWAV(42))
+		return err
+	}
+
+	err := scriber.Process(context.TODO(), Input{
+		Name:       "test.mp4",
+		OutputType: OutputTypeTranscript,
+		Language:   "en",
+		Data:       io.NopCloser(bytes.NewBufferString("foo")),
+	})
+	require.NoError(t, err)
+
+	output := <-scriber.Collect()
+
+	assert.Equal(t, 42*time.Second, output.AudioDuration)
+	assert.GreaterOrEqual(t, output.ProcessingTime.Convert, 20*time.Millisecond)
+	assert.GreaterOrEqual(t, output.ProcessingTime.Transcribe, output.ProcessingTime.Convert)
+	assert.GreaterOrEqual(t, output.ProcessingTime.Total, output.ProcessingTime.Transcribe)
+}
+
+func TestProcess_ClosesDataOnce(t *testing.T) {
+	t.Parallel()
+
+	validInput := func(data io.ReadCloser, keepOpen bool) Input {
+		return Input{
+			Name:       "test.mp4",
+			OutputType: OutputTypeTranscript,
+			Language:   "en",
+			Data:       data,
+			KeepOpen:   keepOpen,
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		givenInput    func(data io.ReadCloser) Input
+		givenConvErr  error
+		expectedErr   bool
+		expectedClose int32
+	}{
+		{
+			name:          "success",
+			givenInput:    func(data io.ReadCloser) Input { return validInput(data, false) },
+			expectedClose: 1,
+		},
+		{
+			name: "validation failure",
+			givenInput: func(data io.ReadCloser) Input {
+				in := validInput(data, false)
+				in.Language = ""
+				return in
+			},
+			expectedErr:   true,
+			expectedClose: 1,
+		},
+		{
+			name:          "conversion failure",
+			givenInput:    func(data io.ReadCloser) Input { return validInput(data, false) },
+			givenConvErr:  assert.AnError,
+			expectedErr:   true,
+			expectedClose: 1,
+		},
+		{
+			name:          "keep open on success",
+			givenInput:    func(data io.ReadCloser) Input { return validInput(data, true) },
+			expectedClose: 0,
+		},
+		{
+			name: "keep open on validation failure",
+			givenInput: func(data io.ReadCloser) Input {
+				in := validInput(data, true)
+				in.Name = ""
+				return in
+			},
+			expectedErr:   true,
+			expectedClose: 0,
+		},
+		{
+			name:          "keep open on conversion failure",
+			givenInput:    func(data io.ReadCloser) Input { return validInput(data, true) },
+			givenConvErr:  assert.AnError,
+			expectedErr:   true,
+			expectedClose: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.Copy(io.Discard, in.Data)
+					require.NoError(t, err)
+					return []byte("mock transcription"), nil
+				},
+			}
+
+			scriber := New(noopLogger(), mockClient)
+			scriber.convertToWavFunc = func(_ context.Context, r io.Reader, w io.Writer) error {
+				if _, err := io.Copy(w, r); err != nil {
+					return err
+				}
+				return tc.givenConvErr
+			}
+
+			data := &closeCounter{Reader: bytes.NewBufferString("foo")}
+
+			err := scriber.Process(context.TODO(), tc.givenInput(data))
+			if tc.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.expectedClose, data.closes.Load())
+		})
+	}
+}
+
+func TestRunConverter(t *testing.T) {
+	t.Parallel()
+
+	for _, bin := range []string{"cat", "sleep", "false"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s not available: %v", bin, err)
+		}
+	}
+
+	t.Run("copies input through the command", func(t *testing.T) {
+		t.Parallel()
+
+		var out bytes.Buffer
+		err := runConverter(exec.Command("cat"), bytes.NewBufferString("hello"), &out)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", out.String())
+	})
+
+	t.Run("command failure", func(t *testing.T) {
+		t.Parallel()
+
+		err := runConverter(exec.Command("false"), bytes.NewBufferString("hello"), io.Discard)
+		var exitErr *exec.ExitError
+		require.ErrorAs(t, err, &exitErr)
+	})
+
+	t.Run("input error kills the command", func(t *testing.T) {
+		t.Parallel()
+
+		start := time.Now()
+
+		// sleep never reads its stdin nor exits on its own.
+		err := runConverter(exec.Command("sleep", "30"), &failingReader{data: []byte("partial")}, io.Discard)
+		require.ErrorIs(t, err, assert.AnError)
+		assert.Less(t, time.Since(start), 10*time.Second)
+	})
+}
+
+func TestProcess_InputReadError(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skipf("cat not available: %v", err)
+	}
+
+	handler := newCapturingHandler()
+
+	mockClient := &mockWhisperClient{
+		transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.Copy(io.Discard, in.Data)
+			require.NoError(t, err)
+			return []byte("mock transcription"), nil
+		},
+	}
+
+	scriber := New(slog.New(handler), mockClient, WithContextAttrExtractor(func(ctx context.Context) []slog.Attr {
+		return []slog.Attr{slog.String("request_id", "req-1")}
+	}))
+	scriber.convertToWavFunc = func(_ context.Context, r io.Reader, w io.Writer) error {
+		return runConverter(exec.Command("cat"), r, w)
+	}
+
+	err := scriber.Process(context.TODO(), Input{
+		Name:       "test.mp4",
+		OutputType: OutputTypeTranscript,
+		Language:   "en",
+		Data:       io.NopCloser(&failingReader{data: []byte("partial")}),
+	})
+	require.ErrorIs(t, err, assert.AnError)
+
+	var logged bool
+	for _, e := range handler.entries() {
+		if e.msg == "Conversion failed" {
+			logged = true
+			assert.Equal(t, "req-1", e.attrs["scriber.request_id"])
+			assert.Contains(t, e.attrs["scriber.error"], assert.AnError.Error())
+		}
+	}
+	assert.True(t, logged, "conversion failure must be logged")
+}
+
+// failingReader returns data, then fails with assert.AnError.
+type failingReader struct {
+	data []byte
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, assert.AnError
+	}
+
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestProcess_ErrorStages(t *testing.T) {
+	t.Parallel()
+
+	validInput := func() Input {
+		return Input{
+			Name:       "test.mp4",
+			OutputType: OutputTypeSubtitles,
+			Language:   "en",
+			Data:       io.NopCloser(bytes.NewBufferString("foo")),
+		}
+	}
+
+	passthrough := copyConverter
+
+	respond := func(text string, err error) *mockWhisperClient {
+		return &mockWhisperClient{
+			transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+				_, _ = io.Copy(io.Discard, in.Data)
+				return []byte(text), err
+			},
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		givenInput    func() Input
+		givenClient   *mockWhisperClient
+		givenConvert  func(_ context.Context, r io.Reader, w io.Writer) error
+		givenOpts     []Option
+		givenCancel   bool
+		expectedStage Stage
+		expectedErr   error
+	}{
+		{
+			name: "validation",
+			givenInput: func() Input {
+				in := validInput()
+				in.Language = ""
+				return in
+			},
+			givenClient:   respond("ok", nil),
+			givenConvert:  passthrough,
+			expectedStage: StageValidation,
+			expectedErr:   errorLanguage,
+		},
+		{
+			name:          "conversion",
+			givenInput:    validInput,
+			givenClient:   respond("ok", nil),
+			givenConvert:  func(_ context.Context, r io.Reader, w io.Writer) error { return assert.AnError },
+			expectedStage: StageConversion,
+			expectedErr:   assert.AnError,
+		},
+		{
+			name:          "transcription",
+			givenInput:    validInput,
+			givenClient:   respond("", assert.AnError),
+			givenConvert:  passthrough,
+			expectedStage: StageTranscription,
+			expectedErr:   assert.AnError,
+		},
+		{
+			name:          "empty transcription",
+			givenInput:    validInput,
+			givenClient:   respond("", nil),
+			givenConvert:  passthrough,
+			expectedStage: StageTranscription,
+			expectedErr:   errEmptyTranscription,
+		},
+		{
+			name:        "chunked conversion",
+			givenInput:  validInput,
+			givenClient: respond("ok", nil),
+			givenConvert: func(_ context.Context, r io.Reader, w io.Writer) error {
+				_, err := io.WriteString(w, "this is not a wav stream")
+				return err
+			},
+			givenOpts:     []Option{WithChunking(ChunkConfig{Length: time.Second})},
+			expectedStage: StageConversion,
+			expectedErr:   wav.ErrNotWAV,
+		},
+		{
+			name:        "chunked stitching",
+			givenInput:  validInput,
+			givenClient: respond("not srt", nil),
+			givenConvert: func(_ context.Context, r io.Reader, w io.Writer) error {
+				_, err := w.Write(syntheticWAV(2))
+				return err
+			},
+			givenOpts:     []Option{WithChunking(ChunkConfig{Length: time.Second})},
+			expectedStage: StagePostProcess,
+		},
+		{
+			name:          "post-processing",
+			givenInput:    validInput,
+			givenClient:   respond("1\n00:00:00,000 --> 00:00:01,000\nhi\n", nil),
+			givenConvert:  passthrough,
+			givenOpts:     []Option{WithSpoolThreshold(1, "/nonexistent/dir")},
+			expectedStage: StagePostProcess,
+		},
+		{
+			name:          "canceled after transcription",
+			givenInput:    validInput,
+			givenClient:   respond("1\n00:00:00,000 --> 00:00:01,000\nhi\n", nil),
+			givenConvert:  passthrough,
+			givenCancel:   true,
+			expectedStage: StagePostProcess,
+			expectedErr:   context.Canceled,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			scriber := New(noopLogger(), tc.givenClient, append(tc.givenOpts, WithConverter(tc.givenConvert))...)
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+
+			if tc.givenCancel {
+				scriber.resultsCh = make(chan Output) // Nobody reads.
+				client := tc.givenClient.transcribeAudioFunc
+				tc.givenClient = &mockWhisperClient{
+					transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+						defer cancel()
+						return client(ctx, in)
+					},
+				}
+				scriber.whisperClient = tc.givenClient
+			}
+
+			err := scriber.Process(ctx, tc.givenInput())
+
+			var pe *ProcessError
+			require.ErrorAs(t, err, &pe)
+
+			if tc.givenCancel && pe.Stage == StageConversion {
+				// Cancellation may be observed while waiting for the converter.
+				return
+			}
+
+			assert.Equal(t, tc.expectedStage, pe.Stage)
+			assert.Equal(t, "test.mp4", pe.Input.Name)
+			assert.Equal(t, OutputTypeSubtitles, pe.Input.OutputType)
+			assert.Contains(t, err.Error(), string(tc.expectedStage))
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+// closeCounter is an io.ReadCloser that counts calls to Close.
+type closeCounter struct {
+	io.Reader
+	closes atomic.Int32
+}
+
+func (c *closeCounter) Close() error {
+	c.closes.Add(1)
+	return nil
+}
+
+func BenchmarkProcess(b *testing.B) {
+	sizes := []struct {
+		name string
+		size int64
+	}{
+		{name: "1MB", size: 1 << 20},
+		{name: "10MB", size: 10 << 20},
+		{name: "100MB", size: 100 << 20},
+		{name: "1GB", size: 1 << 30},
+	}
+
+	mockClient := &mockWhisperClient{
+		transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			if _, err := io.Copy(io.Discard, in.Data); err != nil {
+				return nil, err
+			}
+			return []byte("mock transcription"), nil
+		},
+	}
+
+	for _, sz := range sizes {
+		b.Run(sz.name, func(b *testing.B) {
+			scriber := New(noopLogger(), mockClient)
+			scriber.convertToWavFunc = copyConverter
+
+			b.ReportAllocs()
+			b.SetBytes(sz.size)
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				err := scriber.Process(context.TODO(), Input{
+					Name:       "bench.mp4",
+					OutputType: OutputTypeSubtitles,
+					Language:   "en",
+					Data:       io.NopCloser(&synthReader{remaining: sz.size}),
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+				<-scriber.Collect()
+			}
+		})
+	}
+}
+
+func BenchmarkGenerateOutputFileName(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = generateOutputFileName("some/path/to/recording.mp4", OutputTypeSubtitles)
+	}
+}
+
+func BenchmarkInputValidate(b *testing.B) {
+	in := Input{
+		Name:       "recording.mp4",
+		OutputType: OutputTypeSubtitles,
+		Language:   "en",
+		Data:       io.NopCloser(bytes.NewReader(nil)),
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := in.validate(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// synthReader produces the given number of zero bytes without allocating.
+type synthReader struct {
+	remaining int64
+}
+
+func (r *synthReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	clear(p)
+	r.remaining -= int64(len(p))
+	return len(p), nil
+}
+
+type requestIDKey struct{}
+
+func TestProcess_ContextAttrExtractor(t *testing.T) {
+	t.Parallel()
+
+	handler := newCapturingHandler()
+
+	mockClient := &mockWhisperClient{
+		transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.Copy(io.Discard, in.Data)
+			require.NoError(t, err)
+			return []byte("mock transcription"), nil
+		},
+	}
+
+	scriber := New(slog.New(handler), mockClient, WithContextAttrExtractor(func(ctx context.Context) []slog.Attr {
+		id, _ := ctx.Value(requestIDKey{}).(string)
+		return []slog.Attr{
+			slog.String("request_id", id),
+			slog.Group("tenant", slog.String("id", "acme")),
+		}
+	}))
+	scriber.convertToWavFunc = copyConverter
+
+	ctx := context.WithValue(context.TODO(), requestIDKey{}, "req-123")
+
+	err := scriber.Process(ctx, Input{
+		Name:       "test.mp4",
+		OutputType: OutputTypeSubtitles,
+		Language:   "en",
+		Data:       io.NopCloser(bytes.NewBufferString("foo")),
+	})
+	require.NoError(t, err)
+
+	output := <-scriber.Collect()
+	assert.Equal(t, "req-123", output.Metadata["request_id"])
+	assert.Equal(t, "acme", output.Metadata["tenant.id"])
+
+	entries := handler.entries()
+	require.NotEmpty(t, entries)
+
+	for _, e := range entries {
+		assert.Equal(t, "req-123", e.attrs["scriber.request_id"], e.msg)
+		assert.Equal(t, "acme", e.attrs["scriber.tenant.id"], e.msg)
+	}
+}
+
+func TestAttrsToMetadata(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, attrsToMetadata(nil))
+	assert.Equal(t,
+		map[string]string{"a": "1", "g.b": "true"},
+		attrsToMetadata([]slog.Attr{slog.Int("a", 1), slog.Group("g", slog.Bool("b", true))}),
+	)
+}
+
+type capturedEntry struct {
+	msg   string
+	attrs map[string]string
+}
+
+// capturingHandler is a slog.Handler that records every log entry
+// with its attributes flattened into dot-separated keys.
+type capturingHandler struct {
+	mu     *sync.Mutex
+	out    *[]capturedEntry
+	attrs  map[string]string
+	groups []string
+}
+
+func newCapturingHandler() *capturingHandler {
+	return &capturingHandler{
+		mu:    &sync.Mutex{},
+		out:   &[]capturedEntry{},
+		attrs: map[string]string{},
+	}
+}
+
+func (h *capturingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *capturingHandler) Handle(_ context.Context, r slog.Record) error {
+	attrs := make(map[string]string, len(h.attrs))
+	for k, v := range h.attrs {
+		attrs[k] = v
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		h.flatten(attrs, h.groups, a)
+		return true
+	})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.out = append(*h.out, capturedEntry{msg: r.Message, attrs: attrs})
+	return nil
+}
+
+func (h *capturingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = make(map[string]string, len(h.attrs)+len(attrs))
+	for k, v := range h.attrs {
+		h2.attrs[k] = v
+	}
+	for _, a := range attrs {
+		h.flatten(h2.attrs, h.groups, a)
+	}
+	return &h2
+}
+
+func (h *capturingHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.groups = append(append([]string{}, h.groups...), name)
+	return &h2
+}
+
+func (h *capturingHandler) flatten(dst map[string]string, groups []string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		for _, ga := range v.Group() {
+			h.flatten(dst, append(append([]string{}, groups...), a.Key), ga)
+		}
+		return
+	}
+	dst[strings.Join(append(append([]string{}, groups...), a.Key), ".")] = v.String()
+}
+
+func (h *capturingHandler) entries() []capturedEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]capturedEntry{}, *h.out...)
+}
+
 func noopLogger() *slog.Logger {
 	return slog.New(
 		slog.NewTextHandler(
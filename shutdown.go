@@ -0,0 +1,151 @@
+package scriber
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// AbandonedJobsError is returned by Shutdown when jobs were still
+// running at the deadline and had to be canceled.
+type AbandonedJobsError struct {
+	Abandoned int
+	Err       error
+}
+
+func (e *AbandonedJobsError) Error() string {
+	return fmt.Sprintf("shutdown abandoned %d jobs: %v", e.Abandoned, e.Err)
+}
+
+func (e *AbandonedJobsError) Unwrap() error { return e.Err }
+
+// admit registers j as in flight and returns a context that Shutdown
+// cancels if the job outlives its deadline. release must be called
+// once the job is done. admit fails once Shutdown has been called.
+func (s *Scriber) admit(ctx context.Context, j *job) (context.Context, func(), error) {
+	jobCtx, cancel := context.WithCancel(ctx)
+
+	admitted := s.outlets.open(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.inflight == nil {
+			s.inflight = make(map[*job]context.CancelFunc)
+		}
+		s.inflight[j] = cancel
+		s.jobs.Add(1)
+
+		if s.sequencer != nil {
+			j.seq = s.sequencer.submit()
+		}
+	})
+	if !admitted {
+		cancel()
+		return ctx, func() {}, errShuttingDown
+	}
+
+	release := func() {
+		s.mu.Lock()
+		delete(s.inflight, j)
+		s.mu.Unlock()
+
+		if s.sequencer != nil {
+			s.sequencer.finish(j.seq)
+		}
+
+		cancel()
+		s.jobs.Done()
+	}
+	return jobCtx, release, nil
+}
+
+// Shutdown stops accepting new jobs, making Process fail with a
+// ShutdownError, and waits for in-flight jobs to finish. If ctx is done
+// first, the remaining jobs are canceled, which kills their conversion
+// and aborts their upload, and Shutdown returns an *AbandonedJobsError
+// once they have returned.
+//
+// When all jobs are done, the channels returned by Collect, Partials,
+// and Errors are closed: receiving from them then yields zero values with
+// ok false, once the buffered values are drained. Anything sent once they
+// are closed is dropped and counted in PublishStats.DroppedAfterClose; a
+// job publishing its Output then fails with a ClosedError. With
+// WithResultOverflow, Shutdown first waits for the overflowed Outputs to be
+// received, until ctx is done, leaving the others for the next run.
+// Shutdown may be called more than once.
+func (s *Scriber) Shutdown(ctx context.Context) error {
+	s.outlets.drain()
+
+	done := make(chan struct{})
+	go func() {
+		s.jobs.Wait()
+		close(done)
+	}()
+
+	var err error
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.mu.Lock()
+		abandoned := len(s.inflight)
+		for _, cancel := range s.inflight {
+			cancel()
+		}
+		s.mu.Unlock()
+
+		<-done
+
+		if abandoned > 0 {
+			err = &AbandonedJobsError{Abandoned: abandoned, Err: ctx.Err()}
+		}
+	}
+
+	if s.overflow != nil {
+		if left := s.overflow.drain(ctx.Done()); left > 0 {
+			s.logger.Warn("Overflowed outputs left for the next run", slog.String("dir", s.overflow.dir), slog.Int("count", left))
+		}
+	}
+
+	s.outlets.close(func() {
+		close(s.resultsCh)
+		if s.partialsCh != nil {
+			close(s.partialsCh)
+		}
+		if s.errorsCh != nil {
+			close(s.errorsCh)
+		}
+	})
+	return err
+}
+
+// Close shuts s down like Shutdown, waiting for every in-flight job to
+// finish. Unlike Shutdown, it is meant to be called once: it returns a
+// ClosedError, without waiting, if s is already shutting down or closed.
+func (s *Scriber) Close() error {
+	if !s.outlets.drain() {
+		return errClosed
+	}
+	return s.Shutdown(context.Background())
+}
+
+// RunUntilSignal blocks until ctx is done or one of signals is received
+// (os.Interrupt when none are given), then shuts s down, giving in-flight
+// jobs up to grace to finish.
+func (s *Scriber) RunUntilSignal(ctx context.Context, grace time.Duration, signals ...os.Signal) error {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt}
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, signals...)
+	<-ctx.Done()
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+
+	return s.Shutdown(shutdownCtx)
+}
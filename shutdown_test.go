@@ -0,0 +1,190 @@
+package scriber
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdown(t *testing.T) {
+	t.Parallel()
+
+	newInput := func() Input {
+		return Input{
+			Name:       "test.mp4",
+			OutputType: OutputTypeTranscript,
+			Language:   "en",
+			Data:       io.NopCloser(strings.NewReader("data")),
+		}
+	}
+
+	passthrough := copyConverter
+
+	t.Run("waits for in-flight jobs", func(t *testing.T) {
+		t.Parallel()
+
+		started := make(chan struct{})
+		s := New(noopLogger(), &mockWhisperClient{
+			transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+				close(started)
+				time.Sleep(100 * time.Millisecond)
+				_, err := io.Copy(io.Discard, in.Data)
+				return []byte("done"), err
+			},
+		}, WithConverter(passthrough))
+
+		errCh := make(chan error, 1)
+		go func() { errCh <- s.Process(context.TODO(), newInput()) }()
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+		defer cancel()
+
+		require.NoError(t, s.Shutdown(ctx))
+		require.NoError(t, <-errCh)
+
+		out, ok := <-s.Collect()
+		require.True(t, ok)
+		assert.Equal(t, "done", string(out.Text))
+		out.Body.Close()
+
+		_, ok = <-s.Collect()
+		assert.False(t, ok, "results channel should be closed")
+	})
+
+	t.Run("rejects new jobs", func(t *testing.T) {
+		t.Parallel()
+
+		s := New(noopLogger(), &mockWhisperClient{}, WithConverter(passthrough))
+		require.NoError(t, s.Shutdown(context.TODO()))
+		require.NoError(t, s.Shutdown(context.TODO()), "shutdown should be idempotent")
+
+		data := &closeCounter{Reader: strings.NewReader("data")}
+		in := newInput()
+		in.Data = data
+
+		err := s.Process(context.TODO(), in)
+
+		var shutdownErr ShutdownError
+		require.ErrorAs(t, err, &shutdownErr)
+
+		var pe *ProcessError
+		require.ErrorAs(t, err, &pe)
+		assert.Equal(t, StageAdmission, pe.Stage)
+		assert.EqualValues(t, 1, data.closes.Load())
+	})
+
+	t.Run("cancels stragglers at the deadline", func(t *testing.T) {
+		t.Parallel()
+
+		const jobs = 3
+
+		var started sync.WaitGroup
+		started.Add(jobs)
+
+		s := New(noopLogger(), &mockWhisperClient{
+			transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+				started.Done()
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		}, WithConverter(passthrough))
+
+		errCh := make(chan error, jobs)
+		for range jobs {
+			go func() { errCh <- s.Process(context.TODO(), newInput()) }()
+		}
+		started.Wait()
+
+		ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+		defer cancel()
+
+		err := s.Shutdown(ctx)
+
+		var abandonedErr *AbandonedJobsError
+		require.ErrorAs(t, err, &abandonedErr)
+		assert.Equal(t, jobs, abandonedErr.Abandoned)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		for range jobs {
+			select {
+			case err := <-errCh:
+				assert.ErrorIs(t, err, context.Canceled)
+			case <-time.After(5 * time.Second):
+				t.Fatal("job still running after Shutdown returned")
+			}
+		}
+	})
+
+	t.Run("kills the converter of abandoned jobs", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := exec.LookPath("sleep"); err != nil {
+			t.Skipf("sleep not available: %v", err)
+		}
+
+		started := make(chan struct{})
+		s := New(noopLogger(), &mockWhisperClient{
+			transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+				close(started)
+				// Blocks until the converter exits, as an upload would.
+				_, err := io.Copy(io.Discard, in.Data)
+				return []byte("partial"), err
+			},
+		}, WithConverter(func(ctx context.Context, r io.Reader, w io.Writer) error {
+			// sleep never reads its stdin nor exits on its own.
+			return runConverter(exec.CommandContext(ctx, "sleep", "30"), r, w)
+		}))
+
+		errCh := make(chan error, 1)
+		go func() { errCh <- s.Process(context.TODO(), newInput()) }()
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err := s.Shutdown(ctx)
+
+		var abandonedErr *AbandonedJobsError
+		require.ErrorAs(t, err, &abandonedErr)
+		assert.Equal(t, 1, abandonedErr.Abandoned)
+		assert.Less(t, time.Since(start), 10*time.Second)
+
+		var pe *ProcessError
+		require.ErrorAs(t, <-errCh, &pe)
+		assert.Equal(t, StageConversion, pe.Stage)
+	})
+}
+
+func TestRunUntilSignal(t *testing.T) {
+	t.Parallel()
+
+	s := New(noopLogger(), &mockWhisperClient{})
+
+	ctx, cancel := context.WithCancel(context.TODO())
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.RunUntilSignal(ctx, time.Second) }()
+
+	cancel()
+
+	select {
+	case err := <-errCh:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunUntilSignal did not return")
+	}
+
+	err := s.Process(context.TODO(), Input{Name: "test.mp4"})
+	assert.True(t, errors.As(err, new(ShutdownError)))
+}
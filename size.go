@@ -0,0 +1,183 @@
+package scriber
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// progressStep is how often progress is reported, in bytes,
+// when the size of the input is unknown.
+const progressStep = 1 << 20
+
+// SizeMismatchPolicy controls what happens when an input
+// delivers more bytes than its Size hint declared.
+type SizeMismatchPolicy int
+
+const (
+	// SizeMismatchWarn logs a warning, reported in Output.Warnings
+	// as WarningSizeMismatch, and keeps reading.
+	SizeMismatchWarn SizeMismatchPolicy = iota
+
+	// SizeMismatchFail fails the job with a SizeMismatchError.
+	SizeMismatchFail
+)
+
+// Progress reports how much of an input has been read.
+type Progress struct {
+	JobID     string
+	Name      string
+	BytesRead int64
+
+	// Size is the input's Size hint, or zero if unknown.
+	Size int64
+
+	// Percent is BytesRead as a percentage of Size,
+	// or -1 when the size is unknown.
+	Percent float64
+}
+
+// WithMaxInputSize limits inputs to n bytes. Inputs whose Size hint exceeds
+// the limit fail before anything is read; others fail once more than n bytes
+// have been read. Both fail with an InputTooLargeError. Zero disables the limit.
+func WithMaxInputSize(n int64) Option {
+	return func(s *Scriber) {
+		s.maxInputSize = n
+	}
+}
+
+// WithSizeMismatchPolicy sets what happens when an input delivers more bytes
+// than its Size hint declared. Defaults to SizeMismatchWarn.
+func WithSizeMismatchPolicy(p SizeMismatchPolicy) Option {
+	return func(s *Scriber) {
+		s.sizeMismatchPolicy = p
+	}
+}
+
+// WithAdaptiveTimeout extends the transcription timeout by perMB
+// for every MiB of input, for inputs with a Size hint.
+func WithAdaptiveTimeout(perMB time.Duration) Option {
+	return func(s *Scriber) {
+		s.timeoutPerMB = perMB
+	}
+}
+
+// WithProgress sets a function called as inputs are read. It is called from
+// the goroutine reading the input, so it must not block. Progress is reported
+// on every whole percent when the input has a Size hint, and every MiB otherwise.
+func WithProgress(fn func(Progress)) Option {
+	return func(s *Scriber) {
+		s.progressFunc = fn
+	}
+}
+
+// checkSizeHint fails if the input's size hint already exceeds the maximum size.
+func (s *Scriber) checkSizeHint(in Input) error {
+	if s.maxInputSize > 0 && in.Size > s.maxInputSize {
+		return fmt.Errorf("%w: size %d exceeds %d bytes", errInputTooLarge, in.Size, s.maxInputSize)
+	}
+	return nil
+}
+
+// transcriptionTimeoutFor returns the transcription timeout for in.
+func (s *Scriber) transcriptionTimeoutFor(in Input) time.Duration {
+	if s.transcriptionTimeout <= 0 || s.timeoutPerMB <= 0 || in.Size <= 0 {
+		return s.transcriptionTimeout
+	}
+	return s.transcriptionTimeout + time.Duration(in.Size*int64(s.timeoutPerMB)/(1<<20))
+}
+
+// inputReader returns a reader over the job's data that enforces the
+// maximum size and the size mismatch policy, and reports progress.
+// The returned function must be called once done with the reader.
+func (s *Scriber) inputReader(j *job) (io.Reader, func()) {
+	r, stop := newIdleTimeoutReader(j.in.Data, s.inputIdleTimeout)
+	return &inputMeter{
+		r:        r,
+		j:        j,
+		max:      s.maxInputSize,
+		policy:   s.sizeMismatchPolicy,
+		progress: s.progressFunc,
+	}, stop
+}
+
+// inputMeter counts the bytes read from an input.
+type inputMeter struct {
+	r        io.Reader
+	j        *job
+	max      int64
+	policy   SizeMismatchPolicy
+	progress func(Progress)
+
+	n        int64
+	reported int64
+	warned   bool
+}
+
+func (m *inputMeter) Read(p []byte) (int, error) {
+	n, err := m.r.Read(p)
+	m.n += int64(n)
+	m.j.inputBytes = m.n
+
+	if m.max > 0 && m.n > m.max {
+		return n, fmt.Errorf("%w: read more than %d bytes", errInputTooLarge, m.max)
+	}
+
+	if size := m.j.in.Size; size > 0 && m.n > size {
+		if m.policy == SizeMismatchFail {
+			return n, fmt.Errorf("%w: read more than %d bytes", errSizeMismatch, size)
+		}
+		if !m.warned {
+			m.warned = true
+			if werr := m.j.warn(WarningSizeMismatch, fmt.Sprintf("read more than the declared size of %d bytes", size)); werr != nil {
+				return n, werr
+			}
+			m.j.logger.Warn("Input is larger than its declared size",
+				slog.String("file", m.j.in.Name),
+				slog.Int64("size", size),
+			)
+		}
+	}
+
+	if m.progress != nil && (n > 0 || err == io.EOF) {
+		if perr := m.report(err == io.EOF); perr != nil {
+			return n, fmt.Errorf("progress function failed: %w", perr)
+		}
+	}
+	return n, err
+}
+
+// report calls the progress function if enough has been read since the
+// last call. A panic in the progress function is returned as an error.
+func (m *inputMeter) report(done bool) (err error) {
+	size := m.j.in.Size
+
+	step := int64(progressStep)
+	if size > 0 {
+		step = max(size/100, 1)
+	}
+	if !done && m.n-m.reported < step {
+		return nil
+	}
+	if done && m.n == m.reported && m.reported > 0 {
+		return nil
+	}
+	m.reported = m.n
+
+	percent := -1.0
+	if size > 0 {
+		percent = min(float64(m.n)*100/float64(size), 100)
+	}
+
+	defer recoverPanic(&err)
+
+	m.progress(Progress{
+		JobID:     m.j.id,
+		Name:      m.j.in.Name,
+		BytesRead: m.n,
+		Size:      size,
+		Percent:   percent,
+	})
+	return nil
+}
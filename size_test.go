@@ -0,0 +1,320 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alesr/scriber/wav"
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_SizeHint(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("x"), 1000)
+
+	testCases := []struct {
+		name          string
+		givenOpts     []Option
+		givenSize     int64
+		expectedErr   error
+		expectedStage Stage
+		expectedWarn  bool
+		expectedRead  bool
+	}{
+		{
+			name:          "hint exceeds the maximum",
+			givenOpts:     []Option{WithMaxInputSize(500)},
+			givenSize:     1000,
+			expectedErr:   errInputTooLarge,
+			expectedStage: StageValidation,
+		},
+		{
+			name:          "stream exceeds the maximum",
+			givenOpts:     []Option{WithMaxInputSize(500)},
+			expectedErr:   errInputTooLarge,
+			expectedStage: StageConversion,
+			expectedRead:  true,
+		},
+		{
+			name:         "within the maximum",
+			givenOpts:    []Option{WithMaxInputSize(1000)},
+			givenSize:    1000,
+			expectedRead: true,
+		},
+		{
+			name:         "stream exceeds the hint with warn policy",
+			givenSize:    100,
+			expectedWarn: true,
+			expectedRead: true,
+		},
+		{
+			name:          "stream exceeds the hint with fail policy",
+			givenOpts:     []Option{WithSizeMismatchPolicy(SizeMismatchFail)},
+			givenSize:     100,
+			expectedErr:   errSizeMismatch,
+			expectedStage: StageConversion,
+			expectedRead:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := newCapturingHandler()
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.Copy(io.Discard, in.Data)
+					return []byte("text"), err
+				},
+			}
+
+			var read bool
+			opts := append([]Option{WithConverter(func(_ context.Context, r io.Reader, w io.Writer) error {
+				read = true
+				_, err := io.Copy(w, r)
+				return err
+			})}, tc.givenOpts...)
+
+			s := New(slog.New(handler), mockClient, opts...)
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "test.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(data)),
+				Size:       tc.givenSize,
+			})
+			assert.Equal(t, tc.expectedRead, read)
+
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+
+				var pe *ProcessError
+				require.ErrorAs(t, err, &pe)
+				assert.Equal(t, tc.expectedStage, pe.Stage)
+				return
+			}
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			out.Body.Close()
+
+			var warned bool
+			for _, e := range handler.entries() {
+				switch e.msg {
+				case "Input is larger than its declared size":
+					warned = true
+				case "Processing file":
+					if tc.givenSize > 0 {
+						assert.Equal(t, strconv.FormatInt(tc.givenSize, 10), e.attrs["scriber.size"])
+					}
+				}
+			}
+			assert.Equal(t, tc.expectedWarn, warned)
+		})
+	}
+}
+
+func TestProcess_Progress(t *testing.T) {
+	t.Parallel()
+
+	const size = 3 << 20
+
+	testCases := []struct {
+		name            string
+		givenSize       int64
+		expectedPercent float64
+	}{
+		{
+			name:            "known size",
+			givenSize:       size,
+			expectedPercent: 100,
+		},
+		{
+			name:            "unknown size",
+			expectedPercent: -1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				mu      sync.Mutex
+				reports []Progress
+			)
+
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.Copy(io.Discard, in.Data)
+					return []byte("text"), err
+				},
+			},
+				WithConverter(copyConverter),
+				WithProgress(func(p Progress) {
+					mu.Lock()
+					defer mu.Unlock()
+					reports = append(reports, p)
+				}),
+			)
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "test.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(make([]byte, size))),
+				Size:       tc.givenSize,
+			})
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			out.Body.Close()
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			require.NotEmpty(t, reports)
+
+			last := reports[len(reports)-1]
+			assert.EqualValues(t, size, last.BytesRead)
+			assert.Equal(t, tc.givenSize, last.Size)
+			assert.Equal(t, tc.expectedPercent, last.Percent)
+			assert.Equal(t, out.JobID, last.JobID)
+
+			for i := 1; i < len(reports); i++ {
+				assert.Greater(t, reports[i].BytesRead, reports[i-1].BytesRead)
+			}
+			if tc.givenSize == 0 {
+				assert.LessOrEqual(t, len(reports), size/progressStep+1)
+			} else {
+				assert.LessOrEqual(t, len(reports), 101)
+			}
+		})
+	}
+}
+
+func TestTranscriptionTimeoutFor(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		givenOpts []Option
+		givenSize int64
+		expected  time.Duration
+	}{
+		{
+			name:     "default",
+			expected: defaultTranscriptionTimeout,
+		},
+		{
+			name:      "adaptive with size",
+			givenOpts: []Option{WithAdaptiveTimeout(time.Second)},
+			givenSize: 10 << 20,
+			expected:  defaultTranscriptionTimeout + 10*time.Second,
+		},
+		{
+			name:      "adaptive without size",
+			givenOpts: []Option{WithAdaptiveTimeout(time.Second)},
+			expected:  defaultTranscriptionTimeout,
+		},
+		{
+			name:      "disabled timeout stays disabled",
+			givenOpts: []Option{WithTranscriptionTimeout(0), WithAdaptiveTimeout(time.Second)},
+			givenSize: 10 << 20,
+			expected:  0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := New(noopLogger(), &mockWhisperClient{}, tc.givenOpts...)
+			assert.Equal(t, tc.expected, s.transcriptionTimeoutFor(Input{Size: tc.givenSize}))
+		})
+	}
+}
+
+func TestProcess_ByteCounts(t *testing.T) {
+	t.Parallel()
+
+	const inputSize = 1234
+	audio := syntheticWAV(3)
+
+	testCases := []struct {
+		name              string
+		givenOpts         []Option
+		expectedConverted int64
+	}{
+		{
+			name:              "streaming",
+			expectedConverted: int64(len(audio)),
+		},
+		{
+			name:              "chunked",
+			givenOpts:         []Option{WithChunking(ChunkConfig{Length: time.Second})},
+			expectedConverted: 3 * (wav.HeaderSize + testWAVFormat.ByteRate()),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := newCapturingHandler()
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.Copy(io.Discard, in.Data)
+					return []byte("text"), err
+				},
+			}
+
+			opts := append([]Option{WithConverter(func(_ context.Context, r io.Reader, w io.Writer) error {
+				if _, err := io.Copy(io.Discard, r); err != nil {
+					return err
+				}
+				_, err := w.Write(audio)
+				return err
+			})}, tc.givenOpts...)
+
+			s := New(slog.New(handler), mockClient, opts...)
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "test.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(make([]byte, inputSize))),
+			})
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			out.Body.Close()
+
+			assert.EqualValues(t, inputSize, out.InputBytes)
+			assert.Equal(t, tc.expectedConverted, out.ConvertedBytes)
+
+			var logged bool
+			for _, e := range handler.entries() {
+				if e.msg == "Processing complete" {
+					logged = true
+					assert.Equal(t, strconv.Itoa(inputSize), e.attrs["scriber.input_bytes"])
+					assert.Equal(t, strconv.FormatInt(tc.expectedConverted, 10), e.attrs["scriber.converted_bytes"])
+				}
+			}
+			assert.True(t, logged)
+		})
+	}
+}
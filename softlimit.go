@@ -0,0 +1,129 @@
+package scriber
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// defaultSoftLimit is the fraction of a limit at which jobs warn by default.
+const defaultSoftLimit = 0.9
+
+// SoftLimits sets, for each limit, the fraction of it a job must reach to
+// warn that the limit is close, with a warning in Output.Warnings and a
+// log line. Jobs exceeding a limit don't warn, whether they fail or not.
+// Zero fractions default to 0.9; negative ones disable the warning.
+// Warnings are only raised for the limits that are configured.
+type SoftLimits struct {
+	// InputSize applies to the bytes read from the input, against
+	// WithMaxInputSize. It raises WarningInputSizeNearLimit.
+	InputSize float64
+
+	// UploadSize applies to the converted audio uploaded in a single
+	// request, against UploadLimit.MaxSize. It raises
+	// WarningUploadSizeNearLimit.
+	UploadSize float64
+
+	// UsageCap applies to the audio transcribed within the usage window,
+	// the job's included, against WithUsageCap. It raises
+	// WarningUsageNearCap.
+	UsageCap float64
+
+	// TenantQuota applies to the audio transcribed by the job's tenant
+	// today, the job's included, against TenantLimits.DailyAudioQuota.
+	// It raises WarningTenantQuotaNearLimit.
+	TenantQuota float64
+}
+
+// WithSoftLimits sets the fractions of the limits at which jobs warn.
+func WithSoftLimits(l SoftLimits) Option {
+	return func(s *Scriber) {
+		s.softLimits = l
+	}
+}
+
+// softLimitFraction returns the fraction of a limit to warn at,
+// given f, or zero if the warning is disabled.
+func softLimitFraction(f float64) float64 {
+	switch {
+	case f < 0:
+		return 0
+	case f == 0:
+		return defaultSoftLimit
+	default:
+		return f
+	}
+}
+
+// nearLimit reports whether used reaches fraction of limit without exceeding it.
+func nearLimit(used, limit int64, fraction float64) bool {
+	fraction = softLimitFraction(fraction)
+	if limit <= 0 || fraction == 0 || used > limit {
+		return false
+	}
+	return float64(used) >= fraction*float64(limit)
+}
+
+// checkSoftLimits warns about the limits the job came close to. It fails
+// if one of the warnings is escalated.
+func (s *Scriber) checkSoftLimits(ctx context.Context, j *job) error {
+	if s.chunking == nil && s.vad == nil && nearLimit(j.convertedBytes, s.uploadLimit.MaxSize, s.softLimits.UploadSize) {
+		// Chunks are fitted to the upload limit, so only single uploads warn.
+		if err := s.warnNearLimit(j, WarningUploadSizeNearLimit, "upload size", fmt.Sprintf("%d bytes", j.convertedBytes), fmt.Sprintf("%d bytes", s.uploadLimit.MaxSize)); err != nil {
+			return err
+		}
+	}
+
+	if nearLimit(j.inputBytes, s.maxInputSize, s.softLimits.InputSize) {
+		if err := s.warnNearLimit(j, WarningInputSizeNearLimit, "input size", fmt.Sprintf("%d bytes", j.inputBytes), fmt.Sprintf("%d bytes", s.maxInputSize)); err != nil {
+			return err
+		}
+	}
+
+	// Usage is recorded once the job succeeds, so count the job's audio in.
+	if limit := s.usage.limit; limit > 0 {
+		used := s.usage.stats().Audio + j.audioDuration
+		if nearLimit(int64(used), int64(limit), s.softLimits.UsageCap) {
+			if err := s.warnNearLimit(j, WarningUsageNearCap, "usage cap", used.String(), limit.String()); err != nil {
+				return err
+			}
+		}
+	}
+
+	if quota := s.tenantLimits.DailyAudioQuota; quota > 0 && s.tenants != nil {
+		used := s.tenants.used(tenantFromContext(ctx)) + j.audioDuration
+		if nearLimit(int64(used), int64(quota), s.softLimits.TenantQuota) {
+			if err := s.warnNearLimit(j, WarningTenantQuotaNearLimit, "tenant quota", used.String(), quota.String()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// warnNearLimit raises code for the limit named name, used up to used of limit.
+func (s *Scriber) warnNearLimit(j *job, code WarningCode, name, used, limit string) error {
+	if err := j.warn(code, fmt.Sprintf("%s is close to its limit: %s of %s", name, used, limit)); err != nil {
+		return err
+	}
+	j.logger.Warn("Close to a limit",
+		slog.String("file", j.in.Name),
+		slog.String("limit", name),
+		slog.String("used", used),
+		slog.String("max", limit),
+	)
+	return nil
+}
+
+// used returns the audio transcribed by tenant today.
+func (l *tenantLimiter) used(tenant string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	st, ok := l.tenants[tenant]
+	if !ok || !st.day.Equal(l.now().UTC().Truncate(24*time.Hour)) {
+		return 0
+	}
+	return st.used
+}
@@ -0,0 +1,203 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_SoftLimits(t *testing.T) {
+	t.Parallel()
+
+	const seconds = 10
+
+	var (
+		audio    = syntheticWAV(seconds)
+		size     = float64(len(audio)) // Read, converted, and uploaded alike.
+		duration = float64(seconds * time.Second)
+	)
+
+	testCases := []struct {
+		name    string
+		code    WarningCode
+		limitAt func(ratio float64) []Option // Options setting the limit to have the job use ratio of it.
+		overErr error                        // The error of a job over the limit, if it fails.
+	}{
+		{
+			name: "input size",
+			code: WarningInputSizeNearLimit,
+			limitAt: func(ratio float64) []Option {
+				return []Option{WithMaxInputSize(int64(math.Round(size / ratio)))}
+			},
+			overErr: errInputTooLarge,
+		},
+		{
+			name: "upload size",
+			code: WarningUploadSizeNearLimit,
+			limitAt: func(ratio float64) []Option {
+				return []Option{WithUploadLimit(UploadLimit{MaxSize: int64(math.Round(size / ratio))})}
+			},
+		},
+		{
+			name: "usage cap",
+			code: WarningUsageNearCap,
+			limitAt: func(ratio float64) []Option {
+				return []Option{WithUsageCap(time.Duration(duration / ratio))}
+			},
+		},
+		{
+			name: "tenant quota",
+			code: WarningTenantQuotaNearLimit,
+			limitAt: func(ratio float64) []Option {
+				return []Option{WithTenantKeyFunc(func(Input) string { return "acme" }, TenantLimits{
+					DailyAudioQuota: time.Duration(duration / ratio),
+				})}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		for _, ratio := range []float64{0.85, 0.95, 1.05} {
+			t.Run(fmt.Sprintf("%s at %.0f%%", tc.name, ratio*100), func(t *testing.T) {
+				t.Parallel()
+
+				s := New(noopLogger(), &mockWhisperClient{
+					transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+						_, err := io.ReadAll(in.Data)
+						return []byte("hello"), err
+					},
+				}, tc.limitAt(ratio)...)
+				s.convertToWavFunc = copyConverter
+
+				err := s.Process(context.TODO(), Input{
+					Name:       "talk.wav",
+					OutputType: OutputTypeTranscript,
+					Language:   "en",
+					Data:       io.NopCloser(bytes.NewReader(audio)),
+				})
+				if ratio > 1 && tc.overErr != nil {
+					require.ErrorIs(t, err, tc.overErr)
+					return
+				}
+				require.NoError(t, err)
+
+				out := <-s.Collect()
+				require.NoError(t, out.Body.Close())
+
+				var codes []WarningCode
+				for _, w := range out.Warnings {
+					codes = append(codes, w.Code)
+				}
+				if ratio == 0.95 {
+					assert.Equal(t, []WarningCode{tc.code}, codes)
+				} else {
+					assert.Empty(t, codes)
+				}
+			})
+		}
+	}
+}
+
+func TestProcess_SoftLimitsConfig(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		givenOpts     []Option
+		expectedCodes []WarningCode
+		expectedFail  bool
+	}{
+		{
+			name:          "default fraction",
+			givenOpts:     []Option{WithUsageCap(11 * time.Second)},
+			expectedCodes: []WarningCode{WarningUsageNearCap},
+		},
+		{
+			name: "lower fraction",
+			givenOpts: []Option{
+				WithUsageCap(20 * time.Second),
+				WithSoftLimits(SoftLimits{UsageCap: 0.5}),
+			},
+			expectedCodes: []WarningCode{WarningUsageNearCap},
+		},
+		{
+			name: "disabled",
+			givenOpts: []Option{
+				WithUsageCap(11 * time.Second),
+				WithSoftLimits(SoftLimits{UsageCap: -1}),
+			},
+		},
+		{
+			name: "other limits unaffected",
+			givenOpts: []Option{
+				WithUsageCap(11 * time.Second),
+				WithSoftLimits(SoftLimits{InputSize: -1}),
+			},
+			expectedCodes: []WarningCode{WarningUsageNearCap},
+		},
+		{
+			name: "escalated",
+			givenOpts: []Option{
+				WithUsageCap(11 * time.Second),
+				WithWarningsAsErrors(WarningUsageNearCap),
+			},
+			expectedFail: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.ReadAll(in.Data)
+					return []byte("hello"), err
+				},
+			}, tc.givenOpts...)
+			s.convertToWavFunc = copyConverter
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "talk.wav",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(syntheticWAV(10))),
+			})
+			if tc.expectedFail {
+				var werr *WarningError
+				require.ErrorAs(t, err, &werr)
+				assert.Equal(t, WarningUsageNearCap, werr.Code)
+				return
+			}
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+
+			var codes []WarningCode
+			for _, w := range out.Warnings {
+				codes = append(codes, w.Code)
+			}
+			assert.Equal(t, tc.expectedCodes, codes)
+		})
+	}
+}
+
+func TestNearLimit(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, nearLimit(89, 100, 0))
+	assert.True(t, nearLimit(90, 100, 0))
+	assert.True(t, nearLimit(100, 100, 0))
+	assert.False(t, nearLimit(101, 100, 0))
+	assert.False(t, nearLimit(95, 100, -1))
+	assert.False(t, nearLimit(95, 0, 0), "no limit")
+}
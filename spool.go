@@ -0,0 +1,111 @@
+package scriber
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// spooledBody is an io.ReadCloser backed by a temporary file.
+// Closing it removes the file. If the body is never closed,
+// a finalizer removes the file once the body is garbage collected.
+type spooledBody struct {
+	mu     sync.Mutex
+	f      *os.File
+	closed bool
+}
+
+// newSpooledBody writes data to a temporary file in dir
+// and returns a body that reads it from the start.
+func newSpooledBody(dir string, data []byte) (*spooledBody, error) {
+	f, err := os.CreateTemp(dir, "scriber-output-*")
+	if err != nil {
+		return nil, fmt.Errorf("could not create spool file: %w", err)
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("could not write spool file: %w", err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("could not rewind spool file: %w", err)
+	}
+
+	b := &spooledBody{f: f}
+	runtime.SetFinalizer(b, (*spooledBody).Close)
+	return b, nil
+}
+
+func (b *spooledBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return 0, os.ErrClosed
+	}
+	return b.f.Read(p)
+}
+
+// Close closes and removes the underlying file. It is safe to call more than once.
+func (b *spooledBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	runtime.SetFinalizer(b, nil)
+
+	closeErr := b.f.Close()
+	if err := os.Remove(b.f.Name()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not remove spool file: %w", err)
+	}
+	return closeErr
+}
+
+// newOutputBody returns the body for an output payload.
+// Payloads larger than threshold are spooled to a temporary file
+// and the returned text is nil. Smaller payloads are returned as is,
+// with an in-memory body over them.
+func newOutputBody(text []byte, threshold int64, dir string) ([]byte, io.ReadCloser, error) {
+	if threshold <= 0 || int64(len(text)) <= threshold {
+		return text, io.NopCloser(bytes.NewReader(text)), nil
+	}
+
+	body, err := newSpooledBody(dir, text)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, body, nil
+}
+
+// spoolInput copies r to a temporary file in dir, so that it can be read
+// more than once. The returned file is rewound; the caller must close and
+// remove it.
+func spoolInput(r io.Reader, dir string, pool *bufferPool) (*os.File, error) {
+	f, err := os.CreateTemp(dir, "scriber-input-*")
+	if err != nil {
+		return nil, fmt.Errorf("could not create input spool: %w", err)
+	}
+
+	if _, err := io.Copy(f, newPooledReader(r, pool)); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("could not write input spool: %w", err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("could not rewind input spool: %w", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,173 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOutputBody(t *testing.T) {
+	t.Parallel()
+
+	t.Run("below threshold keeps text in memory", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+
+		text, body, err := newOutputBody([]byte("small"), 10, dir)
+		require.NoError(t, err)
+		defer body.Close()
+
+		assert.Equal(t, []byte("small"), text)
+
+		got, err := io.ReadAll(body)
+		require.NoError(t, err)
+		assert.Equal(t, "small", string(got))
+
+		assertDirEmpty(t, dir)
+	})
+
+	t.Run("spooling disabled", func(t *testing.T) {
+		t.Parallel()
+
+		text, body, err := newOutputBody(bytes.Repeat([]byte("a"), 100), 0, t.TempDir())
+		require.NoError(t, err)
+		defer body.Close()
+
+		assert.Len(t, text, 100)
+	})
+
+	t.Run("above threshold spools to disk", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		payload := bytes.Repeat([]byte("a"), 100)
+
+		text, body, err := newOutputBody(payload, 10, dir)
+		require.NoError(t, err)
+
+		assert.Nil(t, text)
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+
+		got, err := io.ReadAll(body)
+		require.NoError(t, err)
+		assert.Equal(t, payload, got)
+
+		require.NoError(t, body.Close())
+		require.NoError(t, body.Close(), "close must be idempotent")
+
+		_, err = body.Read(make([]byte, 1))
+		assert.ErrorIs(t, err, os.ErrClosed)
+
+		assertDirEmpty(t, dir)
+	})
+
+	t.Run("unread body is removed when collected", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+
+		_, body, err := newOutputBody(bytes.Repeat([]byte("a"), 100), 10, dir)
+		require.NoError(t, err)
+		require.NotNil(t, body)
+		body = nil
+
+		require.Eventually(t, func() bool {
+			runtime.GC()
+			entries, err := os.ReadDir(dir)
+			return err == nil && len(entries) == 0
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestProcess_SpoolsLargeOutputs(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	payload := bytes.Repeat([]byte("x"), 1024)
+
+	mockClient := &mockWhisperClient{
+		transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.Copy(io.Discard, in.Data)
+			require.NoError(t, err)
+			return payload, nil
+		},
+	}
+
+	scriber := New(noopLogger(), mockClient, WithSpoolThreshold(512, dir))
+	scriber.convertToWavFunc = copyConverter
+
+	err := scriber.Process(context.TODO(), Input{
+		Name:       "test.mp4",
+		OutputType: OutputTypeTranscript,
+		Language:   "en",
+		Data:       io.NopCloser(bytes.NewBufferString("foo")),
+	})
+	require.NoError(t, err)
+
+	output := <-scriber.Collect()
+	assert.Nil(t, output.Text)
+
+	got, err := io.ReadAll(output.Body)
+	require.NoError(t, err)
+	assert.Equal(t, payload, got)
+
+	require.NoError(t, output.Body.Close())
+	assertDirEmpty(t, dir)
+}
+
+func TestProcess_RemovesSpoolWhenPublishFails(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.TODO())
+
+	mockClient := &mockWhisperClient{
+		transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.Copy(io.Discard, in.Data)
+			require.NoError(t, err)
+			cancel()
+			return bytes.Repeat([]byte("x"), 1024), nil
+		},
+	}
+
+	scriber := New(noopLogger(), mockClient, WithSpoolThreshold(512, dir))
+	scriber.resultsCh = make(chan Output) // Nobody reads.
+	scriber.convertToWavFunc = copyConverter
+
+	err := scriber.Process(ctx, Input{
+		Name:       "test.mp4",
+		OutputType: OutputTypeTranscript,
+		Language:   "en",
+		Data:       io.NopCloser(bytes.NewBufferString("foo")),
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	assertDirEmpty(t, dir)
+}
+
+func assertDirEmpty(t *testing.T, dir string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, filepath.Join(dir, e.Name()))
+	}
+	assert.Empty(t, names)
+}
@@ -0,0 +1,22 @@
+package scriber
+
+import (
+	"bytes"
+
+	"github.com/alesr/scriber/subtitle"
+)
+
+// parseSRT parses an SRT document into cues, leniently, since
+// transcriptions come from backends and users alike.
+// Cue indexes are ignored; they are regenerated when formatting.
+func parseSRT(data []byte) ([]subtitle.Cue, error) {
+	return subtitle.ParseSRT(bytes.NewReader(data), subtitle.Lenient())
+}
+
+// formatSRT serializes cues, numbering them from 1.
+func formatSRT(cues []subtitle.Cue) []byte {
+	var b bytes.Buffer
+	// Writes to a bytes.Buffer don't fail.
+	_ = subtitle.WriteSRT(&b, cues)
+	return b.Bytes()
+}
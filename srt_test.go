@@ -0,0 +1,30 @@
+package scriber
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alesr/scriber/subtitle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSRT(t *testing.T) {
+	t.Parallel()
+
+	// Transcriptions are parsed leniently.
+	given := "\ufeff1\r\n00:00:01,000 --> 00:00:02,500\r\nHello\r\nworld\r\n2\n00:01:00,000 --> 01:00:00,001\nBye\n\n01:00,000 --> 01:01,000\nHourless\n"
+
+	cues, err := parseSRT([]byte(given))
+	require.NoError(t, err)
+
+	assert.Equal(t, []subtitle.Cue{
+		{Start: time.Second, End: 2500 * time.Millisecond, Text: "Hello\nworld"},
+		{Start: time.Minute, End: time.Hour + time.Millisecond, Text: "Bye"},
+		{Start: time.Minute, End: time.Minute + time.Second, Text: "Hourless"},
+	}, cues)
+
+	parsed, err := parseSRT(formatSRT(cues))
+	require.NoError(t, err)
+	assert.Equal(t, cues, parsed)
+}
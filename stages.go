@@ -0,0 +1,96 @@
+package scriber
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Convert converts the media read from r to WAV audio written to w, as
+// Process does before transcribing it. It honors the options that apply
+// to conversions: the converter, the conversion slots, and the input idle
+// timeout. The audio is streamed, so w receives it while r is still read.
+func (s *Scriber) Convert(ctx context.Context, r io.Reader, w io.Writer) error {
+	r, stop := newIdleTimeoutReader(r, s.inputIdleTimeout)
+	defer stop()
+	if err := s.convert(ctx, nil, newPooledReader(r, s.buffers()), w); err != nil {
+		return fmt.Errorf("could not convert to wav: %w", err)
+	}
+	return nil
+}
+
+// Transcribe sends audio, named name, to the transcription backend and returns
+// the transcription of type outType in language, as Process does once the
+// input is converted. It honors the options that apply to transcriptions:
+// the upload slots, the timeouts, and retries if audio implements io.Seeker.
+// The transcription is returned as is, without post-processing.
+//
+// The idempotency key of ctx, if any, is forwarded to the backend;
+// otherwise one is generated. So is its user tag, or the default one.
+func (s *Scriber) Transcribe(ctx context.Context, name, language string, outType OutputType, audio io.Reader) ([]byte, error) {
+	in := Input{Name: name, Language: language, OutputType: outType, UserTag: s.inputDefaults.UserTag}
+	if key, ok := IdempotencyKeyFromContext(ctx); ok {
+		in.IdempotencyKey = key
+	}
+	if tag, ok := UserTagFromContext(ctx); ok {
+		in.UserTag = tag
+	}
+
+	in, err := s.normalizeInput(in)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+
+	if err := in.validateTranscription(); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+
+	attrs, err := s.contextAttrs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not extract context attributes: %w", err)
+	}
+
+	j := s.newJob(in, attrs)
+	j.callerAudio()
+	ctx = withIdempotencyKey(ctx, j.idempotencyKey)
+	ctx = withUserTag(ctx, in.UserTag)
+
+	seeker, ok := audio.(io.ReadSeeker)
+	if s.retry == nil || !ok {
+		return s.transcribeAudio(ctx, j, newPooledReader(audio, s.buffers()), nil)
+	}
+
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("could not get audio position: %w", err)
+	}
+
+	return s.transcribeRetrying(ctx, j, func() io.Reader {
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return errReader{fmt.Errorf("could not rewind audio: %w", err)}
+		}
+		return seeker
+	}, 0)
+}
+
+// validateTranscription validates the fields of i needed to transcribe it.
+func (i *Input) validateTranscription() error {
+	if i.Name == "" {
+		return errNameRequired
+	}
+	if _, ok := supportedOutputTypes[i.OutputType]; !ok {
+		return fmt.Errorf("%w: %q", errorOutputType, i.OutputType)
+	}
+	if i.Language == "" {
+		return errorLanguage
+	}
+	if i.Language == LanguageAuto {
+		return errLanguageAuto
+	}
+	return nil
+}
+
+// errReader is a reader failing with err.
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
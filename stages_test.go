@@ -0,0 +1,236 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScriber_Convert(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		givenInput  io.Reader
+		givenConv   func(ctx context.Context, r io.Reader, w io.Writer) error
+		expected    string
+		expectedErr error
+	}{
+		{
+			name:       "converts",
+			givenInput: strings.NewReader("media"),
+			givenConv: func(_ context.Context, r io.Reader, w io.Writer) error {
+				data, err := io.ReadAll(r)
+				if err != nil {
+					return err
+				}
+				_, err = w.Write(bytes.ToUpper(data))
+				return err
+			},
+			expected: "MEDIA",
+		},
+		{
+			name:       "converter fails",
+			givenInput: strings.NewReader("media"),
+			givenConv: func(context.Context, io.Reader, io.Writer) error {
+				return assert.AnError
+			},
+			expectedErr: assert.AnError,
+		},
+		{
+			name:       "converter panics",
+			givenInput: strings.NewReader("media"),
+			givenConv: func(context.Context, io.Reader, io.Writer) error {
+				panic("boom")
+			},
+			expectedErr: &PanicError{},
+		},
+		{
+			name:        "input stalls",
+			givenInput:  stalledReader{},
+			givenConv:   copyConverter,
+			expectedErr: errInputStalled,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := New(noopLogger(), &mockWhisperClient{},
+				WithConverter(tc.givenConv),
+				WithInputIdleTimeout(50*time.Millisecond),
+			)
+
+			var out bytes.Buffer
+			err := s.Convert(context.TODO(), tc.givenInput, &out)
+
+			if tc.expectedErr != nil {
+				var pe *PanicError
+				if errors.As(tc.expectedErr, &pe) {
+					assert.ErrorAs(t, err, &pe)
+					return
+				}
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, out.String())
+		})
+	}
+}
+
+// stalledReader never returns.
+type stalledReader struct{}
+
+func (stalledReader) Read([]byte) (int, error) { select {} }
+
+func TestScriber_Transcribe(t *testing.T) {
+	t.Parallel()
+
+	audio := syntheticWAV(2)
+
+	testCases := []struct {
+		name             string
+		givenName        string
+		givenLanguage    string
+		givenType        OutputType
+		givenKey         string
+		givenSeekable    bool
+		givenFailures    int32
+		expectedFormat   string
+		expectedCalls    int32
+		expectedErr      error
+		expectedAnyError bool
+	}{
+		{
+			name:           "subtitles",
+			givenName:      "talk.wav",
+			givenLanguage:  "pt",
+			givenType:      OutputTypeSubtitles,
+			expectedFormat: whisperclient.FormatSrt,
+			expectedCalls:  1,
+		},
+		{
+			name:           "idempotency key of the context",
+			givenName:      "talk.wav",
+			givenLanguage:  "pt",
+			givenType:      OutputTypeTranscript,
+			givenKey:       "key",
+			expectedFormat: whisperclient.FormatText,
+			expectedCalls:  1,
+		},
+		{
+			name:           "seekable audio is retried",
+			givenName:      "talk.wav",
+			givenLanguage:  "pt",
+			givenType:      OutputTypeTranscript,
+			givenSeekable:  true,
+			givenFailures:  1,
+			expectedFormat: whisperclient.FormatText,
+			expectedCalls:  2,
+		},
+		{
+			name:             "streamed audio isn't retried",
+			givenName:        "talk.wav",
+			givenLanguage:    "pt",
+			givenType:        OutputTypeTranscript,
+			givenFailures:    1,
+			expectedFormat:   whisperclient.FormatText,
+			expectedCalls:    1,
+			expectedAnyError: true,
+		},
+		{
+			name:          "name required",
+			givenLanguage: "pt",
+			givenType:     OutputTypeTranscript,
+			expectedErr:   errNameRequired,
+		},
+		{
+			name:          "language detection",
+			givenName:     "talk.wav",
+			givenLanguage: LanguageAuto,
+			givenType:     OutputTypeTranscript,
+			expectedErr:   errLanguageAuto,
+		},
+		{
+			name:          "unsupported output type",
+			givenName:     "talk.wav",
+			givenLanguage: "pt",
+			givenType:     "poem",
+			expectedErr:   errorOutputType,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls atomic.Int32
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					data, err := io.ReadAll(in.Data)
+					require.NoError(t, err)
+					assert.Equal(t, audio, data)
+
+					assert.Equal(t, tc.givenName, in.Name)
+					assert.Equal(t, tc.givenLanguage, in.Language)
+					assert.Equal(t, tc.expectedFormat, in.Format)
+
+					key, ok := IdempotencyKeyFromContext(ctx)
+					assert.True(t, ok)
+					if tc.givenKey != "" {
+						assert.Equal(t, tc.givenKey, key)
+					}
+
+					if calls.Add(1) <= tc.givenFailures {
+						return nil, &net.OpError{Op: "read", Err: errors.New("connection reset")}
+					}
+					return []byte("text"), nil
+				},
+			}
+
+			s := New(noopLogger(), mockClient,
+				WithRetry(RetryConfig{Attempts: 3}),
+				WithConverter(func(context.Context, io.Reader, io.Writer) error {
+					t.Error("the audio was converted")
+					return nil
+				}),
+			)
+
+			ctx := context.TODO()
+			if tc.givenKey != "" {
+				ctx = withIdempotencyKey(ctx, tc.givenKey)
+			}
+
+			var data io.Reader = bytes.NewReader(audio)
+			if !tc.givenSeekable {
+				data = io.MultiReader(data)
+			}
+
+			text, err := s.Transcribe(ctx, tc.givenName, tc.givenLanguage, tc.givenType, data)
+			assert.Equal(t, tc.expectedCalls, calls.Load())
+
+			switch {
+			case tc.expectedErr != nil:
+				assert.ErrorIs(t, err, tc.expectedErr)
+			case tc.expectedAnyError:
+				assert.Error(t, err)
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, "text", string(text))
+			}
+		})
+	}
+}
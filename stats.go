@@ -0,0 +1,69 @@
+package scriber
+
+import (
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TextStats holds simple length and density statistics of a transcript.
+type TextStats struct {
+	// WordCount counts whitespace-separated words. Chinese and Japanese
+	// text (Han, Hiragana, and Katakana) isn't separated by spaces, so each
+	// such character counts as one word instead. Korean uses spaces and is
+	// counted like other languages.
+	WordCount int
+
+	// CharCount counts the runes of the text, excluding whitespace.
+	CharCount int
+
+	// WordsPerMinute is WordCount divided by the audio duration.
+	// It is zero when the duration is unknown.
+	WordsPerMinute float64
+}
+
+// computeTextStats computes statistics over the plain text view of a transcript.
+func computeTextStats(text string, duration time.Duration) TextStats {
+	var stats TextStats
+
+	for _, field := range strings.Fields(text) {
+		stats.CharCount += utf8.RuneCountInString(field)
+		stats.WordCount += countWords(field)
+	}
+
+	if duration > 0 {
+		stats.WordsPerMinute = float64(stats.WordCount) / duration.Minutes()
+	}
+	return stats
+}
+
+// countWords counts the words in a whitespace-free field.
+// Every CJK character counts as a word; any run of other
+// characters that contains a letter or digit counts as one.
+func countWords(field string) int {
+	var (
+		n      int
+		inWord bool
+	)
+
+	for _, r := range field {
+		switch {
+		case isCJK(r):
+			n++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if !inWord {
+				n++
+				inWord = true
+			}
+		}
+	}
+	return n
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r)
+}
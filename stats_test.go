@@ -0,0 +1,120 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeTextStats(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		givenText     string
+		givenDuration time.Duration
+		expected      TextStats
+	}{
+		{
+			name:          "english",
+			givenText:     "Hello there, don't panic!\nIt's fine.",
+			givenDuration: 30 * time.Second,
+			expected:      TextStats{WordCount: 6, CharCount: 31, WordsPerMinute: 12},
+		},
+		{
+			name:      "unknown duration",
+			givenText: "one two three",
+			expected:  TextStats{WordCount: 3, CharCount: 11},
+		},
+		{
+			name:      "japanese counts characters",
+			givenText: "こんにちは世界",
+			expected:  TextStats{WordCount: 7, CharCount: 7},
+		},
+		{
+			name:      "chinese mixed with latin",
+			givenText: "我爱 Go 语言",
+			expected:  TextStats{WordCount: 5, CharCount: 6},
+		},
+		{
+			name:      "korean uses spaces",
+			givenText: "안녕하세요 세계",
+			expected:  TextStats{WordCount: 2, CharCount: 7},
+		},
+		{
+			name:      "punctuation only",
+			givenText: "... — !!",
+			expected:  TextStats{WordCount: 0, CharCount: 6},
+		},
+		{
+			name:     "empty",
+			expected: TextStats{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, computeTextStats(tc.givenText, tc.givenDuration))
+		})
+	}
+}
+
+func TestProcess_TextStats(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		outputType OutputType
+		response   string
+	}{
+		{
+			name:       "transcript",
+			outputType: OutputTypeTranscript,
+			response:   "one two three four five six",
+		},
+		{
+			name:       "subtitles",
+			outputType: OutputTypeSubtitles,
+			response:   "1\n00:00:00,000 --> 00:00:05,000\none two three\n\n2\n00:00:05,000 --> 00:00:10,000\nfour five six\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.Copy(io.Discard, in.Data)
+					require.NoError(t, err)
+					return []byte(tc.response), nil
+				},
+			}
+
+			scriber := New(noopLogger(), mockClient)
+			scriber.convertToWavFunc = func(_ context.Context, r io.Reader, w io.Writer) error {
+				_, err := w.Write(syntheticWAV(12))
+				return err
+			}
+
+			err := scriber.Process(context.TODO(), Input{
+				Name:       "test.mp4",
+				OutputType: tc.outputType,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewBufferString("foo")),
+			})
+			require.NoError(t, err)
+
+			output := <-scriber.Collect()
+			assert.Equal(t, TextStats{WordCount: 6, CharCount: 22, WordsPerMinute: 30}, output.TextStats)
+		})
+	}
+}
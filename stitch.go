@@ -0,0 +1,131 @@
+package scriber
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alesr/scriber/subtitle"
+)
+
+// duplicateCueSimilarity is the minimum text similarity for a cue in an
+// overlap region to be considered a re-transcription of an earlier cue.
+const duplicateCueSimilarity = 0.6
+
+// SubtitleChunk is an SRT document transcribed from a chunk of audio
+// starting at Offset within the full recording.
+type SubtitleChunk struct {
+	Offset time.Duration
+	SRT    []byte
+}
+
+// StitchSubtitles merges SRT documents transcribed from overlapping chunks
+// into a single document. Every cue is shifted by its chunk's offset.
+// Cues in an overlap region that repeat (by fuzzy text match) or fall entirely
+// within what earlier chunks already covered are dropped; a repeated cue that
+// extends past the chunk boundary replaces the truncated earlier one.
+// Cues are renumbered from 1. Empty chunks are skipped.
+func StitchSubtitles(chunks []SubtitleChunk) ([]byte, error) {
+	parsed := make([][]subtitle.Cue, len(chunks))
+	offsets := make([]time.Duration, len(chunks))
+
+	for i, c := range chunks {
+		cues, err := parseSRT(c.SRT)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse chunk %d: %w", i, err)
+		}
+		parsed[i] = cues
+		offsets[i] = c.Offset
+	}
+	return formatSRT(stitchCues(parsed, offsets)), nil
+}
+
+// stitchCues implements StitchSubtitles over parsed cues.
+func stitchCues(chunks [][]subtitle.Cue, offsets []time.Duration) []subtitle.Cue {
+	var (
+		out     []subtitle.Cue
+		covered time.Duration // End of the latest cue kept so far.
+	)
+
+	for i, cues := range chunks {
+		offset := offsets[i]
+
+		for _, c := range cues {
+			c.Start += offset
+			c.End += offset
+
+			if c.Start < covered {
+				if j := findDuplicateCue(out, c, offset); j >= 0 {
+					// The same words, transcribed again. Prefer the later version
+					// when it runs past the boundary that cut the earlier one.
+					if c.End > out[j].End {
+						out[j].End = c.End
+						out[j].Text = c.Text
+						covered = max(covered, c.End)
+					}
+					continue
+				}
+
+				if c.End <= covered {
+					continue
+				}
+				c.Start = covered
+			}
+
+			out = append(out, c)
+			covered = max(covered, c.End)
+		}
+	}
+	return out
+}
+
+// findDuplicateCue returns the index of the kept cue, among those ending after
+// the chunk offset (i.e. within the overlap), whose text best matches c.
+// It returns -1 if none is similar enough.
+func findDuplicateCue(kept []subtitle.Cue, c subtitle.Cue, offset time.Duration) int {
+	best, bestScore := -1, duplicateCueSimilarity
+
+	for j := len(kept) - 1; j >= 0 && kept[j].End > offset; j-- {
+		if score := textSimilarity(kept[j].Text, c.Text); score >= bestScore {
+			best, bestScore = j, score
+		}
+	}
+	return best
+}
+
+// textSimilarity returns a score in [0, 1] based on the longest common
+// subsequence of normalized words in a and b.
+func textSimilarity(a, b string) float64 {
+	wa, wb := normalizedWords(a), normalizedWords(b)
+	if len(wa) == 0 || len(wb) == 0 {
+		return 0
+	}
+
+	// Classic LCS over words, keeping a single row.
+	row := make([]int, len(wb)+1)
+	for i := 1; i <= len(wa); i++ {
+		prev := 0
+		for j := 1; j <= len(wb); j++ {
+			tmp := row[j]
+			if wa[i-1] == wb[j-1] {
+				row[j] = prev + 1
+			} else {
+				row[j] = max(row[j], row[j-1])
+			}
+			prev = tmp
+		}
+	}
+	return 2 * float64(row[len(wb)]) / float64(len(wa)+len(wb))
+}
+
+func normalizedWords(s string) []string {
+	fields := strings.Fields(s)
+
+	words := fields[:0]
+	for _, f := range fields {
+		if w := normalizeWord(f); w != "" {
+			words = append(words, w)
+		}
+	}
+	return words
+}
@@ -0,0 +1,146 @@
+package scriber
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alesr/scriber/subtitle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStitchSubtitles(t *testing.T) {
+	t.Parallel()
+
+	srt := func(cues ...subtitle.Cue) []byte { return formatSRT(cues) }
+
+	testCases := []struct {
+		name     string
+		given    []SubtitleChunk
+		expected []subtitle.Cue
+	}{
+		{
+			name: "cues are shifted by chunk offset and renumbered",
+			given: []SubtitleChunk{
+				{Offset: 0, SRT: srt(subtitle.Cue{Start: 0, End: 4 * time.Second, Text: "one"})},
+				{Offset: 10 * time.Second, SRT: srt(subtitle.Cue{Start: time.Second, End: 3 * time.Second, Text: "two"})},
+			},
+			expected: []subtitle.Cue{
+				{Start: 0, End: 4 * time.Second, Text: "one"},
+				{Start: 11 * time.Second, End: 13 * time.Second, Text: "two"},
+			},
+		},
+		{
+			name: "fuzzy duplicate in the overlap is dropped",
+			given: []SubtitleChunk{
+				{Offset: 0, SRT: srt(
+					subtitle.Cue{Start: 0, End: 5 * time.Second, Text: "Hello there."},
+					subtitle.Cue{Start: 7 * time.Second, End: 9500 * time.Millisecond, Text: "How are you doing today?"},
+				)},
+				{Offset: 8 * time.Second, SRT: srt(
+					subtitle.Cue{Start: 100 * time.Millisecond, End: 1400 * time.Millisecond, Text: "how are you doing today"},
+					subtitle.Cue{Start: 2 * time.Second, End: 4 * time.Second, Text: "Fine, thanks."},
+				)},
+			},
+			expected: []subtitle.Cue{
+				{Start: 0, End: 5 * time.Second, Text: "Hello there."},
+				{Start: 7 * time.Second, End: 9500 * time.Millisecond, Text: "How are you doing today?"},
+				{Start: 10 * time.Second, End: 12 * time.Second, Text: "Fine, thanks."},
+			},
+		},
+		{
+			name: "cue spanning the boundary replaces the truncated earlier cue",
+			given: []SubtitleChunk{
+				{Offset: 0, SRT: srt(
+					subtitle.Cue{Start: 8 * time.Second, End: 10 * time.Second, Text: "this sentence was cut"},
+				)},
+				{Offset: 8 * time.Second, SRT: srt(
+					subtitle.Cue{Start: 0, End: 4 * time.Second, Text: "This sentence was cut short by the boundary."},
+					subtitle.Cue{Start: 4 * time.Second, End: 6 * time.Second, Text: "Next."},
+				)},
+			},
+			expected: []subtitle.Cue{
+				{Start: 8 * time.Second, End: 12 * time.Second, Text: "This sentence was cut short by the boundary."},
+				{Start: 12 * time.Second, End: 14 * time.Second, Text: "Next."},
+			},
+		},
+		{
+			name: "different text entirely inside the covered range is dropped",
+			given: []SubtitleChunk{
+				{Offset: 0, SRT: srt(subtitle.Cue{Start: 0, End: 10 * time.Second, Text: "long cue"})},
+				{Offset: 8 * time.Second, SRT: srt(subtitle.Cue{Start: 0, End: time.Second, Text: "something else"})},
+			},
+			expected: []subtitle.Cue{
+				{Start: 0, End: 10 * time.Second, Text: "long cue"},
+			},
+		},
+		{
+			name: "different text partially covered is trimmed",
+			given: []SubtitleChunk{
+				{Offset: 0, SRT: srt(subtitle.Cue{Start: 0, End: 10 * time.Second, Text: "long cue"})},
+				{Offset: 8 * time.Second, SRT: srt(subtitle.Cue{Start: time.Second, End: 4 * time.Second, Text: "something else"})},
+			},
+			expected: []subtitle.Cue{
+				{Start: 0, End: 10 * time.Second, Text: "long cue"},
+				{Start: 10 * time.Second, End: 12 * time.Second, Text: "something else"},
+			},
+		},
+		{
+			name: "empty chunks and chunks without cues",
+			given: []SubtitleChunk{
+				{Offset: 0, SRT: nil},
+				{Offset: 8 * time.Second, SRT: []byte("\n\n")},
+				{Offset: 16 * time.Second, SRT: srt(subtitle.Cue{Start: 0, End: time.Second, Text: "finally"})},
+			},
+			expected: []subtitle.Cue{
+				{Start: 16 * time.Second, End: 17 * time.Second, Text: "finally"},
+			},
+		},
+		{
+			name:     "no chunks",
+			given:    nil,
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := StitchSubtitles(tc.given)
+			require.NoError(t, err)
+			assert.Equal(t, string(formatSRT(tc.expected)), string(got))
+		})
+	}
+}
+
+func TestStitchSubtitles_InvalidChunk(t *testing.T) {
+	t.Parallel()
+
+	_, err := StitchSubtitles([]SubtitleChunk{{SRT: []byte("garbage\n")}})
+	require.Error(t, err)
+}
+
+func TestTextSimilarity(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		a, b     string
+		expected float64
+	}{
+		{name: "identical", a: "hello world", b: "hello world", expected: 1},
+		{name: "case and punctuation", a: "Hello, world!", b: "hello world", expected: 1},
+		{name: "disjoint", a: "foo bar", b: "baz qux", expected: 0},
+		{name: "partial", a: "a b c d", b: "a b x y", expected: 0.5},
+		{name: "empty", a: "", b: "hello", expected: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.InDelta(t, tc.expected, textSimilarity(tc.a, tc.b), 1e-9)
+		})
+	}
+}
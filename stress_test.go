@@ -0,0 +1,171 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestScriber_ConcurrentReuse shares one Scriber, with the features that
+// keep state across jobs enabled, between thousands of concurrent Process
+// calls that succeed, fail, or are canceled, while its stats are read.
+// It is meant to run with -race.
+func TestScriber_ConcurrentReuse(t *testing.T) {
+	t.Parallel()
+
+	jobs := 2000
+	if testing.Short() {
+		jobs = 200
+	}
+
+	client := &mockWhisperClient{
+		transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			if _, err := io.Copy(io.Discard, in.Data); err != nil {
+				return nil, err
+			}
+			tag, _ := UserTagFromContext(ctx)
+			switch tag {
+			case "fail":
+				return nil, statusError(http.StatusBadRequest)
+			case "flaky":
+				if in.Language == "en" && time.Now().UnixNano()%2 == 0 {
+					return nil, statusError(http.StatusServiceUnavailable)
+				}
+			case "cancel":
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			return []byte("1\n00:00:00,000 --> 00:00:01,000\nhello jane@example.com\n"), nil
+		},
+	}
+
+	s := New(noopLogger(), client,
+		WithConverter(copyConverter),
+		WithMaxConcurrentConversions(16),
+		WithMaxConcurrentTranscriptions(8),
+		WithRetry(RetryConfig{Attempts: 2}),
+		WithDedupCache(64, time.Minute),
+		WithOutputDir(t.TempDir()),
+		WithRedactor(Redactor{Emails: true}),
+		WithTranslation(&mockTranslator{translateFunc: prefixSegments}, TranslationConfig{Languages: []string{"es"}}),
+		WithErrorChannel(16),
+		WithUsageCap(1000*time.Hour),
+	)
+
+	var outputs atomic.Int64
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for out := range s.Collect() {
+			out.Body.Close()
+			outputs.Add(1)
+		}
+	}()
+	go func() {
+		for range s.Errors() {
+		}
+	}()
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = s.Stats()
+				_ = s.TimingStats()
+				_ = s.DedupStats()
+				_ = s.PublishStats()
+				_ = s.LogStats()
+			}
+		}
+	}()
+
+	kinds := []string{"ok", "duplicate", "fail", "flaky", "cancel", "invalid"}
+	var (
+		wg        sync.WaitGroup
+		succeeded atomic.Int64
+		failed    atomic.Int64
+	)
+	for i := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			kind := kinds[i%len(kinds)]
+			ctx := context.Background()
+			if kind == "cancel" {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, time.Millisecond)
+				defer cancel()
+			}
+
+			data := syntheticWAV(1)
+			if kind != "duplicate" {
+				// Distinct audio, so that the dedup cache doesn't answer.
+				data = append(data, fmt.Sprint(i)...)
+			}
+			in := Input{
+				Name:       fmt.Sprintf("talk-%d.mp4", i%50),
+				OutputType: OutputTypeSubtitles,
+				Language:   "en",
+				UserTag:    kind,
+				Data:       io.NopCloser(bytes.NewReader(data)),
+			}
+			if kind == "invalid" {
+				in.OutputType = "vtt"
+			}
+
+			if err := s.Process(ctx, in); err != nil {
+				failed.Add(1)
+				return
+			}
+			succeeded.Add(1)
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Minute):
+		t.Fatal("Process calls deadlocked")
+	}
+
+	close(stop)
+	readers.Wait()
+
+	require.NoError(t, s.Shutdown(context.Background()))
+	<-drained
+
+	assert.Equal(t, int64(jobs), succeeded.Load()+failed.Load())
+	assert.Positive(t, succeeded.Load())
+	assert.Positive(t, failed.Load())
+	// Each success publishes the transcription and its translation.
+	assert.Equal(t, 2*succeeded.Load(), outputs.Load())
+
+	stats := s.TimingStats()
+	var failedJobs int64
+	for _, st := range stats.Failed {
+		failedJobs += st.Jobs
+	}
+	assert.Equal(t, succeeded.Load(), stats.Succeeded.Jobs)
+	assert.Equal(t, failed.Load(), failedJobs)
+}
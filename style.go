@@ -0,0 +1,126 @@
+package scriber
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultLanguageProfile is the key of the profile WithLanguageProfiles
+// applies to languages without a profile of their own.
+const DefaultLanguageProfile = "default"
+
+// SubtitleStyle configures the post-processing of the transcriptions in a
+// language. Unset fields inherit the style the profile is merged over.
+type SubtitleStyle struct {
+	// Formatting replaces the config set with WithFormatting.
+	Formatting *FormattingConfig
+
+	// RTLMarks overrides WithRTLMarks.
+	RTLMarks *bool
+
+	// MaxLineLength wraps the cue lines of subtitles longer than this
+	// many characters, at spaces when the line has any. Zero inherits,
+	// and a negative value leaves lines unwrapped.
+	MaxLineLength int
+}
+
+// merge returns style with the fields set in p overriding its own.
+func (style SubtitleStyle) merge(p SubtitleStyle) SubtitleStyle {
+	if p.Formatting != nil {
+		style.Formatting = p.Formatting
+	}
+	if p.RTLMarks != nil {
+		style.RTLMarks = p.RTLMarks
+	}
+	if p.MaxLineLength != 0 {
+		style.MaxLineLength = p.MaxLineLength
+	}
+	return style
+}
+
+// WithLanguageProfiles selects the post-processing style of each output by
+// its language, requested or detected, so that e.g. Japanese subtitles get
+// narrower lines than English ones and Arabic ones get RTL marks. Profiles
+// are keyed by language code; a code with a region, such as pt-BR, falls
+// back to the profile of its language. Languages without a profile get the
+// one under DefaultLanguageProfile, if any. Profiles are merged field by
+// field over the default profile, itself merged over WithFormatting and
+// WithRTLMarks.
+func WithLanguageProfiles(profiles map[string]SubtitleStyle) Option {
+	return func(s *Scriber) {
+		s.languageProfiles = make(map[string]SubtitleStyle, len(profiles))
+		for lang, p := range profiles {
+			s.languageProfiles[strings.ToLower(lang)] = p
+		}
+	}
+}
+
+// styleFor returns the post-processing style of the outputs in lang.
+func (s *Scriber) styleFor(lang string) SubtitleStyle {
+	formatting, rtlMarks := s.formatting, s.rtlMarks
+	style := SubtitleStyle{Formatting: &formatting, RTLMarks: &rtlMarks}
+
+	style = style.merge(s.languageProfiles[DefaultLanguageProfile])
+
+	lang = strings.ToLower(lang)
+	if p, ok := s.languageProfiles[lang]; ok {
+		return style.merge(p)
+	}
+	code, _, _ := strings.Cut(lang, "-")
+	code, _, _ = strings.Cut(code, "_")
+	return style.merge(s.languageProfiles[code])
+}
+
+// wrapLines wraps the cue lines of subtitles longer than width characters.
+func wrapLines(text []byte, t OutputType, width int) []byte {
+	if width <= 0 || !isSubtitles(t) {
+		return text
+	}
+
+	cues, err := parseSRT(text)
+	if err != nil {
+		return text
+	}
+
+	for i, c := range cues {
+		var lines []string
+		for _, line := range strings.Split(c.Text, "\n") {
+			lines = append(lines, wrapLine(line, width)...)
+		}
+		cues[i].Text = strings.Join(lines, "\n")
+	}
+	return formatSRT(cues)
+}
+
+// wrapLine splits line into lines of at most width characters, breaking
+// at spaces, and within words longer than width, such as the space-less
+// lines of Chinese or Japanese.
+func wrapLine(line string, width int) []string {
+	if utf8.RuneCountInString(line) <= width {
+		return []string{line}
+	}
+
+	var (
+		lines []string
+		cur   []rune
+	)
+	for _, word := range strings.Fields(line) {
+		w := []rune(word)
+		if len(cur) > 0 && len(cur)+1+len(w) <= width {
+			cur = append(append(cur, ' '), w...)
+			continue
+		}
+		if len(cur) > 0 {
+			lines = append(lines, string(cur))
+		}
+		for len(w) > width {
+			lines = append(lines, string(w[:width]))
+			w = w[width:]
+		}
+		cur = w
+	}
+	if len(cur) > 0 {
+		lines = append(lines, string(cur))
+	}
+	return lines
+}
@@ -0,0 +1,184 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapLine(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		givenLine string
+		expected  []string
+	}{
+		{
+			name:      "short line",
+			givenLine: "Hello you",
+			expected:  []string{"Hello you"},
+		},
+		{
+			name:      "at spaces",
+			givenLine: "The quick brown fox jumps",
+			expected:  []string{"The quick", "brown fox", "jumps"},
+		},
+		{
+			name:      "long word",
+			givenLine: "a supercalifragilistic word",
+			expected:  []string{"a", "supercalif", "ragilistic", "word"},
+		},
+		{
+			name:      "without spaces",
+			givenLine: "今日はとても良い天気ですね",
+			expected:  []string{"今日はとても良い天気", "ですね"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, wrapLine(tc.givenLine, 10))
+		})
+	}
+}
+
+func TestScriber_StyleFor(t *testing.T) {
+	t.Parallel()
+
+	enabled := true
+
+	s := New(noopLogger(), &mockWhisperClient{},
+		WithFormatting(FormattingConfig{RemoveItalics: true}),
+		WithLanguageProfiles(map[string]SubtitleStyle{
+			DefaultLanguageProfile: {MaxLineLength: 42},
+			"ja":                   {MaxLineLength: 16},
+			"pt-BR":                {Formatting: &FormattingConfig{MusicNotes: MusicNotesRemove}},
+			"ar":                   {RTLMarks: &enabled},
+		}),
+	)
+
+	testCases := []struct {
+		name               string
+		givenLanguage      string
+		expectedFormatting FormattingConfig
+		expectedRTLMarks   bool
+		expectedMaxLength  int
+	}{
+		{
+			name:               "default profile",
+			givenLanguage:      "en",
+			expectedFormatting: FormattingConfig{RemoveItalics: true},
+			expectedMaxLength:  42,
+		},
+		{
+			name:               "language profile",
+			givenLanguage:      "ja",
+			expectedFormatting: FormattingConfig{RemoveItalics: true},
+			expectedMaxLength:  16,
+		},
+		{
+			name:               "region profile",
+			givenLanguage:      "pt-br",
+			expectedFormatting: FormattingConfig{MusicNotes: MusicNotesRemove},
+			expectedMaxLength:  42,
+		},
+		{
+			name:               "language of region",
+			givenLanguage:      "ar_EG",
+			expectedFormatting: FormattingConfig{RemoveItalics: true},
+			expectedRTLMarks:   true,
+			expectedMaxLength:  42,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			style := s.styleFor(tc.givenLanguage)
+
+			assert.Equal(t, tc.expectedFormatting, *style.Formatting)
+			assert.Equal(t, tc.expectedRTLMarks, *style.RTLMarks)
+			assert.Equal(t, tc.expectedMaxLength, style.MaxLineLength)
+		})
+	}
+}
+
+func TestProcess_LanguageProfiles(t *testing.T) {
+	t.Parallel()
+
+	enabled := true
+	profiles := map[string]SubtitleStyle{
+		DefaultLanguageProfile: {MaxLineLength: 20},
+		"ja":                   {MaxLineLength: 8},
+		"ar":                   {RTLMarks: &enabled, MaxLineLength: -1},
+	}
+
+	testCases := []struct {
+		name            string
+		givenProbeReply string
+		givenText       string
+		expectedText    string
+	}{
+		{
+			name:            "default profile",
+			givenProbeReply: `{"language":"english"}`,
+			givenText:       "1\n00:00:00,000 --> 00:00:02,000\nThe quick brown fox jumps over the lazy dog\n",
+			expectedText:    "1\n00:00:00,000 --> 00:00:02,000\nThe quick brown fox\njumps over the lazy\ndog\n",
+		},
+		{
+			name:            "japanese",
+			givenProbeReply: `{"language":"japanese"}`,
+			givenText:       "1\n00:00:00,000 --> 00:00:02,000\n今日はとても良い天気ですね\n",
+			expectedText:    "1\n00:00:00,000 --> 00:00:02,000\n今日はとても良い\n天気ですね\n",
+		},
+		{
+			name:            "arabic",
+			givenProbeReply: `{"language":"arabic"}`,
+			givenText:       "1\n00:00:00,000 --> 00:00:02,000\nمرحبا بكم في هذا البرنامج الجميل.\n",
+			expectedText:    "1\n00:00:00,000 --> 00:00:02,000\n\u202Bمرحبا بكم في هذا البرنامج الجميل.\u200F\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					if _, err := io.Copy(io.Discard, in.Data); err != nil {
+						return nil, err
+					}
+					if in.Format == formatVerboseJSON {
+						return []byte(tc.givenProbeReply), nil
+					}
+					return []byte(tc.givenText), nil
+				},
+			},
+				WithLanguageProfiles(profiles),
+				WithConverter(copyConverter),
+			)
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "test.mp4",
+				OutputType: OutputTypeSubtitles,
+				Language:   LanguageAuto,
+				Data:       io.NopCloser(bytes.NewReader(syntheticWAV(2))),
+			})
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+
+			assert.Equal(t, tc.expectedText, string(out.Text))
+		})
+	}
+}
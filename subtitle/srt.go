@@ -0,0 +1,409 @@
+// Package subtitle reads and writes SRT subtitles.
+package subtitle
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnexpectedLine is returned for a line that is neither a cue
+	// index, a timing line, nor the text of a cue.
+	ErrUnexpectedLine = errors.New("unexpected line")
+
+	// ErrInvalidTiming is returned for a timing line whose
+	// timestamps are missing or malformed.
+	ErrInvalidTiming = errors.New("invalid timing")
+
+	// ErrMissingBlankLine is returned for a cue that starts right after
+	// the text of the previous one. See AllowMissingBlankLines.
+	ErrMissingBlankLine = errors.New("missing blank line before cue")
+
+	// ErrBOM is returned for a stream starting with a byte order mark.
+	// See AllowBOM.
+	ErrBOM = errors.New("byte order mark")
+
+	// ErrCRLF is returned for a line ending with CRLF. See AllowCRLF.
+	ErrCRLF = errors.New("CRLF line ending")
+)
+
+// ParseError is the error returned for malformed SRT. It records the
+// line the error was found on.
+type ParseError struct {
+	// Line is the number of the line, from 1.
+	Line int
+
+	// Text is the content of the line.
+	Text string
+
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %v %q", e.Line, e.Err, e.Text)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// Cue is a single subtitle entry.
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+
+	// Text holds the lines of the cue, separated by \n.
+	Text string
+}
+
+// ParseOption relaxes the parsing of SRT, which is strict by default.
+type ParseOption func(*parseOptions)
+
+type parseOptions struct {
+	bom          bool
+	crlf         bool
+	missingBlank bool
+	hourless     bool
+}
+
+// AllowBOM ignores a UTF-8 byte order mark at the start of the stream,
+// as written by some Windows editors.
+func AllowBOM() ParseOption {
+	return func(o *parseOptions) {
+		o.bom = true
+	}
+}
+
+// AllowCRLF accepts lines ending with CRLF.
+func AllowCRLF() ParseOption {
+	return func(o *parseOptions) {
+		o.crlf = true
+	}
+}
+
+// AllowMissingBlankLines accepts cues that start right after the text of
+// the previous one. A timing line, or a number followed by a timing line,
+// then ends the previous cue.
+func AllowMissingBlankLines() ParseOption {
+	return func(o *parseOptions) {
+		o.missingBlank = true
+	}
+}
+
+// AllowHourlessTimestamps accepts MM:SS,mmm timestamps.
+func AllowHourlessTimestamps() ParseOption {
+	return func(o *parseOptions) {
+		o.hourless = true
+	}
+}
+
+// Lenient applies every option, to read SRT from any source.
+func Lenient() ParseOption {
+	return func(o *parseOptions) {
+		for _, opt := range []ParseOption{AllowBOM(), AllowCRLF(), AllowMissingBlankLines(), AllowHourlessTimestamps()} {
+			opt(o)
+		}
+	}
+}
+
+// ParseSRT reads every cue from r. Cue indexes are optional and ignored;
+// WriteSRT regenerates them. Milliseconds may be separated by a period,
+// as in WebVTT, and settings after the end timestamp are ignored.
+func ParseSRT(r io.Reader, opts ...ParseOption) ([]Cue, error) {
+	var cues []Cue
+
+	sr := NewReader(r, opts...)
+	for {
+		c, err := sr.Read()
+		if err == io.EOF {
+			return cues, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		cues = append(cues, c)
+	}
+}
+
+// Reader reads cues from an SRT stream one at a time.
+type Reader struct {
+	sc     *bufio.Scanner
+	opts   parseOptions
+	lineNo int
+
+	// pending holds the lines read ahead, to be read again.
+	pending []line
+}
+
+// line is a line of the stream, without its line ending.
+type line struct {
+	no   int
+	text string
+}
+
+// NewReader returns a Reader reading SRT from r.
+func NewReader(r io.Reader, opts ...ParseOption) *Reader {
+	sr := &Reader{sc: bufio.NewScanner(r)}
+	sr.sc.Split(scanLines)
+	for _, opt := range opts {
+		opt(&sr.opts)
+	}
+	return sr
+}
+
+// scanLines splits lines like bufio.ScanLines, but keeps the
+// carriage returns, so that CRLF line endings can be told apart.
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// Read returns the next cue, or io.EOF once the stream is done.
+// Malformed streams return a *ParseError.
+func (r *Reader) Read() (Cue, error) {
+	// Skip the blank lines before the cue.
+	l, err := r.next()
+	for err == nil && isBlank(l.text) {
+		l, err = r.next()
+	}
+	if err != nil {
+		return Cue{}, err
+	}
+
+	if !isTiming(l.text) {
+		if !isIndex(l.text) {
+			return Cue{}, &ParseError{Line: l.no, Text: l.text, Err: ErrUnexpectedLine}
+		}
+		if l, err = r.next(); err == io.EOF || (err == nil && !isTiming(l.text)) {
+			return Cue{}, &ParseError{Line: l.no, Text: l.text, Err: ErrInvalidTiming}
+		}
+		if err != nil {
+			return Cue{}, err
+		}
+	}
+
+	c, err := r.parseTiming(l)
+	if err != nil {
+		return Cue{}, err
+	}
+
+	var text []string
+	for {
+		l, err := r.next()
+		if err == io.EOF || (err == nil && isBlank(l.text)) {
+			break
+		}
+		if err != nil {
+			return Cue{}, err
+		}
+
+		if starts, err := r.startsCue(l); err != nil {
+			return Cue{}, err
+		} else if starts {
+			r.pending = append([]line{l}, r.pending...)
+			break
+		}
+		text = append(text, l.text)
+	}
+
+	c.Text = strings.Join(text, "\n")
+	return c, nil
+}
+
+// startsCue reports whether l, read within the text of a cue, starts the
+// next one, failing unless missing blank lines are allowed.
+func (r *Reader) startsCue(l line) (bool, error) {
+	starts := isTiming(l.text)
+	if !starts && isIndex(l.text) {
+		next, err := r.next()
+		if err != nil && err != io.EOF {
+			return false, err
+		}
+		if err == nil {
+			r.pending = append([]line{next}, r.pending...)
+			starts = isTiming(next.text)
+		}
+	}
+
+	if starts && !r.opts.missingBlank {
+		return false, &ParseError{Line: l.no, Text: l.text, Err: ErrMissingBlankLine}
+	}
+	return starts, nil
+}
+
+// next returns the next line, read ahead or from the stream.
+func (r *Reader) next() (line, error) {
+	if len(r.pending) > 0 {
+		l := r.pending[0]
+		r.pending = r.pending[1:]
+		return l, nil
+	}
+
+	if !r.sc.Scan() {
+		if err := r.sc.Err(); err != nil {
+			return line{}, fmt.Errorf("could not read subtitles: %w", err)
+		}
+		return line{no: r.lineNo + 1}, io.EOF
+	}
+	r.lineNo++
+	l := line{no: r.lineNo, text: r.sc.Text()}
+
+	if l.no == 1 && strings.HasPrefix(l.text, "\ufeff") {
+		if !r.opts.bom {
+			return line{}, &ParseError{Line: l.no, Text: l.text, Err: ErrBOM}
+		}
+		l.text = strings.TrimPrefix(l.text, "\ufeff")
+	}
+
+	if strings.HasSuffix(l.text, "\r") {
+		if !r.opts.crlf {
+			return line{}, &ParseError{Line: l.no, Text: l.text, Err: ErrCRLF}
+		}
+		l.text = strings.TrimSuffix(l.text, "\r")
+	}
+	return l, nil
+}
+
+// parseTiming parses a "start --> end" timing line into a cue.
+func (r *Reader) parseTiming(l line) (Cue, error) {
+	invalid := &ParseError{Line: l.no, Text: l.text, Err: ErrInvalidTiming}
+
+	start, rest, _ := strings.Cut(l.text, "-->")
+
+	// Ignore any position settings after the end timestamp.
+	endFields := strings.Fields(rest)
+	if len(endFields) == 0 {
+		return Cue{}, invalid
+	}
+
+	var c Cue
+	var ok bool
+	if c.Start, ok = r.parseTimestamp(strings.TrimSpace(start)); !ok {
+		return Cue{}, invalid
+	}
+	if c.End, ok = r.parseTimestamp(endFields[0]); !ok {
+		return Cue{}, invalid
+	}
+	return c, nil
+}
+
+// parseTimestamp parses HH:MM:SS,mmm, or MM:SS,mmm if allowed.
+func (r *Reader) parseTimestamp(s string) (time.Duration, bool) {
+	clock, frac, ok := strings.Cut(s, ",")
+	if !ok {
+		clock, frac, ok = strings.Cut(s, ".")
+	}
+	if !ok {
+		return 0, false
+	}
+
+	parts := strings.Split(clock, ":")
+	if len(parts) == 2 && r.opts.hourless {
+		parts = append([]string{"0"}, parts...)
+	}
+	if len(parts) != 3 {
+		return 0, false
+	}
+
+	var n [4]int
+	for i, p := range append(parts, frac) {
+		v, ok := atoi(p)
+		if !ok {
+			return 0, false
+		}
+		n[i] = v
+	}
+
+	return time.Duration(n[0])*time.Hour +
+		time.Duration(n[1])*time.Minute +
+		time.Duration(n[2])*time.Second +
+		time.Duration(n[3])*time.Millisecond, true
+}
+
+// atoi parses s, which must be made of ASCII digits only.
+func atoi(s string) (int, bool) {
+	if s == "" {
+		return 0, false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+func isBlank(s string) bool { return strings.TrimSpace(s) == "" }
+
+func isTiming(s string) bool { return strings.Contains(s, "-->") }
+
+func isIndex(s string) bool {
+	_, ok := atoi(strings.TrimSpace(s))
+	return ok
+}
+
+// WriteSRT writes cues to w as SRT, numbering them from 1.
+func WriteSRT(w io.Writer, cues []Cue) error {
+	sw := NewWriter(w)
+	for _, c := range cues {
+		if err := sw.Write(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Writer writes cues to an SRT stream one at a time.
+type Writer struct {
+	w io.Writer
+	n int
+}
+
+// NewWriter returns a Writer writing SRT to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes c, numbered after the cues written before it.
+func (w *Writer) Write(c Cue) error {
+	sep := ""
+	if w.n > 0 {
+		sep = "\n"
+	}
+
+	if _, err := fmt.Fprintf(w.w, "%s%d\n%s --> %s\n%s\n", sep, w.n+1, FormatTimestamp(c.Start), FormatTimestamp(c.End), c.Text); err != nil {
+		return fmt.Errorf("could not write cue %d: %w", w.n+1, err)
+	}
+	w.n++
+	return nil
+}
+
+// FormatTimestamp formats d as an SRT timestamp, HH:MM:SS,mmm.
+// Negative durations are formatted as zero.
+func FormatTimestamp(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+
+	h := d / time.Hour
+	d -= h * time.Hour
+	m := d / time.Minute
+	d -= m * time.Minute
+	s := d / time.Second
+	d -= s * time.Second
+
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", h, m, s, d/time.Millisecond)
+}
@@ -0,0 +1,230 @@
+package subtitle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSRT_Fixtures(t *testing.T) {
+	t.Parallel()
+
+	ms := time.Millisecond
+
+	testCases := []struct {
+		fixture      string
+		givenOpts    []ParseOption
+		expected     []Cue
+		expectedErr  error
+		expectedLine int
+	}{
+		{
+			fixture: "basic.srt",
+			expected: []Cue{
+				{Start: time.Second, End: 2500 * ms, Text: "<i>Hello</i>\nworld"},
+				{Start: 3 * time.Second, End: 4250 * ms, Text: "Periods, as in WebVTT"},
+				{Start: 5 * time.Second, End: 6 * time.Second, Text: "No index"},
+				{Start: time.Hour + 2*time.Minute + 3004*ms, End: time.Hour + 2*time.Minute + 5*time.Second, Text: "42\nis the answer"},
+			},
+		},
+		{
+			fixture:      "windows.srt",
+			expectedErr:  ErrBOM,
+			expectedLine: 1,
+		},
+		{
+			fixture:      "windows.srt",
+			givenOpts:    []ParseOption{AllowBOM()},
+			expectedErr:  ErrCRLF,
+			expectedLine: 1,
+		},
+		{
+			fixture:   "windows.srt",
+			givenOpts: []ParseOption{AllowBOM(), AllowCRLF()},
+			expected: []Cue{
+				{Start: time.Second, End: 2 * time.Second, Text: "Saved by Notepad"},
+				{Start: 2 * time.Second, End: 3 * time.Second, Text: "with CRLF"},
+			},
+		},
+		{
+			fixture:      "missing-blank-lines.srt",
+			expectedErr:  ErrMissingBlankLine,
+			expectedLine: 4,
+		},
+		{
+			fixture:   "missing-blank-lines.srt",
+			givenOpts: []ParseOption{AllowMissingBlankLines()},
+			expected: []Cue{
+				{Start: time.Second, End: 2 * time.Second, Text: "First"},
+				{Start: 2 * time.Second, End: 3 * time.Second, Text: "Second"},
+				{Start: 3 * time.Second, End: 4 * time.Second, Text: "Third"},
+			},
+		},
+		{
+			fixture:      "hourless.srt",
+			expectedErr:  ErrInvalidTiming,
+			expectedLine: 2,
+		},
+		{
+			fixture:   "hourless.srt",
+			givenOpts: []ParseOption{AllowHourlessTimestamps()},
+			expected: []Cue{
+				{Start: time.Second, End: 3500 * ms, Text: "Short timestamps"},
+				{Start: time.Minute, End: time.Minute + 2*time.Second, Text: "from a phone app"},
+			},
+		},
+		{
+			fixture:      "broken-arrow.srt",
+			givenOpts:    []ParseOption{Lenient()},
+			expectedErr:  ErrInvalidTiming,
+			expectedLine: 2,
+		},
+		{
+			fixture:      "bad-timestamp.srt",
+			givenOpts:    []ParseOption{Lenient()},
+			expectedErr:  ErrInvalidTiming,
+			expectedLine: 6,
+		},
+		{
+			fixture:      "webvtt.srt",
+			givenOpts:    []ParseOption{Lenient()},
+			expectedErr:  ErrUnexpectedLine,
+			expectedLine: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.fixture, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := os.Open(filepath.Join("testdata", tc.fixture))
+			require.NoError(t, err)
+			defer f.Close()
+
+			cues, err := ParseSRT(f, tc.givenOpts...)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+
+				var pe *ParseError
+				require.ErrorAs(t, err, &pe)
+				assert.Equal(t, tc.expectedLine, pe.Line)
+				assert.Contains(t, err.Error(), fmt.Sprintf("line %d:", tc.expectedLine))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, cues)
+		})
+	}
+}
+
+func TestParseSRT_Lenient(t *testing.T) {
+	t.Parallel()
+
+	given := "\ufeff1\r\n00:01,000 --> 00:02,500\r\nHello\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\nBye"
+
+	cues, err := ParseSRT(strings.NewReader(given), Lenient())
+	require.NoError(t, err)
+
+	assert.Equal(t, []Cue{
+		{Start: time.Second, End: 2500 * time.Millisecond, Text: "Hello"},
+		{Start: 3 * time.Second, End: 4 * time.Second, Text: "Bye"},
+	}, cues)
+}
+
+func TestParseSRT_Invalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		given        string
+		expectedErr  error
+		expectedLine int
+	}{
+		{name: "bad index", given: "one\n00:00:01,000 --> 00:00:02,000\nHi\n", expectedErr: ErrUnexpectedLine, expectedLine: 1},
+		{name: "bad timestamp", given: "1\n00:00:xx,000 --> 00:00:02,000\nHi\n", expectedErr: ErrInvalidTiming, expectedLine: 2},
+		{name: "missing end", given: "1\n00:00:01,000 -->\nHi\n", expectedErr: ErrInvalidTiming, expectedLine: 2},
+		{name: "missing milliseconds", given: "1\n00:00:01 --> 00:00:02,000\nHi\n", expectedErr: ErrInvalidTiming, expectedLine: 2},
+		{name: "signed timestamp", given: "1\n00:00:+1,000 --> 00:00:02,000\nHi\n", expectedErr: ErrInvalidTiming, expectedLine: 2},
+		{name: "index without timing", given: "1\n", expectedErr: ErrInvalidTiming, expectedLine: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParseSRT(strings.NewReader(tc.given), Lenient())
+			require.ErrorIs(t, err, tc.expectedErr)
+
+			var pe *ParseError
+			require.ErrorAs(t, err, &pe)
+			assert.Equal(t, tc.expectedLine, pe.Line)
+		})
+	}
+}
+
+func TestReader(t *testing.T) {
+	t.Parallel()
+
+	r := NewReader(strings.NewReader("1\n00:00:01,000 --> 00:00:02,000\nOne\n\n2\n00:00:0x,000 --> 00:00:03,000\nTwo\n"))
+
+	c, err := r.Read()
+	require.NoError(t, err)
+	assert.Equal(t, Cue{Start: time.Second, End: 2 * time.Second, Text: "One"}, c)
+
+	// Cues read before an error are kept.
+	_, err = r.Read()
+	require.ErrorIs(t, err, ErrInvalidTiming)
+
+	r = NewReader(strings.NewReader(""))
+	_, err = r.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestWriteSRT(t *testing.T) {
+	t.Parallel()
+
+	cues := []Cue{
+		{Start: 0, End: 1500 * time.Millisecond, Text: "a"},
+		{Start: 2*time.Hour + 3*time.Minute, End: 2*time.Hour + 4*time.Minute + 5*time.Second, Text: "b\nc"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteSRT(&buf, cues))
+
+	expected := "1\n00:00:00,000 --> 00:00:01,500\na\n\n2\n02:03:00,000 --> 02:04:05,000\nb\nc\n"
+	assert.Equal(t, expected, buf.String())
+
+	parsed, err := ParseSRT(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, cues, parsed)
+}
+
+func TestWriteSRT_Error(t *testing.T) {
+	t.Parallel()
+
+	err := WriteSRT(failingWriter{}, []Cue{{Text: "a"}})
+	assert.ErrorIs(t, err, errWrite)
+}
+
+var errWrite = errors.New("write failed")
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errWrite }
+
+func TestFormatTimestamp(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "00:00:00,000", FormatTimestamp(-time.Second))
+	assert.Equal(t, "100:00:00,001", FormatTimestamp(100*time.Hour+time.Millisecond))
+}
@@ -0,0 +1,157 @@
+package scriber
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TenantLimits bounds the resources each tenant may use.
+// See WithTenantKeyFunc.
+type TenantLimits struct {
+	// MaxConcurrentJobs is how many jobs of a tenant run at once.
+	// Others wait for their turn. Zero means no limit.
+	MaxConcurrentJobs int
+
+	// DailyAudioQuota is how much audio a tenant may transcribe per UTC
+	// day, counted as jobs succeed. Once it is used up, jobs fail before
+	// running with a *QuotaExceededError. Zero means no quota.
+	DailyAudioQuota time.Duration
+}
+
+// QuotaExceededError is returned for jobs of a tenant
+// that has used up its daily audio quota.
+type QuotaExceededError struct {
+	Tenant string
+	Used   time.Duration
+	Quota  time.Duration
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("tenant %q exceeded its daily audio quota: used %s of %s", e.Tenant, e.Used, e.Quota)
+}
+
+// WithTenantKeyFunc identifies the tenant each input belongs to, so that
+// tenants share conversion and upload slots fairly: waiting jobs of equal
+// priority are served round-robin across tenants. limits applies to each
+// tenant. Usage is tracked in memory, so it resets when the Scriber does.
+func WithTenantKeyFunc(fn func(Input) string, limits TenantLimits) Option {
+	return func(s *Scriber) {
+		s.tenantKeyFunc = fn
+		s.tenantLimits = limits
+	}
+}
+
+type tenantCtxKey struct{}
+
+// withTenant attaches the tenant of a job to ctx.
+func withTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantCtxKey{}, tenant)
+}
+
+// tenantFromContext returns the tenant attached to ctx, or "".
+func tenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantCtxKey{}).(string)
+	return tenant
+}
+
+// tenantKey returns the tenant of in, recovering from the key function's panics.
+func (s *Scriber) tenantKey(in Input) (tenant string, err error) {
+	if s.tenantKeyFunc == nil {
+		return "", nil
+	}
+
+	defer recoverPanic(&err)
+	return s.tenantKeyFunc(in), nil
+}
+
+// tenantLimiter enforces TenantLimits.
+type tenantLimiter struct {
+	limits TenantLimits
+	aging  time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	tenants map[string]*tenantState
+}
+
+// tenantState tracks the jobs and usage of a tenant.
+type tenantState struct {
+	jobs   *semaphore
+	active int
+
+	// day is the UTC day used counts the audio of.
+	day  time.Time
+	used time.Duration
+}
+
+func newTenantLimiter(limits TenantLimits, aging time.Duration) *tenantLimiter {
+	return &tenantLimiter{
+		limits:  limits,
+		aging:   aging,
+		now:     time.Now,
+		tenants: make(map[string]*tenantState),
+	}
+}
+
+// admit checks the quota of tenant and waits for one of its job slots.
+// The returned function must be called with the audio duration of the
+// job once it is done, zero if it failed.
+func (l *tenantLimiter) admit(ctx context.Context, tenant string) (func(time.Duration), error) {
+	l.mu.Lock()
+	st := l.state(tenant)
+	if q := l.limits.DailyAudioQuota; q > 0 && st.used >= q {
+		l.mu.Unlock()
+		return nil, &QuotaExceededError{Tenant: tenant, Used: st.used, Quota: q}
+	}
+	st.active++
+	l.mu.Unlock()
+
+	release, err := st.jobs.acquire(ctx)
+	if err != nil {
+		l.done(tenant, 0)
+		return nil, err
+	}
+
+	return func(audio time.Duration) {
+		release()
+		l.done(tenant, audio)
+	}, nil
+}
+
+// state returns the state of tenant, resetting its usage on a new day.
+// l.mu must be held.
+func (l *tenantLimiter) state(tenant string) *tenantState {
+	st, ok := l.tenants[tenant]
+	if !ok {
+		st = &tenantState{jobs: newSemaphore(l.limits.MaxConcurrentJobs, l.aging)}
+		l.tenants[tenant] = st
+	}
+
+	if day := l.now().UTC().Truncate(24 * time.Hour); !day.Equal(st.day) {
+		st.day, st.used = day, 0
+	}
+	return st
+}
+
+// done records the audio of a finished job of tenant.
+func (l *tenantLimiter) done(tenant string, audio time.Duration) {
+	l.mu.Lock()
+	st := l.state(tenant)
+	st.active--
+	st.used += audio
+	l.mu.Unlock()
+
+	l.forget(tenant)
+}
+
+// forget drops the state of tenant once it holds nothing worth keeping.
+func (l *tenantLimiter) forget(tenant string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if st := l.tenants[tenant]; st != nil && st.active == 0 && st.used == 0 {
+		delete(l.tenants, tenant)
+	}
+}
@@ -0,0 +1,198 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tenantOfName returns the part of the input name before the first dash.
+func tenantOfName(in Input) string {
+	tenant, _, _ := strings.Cut(in.Name, "-")
+	return tenant
+}
+
+// newTenantScriber returns a Scriber whose tenants are named after their
+// inputs, with a converter that waits for delay. If converting is not nil,
+// it tracks the conversions of each tenant it holds, and of all under "".
+func newTenantScriber(t *testing.T, limits TenantLimits, delay time.Duration, converting map[string]*gauge) *Scriber {
+	t.Helper()
+
+	return New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.Copy(io.Discard, in.Data)
+			return []byte("text"), err
+		},
+	},
+		WithTenantKeyFunc(tenantOfName, limits),
+		WithConverter(func(ctx context.Context, r io.Reader, w io.Writer) error {
+			if converting != nil {
+				for _, g := range []*gauge{converting[tenantFromContext(ctx)], converting[""]} {
+					g.enter()
+					defer g.leave()
+				}
+			}
+
+			time.Sleep(delay)
+			_, err := io.Copy(w, r)
+			return err
+		}),
+	)
+}
+
+func TestProcess_TenantQuota(t *testing.T) {
+	t.Parallel()
+
+	s := newTenantScriber(t, TenantLimits{DailyAudioQuota: 5 * time.Second}, 0, nil)
+
+	now := time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)
+	s.tenants.now = func() time.Time { return now }
+
+	go func() {
+		for out := range s.Collect() {
+			out.Body.Close()
+		}
+	}()
+
+	process := func(name string) error {
+		// Three seconds of audio.
+		return s.Process(context.TODO(), Input{
+			Name:       name,
+			OutputType: OutputTypeTranscript,
+			Language:   "en",
+			Data:       io.NopCloser(bytes.NewReader(syntheticWAV(3))),
+		})
+	}
+
+	// The quota is checked before each job, so the second one overruns it.
+	require.NoError(t, process("a-1.mp4"))
+	require.NoError(t, process("a-2.mp4"))
+
+	err := process("a-3.mp4")
+	var pe *ProcessError
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, StageAdmission, pe.Stage)
+
+	var qe *QuotaExceededError
+	require.ErrorAs(t, err, &qe)
+	assert.Equal(t, &QuotaExceededError{Tenant: "a", Used: 6 * time.Second, Quota: 5 * time.Second}, qe)
+
+	// Other tenants have their own quota.
+	require.NoError(t, process("b-1.mp4"))
+
+	// Quotas reset every day.
+	now = now.Add(time.Hour)
+	require.NoError(t, process("a-4.mp4"))
+}
+
+func TestProcess_TenantQuotaFailedJob(t *testing.T) {
+	t.Parallel()
+
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.Copy(io.Discard, in.Data)
+			// Subtitles ending well before the end of the audio.
+			return []byte("1\n00:00:00,000 --> 00:00:01,000\nHello.\n"), err
+		},
+	},
+		WithConverter(copyConverter),
+		WithTenantKeyFunc(tenantOfName, TenantLimits{DailyAudioQuota: 5 * time.Second}),
+		WithTruncationCheck(10*time.Second, TruncationFail),
+	)
+
+	err := s.Process(context.TODO(), Input{
+		Name:       "a-1.mp4",
+		OutputType: OutputTypeSubtitles,
+		Language:   "en",
+		Data:       io.NopCloser(bytes.NewReader(syntheticWAV(30))),
+	})
+	require.Error(t, err)
+
+	// Usage is counted as jobs succeed: the failed job isn't charged.
+	assert.Zero(t, s.tenants.used("a"))
+}
+
+func TestProcess_TenantConcurrentJobs(t *testing.T) {
+	t.Parallel()
+
+	converting := map[string]*gauge{"": {}, "a": {}, "b": {}}
+	s := newTenantScriber(t, TenantLimits{MaxConcurrentJobs: 1}, 50*time.Millisecond, converting)
+
+	go func() {
+		for out := range s.Collect() {
+			out.Body.Close()
+		}
+	}()
+
+	// Tenant a is busier than tenant b.
+	var wg sync.WaitGroup
+	for _, name := range []string{"a-1", "a-2", "a-3", "a-4", "b-1", "b-2"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := s.Process(context.TODO(), Input{
+				Name:       name + ".mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       io.NopCloser(strings.NewReader("data")),
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 1, converting["a"].peak.Load())
+	assert.EqualValues(t, 1, converting["b"].peak.Load())
+	assert.EqualValues(t, 2, converting[""].peak.Load(), "tenants run side by side")
+}
+
+func TestSemaphore_TenantRoundRobin(t *testing.T) {
+	t.Parallel()
+
+	sem := newSemaphore(1, time.Hour)
+
+	release, err := sem.acquire(context.TODO())
+	require.NoError(t, err)
+
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+
+	// Tenant a queues up four jobs before tenant b queues two.
+	for i, name := range []string{"a-1", "a-2", "a-3", "a-4", "b-1", "b-2"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			release, err := sem.acquire(withTenant(context.TODO(), name[:1]))
+			require.NoError(t, err)
+
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			release()
+		}()
+
+		require.Eventually(t, func() bool {
+			sem.mu.Lock()
+			defer sem.mu.Unlock()
+			return len(sem.waiters) == i+1
+		}, time.Second, time.Millisecond)
+	}
+
+	release()
+	wg.Wait()
+
+	assert.Equal(t, []string{"a-1", "b-1", "a-2", "b-2", "a-3", "a-4"}, order)
+}
@@ -0,0 +1,368 @@
+package scriber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultTranscriptionTimeout bounds a transcription request.
+const defaultTranscriptionTimeout = 5 * time.Minute
+
+// WithTranscriptionTimeout bounds each transcription request to d.
+// The timeout starts when the first byte of converted audio is uploaded,
+// not when the job starts, so slow conversions don't eat into it.
+// A zero duration disables the timeout. It defaults to 5 minutes.
+func WithTranscriptionTimeout(d time.Duration) Option {
+	return func(s *Scriber) {
+		s.transcriptionTimeout = d
+	}
+}
+
+// withFirstByteTimeout returns a context that is canceled with
+// errTranscriptionTimeout once timeout has elapsed since the first byte
+// was read from the returned reader. If extension is not nil, the timeout
+// is extended by its result, checked whenever the timeout elapses. The
+// cancel function must be called to release the resources associated with
+// the context; it returns once the timer goroutine has exited.
+func withFirstByteTimeout(ctx context.Context, r io.Reader, timeout time.Duration, extension func() time.Duration) (context.Context, io.Reader, context.CancelFunc) {
+	if timeout <= 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, r, cancel
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	fr := &firstByteReader{r: r, first: make(chan struct{})}
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		select {
+		case <-fr.first:
+		case <-ctx.Done():
+			return
+		}
+
+		first := time.Now()
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-timer.C:
+				total := timeout
+				if extension != nil {
+					total += extension()
+				}
+				if remaining := total - time.Since(first); remaining > 0 {
+					timer.Reset(remaining)
+					continue
+				}
+				cancel(fmt.Errorf("%w after %s", errTranscriptionTimeout, total.Round(time.Millisecond)))
+			case <-ctx.Done():
+			}
+			return
+		}
+	}()
+
+	return ctx, fr, func() {
+		cancel(nil)
+		<-done
+	}
+}
+
+// firstByteReader closes first once data has been read from r.
+type firstByteReader struct {
+	r     io.Reader
+	once  sync.Once
+	first chan struct{}
+}
+
+func (r *firstByteReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.signal()
+	}
+	return n, err
+}
+
+// WriteTo keeps the underlying reader's WriteTo, if any, in use.
+func (r *firstByteReader) WriteTo(w io.Writer) (int64, error) {
+	return io.Copy(firstByteWriter{w: w, r: r}, r.r)
+}
+
+func (r *firstByteReader) signal() {
+	r.once.Do(func() { close(r.first) })
+}
+
+// firstByteWriter signals its reader on the first non-empty write.
+type firstByteWriter struct {
+	w io.Writer
+	r *firstByteReader
+}
+
+func (w firstByteWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		w.r.signal()
+	}
+	return w.w.Write(p)
+}
+
+// contextReader fails reads from r with ctx's error once ctx is done,
+// so that uploads stop promptly instead of draining the audio.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// newContextReader returns r, with reads failing once ctx is done. If abort
+// is not nil, it is called with ctx's error then, to unblock a pending read.
+// The returned function stops watching ctx.
+func newContextReader(ctx context.Context, r io.Reader, abort func(error)) (*contextReader, func() bool) {
+	stop := func() bool { return false }
+	if abort != nil {
+		stop = context.AfterFunc(ctx, func() { abort(ctx.Err()) })
+	}
+	return &contextReader{ctx: ctx, r: r}, stop
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	// The audio may also end because the job was canceled.
+	n, err := r.r.Read(p)
+	if err != nil && r.ctx.Err() != nil {
+		err = r.ctx.Err()
+	}
+	return n, err
+}
+
+// WriteTo keeps the underlying reader's WriteTo, if any, in use,
+// failing writes once ctx is done.
+func (r *contextReader) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.Copy(contextWriter{ctx: r.ctx, w: w}, r.r)
+	if ctxErr := r.ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+	return n, err
+}
+
+// contextWriter fails writes to w once ctx is done.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (w contextWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return w.w.Write(p)
+}
+
+// abortableReader is an internal reader whose pending reads can be
+// failed, such as the pipe audio is streamed through from the converter.
+type abortableReader interface {
+	io.Reader
+	abort(err error)
+}
+
+// pipeAudio is audio streamed from the converter through pipe.
+type pipeAudio struct {
+	*pooledReader
+	pipe *io.PipeReader
+}
+
+// errUploadAborted fails the converter's writes to the pipe of an aborted upload.
+var errUploadAborted = errors.New("upload aborted")
+
+func (a pipeAudio) abort(err error) {
+	a.pipe.CloseWithError(fmt.Errorf("%w: %w", errUploadAborted, err))
+}
+
+// WithInputIdleTimeout fails jobs whose input produces no data for d while
+// it is being read, e.g. an upstream body whose client went away without
+// closing it. The read fails with an InputStalledError, which stops the
+// converter, and the job fails in StageConversion. Time spent while the
+// input isn't read, because the converter or the upload is slower, doesn't
+// count. A zero duration, the default, disables the timeout.
+//
+// Inputs implementing SetReadDeadline, such as network connections and
+// pipes, are bounded with read deadlines, cleared once the job is done.
+// Others are read from a goroutine, whose stalled read can only be
+// interrupted by closing the input, which Process does before returning
+// unless Input.KeepOpen is set.
+func WithInputIdleTimeout(d time.Duration) Option {
+	return func(s *Scriber) {
+		s.inputIdleTimeout = d
+	}
+}
+
+// readDeadliner is implemented by readers whose reads can be bounded
+// with a deadline, such as network connections and pipes.
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// newIdleTimeoutReader returns r, with reads bounded by timeout if it is
+// positive. The returned function stops bounding them, and must be called
+// once done with the reader.
+func newIdleTimeoutReader(r io.Reader, timeout time.Duration) (io.Reader, func()) {
+	if timeout <= 0 {
+		return r, func() {}
+	}
+	// Setting no deadline tells whether deadlines are supported:
+	// regular files, for one, don't.
+	if d, ok := r.(readDeadliner); ok && d.SetReadDeadline(time.Time{}) == nil {
+		dr := &deadlineReader{r: r, d: d, timeout: timeout}
+		return dr, dr.stop
+	}
+	ir := &idleTimeoutReader{
+		r:       r,
+		timeout: timeout,
+		reqs:    make(chan []byte),
+		results: make(chan idleReadResult),
+		done:    make(chan struct{}),
+	}
+	return ir, ir.stop
+}
+
+// deadlineReader fails reads from r that return no data within timeout,
+// setting a read deadline. Once a read has stalled, every later read fails.
+type deadlineReader struct {
+	r       io.Reader
+	d       readDeadliner
+	timeout time.Duration
+	err     error
+}
+
+func (r *deadlineReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if err := r.d.SetReadDeadline(time.Now().Add(r.timeout)); err != nil {
+		return 0, fmt.Errorf("could not set read deadline: %w", err)
+	}
+
+	n, err := r.r.Read(p)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		r.err = fmt.Errorf("%w: no data for %s", errInputStalled, r.timeout)
+		return n, r.err
+	}
+	return n, err
+}
+
+// stop clears the deadline, for the reader to be used without it.
+func (r *deadlineReader) stop() {
+	_ = r.d.SetReadDeadline(time.Time{})
+}
+
+// idleTimeoutReader fails reads from r that return no data within timeout.
+// Reads from r run in a goroutine of its own, started by the first read,
+// into a buffer of their own, so that a stalled read can be abandoned.
+// Once a read has stalled or failed, every later read fails.
+//
+// The goroutine exits once stopped. A stalled read can only be interrupted
+// by closing the input, which Process does before returning unless
+// Input.KeepOpen is set; until then, the goroutine waits for it.
+type idleTimeoutReader struct {
+	r       io.Reader
+	timeout time.Duration
+
+	// reqs hands the goroutine the buffers to read into,
+	// and results hands the reads back.
+	reqs     chan []byte
+	results  chan idleReadResult
+	started  bool
+	done     chan struct{}
+	stopOnce sync.Once
+
+	buf   []byte
+	timer *time.Timer
+	err   error
+}
+
+type idleReadResult struct {
+	n   int
+	err error
+}
+
+func (r *idleTimeoutReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if !r.started {
+		r.started = true
+		go r.read()
+	}
+
+	if len(r.buf) < len(p) {
+		r.buf = make([]byte, len(p))
+	}
+	buf := r.buf[:len(p)]
+	select {
+	case r.reqs <- buf:
+	case <-r.done:
+		return 0, io.ErrClosedPipe
+	}
+
+	if r.timer == nil {
+		r.timer = time.NewTimer(r.timeout)
+	} else {
+		r.timer.Reset(r.timeout)
+	}
+
+	select {
+	case res := <-r.results:
+		if !r.timer.Stop() {
+			<-r.timer.C
+		}
+		if res.err != nil {
+			// The goroutine has exited.
+			r.err = res.err
+		}
+		return copy(p, buf[:res.n]), res.err
+	case <-r.timer.C:
+		r.err = fmt.Errorf("%w: no data for %s", errInputStalled, r.timeout)
+		return 0, r.err
+	}
+}
+
+// read serves the reads from r until one fails or r is stopped.
+func (r *idleTimeoutReader) read() {
+	for {
+		var buf []byte
+		select {
+		case buf = <-r.reqs:
+		case <-r.done:
+			return
+		}
+
+		n, err := r.r.Read(buf)
+		select {
+		case r.results <- idleReadResult{n, err}:
+		case <-r.done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// stop makes the goroutine exit, once its pending read
+// returns if one is stalled.
+func (r *idleTimeoutReader) stop() {
+	r.stopOnce.Do(func() { close(r.done) })
+}
@@ -0,0 +1,446 @@
+package scriber
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestProcess_TranscriptionTimeout(t *testing.T) {
+	t.Parallel()
+
+	const timeout = 100 * time.Millisecond
+
+	testCases := []struct {
+		name          string
+		givenTimeout  time.Duration
+		givenConvert  time.Duration // Delay before the converter writes.
+		givenUpload   time.Duration // Delay after the first byte is uploaded.
+		expectedError error
+	}{
+		{
+			name:         "slow conversion doesn't count",
+			givenTimeout: timeout,
+			givenConvert: 3 * timeout,
+		},
+		{
+			name:          "slow upload times out",
+			givenTimeout:  timeout,
+			givenUpload:   time.Minute,
+			expectedError: errTranscriptionTimeout,
+		},
+		{
+			name:         "disabled",
+			givenTimeout: 0,
+			givenConvert: 3 * timeout,
+			givenUpload:  3 * timeout,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					if _, err := in.Data.Read(make([]byte, 1)); err != nil {
+						return nil, err
+					}
+					if err := ctx.Err(); err != nil {
+						return nil, err
+					}
+
+					select {
+					case <-time.After(tc.givenUpload):
+					case <-ctx.Done():
+						return nil, ctx.Err()
+					}
+
+					_, err := io.Copy(io.Discard, in.Data)
+					return []byte("done"), err
+				},
+			}
+
+			s := New(noopLogger(), mockClient,
+				WithTranscriptionTimeout(tc.givenTimeout),
+				WithConverter(func(_ context.Context, r io.Reader, w io.Writer) error {
+					time.Sleep(tc.givenConvert)
+					_, err := io.Copy(w, r)
+					return err
+				}),
+			)
+
+			start := time.Now()
+			err := s.Process(context.TODO(), Input{
+				Name:       "test.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       io.NopCloser(strings.NewReader("data")),
+			})
+
+			if tc.expectedError == nil {
+				require.NoError(t, err)
+				out := <-s.Collect()
+				out.Body.Close()
+				return
+			}
+
+			require.ErrorIs(t, err, tc.expectedError)
+
+			var timeoutErr TranscriptionTimeoutError
+			assert.ErrorAs(t, err, &timeoutErr)
+
+			var pe *ProcessError
+			require.ErrorAs(t, err, &pe)
+			assert.Equal(t, StageTranscription, pe.Stage)
+			assert.Less(t, time.Since(start), 10*time.Second)
+		})
+	}
+}
+
+func TestFirstByteReader_WriteTo(t *testing.T) {
+	t.Parallel()
+
+	fr := &firstByteReader{r: strings.NewReader("data"), first: make(chan struct{})}
+
+	var sb strings.Builder
+	n, err := io.Copy(&sb, fr)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, n)
+	assert.Equal(t, "data", sb.String())
+
+	select {
+	case <-fr.first:
+	default:
+		t.Fatal("first byte not signaled")
+	}
+}
+
+func TestProcess_InputIdleTimeout(t *testing.T) {
+	t.Parallel()
+
+	const timeout = 100 * time.Millisecond
+
+	testCases := []struct {
+		name            string
+		givenChunkDelay time.Duration // Delay before the source writes each chunk.
+		givenStall      bool          // The source stops without closing.
+		givenConvert    time.Duration // Delay before the converter reads the last chunk.
+		expectedErr     error
+	}{
+		{
+			name:        "stalled source",
+			givenStall:  true,
+			expectedErr: errInputStalled,
+		},
+		{
+			name:            "slow source",
+			givenChunkDelay: timeout / 2,
+		},
+		{
+			name:         "slow converter doesn't count",
+			givenConvert: 3 * timeout,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			pr, pw := io.Pipe()
+			go func() {
+				for range 4 {
+					time.Sleep(tc.givenChunkDelay)
+					if _, err := pw.Write([]byte("media")); err != nil {
+						return
+					}
+				}
+				if !tc.givenStall {
+					pw.Close()
+				}
+			}()
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.ReadAll(in.Data)
+					require.NoError(t, err)
+					return []byte("text"), nil
+				},
+			}
+
+			s := New(noopLogger(), mockClient,
+				WithInputIdleTimeout(timeout),
+				WithConverter(func(_ context.Context, r io.Reader, w io.Writer) error {
+					first := make([]byte, 5)
+					if _, err := io.ReadFull(r, first); err != nil {
+						return err
+					}
+					time.Sleep(tc.givenConvert)
+
+					rest, err := io.ReadAll(r)
+					if err != nil {
+						return err
+					}
+					assert.Equal(t, strings.Repeat("media", 4), string(first)+string(rest))
+
+					_, err = w.Write(syntheticWAV(1))
+					return err
+				}),
+			)
+
+			done := make(chan error, 1)
+			go func() {
+				done <- s.Process(context.TODO(), Input{
+					Name:       "test.mp4",
+					OutputType: OutputTypeTranscript,
+					Language:   "en",
+					Data:       pr,
+				})
+			}()
+
+			var err error
+			select {
+			case err = <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Process didn't return")
+			}
+
+			if tc.expectedErr != nil {
+				var pe *ProcessError
+				require.ErrorAs(t, err, &pe)
+				assert.Equal(t, StageConversion, pe.Stage)
+				assert.ErrorIs(t, err, tc.expectedErr)
+
+				var stalled InputStalledError
+				assert.ErrorAs(t, err, &stalled)
+				return
+			}
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+		})
+	}
+}
+
+// TestIdleTimeoutReader doesn't run in parallel, so that goroutines
+// started by other tests don't show up.
+func TestIdleTimeoutReader(t *testing.T) {
+	testCases := []struct {
+		name      string
+		givenPipe func() (io.ReadCloser, io.WriteCloser, error)
+		// expectedReusable is set when the source can be read
+		// again once stopped, without having been closed.
+		expectedReusable bool
+	}{
+		{
+			name: "without read deadlines",
+			givenPipe: func() (io.ReadCloser, io.WriteCloser, error) {
+				pr, pw := io.Pipe()
+				return pr, pw, nil
+			},
+		},
+		{
+			name: "with read deadlines",
+			givenPipe: func() (io.ReadCloser, io.WriteCloser, error) {
+				return os.Pipe()
+			},
+			expectedReusable: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+			pr, pw, err := tc.givenPipe()
+			require.NoError(t, err)
+			defer pw.Close()
+
+			r, stop := newIdleTimeoutReader(pr, 50*time.Millisecond)
+
+			go pw.Write([]byte("abc"))
+
+			p := make([]byte, 10)
+			n, err := r.Read(p)
+			require.NoError(t, err)
+			assert.Equal(t, "abc", string(p[:n]))
+
+			// The source is silent.
+			_, err = r.Read(p)
+			require.ErrorIs(t, err, errInputStalled)
+
+			// Later reads fail without reading the source.
+			_, err = r.Read(p)
+			require.ErrorIs(t, err, errInputStalled)
+
+			stop()
+			if tc.expectedReusable {
+				// No read is left pending, nor deadline set.
+				_, err = pw.Write([]byte("def"))
+				require.NoError(t, err)
+				n, err = pr.Read(p)
+				require.NoError(t, err)
+				assert.Equal(t, "def", string(p[:n]))
+			}
+
+			// Closing the source interrupts the stalled read, if any.
+			require.NoError(t, pr.Close())
+		})
+	}
+
+	t.Run("no timeout", func(t *testing.T) {
+		pr, _ := io.Pipe()
+		r, stop := newIdleTimeoutReader(pr, 0)
+		defer stop()
+		assert.Same(t, io.Reader(pr), r)
+	})
+}
+
+func TestProcess_UploadAbortedOnCancellation(t *testing.T) {
+	t.Parallel()
+
+	const maxLatency = time.Second
+
+	testCases := []struct {
+		name          string
+		givenOpts     []Option
+		givenCancel   bool
+		expectedError error
+		expectedStage Stage
+	}{
+		{
+			name:          "canceled job",
+			givenCancel:   true,
+			expectedError: context.Canceled,
+			expectedStage: StageTranscription,
+		},
+		{
+			name:          "transcription timeout",
+			givenOpts:     []Option{WithTranscriptionTimeout(50 * time.Millisecond)},
+			expectedError: errTranscriptionTimeout,
+			expectedStage: StageTranscription,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			reading := make(chan struct{})
+			readErr := make(chan error, 1)
+
+			opts := append([]Option{
+				// The converter produces a byte, then no more audio until the job is done.
+				WithConverter(func(ctx context.Context, _ io.Reader, w io.Writer) error {
+					if _, err := w.Write([]byte{0}); err != nil {
+						return err
+					}
+					<-ctx.Done()
+					return nil
+				}),
+			}, tc.givenOpts...)
+
+			s := New(noopLogger(), &mockWhisperClient{
+				// The client ignores ctx while reading the audio.
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := in.Data.Read(make([]byte, 1))
+					require.NoError(t, err)
+
+					close(reading)
+					_, err = in.Data.Read(make([]byte, 1))
+					readErr <- err
+					return nil, err
+				},
+			}, opts...)
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() {
+				done <- s.Process(ctx, Input{
+					Name:       "talk.mp4",
+					OutputType: OutputTypeTranscript,
+					Language:   "en",
+					Data:       io.NopCloser(strings.NewReader("media")),
+				})
+			}()
+
+			<-reading
+			start := time.Now()
+			if tc.givenCancel {
+				cancel()
+			}
+
+			var err error
+			select {
+			case err = <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("the upload wasn't aborted")
+			}
+			assert.Less(t, time.Since(start), maxLatency)
+
+			assert.ErrorIs(t, <-readErr, context.Canceled)
+			assert.ErrorIs(t, err, tc.expectedError)
+
+			var pe *ProcessError
+			require.ErrorAs(t, err, &pe)
+			assert.Equal(t, tc.expectedStage, pe.Stage)
+		})
+	}
+}
+
+func TestContextReader(t *testing.T) {
+	t.Parallel()
+
+	t.Run("aborts a pending read", func(t *testing.T) {
+		t.Parallel()
+
+		pr, pw := io.Pipe()
+		defer pw.Close()
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		r, stop := newContextReader(ctx, pr, pipeAudio{pipe: pr}.abort)
+		defer stop()
+
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		start := time.Now()
+		_, err := r.Read(make([]byte, 1))
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Less(t, time.Since(start), time.Second)
+
+		// The converter's writes fail too.
+		_, err = pw.Write([]byte{0})
+		assert.ErrorIs(t, err, errUploadAborted)
+	})
+
+	t.Run("fails reads once done", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		r, stop := newContextReader(ctx, strings.NewReader("audio"), nil)
+		defer stop()
+
+		p := make([]byte, 2)
+		n, err := r.Read(p)
+		require.NoError(t, err)
+		assert.Equal(t, "au", string(p[:n]))
+
+		cancel()
+		_, err = r.Read(p)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		_, err = r.WriteTo(io.Discard)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,119 @@
+package scriber
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// TimingStats sums up the processing times of the jobs done since s was
+// created, successful or not, by stage. Deferred jobs, see DeferredError,
+// are neither.
+type TimingStats struct {
+	Succeeded StageTimings
+
+	// Failed sums up the failed jobs by the stage they failed in, with
+	// the time they spent in each stage up to the failure.
+	Failed map[Stage]StageTimings
+}
+
+// StageTimings sums up the processing times of Jobs jobs.
+type StageTimings struct {
+	Jobs int64
+	ProcessingTime
+}
+
+// TimingStats returns the processing times of the jobs done since s was created.
+func (s *Scriber) TimingStats() TimingStats {
+	return s.timings.stats()
+}
+
+// timingTracker sums up the processing times of jobs.
+type timingTracker struct {
+	mu        sync.Mutex
+	succeeded StageTimings
+	failed    map[Stage]StageTimings
+}
+
+// record adds the processing times of a job, failed in stage unless stage is empty.
+func (t *timingTracker) record(stage Stage, pt ProcessingTime) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if stage == "" {
+		t.succeeded.add(pt)
+		return
+	}
+	if t.failed == nil {
+		t.failed = make(map[Stage]StageTimings)
+	}
+	st := t.failed[stage]
+	st.add(pt)
+	t.failed[stage] = st
+}
+
+func (t *timingTracker) stats() TimingStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	failed := make(map[Stage]StageTimings, len(t.failed))
+	for stage, st := range t.failed {
+		failed[stage] = st
+	}
+	return TimingStats{Succeeded: t.succeeded, Failed: failed}
+}
+
+// add counts a job that took pt in.
+func (st *StageTimings) add(pt ProcessingTime) {
+	st.Jobs++
+	st.Total += pt.Total
+	st.Convert += pt.Convert
+	st.Transcribe += pt.Transcribe
+	st.PostProcess += pt.PostProcess
+}
+
+// finishTiming records the processing times of the job, done with err.
+// Failed jobs get the times of the stages they went through up to the
+// failure, which are attached to their ProcessErrors and logged.
+func (s *Scriber) finishTiming(j *job, err error) {
+	if j.timing.Total == 0 {
+		j.timing.Total = time.Since(j.started)
+	}
+
+	if err == nil {
+		s.timings.record("", j.timing)
+		return
+	}
+
+	pes := processErrors(err)
+	for _, pe := range pes {
+		pe.Timing = j.timing
+	}
+
+	// A deferred job didn't fail, but wasn't processed either.
+	var deferred *DeferredError
+	if errors.As(err, &deferred) {
+		j.logger.Info("Processing deferred",
+			slog.String("file", j.in.Name),
+			slog.Duration("duration", deferred.Duration),
+			slog.Duration("processing_time", j.timing.Total),
+		)
+		return
+	}
+
+	stage := pes[0].Stage
+	if stage == "" {
+		stage = StageAdmission
+	}
+	s.timings.record(stage, j.timing)
+
+	j.logger.Info("Processing failed",
+		slog.String("file", j.in.Name),
+		slog.String("stage", string(stage)),
+		slog.Duration("processing_time", j.timing.Total),
+		slog.Duration("convert_time", j.timing.Convert),
+		slog.Duration("transcribe_time", j.timing.Transcribe),
+		slog.Duration("postprocess_time", j.timing.PostProcess),
+	)
+}
@@ -0,0 +1,141 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_FailureTiming(t *testing.T) {
+	t.Parallel()
+
+	const delay = 30 * time.Millisecond
+
+	sleepyConverter := func(convErr error) Option {
+		return WithConverter(func(_ context.Context, r io.Reader, w io.Writer) error {
+			time.Sleep(delay)
+			if convErr != nil {
+				return convErr
+			}
+			_, err := io.Copy(w, r)
+			return err
+		})
+	}
+
+	testCases := []struct {
+		name                string
+		givenOpts           []Option
+		givenTranscribeErr  error
+		expectedStage       Stage
+		expectedConvert     bool
+		expectedTranscribe  bool
+		expectedPostProcess bool
+	}{
+		{
+			name:            "conversion",
+			givenOpts:       []Option{sleepyConverter(assert.AnError), WithChunking(ChunkConfig{Length: time.Second})},
+			expectedStage:   StageConversion,
+			expectedConvert: true,
+		},
+		{
+			name:               "transcription",
+			givenOpts:          []Option{sleepyConverter(nil)},
+			givenTranscribeErr: assert.AnError,
+			expectedStage:      StageTranscription,
+			expectedConvert:    true,
+			expectedTranscribe: true,
+		},
+		{
+			name: "publish",
+			givenOpts: []Option{
+				sleepyConverter(nil),
+				WithExistsFunc(func(context.Context, string) (bool, error) {
+					time.Sleep(delay)
+					return false, assert.AnError
+				}, 1),
+			},
+			expectedStage:       StagePublish,
+			expectedConvert:     true,
+			expectedTranscribe:  true,
+			expectedPostProcess: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.Copy(io.Discard, in.Data)
+					require.NoError(t, err)
+					time.Sleep(delay)
+					return []byte("text"), tc.givenTranscribeErr
+				},
+			}, tc.givenOpts...)
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "talk.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(syntheticWAV(2))),
+			})
+			require.ErrorIs(t, err, assert.AnError)
+
+			var pe *ProcessError
+			require.ErrorAs(t, err, &pe)
+			assert.Equal(t, tc.expectedStage, pe.Stage)
+
+			timing := pe.Timing
+			assert.Equal(t, tc.expectedConvert, timing.Convert >= delay, "convert time %s", timing.Convert)
+			assert.Equal(t, tc.expectedTranscribe, timing.Transcribe >= delay, "transcribe time %s", timing.Transcribe)
+			assert.Equal(t, tc.expectedPostProcess, timing.PostProcess >= delay, "post-process time %s", timing.PostProcess)
+			assert.GreaterOrEqual(t, timing.Total, timing.Convert)
+			assert.GreaterOrEqual(t, timing.Total, timing.Transcribe)
+			assert.GreaterOrEqual(t, timing.Total, timing.PostProcess)
+
+			stats := s.TimingStats()
+			assert.Equal(t, StageTimings{}, stats.Succeeded)
+			assert.Equal(t, map[Stage]StageTimings{tc.expectedStage: {Jobs: 1, ProcessingTime: timing}}, stats.Failed)
+		})
+	}
+}
+
+func TestScriber_TimingStats(t *testing.T) {
+	t.Parallel()
+
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.Copy(io.Discard, in.Data)
+			return []byte("text"), err
+		},
+	}, WithConverter(copyConverter))
+
+	var total time.Duration
+	for range 2 {
+		require.NoError(t, s.Process(context.TODO(), Input{
+			Name:       "talk.mp4",
+			OutputType: OutputTypeTranscript,
+			Language:   "en",
+			Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+		}))
+
+		out := <-s.Collect()
+		require.NoError(t, out.Body.Close())
+		total += out.ProcessingTime.Total
+	}
+
+	err := s.Process(context.TODO(), Input{Name: "talk.mp4", OutputType: "vtt", Language: "en", Data: io.NopCloser(bytes.NewReader(nil))})
+	require.Error(t, err)
+
+	stats := s.TimingStats()
+	assert.EqualValues(t, 2, stats.Succeeded.Jobs)
+	assert.Equal(t, total, stats.Succeeded.Total)
+	assert.EqualValues(t, 1, stats.Failed[StageValidation].Jobs)
+}
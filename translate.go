@@ -0,0 +1,219 @@
+package scriber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// MetadataTranslatedFrom is the Output.Metadata key holding the language
+// a translated output was translated from. See WithTranslation.
+const MetadataTranslatedFrom = "translated_from"
+
+const (
+	defaultTranslationBatchCues  = 50
+	defaultTranslationBatchChars = 5000
+)
+
+// Translator translates text from srcLang to dstLang, both language
+// codes such as "en". The text holds one or more segments, cue texts or
+// paragraphs, separated by blank lines, which the translation must keep.
+type Translator interface {
+	Translate(ctx context.Context, text, srcLang, dstLang string) (string, error)
+}
+
+// TranslationConfig configures WithTranslation.
+type TranslationConfig struct {
+	// Languages are the languages transcriptions are translated into.
+	Languages []string
+
+	// MaxBatchCues is the most cues, or paragraphs of transcripts, sent
+	// to the translator in one request, 50 by default.
+	MaxBatchCues int
+
+	// MaxBatchChars is the most characters sent to the translator in
+	// one request, 5000 by default. Longer cues are sent alone.
+	MaxBatchChars int
+}
+
+// WithTranslation publishes, along with each transcription, its
+// translation into each of cfg.Languages by t, as an Output of its own
+// named with the language, e.g. talk.es.srt, and carrying
+// MetadataTranslatedFrom. Subtitles are translated cue by cue, keeping
+// their timing, and cues are sent in batches. Translations are
+// post-processed and published as transcriptions are, in the target
+// language. The transcription is translated once redacted, see
+// WithRedactor, so that personal data doesn't reach the translator.
+//
+// Translation starts once the transcription is published, so a failed
+// translation doesn't fail it: the failure is logged and reported on the
+// Errors channel as a *ProcessError for the target language, in
+// StagePostProcess. Languages the input is transcribed in are skipped.
+func WithTranslation(t Translator, cfg TranslationConfig) Option {
+	return func(s *Scriber) {
+		if cfg.MaxBatchCues <= 0 {
+			cfg.MaxBatchCues = defaultTranslationBatchCues
+		}
+		if cfg.MaxBatchChars <= 0 {
+			cfg.MaxBatchChars = defaultTranslationBatchChars
+		}
+		s.translator = t
+		s.translation = cfg
+	}
+}
+
+// translate publishes the translations of the job's transcription,
+// reporting the failed ones on the Errors channel.
+func (s *Scriber) translate(ctx context.Context, j *job) {
+	if s.translator == nil || j.translatedFrom != "" {
+		return
+	}
+
+	for _, lang := range s.translation.Languages {
+		if lang == j.in.Language {
+			continue
+		}
+
+		tj := j.forLanguage(lang)
+		tj.translatedFrom = j.in.Language
+		tj.in.ReferenceText = ""
+		tj.raw = nil
+
+		if err := s.publishTranslation(ctx, j, tj); err != nil {
+			tj.logger.Error("Translation failed", slog.String("file", j.in.Name), slog.String("error", err.Error()))
+			var pe *PanicError
+			if errors.As(err, &pe) {
+				s.notifyPanic(tj, err)
+				continue
+			}
+			s.notify(err)
+		}
+	}
+}
+
+// publishTranslation translates the transcription of j into the language
+// of tj, and publishes it.
+func (s *Scriber) publishTranslation(ctx context.Context, j, tj *job) error {
+	src, _ := s.redact(j.raw, j.in.OutputType)
+
+	text, err := s.translateText(ctx, src, j.in.OutputType, j.in.Language, tj.in.Language)
+	if err != nil {
+		return s.fail(tj, StagePostProcess, fmt.Errorf("could not translate into %q: %w", tj.in.Language, err))
+	}
+	tj.logger.Info("Translated transcription", slog.String("file", j.in.Name), slog.String("from", j.in.Language))
+	return s.publishTranscription(ctx, tj, text)
+}
+
+// translateText translates text, a transcription of type t, from src to
+// dst: subtitles cue by cue, and transcripts paragraph by paragraph.
+func (s *Scriber) translateText(ctx context.Context, text []byte, t OutputType, src, dst string) ([]byte, error) {
+	if !isSubtitles(t) {
+		translated, err := s.translateSegments(ctx, splitSegments(string(text)), src, dst)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strings.Join(translated, "\n\n")), nil
+	}
+
+	cues, err := parseSRT(text)
+	if err != nil {
+		return nil, err
+	}
+
+	texts := make([]string, len(cues))
+	for i, c := range cues {
+		texts[i] = c.Text
+	}
+	translated, err := s.translateSegments(ctx, texts, src, dst)
+	if err != nil {
+		return nil, err
+	}
+	for i := range cues {
+		cues[i].Text = translated[i]
+	}
+	return formatSRT(cues), nil
+}
+
+// translateSegments translates segs in batches, returning as many
+// translated segments. Batches whose translation doesn't keep the
+// segments apart are translated again one segment at a time.
+func (s *Scriber) translateSegments(ctx context.Context, segs []string, src, dst string) ([]string, error) {
+	translated := make([]string, len(segs))
+
+	for _, batch := range s.translationBatches(segs) {
+		texts := make([]string, len(batch))
+		for i, idx := range batch {
+			texts[i] = segs[idx]
+		}
+
+		got, err := s.callTranslator(ctx, strings.Join(texts, "\n\n"), src, dst)
+		if err != nil {
+			return nil, err
+		}
+
+		parts := splitSegments(got)
+		if len(parts) != len(batch) {
+			if parts, err = s.translateOneByOne(ctx, texts, src, dst); err != nil {
+				return nil, err
+			}
+		}
+		for i, idx := range batch {
+			translated[idx] = parts[i]
+		}
+	}
+	return translated, nil
+}
+
+// translateOneByOne translates each of texts in a request of its own.
+func (s *Scriber) translateOneByOne(ctx context.Context, texts []string, src, dst string) ([]string, error) {
+	translated := make([]string, len(texts))
+	for i, text := range texts {
+		got, err := s.callTranslator(ctx, text, src, dst)
+		if err != nil {
+			return nil, err
+		}
+		translated[i] = strings.Join(splitSegments(got), "\n")
+	}
+	return translated, nil
+}
+
+// translationBatches groups the indexes of the non-blank segs into
+// batches within the configured limits. Blank segments stay blank.
+func (s *Scriber) translationBatches(segs []string) [][]int {
+	var (
+		batches [][]int
+		batch   []int
+		chars   int
+	)
+	for i, seg := range segs {
+		if strings.TrimSpace(seg) == "" {
+			continue
+		}
+
+		n := len([]rune(seg))
+		if len(batch) > 0 && (len(batch) == s.translation.MaxBatchCues || chars+n > s.translation.MaxBatchChars) {
+			batches = append(batches, batch)
+			batch, chars = nil, 0
+		}
+		batch = append(batch, i)
+		chars += n
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+var blankLines = regexp.MustCompile(`\n[ \t]*\n\s*`)
+
+// splitSegments splits text into its segments, separated by blank lines.
+func splitSegments(text string) []string {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return nil
+	}
+	return blankLines.Split(text, -1)
+}
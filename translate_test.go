@@ -0,0 +1,314 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockTranslator struct {
+	mu       sync.Mutex
+	requests []string
+
+	translateFunc func(text, srcLang, dstLang string) (string, error)
+}
+
+func (m *mockTranslator) Translate(_ context.Context, text, srcLang, dstLang string) (string, error) {
+	m.mu.Lock()
+	m.requests = append(m.requests, dstLang+": "+text)
+	m.mu.Unlock()
+	return m.translateFunc(text, srcLang, dstLang)
+}
+
+// prefixSegments translates by prefixing each segment with the language.
+func prefixSegments(text, _, dstLang string) (string, error) {
+	segs := splitSegments(text)
+	for i, seg := range segs {
+		segs[i] = "[" + dstLang + "] " + seg
+	}
+	return strings.Join(segs, "\n\n"), nil
+}
+
+func testSubtitles(n int) string {
+	var b strings.Builder
+	for i := range n {
+		fmt.Fprintf(&b, "%d\n00:00:%02d,000 --> 00:00:%02d,500\ncue %d\nline two\n\n", i+1, i, i, i+1)
+	}
+	return b.String()
+}
+
+func TestProcess_Translation(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		givenType        OutputType
+		givenText        string
+		givenConfig      TranslationConfig
+		givenTranslate   func(text, srcLang, dstLang string) (string, error)
+		expectedTexts    map[string]string
+		expectedRequests int
+		expectedFailed   []string
+	}{
+		{
+			name:        "subtitles in batches",
+			givenType:   OutputTypeSubtitles,
+			givenText:   testSubtitles(5),
+			givenConfig: TranslationConfig{Languages: []string{"es"}, MaxBatchCues: 2},
+			expectedTexts: map[string]string{
+				"talk.es.srt": "1\n00:00:00,000 --> 00:00:00,500\n[es] cue 1\nline two\n\n" +
+					"2\n00:00:01,000 --> 00:00:01,500\n[es] cue 2\nline two\n\n" +
+					"3\n00:00:02,000 --> 00:00:02,500\n[es] cue 3\nline two\n\n" +
+					"4\n00:00:03,000 --> 00:00:03,500\n[es] cue 4\nline two\n\n" +
+					"5\n00:00:04,000 --> 00:00:04,500\n[es] cue 5\nline two\n",
+			},
+			expectedRequests: 3,
+		},
+		{
+			name:             "batches within the character limit",
+			givenType:        OutputTypeSubtitles,
+			givenText:        testSubtitles(4),
+			givenConfig:      TranslationConfig{Languages: []string{"es"}, MaxBatchChars: 30},
+			expectedRequests: 2,
+		},
+		{
+			name:        "transcript by paragraph",
+			givenType:   OutputTypeTranscript,
+			givenText:   "First paragraph.\n\nSecond paragraph.\n",
+			givenConfig: TranslationConfig{Languages: []string{"es", "fr"}},
+			expectedTexts: map[string]string{
+				"talk.es.txt": "[es] First paragraph.\n\n[es] Second paragraph.",
+				"talk.fr.txt": "[fr] First paragraph.\n\n[fr] Second paragraph.",
+			},
+			expectedRequests: 2,
+		},
+		{
+			name:        "source language skipped",
+			givenType:   OutputTypeTranscript,
+			givenText:   "Hello.",
+			givenConfig: TranslationConfig{Languages: []string{"en", "es"}},
+			expectedTexts: map[string]string{
+				"talk.es.txt": "[es] Hello.",
+			},
+			expectedRequests: 1,
+		},
+		{
+			name:        "merged segments retried one by one",
+			givenType:   OutputTypeSubtitles,
+			givenText:   testSubtitles(2),
+			givenConfig: TranslationConfig{Languages: []string{"es"}},
+			givenTranslate: func(text, _, _ string) (string, error) {
+				return strings.ReplaceAll(strings.ReplaceAll(text, "\n\n", "\n"), "cue", "pista"), nil
+			},
+			expectedTexts: map[string]string{
+				"talk.es.srt": "1\n00:00:00,000 --> 00:00:00,500\npista 1\nline two\n\n" +
+					"2\n00:00:01,000 --> 00:00:01,500\npista 2\nline two\n",
+			},
+			expectedRequests: 3,
+		},
+		{
+			name:        "failed language",
+			givenType:   OutputTypeSubtitles,
+			givenText:   testSubtitles(2),
+			givenConfig: TranslationConfig{Languages: []string{"de", "es"}},
+			givenTranslate: func(text, srcLang, dstLang string) (string, error) {
+				if dstLang == "de" {
+					return "", assert.AnError
+				}
+				return prefixSegments(text, srcLang, dstLang)
+			},
+			expectedTexts: map[string]string{
+				"talk.es.srt": "1\n00:00:00,000 --> 00:00:00,500\n[es] cue 1\nline two\n\n" +
+					"2\n00:00:01,000 --> 00:00:01,500\n[es] cue 2\nline two\n",
+			},
+			expectedRequests: 2,
+			expectedFailed:   []string{"de"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			translate := tc.givenTranslate
+			if translate == nil {
+				translate = prefixSegments
+			}
+			translator := &mockTranslator{translateFunc: translate}
+
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.Copy(io.Discard, in.Data)
+					return []byte(tc.givenText), err
+				},
+			},
+				WithConverter(copyConverter),
+				WithTranslation(translator, tc.givenConfig),
+				WithErrorChannel(len(tc.givenConfig.Languages)),
+			)
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "talk.mp4",
+				OutputType: tc.givenType,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+			})
+			require.NoError(t, err)
+
+			original := <-s.Collect()
+			require.NoError(t, original.Body.Close())
+			assert.Equal(t, "en", original.Language)
+			assert.NotContains(t, original.Metadata, MetadataTranslatedFrom)
+
+			translations := len(tc.givenConfig.Languages) - len(tc.expectedFailed)
+			if slices.Contains(tc.givenConfig.Languages, "en") {
+				translations--
+			}
+
+			var sourceCues []string
+			if isSubtitles(tc.givenType) {
+				cues, err := parseSRT(original.Text)
+				require.NoError(t, err)
+				for _, c := range cues {
+					sourceCues = append(sourceCues, c.Text)
+				}
+			}
+
+			for range translations {
+				out := <-s.Collect()
+				require.NoError(t, out.Body.Close())
+				assert.Equal(t, "en", out.Metadata[MetadataTranslatedFrom])
+
+				if expected, ok := tc.expectedTexts[out.Name]; ok {
+					assert.Equal(t, expected, string(out.Text))
+				}
+
+				if isSubtitles(tc.givenType) {
+					cues, err := parseSRT(out.Text)
+					require.NoError(t, err)
+					assert.Len(t, cues, len(sourceCues), "the cue count should be preserved")
+				}
+			}
+			assert.Equal(t, tc.expectedRequests, len(translator.requests))
+
+			for _, lang := range tc.expectedFailed {
+				err := <-s.Errors()
+				var pe *ProcessError
+				require.ErrorAs(t, err, &pe)
+				assert.Equal(t, StagePostProcess, pe.Stage)
+				assert.ErrorIs(t, err, assert.AnError)
+				assert.Contains(t, err.Error(), lang)
+			}
+		})
+	}
+}
+
+func TestProcess_TranslationRedacted(t *testing.T) {
+	t.Parallel()
+
+	translator := &mockTranslator{translateFunc: prefixSegments}
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.Copy(io.Discard, in.Data)
+			return []byte("Write to jane@example.com."), err
+		},
+	},
+		WithConverter(copyConverter),
+		WithRedactor(Redactor{Emails: true}),
+		WithTranslation(translator, TranslationConfig{Languages: []string{"es"}}),
+	)
+
+	err := s.Process(context.TODO(), Input{
+		Name:       "call.mp4",
+		OutputType: OutputTypeTranscript,
+		Language:   "en",
+		Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+	})
+	require.NoError(t, err)
+
+	for range 2 {
+		out := <-s.Collect()
+		require.NoError(t, out.Body.Close())
+	}
+	require.Len(t, translator.requests, 1)
+	assert.Equal(t, "es: Write to [EMAIL].", translator.requests[0])
+}
+
+func TestProcess_TranslatorPanic(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		givenOpts []Option
+	}{
+		{
+			name: "synchronous post-processing",
+		},
+		{
+			name:      "asynchronous post-processing",
+			givenOpts: []Option{WithAsyncPostProcessing(1)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			translator := &mockTranslator{translateFunc: func(text, srcLang, dstLang string) (string, error) {
+				if dstLang == "de" {
+					panic("boom")
+				}
+				return prefixSegments(text, srcLang, dstLang)
+			}}
+
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.Copy(io.Discard, in.Data)
+					return []byte("Hello."), err
+				},
+			}, append([]Option{
+				WithConverter(copyConverter),
+				WithTranslation(translator, TranslationConfig{Languages: []string{"de", "es"}}),
+				WithErrorChannel(1),
+			}, tc.givenOpts...)...)
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "talk.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(syntheticWAV(1))),
+			})
+			require.NoError(t, err)
+
+			// The transcription and the translations that didn't panic.
+			var names []string
+			for range 2 {
+				out := <-s.Collect()
+				require.NoError(t, out.Body.Close())
+				names = append(names, out.Name)
+			}
+			assert.ElementsMatch(t, []string{"talk.txt", "talk.es.txt"}, names)
+
+			err = <-s.Errors()
+			var pe *ProcessError
+			require.ErrorAs(t, err, &pe)
+			assert.Equal(t, StagePostProcess, pe.Stage)
+			assert.Contains(t, err.Error(), "de")
+
+			var panicErr *PanicError
+			require.ErrorAs(t, err, &panicErr)
+			assert.Equal(t, "boom", panicErr.Value)
+
+			require.NoError(t, s.Shutdown(context.TODO()))
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package scriber
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// TruncationPolicy controls what happens to subtitles
+// that end well before the end of the audio.
+type TruncationPolicy int
+
+const (
+	// TruncationWarn logs a warning and reports the gap in Output.Truncated,
+	// and in Output.Warnings as WarningTruncated.
+	TruncationWarn TruncationPolicy = iota
+
+	// TruncationFail fails the job with the TruncatedTranscriptionWarning.
+	TruncationFail
+)
+
+// TruncatedTranscriptionWarning reports subtitles whose last cue
+// ends well before the end of the audio, which is how the backend
+// silently truncating a long transcription shows up.
+type TruncatedTranscriptionWarning struct {
+	// AudioDuration is the duration of the audio.
+	AudioDuration time.Duration
+
+	// LastCueEnd is when the last cue ends.
+	LastCueEnd time.Duration
+}
+
+func (w *TruncatedTranscriptionWarning) Error() string {
+	return fmt.Sprintf("transcription may be truncated: last cue ends at %s of %s of audio", w.LastCueEnd, w.AudioDuration)
+}
+
+// WithTruncationCheck compares the end of the last cue of subtitles with the
+// duration of the audio, and applies policy when the audio runs for more than
+// threshold past it. Transcripts carry no timing and aren't checked.
+func WithTruncationCheck(threshold time.Duration, policy TruncationPolicy) Option {
+	return func(s *Scriber) {
+		s.truncationThreshold = threshold
+		s.truncationPolicy = policy
+	}
+}
+
+// checkTruncation applies the truncation policy to the job's transcription.
+func (s *Scriber) checkTruncation(j *job, text []byte) error {
+	if s.truncationThreshold <= 0 || !isSubtitles(j.in.OutputType) || j.audioDuration <= 0 {
+		return nil
+	}
+
+	w := transcriptionGap(text, j.audioDuration, s.truncationThreshold)
+	if w == nil {
+		return nil
+	}
+
+	if s.truncationPolicy == TruncationFail {
+		return w
+	}
+	if err := j.warn(WarningTruncated, w.Error()); err != nil {
+		return err
+	}
+
+	j.logger.Warn("Transcription may be truncated",
+		slog.String("file", j.in.Name),
+		slog.Duration("audio_duration", w.AudioDuration),
+		slog.Duration("last_cue_end", w.LastCueEnd),
+	)
+	j.truncated = w
+	return nil
+}
+
+// transcriptionGap returns a warning if the cues of the subtitles in
+// text end more than threshold before audio. Subtitles that can't be
+// parsed or have no cues aren't reported.
+func transcriptionGap(text []byte, audio, threshold time.Duration) *TruncatedTranscriptionWarning {
+	cues, err := parseSRT(text)
+	if err != nil || len(cues) == 0 {
+		return nil
+	}
+
+	var end time.Duration
+	for _, c := range cues {
+		end = max(end, c.End)
+	}
+
+	if audio-end <= threshold {
+		return nil
+	}
+	return &TruncatedTranscriptionWarning{AudioDuration: audio, LastCueEnd: end}
+}
@@ -0,0 +1,157 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Subtitles for 40 seconds of audio, ending early and on time.
+const (
+	truncatedSRT = "1\n00:00:00,000 --> 00:00:10,000\nhello\n\n2\n00:00:10,000 --> 00:00:23,000\nworld\n"
+	completeSRT  = "1\n00:00:00,000 --> 00:00:20,000\nhello\n\n2\n00:00:20,000 --> 00:00:39,500\nworld\n"
+)
+
+func TestTranscriptionGap(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		givenText   string
+		expectedEnd time.Duration // Zero when no gap is expected.
+	}{
+		{
+			name:        "ends early",
+			givenText:   truncatedSRT,
+			expectedEnd: 23 * time.Second,
+		},
+		{
+			name:      "ends on time",
+			givenText: completeSRT,
+		},
+		{
+			name:      "within threshold",
+			givenText: "1\n00:00:00,000 --> 00:00:36,000\nhello\n",
+		},
+		{
+			name:        "out of order cues",
+			givenText:   "1\n00:00:20,000 --> 00:00:25,000\nworld\n\n2\n00:00:00,000 --> 00:00:20,000\nhello\n",
+			expectedEnd: 25 * time.Second,
+		},
+		{
+			name:      "no cues",
+			givenText: "",
+		},
+		{
+			name:      "not subtitles",
+			givenText: "hello world",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := transcriptionGap([]byte(tc.givenText), 40*time.Second, 5*time.Second)
+			if tc.expectedEnd == 0 {
+				assert.Nil(t, w)
+				return
+			}
+			require.NotNil(t, w)
+			assert.Equal(t, tc.expectedEnd, w.LastCueEnd)
+			assert.Equal(t, 40*time.Second, w.AudioDuration)
+		})
+	}
+}
+
+func TestProcess_TruncationCheck(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name              string
+		givenText         string
+		givenOutputType   OutputType
+		givenPolicy       TruncationPolicy
+		expectedTruncated bool
+		expectedErr       bool
+	}{
+		{
+			name:              "truncated subtitles warn",
+			givenText:         truncatedSRT,
+			givenOutputType:   OutputTypeSubtitles,
+			givenPolicy:       TruncationWarn,
+			expectedTruncated: true,
+		},
+		{
+			name:            "truncated subtitles fail",
+			givenText:       truncatedSRT,
+			givenOutputType: OutputTypeSubtitles,
+			givenPolicy:     TruncationFail,
+			expectedErr:     true,
+		},
+		{
+			name:            "complete subtitles",
+			givenText:       completeSRT,
+			givenOutputType: OutputTypeSubtitles,
+			givenPolicy:     TruncationFail,
+		},
+		{
+			name:            "transcripts aren't checked",
+			givenText:       "hello",
+			givenOutputType: OutputTypeTranscript,
+			givenPolicy:     TruncationFail,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					_, err := io.Copy(io.Discard, in.Data)
+					return []byte(tc.givenText), err
+				},
+			},
+				WithTruncationCheck(5*time.Second, tc.givenPolicy),
+				WithConverter(copyConverter),
+			)
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "test.mp4",
+				OutputType: tc.givenOutputType,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(syntheticWAV(40))),
+			})
+
+			if tc.expectedErr {
+				var pe *ProcessError
+				require.ErrorAs(t, err, &pe)
+				assert.Equal(t, StagePostProcess, pe.Stage)
+
+				var w *TruncatedTranscriptionWarning
+				require.ErrorAs(t, err, &w)
+				assert.Equal(t, 23*time.Second, w.LastCueEnd)
+				assert.Equal(t, 40*time.Second, w.AudioDuration)
+				return
+			}
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			out.Body.Close()
+
+			if !tc.expectedTruncated {
+				assert.Nil(t, out.Truncated)
+				return
+			}
+			require.NotNil(t, out.Truncated)
+			assert.Equal(t, 23*time.Second, out.Truncated.LastCueEnd)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package scriber
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/alesr/scriber/wav"
+)
+
+// UploadLimit describes the largest upload the transcription backend
+// accepts, and the audio the converter produces, from which upload sizes
+// are estimated.
+type UploadLimit struct {
+	// MaxSize is the largest upload accepted, in bytes, e.g. 25 MB
+	// for the OpenAI API.
+	MaxSize int64
+
+	// SampleRate and Channels describe the converted audio, which is
+	// 16-bit PCM. They default to those of the default converter, 5200 Hz
+	// stereo unless negotiated otherwise (see AudioPreferrer), and must be
+	// set when WithConverter converts otherwise. FLAC uploads are estimated
+	// as PCM, which overestimates them.
+	SampleRate int
+	Channels   int
+
+	// ChunkMargin is the fraction of MaxSize chunks fitted to the limit
+	// leave free, as a safety margin against estimates and backends
+	// counting form fields in. Zero defaults to 0.05; negative leaves none.
+	ChunkMargin float64
+}
+
+// defaultChunkMargin is the fraction of the upload limit fitted chunks
+// leave free by default.
+const defaultChunkMargin = 0.05
+
+// minChunkLength is the shortest chunk fitted to the upload limit.
+// Shorter chunks carry too little speech to be transcribed well.
+const minChunkLength = 500 * time.Millisecond
+
+// chunkSize returns the size chunks are fitted to, MaxSize less the margin.
+func (l UploadLimit) chunkSize() int64 {
+	margin := l.ChunkMargin
+	switch {
+	case margin < 0:
+		margin = 0
+	case margin == 0:
+		margin = defaultChunkMargin
+	}
+	return int64(float64(l.MaxSize) * (1 - margin))
+}
+
+// maxChunkLength returns the duration of the longest chunk of audio in
+// format f that fits the limit with its margin. It fails if that chunk
+// is shorter than minChunkLength, or not longer than overlap.
+func (l UploadLimit) maxChunkLength(f wav.Format, overlap time.Duration) (time.Duration, error) {
+	fit := maxUploadDuration(l.chunkSize(), f)
+	if fit < minChunkLength || fit <= overlap {
+		return 0, stageError(StageValidation, fmt.Errorf(
+			"%w: no chunk of at least %s, and longer than the overlap of %s, fits in %d bytes of %d Hz %d-channel audio; raise the limit or reduce the sample rate or channels",
+			errUploadTooLarge, minChunkLength, overlap, l.chunkSize(), f.SampleRate, f.Channels,
+		))
+	}
+	return fit, nil
+}
+
+// format returns the format of the converted audio.
+func (l UploadLimit) format() wav.Format {
+	return wav.Format{
+		AudioFormat:   wav.FormatPCM,
+		Channels:      uint16(l.Channels),
+		SampleRate:    uint32(l.SampleRate),
+		BitsPerSample: 16,
+	}
+}
+
+// WithUploadLimit makes jobs fail fast, instead of with a 413 from the
+// backend once everything has been uploaded, when their converted audio
+// would exceed the upload limit. The duration of seekable inputs is probed
+// before converting them, and their upload size estimated as
+// duration × sample rate × channels × 2 bytes. Jobs whose estimate exceeds
+// the limit fail in StageValidation with an UploadTooLargeError; inputs
+// whose duration can't be probed are uploaded as usual.
+//
+// With WithChunking, chunks are shortened to fit the limit, less its
+// ChunkMargin, instead, and a zero ChunkConfig.Length picks the longest
+// that fits, computed from the format of the converted audio. The chosen
+// length is logged and set in Output.Metadata, see MetadataChunkLength.
+// Jobs fail in StageValidation with an UploadTooLargeError if no chunk of
+// at least half a second, and longer than the overlap, fits.
+//
+// Without WithUploadLimit, the MaxUploadSize of the backend's
+// Capabilities, if known, is the limit.
+func WithUploadLimit(l UploadLimit) Option {
+	return func(s *Scriber) {
+		s.uploadLimit = l
+	}
+}
+
+// estimateUploadSize returns the size of a WAV upload of d of audio in format f.
+func estimateUploadSize(d time.Duration, f wav.Format) int64 {
+	n := int64(d.Seconds() * float64(f.ByteRate()))
+	return wav.HeaderSize + n - n%f.BlockAlign()
+}
+
+// maxUploadDuration returns the duration of the longest WAV upload
+// of audio in format f that fits in maxSize bytes, or zero if none does.
+func maxUploadDuration(maxSize int64, f wav.Format) time.Duration {
+	n := maxSize - wav.HeaderSize
+	n -= n % f.BlockAlign()
+	if n <= 0 {
+		return 0
+	}
+	return f.Duration(n)
+}
+
+// checkUploadSize fails if the probed duration of the job's input
+// makes its upload exceed the limit. Chunked uploads aren't checked,
+// since their chunks are fitted to the limit.
+func (s *Scriber) checkUploadSize(j *job) error {
+	if s.uploadLimit.MaxSize <= 0 || s.chunking != nil || s.vad != nil || j.probedDuration <= 0 {
+		return nil
+	}
+
+	size := estimateUploadSize(j.probedDuration, s.uploadLimit.format())
+	if size <= s.uploadLimit.MaxSize {
+		return nil
+	}
+	return stageError(StageValidation, fmt.Errorf(
+		"%w: %s of audio would upload about %d bytes, over the limit of %d; enable chunking or reduce the sample rate or channels",
+		errUploadTooLarge, j.probedDuration, size, s.uploadLimit.MaxSize,
+	))
+}
+
+// checkFileUploadSize fails if the converted audio of size bytes exceeds the limit.
+func (s *Scriber) checkFileUploadSize(size int64) error {
+	if s.uploadLimit.MaxSize <= 0 || size <= s.uploadLimit.MaxSize {
+		return nil
+	}
+	return fmt.Errorf("%w: converted audio is %d bytes, over the limit of %d; enable chunking or reduce the sample rate or channels",
+		errUploadTooLarge, size, s.uploadLimit.MaxSize)
+}
+
+// chunkConfigFor returns the chunk config for audio in format f. With an
+// upload limit, chunks too long to fit it are shortened, and a zero length
+// becomes the longest that fits. The chosen length is recorded in the job.
+func (s *Scriber) chunkConfigFor(j *job, f wav.Format) (ChunkConfig, error) {
+	cfg := *s.chunking
+	defer func() { j.chunkLength = cfg.Length }()
+
+	if s.uploadLimit.MaxSize <= 0 {
+		return cfg, nil
+	}
+
+	fit, err := s.uploadLimit.maxChunkLength(f, cfg.Overlap)
+	if err != nil {
+		cfg = ChunkConfig{}
+		return cfg, err
+	}
+
+	if cfg.Length == 0 || cfg.Length > fit {
+		j.logger.Info("Fitting chunks to the upload limit",
+			slog.String("file", j.in.Name),
+			slog.Duration("length", cfg.Length),
+			slog.Duration("fitted_length", fit),
+		)
+		cfg.Length = fit
+	}
+	return cfg, nil
+}
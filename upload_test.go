@@ -0,0 +1,277 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alesr/scriber/wav"
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateUploadSize(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		givenDuration time.Duration
+		givenFormat   wav.Format
+		expected      int64
+	}{
+		{
+			name:          "default conversion",
+			givenDuration: 10 * time.Minute,
+			givenFormat:   UploadLimit{SampleRate: convertedSampleRate, Channels: convertedChannels}.format(),
+			expected:      wav.HeaderSize + 600*5200*2*2,
+		},
+		{
+			name:          "16 kHz mono",
+			givenDuration: time.Hour,
+			givenFormat:   UploadLimit{SampleRate: 16000, Channels: 1}.format(),
+			expected:      wav.HeaderSize + 3600*16000*2,
+		},
+		{
+			name:          "partial frame",
+			givenDuration: 1503 * time.Millisecond,
+			givenFormat:   testWAVFormat,
+			expected:      wav.HeaderSize + 300,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, estimateUploadSize(tc.givenDuration, tc.givenFormat))
+		})
+	}
+}
+
+func TestMaxUploadDuration(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		givenMax int64
+		expected time.Duration
+	}{
+		{name: "exact fit", givenMax: wav.HeaderSize + 200, expected: time.Second},
+		{name: "partial frame", givenMax: wav.HeaderSize + 201, expected: time.Second},
+		{name: "header only", givenMax: wav.HeaderSize},
+		{name: "smaller than header", givenMax: 10},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := maxUploadDuration(tc.givenMax, testWAVFormat)
+			assert.Equal(t, tc.expected, got)
+			if got > 0 {
+				assert.LessOrEqual(t, estimateUploadSize(got, testWAVFormat), tc.givenMax)
+			}
+		})
+	}
+}
+
+func TestUploadLimit_MaxChunkLength(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		givenLimit   UploadLimit
+		givenFormat  wav.Format
+		givenOverlap time.Duration
+		expected     time.Duration
+		expectedErr  bool
+	}{
+		{
+			name:        "default margin",
+			givenLimit:  UploadLimit{MaxSize: wav.HeaderSize + 2000},
+			givenFormat: testWAVFormat,
+			// 95% of 2044 bytes is 1941, less the header, down to a frame.
+			expected: 9480 * time.Millisecond,
+		},
+		{
+			name:        "no margin",
+			givenLimit:  UploadLimit{MaxSize: wav.HeaderSize + 2000, ChunkMargin: -1},
+			givenFormat: testWAVFormat,
+			expected:    10 * time.Second,
+		},
+		{
+			name:        "custom margin",
+			givenLimit:  UploadLimit{MaxSize: 25_000_000, ChunkMargin: 0.2},
+			givenFormat: wav.Format{AudioFormat: wav.FormatPCM, Channels: 2, SampleRate: 5200, BitsPerSample: 16},
+			// 80% of 25 MB, less the header, at 20800 bytes per second.
+			expected: 19_999_956 * time.Second / 20800,
+		},
+		{
+			name:        "whisper limit at 16 kHz mono",
+			givenLimit:  UploadLimit{MaxSize: 25 << 20},
+			givenFormat: wav.Format{AudioFormat: wav.FormatPCM, Channels: 1, SampleRate: 16000, BitsPerSample: 16},
+			// 95% of 25 MiB, less the header, at 32000 bytes per second.
+			expected: 24_903_636 * time.Second / 32000,
+		},
+		{
+			name:        "whisper limit at 48 kHz stereo",
+			givenLimit:  UploadLimit{MaxSize: 25 << 20},
+			givenFormat: wav.Format{AudioFormat: wav.FormatPCM, Channels: 2, SampleRate: 48000, BitsPerSample: 16},
+			expected:    24_903_636 * time.Second / 192000,
+		},
+		{
+			name:        "shorter than the minimum",
+			givenLimit:  UploadLimit{MaxSize: wav.HeaderSize + 99, ChunkMargin: -1},
+			givenFormat: testWAVFormat,
+			expectedErr: true,
+		},
+		{
+			name:         "not longer than the overlap",
+			givenLimit:   UploadLimit{MaxSize: wav.HeaderSize + 2000, ChunkMargin: -1},
+			givenFormat:  testWAVFormat,
+			givenOverlap: 10 * time.Second,
+			expectedErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tc.givenLimit.maxChunkLength(tc.givenFormat, tc.givenOverlap)
+			if tc.expectedErr {
+				require.ErrorIs(t, err, errUploadTooLarge)
+
+				var pe *ProcessError
+				require.ErrorAs(t, err, &pe)
+				assert.Equal(t, StageValidation, pe.Stage)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+			assert.LessOrEqual(t, estimateUploadSize(got, tc.givenFormat), tc.givenLimit.chunkSize())
+		})
+	}
+}
+
+func TestProcess_UploadLimit(t *testing.T) {
+	t.Parallel()
+
+	// Fits one second of testWAVFormat audio, chunks included.
+	limit := UploadLimit{MaxSize: wav.HeaderSize + 200, SampleRate: 100, Channels: 1, ChunkMargin: -1}
+
+	testCases := []struct {
+		name                string
+		givenProbed         time.Duration
+		givenSeekable       bool
+		givenChunking       *ChunkConfig
+		expectedCalls       int32
+		expectedChunkLength string
+		expectedErr         error
+	}{
+		{
+			name:          "estimate over the limit",
+			givenProbed:   3 * time.Second,
+			givenSeekable: true,
+			expectedErr:   errUploadTooLarge,
+		},
+		{
+			name:          "estimate within the limit",
+			givenProbed:   time.Second,
+			givenSeekable: true,
+			expectedCalls: 1,
+		},
+		{
+			name:          "unprobed input",
+			givenProbed:   3 * time.Second,
+			expectedCalls: 1,
+		},
+		{
+			name:                "chunk length chosen",
+			givenProbed:         3 * time.Second,
+			givenSeekable:       true,
+			givenChunking:       &ChunkConfig{},
+			expectedCalls:       3,
+			expectedChunkLength: "1s",
+		},
+		{
+			name:                "chunks shortened",
+			givenProbed:         3 * time.Second,
+			givenSeekable:       true,
+			givenChunking:       &ChunkConfig{Length: 10 * time.Second, Parallelism: 2},
+			expectedCalls:       3,
+			expectedChunkLength: "1s",
+		},
+		{
+			name:          "overlap too long",
+			givenProbed:   3 * time.Second,
+			givenSeekable: true,
+			givenChunking: &ChunkConfig{Length: 10 * time.Second, Overlap: 2 * time.Second},
+			expectedErr:   errUploadTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls atomic.Int32
+
+			opts := []Option{
+				WithUploadLimit(limit),
+				WithConverter(copyConverter),
+			}
+			if tc.givenChunking != nil {
+				opts = append(opts, WithChunking(*tc.givenChunking))
+			}
+
+			s := New(noopLogger(), &mockWhisperClient{
+				transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					data, err := io.ReadAll(in.Data)
+					require.NoError(t, err)
+					calls.Add(1)
+
+					if tc.givenChunking != nil {
+						assert.LessOrEqual(t, int64(len(data)), limit.MaxSize)
+					}
+					return []byte("text"), nil
+				},
+			}, opts...)
+			s.probeDurationFunc = func(context.Context, io.Reader) (time.Duration, error) {
+				return tc.givenProbed, nil
+			}
+
+			var data io.ReadCloser = readSeekNopCloser{bytes.NewReader(syntheticWAV(3))}
+			if !tc.givenSeekable {
+				data = io.NopCloser(bytes.NewReader(syntheticWAV(3)))
+			}
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "talk.mp4",
+				OutputType: OutputTypeTranscript,
+				Language:   "en",
+				Data:       data,
+			})
+			assert.Equal(t, tc.expectedCalls, calls.Load())
+
+			if tc.expectedErr != nil {
+				var pe *ProcessError
+				require.ErrorAs(t, err, &pe)
+				assert.ErrorIs(t, err, tc.expectedErr)
+				if tc.givenChunking == nil {
+					assert.Equal(t, StageValidation, pe.Stage)
+				}
+				return
+			}
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+			assert.Equal(t, tc.expectedChunkLength, out.Metadata[MetadataChunkLength])
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package scriber
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// UsageWindow decides which usage counts at a given time,
+// e.g. that of the current calendar month. See WithUsageCap.
+type UsageWindow interface {
+	// Start returns the earliest time whose usage counts at now.
+	Start(now time.Time) time.Time
+}
+
+// CalendarMonth counts the usage of the current calendar month in
+// Location, UTC when nil. It is the default UsageWindow.
+type CalendarMonth struct {
+	Location *time.Location
+}
+
+func (w CalendarMonth) Start(now time.Time) time.Time {
+	loc := w.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	y, m, _ := now.In(loc).Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, loc)
+}
+
+// RollingWindow counts the usage of the last Period, e.g. 30 days,
+// to the minute.
+type RollingWindow struct {
+	Period time.Duration
+}
+
+func (w RollingWindow) Start(now time.Time) time.Time {
+	return now.Add(-w.Period)
+}
+
+// UsageStats is the usage of the transcription backend
+// within the current usage window.
+type UsageStats struct {
+	// WindowStart is the start of the window the usage is counted over.
+	WindowStart time.Time
+
+	// Audio is the duration of the audio transcribed by the jobs that
+	// succeeded, as measured from the converted audio.
+	Audio time.Duration
+
+	// Requests is the number of requests sent to the backend, whether
+	// their job succeeded or not, as the backend meters them all.
+	Requests int64
+
+	// Cap is the usage cap, zero if there is none.
+	Cap time.Duration
+}
+
+// UsageCapExceededError is returned for jobs submitted once the audio
+// transcribed within the usage window reached the usage cap.
+type UsageCapExceededError struct {
+	Used time.Duration
+	Cap  time.Duration
+}
+
+func (e *UsageCapExceededError) Error() string {
+	return fmt.Sprintf("usage cap exceeded: transcribed %s of %s", e.Used, e.Cap)
+}
+
+// WithUsageCap fails the jobs submitted once the audio transcribed within
+// the usage window, a calendar month unless set with WithUsageWindow,
+// reaches limit, with a *UsageCapExceededError. Jobs in flight when the
+// cap is reached still complete. Usage is tracked in memory, so it resets
+// when the Scriber does; see Stats.
+func WithUsageCap(limit time.Duration) Option {
+	return func(s *Scriber) {
+		s.usage.limit = limit
+	}
+}
+
+// WithUsageWindow sets the window usage is counted over,
+// CalendarMonth in UTC by default.
+func WithUsageWindow(w UsageWindow) Option {
+	return func(s *Scriber) {
+		s.usage.window = w
+	}
+}
+
+// Stats returns the usage of the transcription backend
+// within the current usage window.
+func (s *Scriber) Stats() UsageStats {
+	return s.usage.stats()
+}
+
+// ResetUsage forgets the usage recorded so far,
+// e.g. once the backend's budget was raised.
+func (s *Scriber) ResetUsage() {
+	s.usage.reset()
+}
+
+// usageTracker records usage by the minute, keeping what the window counts.
+type usageTracker struct {
+	limit  time.Duration
+	window UsageWindow
+	now    func() time.Time
+
+	mu      sync.Mutex
+	records []usageRecord
+}
+
+// usageRecord is the usage recorded within a minute.
+type usageRecord struct {
+	at       time.Time
+	audio    time.Duration
+	requests int64
+}
+
+// check fails once the usage cap is reached.
+func (t *usageTracker) check() error {
+	if t.limit <= 0 {
+		return nil
+	}
+
+	used := t.stats().Audio
+	if used >= t.limit {
+		return &UsageCapExceededError{Used: used, Cap: t.limit}
+	}
+	return nil
+}
+
+// record adds the audio of a successful job, or a request sent.
+func (t *usageTracker) record(audio time.Duration, requests int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	at := t.clock().Truncate(time.Minute)
+	if n := len(t.records); n > 0 && t.records[n-1].at.Equal(at) {
+		t.records[n-1].audio += audio
+		t.records[n-1].requests += requests
+		return
+	}
+	t.records = append(t.records, usageRecord{at: at, audio: audio, requests: requests})
+}
+
+func (t *usageTracker) stats() UsageStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	start := t.windowStart()
+
+	// Records are in time order: drop those the window no longer counts.
+	i := 0
+	for i < len(t.records) && t.records[i].at.Before(start.Truncate(time.Minute)) {
+		i++
+	}
+	t.records = t.records[i:]
+
+	stats := UsageStats{WindowStart: start, Cap: t.limit}
+	for _, r := range t.records {
+		stats.Audio += r.audio
+		stats.Requests += r.requests
+	}
+	return stats
+}
+
+func (t *usageTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.records = nil
+}
+
+// windowStart returns the start of the current window. t.mu must be held.
+func (t *usageTracker) windowStart() time.Time {
+	w := t.window
+	if w == nil {
+		w = CalendarMonth{}
+	}
+	return w.Start(t.clock())
+}
+
+func (t *usageTracker) clock() time.Time {
+	if t.now == nil {
+		return time.Now()
+	}
+	return t.now()
+}
@@ -0,0 +1,165 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a clock tests move by hand.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestUsageWindows(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, time.March, 15, 10, 30, 0, 0, time.UTC)
+	saoPaulo := time.FixedZone("BRT", -3*60*60)
+
+	assert.Equal(t, time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), CalendarMonth{}.Start(now))
+	assert.Equal(t, time.Date(2024, time.March, 1, 0, 0, 0, 0, saoPaulo), CalendarMonth{Location: saoPaulo}.Start(now))
+	assert.Equal(t, time.Date(2024, time.February, 1, 0, 0, 0, 0, saoPaulo), CalendarMonth{Location: saoPaulo}.Start(time.Date(2024, time.March, 1, 2, 0, 0, 0, time.UTC)))
+	assert.Equal(t, now.Add(-24*time.Hour), RollingWindow{Period: 24 * time.Hour}.Start(now))
+}
+
+func TestUsageTracker(t *testing.T) {
+	t.Parallel()
+
+	t.Run("calendar month", func(t *testing.T) {
+		t.Parallel()
+
+		clock := &fakeClock{now: time.Date(2024, time.January, 31, 23, 58, 0, 0, time.UTC)}
+		tracker := &usageTracker{limit: 10 * time.Minute, now: clock.Now}
+
+		tracker.record(4*time.Minute, 1)
+		clock.Advance(time.Minute)
+		tracker.record(6*time.Minute, 2)
+
+		assert.Equal(t, UsageStats{
+			WindowStart: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+			Audio:       10 * time.Minute,
+			Requests:    3,
+			Cap:         10 * time.Minute,
+		}, tracker.stats())
+
+		var capErr *UsageCapExceededError
+		require.ErrorAs(t, tracker.check(), &capErr)
+		assert.Equal(t, 10*time.Minute, capErr.Used)
+		assert.Equal(t, 10*time.Minute, capErr.Cap)
+
+		// Crossing into February starts a new window.
+		clock.Advance(time.Minute)
+		assert.Equal(t, UsageStats{
+			WindowStart: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+			Cap:         10 * time.Minute,
+		}, tracker.stats())
+		require.NoError(t, tracker.check())
+		assert.Empty(t, tracker.records)
+	})
+
+	t.Run("rolling", func(t *testing.T) {
+		t.Parallel()
+
+		clock := &fakeClock{now: time.Date(2024, time.January, 31, 12, 0, 0, 0, time.UTC)}
+		tracker := &usageTracker{window: RollingWindow{Period: 24 * time.Hour}, now: clock.Now}
+
+		tracker.record(time.Minute, 1)
+		clock.Advance(12 * time.Hour)
+		tracker.record(2*time.Minute, 1)
+
+		clock.Advance(12 * time.Hour)
+		assert.Equal(t, 3*time.Minute, tracker.stats().Audio)
+
+		clock.Advance(time.Minute)
+		stats := tracker.stats()
+		assert.Equal(t, 2*time.Minute, stats.Audio)
+		assert.Equal(t, int64(1), stats.Requests)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		t.Parallel()
+
+		tracker := &usageTracker{limit: time.Minute}
+		tracker.record(time.Hour, 1)
+		require.Error(t, tracker.check())
+
+		tracker.reset()
+		require.NoError(t, tracker.check())
+		assert.Zero(t, tracker.stats().Audio)
+	})
+}
+
+func TestProcess_UsageCap(t *testing.T) {
+	t.Parallel()
+
+	clock := &fakeClock{now: time.Date(2024, time.January, 31, 23, 0, 0, 0, time.UTC)}
+
+	failing := false
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			if _, err := io.Copy(io.Discard, in.Data); err != nil {
+				return nil, err
+			}
+			if failing {
+				return nil, assert.AnError
+			}
+			return []byte("hello"), nil
+		},
+	},
+		WithConverter(copyConverter),
+		WithUsageCap(2*time.Minute),
+	)
+	s.usage.now = clock.Now
+
+	process := func() error {
+		err := s.Process(context.TODO(), Input{
+			Name:       "talk.mp4",
+			OutputType: OutputTypeTranscript,
+			Language:   "en",
+			Data:       io.NopCloser(bytes.NewReader(syntheticWAV(60))),
+		})
+		if err == nil {
+			out := <-s.Collect()
+			out.Body.Close()
+		}
+		return err
+	}
+
+	// Failed jobs count their requests, but not their audio.
+	failing = true
+	require.Error(t, process())
+	failing = false
+
+	require.NoError(t, process())
+	require.NoError(t, process())
+
+	stats := s.Stats()
+	assert.Equal(t, 2*time.Minute, stats.Audio)
+	assert.Equal(t, int64(3), stats.Requests)
+
+	err := process()
+
+	var capErr *UsageCapExceededError
+	require.ErrorAs(t, err, &capErr)
+
+	var pe *ProcessError
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, StageAdmission, pe.Stage)
+
+	// A new month, or a reset, lifts the cap.
+	clock.Advance(time.Hour)
+	require.NoError(t, process())
+
+	s.ResetUsage()
+	assert.Zero(t, s.Stats().Audio)
+}
@@ -0,0 +1,53 @@
+package scriber
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// maxUserTagLength is the longest user tag accepted, in bytes.
+const maxUserTagLength = 128
+
+type userTagCtxKey struct{}
+
+// UserTagFromContext returns the user tag of the transcription request ctx
+// belongs to. Backends that attribute requests to end users, like the
+// OpenAI API through its user field, should forward it.
+func UserTagFromContext(ctx context.Context) (string, bool) {
+	tag, ok := ctx.Value(userTagCtxKey{}).(string)
+	return tag, ok
+}
+
+// withUserTag attaches tag to ctx, unless it is empty.
+func withUserTag(ctx context.Context, tag string) context.Context {
+	if tag == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, userTagCtxKey{}, tag)
+}
+
+// WithDefaultUserTag sets the user tag used for inputs that don't set one.
+func WithDefaultUserTag(tag string) Option {
+	return func(s *Scriber) {
+		s.inputDefaults.UserTag = tag
+	}
+}
+
+// normalizeUserTag returns tag stripped of control characters and
+// surrounding whitespace, or an error if it is too long.
+func normalizeUserTag(tag string) (string, error) {
+	tag = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, tag)
+
+	tag = strings.TrimSpace(tag)
+	if len(tag) > maxUserTagLength {
+		return "", fmt.Errorf("%w: %d bytes, at most %d", errUserTagTooLong, len(tag), maxUserTagLength)
+	}
+	return tag, nil
+}
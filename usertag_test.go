@@ -0,0 +1,161 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeUserTag(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		given       string
+		expected    string
+		expectedErr error
+	}{
+		{name: "empty", given: "", expected: ""},
+		{name: "plain", given: "user-42", expected: "user-42"},
+		{name: "control characters", given: "user\x00-42\r\n\x1b[31m", expected: "user-42[31m"},
+		{name: "surrounding whitespace", given: "\t user-42 ", expected: "user-42"},
+		{name: "unicode", given: "usuário-42", expected: "usuário-42"},
+		{name: "at the maximum length", given: strings.Repeat("a", maxUserTagLength), expected: strings.Repeat("a", maxUserTagLength)},
+		{name: "too long", given: strings.Repeat("a", maxUserTagLength+1), expectedErr: errUserTagTooLong},
+		{name: "too long once stripped", given: strings.Repeat("a\x07", maxUserTagLength), expected: strings.Repeat("a", maxUserTagLength)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tag, err := normalizeUserTag(tc.given)
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, tag)
+		})
+	}
+}
+
+func TestProcess_UserTag(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		givenOpts    []Option
+		givenTag     string
+		expectedTag  string // Empty when the context carries no tag.
+		expectedErr  error
+		expectedLogs string
+	}{
+		{
+			name: "no tag",
+		},
+		{
+			name:         "input tag",
+			givenTag:     "user-42",
+			expectedTag:  "user-42",
+			expectedLogs: "user_tag=user-42",
+		},
+		{
+			name:         "default tag",
+			givenOpts:    []Option{WithDefaultUserTag("team-a")},
+			expectedTag:  "team-a",
+			expectedLogs: "user_tag=team-a",
+		},
+		{
+			name:         "input tag over the default",
+			givenOpts:    []Option{WithDefaultUserTag("team-a")},
+			givenTag:     "user-42",
+			expectedTag:  "user-42",
+			expectedLogs: "user_tag=user-42",
+		},
+		{
+			name:         "control characters stripped",
+			givenTag:     "user-42\n",
+			expectedTag:  "user-42",
+			expectedLogs: "user_tag=user-42",
+		},
+		{
+			name:        "too long",
+			givenTag:    strings.Repeat("a", 4096),
+			expectedErr: errUserTagTooLong,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				gotTag    string
+				gotTagged bool
+				logs      bytes.Buffer
+			)
+
+			logger := slog.New(slog.NewTextHandler(&logs, nil))
+			s := New(logger, &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					gotTag, gotTagged = UserTagFromContext(ctx)
+					_, err := io.ReadAll(in.Data)
+					return []byte("hello"), err
+				},
+			}, tc.givenOpts...)
+			s.convertToWavFunc = copyConverter
+
+			err := s.Process(context.TODO(), NewInput("talk.wav", bytes.NewReader(syntheticWAV(1)),
+				WithInputLanguage("en"),
+				WithInputOutputType(OutputTypeTranscript),
+				WithInputUserTag(tc.givenTag),
+			))
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+
+			assert.Equal(t, tc.expectedTag != "", gotTagged)
+			assert.Equal(t, tc.expectedTag, gotTag)
+			if tc.expectedLogs != "" {
+				assert.Contains(t, logs.String(), tc.expectedLogs)
+			} else {
+				assert.NotContains(t, logs.String(), "user_tag")
+			}
+		})
+	}
+}
+
+func TestTranscribe_UserTag(t *testing.T) {
+	t.Parallel()
+
+	var gotTag string
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			gotTag, _ = UserTagFromContext(ctx)
+			_, err := io.ReadAll(in.Data)
+			return []byte("hello"), err
+		},
+	}, WithDefaultUserTag("team-a"))
+
+	_, err := s.Transcribe(context.TODO(), "talk.wav", "en", OutputTypeTranscript, bytes.NewReader(syntheticWAV(1)))
+	require.NoError(t, err)
+	assert.Equal(t, "team-a", gotTag)
+
+	ctx := withUserTag(context.TODO(), "user-42\x00")
+	_, err = s.Transcribe(ctx, "talk.wav", "en", OutputTypeTranscript, bytes.NewReader(syntheticWAV(1)))
+	require.NoError(t, err)
+	assert.Equal(t, "user-42", gotTag)
+}
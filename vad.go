@@ -0,0 +1,315 @@
+package scriber
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alesr/scriber/wav"
+)
+
+// VADConfig configures voice activity detection. Zero fields take
+// their defaults.
+type VADConfig struct {
+	// Threshold is the level, in dBFS, above which a frame is considered
+	// speech. It defaults to -40.
+	Threshold float64
+
+	// Frame is the length of the frames whose level is measured.
+	// It defaults to 20ms.
+	Frame time.Duration
+
+	// MinSilence is how long a silence must last to split speech
+	// regions. It defaults to 500ms.
+	MinSilence time.Duration
+
+	// MinSpeech is how long a speech region must last not to be
+	// discarded as noise. It defaults to 100ms.
+	MinSpeech time.Duration
+
+	// Padding is added before and after every speech region, so that
+	// soft onsets and endings aren't cut. It defaults to 200ms.
+	Padding time.Duration
+
+	// Parallelism is how many regions are transcribed at once.
+	// It defaults to 1. With WithChunking, the chunk parallelism
+	// applies instead.
+	Parallelism int
+}
+
+func (c VADConfig) validate() error {
+	if c.Threshold > 0 {
+		return errors.New("threshold must not be above 0 dBFS")
+	}
+	if c.Frame < 0 || c.MinSilence < 0 || c.MinSpeech < 0 || c.Padding < 0 {
+		return errors.New("durations must not be negative")
+	}
+	return nil
+}
+
+// SpeechRegion is a region of the audio in which speech was detected.
+type SpeechRegion struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// WithVoiceActivityDetection transcribes only the regions of the audio in
+// which speech is detected, which saves on recordings that are mostly
+// silent. The converted audio is spooled to a temporary file and its
+// level measured, then every speech region is transcribed separately,
+// and the results stitched with timestamps relative to the whole audio.
+// Nothing is inserted for silences. The regions are reported in
+// Output.SpeechRegions. Without speech, the transcription is empty, and
+// handled as set with WithEmptyTranscriptionPolicy.
+//
+// Detection needs 8 or 16-bit PCM audio, as converted by default.
+func WithVoiceActivityDetection(cfg VADConfig) Option {
+	return func(s *Scriber) {
+		if cfg.Threshold == 0 {
+			cfg.Threshold = -40
+		}
+		if cfg.Frame == 0 {
+			cfg.Frame = 20 * time.Millisecond
+		}
+		if cfg.MinSilence == 0 {
+			cfg.MinSilence = 500 * time.Millisecond
+		}
+		if cfg.MinSpeech == 0 {
+			cfg.MinSpeech = 100 * time.Millisecond
+		}
+		if cfg.Padding == 0 {
+			cfg.Padding = 200 * time.Millisecond
+		}
+		if cfg.Parallelism <= 0 {
+			cfg.Parallelism = 1
+		}
+		s.vad = &cfg
+	}
+}
+
+// transcribeSpeech converts the input into a temporary WAV file and
+// transcribes the regions of it in which speech is detected.
+func (s *Scriber) transcribeSpeech(ctx context.Context, j *job) ([]byte, error) {
+	audio, err := s.convertToFile(ctx, j)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		audio.Close()
+		os.Remove(audio.Name())
+	}()
+
+	return s.transcribeSpeechFile(ctx, j, audio)
+}
+
+// transcribeSpeechFile transcribes the regions of the WAV file audio in
+// which speech is detected, chunking them if enabled, and stitches the results.
+func (s *Scriber) transcribeSpeechFile(ctx context.Context, j *job, audio *os.File) ([]byte, error) {
+	format, dataOffset, dataLen, err := wavFileLayout(audio)
+	if err != nil {
+		return nil, stageError(StageConversion, err)
+	}
+	j.audioDuration = format.Duration(dataLen)
+
+	regions, err := detectSpeech(io.NewSectionReader(audio, dataOffset, dataLen), format, dataLen, *s.vad)
+	if err != nil {
+		return nil, stageError(StageTranscription, fmt.Errorf("could not detect speech: %w", err))
+	}
+
+	j.speechRegions = make([]SpeechRegion, len(regions))
+	for i, r := range regions {
+		j.speechRegions[i] = SpeechRegion{Start: r.start, End: r.end}
+	}
+
+	var chunking ChunkConfig
+	if s.chunking != nil {
+		if chunking, err = s.chunkConfigFor(j, format); err != nil {
+			return nil, err
+		}
+	}
+
+	// Split long regions into chunks, remembering the region of each.
+	var (
+		windows     []chunkWindow
+		regionOf    []int
+		parallelism = s.vad.Parallelism
+	)
+	for i, r := range regions {
+		if s.chunking == nil {
+			r.index = len(windows)
+			windows = append(windows, r)
+			regionOf = append(regionOf, i)
+			continue
+		}
+
+		for _, w := range chunkWindows(r.size, format, chunking) {
+			w.index = len(windows)
+			w.offset += r.offset
+			w.start, w.end = format.Duration(w.offset), format.Duration(w.offset+w.size)
+			windows = append(windows, w)
+			regionOf = append(regionOf, i)
+		}
+	}
+	if s.chunking != nil {
+		parallelism = chunking.Parallelism
+	}
+
+	j.logger.Debug("Transcribing speech regions",
+		slog.String("file", j.in.Name),
+		slog.Int("regions", len(regions)),
+		slog.Duration("speech_duration", speechDuration(j.speechRegions)),
+	)
+
+	transcribeStart := time.Now()
+	results, err := s.transcribeWindows(ctx, j, audio, dataOffset, format, windows, parallelism)
+	j.timing.Transcribe = time.Since(transcribeStart)
+	if err != nil {
+		return nil, stageError(StageTranscription, err)
+	}
+
+	j.uploadSpec = wavSpec(format)
+	j.convertedBytes = 0
+	for _, w := range windows {
+		j.convertedBytes += wav.HeaderSize + w.size
+	}
+
+	postStart := time.Now()
+	defer func() { j.timing.PostProcess = time.Since(postStart) }()
+
+	if j.in.OutputType == OutputTypeTranscript {
+		// Chunks of a region overlap, regions don't.
+		texts := make([][]string, len(regions))
+		for i, r := range results {
+			texts[regionOf[i]] = append(texts[regionOf[i]], r)
+		}
+
+		paragraphs := make([]string, 0, len(texts))
+		for _, t := range texts {
+			if p := stitchTranscripts(t); p != "" {
+				paragraphs = append(paragraphs, p)
+			}
+		}
+		return []byte(strings.Join(paragraphs, "\n")), nil
+	}
+
+	chunks := make([]SubtitleChunk, len(results))
+	for i, r := range results {
+		chunks[i] = SubtitleChunk{Offset: windows[i].start, SRT: []byte(r)}
+	}
+	text, err := StitchSubtitles(chunks)
+	if err != nil {
+		// Keep the region transcriptions so they can be salvaged.
+		j.raw = []byte(strings.Join(results, "\n"))
+		return nil, stageError(StagePostProcess, err)
+	}
+	return text, nil
+}
+
+// detectSpeech measures the level of the dataLen bytes of PCM audio read
+// from r frame by frame, and returns the windows in which speech is
+// detected, as configured by cfg.
+func detectSpeech(r io.Reader, f wav.Format, dataLen int64, cfg VADConfig) ([]chunkWindow, error) {
+	if f.AudioFormat != wav.FormatPCM || (f.BitsPerSample != 8 && f.BitsPerSample != 16) {
+		return nil, fmt.Errorf("unsupported audio format: %d-bit, format %d", f.BitsPerSample, f.AudioFormat)
+	}
+
+	align := f.BlockAlign()
+	toBytes := func(d time.Duration) int64 {
+		n := int64(d) * f.ByteRate() / int64(time.Second)
+		return n - n%align
+	}
+
+	var (
+		frameLen  = max(toBytes(cfg.Frame), align)
+		threshold = math.Pow(10, cfg.Threshold/20)
+		frame     = make([]byte, frameLen)
+		br        = bufio.NewReader(r)
+		spans     []chunkWindow
+	)
+
+	// Join the speech frames into spans, bridging short silences.
+	for offset := int64(0); offset < dataLen; offset += frameLen {
+		n, err := io.ReadFull(br, frame[:min(frameLen, dataLen-offset)])
+		if err != nil {
+			return nil, fmt.Errorf("could not read audio: %w", err)
+		}
+		if frameLevel(frame[:n], f.BitsPerSample) < threshold {
+			continue
+		}
+
+		end := offset + int64(n)
+		if last := len(spans) - 1; last >= 0 && offset-(spans[last].offset+spans[last].size) < toBytes(cfg.MinSilence) {
+			spans[last].size = end - spans[last].offset
+			continue
+		}
+		spans = append(spans, chunkWindow{offset: offset, size: end - offset})
+	}
+
+	// Drop the short spans, pad the others, and merge those that meet.
+	var windows []chunkWindow
+	for _, s := range spans {
+		if s.size < toBytes(cfg.MinSpeech) {
+			continue
+		}
+
+		start := max(s.offset-toBytes(cfg.Padding), 0)
+		end := min(s.offset+s.size+toBytes(cfg.Padding), dataLen)
+
+		if last := len(windows) - 1; last >= 0 && start <= windows[last].offset+windows[last].size {
+			windows[last].size = end - windows[last].offset
+			continue
+		}
+		windows = append(windows, chunkWindow{offset: start, size: end - start})
+	}
+
+	for i := range windows {
+		w := &windows[i]
+		w.index = i
+		w.start, w.end = f.Duration(w.offset), f.Duration(w.offset+w.size)
+	}
+	return windows, nil
+}
+
+// frameLevel returns the RMS level of the PCM samples in frame, relative
+// to full scale. 8-bit samples are unsigned, 16-bit ones little-endian.
+func frameLevel(frame []byte, bitsPerSample uint16) float64 {
+	var sum float64
+	var n int
+
+	switch bitsPerSample {
+	case 8:
+		for _, b := range frame {
+			v := (float64(b) - 128) / 128
+			sum += v * v
+		}
+		n = len(frame)
+	default:
+		for i := 0; i+1 < len(frame); i += 2 {
+			v := float64(int16(binary.LittleEndian.Uint16(frame[i:]))) / 32768
+			sum += v * v
+		}
+		n = len(frame) / 2
+	}
+
+	if n == 0 {
+		return 0
+	}
+	return math.Sqrt(sum / float64(n))
+}
+
+// speechDuration returns the total duration of regions.
+func speechDuration(regions []SpeechRegion) time.Duration {
+	var d time.Duration
+	for _, r := range regions {
+		d += r.End - r.Start
+	}
+	return d
+}
@@ -0,0 +1,224 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alesr/scriber/wav"
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// toneSegment is a stretch of a synthetic recording, either a loud
+// square wave or near silence.
+type toneSegment struct {
+	tone bool
+	d    time.Duration
+}
+
+// toneWAV returns a WAV file in testWAVFormat made of segments.
+func toneWAV(segments ...toneSegment) []byte {
+	var data bytes.Buffer
+	for _, seg := range segments {
+		samples := int(int64(seg.d) * int64(testWAVFormat.SampleRate) / int64(time.Second))
+		for i := range samples {
+			v := int16(10)
+			if seg.tone {
+				v = 16000
+			}
+			if i%2 == 1 {
+				v = -v
+			}
+			_ = binary.Write(&data, binary.LittleEndian, v)
+		}
+	}
+
+	var buf bytes.Buffer
+	_ = wav.WriteHeader(&buf, testWAVFormat, uint32(data.Len()))
+	buf.Write(data.Bytes())
+	return buf.Bytes()
+}
+
+func TestDetectSpeech(t *testing.T) {
+	t.Parallel()
+
+	cfg := VADConfig{
+		Threshold:  -40,
+		Frame:      100 * time.Millisecond,
+		MinSilence: 500 * time.Millisecond,
+		MinSpeech:  300 * time.Millisecond,
+		Padding:    300 * time.Millisecond,
+	}
+	ms := time.Millisecond
+
+	testCases := []struct {
+		name            string
+		givenSegments   []toneSegment
+		expectedRegions []SpeechRegion
+	}{
+		{
+			name: "speech and silence",
+			givenSegments: []toneSegment{
+				{true, 2 * time.Second}, {false, 3 * time.Second},
+				{true, time.Second}, {false, 4 * time.Second},
+			},
+			expectedRegions: []SpeechRegion{{0, 2300 * ms}, {4700 * ms, 6300 * ms}},
+		},
+		{
+			name: "short silence is bridged",
+			givenSegments: []toneSegment{
+				{false, time.Second}, {true, time.Second}, {false, 300 * ms}, {true, time.Second},
+			},
+			expectedRegions: []SpeechRegion{{700 * ms, 3300 * ms}},
+		},
+		{
+			name: "padded regions are merged",
+			givenSegments: []toneSegment{
+				{true, time.Second}, {false, 500 * ms}, {true, time.Second}, {false, 2 * time.Second},
+			},
+			expectedRegions: []SpeechRegion{{0, 2800 * ms}},
+		},
+		{
+			name:          "short noise is dropped",
+			givenSegments: []toneSegment{{false, 2 * time.Second}, {true, 100 * ms}, {false, 2 * time.Second}},
+		},
+		{
+			name:          "silence",
+			givenSegments: []toneSegment{{false, 5 * time.Second}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			audio := toneWAV(tc.givenSegments...)
+			data := audio[wav.HeaderSize:]
+
+			windows, err := detectSpeech(bytes.NewReader(data), testWAVFormat, int64(len(data)), cfg)
+			require.NoError(t, err)
+
+			var regions []SpeechRegion
+			for i, w := range windows {
+				assert.Equal(t, i, w.index)
+				assert.Equal(t, testWAVFormat.Duration(w.offset), w.start)
+				assert.Equal(t, testWAVFormat.Duration(w.offset+w.size), w.end)
+				regions = append(regions, SpeechRegion{Start: w.start, End: w.end})
+			}
+			assert.Equal(t, tc.expectedRegions, regions)
+		})
+	}
+
+	t.Run("unsupported format", func(t *testing.T) {
+		t.Parallel()
+
+		f := testWAVFormat
+		f.BitsPerSample = 32
+
+		_, err := detectSpeech(bytes.NewReader(nil), f, 0, cfg)
+		require.Error(t, err)
+	})
+}
+
+func TestProcess_VoiceActivityDetection(t *testing.T) {
+	t.Parallel()
+
+	// Speech from 0s to 2s, and from 5s to 6s.
+	audio := toneWAV(
+		toneSegment{true, 2 * time.Second}, toneSegment{false, 3 * time.Second},
+		toneSegment{true, time.Second}, toneSegment{false, 4 * time.Second},
+	)
+
+	testCases := []struct {
+		name          string
+		givenType     OutputType
+		givenChunking *ChunkConfig
+		expectedCalls int
+		expectedText  string
+	}{
+		{
+			name:          "subtitles",
+			givenType:     OutputTypeSubtitles,
+			expectedCalls: 2,
+			expectedText: "1\n00:00:00,100 --> 00:00:02,200\n2200ms of speech\n\n" +
+				"2\n00:00:04,900 --> 00:00:06,200\n1400ms of speech\n",
+		},
+		{
+			name:          "transcript",
+			givenType:     OutputTypeTranscript,
+			expectedCalls: 2,
+			expectedText:  "2200ms of speech\n1400ms of speech",
+		},
+		{
+			name:          "chunked regions",
+			givenType:     OutputTypeSubtitles,
+			givenChunking: &ChunkConfig{Length: time.Second, Parallelism: 2},
+			expectedCalls: 5,
+			expectedText: "1\n00:00:00,100 --> 00:00:01,000\n1000ms of speech\n\n" +
+				"2\n00:00:01,100 --> 00:00:02,000\n1000ms of speech\n\n" +
+				"3\n00:00:02,100 --> 00:00:02,200\n200ms of speech\n\n" +
+				"4\n00:00:04,900 --> 00:00:05,800\n1000ms of speech\n\n" +
+				"5\n00:00:05,900 --> 00:00:06,200\n400ms of speech\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls atomic.Int32
+
+			mockClient := &mockWhisperClient{
+				transcribeAudioFunc: func(ctx context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+					data, err := io.ReadAll(in.Data)
+					require.NoError(t, err)
+					calls.Add(1)
+
+					d := testWAVFormat.Duration(int64(len(data) - wav.HeaderSize))
+					text := fmt.Sprintf("%dms of speech", d.Milliseconds())
+					if in.Format == whisperclient.FormatText {
+						return []byte(text), nil
+					}
+
+					end := d.Milliseconds()
+					return []byte(fmt.Sprintf("1\n00:00:00,100 --> 00:00:%02d,%03d\n%s\n", end/1000, end%1000, text)), nil
+				},
+			}
+
+			opts := []Option{
+				WithVoiceActivityDetection(VADConfig{Frame: 100 * time.Millisecond}),
+				WithConverter(copyConverter),
+			}
+			if tc.givenChunking != nil {
+				opts = append(opts, WithChunking(*tc.givenChunking))
+			}
+			s := New(noopLogger(), mockClient, opts...)
+
+			err := s.Process(context.TODO(), Input{
+				Name:       "test.wav",
+				OutputType: tc.givenType,
+				Language:   "en",
+				Data:       io.NopCloser(bytes.NewReader(audio)),
+			})
+			require.NoError(t, err)
+
+			out := <-s.Collect()
+			require.NoError(t, out.Body.Close())
+
+			assert.Equal(t, tc.expectedText, string(out.Text))
+			assert.EqualValues(t, tc.expectedCalls, calls.Load())
+			assert.Equal(t, []SpeechRegion{
+				{Start: 0, End: 2200 * time.Millisecond},
+				{Start: 4800 * time.Millisecond, End: 6200 * time.Millisecond},
+			}, out.SpeechRegions)
+			assert.Equal(t, 10*time.Second, out.AudioDuration)
+		})
+	}
+}
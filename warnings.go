@@ -0,0 +1,122 @@
+package scriber
+
+import (
+	"sync"
+)
+
+// WarningCode identifies a kind of non-fatal anomaly.
+type WarningCode string
+
+const (
+	// WarningTruncated reports subtitles that end well before the end of
+	// the audio. See WithTruncationCheck.
+	WarningTruncated WarningCode = "truncated"
+
+	// WarningDurationMismatch reports converted audio whose duration differs
+	// from the probed duration of the input. See WithDurationProbe.
+	WarningDurationMismatch WarningCode = "duration_mismatch"
+
+	// WarningDurationProbeFailed reports an input whose duration
+	// couldn't be probed.
+	WarningDurationProbeFailed WarningCode = "duration_probe_failed"
+
+	// WarningLayoutProbeFailed reports an input whose channel layout
+	// couldn't be probed. See WithDialogDownmix.
+	WarningLayoutProbeFailed WarningCode = "layout_probe_failed"
+
+	// WarningDownmixed reports a surround input downmixed to the converted
+	// channels, with the filter used, if any. See WithDialogDownmix.
+	WarningDownmixed WarningCode = "downmixed"
+
+	// WarningSizeMismatch reports an input larger than its Size hint.
+	// See WithSizeMismatchPolicy.
+	WarningSizeMismatch WarningCode = "size_mismatch"
+
+	// WarningInputSizeNearLimit reports an input close to the maximum
+	// input size. See WithSoftLimits.
+	WarningInputSizeNearLimit WarningCode = "input_size_near_limit"
+
+	// WarningUploadSizeNearLimit reports an upload close to the upload
+	// limit. See WithSoftLimits.
+	WarningUploadSizeNearLimit WarningCode = "upload_size_near_limit"
+
+	// WarningUsageNearCap reports usage close to the usage cap.
+	// See WithSoftLimits.
+	WarningUsageNearCap WarningCode = "usage_near_cap"
+
+	// WarningTenantQuotaNearLimit reports a tenant close to its daily
+	// audio quota. See WithSoftLimits.
+	WarningTenantQuotaNearLimit WarningCode = "tenant_quota_near_limit"
+
+	// WarningOutputTypeFallback reports an input of an unknown output type
+	// processed as a transcript. See WithUnknownOutputTypePolicy.
+	WarningOutputTypeFallback WarningCode = "output_type_fallback"
+)
+
+// Warning is a non-fatal anomaly detected while processing an input.
+type Warning struct {
+	Code    WarningCode
+	Message string
+}
+
+// WarningError is the error failing a job whose warning
+// was escalated with WithWarningsAsErrors.
+type WarningError struct {
+	Warning
+}
+
+func (e *WarningError) Error() string {
+	return e.Message
+}
+
+// WithWarningsAsErrors fails the jobs raising a warning with one of codes,
+// with a *WarningError, instead of reporting it in Output.Warnings.
+func WithWarningsAsErrors(codes ...WarningCode) Option {
+	return func(s *Scriber) {
+		if s.escalatedWarnings == nil {
+			s.escalatedWarnings = make(map[WarningCode]bool, len(codes))
+		}
+		for _, c := range codes {
+			s.escalatedWarnings[c] = true
+		}
+	}
+}
+
+// warningLog records the warnings of a job.
+type warningLog struct {
+	mu       sync.Mutex
+	warnings []Warning
+}
+
+func (l *warningLog) add(w Warning) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warnings = append(l.warnings, w)
+}
+
+func (l *warningLog) snapshot() []Warning {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.warnings) == 0 {
+		return nil
+	}
+	return append([]Warning(nil), l.warnings...)
+}
+
+// fork returns a log starting with the warnings recorded so far,
+// for a job that carries on separately.
+func (l *warningLog) fork() *warningLog {
+	return &warningLog{warnings: l.snapshot()}
+}
+
+// warn records a warning on the job, or returns it as a
+// *WarningError if its code is escalated.
+func (j *job) warn(code WarningCode, msg string) error {
+	w := Warning{Code: code, Message: msg}
+	if j.escalatedWarnings[code] {
+		return &WarningError{Warning: w}
+	}
+	j.warnings.add(w)
+	return nil
+}
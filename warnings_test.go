@@ -0,0 +1,179 @@
+package scriber
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/alesr/whisperclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess_Warnings(t *testing.T) {
+	t.Parallel()
+
+	// Subtitles ending 30 seconds before the end of the audio.
+	const subtitles = "1\n00:00:01,000 --> 00:00:10,000\nhello\n"
+
+	testCases := []struct {
+		name          string
+		givenOpts     []Option
+		givenProbe    func(context.Context, io.Reader) (time.Duration, error)
+		givenSize     int64
+		expected      []WarningCode
+		expectedStage Stage // Of the escalated warning.
+	}{
+		{
+			name:          "truncated",
+			givenOpts:     []Option{WithTruncationCheck(5*time.Second, TruncationWarn)},
+			expected:      []WarningCode{WarningTruncated},
+			expectedStage: StagePostProcess,
+		},
+		{
+			name:      "duration mismatch",
+			givenOpts: []Option{WithDurationProbe(time.Second)},
+			givenProbe: func(_ context.Context, r io.Reader) (time.Duration, error) {
+				_, err := io.Copy(io.Discard, r)
+				return time.Hour, err
+			},
+			expected:      []WarningCode{WarningDurationMismatch},
+			expectedStage: StagePostProcess,
+		},
+		{
+			name:      "duration probe failure",
+			givenOpts: []Option{WithDurationProbe(time.Second)},
+			givenProbe: func(context.Context, io.Reader) (time.Duration, error) {
+				return 0, assert.AnError
+			},
+			expected:      []WarningCode{WarningDurationProbeFailed},
+			expectedStage: StageValidation,
+		},
+		{
+			name:          "size mismatch",
+			givenSize:     100,
+			expected:      []WarningCode{WarningSizeMismatch},
+			expectedStage: StageConversion,
+		},
+		{
+			name: "several",
+			givenOpts: []Option{
+				WithDurationProbe(time.Second),
+				WithTruncationCheck(5*time.Second, TruncationWarn),
+			},
+			givenProbe: func(context.Context, io.Reader) (time.Duration, error) {
+				return 0, assert.AnError
+			},
+			givenSize:     100,
+			expected:      []WarningCode{WarningDurationProbeFailed, WarningSizeMismatch, WarningTruncated},
+			expectedStage: StageValidation,
+		},
+		{
+			name: "none",
+		},
+	}
+
+	for _, tc := range testCases {
+		for _, escalate := range []bool{false, true} {
+			name := tc.name
+			if escalate {
+				name += " escalated"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				opts := append([]Option{
+					WithConverter(copyConverter),
+				}, tc.givenOpts...)
+				if escalate {
+					opts = append(opts, WithWarningsAsErrors(tc.expected...))
+				}
+
+				s := New(noopLogger(), &mockWhisperClient{
+					transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+						_, err := io.Copy(io.Discard, in.Data)
+						return []byte(subtitles), err
+					},
+				}, opts...)
+				if tc.givenProbe != nil {
+					s.probeDurationFunc = tc.givenProbe
+				}
+
+				err := s.Process(context.TODO(), Input{
+					Name:       "talk.mp4",
+					OutputType: OutputTypeSubtitles,
+					Language:   "en",
+					Size:       tc.givenSize,
+					Data:       readSeekNopCloser{bytes.NewReader(syntheticWAV(40))},
+				})
+
+				if escalate && len(tc.expected) > 0 {
+					var we *WarningError
+					require.ErrorAs(t, err, &we)
+					assert.Equal(t, tc.expected[0], we.Code)
+					assert.NotEmpty(t, we.Message)
+
+					var pe *ProcessError
+					require.ErrorAs(t, err, &pe)
+					assert.Equal(t, tc.expectedStage, pe.Stage)
+					return
+				}
+				require.NoError(t, err)
+
+				out := <-s.Collect()
+				require.NoError(t, out.Body.Close())
+
+				var codes []WarningCode
+				for _, w := range out.Warnings {
+					assert.NotEmpty(t, w.Message)
+					codes = append(codes, w.Code)
+				}
+				assert.Equal(t, tc.expected, codes)
+			})
+		}
+	}
+}
+
+func TestProcess_WarningsPerLanguage(t *testing.T) {
+	t.Parallel()
+
+	s := New(noopLogger(), &mockWhisperClient{
+		transcribeAudioFunc: func(_ context.Context, in whisperclient.TranscribeAudioInput) ([]byte, error) {
+			_, err := io.Copy(io.Discard, in.Data)
+			if in.Language == "fr" {
+				return []byte("1\n00:00:01,000 --> 00:00:02,000\nbonjour\n"), err
+			}
+			return []byte("1\n00:00:01,000 --> 00:00:10,000\nhello\n"), err
+		},
+	},
+		WithTruncationCheck(5*time.Second, TruncationWarn),
+		WithConverter(copyConverter),
+	)
+
+	err := s.Process(context.TODO(), Input{
+		Name:       "talk.mp4",
+		OutputType: OutputTypeSubtitles,
+		Languages:  []string{"en", "fr"},
+		Size:       100,
+		Data:       io.NopCloser(bytes.NewReader(syntheticWAV(12))),
+	})
+	require.NoError(t, err)
+
+	codes := map[string][]WarningCode{}
+	for range 2 {
+		out := <-s.Collect()
+		require.NoError(t, out.Body.Close())
+		for _, w := range out.Warnings {
+			codes[out.Language] = append(codes[out.Language], w.Code)
+		}
+	}
+
+	// The input's warnings are reported for every language.
+	assert.Equal(t, map[string][]WarningCode{
+		"en": {WarningSizeMismatch},
+		"fr": {WarningSizeMismatch, WarningTruncated},
+	}, codes)
+}
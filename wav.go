@@ -0,0 +1,128 @@
+package scriber
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/alesr/scriber/wav"
+)
+
+// readWAVHeader reads the RIFF header from r up to the start of the data chunk.
+// It returns the format, the offset of the first data byte, and the data length
+// declared in the header, which may be wav.UnknownSize or zero for streamed output.
+func readWAVHeader(r io.Reader) (wav.Format, int64, uint32, error) {
+	cr := &countingReader{r: r}
+	format, dataLen, err := wav.ReadHeader(cr)
+	return format, cr.n, dataLen, err
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// maxWAVHeaderSize bounds how much of a stream audioCounter
+// buffers while looking for the start of the data chunk.
+const maxWAVHeaderSize = 4096
+
+// audioCounter is an io.Writer that forwards a WAV or FLAC stream to w,
+// counting the bytes written and parsing the header on the fly so the
+// duration of the audio can be computed, from the PCM byte count for
+// WAV, while the stream is written or afterwards.
+type audioCounter struct {
+	w    io.Writer
+	flac bool
+
+	mu         sync.Mutex
+	n          int64
+	header     []byte
+	parsed     bool
+	format     wav.Format
+	dataOffset int64
+	streamInfo flacStreamInfo
+}
+
+func newWAVCounter(w io.Writer) *audioCounter {
+	return &audioCounter{w: w}
+}
+
+// newFLACCounter returns a counter for a FLAC stream, whose duration
+// is only known when its STREAMINFO declares the number of samples,
+// which ffmpeg can't do when writing to a pipe.
+func newFLACCounter(w io.Writer) *audioCounter {
+	return &audioCounter{w: w, flac: true}
+}
+
+func (c *audioCounter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.n += int64(n)
+
+	if !c.parsed && len(c.header) < maxWAVHeaderSize {
+		c.header = append(c.header, p[:n]...)
+		c.parseHeader()
+	}
+	return n, err
+}
+
+// parseHeader parses the header buffered so far, if complete.
+func (c *audioCounter) parseHeader() {
+	if c.flac {
+		info, err := readFLACHeader(bytes.NewReader(c.header))
+		if err == nil {
+			c.parsed, c.streamInfo = true, info
+			c.header = nil
+		}
+		return
+	}
+
+	format, offset, _, err := readWAVHeader(bytes.NewReader(c.header))
+	if err == nil {
+		c.parsed, c.format, c.dataOffset = true, format, offset
+		c.header = nil
+	}
+}
+
+// spec returns the spec of the audio written, or a zero spec if the
+// stream doesn't look like WAV or FLAC.
+func (c *audioCounter) spec() AudioSpec {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case !c.parsed:
+		return AudioSpec{}
+	case c.flac:
+		return AudioSpec{SampleRate: c.streamInfo.sampleRate, Channels: c.streamInfo.channels, Codec: CodecFLAC}
+	default:
+		return wavSpec(c.format)
+	}
+}
+
+// duration returns the duration of the audio written so far,
+// or zero if the stream doesn't look like WAV or FLAC, or doesn't
+// declare its duration.
+func (c *audioCounter) duration() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.parsed {
+		return 0
+	}
+	if c.flac {
+		return c.streamInfo.duration()
+	}
+	return c.format.Duration(c.n - c.dataOffset)
+}
@@ -0,0 +1,221 @@
+// Package wav reads and writes the RIFF/WAVE headers of PCM audio streams.
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// HeaderSize is the size of the canonical header written by WriteHeader.
+	HeaderSize = 44
+
+	// FormatPCM is the audio format of uncompressed PCM data.
+	FormatPCM = 1
+
+	// UnknownSize is the data length declared by streams whose
+	// length wasn't known when the header was written.
+	UnknownSize = 0xFFFFFFFF
+
+	chunkHeaderSize = 8
+)
+
+// ErrNotWAV is returned when a stream doesn't start with a RIFF/WAVE header.
+var ErrNotWAV = errors.New("not a RIFF/WAVE stream")
+
+// Format describes the PCM layout of a WAV stream.
+type Format struct {
+	AudioFormat   uint16
+	Channels      uint16
+	SampleRate    uint32
+	BitsPerSample uint16
+}
+
+// BlockAlign returns the number of bytes per sample frame.
+func (f Format) BlockAlign() int64 {
+	return int64(f.Channels) * int64(f.BitsPerSample) / 8
+}
+
+// ByteRate returns the number of bytes per second of audio.
+func (f Format) ByteRate() int64 {
+	return int64(f.SampleRate) * f.BlockAlign()
+}
+
+// Duration returns the playback duration of n bytes of PCM data.
+func (f Format) Duration(n int64) time.Duration {
+	rate := f.ByteRate()
+	if rate == 0 {
+		return 0
+	}
+
+	// Split to avoid overflowing int64 for multi-gigabyte streams.
+	return time.Duration(n/rate)*time.Second + time.Duration(n%rate)*time.Second/time.Duration(rate)
+}
+
+// ReadHeader reads the RIFF header from r, leaving r at the first byte of
+// the data chunk. It returns the format and the data length declared in
+// the header, which may be UnknownSize or zero for streamed output.
+func ReadHeader(r io.Reader) (Format, uint32, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return Format{}, 0, fmt.Errorf("could not read riff header: %w", err)
+	}
+
+	if !bytes.Equal(riff[0:4], []byte("RIFF")) || !bytes.Equal(riff[8:12], []byte("WAVE")) {
+		return Format{}, 0, ErrNotWAV
+	}
+
+	var (
+		format  Format
+		haveFmt bool
+	)
+
+	for {
+		var hdr [chunkHeaderSize]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return Format{}, 0, fmt.Errorf("could not read chunk header: %w", err)
+		}
+
+		id := string(hdr[0:4])
+		size := binary.LittleEndian.Uint32(hdr[4:8])
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return Format{}, 0, fmt.Errorf("fmt chunk too small: %d bytes", size)
+			}
+
+			body := make([]byte, size)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return Format{}, 0, fmt.Errorf("could not read fmt chunk: %w", err)
+			}
+
+			format = Format{
+				AudioFormat:   binary.LittleEndian.Uint16(body[0:2]),
+				Channels:      binary.LittleEndian.Uint16(body[2:4]),
+				SampleRate:    binary.LittleEndian.Uint32(body[4:8]),
+				BitsPerSample: binary.LittleEndian.Uint16(body[14:16]),
+			}
+			haveFmt = true
+		case "data":
+			if !haveFmt {
+				return Format{}, 0, errors.New("data chunk before fmt chunk")
+			}
+			if format.BlockAlign() == 0 {
+				return Format{}, 0, errors.New("invalid wav format")
+			}
+			return format, size, nil
+		default:
+			// Skip chunks we don't care about (e.g. LIST), honoring the pad byte.
+			skip := int64(size) + int64(size%2)
+			if _, err := io.CopyN(io.Discard, r, skip); err != nil {
+				return Format{}, 0, fmt.Errorf("could not skip %q chunk: %w", id, err)
+			}
+		}
+	}
+}
+
+// WriteHeader writes a canonical 44-byte header for dataLen bytes of PCM data.
+func WriteHeader(w io.Writer, f Format, dataLen uint32) error {
+	var h [HeaderSize]byte
+
+	riffLen := uint32(UnknownSize)
+	if dataLen <= UnknownSize-(HeaderSize-chunkHeaderSize) {
+		riffLen = HeaderSize - chunkHeaderSize + dataLen
+	}
+
+	copy(h[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:8], riffLen)
+	copy(h[8:12], "WAVE")
+	copy(h[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(h[16:20], 16)
+	binary.LittleEndian.PutUint16(h[20:22], f.AudioFormat)
+	binary.LittleEndian.PutUint16(h[22:24], f.Channels)
+	binary.LittleEndian.PutUint32(h[24:28], f.SampleRate)
+	binary.LittleEndian.PutUint32(h[28:32], uint32(f.ByteRate()))
+	binary.LittleEndian.PutUint16(h[32:34], uint16(f.BlockAlign()))
+	binary.LittleEndian.PutUint16(h[34:36], f.BitsPerSample)
+	copy(h[36:40], "data")
+	binary.LittleEndian.PutUint32(h[40:44], dataLen)
+
+	_, err := w.Write(h[:])
+	return err
+}
+
+// Writer writes a WAV stream whose data length isn't known upfront.
+// The header declares UnknownSize, the convention for pipes, until Close
+// rewrites it with the actual length when the destination is an io.WriteSeeker.
+type Writer struct {
+	w       io.Writer
+	format  Format
+	n       int64
+	started bool
+}
+
+// NewWriter returns a Writer writing PCM data in format f to w.
+func NewWriter(w io.Writer, f Format) *Writer {
+	return &Writer{w: w, format: f}
+}
+
+// Write writes PCM data, preceded by the header on the first call.
+func (w *Writer) Write(p []byte) (int, error) {
+	if err := w.start(); err != nil {
+		return 0, err
+	}
+
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// Len returns the number of data bytes written so far.
+func (w *Writer) Len() int64 {
+	return w.n
+}
+
+// Close writes the header if no data was written, and rewrites it with the
+// data length if the destination is seekable and the length fits. It doesn't
+// close the destination.
+func (w *Writer) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+
+	ws, ok := w.w.(io.WriteSeeker)
+	if !ok || w.n >= UnknownSize {
+		return nil
+	}
+
+	end, err := ws.Seek(0, io.SeekCurrent)
+	if err != nil {
+		// Not every io.WriteSeeker can seek, e.g. an *os.File on a pipe.
+		return nil
+	}
+
+	if _, err := ws.Seek(end-w.n-HeaderSize, io.SeekStart); err != nil {
+		return fmt.Errorf("could not seek to wav header: %w", err)
+	}
+	if err := WriteHeader(ws, w.format, uint32(w.n)); err != nil {
+		return fmt.Errorf("could not rewrite wav header: %w", err)
+	}
+	if _, err := ws.Seek(end, io.SeekStart); err != nil {
+		return fmt.Errorf("could not seek to end of wav data: %w", err)
+	}
+	return nil
+}
+
+func (w *Writer) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+
+	if err := WriteHeader(w.w, w.format, UnknownSize); err != nil {
+		return fmt.Errorf("could not write wav header: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testFormat = Format{AudioFormat: FormatPCM, Channels: 2, SampleRate: 16000, BitsPerSample: 16}
+
+func TestHeaderRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteHeader(&buf, testFormat, 1234))
+	assert.Equal(t, HeaderSize, buf.Len())
+
+	format, dataLen, err := ReadHeader(&buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, testFormat, format)
+	assert.Equal(t, uint32(1234), dataLen)
+	assert.Zero(t, buf.Len(), "the reader must be left at the start of the data")
+	assert.Equal(t, int64(4), format.BlockAlign())
+	assert.Equal(t, int64(64000), format.ByteRate())
+}
+
+func TestWriteHeader_UnknownSize(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteHeader(&buf, testFormat, UnknownSize))
+
+	assert.Equal(t, uint32(UnknownSize), binary.LittleEndian.Uint32(buf.Bytes()[4:8]))
+	assert.Equal(t, uint32(UnknownSize), binary.LittleEndian.Uint32(buf.Bytes()[40:44]))
+}
+
+func TestReadHeader_SkipsUnknownChunks(t *testing.T) {
+	t.Parallel()
+
+	// Mimic ffmpeg's streamed output: a LIST chunk with an odd size
+	// before the data chunk and an unknown data size.
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(UnknownSize))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, []uint16{FormatPCM, 1})
+	binary.Write(&buf, binary.LittleEndian, []uint32{8000, 16000})
+	binary.Write(&buf, binary.LittleEndian, []uint16{2, 16})
+	buf.WriteString("LIST")
+	binary.Write(&buf, binary.LittleEndian, uint32(3))
+	buf.WriteString("abc\x00")
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(UnknownSize))
+	buf.WriteString("pcm")
+
+	format, dataLen, err := ReadHeader(&buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, uint32(8000), format.SampleRate)
+	assert.Equal(t, uint16(1), format.Channels)
+	assert.Equal(t, uint32(UnknownSize), dataLen)
+	assert.Equal(t, "pcm", buf.String())
+}
+
+func TestReadHeader_Invalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		given       []byte
+		expectedErr error
+	}{
+		{name: "empty", given: nil, expectedErr: io.EOF},
+		{name: "not riff", given: []byte("not a wav file at all"), expectedErr: ErrNotWAV},
+		{name: "truncated", given: []byte("RIFF\x00\x00\x00\x00WAVE"), expectedErr: io.EOF},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, _, err := ReadHeader(bytes.NewReader(tc.given))
+			require.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, time.Duration(0), testFormat.Duration(0))
+	assert.Equal(t, time.Second, testFormat.Duration(64000))
+	assert.Equal(t, 1500*time.Millisecond, testFormat.Duration(96000))
+	assert.Equal(t, 100*time.Hour, testFormat.Duration(64000*3600*100))
+	assert.Equal(t, time.Duration(0), Format{}.Duration(100))
+}
+
+func TestWriter(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte{1, 2, 3, 4}, 1000)
+
+	testCases := []struct {
+		name            string
+		givenSeekable   bool
+		givenData       []byte
+		expectedDataLen uint32
+	}{
+		{
+			name:            "seekable",
+			givenSeekable:   true,
+			givenData:       data,
+			expectedDataLen: uint32(len(data)),
+		},
+		{
+			name:            "seekable without data",
+			givenSeekable:   true,
+			expectedDataLen: 0,
+		},
+		{
+			name:            "not seekable",
+			givenData:       data,
+			expectedDataLen: UnknownSize,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := os.Create(filepath.Join(t.TempDir(), "out.wav"))
+			require.NoError(t, err)
+			defer f.Close()
+
+			var dst io.Writer = f
+			if !tc.givenSeekable {
+				dst = struct{ io.Writer }{f}
+			}
+
+			w := NewWriter(dst, testFormat)
+			for src := tc.givenData; len(src) > 0; {
+				n := min(7, len(src))
+				_, err := w.Write(src[:n])
+				require.NoError(t, err)
+				src = src[n:]
+			}
+			require.NoError(t, w.Close())
+			assert.EqualValues(t, len(tc.givenData), w.Len())
+
+			written, err := os.ReadFile(f.Name())
+			require.NoError(t, err)
+			require.Len(t, written, HeaderSize+len(tc.givenData))
+
+			format, dataLen, err := ReadHeader(bytes.NewReader(written))
+			require.NoError(t, err)
+			assert.Equal(t, testFormat, format)
+			assert.Equal(t, tc.expectedDataLen, dataLen)
+			assert.Equal(t, string(tc.givenData), string(written[HeaderSize:]), "the data must be left untouched")
+		})
+	}
+}
+
+func TestReadHeader_FFmpeg(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg not found")
+	}
+
+	// One second of silence, written to a file and to a pipe,
+	// in the format scriber converts inputs to.
+	args := []string{"-v", "error", "-f", "lavfi", "-i", "anullsrc=r=16000:cl=mono", "-t", "1", "-acodec", "pcm_s16le", "-f", "wav"}
+	expectedFormat := Format{AudioFormat: FormatPCM, Channels: 1, SampleRate: 16000, BitsPerSample: 16}
+
+	t.Run("file", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "out.wav")
+		require.NoError(t, exec.Command("ffmpeg", append(args, path)...).Run())
+
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		defer f.Close()
+
+		format, dataLen, err := ReadHeader(f)
+		require.NoError(t, err)
+		assert.Equal(t, expectedFormat, format)
+		assert.Equal(t, time.Second, format.Duration(int64(dataLen)))
+
+		// Writing the header back yields the same stream, minus ffmpeg's metadata.
+		rest, err := io.ReadAll(f)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		require.NoError(t, WriteHeader(&buf, format, dataLen))
+		buf.Write(rest)
+
+		format, dataLen, err = ReadHeader(&buf)
+		require.NoError(t, err)
+		assert.Equal(t, expectedFormat, format)
+		assert.Equal(t, rest, buf.Bytes())
+		assert.EqualValues(t, len(rest), dataLen)
+	})
+
+	t.Run("pipe", func(t *testing.T) {
+		t.Parallel()
+
+		out, err := exec.Command("ffmpeg", append(args, "pipe:1")...).Output()
+		require.NoError(t, err)
+
+		r := bytes.NewReader(out)
+		format, _, err := ReadHeader(r)
+		require.NoError(t, err)
+		assert.Equal(t, expectedFormat, format)
+		assert.Equal(t, time.Second, format.Duration(int64(r.Len())))
+	})
+}
@@ -0,0 +1,43 @@
+package scriber
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWAVCounter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("wav stream written in small pieces", func(t *testing.T) {
+		t.Parallel()
+
+		var dst bytes.Buffer
+		counter := newWAVCounter(&dst)
+
+		src := syntheticWAV(3)
+		for len(src) > 0 {
+			n := min(7, len(src))
+			_, err := counter.Write(src[:n])
+			require.NoError(t, err)
+			src = src[n:]
+		}
+
+		assert.Equal(t, syntheticWAV(3), dst.Bytes())
+		assert.Equal(t, 3*time.Second, counter.duration())
+	})
+
+	t.Run("not a wav stream", func(t *testing.T) {
+		t.Parallel()
+
+		counter := newWAVCounter(io.Discard)
+		_, err := counter.Write(bytes.Repeat([]byte("x"), 2*maxWAVHeaderSize))
+		require.NoError(t, err)
+
+		assert.Equal(t, time.Duration(0), counter.duration())
+	})
+}